@@ -1,34 +1,86 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/irisdrone/backend/database"
-	"github.com/irisdrone/backend/models"
 	"github.com/joho/godotenv"
 )
 
+// cleanupTables are emptied by the cleanup, in order
+var cleanupTables = []string{"devices", "worker_camera_assignments"}
+
+// safeDatabaseMarkers must appear in the database name unless --force-prod is set
+var safeDatabaseMarkers = []string{"dev", "test"}
+
 func main() {
+	yes := flag.Bool("yes", false, "Confirm deletion (required unless --dry-run)")
+	dryRun := flag.Bool("dry-run", false, "Report how many rows would be deleted without deleting")
+	forceProd := flag.Bool("force-prod", false, "Allow running against a database whose name doesn't contain dev/test")
+	flag.Parse()
+
 	// Load .env file
 	if err := godotenv.Load("../../.env"); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Connect to database
-	database.Connect()
+	// Connect without migrating: nothing is written until the database has
+	// passed the safety check and the run isn't a dry run
+	if err := database.Open(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
 
-	log.Println("Cleaning up devices...")
+	var dbName string
+	if err := database.DB.Raw("SELECT current_database()").Scan(&dbName).Error; err != nil {
+		log.Fatalf("Failed to read database name: %v", err)
+	}
+	if !isSafeDatabase(dbName) && !*forceProd {
+		log.Fatalf("Refusing to clean up database %q: its name doesn't contain %s. Pass --force-prod if this is intended.",
+			dbName, strings.Join(safeDatabaseMarkers, "/"))
+	}
 
-	// Delete all devices
-	if err := database.DB.Exec("DELETE FROM devices").Error; err != nil {
-		log.Fatalf("Failed to delete devices: %v", err)
+	counts := make(map[string]int64, len(cleanupTables))
+	for _, table := range cleanupTables {
+		var count int64
+		if err := database.DB.Table(table).Count(&count).Error; err != nil {
+			log.Fatalf("Failed to count %s: %v", table, err)
+		}
+		counts[table] = count
+		fmt.Printf("%-28s %d rows\n", table, count)
 	}
-    
-    // Also clear assignments
-    if err := database.DB.Exec("DELETE FROM worker_camera_assignments").Error; err != nil {
-        log.Fatalf("Failed to delete assignments: %v", err)
-    }
 
-	log.Println("Successfully deleted all devices and assignments.")
+	if *dryRun {
+		log.Printf("Dry run against %q, nothing deleted.", dbName)
+		return
+	}
+	if !*yes {
+		log.Println("Refusing to delete without --yes (use --dry-run to preview).")
+		os.Exit(1)
+	}
+
+	log.Printf("Cleaning up devices in %q...", dbName)
+
+	for _, table := range cleanupTables {
+		if err := database.DB.Exec("DELETE FROM " + table).Error; err != nil {
+			log.Fatalf("Failed to delete %s: %v", table, err)
+		}
+	}
+
+	log.Printf("Successfully deleted %d devices and %d assignments.",
+		counts["devices"], counts["worker_camera_assignments"])
+}
+
+// isSafeDatabase reports whether a database name marks it as a dev or test database
+func isSafeDatabase(name string) bool {
+	name = strings.ToLower(name)
+	for _, marker := range safeDatabaseMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
 }
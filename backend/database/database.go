@@ -14,8 +14,32 @@ import (
 
 var DB *gorm.DB
 
-// Connect initializes the database connection
+// Connect initializes the database connection and migrates the schema
 func Connect() error {
+	if err := Open(); err != nil {
+		return err
+	}
+
+	// Auto-migrate models
+	if err := autoMigrate(); err != nil {
+		return fmt.Errorf("failed to auto-migrate: %w", err)
+	}
+
+	if err := dropSupersededIndexes(); err != nil {
+		return fmt.Errorf("failed to drop superseded indexes: %w", err)
+	}
+
+	if err := ensureDefaultSite(); err != nil {
+		return fmt.Errorf("failed to create default site: %w", err)
+	}
+
+	return nil
+}
+
+// Open initializes the database connection without touching the schema or
+// data, for tools that must not write before they've checked what they're
+// connected to
+func Open() error {
 	databaseURL := os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
 		return fmt.Errorf("DATABASE_URL environment variable is not set")
@@ -43,12 +67,6 @@ func Connect() error {
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
 	log.Println("✅ Database connected successfully")
-
-	// Auto-migrate models
-	if err := autoMigrate(); err != nil {
-		return fmt.Errorf("failed to auto-migrate: %w", err)
-	}
-
 	return nil
 }
 
@@ -61,16 +79,144 @@ func autoMigrate() error {
 		&models.WorkerToken{},
 		&models.WorkerCameraAssignment{},
 		&models.WorkerApprovalRequest{},
+		&models.WorkerBootEvent{},
 		&models.CrowdAnalysis{},
 		&models.CrowdAlert{},
 		&models.TrafficViolation{},
+		&models.OffenseSession{},
+		&models.ViolationPaymentRequest{},
 		&models.Vehicle{},
 		&models.VehicleDetection{},
 		&models.Watchlist{},
+		&models.ArchivedVehicle{},
+		&models.ViolationAutoApproveRule{},
+		&models.ViolationRuleAudit{},
+		&models.LegalHoldAudit{},
+		&models.SystemSetting{},
+		&models.StoredImage{},
+		&models.PendingImage{},
+		&models.DeadLetterEvent{},
+		&models.DeviceStorageQuota{},
+		&models.PlateCorrection{},
+		&models.PlateSubstitution{},
+		&models.ViewRotation{},
+		&models.ViolationConfidenceThreshold{},
+		&models.VehicleTypeMapping{},
+		&models.Site{},
+		&models.Geofence{},
+		&models.GeofenceVehicle{},
+		&models.QuietHoursWindow{},
+		&models.ZoneEnforcement{},
 		&models.User{},
 	)
 }
 
+// supersededIndexes are indexes AutoMigrate created under an earlier model
+// that a renamed index has replaced
+var supersededIndexes = []string{
+	"idx_detection_bbox", // by idx_detection_bbox_centre
+	"idx_violation_bbox", // by idx_violation_bbox_centre
+}
+
+// dropSupersededIndexes drops indexes AutoMigrate no longer maintains
+func dropSupersededIndexes() error {
+	for _, name := range supersededIndexes {
+		if err := DB.Exec("DROP INDEX IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureDefaultSite creates the default site and places devices without a
+// site in it
+func ensureDefaultSite() error {
+	site := models.Site{ID: models.DefaultSiteID, Name: "Default"}
+	if err := DB.Where("id = ?", site.ID).FirstOrCreate(&site).Error; err != nil {
+		return err
+	}
+	return DB.Model(&models.Device{}).
+		Where("site_id IS NULL OR site_id = ''").
+		Update("site_id", models.DefaultSiteID).Error
+}
+
+// vehicleIdentityIndexes are the unique indexes on vehicles for each
+// identity mode
+var vehicleIdentityIndexes = map[string]string{
+	models.VehicleIdentityPlate:       "CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicles_identity_plate ON vehicles (plate_number)",
+	models.VehicleIdentityPlateRegion: "CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicles_identity_plate_region ON vehicles (plate_number, plate_region)",
+}
+
+// EnsureVehicleIdentityIndex makes vehicles unique on the identity mode's
+// columns. The new unique index is created before the others are dropped, so
+// if existing vehicles conflict under the new mode (the same plate in two
+// regions when switching back to plate) the previous constraint is kept and
+// the error returned.
+func EnsureVehicleIdentityIndex(mode string) error {
+	create, ok := vehicleIdentityIndexes[mode]
+	if !ok {
+		return fmt.Errorf("unknown vehicle identity mode %q", mode)
+	}
+	if err := DB.Exec(create).Error; err != nil {
+		return fmt.Errorf("failed to create %s identity index: %w", mode, err)
+	}
+
+	for other := range vehicleIdentityIndexes {
+		if other != mode {
+			if err := DB.Exec("DROP INDEX IF EXISTS idx_vehicles_identity_" + other).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	// Before identity modes, plate_number's own index was the unique one
+	var unique bool
+	if err := DB.Raw(`SELECT EXISTS (SELECT 1 FROM pg_indexes
+		WHERE tablename = 'vehicles' AND indexname = 'idx_vehicles_plate_number'
+			AND indexdef LIKE 'CREATE UNIQUE%')`).Scan(&unique).Error; err != nil {
+		return err
+	}
+	if unique {
+		if err := DB.Exec("DROP INDEX idx_vehicles_plate_number").Error; err != nil {
+			return err
+		}
+		if err := DB.Exec("CREATE INDEX idx_vehicles_plate_number ON vehicles (plate_number)").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureVehicleEmbeddings migrates the vehicle_embeddings table and, when the
+// pgvector extension can be enabled, a vec column of the given dimensions with
+// an HNSW cosine index. Returns whether pgvector is in use; without it,
+// similarity search falls back to scanning candidates in Go.
+func EnsureVehicleEmbeddings(dims int) (bool, error) {
+	if err := DB.AutoMigrate(&models.VehicleEmbedding{}); err != nil {
+		return false, err
+	}
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+		log.Printf("ℹ️ pgvector unavailable (%v), vehicle similarity search scans in Go", err)
+		return false, nil
+	}
+	if err := DB.Exec(fmt.Sprintf("ALTER TABLE vehicle_embeddings ADD COLUMN IF NOT EXISTS vec vector(%d)", dims)).Error; err != nil {
+		return false, fmt.Errorf("failed to add embedding vector column: %w", err)
+	}
+	var existing int
+	if err := DB.Raw(`SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = 'vehicle_embeddings'::regclass AND attname = 'vec'`).Scan(&existing).Error; err != nil {
+		return false, err
+	}
+	if existing != dims {
+		return false, fmt.Errorf("embedding vector column has %d dimensions, not %d", existing, dims)
+	}
+	// HNSW needs pgvector 0.5; older versions still search, just unindexed
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_vehicle_embeddings_vec ON vehicle_embeddings USING hnsw (vec vector_cosine_ops)").Error; err != nil {
+		log.Printf("⚠️ Failed to index embedding vectors: %v", err)
+	}
+	return true, nil
+}
+
 // Close closes the database connection
 func Close() error {
 	sqlDB, err := DB.DB()
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// defaultCoverageWindow is how recent a camera's output must be to count as running
+const defaultCoverageWindow = 15 * time.Minute
+
+// jsonbStrings decodes a JSONB string array (e.g. assignment analytics)
+func jsonbStrings(j models.JSONB) []string {
+	list, _ := j.Data.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, strings.ToLower(s))
+		}
+	}
+	return result
+}
+
+// workerReportedAnalytics returns the analytics a worker reported running in its last heartbeat
+func workerReportedAnalytics(worker *models.Worker) []string {
+	meta, ok := worker.Metadata.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, _ := meta["analyticsRunning"].([]interface{})
+	return jsonbStrings(models.NewJSONB(list))
+}
+
+// analyticActivity selects the devices that produced output for an analytic since cutoff
+func analyticActivity(analytic string, cutoff time.Time) *gorm.DB {
+	switch analytic {
+	case "anpr":
+		return database.DB.Model(&models.VehicleDetection{}).
+			Where("timestamp >= ? AND plate_detected = true", cutoff)
+	case "vcc":
+		return database.DB.Model(&models.VehicleDetection{}).
+			Where("timestamp >= ?", cutoff)
+	case "crowd":
+		return database.DB.Model(&models.CrowdAnalysis{}).
+			Where("timestamp >= ?", cutoff)
+	}
+	if violationType := models.ViolationType(strings.ToUpper(analytic)); validViolationType(violationType) {
+		return database.DB.Model(&models.TrafficViolation{}).
+			Where("timestamp >= ? AND violation_type = ?", cutoff, violationType)
+	}
+	return database.DB.Model(&models.Event{}).
+		Where("timestamp >= ? AND type = ?", cutoff, analytic)
+}
+
+// analyticCoverage is the camera breakdown for one analytic
+type analyticCoverage struct {
+	Analytic          string   `json:"analytic"`
+	Running           int      `json:"running"`
+	Silent            int      `json:"silent"`
+	CapableUnassigned int      `json:"capableUnassigned"`
+	SilentCameras     []string `json:"silentCameras"` // workerID.cameraID
+}
+
+// GetAnalyticsCoverage returns, per analytic, how many cameras are running it,
+// assigned but silent, or on a capable worker but unassigned (admin)
+// GET /api/admin/analytics/coverage
+func GetAnalyticsCoverage(c *gin.Context) {
+	window := defaultCoverageWindow
+	if v := c.Query("windowMinutes"); v != "" {
+		mins, err := strconv.Atoi(v)
+		if err != nil || mins <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "windowMinutes must be a positive integer"})
+			return
+		}
+		window = time.Duration(mins) * time.Minute
+	}
+	cutoff := time.Now().Add(-window)
+
+	var workers []models.Worker
+	if err := database.DB.Select("id, metadata").
+		Where("status NOT IN ?", []models.WorkerStatus{models.WorkerStatusPending, models.WorkerStatusRevoked}).
+		Find(&workers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workers"})
+		return
+	}
+
+	workerIDs := make([]string, 0, len(workers))
+	for _, w := range workers {
+		workerIDs = append(workerIDs, w.ID)
+	}
+
+	var assignments []models.WorkerCameraAssignment
+	if err := database.DB.Where("worker_id IN ? AND is_active = true", workerIDs).Find(&assignments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assignments"})
+		return
+	}
+
+	var discovered []models.Device
+	if err := database.DB.Select("id, worker_id").Where("worker_id IN ?", workerIDs).Find(&discovered).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cameras"})
+		return
+	}
+
+	// Cameras per worker, and what each camera is assigned
+	cameras := make(map[string]map[string]bool)  // workerID -> deviceID set
+	assigned := make(map[string]map[string]bool) // workerID.deviceID -> analytics
+	capable := make(map[string]map[string]bool)  // workerID -> analytics it can run
+	for _, w := range workers {
+		cameras[w.ID] = make(map[string]bool)
+		capable[w.ID] = make(map[string]bool)
+		for _, a := range workerReportedAnalytics(&w) {
+			capable[w.ID][a] = true
+		}
+	}
+	for _, d := range discovered {
+		cameras[*d.WorkerID][d.ID] = true
+	}
+
+	analytics := make(map[string]bool)
+	for _, a := range assignments {
+		cameras[a.WorkerID][a.DeviceID] = true
+		key := a.WorkerID + "." + a.DeviceID
+		assigned[key] = make(map[string]bool)
+		for _, analytic := range jsonbStrings(a.Analytics) {
+			assigned[key][analytic] = true
+			capable[a.WorkerID][analytic] = true
+			analytics[analytic] = true
+		}
+	}
+	for _, set := range capable {
+		for analytic := range set {
+			analytics[analytic] = true
+		}
+	}
+
+	coverage := make([]analyticCoverage, 0, len(analytics))
+	for analytic := range analytics {
+		var active []string
+		if err := analyticActivity(analytic, cutoff).Distinct("device_id").Pluck("device_id", &active).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch " + analytic + " activity"})
+			return
+		}
+		producing := make(map[string]bool, len(active))
+		for _, id := range active {
+			producing[id] = true
+		}
+
+		entry := analyticCoverage{Analytic: analytic, SilentCameras: []string{}}
+		for workerID, devices := range cameras {
+			for deviceID := range devices {
+				key := workerID + "." + deviceID
+				switch {
+				case assigned[key][analytic] && producing[deviceID]:
+					entry.Running++
+				case assigned[key][analytic]:
+					entry.Silent++
+					entry.SilentCameras = append(entry.SilentCameras, key)
+				case capable[workerID][analytic]:
+					entry.CapableUnassigned++
+				}
+			}
+		}
+		sort.Strings(entry.SilentCameras)
+		coverage = append(coverage, entry)
+	}
+	sort.Slice(coverage, func(i, j int) bool { return coverage[i].Analytic < coverage[j].Analytic })
+
+	c.JSON(http.StatusOK, gin.H{
+		"windowMinutes": int(window.Minutes()),
+		"workers":       len(workers),
+		"assignments":   len(assignments),
+		"analytics":     coverage,
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIPrefix is the unversioned API, kept as an alias of v1 for clients
+	// that predate versioning
+	APIPrefix = "/api"
+	// APIV1Prefix is the current API version. Breaking changes go under a new
+	// version so v1 clients, such as lagging edge workers, keep working.
+	APIV1Prefix = "/api/v1"
+)
+
+// apiVersioning controls the unversioned /api alias
+var apiVersioning = struct {
+	unversioned bool
+	sunset      time.Time // Zero = not deprecated
+}{unversioned: true}
+
+// InitAPIVersioning reads API_UNVERSIONED_ROUTES (default true, serve the v1
+// routes under /api as well) and API_UNVERSIONED_SUNSET (an RFC 3339 time or
+// YYYY-MM-DD date; when set, /api responses are marked deprecated with it as
+// the Sunset). Returns whether /api is served and its sunset.
+func InitAPIVersioning() (bool, time.Time) {
+	apiVersioning.unversioned = os.Getenv("API_UNVERSIONED_ROUTES") != "false"
+	apiVersioning.sunset = time.Time{}
+	if v := os.Getenv("API_UNVERSIONED_SUNSET"); v != "" {
+		sunset, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			sunset, err = time.Parse("2006-01-02", v)
+		}
+		if err != nil {
+			log.Printf("⚠️ [API] Invalid API_UNVERSIONED_SUNSET %q, ignoring", v)
+		} else {
+			apiVersioning.sunset = sunset
+		}
+	}
+	return apiVersioning.unversioned, apiVersioning.sunset
+}
+
+// unversionedAPIPath maps an /api/v1 path to its /api alias, so lookups keyed
+// on /api paths match both
+func unversionedAPIPath(path string) string {
+	if path == APIV1Prefix || strings.HasPrefix(path, APIV1Prefix+"/") {
+		return APIPrefix + strings.TrimPrefix(path, APIV1Prefix)
+	}
+	return path
+}
+
+// setDeprecationHeaders marks a response as coming from a deprecated endpoint:
+// Deprecation, and when known, Sunset and a Link to the successor (RFC 8594)
+func setDeprecationHeaders(c *gin.Context, sunset time.Time, successor string) {
+	c.Header("Deprecation", "true")
+	if !sunset.IsZero() {
+		c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if successor != "" {
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+	}
+}
+
+// Deprecated marks the responses of an endpoint slated for removal. Pass a
+// zero sunset when no removal date is set and an empty successor when the
+// endpoint has no replacement.
+func Deprecated(sunset time.Time, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setDeprecationHeaders(c, sunset, successor)
+		c.Next()
+	}
+}
+
+// UnversionedAPIMiddleware marks /api responses as deprecated, linking to the
+// same path under /api/v1, once API_UNVERSIONED_SUNSET is set
+func UnversionedAPIMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !apiVersioning.sunset.IsZero() {
+			successor := APIV1Prefix + strings.TrimPrefix(c.Request.URL.Path, APIPrefix)
+			setDeprecationHeaders(c, apiVersioning.sunset, successor)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// bboxBackfillBatch is how many rows the backfill reads at a time
+const bboxBackfillBatch = 500
+
+// bboxKeys are the metadata keys an edge may put an object's bounding box under
+var bboxKeys = []string{"bbox", "boundingBox", "bounding_box", "box"}
+
+// frameSizeKeys are the metadata keys giving the frame size a pixel bounding
+// box is relative to, as width and height pairs
+var frameSizeKeys = [][2]string{{"frame_width", "frame_height"}, {"image_width", "image_height"}}
+
+// bboxColumns controls whether ingest copies bounding boxes from metadata
+// into the bbox columns
+var bboxColumns = true
+
+// InitBBoxColumns reads BBOX_COLUMNS (default true) and returns whether
+// bounding boxes are denormalized. When they are, rows stored before are
+// backfilled in the background.
+func InitBBoxColumns() bool {
+	bboxColumns = os.Getenv("BBOX_COLUMNS") != "false"
+	if bboxColumns {
+		go backfillBBoxColumns()
+	}
+	return bboxColumns
+}
+
+// frameBBox reads the bounding box in a row's metadata as x, y, width and
+// height fractions of the frame. Boxes whose coordinates are all within 0-1
+// are taken as normalized; pixel boxes need the frame size alongside them.
+// All nil when there's no usable box.
+func frameBBox(metadata models.JSONB) (x, y, w, h *float64) {
+	data, ok := metadata.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	box, ok := parseBBox(data, bboxKeys)
+	if !ok || box[2] <= box[0] || box[3] <= box[1] {
+		return
+	}
+
+	if box[0] > 1 || box[1] > 1 || box[2] > 1 || box[3] > 1 {
+		var width, height float64
+		for _, keys := range frameSizeKeys {
+			fw, okW := bboxNumber(data[keys[0]])
+			fh, okH := bboxNumber(data[keys[1]])
+			if okW && okH && fw > 0 && fh > 0 {
+				width, height = fw, fh
+				break
+			}
+		}
+		if width == 0 {
+			return
+		}
+		box[0], box[2] = box[0]/width, box[2]/width
+		box[1], box[3] = box[1]/height, box[3]/height
+	}
+
+	// Clip to the frame
+	for i := range box {
+		if box[i] < 0 {
+			box[i] = 0
+		} else if box[i] > 1 {
+			box[i] = 1
+		}
+	}
+	bx, by, bw, bh := box[0], box[1], box[2]-box[0], box[3]-box[1]
+	return &bx, &by, &bw, &bh
+}
+
+// stampDetectionBBox copies a detection's bounding box into its bbox columns
+func stampDetectionBBox(detection *models.VehicleDetection) {
+	if bboxColumns {
+		detection.BBoxX, detection.BBoxY, detection.BBoxW, detection.BBoxH = frameBBox(detection.Metadata)
+	}
+}
+
+// stampViolationBBox copies a violation's bounding box into its bbox columns
+func stampViolationBBox(violation *models.TrafficViolation) {
+	if bboxColumns {
+		violation.BBoxX, violation.BBoxY, violation.BBoxW, violation.BBoxH = frameBBox(violation.Metadata)
+	}
+}
+
+// backfillBBoxColumns fills the bbox columns of detections and violations
+// stored before they existed. Each row is visited once per run; rows without
+// a usable box stay nil and are looked at again on the next start.
+func backfillBBoxColumns() {
+	for _, table := range []string{"vehicle_detections", "traffic_violations"} {
+		filled, err := backfillBBoxTable(table)
+		if err != nil {
+			log.Printf("⚠️ [BBOX] Backfill of %s stopped: %v", table, err)
+		}
+		if filled > 0 {
+			log.Printf("📐 [BBOX] Backfilled bounding boxes of %d %s", filled, table)
+		}
+	}
+}
+
+// backfillBBoxTable fills the bbox columns of one table and returns how many
+// rows got a box
+func backfillBBoxTable(table string) (int, error) {
+	keys := make([]string, len(bboxKeys))
+	for i, key := range bboxKeys {
+		keys[i] = "'" + key + "'"
+	}
+	hasBox := fmt.Sprintf("jsonb_exists_any(metadata, array[%s])", strings.Join(keys, ","))
+
+	filled := 0
+	var lastID int64
+	for {
+		var rows []struct {
+			ID       int64
+			Metadata models.JSONB
+		}
+		if err := database.DB.Table(table).Select("id, metadata").
+			Where("id > ? AND bbox_x IS NULL AND metadata IS NOT NULL AND "+hasBox, lastID).
+			Order("id ASC").Limit(bboxBackfillBatch).Scan(&rows).Error; err != nil {
+			return filled, err
+		}
+		if len(rows) == 0 {
+			return filled, nil
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			x, y, w, h := frameBBox(row.Metadata)
+			if x == nil {
+				continue
+			}
+			if err := database.DB.Table(table).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"bbox_x": *x, "bbox_y": *y, "bbox_w": *w, "bbox_h": *h,
+			}).Error; err != nil {
+				return filled, err
+			}
+			filled++
+		}
+	}
+}
+
+// parseFrameRegion reads the optional frameRegion query parameter: a
+// rectangle x1,y1,x2,y2 in fractions of the frame. nil when absent.
+func parseFrameRegion(c *gin.Context) (*[4]float64, error) {
+	v := c.Query("frameRegion")
+	if v == "" {
+		return nil, nil
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("frameRegion must be x1,y1,x2,y2")
+	}
+	var region [4]float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || n < 0 || n > 1 {
+			return nil, fmt.Errorf("frameRegion coordinates must be between 0 and 1")
+		}
+		region[i] = n
+	}
+	if region[2] <= region[0] || region[3] <= region[1] {
+		return nil, fmt.Errorf("frameRegion must have x1 < x2 and y1 < y2")
+	}
+	return &region, nil
+}
+
+// frameRegionScope keeps rows whose bounding box centre lies in region. Rows
+// without bbox columns are left out.
+func frameRegionScope(query *gorm.DB, region *[4]float64) *gorm.DB {
+	if region == nil {
+		return query
+	}
+	return query.Where("bbox_x + bbox_w / 2 BETWEEN ? AND ? AND bbox_y + bbox_h / 2 BETWEEN ? AND ?",
+		region[0], region[2], region[1], region[3])
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/irisdrone/backend/models"
+)
+
+// maxROIPoints bounds the size of a region-of-interest polygon
+const maxROIPoints = 32
+
+// minROIArea rejects degenerate polygons (fraction of the frame)
+const minROIArea = 0.001
+
+// validateROI checks a region-of-interest polygon. Points are [x, y] fractions
+// of the frame (0-1); the polygon must be simple (no crossing edges).
+// An empty polygon means the whole frame and is valid.
+func validateROI(points [][2]float64) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if len(points) < 3 {
+		return fmt.Errorf("roi needs at least 3 points")
+	}
+	if len(points) > maxROIPoints {
+		return fmt.Errorf("roi can have at most %d points", maxROIPoints)
+	}
+
+	for i, p := range points {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || p[0] < 0 || p[0] > 1 || p[1] < 0 || p[1] > 1 {
+			return fmt.Errorf("roi point %d must have x and y between 0 and 1", i)
+		}
+	}
+
+	// Shoelace area
+	var area float64
+	for i := range points {
+		j := (i + 1) % len(points)
+		area += points[i][0]*points[j][1] - points[j][0]*points[i][1]
+	}
+	if math.Abs(area)/2 < minROIArea {
+		return fmt.Errorf("roi area is too small")
+	}
+
+	// Non-adjacent edges must not intersect
+	n := len(points)
+	for i := 0; i < n; i++ {
+		a1, a2 := points[i], points[(i+1)%n]
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue // adjacent edges share a vertex
+			}
+			b1, b2 := points[j], points[(j+1)%n]
+			if segmentsIntersect(a1, a2, b1, b2) {
+				return fmt.Errorf("roi edges %d and %d cross", i, j)
+			}
+		}
+	}
+
+	return nil
+}
+
+// roiJSONB converts a validated polygon for storage; an empty polygon is stored as NULL
+func roiJSONB(points [][2]float64) models.JSONB {
+	if len(points) == 0 {
+		return models.JSONB{}
+	}
+	return models.NewJSONB(points)
+}
+
+func segmentsIntersect(p1, p2, q1, q2 [2]float64) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	// Collinear overlaps
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// orientation is the cross product of (b-a) x (c-a)
+func orientation(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// onSegment reports whether c, known to be collinear with a-b, lies on the segment
+func onSegment(a, b, c [2]float64) bool {
+	return math.Min(a[0], b[0]) <= c[0] && c[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= c[1] && c[1] <= math.Max(a[1], b[1])
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultClockSkewThreshold = 30 * time.Second
+
+// clockSkewThreshold is how far a worker's clock may drift from the server's
+// before the worker is flagged (0 = never flagged)
+var clockSkewThreshold = defaultClockSkewThreshold
+
+// InitClockSkew reads WORKER_CLOCK_SKEW_THRESHOLD_SECONDS (default 30, 0 = no
+// flagging) and returns the threshold
+func InitClockSkew() time.Duration {
+	clockSkewThreshold = defaultClockSkewThreshold
+	if v := os.Getenv("WORKER_CLOCK_SKEW_THRESHOLD_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			clockSkewThreshold = time.Duration(secs) * time.Second
+		}
+	}
+	return clockSkewThreshold
+}
+
+// recordClockSkew stores the skew between the time a worker reported and the
+// time its heartbeat arrived, logging when the worker crosses the threshold.
+// Network latency makes the worker look slightly behind; that's well below
+// any threshold worth setting.
+func recordClockSkew(worker *models.Worker, workerTime, received time.Time) {
+	wasSkewed := clockSkewed(worker)
+	skew := workerTime.Sub(received).Milliseconds()
+	worker.ClockSkewMs = &skew
+
+	switch skewed := clockSkewed(worker); {
+	case skewed && !wasSkewed:
+		log.Printf("⏰ [WORKER] Clock skew on %s (%s): %s off server time, check NTP",
+			worker.Name, worker.ID, time.Duration(skew)*time.Millisecond)
+	case !skewed && wasSkewed:
+		log.Printf("⏰ [WORKER] Clock on %s (%s) back within %s of server time", worker.Name, worker.ID, clockSkewThreshold)
+	}
+}
+
+// clockSkewed reports whether a worker's last reported clock skew exceeds the threshold
+func clockSkewed(worker *models.Worker) bool {
+	if clockSkewThreshold == 0 || worker.ClockSkewMs == nil {
+		return false
+	}
+	skew := *worker.ClockSkewMs
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew > clockSkewThreshold.Milliseconds()
+}
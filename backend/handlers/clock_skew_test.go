@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/irisdrone/backend/models"
+)
+
+func TestClockSkewedThreshold(t *testing.T) {
+	defer func(threshold time.Duration) { clockSkewThreshold = threshold }(clockSkewThreshold)
+
+	skew := int64(-45000)
+	worker := &models.Worker{ClockSkewMs: &skew}
+
+	clockSkewThreshold = 30 * time.Second
+	if !clockSkewed(worker) {
+		t.Error("45s behind not flagged with a 30s threshold")
+	}
+
+	clockSkewThreshold = 0
+	if clockSkewed(worker) {
+		t.Error("worker flagged with the check off")
+	}
+	if clockSkewed(&models.Worker{}) {
+		t.Error("worker without a reported skew flagged")
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+	"gorm.io/gorm"
+)
+
+const defaultColdStorageAge = 30 * 24 * time.Hour
+
+// coldImagePrefix is the image path under /api/v1/images that serves an
+// image from cold storage
+const coldImagePrefix = "cold/"
+
+// coldStorage is where detection images are tiered to once they're old
+var coldStorage = struct {
+	store services.ColdStore // nil = tiering off
+	age   time.Duration
+}{
+	age: defaultColdStorageAge,
+}
+
+// SetColdStore sets the store old detection images are moved to
+func SetColdStore(store services.ColdStore) {
+	coldStorage.store = store
+}
+
+// InitColdStorage reads COLD_STORAGE (dir or http; unset = off),
+// COLD_STORAGE_DIR for dir, COLD_STORAGE_URL, COLD_STORAGE_API_KEY and
+// COLD_STORAGE_CLASS for http, and COLD_STORAGE_AFTER_DAYS (default 30).
+// Returns the store's name, which is safe to log, and the tiering age.
+func InitColdStorage() (string, time.Duration) {
+	coldStorage.age = defaultColdStorageAge
+	if v := os.Getenv("COLD_STORAGE_AFTER_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			coldStorage.age = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	switch backend := os.Getenv("COLD_STORAGE"); backend {
+	case "":
+		return "", 0
+	case "dir":
+		dir := os.Getenv("COLD_STORAGE_DIR")
+		if dir == "" {
+			log.Printf("⚠️ [STORAGE] COLD_STORAGE_DIR is not set, cold storage disabled")
+			return "", 0
+		}
+		SetColdStore(services.NewDirColdStore(dir))
+	case "http":
+		u, err := url.Parse(os.Getenv("COLD_STORAGE_URL"))
+		if err != nil || u.Host == "" {
+			log.Printf("⚠️ [STORAGE] Invalid COLD_STORAGE_URL, cold storage disabled")
+			return "", 0
+		}
+		SetColdStore(services.NewHTTPColdStore(u.String(), os.Getenv("COLD_STORAGE_API_KEY"), os.Getenv("COLD_STORAGE_CLASS")))
+		return u.Host, coldStorage.age
+	default:
+		log.Printf("⚠️ [STORAGE] Unknown COLD_STORAGE %q, cold storage disabled", backend)
+		return "", 0
+	}
+	return coldStorage.store.Name(), coldStorage.age
+}
+
+// coldImageURL is the URL an image is served from once it's in cold storage
+func coldImageURL(key string) string {
+	return APIV1Prefix + "/images/" + coldImagePrefix + key
+}
+
+// tierableDetectionImages scopes stored_images to local detection images
+// older than cutoff that no violation references. Violation evidence stays on
+// local storage, so violation URLs never need rewriting.
+func tierableDetectionImages(db *gorm.DB, cutoff time.Time) *gorm.DB {
+	return db.Model(&models.StoredImage{}).
+		Where("cold_key IS NULL AND event_type IN ? AND created_at < ?", detectionImageEventTypes, cutoff).
+		Where("NOT " + violationImageCondition)
+}
+
+// archiveColdImages moves detection images past the tiering age to cold
+// storage and points their stored_images and vehicle_detections URLs at the
+// cold copy. Rows are kept; a failed upload leaves the image where it was.
+func archiveColdImages() {
+	if coldStorage.store == nil {
+		return
+	}
+	cutoff := time.Now().Add(-coldStorage.age)
+
+	var moved, bytes int64
+	for {
+		var batch []models.StoredImage
+		if err := tierableDetectionImages(database.DB, cutoff).
+			Order("created_at ASC").
+			Limit(retentionBatchSize).
+			Find(&batch).Error; err != nil {
+			log.Printf("⚠️ [STORAGE] Failed to load images to archive: %v", err)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, img := range batch {
+			if err := archiveColdImage(img); err != nil {
+				// Most likely the cold store is unreachable; try again next run
+				log.Printf("⚠️ [STORAGE] Failed to archive %s: %v", img.Path, err)
+				if moved > 0 {
+					log.Printf("🧊 [STORAGE] Archived %d detection images to cold storage (%d MB)", moved, bytes/(1024*1024))
+				}
+				return
+			}
+			moved++
+			bytes += img.Bytes
+		}
+		if len(batch) < retentionBatchSize {
+			break
+		}
+	}
+
+	if moved > 0 {
+		log.Printf("🧊 [STORAGE] Archived %d detection images older than %s to cold storage (%d MB)",
+			moved, coldStorage.age, bytes/(1024*1024))
+	}
+}
+
+// archiveColdImage uploads one image, rewrites the URLs that reference it and
+// then removes the local file. An image whose file is already gone is
+// recorded as archived without a cold copy being made.
+func archiveColdImage(img models.StoredImage) error {
+	key := strings.TrimPrefix(img.URL, "/uploads/")
+	file, err := os.Open(img.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if file != nil {
+		err = coldStorage.store.Put(key, file, img.Bytes)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	newURL := coldImageURL(key)
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&img).Updates(map[string]interface{}{
+			"cold_key":    key,
+			"url":         newURL,
+			"archived_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		for _, column := range []string{"full_image_url", "plate_image_url", "vehicle_image_url"} {
+			if err := tx.Model(&models.VehicleDetection{}).Where(column+" = ?", img.URL).
+				Update(column, newURL).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(img.Path); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️ [STORAGE] Archived %s but failed to delete the local copy: %v", img.Path, err)
+	}
+	return nil
+}
+
+// removeStoredImage deletes an image's file from wherever it's kept
+func removeStoredImage(img models.StoredImage) error {
+	if img.ColdKey != nil {
+		if coldStorage.store == nil {
+			return errors.New("cold storage is not configured")
+		}
+		return coldStorage.store.Delete(*img.ColdKey)
+	}
+	if err := os.Remove(img.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// serveColdImage streams an image from cold storage
+func serveColdImage(c *gin.Context, key string) {
+	if coldStorage.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cold storage is not configured"})
+		return
+	}
+	body, err := coldStorage.store.Get(key)
+	if err != nil {
+		if errors.Is(err, services.ErrColdObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		log.Printf("⚠️ [STORAGE] Failed to fetch %s from cold storage: %v", key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch image from cold storage"})
+		return
+	}
+	defer body.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, body)
+}
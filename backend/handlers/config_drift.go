@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultConfigDriftGrace = 5 * time.Minute
+
+// configDriftGrace is how long a worker may take to apply a new config
+// version before it's flagged as drifted
+var configDriftGrace = defaultConfigDriftGrace
+
+// InitConfigDrift reads WORKER_CONFIG_DRIFT_GRACE_SECONDS (default 300) and
+// returns the grace period
+func InitConfigDrift() time.Duration {
+	configDriftGrace = defaultConfigDriftGrace
+	if v := os.Getenv("WORKER_CONFIG_DRIFT_GRACE_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			configDriftGrace = time.Duration(secs) * time.Second
+		}
+	}
+	return configDriftGrace
+}
+
+// bumpConfigVersion increments a worker's config version after its
+// assignments change, so it refetches its config
+func bumpConfigVersion(db *gorm.DB, workerID string) error {
+	return db.Model(&models.Worker{}).Where("id = ?", workerID).Updates(map[string]interface{}{
+		"config_version":    gorm.Expr("config_version + 1"),
+		"config_updated_at": time.Now(),
+	}).Error
+}
+
+// recordAppliedConfig stores the config version a worker reports running and
+// the error of its last failed apply, if any
+func recordAppliedConfig(worker *models.Worker, version int, applyError string) {
+	now := time.Now()
+	if worker.AppliedConfigVersion == nil || *worker.AppliedConfigVersion != version {
+		worker.ConfigAppliedAt = &now
+	}
+	worker.AppliedConfigVersion = &version
+	if applyError != "" {
+		if worker.ConfigApplyError == nil || *worker.ConfigApplyError != applyError {
+			log.Printf("⚙️ [WORKER] %s (%s) failed to apply config v%d: %s", worker.Name, worker.ID, worker.ConfigVersion, applyError)
+		}
+		worker.ConfigApplyError = &applyError
+	} else {
+		worker.ConfigApplyError = nil
+	}
+}
+
+// configDrift is how many config versions a worker is behind; nil when the
+// worker has never reported the version it applied
+func configDrift(worker *models.Worker) *int {
+	if worker.AppliedConfigVersion == nil {
+		return nil
+	}
+	drift := worker.ConfigVersion - *worker.AppliedConfigVersion
+	if drift < 0 {
+		drift = 0
+	}
+	return &drift
+}
+
+// configDrifted reports whether a worker failed to apply its config, or is
+// still behind the assigned version after the grace period. Workers that
+// don't report an applied version are never flagged.
+func configDrifted(worker *models.Worker) bool {
+	drift := configDrift(worker)
+	if drift == nil {
+		return false
+	}
+	if worker.ConfigApplyError != nil {
+		return true
+	}
+	return *drift > 0 && (worker.ConfigUpdatedAt == nil || time.Since(*worker.ConfigUpdatedAt) > configDriftGrace)
+}
+
+// configDriftedScope limits a worker query to drifted workers, matching configDrifted
+func configDriftedScope(query *gorm.DB) *gorm.DB {
+	return query.Where(`applied_config_version IS NOT NULL AND (config_apply_error IS NOT NULL OR
+		(applied_config_version < config_version AND (config_updated_at IS NULL OR config_updated_at < ?)))`,
+		time.Now().Add(-configDriftGrace))
+}
+
+// ConfigAppliedRequest - The config version a worker is running
+type ConfigAppliedRequest struct {
+	ConfigVersion *int   `json:"config_version" binding:"required"`
+	Error         string `json:"error,omitempty"` // Why the latest config couldn't be applied
+}
+
+// ReportConfigApplied records the config version a worker applied, right
+// after it applies one instead of waiting for the next heartbeat
+// POST /api/workers/:id/config/applied
+func ReportConfigApplied(c *gin.Context) {
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if !workerTokenValid(&worker, c.GetHeader("X-Auth-Token")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
+		return
+	}
+
+	var req ConfigAppliedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recordAppliedConfig(&worker, *req.ConfigVersion, req.Error)
+	if err := database.DB.Model(&worker).Select("applied_config_version", "config_applied_at", "config_apply_error").
+		Updates(&worker).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record applied config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"config_version": worker.ConfigVersion,
+		"config_drift":   configDrift(&worker),
+	})
+}
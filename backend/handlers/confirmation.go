@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultConfirmationTTL = 2 * time.Minute
+
+// maxPendingConfirmations caps the tokens waiting to be redeemed; past it,
+// the one closest to expiring is dropped to make room
+const maxPendingConfirmations = 1000
+
+// confirmTokenHeader carries the token that confirms a destructive action;
+// ?confirmToken= works too
+const confirmTokenHeader = "X-Confirm-Token"
+
+// pendingConfirmation is a destructive action that was described to a client
+// and is waiting for it to call again with the token
+type pendingConfirmation struct {
+	action  string
+	target  string
+	expires time.Time
+}
+
+// destructiveConfirmation holds the tokens issued for destructive admin
+// actions. Tokens are single-use and only confirm the action and target they
+// were issued for.
+var destructiveConfirmation = struct {
+	mu      sync.Mutex
+	enabled bool
+	ttl     time.Duration
+	pending map[string]pendingConfirmation
+}{
+	enabled: true,
+	ttl:     defaultConfirmationTTL,
+	pending: make(map[string]pendingConfirmation),
+}
+
+// InitDestructiveConfirmation reads DESTRUCTIVE_CONFIRMATION (default true)
+// and DESTRUCTIVE_CONFIRMATION_TTL_SECONDS (default 120). Returns whether
+// destructive admin actions need a confirmation token and how long one lasts.
+func InitDestructiveConfirmation() (bool, time.Duration) {
+	destructiveConfirmation.mu.Lock()
+	defer destructiveConfirmation.mu.Unlock()
+
+	destructiveConfirmation.enabled = os.Getenv("DESTRUCTIVE_CONFIRMATION") != "false"
+	destructiveConfirmation.ttl = defaultConfirmationTTL
+	if v := os.Getenv("DESTRUCTIVE_CONFIRMATION_TTL_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			destructiveConfirmation.ttl = time.Duration(secs) * time.Second
+		}
+	}
+	return destructiveConfirmation.enabled, destructiveConfirmation.ttl
+}
+
+// pruneConfirmations drops expired tokens, with the lock held
+func pruneConfirmations(now time.Time) {
+	for t, p := range destructiveConfirmation.pending {
+		if now.After(p.expires) {
+			delete(destructiveConfirmation.pending, t)
+		}
+	}
+}
+
+// consumeConfirmation redeems a token for an action on a target, dropping it
+// and any expired ones. A token for another action or target is left alone.
+func consumeConfirmation(token, action, target string, now time.Time) bool {
+	destructiveConfirmation.mu.Lock()
+	defer destructiveConfirmation.mu.Unlock()
+
+	pruneConfirmations(now)
+	p, ok := destructiveConfirmation.pending[token]
+	if !ok || p.action != action || p.target != target {
+		return false
+	}
+	delete(destructiveConfirmation.pending, token)
+	return true
+}
+
+// issueConfirmation stores a new token for an action on a target, dropping
+// expired ones and, at maxPendingConfirmations, the one closest to expiring
+func issueConfirmation(action, target string, now time.Time) (string, time.Time) {
+	destructiveConfirmation.mu.Lock()
+	defer destructiveConfirmation.mu.Unlock()
+
+	pruneConfirmations(now)
+	if len(destructiveConfirmation.pending) >= maxPendingConfirmations {
+		var oldest string
+		for t, p := range destructiveConfirmation.pending {
+			if oldest == "" || p.expires.Before(destructiveConfirmation.pending[oldest].expires) {
+				oldest = t
+			}
+		}
+		delete(destructiveConfirmation.pending, oldest)
+	}
+
+	token := generateAuthToken()
+	expires := now.Add(destructiveConfirmation.ttl)
+	destructiveConfirmation.pending[token] = pendingConfirmation{action: action, target: target, expires: expires}
+	return token, expires
+}
+
+// requireConfirmation gates a destructive action behind a confirmation
+// token. Called without a valid token, it responds 428 with the impact of the
+// action and a short-lived token, and returns false; the client shows the
+// impact and calls again with the token in X-Confirm-Token to go ahead.
+// impact counts what the action would affect; an error there responds 500.
+// Returns true straight away when confirmation is disabled.
+func requireConfirmation(c *gin.Context, action, target string, impact func() (gin.H, error)) bool {
+	destructiveConfirmation.mu.Lock()
+	enabled := destructiveConfirmation.enabled
+	destructiveConfirmation.mu.Unlock()
+	if !enabled {
+		return true
+	}
+
+	now := time.Now()
+	token := c.GetHeader(confirmTokenHeader)
+	if token == "" {
+		token = c.Query("confirmToken")
+	}
+	if token != "" && consumeConfirmation(token, action, target, now) {
+		log.Printf("⚠️ [CONFIRM] Confirmed %s on %s", action, target)
+		return true
+	}
+
+	details, err := impact()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assess the impact of " + action})
+		return false
+	}
+	newToken, expires := issueConfirmation(action, target, now)
+	response := gin.H{
+		"error":                "Confirmation required: call again with the confirmToken in " + confirmTokenHeader,
+		"confirmationRequired": true,
+		"action":               action,
+		"target":               target,
+		"impact":               details,
+		"confirmToken":         newToken,
+		"expiresAt":            expires,
+	}
+	if token != "" {
+		response["error"] = "Confirmation token is invalid or expired; call again with the new confirmToken"
+	}
+	c.JSON(http.StatusPreconditionRequired, response)
+	return false
+}
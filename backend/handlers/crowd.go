@@ -116,6 +116,8 @@ func PostCrowdAnalysis(c *gin.Context) {
 		return
 	}
 
+	flagCrowdAnomalies(&analysis)
+
 	c.JSON(http.StatusCreated, gin.H{"success": true, "id": strconv.FormatInt(analysis.ID, 10)})
 }
 
@@ -127,16 +129,16 @@ func GetCrowdAnalysis(c *gin.Context) {
 		query = query.Where("device_id = ?", deviceID)
 	}
 
-	if startTime := c.Query("startTime"); startTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTime); err == nil {
-			query = query.Where("timestamp >= ?", parsed)
-		}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if endTime := c.Query("endTime"); endTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTime); err == nil {
-			query = query.Where("timestamp <= ?", parsed)
-		}
+	if !startTime.IsZero() {
+		query = query.Where("timestamp >= ?", startTime)
+	}
+	if c.Query("endTime") != "" {
+		query = query.Where("timestamp <= ?", endTime)
 	}
 
 	if severity := c.Query("severity"); severity != "" {
@@ -352,6 +354,7 @@ func PostCrowdAlert(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create crowd alert"})
 		return
 	}
+	notifyAlert(alert.DeviceID, alert.Severity, alert)
 
 	c.JSON(http.StatusCreated, gin.H{"success": true, "id": strconv.FormatInt(alert.ID, 10)})
 }
@@ -447,7 +450,7 @@ func ResolveCrowdAlert(c *gin.Context) {
 func GetHotspots(c *gin.Context) {
 	var devices []models.Device
 	if err := database.DB.Where("lat != ? AND lng != ?", 0, 0).
-		Select("id, name, lat, lng, type, status, zone_id").
+		Select("id, name, lat, lng, type, status, zone_id, last_event_at").
 		Find(&devices).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
 		return
@@ -466,6 +469,8 @@ func GetHotspots(c *gin.Context) {
 		DensityLevel    models.CrowdDensityLevel `json:"densityLevel"`
 		CongestionLevel *int                   `json:"congestionLevel"`
 		LastUpdated     *time.Time             `json:"lastUpdated"`
+		LastEventAt     *time.Time             `json:"lastEventAt"`
+		Online          bool                   `json:"online"`
 	}
 
 	hotspots := make([]Hotspot, 0, len(devices))
@@ -486,6 +491,8 @@ func GetHotspots(c *gin.Context) {
 			ZoneID:          device.ZoneID,
 			HotspotSeverity: models.SeverityGreen,
 			DensityLevel:    models.DensityLow,
+			LastEventAt:     device.LastEventAt,
+			Online:          isDeviceOnline(device.LastEventAt),
 		}
 
 		if latestAnalysis.ID != 0 {
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// anomalyAlertType marks crowd alerts raised from CrowdAnalysis.Anomalies
+	anomalyAlertType = "ANOMALY"
+
+	// anomalyAlertPriority puts anomaly alerts at the top of the 1-10 scale (default 5)
+	anomalyAlertPriority = 10
+
+	defaultAnomalyCooldown = 5 * time.Minute
+)
+
+// defaultFlaggedAnomalies are the anomalies that raise an alert unless
+// CROWD_ANOMALY_TYPES says otherwise
+var defaultFlaggedAnomalies = []string{"stampede_risk", "sudden_dispersal", "crowd_surge", "fight", "fallen_person"}
+
+// crowdAnomalyFlags controls which anomalies raise alerts
+var crowdAnomalyFlags = struct {
+	types    map[string]bool // nil = every anomaly is flagged
+	cooldown time.Duration   // per device and anomaly, while an alert is unresolved
+}{
+	cooldown: defaultAnomalyCooldown,
+}
+
+// InitCrowdAnomalyFlags reads CROWD_ANOMALY_TYPES (comma-separated, "*" for all)
+// and CROWD_ANOMALY_COOLDOWN_SECONDS (default 300), and returns the flagged types
+func InitCrowdAnomalyFlags() []string {
+	list := defaultFlaggedAnomalies
+	if v := strings.TrimSpace(os.Getenv("CROWD_ANOMALY_TYPES")); v != "" {
+		list = strings.Split(v, ",")
+	}
+
+	crowdAnomalyFlags.types = make(map[string]bool)
+	flagged := make([]string, 0, len(list))
+	for _, t := range list {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "*" {
+			crowdAnomalyFlags.types = nil
+			flagged = []string{"*"}
+			break
+		}
+		if t != "" {
+			crowdAnomalyFlags.types[t] = true
+			flagged = append(flagged, t)
+		}
+	}
+
+	crowdAnomalyFlags.cooldown = defaultAnomalyCooldown
+	if v := os.Getenv("CROWD_ANOMALY_COOLDOWN_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			crowdAnomalyFlags.cooldown = time.Duration(secs) * time.Second
+		}
+	}
+
+	return flagged
+}
+
+// crowdAnomaly is one anomaly reported in an analysis
+type crowdAnomaly struct {
+	Type    string
+	Details interface{}
+}
+
+// parseCrowdAnomalies accepts the shapes analytics workers send: a list of
+// names, a list of objects with a "type" (or "name"), or a map of name to
+// details (false/null entries are skipped)
+func parseCrowdAnomalies(raw interface{}) []crowdAnomaly {
+	var anomalies []crowdAnomaly
+	switch v := raw.(type) {
+	case []interface{}:
+		for _, item := range v {
+			switch a := item.(type) {
+			case string:
+				anomalies = append(anomalies, crowdAnomaly{Type: a})
+			case map[string]interface{}:
+				name, _ := a["type"].(string)
+				if name == "" {
+					name, _ = a["name"].(string)
+				}
+				if name != "" {
+					anomalies = append(anomalies, crowdAnomaly{Type: name, Details: a})
+				}
+			}
+		}
+	case map[string]interface{}:
+		for name, details := range v {
+			if details == nil || details == false {
+				continue
+			}
+			anomalies = append(anomalies, crowdAnomaly{Type: name, Details: details})
+		}
+	}
+
+	for i := range anomalies {
+		anomalies[i].Type = strings.ToLower(strings.TrimSpace(anomalies[i].Type))
+	}
+	return anomalies
+}
+
+// flagCrowdAnomalies raises a high-severity crowd alert for each flagged anomaly
+// in an analysis and pushes it to connected clients
+func flagCrowdAnomalies(analysis *models.CrowdAnalysis) {
+	for _, anomaly := range parseCrowdAnomalies(analysis.Anomalies.Data) {
+		if anomaly.Type == "" {
+			continue
+		}
+		if crowdAnomalyFlags.types != nil && !crowdAnomalyFlags.types[anomaly.Type] {
+			continue
+		}
+
+		// Don't repeat an alert that is still open for the same anomaly
+		if crowdAnomalyFlags.cooldown > 0 {
+			var open int64
+			database.DB.Model(&models.CrowdAlert{}).
+				Where("device_id = ? AND alert_type = ? AND is_resolved = false AND timestamp > ?",
+					analysis.DeviceID, anomalyAlertType, analysis.Timestamp.Add(-crowdAnomalyFlags.cooldown)).
+				Where("trigger_rule->>'anomaly' = ?", anomaly.Type).
+				Count(&open)
+			if open > 0 {
+				continue
+			}
+		}
+
+		description := fmt.Sprintf("Anomaly %q detected in crowd analysis %d", anomaly.Type, analysis.ID)
+		alert := models.CrowdAlert{
+			DeviceID:        analysis.DeviceID,
+			Timestamp:       analysis.Timestamp,
+			AlertType:       anomalyAlertType,
+			Severity:        models.SeverityRed,
+			Priority:        anomalyAlertPriority,
+			TriggerRule:     models.NewJSONB(map[string]interface{}{"anomaly": anomaly.Type, "details": anomaly.Details}),
+			PeopleCount:     analysis.PeopleCount,
+			DensityLevel:    analysis.DensityLevel,
+			CongestionLevel: analysis.CongestionLevel,
+			Title:           "Crowd anomaly: " + strings.ReplaceAll(anomaly.Type, "_", " "),
+			Description:     &description,
+			AnalysisID:      &analysis.ID,
+		}
+		if analysis.PeopleCount != nil {
+			alert.ActualValue = float64(*analysis.PeopleCount)
+		}
+		if analysis.MovementType != "" {
+			movement := analysis.MovementType
+			alert.MovementType = &movement
+		}
+
+		if err := database.DB.Create(&alert).Error; err != nil {
+			log.Printf("⚠️ [CROWD_ANOMALY] Failed to create alert - Device: %s, Anomaly: %s, Error: %v", analysis.DeviceID, anomaly.Type, err)
+			continue
+		}
+		log.Printf("🚨 [CROWD_ANOMALY] %s on device %s (analysis %d, alert %d)", anomaly.Type, analysis.DeviceID, analysis.ID, alert.ID)
+
+		notifyAlert(analysis.DeviceID, alert.Severity, alert)
+	}
+}
+
+// GetCrowdAnomalies handles GET /api/crowd/anomalies - List recent anomaly alerts
+func GetCrowdAnomalies(c *gin.Context) {
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.CrowdAlert{}).
+		Where("alert_type = ? AND timestamp >= ? AND timestamp <= ?", anomalyAlertType, startTime, endTime)
+	if deviceID := c.Query("deviceId"); deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if anomaly := c.Query("anomaly"); anomaly != "" {
+		query = query.Where("trigger_rule->>'anomaly' = ?", strings.ToLower(anomaly))
+	}
+
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	var alerts []models.CrowdAlert
+	if err := query.Preload("Device", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, lat, lng, type")
+	}).Order("timestamp DESC").Limit(limit).Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch crowd anomalies"})
+		return
+	}
+
+	occurrences := make([]gin.H, 0, len(alerts))
+	for _, a := range alerts {
+		occurrence := gin.H{
+			"alertId":    a.ID,
+			"deviceId":   a.DeviceID,
+			"device":     a.Device,
+			"timestamp":  a.Timestamp,
+			"isResolved": a.IsResolved,
+			"analysisId": a.AnalysisID,
+		}
+		if rule, ok := a.TriggerRule.Data.(map[string]interface{}); ok {
+			occurrence["anomaly"] = rule["anomaly"]
+			occurrence["details"] = rule["details"]
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+
+	c.JSON(http.StatusOK, occurrences)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// defaultDemographicsWindow is used when no startTime is given
+const defaultDemographicsWindow = 24 * time.Hour
+
+// demographicCategory accumulates the buckets of one demographic breakdown
+// (e.g. "gender" -> male/female, "ageGroups" -> adults/seniors/children)
+type demographicCategory struct {
+	buckets  map[string]float64
+	analyses int
+}
+
+// demographicBuckets extracts the numeric buckets of a category. Non-numeric
+// and negative values are skipped; numeric strings are accepted.
+func demographicBuckets(raw interface{}) map[string]float64 {
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	buckets := make(map[string]float64, len(obj))
+	for name, v := range obj {
+		var n float64
+		switch val := v.(type) {
+		case float64:
+			n = val
+		case string:
+			parsed, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				continue
+			}
+			n = parsed
+		default:
+			continue
+		}
+		if n >= 0 {
+			buckets[name] = n
+		}
+	}
+	return buckets
+}
+
+// GetCrowdDemographics handles GET /api/crowd/demographics - Aggregate demographic
+// breakdowns across crowd analyses in a time window.
+//
+// Bucket values are summed per category. With weighted=true each analysis's
+// breakdown is first converted to shares and scaled by its people count, so the
+// totals estimate people per bucket regardless of whether the edge reports
+// counts or fractions.
+func GetCrowdDemographics(c *gin.Context) {
+	startTime, endTime, err := parseTimeRange(c, defaultDemographicsWindow)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	weighted := c.Query("weighted") == "true"
+
+	deviceID := c.Query("deviceId")
+	window := func() *gorm.DB {
+		query := database.DB.Model(&models.CrowdAnalysis{}).
+			Where("timestamp >= ? AND timestamp <= ?", startTime, endTime)
+		if deviceID != "" {
+			query = query.Where("device_id = ?", deviceID)
+		}
+		return query
+	}
+
+	var total int64
+	if err := window().Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count crowd analyses"})
+		return
+	}
+
+	var rows []models.CrowdAnalysis
+	if err := window().Select("id, people_count, demographics").
+		Where("demographics IS NOT NULL").
+		Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch crowd analyses"})
+		return
+	}
+
+	categories := make(map[string]*demographicCategory)
+	withData := 0
+	for _, row := range rows {
+		demographics, ok := row.Demographics.Data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		contributed := false
+		for name, raw := range demographics {
+			buckets := demographicBuckets(raw)
+			if len(buckets) == 0 {
+				continue
+			}
+
+			scale := 1.0
+			if weighted {
+				var sum float64
+				for _, v := range buckets {
+					sum += v
+				}
+				if row.PeopleCount == nil || sum == 0 {
+					continue
+				}
+				scale = float64(*row.PeopleCount) / sum
+			}
+
+			cat, ok := categories[name]
+			if !ok {
+				cat = &demographicCategory{buckets: make(map[string]float64)}
+				categories[name] = cat
+			}
+			for bucket, v := range buckets {
+				cat.buckets[bucket] += v * scale
+			}
+			cat.analyses++
+			contributed = true
+		}
+		if contributed {
+			withData++
+		}
+	}
+
+	result := make(gin.H, len(categories))
+	for name, cat := range categories {
+		var sum float64
+		for _, v := range cat.buckets {
+			sum += v
+		}
+		percentages := make(gin.H, len(cat.buckets))
+		for bucket, v := range cat.buckets {
+			if sum > 0 {
+				percentages[bucket] = v / sum * 100
+			} else {
+				percentages[bucket] = 0.0
+			}
+		}
+		result[name] = gin.H{
+			"totals":      cat.buckets,
+			"percentages": percentages,
+			"analyses":    cat.analyses,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"startTime":                startTime,
+		"endTime":                  endTime,
+		"weighted":                 weighted,
+		"totalAnalyses":            total,
+		"analysesWithDemographics": withData,
+		"categories":               result,
+	})
+}
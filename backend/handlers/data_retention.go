@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultDataRetentionInterval = 24 * time.Hour
+
+var (
+	errDataRetentionDisabled = errors.New("data retention is disabled (RETENTION_DAYS is unset)")
+	errDataRetentionBusy     = errors.New("a retention pass is already running")
+)
+
+// retentionClass is a kind of record the retention job purges by age
+type retentionClass struct {
+	model func() interface{}
+	// keep matches rows that stay even when they're old and not held
+	// themselves, e.g. violations of a held offense session
+	keep string
+}
+
+// retentionClasses are the record classes a retention policy can cover, keyed
+// by the name used in RETENTION_DAYS and legal holds. Each has a timestamp
+// and a legal_hold column.
+var retentionClasses = map[string]retentionClass{
+	"events":     {model: func() interface{} { return &models.Event{} }},
+	"detections": {model: func() interface{} { return &models.VehicleDetection{} }},
+	"violations": {
+		model: func() interface{} { return &models.TrafficViolation{} },
+		keep:  `EXISTS (SELECT 1 FROM offense_sessions s WHERE s.id = traffic_violations.offense_session_id AND s.legal_hold)`,
+	},
+	"crowd": {
+		model: func() interface{} { return &models.CrowdAnalysis{} },
+		// Alerts point at their analysis; it goes once they have
+		keep: `EXISTS (SELECT 1 FROM crowd_alerts a WHERE a.analysis_id = crowd_analyses.id)`,
+	},
+	"alerts": {model: func() interface{} { return &models.CrowdAlert{} }},
+}
+
+// legalHoldModels are the records a legal hold can be placed on: the
+// retention classes, and offense sessions, which hold their violations
+var legalHoldModels = map[string]func() interface{}{
+	"events":           retentionClasses["events"].model,
+	"detections":       retentionClasses["detections"].model,
+	"violations":       retentionClasses["violations"].model,
+	"crowd":            retentionClasses["crowd"].model,
+	"alerts":           retentionClasses["alerts"].model,
+	"offense_sessions": func() interface{} { return &models.OffenseSession{} },
+}
+
+// heldImageCondition matches stored images that are evidence of a held
+// violation or detection, which no purge or quota trim may delete
+const heldImageCondition = `(EXISTS (
+	SELECT 1 FROM traffic_violations v
+	WHERE (v.legal_hold OR EXISTS (SELECT 1 FROM offense_sessions s WHERE s.id = v.offense_session_id AND s.legal_hold))
+		AND (v.full_snapshot_url = stored_images.url OR v.plate_image_url = stored_images.url
+			OR v.plate_images @> jsonb_build_array(jsonb_build_object('url', stored_images.url)))
+) OR EXISTS (
+	SELECT 1 FROM vehicle_detections d
+	WHERE d.legal_hold AND stored_images.url IN (d.full_image_url, d.plate_image_url, d.vehicle_image_url)
+))`
+
+// dataRetention holds the retention policy and the counters of the purge job
+var dataRetention = struct {
+	mu       sync.Mutex
+	policy   map[string]time.Duration // class -> age past which it's purged; classes not in it are kept
+	interval time.Duration
+	dryRun   bool // only count what would be purged
+
+	running bool
+	purged  map[string]int64 // records purged since startup, per class
+	lastRun *dataRetentionResult
+}{
+	policy:   map[string]time.Duration{},
+	interval: defaultDataRetentionInterval,
+	purged:   map[string]int64{},
+}
+
+// dataRetentionResult is the outcome of one retention pass
+type dataRetentionResult struct {
+	StartedAt time.Time                    `json:"startedAt"`
+	DryRun    bool                         `json:"dryRun"`
+	Classes   map[string]*classPurgeResult `json:"classes"`
+	Error     string                       `json:"error,omitempty"`
+}
+
+// classPurgeResult is what a retention pass did to one class
+type classPurgeResult struct {
+	Cutoff  time.Time `json:"cutoff"`
+	Matched int64     `json:"matched"` // records past the cutoff and not held
+	Held    int64     `json:"held"`    // records past the cutoff kept by a hold, their own or e.g. their session's
+	Purged  int64     `json:"purged"`  // 0 on a dry run
+}
+
+// InitDataRetention reads RETENTION_DAYS, the age in days past which each
+// class is purged (e.g. "events=30,detections=90,violations=1825"; classes
+// left out are kept forever), RETENTION_DRY_RUN and
+// RETENTION_INTERVAL_HOURS (default 24), starts the purge loop and returns
+// the policy
+func InitDataRetention() map[string]time.Duration {
+	dataRetention.mu.Lock()
+	defer dataRetention.mu.Unlock()
+
+	dataRetention.policy = map[string]time.Duration{}
+	for _, pair := range strings.Split(os.Getenv("RETENTION_DAYS"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, known := retentionClasses[name]; !known {
+			log.Printf("⚠️ [RETENTION] Ignoring retention for unknown class %q", name)
+			continue
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || days <= 0 {
+			log.Printf("⚠️ [RETENTION] Ignoring invalid retention %q for %s", value, name)
+			continue
+		}
+		dataRetention.policy[name] = time.Duration(days) * 24 * time.Hour
+	}
+	dataRetention.dryRun = os.Getenv("RETENTION_DRY_RUN") == "true"
+	dataRetention.interval = defaultDataRetentionInterval
+	if v := os.Getenv("RETENTION_INTERVAL_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			dataRetention.interval = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if len(dataRetention.policy) > 0 {
+		interval := dataRetention.interval
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				dataRetention.mu.Lock()
+				dryRun := dataRetention.dryRun
+				dataRetention.mu.Unlock()
+				purgeExpiredData(dryRun)
+			}
+		}()
+	}
+
+	return dataRetention.policy
+}
+
+// expiredRecords scopes a class to records older than cutoff that no legal
+// hold keeps
+func expiredRecords(db *gorm.DB, class retentionClass, cutoff time.Time) *gorm.DB {
+	scope := db.Model(class.model()).Where("timestamp < ? AND legal_hold = ?", cutoff, false)
+	if class.keep != "" {
+		scope = scope.Where("NOT " + class.keep)
+	}
+	return scope
+}
+
+// heldRecords scopes a class to records older than cutoff that stay because
+// of a hold: their own legal hold or the class's keep condition
+func heldRecords(db *gorm.DB, class retentionClass, cutoff time.Time) *gorm.DB {
+	held := "legal_hold = true"
+	if class.keep != "" {
+		held = "(" + held + " OR " + class.keep + ")"
+	}
+	return db.Model(class.model()).Where("timestamp < ?", cutoff).Where(held)
+}
+
+// purgeExpiredData deletes the records of every class in the policy that are
+// past their age, skipping held ones. With dryRun it only counts them. Only
+// one pass runs at a time.
+func purgeExpiredData(dryRun bool) (*dataRetentionResult, error) {
+	dataRetention.mu.Lock()
+	if len(dataRetention.policy) == 0 {
+		dataRetention.mu.Unlock()
+		return nil, errDataRetentionDisabled
+	}
+	if dataRetention.running {
+		dataRetention.mu.Unlock()
+		return nil, errDataRetentionBusy
+	}
+	dataRetention.running = true
+	policy := make(map[string]time.Duration, len(dataRetention.policy))
+	for name, age := range dataRetention.policy {
+		policy[name] = age
+	}
+	dataRetention.mu.Unlock()
+
+	result := &dataRetentionResult{
+		StartedAt: time.Now(),
+		DryRun:    dryRun,
+		Classes:   make(map[string]*classPurgeResult, len(policy)),
+	}
+	var runErr error
+	for _, name := range sortedClassNames(policy) {
+		class := retentionClasses[name]
+		cr := &classPurgeResult{Cutoff: result.StartedAt.Add(-policy[name])}
+		result.Classes[name] = cr
+
+		if err := expiredRecords(database.DB, class, cr.Cutoff).Count(&cr.Matched).Error; err != nil {
+			runErr = fmt.Errorf("%s: %w", name, err)
+			break
+		}
+		if err := heldRecords(database.DB, class, cr.Cutoff).Count(&cr.Held).Error; err != nil {
+			runErr = fmt.Errorf("%s: %w", name, err)
+			break
+		}
+		if dryRun || cr.Matched == 0 {
+			continue
+		}
+
+		purged, err := purgeClass(name, class, cr.Cutoff)
+		cr.Purged = purged
+		if purged > 0 {
+			log.Printf("🗑️ [RETENTION] Purged %d %s older than %s (%d held)", purged, name, policy[name], cr.Held)
+		}
+		if err != nil {
+			runErr = fmt.Errorf("%s: %w", name, err)
+			break
+		}
+	}
+	if runErr != nil {
+		result.Error = runErr.Error()
+		log.Printf("⚠️ [RETENTION] Retention pass failed: %v", runErr)
+	}
+
+	dataRetention.mu.Lock()
+	dataRetention.running = false
+	for name, cr := range result.Classes {
+		dataRetention.purged[name] += cr.Purged
+	}
+	dataRetention.lastRun = result
+	dataRetention.mu.Unlock()
+
+	return result, runErr
+}
+
+// purgeClass deletes a class's expired records in batches, oldest first, and
+// returns how many were deleted
+func purgeClass(name string, class retentionClass, cutoff time.Time) (int64, error) {
+	var purged int64
+	for {
+		var ids []int64
+		if err := expiredRecords(database.DB, class, cutoff).
+			Order("timestamp ASC").
+			Limit(retentionBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// Embeddings only exist for detections, and only with re-identification on
+			if name == "detections" && tx.Migrator().HasTable(&models.VehicleEmbedding{}) {
+				if err := tx.Where("detection_id IN ?", ids).Delete(&models.VehicleEmbedding{}).Error; err != nil {
+					return fmt.Errorf("delete embeddings: %w", err)
+				}
+			}
+			return tx.Delete(class.model(), ids).Error
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += int64(len(ids))
+		if len(ids) < retentionBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// sortedClassNames returns the classes of a policy in a stable order
+func sortedClassNames(policy map[string]time.Duration) []string {
+	names := make([]string, 0, len(policy))
+	for name := range policy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetDataRetention returns the retention policy, counters, last run and the
+// number of held records of each class (admin)
+// GET /api/admin/retention
+func GetDataRetention(c *gin.Context) {
+	dataRetention.mu.Lock()
+	policy := make(map[string]int, len(dataRetention.policy))
+	for name, age := range dataRetention.policy {
+		policy[name] = int(age / (24 * time.Hour))
+	}
+	purged := make(map[string]int64, len(dataRetention.purged))
+	for name, n := range dataRetention.purged {
+		purged[name] = n
+	}
+	response := gin.H{
+		"enabled":       len(dataRetention.policy) > 0,
+		"retentionDays": policy,
+		"intervalHours": int(dataRetention.interval / time.Hour),
+		"dryRun":        dataRetention.dryRun,
+		"running":       dataRetention.running,
+		"purged":        purged,
+		"lastRun":       dataRetention.lastRun,
+	}
+	dataRetention.mu.Unlock()
+
+	held := make(map[string]int64, len(legalHoldModels))
+	for name, model := range legalHoldModels {
+		var n int64
+		database.DB.Model(model()).Where("legal_hold = ?", true).Count(&n)
+		held[name] = n
+	}
+	response["held"] = held
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RunDataRetention runs a retention pass now; ?dryRun=true only counts. A
+// real pass needs a confirmation token (admin).
+// POST /api/admin/retention/run
+func RunDataRetention(c *gin.Context) {
+	dataRetention.mu.Lock()
+	dryRun := dataRetention.dryRun || c.Query("dryRun") == "true"
+	policy := make(map[string]time.Duration, len(dataRetention.policy))
+	for name, age := range dataRetention.policy {
+		policy[name] = age
+	}
+	dataRetention.mu.Unlock()
+
+	if !dryRun && len(policy) > 0 && !requireConfirmation(c, "purge expired data", "retention", func() (gin.H, error) {
+		matched := gin.H{}
+		for name, age := range policy {
+			var n int64
+			if err := expiredRecords(database.DB, retentionClasses[name], time.Now().Add(-age)).Count(&n).Error; err != nil {
+				return nil, err
+			}
+			matched[name] = n
+		}
+		return gin.H{"records": matched}, nil
+	}) {
+		return
+	}
+
+	result, err := purgeExpiredData(dryRun)
+	if errors.Is(err, errDataRetentionDisabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errDataRetentionBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// SetLegalHold places or releases a legal hold on records (admin). Held
+// records are never purged; a held offense session holds its violations.
+// PUT /api/admin/legal-holds
+// Body: {"type": "violations", "ids": [1, 2], "hold": true, "reason": "...", "changedBy": "..."}
+func SetLegalHold(c *gin.Context) {
+	var req struct {
+		Type      string  `json:"type" binding:"required"`
+		IDs       []int64 `json:"ids" binding:"required"`
+		Hold      bool    `json:"hold"`
+		Reason    string  `json:"reason"`
+		ChangedBy string  `json:"changedBy" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	model, ok := legalHoldModels[req.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid type %q, expected events, detections, violations, crowd, alerts or offense_sessions", req.Type)})
+		return
+	}
+	if len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must not be empty"})
+		return
+	}
+	if req.Hold && strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required to place a legal hold"})
+		return
+	}
+
+	var changed []int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Only records whose hold actually changes are updated and audited
+		if err := tx.Model(model()).
+			Where("id IN ? AND legal_hold = ?", req.IDs, !req.Hold).
+			Pluck("id", &changed).Error; err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		if err := tx.Model(model()).Where("id IN ?", changed).Update("legal_hold", req.Hold).Error; err != nil {
+			return err
+		}
+		audits := make([]models.LegalHoldAudit, 0, len(changed))
+		for _, id := range changed {
+			audits = append(audits, models.LegalHoldAudit{
+				RecordType: req.Type,
+				RecordID:   id,
+				Held:       req.Hold,
+				Reason:     req.Reason,
+				ChangedBy:  req.ChangedBy,
+			})
+		}
+		return tx.Create(&audits).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update legal hold"})
+		return
+	}
+
+	verb := "released from"
+	if req.Hold {
+		verb = "placed on"
+	}
+	if len(changed) > 0 {
+		log.Printf("⚖️ [RETENTION] Legal hold %s %d %s by %s: %s", verb, len(changed), req.Type, req.ChangedBy, req.Reason)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"type":    req.Type,
+		"hold":    req.Hold,
+		"changed": changed,
+		"count":   len(changed),
+	})
+}
+
+// GetLegalHoldAudit returns the history of legal holds, newest first (admin)
+// GET /api/admin/legal-holds/audit
+// Query: type, id
+func GetLegalHoldAudit(c *gin.Context) {
+	query := database.DB.Model(&models.LegalHoldAudit{})
+	if recordType := c.Query("type"); recordType != "" {
+		query = query.Where("record_type = ?", recordType)
+	}
+	if id := c.Query("id"); id != "" {
+		recordID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+			return
+		}
+		query = query.Where("record_id = ?", recordID)
+	}
+
+	var entries []models.LegalHoldAudit
+	if err := query.Order("created_at DESC").Limit(200).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB returns a Postgres handle that builds statements without running them
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// balanced reports whether the parentheses of a SQL fragment match up
+func balanced(sql string) bool {
+	depth := 0
+	for _, r := range sql {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+func TestExpiredAndHeldRecordsQueries(t *testing.T) {
+	db := dryRunDB(t)
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+
+	for name, class := range retentionClasses {
+		expired := expiredRecords(db, class, cutoff).Find(class.model()).Statement
+		held := heldRecords(db, class, cutoff).Find(class.model()).Statement
+		expiredSQL, heldSQL := expired.SQL.String(), held.SQL.String()
+
+		if !strings.Contains(expiredSQL, "timestamp < $1 AND legal_hold = $2") || expired.Vars[1] != false {
+			t.Errorf("%s: expired query doesn't skip held records:\n%s %v", name, expiredSQL, expired.Vars)
+		}
+		if !balanced(expiredSQL) || !balanced(heldSQL) {
+			t.Errorf("%s: unbalanced parentheses:\n%s\n%s", name, expiredSQL, heldSQL)
+		}
+		if class.keep == "" {
+			if !strings.Contains(heldSQL, "legal_hold = true") {
+				t.Errorf("%s: held query doesn't count legal holds:\n%s", name, heldSQL)
+			}
+			continue
+		}
+		// What the purge keeps is what it reports as held
+		if !strings.Contains(expiredSQL, "NOT "+class.keep) {
+			t.Errorf("%s: expired query doesn't keep %q:\n%s", name, class.keep, expiredSQL)
+		}
+		if !strings.Contains(heldSQL, "(legal_hold = true OR "+class.keep+")") {
+			t.Errorf("%s: held query doesn't count %q:\n%s", name, class.keep, heldSQL)
+		}
+	}
+}
+
+func TestHeldImageCondition(t *testing.T) {
+	if !balanced(heldImageCondition) {
+		t.Fatalf("unbalanced parentheses:\n%s", heldImageCondition)
+	}
+	// Held violations, violations of held sessions and held detections all
+	// keep their images, including any of a violation's plate images
+	for _, want := range []string{
+		"v.legal_hold OR EXISTS (SELECT 1 FROM offense_sessions s WHERE s.id = v.offense_session_id AND s.legal_hold)",
+		"v.plate_images @> jsonb_build_array(jsonb_build_object('url', stored_images.url))",
+		"d.legal_hold AND stored_images.url IN (d.full_image_url, d.plate_image_url, d.vehicle_image_url)",
+	} {
+		if !strings.Contains(heldImageCondition, want) {
+			t.Errorf("condition is missing %q", want)
+		}
+	}
+
+	sql := dryRunDB(t).Table("stored_images").Where("NOT " + heldImageCondition).Find(&[]map[string]interface{}{}).Statement.SQL.String()
+	if !strings.Contains(sql, "WHERE NOT (EXISTS (") {
+		t.Errorf("condition isn't negated as a whole:\n%s", sql)
+	}
+}
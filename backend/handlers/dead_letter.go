@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// Dead-letter statuses
+const (
+	deadLetterPending     = "pending"     // Waiting for an automatic retry
+	deadLetterFailed      = "failed"      // Out of automatic retries, reprocess manually
+	deadLetterReprocessed = "reprocessed" // Processed successfully on retry
+	deadLetterDiscarded   = "discarded"   // Dropped by an admin
+)
+
+const (
+	defaultDeadLetterRetryInterval    = time.Minute
+	defaultDeadLetterRetryMaxAttempts = 5
+
+	// deadLetterRetryBatch bounds how many events one retry pass reprocesses
+	deadLetterRetryBatch = 50
+)
+
+// errDeadLetterBusy is returned when another reprocessing attempt got there first
+var errDeadLetterBusy = errors.New("event is being reprocessed or was already reprocessed")
+
+// deadLetter holds the retry settings and counters for failed events
+var deadLetter = struct {
+	mu          sync.Mutex
+	enabled     bool
+	interval    time.Duration // 0 = no automatic retries
+	maxAttempts int
+
+	stored      int64 // failed events kept
+	lost        int64 // failed events that couldn't be stored
+	reprocessed int64 // stored events processed on retry
+}{
+	enabled:     true,
+	interval:    defaultDeadLetterRetryInterval,
+	maxAttempts: defaultDeadLetterRetryMaxAttempts,
+}
+
+// InitDeadLetter reads DEAD_LETTER (default on), DEAD_LETTER_RETRY_INTERVAL_SECONDS
+// (default 60, 0 = manual reprocessing only) and DEAD_LETTER_MAX_ATTEMPTS
+// (default 5), starts the retry loop and returns whether the store is enabled
+// and the retry interval
+func InitDeadLetter() (bool, time.Duration) {
+	deadLetter.enabled = os.Getenv("DEAD_LETTER") != "false"
+	deadLetter.interval = defaultDeadLetterRetryInterval
+	if v := os.Getenv("DEAD_LETTER_RETRY_INTERVAL_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			deadLetter.interval = time.Duration(secs) * time.Second
+		}
+	}
+	deadLetter.maxAttempts = defaultDeadLetterRetryMaxAttempts
+	if v := os.Getenv("DEAD_LETTER_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			deadLetter.maxAttempts = n
+		}
+	}
+
+	if deadLetter.enabled && deadLetter.interval > 0 {
+		go func() {
+			ticker := time.NewTicker(deadLetter.interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				retryDeadLetters()
+			}
+		}()
+	}
+
+	return deadLetter.enabled, deadLetter.interval
+}
+
+// deadLetterEvent stores an event whose processing failed. Returns false if
+// the store is disabled or the event couldn't be stored and is lost.
+func deadLetterEvent(event IngestEvent, imageURLs map[string]string, procErr error) bool {
+	if !deadLetter.enabled {
+		return false
+	}
+
+	entry := models.DeadLetterEvent{
+		EventID:   event.ID,
+		WorkerID:  event.WorkerID,
+		DeviceID:  event.DeviceID,
+		EventType: event.Type,
+		Payload:   models.NewJSONB(event),
+		Status:    deadLetterPending,
+		LastError: procErr.Error(),
+	}
+	if len(imageURLs) > 0 {
+		entry.ImageURLs = models.NewJSONB(imageURLs)
+	}
+	if event.Timestamp != nil {
+		entry.ReceivedAt = *event.Timestamp
+	} else {
+		entry.ReceivedAt = time.Now()
+	}
+	if deadLetter.interval > 0 {
+		next := time.Now().Add(deadLetter.interval)
+		entry.NextAttemptAt = &next
+	} else {
+		entry.Status = deadLetterFailed
+	}
+
+	err := database.DB.Create(&entry).Error
+
+	deadLetter.mu.Lock()
+	defer deadLetter.mu.Unlock()
+	if err != nil {
+		deadLetter.lost++
+		log.Printf("❌ [DEAD_LETTER] Event lost - EventID: %s, Type: %s, Processing error: %v, Store error: %v", event.ID, event.Type, procErr, err)
+		return false
+	}
+	deadLetter.stored++
+	log.Printf("📥 [DEAD_LETTER] Event stored - EventID: %s, Type: %s, Device: %s, Error: %v", event.ID, event.Type, event.DeviceID, procErr)
+	return true
+}
+
+// restoreDeadLetter rebuilds the event and its image URLs from a stored entry.
+// The event keeps the time it was originally received.
+func restoreDeadLetter(entry *models.DeadLetterEvent) (IngestEvent, map[string]string, error) {
+	var event IngestEvent
+	payload, err := json.Marshal(entry.Payload)
+	if err == nil {
+		err = json.Unmarshal(payload, &event)
+	}
+	if err != nil {
+		return event, nil, fmt.Errorf("stored payload is unreadable: %w", err)
+	}
+	receivedAt := entry.ReceivedAt
+	event.Timestamp = &receivedAt
+
+	var imageURLs map[string]string
+	if entry.ImageURLs.Data != nil {
+		if raw, err := json.Marshal(entry.ImageURLs); err == nil {
+			json.Unmarshal(raw, &imageURLs)
+		}
+	}
+	return event, imageURLs, nil
+}
+
+// reprocessDeadLetter runs a stored event through processEvent again and
+// records the outcome. Pending events that fail are retried automatically
+// until they run out of attempts; anything else that fails is marked failed.
+func reprocessDeadLetter(entry *models.DeadLetterEvent) error {
+	// Claim the attempt so the retry loop and an admin can't process it twice
+	claim := database.DB.Model(&models.DeadLetterEvent{}).
+		Where("id = ? AND attempts = ? AND status IN ?", entry.ID, entry.Attempts, []string{deadLetterPending, deadLetterFailed, deadLetterDiscarded}).
+		Update("attempts", entry.Attempts+1)
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return errDeadLetterBusy
+	}
+	entry.Attempts++
+
+	event, imageURLs, err := restoreDeadLetter(entry)
+	if err == nil {
+		// Its place in the device's event sequence is long past
+		event.Seq = nil
+		err = processEvent(event, imageURLs)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	if err == nil {
+		entry.Status = deadLetterReprocessed
+		entry.ReprocessedAt = &now
+		entry.NextAttemptAt = nil
+		updates["status"] = entry.Status
+		updates["reprocessed_at"] = now
+		updates["next_attempt_at"] = nil
+
+		deadLetter.mu.Lock()
+		deadLetter.reprocessed++
+		deadLetter.mu.Unlock()
+		log.Printf("♻️ [DEAD_LETTER] Event reprocessed - EventID: %s, Type: %s, Attempt: %d", entry.EventID, entry.EventType, entry.Attempts)
+	} else {
+		entry.LastError = err.Error()
+		updates["last_error"] = entry.LastError
+		if entry.Status == deadLetterPending && entry.Attempts < deadLetter.maxAttempts && deadLetter.interval > 0 {
+			// Back off linearly so a long outage isn't hammered
+			next := now.Add(time.Duration(entry.Attempts+1) * deadLetter.interval)
+			entry.NextAttemptAt = &next
+		} else {
+			entry.Status = deadLetterFailed
+			entry.NextAttemptAt = nil
+		}
+		updates["status"] = entry.Status
+		updates["next_attempt_at"] = entry.NextAttemptAt
+		log.Printf("⚠️ [DEAD_LETTER] Reprocessing failed - EventID: %s, Type: %s, Attempt: %d, Error: %v", entry.EventID, entry.EventType, entry.Attempts, err)
+	}
+
+	if dbErr := database.DB.Model(&models.DeadLetterEvent{}).Where("id = ?", entry.ID).Updates(updates).Error; dbErr != nil {
+		log.Printf("⚠️ [DEAD_LETTER] Failed to update entry %d: %v", entry.ID, dbErr)
+	}
+	return err
+}
+
+// retryDeadLetters reprocesses pending events that are due
+func retryDeadLetters() {
+	var due []models.DeadLetterEvent
+	if err := database.DB.Where("status = ? AND next_attempt_at <= ?", deadLetterPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(deadLetterRetryBatch).
+		Find(&due).Error; err != nil {
+		log.Printf("⚠️ [DEAD_LETTER] Failed to load due events: %v", err)
+		return
+	}
+
+	for i := range due {
+		reprocessDeadLetter(&due[i])
+	}
+}
+
+// deadLetterStats returns the dead-letter backlog and counters
+func deadLetterStats() gin.H {
+	var backlog []struct {
+		Status string
+		Count  int64
+	}
+	database.DB.Model(&models.DeadLetterEvent{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&backlog)
+
+	byStatus := gin.H{}
+	for _, b := range backlog {
+		byStatus[b.Status] = b.Count
+	}
+
+	deadLetter.mu.Lock()
+	defer deadLetter.mu.Unlock()
+	return gin.H{
+		"enabled":       deadLetter.enabled,
+		"byStatus":      byStatus,
+		"stored":        deadLetter.stored,
+		"lost":          deadLetter.lost,
+		"reprocessed":   deadLetter.reprocessed,
+		"retryInterval": deadLetter.interval.String(),
+		"maxAttempts":   deadLetter.maxAttempts,
+	}
+}
+
+// GetDeadLetterEvents lists events whose processing failed (admin)
+// GET /api/admin/events/dead-letter?status=pending&deviceId=..&type=..&limit=50&offset=0
+func GetDeadLetterEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := database.DB.Model(&models.DeadLetterEvent{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if deviceID := c.Query("deviceId"); deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if eventType := c.Query("type"); eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.DeadLetterEvent
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead-letter events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": entries,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+		"stats":  deadLetterStats(),
+	})
+}
+
+// ReprocessDeadLetterEvent runs a failed event through processing again (admin)
+// POST /api/admin/events/dead-letter/:id/reprocess
+func ReprocessDeadLetterEvent(c *gin.Context) {
+	var entry models.DeadLetterEvent
+	if err := database.DB.First(&entry, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead-letter event not found"})
+		return
+	}
+	if entry.Status == deadLetterReprocessed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Event was already reprocessed"})
+		return
+	}
+	if !requireConfirmation(c, "reprocess event", fmt.Sprint(entry.ID), func() (gin.H, error) {
+		return gin.H{
+			"eventId":   entry.EventID,
+			"eventType": entry.EventType,
+			"deviceId":  entry.DeviceID,
+			"attempts":  entry.Attempts,
+			"lastError": entry.LastError,
+		}, nil
+	}) {
+		return
+	}
+
+	err := reprocessDeadLetter(&entry)
+	if errors.Is(err, errDeadLetterBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("Reprocessing failed: %v", err),
+			"event": entry,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "event": entry})
+}
+
+// DiscardDeadLetterEvent marks a failed event as not worth recovering (admin)
+// DELETE /api/admin/events/dead-letter/:id
+func DiscardDeadLetterEvent(c *gin.Context) {
+	var entry models.DeadLetterEvent
+	if err := database.DB.First(&entry, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead-letter event not found"})
+		return
+	}
+	if entry.Status == deadLetterReprocessed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Event was already reprocessed"})
+		return
+	}
+	if !requireConfirmation(c, "discard event", fmt.Sprint(entry.ID), func() (gin.H, error) {
+		return gin.H{
+			"eventId":   entry.EventID,
+			"eventType": entry.EventType,
+			"deviceId":  entry.DeviceID,
+			"attempts":  entry.Attempts,
+			"lastError": entry.LastError,
+		}, nil
+	}) {
+		return
+	}
+
+	result := database.DB.Model(&models.DeadLetterEvent{}).
+		Where("id = ? AND status <> ?", entry.ID, deadLetterReprocessed).
+		Updates(map[string]interface{}{"status": deadLetterDiscarded, "next_attempt_at": nil})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to discard event"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead-letter event not found or already reprocessed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"os"
+
+	"github.com/irisdrone/backend/models"
+)
+
+// detectionLocationStamping controls whether detections record where their
+// device was at ingest
+var detectionLocationStamping = true
+
+// InitDetectionLocation reads DETECTION_LOCATION_STAMPING (default true) and
+// returns whether detections are stamped with their device's location
+func InitDetectionLocation() bool {
+	detectionLocationStamping = os.Getenv("DETECTION_LOCATION_STAMPING") != "false"
+	return detectionLocationStamping
+}
+
+// stampDetectionLocation copies the device's current location onto a
+// detection, so it keeps where it happened if the camera is later moved.
+// Devices without a location (0, 0) leave the detection unstamped.
+func stampDetectionLocation(detection *models.VehicleDetection, device *models.Device) {
+	if !detectionLocationStamping || device == nil || (device.Lat == 0 && device.Lng == 0) {
+		return
+	}
+	lat, lng := device.Lat, device.Lng
+	detection.Lat, detection.Lng = &lat, &lng
+}
+
+// fillDetectionLocation gives detections stored before stamping, or from
+// devices that had no location then, the location of their preloaded device
+func fillDetectionLocation(detections []models.VehicleDetection) {
+	for i := range detections {
+		d := &detections[i]
+		if d.Lat != nil || (d.Device.Lat == 0 && d.Device.Lng == 0) {
+			continue
+		}
+		lat, lng := d.Device.Lat, d.Device.Lng
+		d.Lat, d.Lng = &lat, &lng
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultEvidenceWindow = 10 * time.Minute
+
+// detectionImageEventTypes are the ingest types whose images are plain detections,
+// as opposed to violation evidence
+var detectionImageEventTypes = []string{"anpr", "plate_detected", "vcc", "vehicle_detected"}
+
+// violationImageCondition matches stored images a violation references, as
+// its snapshot or any of its plate images
+const violationImageCondition = `EXISTS (
+	SELECT 1 FROM traffic_violations v
+	WHERE v.full_snapshot_url = stored_images.url OR v.plate_image_url = stored_images.url
+		OR v.plate_images @> jsonb_build_array(jsonb_build_object('url', stored_images.url))
+)`
+
+// detectionRetention controls the fast purge of detection images
+var detectionRetention = struct {
+	maxAge time.Duration // 0 = disabled, detection images only go through quota trimming
+	window time.Duration // a violation this close to a detection with the same plate makes it evidence
+}{
+	window: defaultEvidenceWindow,
+}
+
+// InitDetectionRetention reads DETECTION_IMAGE_RETENTION_HOURS (0 or unset = disabled)
+// and DETECTION_EVIDENCE_WINDOW_MINUTES (default 10), and returns the retention age
+func InitDetectionRetention() time.Duration {
+	detectionRetention.maxAge = 0
+	if v := os.Getenv("DETECTION_IMAGE_RETENTION_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			detectionRetention.maxAge = time.Duration(hours) * time.Hour
+		}
+	}
+	detectionRetention.window = defaultEvidenceWindow
+	if v := os.Getenv("DETECTION_EVIDENCE_WINDOW_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins >= 0 {
+			detectionRetention.window = time.Duration(mins) * time.Minute
+		}
+	}
+	return detectionRetention.maxAge
+}
+
+// purgeableDetectionImages scopes stored_images to detection images older than
+// cutoff that aren't evidence. An image is evidence when a violation references
+// it directly (as its snapshot or any of its plate images), or when it belongs
+// to a detection whose plate was booked for a violation within the evidence
+// window of the detection. Images of held detections are kept too.
+func purgeableDetectionImages(db *gorm.DB, cutoff time.Time, window time.Duration) *gorm.DB {
+	return db.Model(&models.StoredImage{}).
+		Where("event_type IN ? AND created_at < ?", detectionImageEventTypes, cutoff).
+		Where("NOT "+violationImageCondition).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM vehicle_detections d
+			JOIN traffic_violations v ON v.plate_number = d.plate_number
+				AND v.timestamp BETWEEN d.timestamp - ? * INTERVAL '1 second' AND d.timestamp + ? * INTERVAL '1 second'
+			WHERE stored_images.url IN (d.full_image_url, d.plate_image_url, d.vehicle_image_url)
+		)`, int64(window.Seconds()), int64(window.Seconds())).
+		Where("NOT " + heldImageCondition)
+}
+
+// purgeDetectionImages deletes detection images past the retention age, skipping
+// any that turned out to be violation evidence. Devices whose image storage
+// policy sets a retention are purged at that age instead, even when the global
+// retention is disabled.
+func purgeDetectionImages() {
+	overrides := imageRetentionOverrides()
+
+	if detectionRetention.maxAge > 0 {
+		cutoff := time.Now().Add(-detectionRetention.maxAge)
+		excluded := make([]string, 0, len(overrides))
+		for deviceID := range overrides {
+			excluded = append(excluded, deviceID)
+		}
+		removed, freed := purgeImageBatches(func(db *gorm.DB) *gorm.DB {
+			scope := purgeableDetectionImages(db, cutoff, detectionRetention.window)
+			if len(excluded) > 0 {
+				scope = scope.Where("device_id NOT IN ?", excluded)
+			}
+			return scope
+		})
+		if removed > 0 {
+			log.Printf("🧹 [STORAGE] Purged %d detection images older than %s (%d MB freed)",
+				removed, detectionRetention.maxAge, freed/(1024*1024))
+		}
+	}
+
+	for deviceID, age := range overrides {
+		cutoff := time.Now().Add(-age)
+		removed, freed := purgeImageBatches(func(db *gorm.DB) *gorm.DB {
+			return purgeableDetectionImages(db, cutoff, detectionRetention.window).Where("device_id = ?", deviceID)
+		})
+		if removed > 0 {
+			log.Printf("🧹 [STORAGE] Purged %d detection images of %s older than %s (%d MB freed)",
+				removed, deviceID, age, freed/(1024*1024))
+		}
+	}
+}
+
+// purgeImageBatches deletes the images in scope, oldest first, and returns
+// how many were removed and the bytes freed
+func purgeImageBatches(scope func(db *gorm.DB) *gorm.DB) (removed, freed int64) {
+	for {
+		var batch []models.StoredImage
+		if err := scope(database.DB).
+			Order("created_at ASC").
+			Limit(retentionBatchSize).
+			Find(&batch).Error; err != nil {
+			log.Printf("⚠️ [STORAGE] Failed to load expired detection images: %v", err)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]int64, 0, len(batch))
+		for _, img := range batch {
+			if err := removeStoredImage(img); err != nil {
+				log.Printf("⚠️ [STORAGE] Failed to delete %s: %v", img.URL, err)
+				continue
+			}
+			ids = append(ids, img.ID)
+			freed += img.Bytes
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := database.DB.Delete(&models.StoredImage{}, ids).Error; err != nil {
+			log.Printf("⚠️ [STORAGE] Failed to remove detection image records: %v", err)
+			break
+		}
+		removed += int64(len(ids))
+		if len(batch) < retentionBatchSize {
+			break
+		}
+	}
+	return removed, freed
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestInitDetectionRetention(t *testing.T) {
+	tests := []struct {
+		hours, minutes string
+		maxAge, window time.Duration
+	}{
+		{hours: "", minutes: "", maxAge: 0, window: defaultEvidenceWindow},
+		{hours: "48", minutes: "", maxAge: 48 * time.Hour, window: defaultEvidenceWindow},
+		{hours: "0", minutes: "30", maxAge: 0, window: 30 * time.Minute},
+		{hours: "-5", minutes: "0", maxAge: 0, window: 0},
+		{hours: "soon", minutes: "-1", maxAge: 0, window: defaultEvidenceWindow},
+	}
+	saved := detectionRetention
+	t.Cleanup(func() { detectionRetention = saved })
+
+	for _, tt := range tests {
+		t.Setenv("DETECTION_IMAGE_RETENTION_HOURS", tt.hours)
+		t.Setenv("DETECTION_EVIDENCE_WINDOW_MINUTES", tt.minutes)
+		if got := InitDetectionRetention(); got != tt.maxAge {
+			t.Errorf("hours=%q: retention = %s, want %s", tt.hours, got, tt.maxAge)
+		}
+		if detectionRetention.window != tt.window {
+			t.Errorf("minutes=%q: window = %s, want %s", tt.minutes, detectionRetention.window, tt.window)
+		}
+	}
+}
+
+func TestPurgeableDetectionImagesQuery(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	stmt := purgeableDetectionImages(db, cutoff, 15*time.Minute).Find(&[]models.StoredImage{}).Statement
+	sql := stmt.SQL.String()
+
+	// Violation evidence, plate linkage within the window, and holds are all excluded
+	for _, want := range []string{
+		"NOT " + violationImageCondition,
+		"JOIN traffic_violations v ON v.plate_number = d.plate_number",
+		"NOT " + heldImageCondition,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("query is missing %q:\n%s", want, sql)
+		}
+	}
+	windows := 0
+	for _, v := range stmt.Vars {
+		if v == int64(15*60) {
+			windows++
+		}
+	}
+	if windows != 2 {
+		t.Errorf("query binds the evidence window %d times, want 2 (vars %v)", windows, stmt.Vars)
+	}
+}
+
+// TestPurgeableDetectionImagesKeepsEvidence runs against TEST_DATABASE_URL,
+// migrating it, and rolls back what it writes
+func TestPurgeableDetectionImagesKeepsEvidence(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	t.Setenv("DATABASE_URL", url)
+	if err := database.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	tx := database.DB.Begin()
+	defer tx.Rollback()
+
+	const deviceID = "retention-test-cam"
+	if err := tx.Create(&models.Device{ID: deviceID, Type: models.DeviceTypeCamera}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	seen := now.Add(-48 * time.Hour)
+	str := func(s string) *string { return &s }
+	image := func(url string, createdAt time.Time) {
+		t.Helper()
+		img := models.StoredImage{DeviceID: deviceID, EventType: "anpr", Path: "/tmp/" + url, URL: url, CreatedAt: createdAt}
+		if err := tx.Create(&img).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	detection := func(plate, url string) {
+		t.Helper()
+		d := models.VehicleDetection{DeviceID: deviceID, Timestamp: seen, PlateNumber: str(plate), FullImageURL: str(url), Analytic: "anpr"}
+		if err := tx.Create(&d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	violation := func(v models.TrafficViolation) {
+		t.Helper()
+		v.DeviceID = deviceID
+		if err := tx.Create(&v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing ties this detection to a violation
+	image("plain.jpg", seen)
+	detection("KA01RT0001", "plain.jpg")
+
+	// Referenced by a violation as its snapshot, or as one of its plate images
+	image("snapshot.jpg", seen)
+	violation(models.TrafficViolation{Timestamp: seen, FullSnapshotURL: str("snapshot.jpg")})
+	image("plate.jpg", seen)
+	violation(models.TrafficViolation{Timestamp: seen, PlateImages: models.NewJSONB([]models.PlateImage{{URL: "plate.jpg"}})})
+
+	// Same plate booked within the window, and outside it
+	image("linked.jpg", seen)
+	detection("KA01RT0002", "linked.jpg")
+	violation(models.TrafficViolation{Timestamp: seen.Add(5 * time.Minute), PlateNumber: str("KA01RT0002")})
+	image("unlinked.jpg", seen)
+	detection("KA01RT0003", "unlinked.jpg")
+	violation(models.TrafficViolation{Timestamp: seen.Add(30 * time.Minute), PlateNumber: str("KA01RT0003")})
+
+	// Not old enough yet
+	image("recent.jpg", now)
+
+	var urls []string
+	if err := purgeableDetectionImages(tx, now.Add(-24*time.Hour), 10*time.Minute).
+		Where("device_id = ?", deviceID).
+		Pluck("url", &urls).Error; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(urls)
+	if want := []string{"plain.jpg", "unlinked.jpg"}; strings.Join(urls, ",") != strings.Join(want, ",") {
+		t.Errorf("purgeable = %v, want %v", urls, want)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// commissioningTestEventType is the event type analytics events from devices
+// held for commissioning are stored under, instead of the stats tables
+const commissioningTestEventType = "commissioning_test"
+
+// deviceCommissioning controls which devices are held out of live stats
+var deviceCommissioning = struct {
+	required bool // discovered and newly seen devices must be commissioned too
+}{}
+
+// InitDeviceCommissioning reads DEVICE_COMMISSIONING. With "required", devices
+// first seen at ingest start as discovered and discovered devices are held out
+// of live stats until commissioned. Devices in the commissioning state are
+// always held. Returns whether commissioning is required.
+func InitDeviceCommissioning() bool {
+	deviceCommissioning.required = os.Getenv("DEVICE_COMMISSIONING") == "required"
+	return deviceCommissioning.required
+}
+
+// newDeviceStatus is the status of a device first seen at ingest
+func newDeviceStatus() string {
+	if deviceCommissioning.required {
+		return models.DeviceStatusDiscovered
+	}
+	return models.DeviceStatusActive
+}
+
+// deviceHeldForCommissioning reports whether a device's analytics events are
+// kept out of live stats
+func deviceHeldForCommissioning(status string) bool {
+	return status == models.DeviceStatusCommissioning ||
+		(deviceCommissioning.required && status == models.DeviceStatusDiscovered)
+}
+
+// processCommissioningTestEvent stores an analytics event from a device that
+// isn't commissioned yet as a test event, so it counts as proof the pipeline
+// works without feeding detections, violations or crowd stats
+func processCommissioningTestEvent(db *gorm.DB, event IngestEvent, imageURLs map[string]string) error {
+	data := make(map[string]interface{}, len(event.Data)+2)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data["eventType"] = event.Type
+	if len(imageURLs) > 0 {
+		data["images"] = imageURLs
+	}
+
+	log.Printf("🧪 [COMMISSIONING] Test event from device %s (type: %s)", event.DeviceID, event.Type)
+	return db.Create(&models.Event{
+		DeviceID:  event.DeviceID,
+		Timestamp: *event.Timestamp,
+		Type:      commissioningTestEventType,
+		Data:      models.NewJSONB(data),
+	}).Error
+}
+
+// commissioningChecklist is what a device must pass before it goes live
+type commissioningChecklist struct {
+	LocationSet       bool       `json:"locationSet"`
+	AnalyticsAssigned bool       `json:"analyticsAssigned"`
+	TestEventPassed   bool       `json:"testEventPassed"`
+	LastTestEventAt   *time.Time `json:"lastTestEventAt,omitempty"`
+}
+
+// complete reports whether every check passed
+func (c commissioningChecklist) complete() bool {
+	return c.LocationSet && c.AnalyticsAssigned && c.TestEventPassed
+}
+
+// checkCommissioning evaluates the commissioning checklist of a device
+func checkCommissioning(device *models.Device) commissioningChecklist {
+	var checklist commissioningChecklist
+	checklist.LocationSet = device.Lat != 0 || device.Lng != 0
+
+	var assignments []models.WorkerCameraAssignment
+	database.DB.Where("device_id = ? AND is_active = true", device.ID).Find(&assignments)
+	for _, a := range assignments {
+		if len(jsonbStrings(a.Analytics)) > 0 {
+			checklist.AnalyticsAssigned = true
+			break
+		}
+	}
+
+	if device.CommissioningStartedAt != nil {
+		var event models.Event
+		err := database.DB.Select("timestamp").
+			Where("device_id = ? AND type = ? AND timestamp >= ?", device.ID, commissioningTestEventType, *device.CommissioningStartedAt).
+			Order("timestamp DESC").
+			First(&event).Error
+		if err == nil {
+			checklist.TestEventPassed = true
+			checklist.LastTestEventAt = &event.Timestamp
+		}
+	}
+	return checklist
+}
+
+// findCommissioningDevice loads the device named in the URL
+func findCommissioningDevice(c *gin.Context) (*models.Device, bool) {
+	var device models.Device
+	if err := database.DB.First(&device, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return nil, false
+	}
+	return &device, true
+}
+
+// commissioningResponse is the commissioning state of a device
+func commissioningResponse(device *models.Device) gin.H {
+	checklist := checkCommissioning(device)
+	return gin.H{
+		"deviceId":               device.ID,
+		"status":                 device.Status,
+		"heldFromStats":          deviceHeldForCommissioning(device.Status),
+		"commissioningStartedAt": device.CommissioningStartedAt,
+		"commissionedAt":         device.CommissionedAt,
+		"checklist":              checklist,
+		"readyToComplete":        device.Status == models.DeviceStatusCommissioning && checklist.complete(),
+	}
+}
+
+// GetDeviceCommissioning handles GET /api/devices/:id/commissioning - Show a
+// device's commissioning state and checklist
+func GetDeviceCommissioning(c *gin.Context) {
+	device, ok := findCommissioningDevice(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, commissioningResponse(device))
+}
+
+// StartDeviceCommissioning handles POST /api/devices/:id/commissioning/start -
+// Move a device into commissioning. Live devices can be re-commissioned, which
+// takes them out of live stats until they pass again.
+func StartDeviceCommissioning(c *gin.Context) {
+	device, ok := findCommissioningDevice(c)
+	if !ok {
+		return
+	}
+	if device.Status == models.DeviceStatusCommissioning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Device is already being commissioned"})
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(device).Updates(map[string]interface{}{
+		"status":                   models.DeviceStatusCommissioning,
+		"commissioning_started_at": now,
+		"commissioned_at":          nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start commissioning"})
+		return
+	}
+	device.Status = models.DeviceStatusCommissioning
+	device.CommissioningStartedAt = &now
+	device.CommissionedAt = nil
+
+	log.Printf("🧪 [COMMISSIONING] Started for device %s", device.ID)
+	c.JSON(http.StatusOK, commissioningResponse(device))
+}
+
+// SetDeviceLocationRequest - Place a device on the map
+type SetDeviceLocationRequest struct {
+	Lat             *float64 `json:"lat" binding:"required"`
+	Lng             *float64 `json:"lng" binding:"required"`
+	ZoneID          *string  `json:"zoneId"`
+	AllowNullIsland bool     `json:"allowNullIsland"` // Accept 0,0, which otherwise means the location is missing
+}
+
+// SetDeviceCommissioningLocation handles PUT /api/devices/:id/commissioning/location -
+// Set where a device being commissioned is placed. Devices already within a
+// few meters are returned as nearbyDevices, as a warning; the location is
+// still saved.
+func SetDeviceCommissioningLocation(c *gin.Context) {
+	device, ok := findCommissioningDevice(c)
+	if !ok {
+		return
+	}
+
+	var req SetDeviceLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lat, lng := *req.Lat, *req.Lng
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lat must be within ±90 and lng within ±180"})
+		return
+	}
+	if lat == 0 && lng == 0 && !req.AllowNullIsland {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "0,0 is almost always a missing location; set allowNullIsland to use it"})
+		return
+	}
+
+	updates := map[string]interface{}{"lat": lat, "lng": lng}
+	if req.ZoneID != nil {
+		updates["zone_id"] = *req.ZoneID
+	}
+	if err := database.DB.Model(device).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set device location"})
+		return
+	}
+	device.Lat, device.Lng = lat, lng
+
+	response := commissioningResponse(device)
+	if nearby := devicesNear(device.ID, lat, lng); len(nearby) > 0 {
+		log.Printf("⚠️ [COMMISSIONING] Device %s placed within %.0fm of %d other device(s)", device.ID, duplicateLocationMeters, len(nearby))
+		response["nearbyDevices"] = nearby
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteDeviceCommissioning handles POST /api/devices/:id/commissioning/complete -
+// Put a device live once its checklist passes
+func CompleteDeviceCommissioning(c *gin.Context) {
+	device, ok := findCommissioningDevice(c)
+	if !ok {
+		return
+	}
+	if device.Status != models.DeviceStatusCommissioning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Device is not being commissioned"})
+		return
+	}
+
+	checklist := checkCommissioning(device)
+	if !checklist.complete() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Commissioning checklist is incomplete",
+			"checklist": checklist,
+		})
+		return
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&models.Device{}).
+		Where("id = ? AND status = ?", device.ID, models.DeviceStatusCommissioning).
+		Updates(map[string]interface{}{
+			"status":          models.DeviceStatusActive,
+			"commissioned_at": now,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete commissioning"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Device status changed concurrently"})
+		return
+	}
+	device.Status = models.DeviceStatusActive
+	device.CommissionedAt = &now
+
+	log.Printf("✅ [COMMISSIONING] Device %s commissioned and live", device.ID)
+	c.JSON(http.StatusOK, commissioningResponse(device))
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+)
+
+// deviceEventFeedTypes are the event types pushed to a device event feed
+// unless the client asks for others: detections, violations and alerts
+func deviceEventFeedTypes() []string {
+	types := []string{"alert"}
+	for t := range detectionEventTypes {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Device feed views: what a client watching a device's events may see
+const (
+	deviceFeedSignedImages = "images" // signed image URLs rather than image paths
+	deviceFeedFullPlates   = "plates" // plate numbers unmasked
+)
+
+// deviceFeedView is the view of a device feed client, decided when it connects
+func deviceFeedView(c *gin.Context) string {
+	var view []string
+	if listImagesSigned(c) {
+		view = append(view, deviceFeedSignedImages)
+	}
+	if feedPlatesVisible(c) {
+		view = append(view, deviceFeedFullPlates)
+	}
+	return strings.Join(view, ",")
+}
+
+// publishDeviceEvent pushes an ingested event to the clients watching its
+// device. Clients allowed to view images get signed URLs for its images, and
+// only clients allowed to see plates get the plate unmasked.
+func publishDeviceEvent(event IngestEvent, imageURLs map[string]string) {
+	if feedHub == nil || !feedHub.DeviceWatched(event.DeviceID) {
+		return
+	}
+	commissioning := event.Device != nil && deviceHeldForCommissioning(event.Device.Status)
+	feedHub.BroadcastDeviceEvent(event.DeviceID, event.Type, func(view string) interface{} {
+		images := make(map[string]string, len(imageURLs))
+		for name, url := range imageURLs {
+			if strings.Contains(view, deviceFeedSignedImages) {
+				url = signedImageURL(url)
+			}
+			images[name] = url
+		}
+		data := event.Data
+		if plate, ok := data["plate_number"].(string); ok && !strings.Contains(view, deviceFeedFullPlates) {
+			data = make(map[string]interface{}, len(event.Data))
+			for k, v := range event.Data {
+				data[k] = v
+			}
+			data["plate_number"] = maskPlate(plate)
+		}
+		return gin.H{
+			"id":            event.ID,
+			"type":          event.Type,
+			"workerId":      event.WorkerID,
+			"timestamp":     event.Timestamp,
+			"data":          data,
+			"images":        images,
+			"commissioning": commissioning, // stored as a test event only
+		}
+	})
+}
+
+// HandleDeviceEventsWebSocket streams one device's events as they're
+// ingested, so an operator commissioning a camera can watch its analytics work.
+// Each event arrives as {"type":"event","camera":"<device id>","data":{...}}.
+// GET /ws/devices/:id/events
+// Query: types (comma-separated event types, or "all"; default detections,
+// violations and alerts)
+func HandleDeviceEventsWebSocket(c *gin.Context) {
+	if feedHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Feed hub not initialized"})
+		return
+	}
+
+	deviceID := c.Param("id")
+	var device models.Device
+	if err := database.DB.Select("id").First(&device, "id = ?", deviceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	types := deviceEventFeedTypes()
+	if v := c.Query("types"); v == "all" {
+		types = nil
+	} else if v != "" {
+		types = strings.Split(v, ",")
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("⚠️ WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		userID = "anonymous"
+	}
+
+	client := services.NewFeedClient(feedHub, conn, userID, c.ClientIP())
+	client.WatchDevice(deviceID, types, deviceFeedView(c))
+	feedHub.Register(client)
+
+	go client.WritePump()
+	go client.ReadPump()
+}
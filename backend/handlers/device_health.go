@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// recentHealthEvents is how many device_health events GetDeviceHealth lists
+const recentHealthEvents = 10
+
+// GetDeviceHealth handles GET /api/devices/:id/health - Ingest health of a
+// device: when it last sent an event, detections dropped by the rate cap,
+// the integrity of its event ordering and its recent health events
+func GetDeviceHealth(c *gin.Context) {
+	var device models.Device
+	if err := database.DB.Select("id, status, worker_id, last_event_at").
+		First(&device, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	var events []models.Event
+	if err := database.DB.Where("device_id = ? AND type = ?", device.ID, "device_health").
+		Order("timestamp DESC").Limit(recentHealthEvents).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch health events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deviceId":     device.ID,
+		"status":       device.Status,
+		"workerId":     device.WorkerID,
+		"lastEventAt":  device.LastEventAt,
+		"rateCap":      ingestLimiter.deviceStats(device.ID),
+		"ordering":     eventOrdering.deviceStats(device.ID), // null if the device sends no sequence numbers
+		"healthEvents": events,
+	})
+}
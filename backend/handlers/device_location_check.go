@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const (
+	defaultDuplicateLocationMeters = 5.0
+
+	earthRadiusMeters = 6371000.0
+	// metersPerDegreeLat is the length of a degree of latitude, used to size
+	// the bounding box prefilter
+	metersPerDegreeLat = 111320.0
+)
+
+// duplicateLocationMeters is how close two devices may be placed before a
+// location change warns about the other; 0 turns the check off
+var duplicateLocationMeters = defaultDuplicateLocationMeters
+
+// InitDeviceLocationCheck reads DEVICE_DUPLICATE_LOCATION_METERS (default 5,
+// 0 = off) and returns the distance
+func InitDeviceLocationCheck() float64 {
+	duplicateLocationMeters = defaultDuplicateLocationMeters
+	if v := os.Getenv("DEVICE_DUPLICATE_LOCATION_METERS"); v != "" {
+		if meters, err := strconv.ParseFloat(v, 64); err == nil && meters >= 0 {
+			duplicateLocationMeters = meters
+		}
+	}
+	return duplicateLocationMeters
+}
+
+// haversineMeters is the great-circle distance between two points
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+// devicesNear returns the other devices within duplicateLocationMeters of a
+// location, closest first. Overlapping devices stack their map markers, so
+// placing one on top of another is usually a copy-paste mistake. Devices
+// without a location sit at 0,0, so that point isn't checked.
+func devicesNear(deviceID string, lat, lng float64) []gin.H {
+	if duplicateLocationMeters == 0 || (lat == 0 && lng == 0) {
+		return nil
+	}
+
+	// Narrow to a bounding box in SQL, then measure the candidates
+	dLat := duplicateLocationMeters / metersPerDegreeLat
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	var candidates []models.Device
+	if err := database.DB.Select("id, name, lat, lng, status").
+		Where("id <> ? AND lat BETWEEN ? AND ? AND lng BETWEEN ? AND ?", deviceID, lat-dLat, lat+dLat, lng-dLng, lng+dLng).
+		Find(&candidates).Error; err != nil {
+		log.Printf("⚠️ [DEVICE_LOCATION] Failed to check for nearby devices: %v", err)
+		return nil
+	}
+
+	nearby := make([]gin.H, 0)
+	for _, d := range candidates {
+		distance := haversineMeters(lat, lng, d.Lat, d.Lng)
+		if distance > duplicateLocationMeters {
+			continue
+		}
+		entry := gin.H{
+			"deviceId":       d.ID,
+			"lat":            d.Lat,
+			"lng":            d.Lng,
+			"status":         d.Status,
+			"distanceMeters": math.Round(distance*10) / 10,
+		}
+		if d.Name != nil {
+			entry["name"] = *d.Name
+		}
+		nearby = append(nearby, entry)
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i]["distanceMeters"].(float64) < nearby[j]["distanceMeters"].(float64)
+	})
+	return nearby
+}
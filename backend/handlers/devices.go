@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/irisdrone/backend/database"
@@ -9,6 +10,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// deviceOnlineWindow is how recent last_event_at must be for a device to count as online
+const deviceOnlineWindow = 5 * time.Minute
+
+// isDeviceOnline reports whether a device has sent an event within deviceOnlineWindow
+func isDeviceOnline(lastEventAt *time.Time) bool {
+	return lastEventAt != nil && time.Since(*lastEventAt) < deviceOnlineWindow
+}
+
 // GetDevices handles GET /api/devices
 func GetDevices(c *gin.Context) {
 	var devices []models.Device
@@ -24,10 +33,25 @@ func GetDevices(c *gin.Context) {
 		query = query.Where("zone_id = ?", zoneID)
 	}
 
+	// Filter by site
+	if siteID := c.Query("site"); siteID != "" {
+		query = query.Where("site_id = ?", siteID)
+	}
+
+	// Filter by recency (uses the indexed last_event_at column)
+	if online := c.Query("online"); online != "" {
+		cutoff := time.Now().Add(-deviceOnlineWindow)
+		if online == "true" {
+			query = query.Where("last_event_at >= ?", cutoff)
+		} else {
+			query = query.Where("last_event_at IS NULL OR last_event_at < ?", cutoff)
+		}
+	}
+
 	// Minimal mode - return only essential fields
 	if minimal := c.Query("minimal"); minimal == "true" {
 		var devices []models.Device
-		if err := query.Select("id, name, type, lat, lng, status").
+		if err := query.Select("id, name, type, lat, lng, status, last_event_at").
 			Order("id ASC").
 			Find(&devices).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
@@ -36,22 +60,26 @@ func GetDevices(c *gin.Context) {
 
 		// Return slim response
 		type MinimalDevice struct {
-			ID     string            `json:"id"`
-			Name   *string           `json:"name"`
-			Type   models.DeviceType `json:"type"`
-			Lat    float64           `json:"lat"`
-			Lng    float64           `json:"lng"`
-			Status string            `json:"status"`
+			ID          string            `json:"id"`
+			Name        *string           `json:"name"`
+			Type        models.DeviceType `json:"type"`
+			Lat         float64           `json:"lat"`
+			Lng         float64           `json:"lng"`
+			Status      string            `json:"status"`
+			LastEventAt *time.Time        `json:"lastEventAt"`
+			Online      bool              `json:"online"`
 		}
 		result := make([]MinimalDevice, len(devices))
 		for i, d := range devices {
 			result[i] = MinimalDevice{
-				ID:     d.ID,
-				Name:   d.Name,
-				Type:   d.Type,
-				Lat:    d.Lat,
-				Lng:    d.Lng,
-				Status: d.Status,
+				ID:          d.ID,
+				Name:        d.Name,
+				Type:        d.Type,
+				Lat:         d.Lat,
+				Lng:         d.Lng,
+				Status:      d.Status,
+				LastEventAt: d.LastEventAt,
+				Online:      isDeviceOnline(d.LastEventAt),
 			}
 		}
 		c.JSON(http.StatusOK, result)
@@ -59,9 +87,11 @@ func GetDevices(c *gin.Context) {
 	}
 
 	// Full mode - include latest event
-	if err := query.Preload("Events", func(db *gorm.DB) *gorm.DB {
-		return db.Order("timestamp DESC").Limit(1)
-	}).Order("id ASC").Find(&devices).Error; err != nil {
+	if err := query.Order("id ASC").Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+	if err := attachLatestEvents(devices); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
 		return
 	}
@@ -69,6 +99,35 @@ func GetDevices(c *gin.Context) {
 	c.JSON(http.StatusOK, devices)
 }
 
+// attachLatestEvents loads the latest event of each device as its only event.
+// A preload can't do this: its limit applies to all devices' events together.
+func attachLatestEvents(devices []models.Device) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	ids := make([]string, len(devices))
+	for i, d := range devices {
+		ids[i] = d.ID
+	}
+
+	var events []models.Event
+	if err := database.DB.Raw(
+		`SELECT DISTINCT ON (device_id) * FROM events WHERE device_id IN ? ORDER BY device_id, timestamp DESC`,
+		ids).Scan(&events).Error; err != nil {
+		return err
+	}
+	latest := make(map[string]models.Event, len(events))
+	for _, e := range events {
+		latest[e.DeviceID] = e
+	}
+	for i := range devices {
+		if e, ok := latest[devices[i].ID]; ok {
+			devices[i].Events = []models.Event{e}
+		}
+	}
+	return nil
+}
+
 // GetDeviceLatest handles GET /api/devices/:id/latest
 func GetDeviceLatest(c *gin.Context) {
 	deviceID := c.Param("id")
@@ -122,4 +181,3 @@ func GetDeviceSurges(c *gin.Context) {
 
 	c.JSON(http.StatusOK, results)
 }
-
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// eventFiltersConfigKey is the worker config key holding its event filter rules
+const eventFiltersConfigKey = "eventFilters"
+
+// maxEventFilterRules caps the rules of one worker
+const maxEventFilterRules = 50
+
+// Event filter actions
+const (
+	EventFilterForward = "forward"
+	EventFilterDrop    = "drop"
+	EventFilterSample  = "sample"
+)
+
+// filterableEventTypes are the event types a filter rule can name
+var filterableEventTypes = map[string]bool{
+	"anpr": true, "plate_detected": true, "violation": true, "vcc": true,
+	"vehicle_detected": true, "crowd": true, "crowd_density": true, "alert": true,
+}
+
+// filterableVehicleTypes are the vehicle types a filter rule can name, as
+// edges report them in vehicle_type
+var filterableVehicleTypes = map[string]bool{"2W": true, "4W": true, "AUTO": true, "HMV": true, "BUS": true}
+
+// EventFilterRule - One rule a worker applies before queuing an event. A
+// worker checks its rules in order and the first one matching an event
+// decides what happens to it; events matching none are forwarded.
+type EventFilterRule struct {
+	Types        []string `json:"types,omitempty"`        // event types; empty = any
+	Devices      []string `json:"devices,omitempty"`      // camera device IDs; empty = any
+	VehicleTypes []string `json:"vehicleTypes,omitempty"` // vehicle_type of the event; empty = any
+	Action       string   `json:"action"`                 // forward, drop or sample
+	SampleEvery  int      `json:"sampleEvery,omitempty"`  // sample: forward 1 in this many matching events
+}
+
+// validateEventFilterRules checks a rule list, normalizing names
+func validateEventFilterRules(rules []EventFilterRule) error {
+	if len(rules) > maxEventFilterRules {
+		return fmt.Errorf("at most %d rules per worker", maxEventFilterRules)
+	}
+	for i := range rules {
+		rule := &rules[i]
+		for j, t := range rule.Types {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if !filterableEventTypes[t] {
+				return fmt.Errorf("rule %d: unknown event type %q", i+1, t)
+			}
+			rule.Types[j] = t
+		}
+		for j, v := range rule.VehicleTypes {
+			v = strings.ToUpper(strings.TrimSpace(v))
+			if !filterableVehicleTypes[v] {
+				return fmt.Errorf("rule %d: vehicle type must be one of 2W, 4W, AUTO, HMV or BUS", i+1)
+			}
+			rule.VehicleTypes[j] = v
+		}
+		for j, d := range rule.Devices {
+			if rule.Devices[j] = strings.TrimSpace(d); rule.Devices[j] == "" {
+				return fmt.Errorf("rule %d: empty device ID", i+1)
+			}
+		}
+
+		rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+		switch rule.Action {
+		case EventFilterForward, EventFilterDrop:
+			if rule.SampleEvery != 0 {
+				return fmt.Errorf("rule %d: sampleEvery only applies to the sample action", i+1)
+			}
+		case EventFilterSample:
+			if rule.SampleEvery < 2 {
+				return fmt.Errorf("rule %d: sampleEvery must be at least 2", i+1)
+			}
+		default:
+			return fmt.Errorf("rule %d: action must be forward, drop or sample", i+1)
+		}
+	}
+	return nil
+}
+
+// workerEventFilters reads the event filter rules from a worker's config
+func workerEventFilters(worker *models.Worker) []EventFilterRule {
+	rules := []EventFilterRule{}
+	cfg, ok := worker.Config.Data.(map[string]interface{})
+	if !ok || cfg[eventFiltersConfigKey] == nil {
+		return rules
+	}
+	raw, err := json.Marshal(cfg[eventFiltersConfigKey])
+	if err != nil || json.Unmarshal(raw, &rules) != nil {
+		log.Printf("⚠️ [WORKER] Ignoring malformed event filters of worker %s", worker.ID)
+		return []EventFilterRule{}
+	}
+	return rules
+}
+
+// GetWorkerEventFilters returns the event filter rules pushed to a worker (admin)
+// GET /api/admin/workers/:id/event-filters
+func GetWorkerEventFilters(c *gin.Context) {
+	var worker models.Worker
+	if err := database.DB.Select("id, config").First(&worker, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"workerId": worker.ID, "rules": workerEventFilters(&worker)})
+}
+
+// SetWorkerEventFilters replaces the event filter rules of a worker and bumps
+// its config version so it picks them up; an empty list forwards everything (admin)
+// PUT /api/admin/workers/:id/event-filters
+func SetWorkerEventFilters(c *gin.Context) {
+	var req struct {
+		Rules []EventFilterRule `json:"rules"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Rules == nil {
+		req.Rules = []EventFilterRule{}
+	}
+	if err := validateEventFilterRules(req.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workerID := c.Param("id")
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var worker models.Worker
+		if err := tx.Select("id, config").First(&worker, "id = ?", workerID).Error; err != nil {
+			return err
+		}
+		cfg, ok := worker.Config.Data.(map[string]interface{})
+		if !ok {
+			cfg = map[string]interface{}{}
+		}
+		if len(req.Rules) == 0 {
+			delete(cfg, eventFiltersConfigKey)
+		} else {
+			cfg[eventFiltersConfigKey] = req.Rules
+		}
+		if err := tx.Model(&worker).Update("config", models.NewJSONB(cfg)).Error; err != nil {
+			return err
+		}
+		return bumpConfigVersion(tx, workerID)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save event filters"})
+		return
+	}
+
+	log.Printf("📝 [WORKER] Event filters of worker %s set to %d rules", workerID, len(req.Rules))
+	c.JSON(http.StatusOK, gin.H{"workerId": workerID, "rules": req.Rules})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +23,16 @@ import (
 // IngestEvent represents an event from edge worker
 type IngestEvent struct {
 	ID        string                 `json:"id"`
-	TimestampRaw string              `json:"timestamp,omitempty"` // Ignored - we use current time
+	TimestampRaw string              `json:"timestamp,omitempty"` // Edge time; only used to judge how late an event is, records use current time
 	Timestamp *time.Time             `json:"-"` // Set by normalizeEvent, not from JSON
 	WorkerID  string                 `json:"worker_id"`
 	DeviceID  string                 `json:"device_id"`
 	Type      string                 `json:"type"` // anpr, violation, vcc, crowd, alert
 	Data      map[string]interface{} `json:"data"`
 	Images    []string               `json:"images,omitempty"` // Image filenames
+	Seq       *int64                 `json:"seq,omitempty"`       // Optional per-device sequence number
+	SeqEpoch  int64                  `json:"seq_epoch,omitempty"` // Run of the edge Seq counts within; a newer run restarts it
+	Device    *models.Device         `json:"-"` // Set by processEvent
 }
 
 // normalizeEvent sets the timestamp to current time and ensures required fields
@@ -62,7 +67,7 @@ func getOrCreateDevice(deviceID string, workerID string) (*models.Device, error)
 		ID:       deviceID,
 		Type:     models.DeviceTypeCamera,
 		Name:     &name,
-		Status:   "active",
+		Status:   newDeviceStatus(),
 		WorkerID: &workerID,
 	}
 	
@@ -73,6 +78,42 @@ func getOrCreateDevice(deviceID string, workerID string) (*models.Device, error)
     return &device, nil
 }
 
+// lastEventAtResolution limits how often last_event_at is rewritten for a busy device
+const lastEventAtResolution = 5 * time.Second
+
+// touchDeviceLastEvent advances the device's last_event_at. UpdateColumn skips
+// updated_at so this doesn't look like a config change.
+func touchDeviceLastEvent(device *models.Device, ts time.Time) {
+	if device.LastEventAt != nil && ts.Sub(*device.LastEventAt) < lastEventAtResolution {
+		return
+	}
+
+	if err := database.DB.Model(&models.Device{}).Where("id = ?", device.ID).
+		UpdateColumn("last_event_at", ts).Error; err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Failed to update last_event_at - Device: %s, Error: %v", device.ID, err)
+		return
+	}
+	device.LastEventAt = &ts
+}
+
+// maxDecompressedIngestBytes caps a gzip-encoded ingest body once inflated
+const maxDecompressedIngestBytes = 64 << 20
+
+// decompressIngestBody transparently inflates a gzip Content-Encoding body
+func decompressIngestBody(c *gin.Context) error {
+	if !strings.EqualFold(c.GetHeader("Content-Encoding"), "gzip") {
+		return nil
+	}
+	gz, err := gzip.NewReader(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, gz, maxDecompressedIngestBytes)
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.ContentLength = -1
+	return nil
+}
+
 // IngestEventsRequest - Batch event ingest
 type IngestEventsRequest struct {
 	Events []IngestEvent `json:"events"`
@@ -85,7 +126,7 @@ type IngestEventsRequest struct {
 }
 
 // IngestEvents handles event ingestion from edge workers
-// POST /api/events/ingest (multipart form or JSON)
+// POST /api/events/ingest (multipart form, JSON or NDJSON)
 func IngestEvents(c *gin.Context) {
 	// Log incoming request
 	startTime := time.Now()
@@ -111,7 +152,7 @@ func IngestEvents(c *gin.Context) {
 		headerInfo += fmt.Sprintf(", ContentLength: %d", contentLength)
 	}
 	
-	log.Printf("📥 [EVENT_INGEST] Request received - IP: %s, WorkerID: %s, %s", 
+	ingestDebugf("📥 [EVENT_INGEST] Request received - IP: %s, WorkerID: %s, %s", 
 		clientIP, workerID, headerInfo)
 
 	// Validate worker if headers provided
@@ -121,7 +162,7 @@ func IngestEvents(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid worker"})
 			return
 		}
-		if worker.AuthToken != authToken {
+		if !workerTokenValid(&worker, authToken) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 			return
 		}
@@ -131,6 +172,19 @@ func IngestEvents(c *gin.Context) {
 		}
 	}
 
+	// Batched uploads from MagicBox are gzip-compressed
+	if err := decompressIngestBody(c); err != nil {
+		log.Printf("❌ [EVENT_INGEST] Invalid gzip body - IP: %s, WorkerID: %s, Error: %v", clientIP, workerID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid gzip body"})
+		return
+	}
+
+	// High-volume pipelines stream one event per line
+	if isNDJSON(contentType) {
+		ingestNDJSON(c, workerID, startTime)
+		return
+	}
+
 	// Try JSON parsing if content type is JSON or empty (might be JSON without proper header)
 	if contentType == "application/json" || contentType == "" {
 		// JSON batch ingest (no images)
@@ -150,7 +204,7 @@ func IngestEvents(c *gin.Context) {
 		} else {
 			// Successfully parsed as JSON
 			if contentType == "" {
-				log.Printf("ℹ️ [EVENT_INGEST] Detected JSON content (ContentType was empty) - IP: %s, WorkerID: %s", 
+				ingestDebugf("ℹ️ [EVENT_INGEST] Detected JSON content (ContentType was empty) - IP: %s, WorkerID: %s", 
 					clientIP, workerID)
 			}
 			
@@ -165,30 +219,61 @@ func IngestEvents(c *gin.Context) {
 			for _, event := range events {
 				eventTypes[event.Type]++
 			}
-			log.Printf("📦 [EVENT_INGEST] Batch request - WorkerID: %s, Total: %d, Types: %v", 
+			ingestDebugf("📦 [EVENT_INGEST] Batch request - WorkerID: %s, Total: %d, Types: %v", 
 				workerID, len(events), eventTypes)
 		
+			orderBatch(events)
+
 			processed := 0
+			dropped := 0
+			deadLettered := 0
+			failedIDs := []string{}  // Failed and not dead-lettered; the worker resends these
+			droppedIDs := []string{} // Over the rate cap; the worker resends these later
 			for i := range events {
 				// Normalize event (set timestamp to current time)
 				normalizeEvent(&events[i])
 				
-				if err := processEvent(events[i], nil); err != nil {
-					log.Printf("⚠️ [EVENT_INGEST] Failed to process event - WorkerID: %s, EventID: %s, Type: %s, Error: %v", 
+				// Per-device detections-per-second safety valve
+				if !ingestLimiter.allow(events[i]) {
+					recordIngestDropped(events[i].Type)
+					eventOrdering.pass(events[i])
+					dropped++
+					droppedIDs = append(droppedIDs, events[i].ID)
+					continue
+				}
+				
+				err := processEvent(events[i], nil)
+				recordIngestResult(events[i].Type, err)
+				if err != nil {
+					ingestDebugf("⚠️ [EVENT_INGEST] Failed to process event - WorkerID: %s, EventID: %s, Type: %s, Error: %v", 
 						workerID, events[i].ID, events[i].Type, err)
+					if deadLetterEvent(events[i], nil, err) {
+						deadLettered++
+					} else {
+						failedIDs = append(failedIDs, events[i].ID)
+					}
 					continue
 				}
 				processed++
 			}
 		
 			duration := time.Since(startTime)
-			log.Printf("✅ [EVENT_INGEST] Batch processed - WorkerID: %s, Processed: %d/%d, Duration: %v", 
-				workerID, processed, len(events), duration)
+			// Per-event failures are in the debug log; the batch line is enough otherwise
+			logBatch := ingestDebugf
+			if processed+dropped < len(events) {
+				logBatch = log.Printf
+			}
+			logBatch("✅ [EVENT_INGEST] Batch processed - WorkerID: %s, Processed: %d/%d, Dropped: %d, Dead-lettered: %d, Duration: %v", 
+				workerID, processed, len(events), dropped, deadLettered, duration)
 			
 			c.JSON(http.StatusOK, gin.H{
-				"status":    "ok",
-				"processed": processed,
-				"total":     len(events),
+				"status":       "ok",
+				"processed":    processed,
+				"dropped":      dropped,
+				"deadLettered": deadLettered,
+				"failedIds":    failedIDs,
+				"droppedIds":   droppedIDs,
+				"total":        len(events),
 			})
 			return
 		}
@@ -238,9 +323,22 @@ func IngestEvents(c *gin.Context) {
 	normalizeEvent(&event)
 	
 	// Log multipart request details
-	log.Printf("📤 [EVENT_INGEST] Multipart request - WorkerID: %s, EventID: %s, Type: %s, DeviceID: %s", 
+	ingestDebugf("📤 [EVENT_INGEST] Multipart request - WorkerID: %s, EventID: %s, Type: %s, DeviceID: %s", 
 		workerID, event.ID, event.Type, event.DeviceID)
 
+	// Refuse before saving images if the device is over its detection rate cap.
+	// 429 leaves the event queued on the edge, which resends it after a backoff.
+	if !ingestLimiter.allow(event) {
+		recordIngestDropped(event.Type)
+		eventOrdering.pass(event)
+		c.Header("Retry-After", throttleRetryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":    "Detection rate cap exceeded",
+			"event_id": event.ID,
+		})
+		return
+	}
+
 	// Handle uploaded images
 	// Parse multipart form if not already parsed (max 32MB)
 	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
@@ -257,13 +355,23 @@ func IngestEvents(c *gin.Context) {
 		for key := range form.File {
 			fileKeys = append(fileKeys, key)
 		}
-		log.Printf("📎 [EVENT_INGEST] Multipart files found - Keys: %v", fileKeys)
-		
+		ingestDebugf("📎 [EVENT_INGEST] Multipart files found - Keys: %v", fileKeys)
+
+		// Devices whose policy keeps no detection images upload them for nothing
+		storeImages := storesEventImages(event)
+		if !storeImages {
+			log.Printf("🚫 [EVENT_INGEST] Images not stored by device policy - Device: %s, EventID: %s, Type: %s",
+				event.DeviceID, event.ID, event.Type)
+		}
+
 		for key, files := range form.File {
-			if key == "event" {
+			if key == "event" || !storeImages {
 				continue
 			}
-			for _, file := range files {
+			for i, file := range files {
+				// Several files under one field are kept apart (plate.jpg, plate_2.jpg, ...)
+				key := imageKey(key, i)
+
 				// Save image
 				src, err := file.Open()
 				if err != nil {
@@ -272,49 +380,32 @@ func IngestEvents(c *gin.Context) {
 					continue
 				}
 
+				// Transcode to the device's archive format if the edge sent something else
+				archived, filename, err := archiveImage(src, file.Filename, event.DeviceID)
+
 				// Generate storage path
-				storagePath := generateImagePath(event.WorkerID, event.DeviceID, event.Type, file.Filename)
-				
-				// Ensure directory exists
-				dir := filepath.Dir(storagePath)
-				if err := os.MkdirAll(dir, 0755); err != nil {
-					log.Printf("⚠️ [EVENT_INGEST] Failed to create directory - Path: %s, Error: %v", dir, err)
-					src.Close()
-					continue
-				}
-				
+				storagePath := generateImagePath(event.WorkerID, event.DeviceID, event.Type, filename)
+				url := uploadURL(storagePath)
+
 				// Save file
-				dst, err := os.Create(storagePath)
-				if err != nil {
-					log.Printf("⚠️ [EVENT_INGEST] Failed to create file - Path: %s, Error: %v", storagePath, err)
-					src.Close()
-					continue
-				}
-				
-				if _, err := io.Copy(dst, src); err != nil {
-					log.Printf("⚠️ [EVENT_INGEST] Failed to copy file - Path: %s, Error: %v", storagePath, err)
-					src.Close()
-					dst.Close()
-					continue
+				var written int64
+				if err == nil {
+					written, err = writeImageFile(storagePath, archived)
 				}
-
 				src.Close()
-				dst.Close()
-
-				// Generate URL - need to get relative path from base directory
-				baseDir := getUploadBaseDir()
-				
-				// Get relative path from base directory
-				relPath, err := filepath.Rel(baseDir, storagePath)
 				if err != nil {
-					// Fallback to just filename if relative path fails
-					relPath = filepath.Base(storagePath)
+					log.Printf("⚠️ [EVENT_INGEST] Failed to save file - Path: %s, Error: %v", storagePath, err)
+					// Keep the evidence: the event links to the planned URL and
+					// the image is written there once storage recovers
+					if spoolImage(event, key, file, storagePath, url, err) {
+						imageURLs[key] = url
+					}
+					continue
 				}
-				
-				// Convert to forward slashes for URL (Windows compatibility)
-				relPath = filepath.ToSlash(relPath)
-				imageURLs[key] = "/uploads/" + relPath
-				log.Printf("💾 [EVENT_INGEST] Image saved - Key: %s, Path: %s, URL: %s", 
+
+				imageURLs[key] = url
+				recordStoredImage(event, storagePath, imageURLs[key], written)
+				ingestDebugf("💾 [EVENT_INGEST] Image saved - Key: %s, Path: %s, URL: %s", 
 					key, storagePath, imageURLs[key])
 			}
 		}
@@ -334,7 +425,7 @@ func IngestEvents(c *gin.Context) {
 		// Just save images and return URLs, don't process the event
 		duration := time.Since(startTime)
 		imageCount := len(imageURLs)
-		log.Printf("📤 [EVENT_INGEST] Image upload only - WorkerID: %s, EventID: %s, Images: %d, Duration: %v", 
+		ingestDebugf("📤 [EVENT_INGEST] Image upload only - WorkerID: %s, EventID: %s, Images: %d, Duration: %v", 
 			workerID, event.ID, imageCount, duration)
 		
 		c.JSON(http.StatusOK, gin.H{
@@ -346,17 +437,29 @@ func IngestEvents(c *gin.Context) {
 	}
 	
 	// Process the event
-	if err := processEvent(event, imageURLs); err != nil {
+	err := processEvent(event, imageURLs)
+	recordIngestResult(event.Type, err)
+	if err != nil {
 		duration := time.Since(startTime)
 		log.Printf("❌ [EVENT_INGEST] Processing failed - WorkerID: %s, EventID: %s, Type: %s, Error: %v, Duration: %v", 
 			workerID, event.ID, event.Type, err, duration)
+		// Once stored for reprocessing the edge mustn't resend it, or the
+		// event would be recorded twice
+		if deadLetterEvent(event, imageURLs, err) {
+			c.JSON(http.StatusAccepted, gin.H{
+				"status":   "dead_lettered",
+				"event_id": event.ID,
+				"images":   imageURLs,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	duration := time.Since(startTime)
 	imageCount := len(imageURLs)
-	log.Printf("✅ [EVENT_INGEST] Event processed - WorkerID: %s, EventID: %s, Type: %s, Images: %d, Duration: %v", 
+	ingestDebugf("✅ [EVENT_INGEST] Event processed - WorkerID: %s, EventID: %s, Type: %s, Images: %d, Duration: %v", 
 		workerID, event.ID, event.Type, imageCount, duration)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -367,40 +470,108 @@ func IngestEvents(c *gin.Context) {
 }
 
 // processEvent processes a single event based on type
-func processEvent(event IngestEvent, imageURLs map[string]string) error {
+func processEvent(event IngestEvent, imageURLs map[string]string) (err error) {
 	// Ensure device exists before processing event
 	device, err := getOrCreateDevice(event.DeviceID, event.WorkerID)
 	if err != nil {
 		return fmt.Errorf("failed to ensure device exists: %w", err)
 	}
 
+	touchDeviceLastEvent(device, *event.Timestamp)
+	event.Device = device
+
+	// Out-of-order delivery is flagged on the event, or waited out in reorder
+	// mode: an event ahead of its device's sequence is processed later, once
+	// the ones before it have been
+	late, held, done := eventOrdering.admit(event, imageURLs)
+	if held {
+		return nil
+	}
+	defer done()
+	return processSequencedEvent(event, imageURLs, late)
+}
+
+// eventTx is the transaction an event's records are written in. Work that
+// reaches beyond them, such as alerts and pushes to clients, is queued with
+// afterCommit, so an event that fails leaves nothing behind and can be
+// reprocessed from the dead-letter store without duplicating anything.
+type eventTx struct {
+	*gorm.DB
+	committed []func()
+}
+
+// afterCommit queues work to run once the event's records are committed
+func (tx *eventTx) afterCommit(f func()) {
+	tx.committed = append(tx.committed, f)
+}
+
+// processSequencedEvent processes an event once its turn in its device's
+// sequence has come. All of the event's writes are made in one transaction.
+func processSequencedEvent(event IngestEvent, imageURLs map[string]string, late bool) error {
+	if late {
+		if event.Data == nil {
+			event.Data = map[string]interface{}{}
+		}
+		event.Data["out_of_order"] = true
+	}
+
+	var committed []func()
+	err := database.DB.Transaction(func(db *gorm.DB) error {
+		tx := &eventTx{DB: db}
+		if err := storeEvent(tx, event, imageURLs); err != nil {
+			return err
+		}
+		committed = tx.committed
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range committed {
+		f()
+	}
+
+	// Operators watching the device see each event once it's stored
+	publishDeviceEvent(event, imageURLs)
+	return nil
+}
+
+// storeEvent writes an event's records based on its type
+func storeEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
     // Opportunistically update device details if present in event data
     // This handles cases where metadata is sent with generic events, not just camera_status
     if event.Data != nil {
-        updateDeviceFromEventData(device, event.Data)
+        if err := updateDeviceFromEventData(tx.DB, event.Device, event.Data); err != nil {
+            return fmt.Errorf("failed to update device: %w", err)
+        }
     }
 	
+	// Devices that aren't commissioned yet only produce test events
+	if event.Type != "camera_status" && deviceHeldForCommissioning(event.Device.Status) {
+		return processCommissioningTestEvent(tx.DB, event, imageURLs)
+	}
+	
 	switch event.Type {
 	case "camera_status":
-		return processCameraStatusEvent(event, imageURLs)
+		return processCameraStatusEvent(tx, event, imageURLs)
 	case "anpr", "plate_detected":
-		return processANPREvent(event, imageURLs)
+		return processANPREvent(tx, event, imageURLs)
 	case "violation":
-		return processViolationEvent(event, imageURLs)
+		return processViolationEvent(tx, event, imageURLs)
 	case "vcc", "vehicle_detected":
-		return processVCCEvent(event, imageURLs)
+		return processVCCEvent(tx, event, imageURLs)
 	case "crowd", "crowd_density":
-		return processCrowdEvent(event, imageURLs)
+		return processCrowdEvent(tx, event, imageURLs)
 	case "alert":
-		return processAlertEvent(event, imageURLs)
+		return processAlertEvent(tx, event, imageURLs)
 	default:
 		// Store as generic event
-		return processGenericEvent(event, imageURLs)
+		return processGenericEvent(tx, event, imageURLs)
 	}
 }
 
 // updateDeviceFromEventData updates device metadata if specific fields are present
-func updateDeviceFromEventData(device *models.Device, data map[string]interface{}) {
+func updateDeviceFromEventData(db *gorm.DB, device *models.Device, data map[string]interface{}) error {
     cameraName, _ := data["camera_name"].(string)
 	location, _ := data["location"].(string)
     
@@ -434,13 +605,26 @@ func updateDeviceFromEventData(device *models.Device, data map[string]interface{
     
     if shouldSave {
         // Log that we are opportunistic updating
-        log.Printf("ℹ️ [EVENT_INGEST] Updating device metadata from event - ID: %s", device.ID)
-        database.DB.Save(device)
+        ingestDebugf("ℹ️ [EVENT_INGEST] Updating device metadata from event - ID: %s", device.ID)
+        return db.Save(device).Error
     }
+    return nil
+}
+
+// cameraStatusDedup skips saving camera_status events that change nothing
+var cameraStatusDedup = true
+
+// InitCameraStatusDedup reads CAMERA_STATUS_DEDUP (default true) and returns
+// whether unchanged camera status reports are skipped
+func InitCameraStatusDedup() bool {
+	cameraStatusDedup = os.Getenv("CAMERA_STATUS_DEDUP") != "false"
+	return cameraStatusDedup
 }
 
-// processCameraStatusEvent handles camera registration/status events
-func processCameraStatusEvent(event IngestEvent, imageURLs map[string]string) error {
+// processCameraStatusEvent handles camera registration/status events. Cameras
+// report status often; unless dedup is off, the device is only saved when the
+// report changes it, so updated_at keeps meaning a real change.
+func processCameraStatusEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	data := event.Data
 	
 	status, _ := data["status"].(string)
@@ -451,19 +635,26 @@ func processCameraStatusEvent(event IngestEvent, imageURLs map[string]string) er
 	// New fields - handled by opportunistic update as well, but we keep explicit logic here for status/URLs
 	// Find device - verified to exist
 	var device models.Device
-	if err := database.DB.First(&device, "id = ?", event.DeviceID).Error; err != nil {
+	if err := tx.First(&device, "id = ?", event.DeviceID).Error; err != nil {
 		return fmt.Errorf("device not found: %w", err)
 	}
 	
-	// Update fields
-	if status == "online" {
-		device.Status = "active" // Normalize status
-	} else if status != "" {
-		device.Status = status
+	shouldSave := !cameraStatusDedup
+	
+	// Update fields. Status reports don't take a device out of commissioning.
+	if !deviceHeldForCommissioning(device.Status) {
+		if status == "online" {
+			status = "active" // Normalize status
+		}
+		if status != "" && device.Status != status {
+			device.Status = status
+			shouldSave = true
+		}
 	}
 	
-	if rtspURL != "" {
+	if rtspURL != "" && (device.RTSPUrl == nil || *device.RTSPUrl != rtspURL) {
 		device.RTSPUrl = &rtspURL
+		shouldSave = true
 	}
 	
 	// Update metadata with extra URLs
@@ -479,29 +670,38 @@ func processCameraStatusEvent(event IngestEvent, imageURLs map[string]string) er
 		metaMap = make(map[string]interface{})
 	}
 	
-	if hlsURL != "" {
+	if cur, _ := metaMap["hls_stream_url"].(string); hlsURL != "" && cur != hlsURL {
 		metaMap["hls_stream_url"] = hlsURL
+		shouldSave = true
 	}
-	if originalRTSP != "" {
+	if cur, _ := metaMap["original_rtsp_url"].(string); originalRTSP != "" && cur != originalRTSP {
 		metaMap["original_rtsp_url"] = originalRTSP
+		shouldSave = true
 	}
     // Location is handled by updateDeviceFromEventData, but if camera_status sends it, 
     // we want to ensure it's set (updateDeviceFromEventData does this too)
 	
 	// Update last seen
-	device.WorkerID = &event.WorkerID
+	if device.WorkerID == nil || *device.WorkerID != event.WorkerID {
+		device.WorkerID = &event.WorkerID
+		shouldSave = true
+	}
 	
+	if !shouldSave {
+		return nil
+	}
 	device.Metadata = models.NewJSONB(metaMap)
 	
-	return database.DB.Save(&device).Error
+	return tx.Save(&device).Error
 }
 
 // processANPREvent handles ANPR/plate detection events
-func processANPREvent(event IngestEvent, imageURLs map[string]string) error {
+func processANPREvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	data := event.Data
 	
 	// Extract plate info
 	plateNumber, _ := data["plate_number"].(string)
+	plateNumber = NormalizePlate(plateNumber, event.DeviceID)
 	plateConfidence, _ := data["plate_confidence"].(float64)
 	vehicleTypeStr, _ := data["vehicle_type"].(string)
 	vehicleTypeStr = strings.ToUpper(strings.TrimSpace(vehicleTypeStr))
@@ -509,6 +709,9 @@ func processANPREvent(event IngestEvent, imageURLs map[string]string) error {
 	model, _ := data["model"].(string)
 	color, _ := data["color"].(string)
 	
+	// Edges that only send the full frame may still give a plate box
+	cropPlateFromFrame(event, imageURLs)
+	
 	// Determine vehicle type
 	vehicleType := models.VehicleTypeUnknown
 	switch vehicleTypeStr {
@@ -523,45 +726,46 @@ func processANPREvent(event IngestEvent, imageURLs map[string]string) error {
 	case "BUS", "bus":
 		vehicleType = models.VehicleTypeBus
 	}
+	if vehicleType == models.VehicleTypeUnknown && (make != "" || model != "") {
+		vehicleType = inferVehicleType(make, model)
+	}
 
-	// Find or create vehicle if plate detected
-	var vehicleID *int64
-	if plateNumber != "" {
-		var vehicle models.Vehicle
-		err := database.DB.Where("plate_number = ?", plateNumber).First(&vehicle).Error
-		if err != nil {
-			// Create new vehicle
-			now := time.Now()
-			vehicle = models.Vehicle{
-				PlateNumber:    &plateNumber,
-				VehicleType:    vehicleType,
-				FirstSeen:      now,
-				LastSeen:       now,
-				DetectionCount: 1,
+	// Dedup by edge track ID (falling back to plate) so a vehicle seen across
+	// several frames is only counted once
+	trackID := trackIDFromData(data)
+	if existing, dup := findDuplicateDetection("anpr", event.DeviceID, trackID, plateNumber, *event.Timestamp); dup {
+		// Keep the best plate read for the track
+		if plateNumber != "" && (existing.PlateConfidence == nil || plateConfidence > *existing.PlateConfidence) {
+			updates := map[string]interface{}{
+				"plate_number":     plateNumber,
+				"plate_confidence": plateConfidence,
+				"plate_detected":   true,
 			}
-			if make != "" {
-				vehicle.Make = &make
+			if url, ok := imageURLs["plate.jpg"]; ok {
+				updates["plate_image_url"] = url
 			}
-			if model != "" {
-				vehicle.Model = &model
-			}
-			if color != "" {
-				vehicle.Color = &color
+			// A track first seen without a readable plate (or with a misread
+			// one) is linked, and checked against the watchlist, once it's read
+			samePlate := existing.PlateNumber != nil && *existing.PlateNumber == plateNumber
+			if existing.VehicleID == nil || !samePlate {
+				vehicleID, err := linkDetectedVehicle(tx, event, plateNumber, plateConfidence, vehicleType, make, model, color, existing.VehicleID)
+				if err != nil {
+					return err
+				}
+				updates["vehicle_id"] = vehicleID
 			}
-			database.DB.Create(&vehicle)
-		} else {
-			// Update existing
-			vehicle.LastSeen = time.Now()
-			vehicle.DetectionCount++
-			database.DB.Save(&vehicle)
+			return tx.Model(existing).Updates(updates).Error
 		}
-		vehicleID = &vehicle.ID
-		
-		// Check watchlist
-		var watchlist models.Watchlist
-		if err := database.DB.Where("vehicle_id = ? AND is_active = true", vehicle.ID).First(&watchlist).Error; err == nil {
-			// Watchlist match! Create alert
-			// TODO: Send notification
+		ingestDebugf("ℹ️ [EVENT_INGEST] Duplicate ANPR detection skipped - Device: %s, Track: %s, Plate: %s", event.DeviceID, trackID, logPlate(plateNumber))
+		return nil
+	}
+
+	// Find or create vehicle if plate detected
+	var vehicleID *int64
+	if plateNumber != "" {
+		var err error
+		if vehicleID, err = linkDetectedVehicle(tx, event, plateNumber, plateConfidence, vehicleType, make, model, color, nil); err != nil {
+			return err
 		}
 	}
 
@@ -574,8 +778,14 @@ func processANPREvent(event IngestEvent, imageURLs map[string]string) error {
 		VehicleType:     vehicleType,
 		PlateDetected:   plateNumber != "",
 		MakeModelDetected: make != "" || model != "",
+		SchemaVersion:   schemaVersion("anpr"),
+		Analytic:        "anpr",
 	}
 	
+	if trackID != "" {
+		detection.TrackID = &trackID
+	}
+	stampDetectionLocation(&detection, event.Device)
 	if plateConfidence > 0 {
 		detection.PlateConfidence = &plateConfidence
 	}
@@ -600,18 +810,99 @@ func processANPREvent(event IngestEvent, imageURLs map[string]string) error {
 		detection.VehicleImageURL = &url
 	}
 
-	return database.DB.Create(&detection).Error
+	if err := tx.Create(&detection).Error; err != nil {
+		return err
+	}
+	storeDetectionEmbedding(tx.DB, &detection, data)
+	tx.afterCommit(func() { invalidateStatsCache(StatsCacheRealtime) })
+	return nil
+}
+
+// linkDetectedVehicle finds or creates the vehicle of a plate read by ANPR,
+// counts the sighting and checks it against the watchlist once the event is
+// committed, returning the vehicle's ID. linkedTo is the vehicle a detection
+// being relinked was counted for: the sighting moves from it to the new
+// vehicle, and isn't counted again if the vehicle is the same.
+func linkDetectedVehicle(tx *eventTx, event IngestEvent, plateNumber string, plateConfidence float64, vehicleType models.VehicleType, make, model, color string, linkedTo *int64) (*int64, error) {
+	reportedRegion, _ := event.Data["plate_region"].(string)
+	region := plateRegion(plateNumber, reportedRegion, event.Device)
+	var vehicle models.Vehicle
+	err := vehicleByPlate(tx.DB, plateNumber, region).First(&vehicle).Error
+	if err != nil {
+		// Create new vehicle
+		now := time.Now()
+		vehicle = models.Vehicle{
+			PlateNumber:    &plateNumber,
+			PlateRegion:    region,
+			VehicleType:    vehicleType,
+			FirstSeen:      now,
+			LastSeen:       now,
+			DetectionCount: 1,
+		}
+		if make != "" {
+			vehicle.Make = &make
+		}
+		if model != "" {
+			vehicle.Model = &model
+		}
+		if color != "" {
+			vehicle.Color = &color
+		}
+		if err := tx.Create(&vehicle).Error; err != nil {
+			return nil, fmt.Errorf("failed to create vehicle: %w", err)
+		}
+	} else {
+		// Update existing
+		vehicle.LastSeen = time.Now()
+		if linkedTo == nil || *linkedTo != vehicle.ID {
+			vehicle.DetectionCount++
+		}
+		if vehicle.VehicleType == models.VehicleTypeUnknown || vehicle.VehicleType == "" {
+			vehicle.VehicleType = vehicleType
+		}
+		if err := tx.Save(&vehicle).Error; err != nil {
+			return nil, fmt.Errorf("failed to update vehicle: %w", err)
+		}
+	}
+	if linkedTo != nil && *linkedTo != vehicle.ID {
+		if err := tx.Model(&models.Vehicle{}).Where("id = ? AND detection_count > 0", *linkedTo).
+			UpdateColumn("detection_count", gorm.Expr("detection_count - 1")).Error; err != nil {
+			return nil, fmt.Errorf("failed to uncount previous vehicle: %w", err)
+		}
+	}
+
+	// Check watchlist
+	if watchlist, ok := activeWatchlistEntry(vehicle.ID); ok {
+		// Noisy OCR shouldn't raise alarms; low-confidence reads need corroborating
+		if confirmed, reason := watchlistHitConfirmed(watchlist, plateConfidence, *event.Timestamp); confirmed {
+			tx.afterCommit(func() {
+				if watchlist.AlertOnDetection {
+					raiseWatchlistAlert(watchlist, plateNumber, event.DeviceID, "detection", *event.Timestamp)
+				}
+				evaluateGeofences(watchlist, plateNumber, event.Device, *event.Timestamp)
+			})
+		} else {
+			log.Printf("🔇 [WATCHLIST] Hit on %s by %s suppressed: %s", logPlate(plateNumber), event.DeviceID, reason)
+		}
+	}
+	return &vehicle.ID, nil
 }
 
 // processViolationEvent handles traffic violation events
-func processViolationEvent(event IngestEvent, imageURLs map[string]string) error {
+func processViolationEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	data := event.Data
 	
 	// Extract violation info
 	violationTypeStr, _ := data["violation_type"].(string)
 	plateNumber, _ := data["plate_number"].(string)
+	plateNumber = NormalizePlate(plateNumber, event.DeviceID)
 	speed, _ := data["speed"].(float64)
 	speedLimit, _ := data["speed_limit"].(float64)
+	confidence, _ := data["confidence"].(float64)
+	plateConfidence, _ := data["plate_confidence"].(float64)
+	
+	// Edges that only send the full frame may still give a plate box
+	cropPlateFromFrame(event, imageURLs)
 	
 	// Map violation type
 	violationType := models.ViolationOther
@@ -628,11 +919,40 @@ func processViolationEvent(event IngestEvent, imageURLs map[string]string) error
 		violationType = models.ViolationNoSeatbelt
 	}
 
+	// Speed limits and expected direction configured on the device's zone
+	// override what the edge reports. A vehicle the zone doesn't find in
+	// violation is still counted, as a plain detection.
+	var zone *models.ZoneEnforcement
+	var zoneLimit *float64
+	if violationType == models.ViolationSpeed || violationType == models.ViolationWrongSide {
+		if zone = deviceZoneEnforcement(event.DeviceID); zone != nil {
+			switch violationType {
+			case models.ViolationSpeed:
+				vehicleTypeStr, _ := data["vehicle_type"].(string)
+				if zoneLimit = zoneSpeedLimit(zone, models.VehicleType(strings.ToUpper(vehicleTypeStr))); zoneLimit != nil && speed > 0 && speed <= *zoneLimit {
+					log.Printf("ℹ️ [EVENT_INGEST] No speed violation from %s: %.0f km/h is within zone %s limit %.0f, storing as a detection", event.DeviceID, speed, zone.ZoneID, *zoneLimit)
+					return processVCCEvent(tx, event, imageURLs)
+				}
+			case models.ViolationWrongSide:
+				if zone.ExpectedDirection != nil {
+					direction, _ := data["direction"].(string)
+					if strings.EqualFold(direction, *zone.ExpectedDirection) {
+						log.Printf("ℹ️ [EVENT_INGEST] No wrong-way violation from %s: %s is zone %s's expected direction, storing as a detection", event.DeviceID, direction, zone.ZoneID)
+						data["wrong"] = false
+						return processVCCEvent(tx, event, imageURLs)
+					}
+					data["expected_direction"] = *zone.ExpectedDirection
+				}
+			}
+		}
+	}
+
 	// Find vehicle by plate
 	var vehicleID *int64
 	if plateNumber != "" {
+		reportedRegion, _ := data["plate_region"].(string)
 		var vehicle models.Vehicle
-		if err := database.DB.Where("plate_number = ?", plateNumber).First(&vehicle).Error; err == nil {
+		if err := vehicleByPlate(tx.DB, plateNumber, plateRegion(plateNumber, reportedRegion, event.Device)).First(&vehicle).Error; err == nil {
 			vehicleID = &vehicle.ID
 		}
 	}
@@ -644,6 +964,7 @@ func processViolationEvent(event IngestEvent, imageURLs map[string]string) error
 		ViolationType:   violationType,
 		Status:          models.ViolationPending,
 		DetectionMethod: models.DetectionAIVision,
+		SchemaVersion:   schemaVersion("violation"),
 	}
 	
 	if plateNumber != "" {
@@ -652,31 +973,91 @@ func processViolationEvent(event IngestEvent, imageURLs map[string]string) error
 	if speed > 0 {
 		violation.DetectedSpeed = &speed
 	}
-	if speedLimit > 0 {
+	if zoneLimit != nil {
+		violation.SpeedLimit2W = zone.SpeedLimit2W
+		violation.SpeedLimit4W = zone.SpeedLimit4W
+		if speed > 0 {
+			over := speed - *zoneLimit
+			violation.SpeedOverLimit = &over
+		}
+	} else if speedLimit > 0 {
 		violation.SpeedLimit4W = &speedLimit
 	}
+	if confidence > 0 {
+		violation.Confidence = &confidence
+	}
+	if plateConfidence > 0 {
+		violation.PlateConfidence = &plateConfidence
+	}
+	
+	// Violations below the type's confidence threshold are kept but flagged
+	if isLowConfidence(violationType, confidence) {
+		violation.LowConfidence = true
+	}
+
+	// High-confidence violations matching an auto-approve rule skip manual review
+	if !violation.LowConfidence && shouldAutoApprove(violationType, confidence, plateNumber, plateConfidence) {
+		now := time.Now()
+		reviewer := autoApproveReviewer
+		violation.Status = models.ViolationApproved
+		violation.AutoApproved = true
+		violation.ReviewedAt = &now
+		violation.ReviewedBy = &reviewer
+		log.Printf("✅ [EVENT_INGEST] Auto-approved %s violation - Device: %s, Plate: %s", violationType, event.DeviceID, logPlate(plateNumber))
+	}
 	
 	// Add image URLs
 	if url, ok := imageURLs["frame.jpg"]; ok {
 		violation.FullSnapshotURL = &url
 	}
-	if url, ok := imageURLs["plate.jpg"]; ok {
-		violation.PlateImageURL = &url
+	if images := collectPlateImages(imageURLs, data); len(images) > 0 {
+		violation.PlateImageURL = &images[0].URL
+		violation.PlateImages = models.NewJSONB(images)
 	}
 	
 	// Store additional data as metadata
 	violation.Metadata = models.NewJSONB(data)
+	stampViolationBBox(&violation)
 
-	return database.DB.Create(&violation).Error
+	if err := tx.Create(&violation).Error; err != nil {
+		return err
+	}
+	groupOffenseSession(tx.DB, &violation)
+
+	// Radar-only and similar violations arrive without an image; grab a live
+	// frame in the background so ingest isn't held up by the worker round trip
+	if violation.FullSnapshotURL == nil && violationFrameCapture.enabled && event.WorkerID != "" {
+		tx.afterCommit(func() {
+			go captureViolationFrame(violation.ID, event.WorkerID, event.DeviceID, eventOccurredAt(event))
+		})
+	}
+
+	if vehicleID != nil {
+		if watchlist, ok := activeWatchlistEntry(*vehicleID); ok && watchlist.AlertOnViolation {
+			tx.afterCommit(func() {
+				raiseWatchlistAlert(watchlist, plateNumber, event.DeviceID, string(violationType)+" violation", *event.Timestamp)
+			})
+		}
+	}
+	return nil
 }
 
 // processVCCEvent handles vehicle counting events
-func processVCCEvent(event IngestEvent, imageURLs map[string]string) error {
+func processVCCEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	data := event.Data
 	
 	vehicleTypeStr, _ := data["vehicle_type"].(string)
 	vehicleTypeStr = strings.ToUpper(strings.TrimSpace(vehicleTypeStr))
 	confidence, _ := data["confidence"].(float64)
+	plateNumber, _ := data["plate_number"].(string)
+	plateNumber = NormalizePlate(plateNumber, event.DeviceID)
+	
+	// Skip detections of a vehicle already counted on this device
+	trackID := trackIDFromData(data)
+	if _, dup := findDuplicateDetection("vcc", event.DeviceID, trackID, plateNumber, *event.Timestamp); dup {
+		ingestDebugf("ℹ️ [EVENT_INGEST] Duplicate VCC detection skipped - Device: %s, Track: %s", event.DeviceID, trackID)
+		return nil
+	}
 	
 	vehicleType := models.VehicleTypeUnknown
 	switch vehicleTypeStr {
@@ -696,8 +1077,15 @@ func processVCCEvent(event IngestEvent, imageURLs map[string]string) error {
 		DeviceID:    event.DeviceID,
 		Timestamp:   *event.Timestamp,
 		VehicleType: vehicleType,
-		Metadata:    models.NewJSONB(data),
+		Metadata:    models.NewJSONB(withoutEmbedding(data)),
+		SchemaVersion: schemaVersion("vcc"),
+		Analytic:      "vcc",
 	}
+	if trackID != "" {
+		detection.TrackID = &trackID
+	}
+	stampDetectionLocation(&detection, event.Device)
+	stampDetectionBBox(&detection)
 
 	// Handle direction based on 'wrong' flag
 	// Default to "Right" unless explicitly marked wrong
@@ -741,11 +1129,16 @@ func processVCCEvent(event IngestEvent, imageURLs map[string]string) error {
 		detection.FullImageURL = &url
 	}
 
-	return database.DB.Create(&detection).Error
+	if err := tx.Create(&detection).Error; err != nil {
+		return err
+	}
+	storeDetectionEmbedding(tx.DB, &detection, data)
+	tx.afterCommit(func() { invalidateStatsCache(StatsCacheRealtime) })
+	return nil
 }
 
 // processCrowdEvent handles crowd density events
-func processCrowdEvent(event IngestEvent, imageURLs map[string]string) error {
+func processCrowdEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	data := event.Data
 	
 	peopleCount, _ := data["people_count"].(float64)
@@ -768,6 +1161,7 @@ func processCrowdEvent(event IngestEvent, imageURLs map[string]string) error {
 		DensityLevel:    densityLevel,
 		MovementType:    models.MovementStatic,
 		HotspotSeverity: models.SeverityGreen,
+		SchemaVersion:   schemaVersion("crowd"),
 	}
 	
 	if peopleCount > 0 {
@@ -784,12 +1178,19 @@ func processCrowdEvent(event IngestEvent, imageURLs map[string]string) error {
 	if url, ok := imageURLs["heatmap.jpg"]; ok {
 		analysis.HeatmapImageURL = &url
 	}
+	if anomalies, ok := data["anomalies"]; ok && anomalies != nil {
+		analysis.Anomalies = models.NewJSONB(anomalies)
+	}
 
-	return database.DB.Create(&analysis).Error
+	if err := tx.Create(&analysis).Error; err != nil {
+		return err
+	}
+	tx.afterCommit(func() { flagCrowdAnomalies(&analysis) })
+	return nil
 }
 
 // processAlertEvent handles alert events
-func processAlertEvent(event IngestEvent, imageURLs map[string]string) error {
+func processAlertEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	// Store as crowd alert for now
 	data := event.Data
 	
@@ -821,17 +1222,22 @@ func processAlertEvent(event IngestEvent, imageURLs map[string]string) error {
 		alert.Description = &description
 	}
 
-	return database.DB.Create(&alert).Error
+	if err := tx.Create(&alert).Error; err != nil {
+		return err
+	}
+	tx.afterCommit(func() { notifyAlert(alert.DeviceID, alert.Severity, alert) })
+	return nil
 }
 
 // processGenericEvent handles unknown event types
-func processGenericEvent(event IngestEvent, imageURLs map[string]string) error {
+func processGenericEvent(tx *eventTx, event IngestEvent, imageURLs map[string]string) error {
 	// Store as generic event
 	genericEvent := models.Event{
 		DeviceID:  event.DeviceID,
 		Timestamp: *event.Timestamp,
 		Type:      event.Type,
 		Data:      models.NewJSONB(event.Data),
+		SchemaVersion: schemaVersion(event.Type),
 	}
 	
 	// Add image URLs to data
@@ -842,7 +1248,7 @@ func processGenericEvent(event IngestEvent, imageURLs map[string]string) error {
 		}
 	}
 
-	return database.DB.Create(&genericEvent).Error
+	return tx.Create(&genericEvent).Error
 }
 
 // getUploadBaseDir returns the base directory for uploads
@@ -861,6 +1267,19 @@ func getUploadBaseDir() string {
 	return baseDir
 }
 
+// uploadURL maps a storage path under the upload directory to its /uploads URL
+func uploadURL(storagePath string) string {
+	// Get relative path from base directory
+	relPath, err := filepath.Rel(getUploadBaseDir(), storagePath)
+	if err != nil {
+		// Fallback to just filename if relative path fails
+		relPath = filepath.Base(storagePath)
+	}
+
+	// Convert to forward slashes for URL (Windows compatibility)
+	return "/uploads/" + filepath.ToSlash(relPath)
+}
+
 // generateImagePath creates a storage path for uploaded images
 func generateImagePath(workerID, deviceID, eventType, filename string) string {
 	// Base directory
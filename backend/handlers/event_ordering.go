@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// Event ordering modes
+const (
+	EventOrderingOff     = "off"     // sequence numbers are ignored
+	EventOrderingFlag    = "flag"    // events are stored as they arrive; gaps and late events are flagged
+	EventOrderingReorder = "reorder" // an early event is held up to the window for the ones before it
+)
+
+const defaultReorderWindow = 2 * time.Second
+
+// maxMissingSeqs caps the skipped sequence numbers remembered per device
+const maxMissingSeqs = 1000
+
+// seqPlacement is where an event falls in its device's sequence
+type seqPlacement int
+
+const (
+	seqNext      seqPlacement = iota // the number expected next, or the first seen
+	seqAhead                         // numbers before it haven't arrived yet
+	seqLate                          // fills a gap, or is left over from a previous run
+	seqDuplicate                     // number already passed, e.g. a resend
+)
+
+// heldEvent is an event waiting for the ones before it in reorder mode
+type heldEvent struct {
+	event     IngestEvent
+	imageURLs map[string]string
+	at        time.Time
+	pass      bool // only moves the sequence past it, see eventSequencer.pass
+}
+
+// deviceSequence tracks the event sequence of one device
+type deviceSequence struct {
+	epoch   int64          // run of the edge the numbers belong to; a newer run restarts them
+	next    int64          // number expected next; 0 = none seen yet
+	missing map[int64]bool // skipped numbers that may still arrive
+
+	// Reorder mode: events ahead of the sequence wait in held until their
+	// turn, or until the oldest has waited the window
+	held    map[int64]heldEvent
+	busy    bool        // the event at next is being processed
+	release *time.Timer // runs the held events of an expired window
+
+	inOrder    int64
+	late       int64
+	duplicate  int64
+	gaps       int64 // times numbers were skipped
+	skipped    int64 // numbers skipped in total
+	restarts   int64
+	lastGapAt  *time.Time
+	lastLateAt *time.Time
+	lastAlert  time.Time
+}
+
+// eventSequencer checks the per-device sequence numbers of ingested events
+type eventSequencer struct {
+	mu      sync.Mutex
+	mode    string
+	window  time.Duration
+	devices map[string]*deviceSequence
+
+	processHeld func(h heldEvent, late bool) // processes held events; nil = processHeldEvent
+}
+
+var eventOrdering = &eventSequencer{
+	mode:    EventOrderingFlag,
+	window:  defaultReorderWindow,
+	devices: make(map[string]*deviceSequence),
+}
+
+// InitEventOrdering reads EVENT_ORDERING (off, flag or reorder; default flag)
+// and EVENT_REORDER_WINDOW_MS (default 2000) and returns the active mode and
+// reorder window
+func InitEventOrdering() (string, time.Duration) {
+	eventOrdering.mode = EventOrderingFlag
+	switch mode := strings.ToLower(os.Getenv("EVENT_ORDERING")); mode {
+	case EventOrderingOff, EventOrderingFlag, EventOrderingReorder:
+		eventOrdering.mode = mode
+	case "":
+	default:
+		log.Printf("⚠️ Unknown EVENT_ORDERING %q, using %s", mode, EventOrderingFlag)
+	}
+	eventOrdering.window = defaultReorderWindow
+	if v := os.Getenv("EVENT_REORDER_WINDOW_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+			eventOrdering.window = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return eventOrdering.mode, eventOrdering.window
+}
+
+// place classifies number n of run epoch, with the sequencer locked
+func (d *deviceSequence) place(n, epoch int64, now time.Time) seqPlacement {
+	switch {
+	case epoch > d.epoch:
+		if d.next > 0 {
+			d.restarts++
+		}
+		d.epoch, d.next = epoch, 0
+		d.missing = make(map[int64]bool)
+	case epoch < d.epoch:
+		d.late++
+		d.lastLateAt = &now
+		return seqLate
+	}
+
+	if d.next == 0 {
+		d.next = n
+	}
+	switch {
+	case n == d.next:
+		return seqNext
+	case n > d.next:
+		return seqAhead
+	case d.missing[n]:
+		delete(d.missing, n)
+		d.late++
+		d.lastLateAt = &now
+		return seqLate
+	default:
+		d.duplicate++
+		return seqDuplicate
+	}
+}
+
+// skipTo gives up on the numbers before n that haven't arrived, remembering
+// them in case they still do. Returns how many were skipped.
+func (d *deviceSequence) skipTo(n int64, now time.Time) int64 {
+	if n <= d.next {
+		return 0
+	}
+	skipped := n - d.next
+	for m := d.next; m < n && len(d.missing) < maxMissingSeqs; m++ {
+		d.missing[m] = true
+	}
+	d.gaps++
+	d.skipped += skipped
+	d.lastGapAt = &now
+	d.next = n
+	return skipped
+}
+
+// advance moves past n
+func (d *deviceSequence) advance(n int64) {
+	if n >= d.next {
+		d.next = n + 1
+	}
+}
+
+// takeHeld removes the events held for the device's current run, in
+// sequence order
+func (d *deviceSequence) takeHeld() []heldEvent {
+	held := make([]heldEvent, 0, len(d.held))
+	for _, h := range d.held {
+		held = append(held, h)
+	}
+	sort.Slice(held, func(a, b int) bool { return *held[a].event.Seq < *held[b].event.Seq })
+	d.held = make(map[int64]heldEvent)
+	if d.release != nil {
+		d.release.Stop()
+		d.release = nil
+	}
+	return held
+}
+
+// admit places an event in its device's sequence before it's processed.
+// Returns whether the event arrived late, and whether it was held: in reorder
+// mode an event ahead of the sequence isn't processed by the caller but kept
+// until the ones before it have been, or the window runs out, and then
+// processed in the background. Unless held, done must be called once the
+// event has been processed.
+func (s *eventSequencer) admit(event IngestEvent, imageURLs map[string]string) (late, held bool, done func()) {
+	return s.sequence(event, imageURLs, false)
+}
+
+// orderBatch sorts the events of each device in a batch by sequence number,
+// keeping the positions the device's events had, so a batch doesn't wait
+// on itself in reorder mode
+func orderBatch(events []IngestEvent) {
+	if eventOrdering.mode != EventOrderingReorder {
+		return
+	}
+	positions := make(map[string][]int)
+	for i, e := range events {
+		if e.Seq != nil {
+			positions[e.DeviceID] = append(positions[e.DeviceID], i)
+		}
+	}
+	for _, idx := range positions {
+		if len(idx) < 2 {
+			continue
+		}
+		sorted := make([]IngestEvent, len(idx))
+		for j, i := range idx {
+			sorted[j] = events[i]
+		}
+		sort.SliceStable(sorted, func(a, b int) bool {
+			if sorted[a].SeqEpoch != sorted[b].SeqEpoch {
+				return sorted[a].SeqEpoch < sorted[b].SeqEpoch
+			}
+			return *sorted[a].Seq < *sorted[b].Seq
+		})
+		for j, i := range idx {
+			events[i] = sorted[j]
+		}
+	}
+}
+
+// pass moves a device's sequence past an event that isn't processed now, such
+// as one throttled by the rate cap, so later events don't wait for it. Its
+// resend is counted as a duplicate and processed as usual.
+func (s *eventSequencer) pass(event IngestEvent) {
+	_, _, done := s.sequence(event, nil, true)
+	done()
+}
+
+// sequence places an event in its device's sequence, holding it if it's
+// ahead in reorder mode, and flags skipped numbers. With pass, the event
+// isn't processed, only stepped over.
+func (s *eventSequencer) sequence(event IngestEvent, imageURLs map[string]string, pass bool) (late, held bool, done func()) {
+	noop := func() {}
+	if s.mode == EventOrderingOff || event.Seq == nil || *event.Seq <= 0 {
+		return false, false, noop
+	}
+	n, epoch := *event.Seq, event.SeqEpoch
+	now := time.Now()
+	reorder := s.mode == EventOrderingReorder
+
+	s.mu.Lock()
+	d, ok := s.devices[event.DeviceID]
+	if !ok {
+		d = &deviceSequence{missing: make(map[int64]bool), held: make(map[int64]heldEvent)}
+		s.devices[event.DeviceID] = d
+	}
+
+	// Events of an old run stop waiting, and the one being processed no
+	// longer holds up the new run
+	if epoch > d.epoch {
+		if orphaned := d.takeHeld(); len(orphaned) > 0 {
+			go s.processOrphaned(orphaned)
+		}
+		d.busy = false
+	}
+
+	p := d.place(n, epoch, now)
+	if reorder {
+		_, waiting := d.held[n]
+		switch {
+		case p == seqNext && d.busy, p == seqAhead && waiting:
+			d.duplicate++
+			p = seqDuplicate
+		case p == seqAhead:
+			d.held[n] = heldEvent{event: event, imageURLs: imageURLs, at: now, pass: pass}
+			s.armRelease(d, s.window)
+			s.mu.Unlock()
+			return false, true, noop
+		}
+	}
+
+	switch p {
+	case seqLate:
+		s.mu.Unlock()
+		return true, false, noop
+	case seqDuplicate:
+		s.mu.Unlock()
+		return false, false, noop
+	case seqNext:
+		d.inOrder++
+	}
+
+	skipped := d.skipTo(n, now)
+	alert := skipped > 0 && now.Sub(d.lastAlert) >= healthEventInterval
+	if alert {
+		d.lastAlert = now
+	}
+	missing := len(d.missing)
+
+	done = noop
+	if reorder {
+		// Held events wait until this one is stored
+		d.busy = true
+		done = func() { s.finish(d, epoch, n) }
+	} else {
+		d.advance(n)
+	}
+	s.mu.Unlock()
+
+	if alert {
+		s.alertGap(event, skipped, missing)
+	}
+	return false, false, done
+}
+
+// finish moves the sequence past event n once it's been processed and runs
+// the held events whose turn that makes it
+func (s *eventSequencer) finish(d *deviceSequence, epoch, n int64) {
+	s.mu.Lock()
+	if d.epoch == epoch {
+		d.advance(n)
+		d.busy = false
+	}
+	_, ready := d.held[d.next]
+	s.mu.Unlock()
+	if ready {
+		go s.drain(d)
+	}
+}
+
+// armRelease has the held events of a device run once wait has passed, if
+// they aren't already due to, with the sequencer locked
+func (s *eventSequencer) armRelease(d *deviceSequence, wait time.Duration) {
+	if d.release != nil {
+		return
+	}
+	d.release = time.AfterFunc(wait, func() {
+		s.mu.Lock()
+		d.release = nil
+		s.mu.Unlock()
+		s.drain(d)
+	})
+}
+
+// drain processes a device's held events in sequence order while it's their
+// turn. Once the oldest has waited the window, the sequence skips ahead to
+// the first held event instead of waiting any longer for the ones before it.
+func (s *eventSequencer) drain(d *deviceSequence) {
+	for {
+		s.mu.Lock()
+		if d.busy || len(d.held) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		first := int64(-1)
+		var oldest time.Time
+		for m, h := range d.held {
+			if first < 0 || m < first {
+				first = m
+			}
+			if oldest.IsZero() || h.at.Before(oldest) {
+				oldest = h.at
+			}
+		}
+		h := d.held[first]
+		now := time.Now()
+
+		late := false
+		var skipped int64
+		switch {
+		case first < d.next:
+			// Stepped over while it waited, e.g. by the window of an earlier gap
+			late = true
+			d.late++
+			d.lastLateAt = &now
+		case first == d.next:
+			d.inOrder++
+		case now.Sub(oldest) >= s.window:
+			skipped = d.skipTo(first, now)
+		default:
+			s.armRelease(d, s.window-now.Sub(oldest))
+			s.mu.Unlock()
+			return
+		}
+		alert := skipped > 0 && now.Sub(d.lastAlert) >= healthEventInterval
+		if alert {
+			d.lastAlert = now
+		}
+		missing := len(d.missing)
+		delete(d.held, first)
+		d.busy = true
+		epoch := d.epoch
+		s.mu.Unlock()
+
+		if alert {
+			s.alertGap(h.event, skipped, missing)
+		}
+		if !h.pass {
+			s.process(h, late)
+		}
+
+		s.mu.Lock()
+		if d.epoch == epoch {
+			d.advance(first)
+			d.busy = false
+		}
+		s.mu.Unlock()
+	}
+}
+
+// processOrphaned processes the held events of a run the edge has moved on
+// from, as late
+func (s *eventSequencer) processOrphaned(orphaned []heldEvent) {
+	for _, h := range orphaned {
+		if !h.pass {
+			s.process(h, true)
+		}
+	}
+}
+
+// process processes a held event once its turn has come
+func (s *eventSequencer) process(h heldEvent, late bool) {
+	if s.processHeld != nil {
+		s.processHeld(h, late)
+		return
+	}
+	processHeldEvent(h, late)
+}
+
+// processHeldEvent processes an event that was held for reordering. Its
+// request has long been answered, so a failure can only be logged.
+func processHeldEvent(h heldEvent, late bool) {
+	if err := processSequencedEvent(h.event, h.imageURLs, late); err != nil {
+		log.Printf("❌ [EVENT_INGEST] Failed to process reordered event - ID: %s, Device: %s, Seq: %d, Error: %v",
+			h.event.ID, h.event.DeviceID, *h.event.Seq, err)
+	}
+}
+
+// alertGap logs and records a gap in a device's event sequence
+func (s *eventSequencer) alertGap(event IngestEvent, skipped int64, missing int) {
+	log.Printf("⚠️ [EVENT_INGEST] Event sequence gap - Device: %s, Worker: %s, Skipped: %d, Missing: %d",
+		event.DeviceID, event.WorkerID, skipped, missing)
+	raiseSequenceGapHealthEvent(event, skipped, missing)
+}
+
+// raiseSequenceGapHealthEvent records a device health event for events
+// missing from a device's sequence
+func raiseSequenceGapHealthEvent(event IngestEvent, skipped int64, missing int) {
+	riskLevel := "medium"
+	healthEvent := models.Event{
+		DeviceID:  event.DeviceID,
+		Timestamp: time.Now(),
+		Type:      "device_health",
+		Data: models.NewJSONB(map[string]interface{}{
+			"reason":        "event_sequence_gap",
+			"worker_id":     event.WorkerID,
+			"seq":           *event.Seq,
+			"skipped":       skipped,
+			"missing_total": missing,
+			"ordering_mode": eventOrdering.mode,
+		}),
+		RiskLevel: &riskLevel,
+	}
+	if err := database.DB.Create(&healthEvent).Error; err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Failed to record health event - Device: %s, Error: %v", event.DeviceID, err)
+	}
+}
+
+// summary reports a device's ordering integrity, with the sequencer locked
+func (d *deviceSequence) summary() gin.H {
+	return gin.H{
+		"intact":     len(d.missing) == 0, // no numbers outstanding
+		"missing":    len(d.missing),
+		"held":       len(d.held), // waiting for the ones before them (reorder mode)
+		"inOrder":    d.inOrder,
+		"late":       d.late,
+		"duplicates": d.duplicate,
+		"gaps":       d.gaps,
+		"skipped":    d.skipped,
+		"restarts":   d.restarts,
+		"nextSeq":    d.next,
+		"lastGapAt":  d.lastGapAt,
+		"lastLateAt": d.lastLateAt,
+	}
+}
+
+// deviceStats returns the ordering integrity of one device; nil if it hasn't
+// sent sequence numbers since startup
+func (s *eventSequencer) deviceStats(deviceID string) gin.H {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.devices[deviceID]
+	if !ok {
+		return nil
+	}
+	return d.summary()
+}
+
+// stats returns the mode and the devices whose events arrived out of order
+func (s *eventSequencer) stats() gin.H {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byDevice := make([]gin.H, 0)
+	for deviceID, d := range s.devices {
+		if d.gaps == 0 && d.late == 0 && d.duplicate == 0 {
+			continue
+		}
+		entry := d.summary()
+		entry["deviceId"] = deviceID
+		byDevice = append(byDevice, entry)
+	}
+
+	return gin.H{
+		"mode":            s.mode,
+		"reorderWindowMs": s.window.Milliseconds(),
+		"sequenced":       len(s.devices),
+		"byDevice":        byDevice,
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReorderHoldsEarlyEvents(t *testing.T) {
+	var mu sync.Mutex
+	var processed []int64
+	record := func(seq int64) {
+		mu.Lock()
+		processed = append(processed, seq)
+		mu.Unlock()
+	}
+	s := &eventSequencer{
+		mode:        EventOrderingReorder,
+		window:      time.Minute,
+		devices:     make(map[string]*deviceSequence),
+		processHeld: func(h heldEvent, late bool) { record(*h.event.Seq) },
+	}
+	event := func(seq int64) IngestEvent {
+		return IngestEvent{DeviceID: "cam-1", Seq: &seq, SeqEpoch: 1}
+	}
+
+	_, held, done := s.admit(event(1), nil)
+	if held {
+		t.Fatal("first event was held")
+	}
+	for _, seq := range []int64{3, 2} {
+		if _, held, _ := s.admit(event(seq), nil); !held {
+			t.Fatalf("event %d ahead of the sequence wasn't held", seq)
+		}
+	}
+	if _, held, _ := s.admit(event(3), nil); held {
+		t.Error("resend of a held event was held again")
+	}
+
+	// The held events run in order once the first has been processed
+	record(1)
+	done()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(processed)
+		mu.Unlock()
+		if n == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(processed) != 3 || processed[0] != 1 || processed[1] != 2 || processed[2] != 3 {
+		t.Errorf("processed %v, want [1 2 3]", processed)
+	}
+	if stats := s.deviceStats("cam-1"); stats["held"] != 0 || stats["nextSeq"] != int64(4) || stats["duplicates"] != int64(1) {
+		t.Errorf("stats = %v, want nothing held, next 4 and 1 duplicate", stats)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventSchemaVersions is the current layout version of each analytic's stored
+// metadata. Bump a type's version whenever the backend changes how it
+// structures that type's metadata, and describe the change below, so clients
+// can tell old rows from new ones by their schemaVersion.
+var eventSchemaVersions = map[string]int{
+	"anpr":      1,
+	"violation": 2,
+	"vcc":       1,
+	"crowd":     1,
+}
+
+// eventSchemaChanges describes what each version changed
+var eventSchemaChanges = map[string]map[int]string{
+	"violation": {
+		2: "metadata.expected_direction is set on wrong-way violations in zones with an expected direction",
+	},
+}
+
+// eventSchemaAliases maps legacy event type names onto their analytic
+var eventSchemaAliases = map[string]string{
+	"plate_detected":   "anpr",
+	"vehicle_detected": "vcc",
+	"crowd_density":    "crowd",
+}
+
+// schemaVersion returns the current metadata layout version of an event
+// type. Types without a registered layout are at version 1.
+func schemaVersion(eventType string) int {
+	if alias, ok := eventSchemaAliases[eventType]; ok {
+		eventType = alias
+	}
+	if version, ok := eventSchemaVersions[eventType]; ok {
+		return version
+	}
+	return 1
+}
+
+// GetEventSchemas returns the current metadata layout version of each analytic
+// GET /api/events/schemas
+func GetEventSchemas(c *gin.Context) {
+	schemas := make(map[string]gin.H, len(eventSchemaVersions))
+	for eventType, version := range eventSchemaVersions {
+		schema := gin.H{"version": version}
+		if changes, ok := eventSchemaChanges[eventType]; ok {
+			schema["changes"] = changes
+		}
+		schemas[eventType] = schema
+	}
+	c.JSON(http.StatusOK, gin.H{"schemas": schemas, "defaultVersion": 1})
+}
@@ -3,6 +3,9 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -25,7 +28,39 @@ func SetFeedHub(hub *services.FeedHub) {
 	feedHub = hub
 }
 
-// HandleFeedWebSocket handles WebSocket connections for camera feeds
+const (
+	defaultFeedAckMaxLag       = 5 * time.Second
+	defaultFeedAckStallTimeout = 30 * time.Second
+)
+
+// InitFeedAcks reads FEED_ACK_MAX_LAG_SECONDS (default 5, 0 = never
+// throttle) and FEED_ACK_STALL_SECONDS (default 30, 0 = never disconnect) and
+// applies them to feed clients that ack the frames they receive. Returns the
+// policy.
+func InitFeedAcks() services.FeedAckPolicy {
+	policy := services.FeedAckPolicy{
+		MaxLag:       defaultFeedAckMaxLag,
+		StallTimeout: defaultFeedAckStallTimeout,
+	}
+	if v := os.Getenv("FEED_ACK_MAX_LAG_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			policy.MaxLag = time.Duration(secs) * time.Second
+		}
+	}
+	if v := os.Getenv("FEED_ACK_STALL_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			policy.StallTimeout = time.Duration(secs) * time.Second
+		}
+	}
+	if feedHub != nil {
+		feedHub.SetAckPolicy(policy)
+	}
+	return policy
+}
+
+// HandleFeedWebSocket handles WebSocket connections for camera feeds.
+// Clients may send {"type":"ack","data":{"seq":n}} with the number of frames
+// received so far, letting the hub measure their lag and throttle them.
 func HandleFeedWebSocket(c *gin.Context) {
 	if feedHub == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Feed hub not initialized"})
@@ -68,6 +103,8 @@ func GetFeedHubStats(c *gin.Context) {
 		"clients":       stats.Clients,
 		"subscriptions": stats.Subscriptions,
 		"activeCameras": stats.ActiveCameras,
+		"cameras":       stats.Cameras,
+		"clientLag":     stats.ClientLag,
 	})
 }
 
@@ -0,0 +1,403 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// Alert types raised when a linked vehicle crosses a geofence boundary
+const (
+	geofenceEnterAlert = "geofence_enter"
+	geofenceExitAlert  = "geofence_exit"
+)
+
+// geofenceAlerts controls whether ANPR detections of watchlisted vehicles are
+// checked against their geofences
+var geofenceAlerts = true
+
+// InitGeofenceAlerts reads GEOFENCE_ALERTS (default true) and returns whether
+// geofence enter/exit alerts are raised
+func InitGeofenceAlerts() bool {
+	geofenceAlerts = os.Getenv("GEOFENCE_ALERTS") != "false"
+	return geofenceAlerts
+}
+
+// GeofenceRequest - Create or update a geofence
+type GeofenceRequest struct {
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Boundary    *[][2]float64 `json:"boundary"` // [[lat, lng], ...]
+	IsActive    *bool         `json:"isActive"`
+	CreatedBy   string        `json:"createdBy"`
+}
+
+// applyGeofenceRequest copies the set fields of req onto fence
+func applyGeofenceRequest(fence *models.Geofence, req *GeofenceRequest) error {
+	if req.Name != nil {
+		if *req.Name == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		fence.Name = *req.Name
+	}
+	if req.Description != nil {
+		fence.Description = req.Description
+	}
+	if req.Boundary != nil {
+		if len(*req.Boundary) == 0 {
+			return fmt.Errorf("boundary cannot be empty")
+		}
+		if err := validateSiteBoundary(*req.Boundary); err != nil {
+			return err
+		}
+		fence.Boundary = models.NewJSONB(*req.Boundary)
+	}
+	if req.IsActive != nil {
+		fence.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// geofenceBoundary decodes a stored boundary polygon
+func geofenceBoundary(fence *models.Geofence) [][2]float64 {
+	raw, err := json.Marshal(fence.Boundary.Data)
+	if err != nil {
+		return nil
+	}
+	var points [][2]float64
+	if err := json.Unmarshal(raw, &points); err != nil {
+		return nil
+	}
+	return points
+}
+
+// pointInPolygon reports whether a [lat, lng] point lies inside a polygon,
+// by counting the edges a ray from the point crosses. Geofences are small
+// enough for lat/lng to be treated as planar.
+func pointInPolygon(lat, lng float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a[0] > lat) != (b[0] > lat) &&
+			lng < (b[1]-a[1])*(lat-a[0])/(b[0]-a[0])+a[1] {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// evaluateGeofences checks a detection of a watchlisted vehicle against every
+// active geofence the vehicle is linked to, and raises an alert for each one
+// it has entered or left since it was last detected. A vehicle first seen
+// outside a geofence raises nothing; detections from devices without a
+// location are skipped.
+func evaluateGeofences(entry *models.Watchlist, plateNumber string, device *models.Device, at time.Time) {
+	if !geofenceAlerts || device == nil || (device.Lat == 0 && device.Lng == 0) {
+		return
+	}
+
+	var fences []models.Geofence
+	if err := database.DB.
+		Joins("JOIN geofence_vehicles gv ON gv.geofence_id = geofences.id").
+		Where("gv.vehicle_id = ? AND geofences.is_active = ?", entry.VehicleID, true).
+		Find(&fences).Error; err != nil {
+		log.Printf("⚠️ [GEOFENCE] Failed to load geofences for vehicle %d: %v", entry.VehicleID, err)
+		return
+	}
+
+	for i := range fences {
+		fence := &fences[i]
+		inside := pointInPolygon(device.Lat, device.Lng, geofenceBoundary(fence))
+
+		// Flip the state only if it changed and no later detection has been
+		// recorded, so concurrent or out-of-order detections alert once
+		scope := database.DB.Model(&models.GeofenceVehicle{}).
+			Where("geofence_id = ? AND vehicle_id = ?", fence.ID, entry.VehicleID).
+			Where("last_seen_at IS NULL OR last_seen_at <= ?", at)
+		updates := map[string]interface{}{
+			"inside":         inside,
+			"last_device_id": device.ID,
+			"last_seen_at":   at,
+		}
+		result := scope.Session(&gorm.Session{}).Where("inside <> ?", inside).Updates(updates)
+		if result.Error != nil {
+			log.Printf("⚠️ [GEOFENCE] Failed to update vehicle %d in geofence %d: %v", entry.VehicleID, fence.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			scope.Updates(updates)
+			continue
+		}
+		raiseGeofenceAlert(entry, fence, plateNumber, device.ID, inside, at)
+	}
+}
+
+// raiseGeofenceAlert records an enter or exit alert for a watchlisted vehicle
+// at the watchlist entry's severity and priority, pushed like a watchlist hit
+func raiseGeofenceAlert(entry *models.Watchlist, fence *models.Geofence, plateNumber, deviceID string, entered bool, at time.Time) {
+	severity := entry.Severity
+	priority, ok := watchlistAlertPriority[severity]
+	if !ok {
+		severity = models.SeverityYellow
+		priority = watchlistAlertPriority[severity]
+	}
+
+	alertType, verb := geofenceExitAlert, "left"
+	if entered {
+		alertType, verb = geofenceEnterAlert, "entered"
+	}
+	description := fmt.Sprintf("%s: %s", entry.Category, entry.Reason)
+	alert := models.CrowdAlert{
+		DeviceID:     deviceID,
+		Timestamp:    at,
+		AlertType:    alertType,
+		Severity:     severity,
+		Priority:     priority,
+		Title:        fmt.Sprintf("Watchlisted vehicle %s %s %s", plateNumber, verb, fence.Name),
+		Description:  &description,
+		DensityLevel: models.DensityLow,
+		TriggerRule: models.NewJSONB(map[string]interface{}{
+			"geofenceId":  fence.ID,
+			"watchlistId": entry.ID,
+			"vehicleId":   entry.VehicleID,
+			"category":    entry.Category,
+			"transition":  verb,
+		}),
+	}
+	if err := database.DB.Create(&alert).Error; err != nil {
+		log.Printf("⚠️ [GEOFENCE] Failed to record alert for %s in %s: %v", logPlate(plateNumber), fence.Name, err)
+		return
+	}
+	log.Printf("🚨 [GEOFENCE] %s vehicle %s %s %s (seen on %s)", entry.Category, logPlate(plateNumber), verb, fence.Name, deviceID)
+
+	if severity == models.SeverityGreen {
+		return
+	}
+	notifyAlert(deviceID, severity, alert)
+}
+
+// geofenceByParam loads the geofence named by the :id path parameter,
+// writing the error response itself when it can't
+func geofenceByParam(c *gin.Context) (*models.Geofence, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence ID"})
+		return nil, false
+	}
+	var fence models.Geofence
+	if err := database.DB.First(&fence, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Geofence not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch geofence"})
+		return nil, false
+	}
+	return &fence, true
+}
+
+// GetGeofences handles GET /api/geofences - List geofences with their linked vehicle counts
+func GetGeofences(c *gin.Context) {
+	var fences []models.Geofence
+	if err := database.DB.Order("name ASC").Find(&fences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch geofences"})
+		return
+	}
+
+	var counts []struct {
+		GeofenceID int64
+		Vehicles   int64
+		Inside     int64
+	}
+	database.DB.Model(&models.GeofenceVehicle{}).
+		Select("geofence_id, COUNT(*) AS vehicles, COUNT(*) FILTER (WHERE inside) AS inside").
+		Group("geofence_id").
+		Scan(&counts)
+	byFence := make(map[int64]int, len(counts))
+	for i, fc := range counts {
+		byFence[fc.GeofenceID] = i
+	}
+
+	result := make([]gin.H, 0, len(fences))
+	for _, fence := range fences {
+		entry := gin.H{
+			"geofence": fence,
+			"vehicles": int64(0),
+			"inside":   int64(0),
+		}
+		if i, ok := byFence[fence.ID]; ok {
+			entry["vehicles"] = counts[i].Vehicles
+			entry["inside"] = counts[i].Inside
+		}
+		result = append(result, entry)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetGeofence handles GET /api/geofences/:id - Get a geofence with its linked vehicles
+func GetGeofence(c *gin.Context) {
+	fence, ok := geofenceByParam(c)
+	if !ok {
+		return
+	}
+
+	var vehicles []models.GeofenceVehicle
+	if err := database.DB.Preload("Vehicle").Where("geofence_id = ?", fence.ID).
+		Order("created_at ASC").Find(&vehicles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch geofence vehicles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"geofence": fence,
+		"vehicles": vehicles,
+	})
+}
+
+// CreateGeofence handles POST /api/geofences - Define a geofence
+func CreateGeofence(c *gin.Context) {
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil || req.Boundary == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name and boundary are required"})
+		return
+	}
+
+	fence := models.Geofence{IsActive: true, CreatedBy: req.CreatedBy}
+	if err := applyGeofenceRequest(&fence, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&fence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create geofence"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, fence)
+}
+
+// UpdateGeofence handles PUT /api/geofences/:id - Rename, reshape, enable or
+// disable a geofence. Reshaping keeps each vehicle's last inside/outside state
+// until it's next detected.
+func UpdateGeofence(c *gin.Context) {
+	fence, ok := geofenceByParam(c)
+	if !ok {
+		return
+	}
+
+	var req GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyGeofenceRequest(fence, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(fence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update geofence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, fence)
+}
+
+// DeleteGeofence handles DELETE /api/geofences/:id - Remove a geofence and its vehicle links
+func DeleteGeofence(c *gin.Context) {
+	fence, ok := geofenceByParam(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("geofence_id = ?", fence.ID).Delete(&models.GeofenceVehicle{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(fence).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete geofence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Geofence deleted"})
+}
+
+// LinkGeofenceVehicle handles POST /api/geofences/:id/vehicles - Link a
+// watchlisted vehicle to a geofence
+func LinkGeofenceVehicle(c *gin.Context) {
+	fence, ok := geofenceByParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		VehicleID int64  `json:"vehicleId" binding:"required"`
+		LinkedBy  string `json:"linkedBy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var entry models.Watchlist
+	if err := database.DB.Where("vehicle_id = ? AND is_active = ?", req.VehicleID, true).First(&entry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Vehicle is not on the watchlist"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist entry"})
+		return
+	}
+
+	link := models.GeofenceVehicle{GeofenceID: fence.ID, VehicleID: req.VehicleID, LinkedBy: req.LinkedBy}
+	result := database.DB.Where("geofence_id = ? AND vehicle_id = ?", fence.ID, req.VehicleID).FirstOrCreate(&link)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link vehicle"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Vehicle is already linked to this geofence"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// UnlinkGeofenceVehicle handles DELETE /api/geofences/:id/vehicles/:vehicleId
+func UnlinkGeofenceVehicle(c *gin.Context) {
+	fence, ok := geofenceByParam(c)
+	if !ok {
+		return
+	}
+	vehicleID, err := strconv.ParseInt(c.Param("vehicleId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return
+	}
+
+	result := database.DB.Where("geofence_id = ? AND vehicle_id = ?", fence.ID, vehicleID).Delete(&models.GeofenceVehicle{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink vehicle"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle is not linked to this geofence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle unlinked"})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram buckets
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// routeKey identifies a route: its method and registered path
+type routeKey struct {
+	method string
+	route  string
+}
+
+// routeMetrics is the latency histogram and status counts of one route
+type routeMetrics struct {
+	buckets  []uint64 // per bucket, with a final +Inf bucket; not cumulative
+	count    uint64
+	sum      float64
+	statuses map[int]uint64
+}
+
+// httpMetrics collects request latency and status codes per route
+var httpMetrics = struct {
+	mu      sync.Mutex
+	enabled bool
+	since   time.Time
+	routes  map[routeKey]*routeMetrics
+}{enabled: true, since: time.Now(), routes: make(map[routeKey]*routeMetrics)}
+
+// InitHTTPMetrics reads HTTP_METRICS (default true) and returns whether
+// request metrics are collected
+func InitHTTPMetrics() bool {
+	httpMetrics.mu.Lock()
+	defer httpMetrics.mu.Unlock()
+	httpMetrics.enabled = os.Getenv("HTTP_METRICS") != "false"
+	return httpMetrics.enabled
+}
+
+// MetricsMiddleware records each request's latency and status code against
+// its route. Requests that match no route are recorded as "unmatched" so
+// scanners can't grow the metrics without bound.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		httpMetrics.mu.Lock()
+		enabled := httpMetrics.enabled
+		httpMetrics.mu.Unlock()
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		elapsed := time.Since(start).Seconds()
+
+		key := routeKey{method: c.Request.Method, route: c.FullPath()}
+		if key.route == "" {
+			key.route = "unmatched"
+		}
+		bucket := sort.SearchFloat64s(latencyBuckets, elapsed)
+
+		httpMetrics.mu.Lock()
+		defer httpMetrics.mu.Unlock()
+		m, ok := httpMetrics.routes[key]
+		if !ok {
+			m = &routeMetrics{buckets: make([]uint64, len(latencyBuckets)+1), statuses: make(map[int]uint64)}
+			httpMetrics.routes[key] = m
+		}
+		m.buckets[bucket]++
+		m.count++
+		m.sum += elapsed
+		m.statuses[c.Writer.Status()]++
+	}
+}
+
+// quantile estimates a latency quantile from a histogram, interpolating
+// linearly within the bucket it falls in. Quantiles in the +Inf bucket are
+// reported as the largest bound.
+func (m *routeMetrics) quantile(q float64) float64 {
+	rank := q * float64(m.count)
+	var seen float64
+	for i, n := range m.buckets {
+		if n == 0 {
+			continue
+		}
+		if seen+float64(n) >= rank {
+			if i == len(latencyBuckets) {
+				return latencyBuckets[len(latencyBuckets)-1]
+			}
+			lower := 0.0
+			if i > 0 {
+				lower = latencyBuckets[i-1]
+			}
+			return lower + (latencyBuckets[i]-lower)*(rank-seen)/float64(n)
+		}
+		seen += float64(n)
+	}
+	return 0
+}
+
+// sortedRoutes returns the recorded routes in path then method order.
+// Callers hold httpMetrics.mu.
+func sortedRoutes() []routeKey {
+	keys := make([]routeKey, 0, len(httpMetrics.routes))
+	for key := range httpMetrics.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].method < keys[j].method
+	})
+	return keys
+}
+
+// GetMetrics exports request metrics in the Prometheus text format
+// GET /metrics
+func GetMetrics(c *gin.Context) {
+	httpMetrics.mu.Lock()
+	defer httpMetrics.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP http_request_duration_seconds Request latency by route.\n")
+	b.WriteString("# TYPE http_request_duration_seconds histogram\n")
+	keys := sortedRoutes()
+	for _, key := range keys {
+		m := httpMetrics.routes[key]
+		labels := fmt.Sprintf("method=%q,route=%q", key.method, key.route)
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += m.buckets[i]
+			fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, m.count)
+		fmt.Fprintf(&b, "http_request_duration_seconds_sum{%s} %g\n", labels, m.sum)
+		fmt.Fprintf(&b, "http_request_duration_seconds_count{%s} %d\n", labels, m.count)
+	}
+
+	b.WriteString("# HELP http_requests_total Requests by route and status code.\n")
+	b.WriteString("# TYPE http_requests_total counter\n")
+	for _, key := range keys {
+		m := httpMetrics.routes[key]
+		statuses := make([]int, 0, len(m.statuses))
+		for status := range m.statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "http_requests_total{method=%q,route=%q,status=\"%d\"} %d\n", key.method, key.route, status, m.statuses[status])
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// GetRouteLatency returns per-route latency percentiles and error rates,
+// slowest p95 first (admin)
+// GET /api/admin/metrics/routes
+func GetRouteLatency(c *gin.Context) {
+	httpMetrics.mu.Lock()
+	defer httpMetrics.mu.Unlock()
+
+	routes := make([]gin.H, 0, len(httpMetrics.routes))
+	for _, key := range sortedRoutes() {
+		m := httpMetrics.routes[key]
+		var errors uint64
+		statuses := make(map[string]uint64, len(m.statuses))
+		for status, n := range m.statuses {
+			statuses[strconv.Itoa(status)] = n
+			if status >= 500 {
+				errors += n
+			}
+		}
+		routes = append(routes, gin.H{
+			"method":    key.method,
+			"route":     key.route,
+			"count":     m.count,
+			"meanMs":    m.sum / float64(m.count) * 1000,
+			"p50Ms":     m.quantile(0.50) * 1000,
+			"p95Ms":     m.quantile(0.95) * 1000,
+			"p99Ms":     m.quantile(0.99) * 1000,
+			"statuses":  statuses,
+			"errorRate": float64(errors) / float64(m.count),
+		})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i]["p95Ms"].(float64) > routes[j]["p95Ms"].(float64)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": httpMetrics.enabled,
+		"since":   httpMetrics.since,
+		"routes":  routes,
+	})
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultSignedImageTTL = time.Hour
+
+// imageAccess controls how evidence images under the upload directory are served
+var imageAccess = struct {
+	open      bool            // serve /uploads without auth (the legacy static mount)
+	roles     map[string]bool // roles allowed to view images; nil = any logged-in user
+	signedTTL time.Duration   // lifetime of signed image URLs
+	signLists bool            // list endpoints return signed URLs instead of image paths
+	secret    []byte          // HMAC key of signed URLs
+}{
+	open:      true,
+	signedTTL: defaultSignedImageTTL,
+	signLists: true,
+}
+
+// InitImageAccess reads IMAGE_ACCESS (open or auth; defaults to auth when
+// ENV=production), IMAGE_ACCESS_ROLES (comma-separated, default any role),
+// IMAGE_URL_TTL_MINUTES (default 60), IMAGE_SIGN_LIST_URLS (default true) and
+// IMAGE_URL_SECRET (default JWT_SECRET). Returns true if /uploads stays open,
+// or an error when auth mode has no secret to sign URLs with.
+func InitImageAccess() (bool, error) {
+	mode := strings.ToLower(os.Getenv("IMAGE_ACCESS"))
+	if mode == "" {
+		mode = "open"
+		if os.Getenv("ENV") == "production" {
+			mode = "auth"
+		}
+	}
+	imageAccess.open = mode != "auth"
+
+	imageAccess.roles = nil
+	if v := os.Getenv("IMAGE_ACCESS_ROLES"); v != "" && v != "*" {
+		imageAccess.roles = make(map[string]bool)
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				imageAccess.roles[role] = true
+			}
+		}
+	}
+
+	imageAccess.signedTTL = defaultSignedImageTTL
+	if v := os.Getenv("IMAGE_URL_TTL_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins > 0 {
+			imageAccess.signedTTL = time.Duration(mins) * time.Minute
+		}
+	}
+
+	imageAccess.signLists = os.Getenv("IMAGE_SIGN_LIST_URLS") != "false"
+
+	// The built-in dev JWT secret is public, so it can't sign anything
+	imageAccess.secret = []byte(os.Getenv("IMAGE_URL_SECRET"))
+	if len(imageAccess.secret) == 0 {
+		imageAccess.secret = []byte(os.Getenv("JWT_SECRET"))
+	}
+	if !imageAccess.open && len(imageAccess.secret) == 0 {
+		return false, fmt.Errorf("IMAGE_ACCESS=auth needs IMAGE_URL_SECRET or JWT_SECRET to sign image URLs")
+	}
+
+	return imageAccess.open, nil
+}
+
+// imageSignature is the HMAC of an image path and its expiry
+func imageSignature(imagePath string, expires int64) string {
+	mac := hmac.New(sha256.New, imageAccess.secret)
+	fmt.Fprintf(mac, "%s\n%d", imagePath, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// imageURLPath returns the image path of an /uploads URL or of a cold
+// storage URL, which is already under /api/v1/images
+func imageURLPath(imageURL string) (string, bool) {
+	if strings.HasPrefix(imageURL, "/uploads/") {
+		return strings.TrimPrefix(imageURL, "/uploads/"), true
+	}
+	if strings.HasPrefix(imageURL, coldImageURL("")) {
+		return strings.TrimPrefix(imageURL, APIV1Prefix+"/images/"), true
+	}
+	return "", false
+}
+
+// signedImageExpiry is when a URL signed now expires: at least the TTL away,
+// rounded up to a multiple of half the TTL so an image keeps the same URL
+// across listings for a while and browsers can cache it
+func signedImageExpiry(now time.Time) int64 {
+	step := int64(imageAccess.signedTTL.Seconds()) / 2
+	expires := now.Add(imageAccess.signedTTL).Unix()
+	if step <= 0 {
+		return expires
+	}
+	return (expires + step - 1) / step * step
+}
+
+// signedImageURL turns an /uploads or cold storage URL into a time-limited
+// /api/v1/images URL. With open image access the URL is returned unchanged.
+func signedImageURL(uploadURL string) string {
+	imagePath, ok := imageURLPath(uploadURL)
+	if imageAccess.open || !ok {
+		return uploadURL
+	}
+	expires := signedImageExpiry(time.Now())
+	return fmt.Sprintf("%s/images/%s?expires=%d&sig=%s", APIV1Prefix, imagePath, expires, imageSignature(imagePath, expires))
+}
+
+// signedImageURLPtr signs an optional image URL
+func signedImageURLPtr(imageURL *string) *string {
+	if imageURL == nil {
+		return nil
+	}
+	signed := signedImageURL(*imageURL)
+	return &signed
+}
+
+// listImagesSigned reports whether a response carries signed image URLs: only
+// with auth image access and list signing on, and only to a requester allowed
+// to view images. Anyone else gets image paths they can't load.
+func listImagesSigned(c *gin.Context) bool {
+	if imageAccess.open || !imageAccess.signLists {
+		return false
+	}
+	allowed, _ := userImageAllowed(c)
+	return allowed
+}
+
+// signViolationImages swaps the image paths of violations in a response for
+// signed URLs, so dashboards can load thumbnails without a token or a lookup
+func signViolationImages(c *gin.Context, violations []models.TrafficViolation) {
+	if !listImagesSigned(c) {
+		return
+	}
+	for i := range violations {
+		signViolationURLs(&violations[i])
+	}
+}
+
+// signViolationImage swaps the image paths of one violation for signed URLs
+func signViolationImage(c *gin.Context, v *models.TrafficViolation) {
+	if listImagesSigned(c) {
+		signViolationURLs(v)
+	}
+}
+
+// signViolationURLs swaps the image paths of a violation for signed URLs
+func signViolationURLs(v *models.TrafficViolation) {
+	if v.PlateImages.Data != nil {
+		images := v.PlateImageList()
+		for j := range images {
+			images[j].URL = signedImageURL(images[j].URL)
+		}
+		v.PlateImages = models.NewJSONB(images)
+	}
+	v.PlateImageURL = signedImageURLPtr(v.PlateImageURL)
+	v.FullSnapshotURL = signedImageURLPtr(v.FullSnapshotURL)
+}
+
+// signDetectionImages swaps the image paths of detections in a response for
+// signed URLs
+func signDetectionImages(c *gin.Context, detections []models.VehicleDetection) {
+	if !listImagesSigned(c) {
+		return
+	}
+	for i := range detections {
+		d := &detections[i]
+		d.FullImageURL = signedImageURLPtr(d.FullImageURL)
+		d.PlateImageURL = signedImageURLPtr(d.PlateImageURL)
+		d.VehicleImageURL = signedImageURLPtr(d.VehicleImageURL)
+	}
+}
+
+// signedImageAllowed checks the expiry and signature of a signed image URL
+func signedImageAllowed(c *gin.Context, imagePath string) (bool, string) {
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false, "Image link has expired"
+	}
+	if !hmac.Equal([]byte(c.Query("sig")), []byte(imageSignature(imagePath, expires))) {
+		return false, "Invalid image signature"
+	}
+	return true, ""
+}
+
+// requestUser returns the user whose Authorization header carries a token, or
+// why there isn't one. The token is never taken from the query string, where
+// it would end up in logs and referrers; <img> tags use signed URLs instead.
+func requestUser(c *gin.Context) (*models.User, string) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, "Authorization required"
+	}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, "Invalid token"
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return nil, "Invalid token"
+	}
+
+	var user models.User
+	if err := database.DB.Select("id, role").First(&user, uint(sub)).Error; err != nil {
+		return nil, "Invalid token"
+	}
+	return &user, ""
+}
+
+// userImageAllowed checks the requester's token and that their role may view images
+func userImageAllowed(c *gin.Context) (bool, string) {
+	user, reason := requestUser(c)
+	if user == nil {
+		return false, reason
+	}
+	if imageAccess.roles != nil && !imageAccess.roles[user.Role] {
+		return false, "Not allowed to view evidence images"
+	}
+	return true, ""
+}
+
+// ServeImage handles GET /api/images/*path - Serve an evidence image to an
+// authorized user or a valid signed URL
+func ServeImage(c *gin.Context) {
+	imagePath := strings.TrimPrefix(path.Clean("/"+c.Param("path")), "/")
+	if imagePath == "" || imagePath == "." {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	// With open access /uploads is public, so cold copies are too
+	allowed, reason := true, ""
+	if !imageAccess.open {
+		if c.Query("sig") != "" {
+			allowed, reason = signedImageAllowed(c, imagePath)
+		} else {
+			allowed, reason = userImageAllowed(c)
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": reason})
+		return
+	}
+
+	if strings.HasPrefix(imagePath, coldImagePrefix) {
+		serveColdImage(c, strings.TrimPrefix(imagePath, coldImagePrefix))
+		return
+	}
+
+	// path.Clean on a rooted path drops any "..", so this stays under the base dir
+	file, err := os.Open(filepath.Join(getUploadBaseDir(), filepath.FromSlash(imagePath)))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	// A signed URL stays valid until it expires, so it can be cached until then
+	maxAge := 300
+	if expires, err := strconv.ParseInt(c.Query("expires"), 10, 64); err == nil && c.Query("sig") != "" && !imageAccess.open {
+		maxAge = int(expires - time.Now().Unix())
+	}
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), file)
+}
+
+// SignImageURLsRequest - Upload URLs to sign
+type SignImageURLsRequest struct {
+	URLs []string `json:"urls" binding:"required"`
+}
+
+// SignImageURLs handles POST /api/images/sign - Exchange /uploads and cold
+// storage URLs for time-limited signed URLs that can be embedded without a token
+func SignImageURLs(c *gin.Context) {
+	if ok, reason := userImageAllowed(c); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": reason})
+		return
+	}
+
+	var req SignImageURLsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	signed := make(map[string]string, len(req.URLs))
+	for _, u := range req.URLs {
+		if parsed, err := url.Parse(u); err == nil {
+			if _, ok := imageURLPath(parsed.Path); ok {
+				signed[u] = signedImageURL(parsed.Path)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"urls":      signed,
+		"expiresIn": int(imageAccess.signedTTL.Seconds()),
+	})
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"log"
+	"os"
+)
+
+// JPEG markers walked when cleaning metadata
+const (
+	jpegSOI  = 0xD8
+	jpegSOS  = 0xDA
+	jpegEOI  = 0xD9
+	jpegAPP1 = 0xE1
+)
+
+// exifOrientationTag is the EXIF tag giving how the stored pixels must be
+// turned to display upright
+const exifOrientationTag = 0x0112
+
+// imageMetadataStripping controls whether EXIF and XMP are removed from
+// uploaded JPEGs, turning them upright first
+var imageMetadataStripping = true
+
+// InitImageMetadata reads IMAGE_STRIP_METADATA (default true) and returns
+// whether uploaded JPEGs are stripped of EXIF and turned upright
+func InitImageMetadata() bool {
+	imageMetadataStripping = os.Getenv("IMAGE_STRIP_METADATA") != "false"
+	return imageMetadataStripping
+}
+
+// jpegSegment is one marker segment of a JPEG before its scan data
+type jpegSegment struct {
+	marker byte
+	data   []byte // whole segment, marker included
+}
+
+// jpegSegments splits a JPEG into its header segments and the rest of the
+// file from the start of scan onwards. ok is false for anything that isn't a
+// well-formed JPEG.
+func jpegSegments(data []byte) (segments []jpegSegment, rest []byte, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != jpegSOI {
+		return nil, nil, false
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, nil, false
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			pos++ // fill byte
+			continue
+		}
+		if marker == jpegSOS || marker == jpegEOI {
+			return segments, data[pos:], true
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, nil, false
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: data[pos:end]})
+		pos = end
+	}
+	return nil, nil, false
+}
+
+// isMetadataSegment reports whether a segment is EXIF or XMP
+func isMetadataSegment(seg jpegSegment) bool {
+	if seg.marker != jpegAPP1 || len(seg.data) < 4 {
+		return false
+	}
+	payload := seg.data[4:]
+	return bytes.HasPrefix(payload, []byte("Exif\x00")) || bytes.HasPrefix(payload, []byte("http://ns.adobe.com/xap/1.0/"))
+}
+
+// exifOrientation reads the orientation (1-8) from an EXIF APP1 segment; 1,
+// upright, when it has none
+func exifOrientation(seg jpegSegment) int {
+	if len(seg.data) < 10 || !bytes.HasPrefix(seg.data[4:], []byte("Exif\x00\x00")) {
+		return 1
+	}
+	tiff := seg.data[10:]
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orientImage turns an image stored with an EXIF orientation upright
+func orientImage(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// stripImageMetadata removes EXIF and XMP from a JPEG so no camera metadata
+// (GPS, serials) is stored with the evidence. A JPEG stored rotated or
+// mirrored is decoded, turned upright and re-encoded, since the orientation
+// tag that made viewers display it correctly goes with the EXIF; otherwise the
+// segments are dropped without touching the image data. Anything that isn't
+// a JPEG, or can't be processed, is returned unchanged.
+func stripImageMetadata(data []byte, filename string) []byte {
+	segments, rest, ok := jpegSegments(data)
+	if !ok {
+		return data
+	}
+
+	orientation := 1
+	stripped := false
+	kept := make([]jpegSegment, 0, len(segments))
+	for _, seg := range segments {
+		if isMetadataSegment(seg) {
+			if o := exifOrientation(seg); o != 1 {
+				orientation = o
+			}
+			stripped = true
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	if !stripped {
+		return data
+	}
+
+	if orientation != 1 {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			log.Printf("⚠️ [EVENT_INGEST] Storing %s as received, can't decode it to orient: %v", filename, err)
+			return data
+		}
+		// Go's encoder writes no metadata, so the result is stripped too
+		var out bytes.Buffer
+		if err := jpeg.Encode(&out, orientImage(img, orientation), &jpeg.Options{Quality: imageFormats.jpegQuality}); err != nil {
+			log.Printf("⚠️ [EVENT_INGEST] Storing %s as received, re-encoding failed: %v", filename, err)
+			return data
+		}
+		return out.Bytes()
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, jpegSOI)
+	for _, seg := range kept {
+		out = append(out, seg.data...)
+	}
+	return append(out, rest...)
+}
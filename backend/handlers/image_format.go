@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+)
+
+const defaultArchiveJPEGQuality = 90
+
+// acceptedImageFormats are the upload formats the ingest can decode
+var acceptedImageFormats = []string{"jpeg", "png", "webp", "gif"}
+
+// imageFormatContentTypes maps the supported image formats to their sniffed content type
+var imageFormatContentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+	"gif":  "image/gif",
+}
+
+// imageFormatExtensions is the file extension each archive format is stored with
+var imageFormatExtensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+}
+
+// imageFormats is the format negotiation advertised to workers and applied at ingest
+var imageFormats = struct {
+	transport   []string // preferred upload formats, most preferred first
+	archive     string   // format evidence is stored in; empty = as received
+	jpegQuality int
+}{
+	transport:   []string{"jpeg"},
+	jpegQuality: defaultArchiveJPEGQuality,
+}
+
+// normalizeImageFormat maps format aliases (jpg) to their canonical name
+func normalizeImageFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "jpg" {
+		return "jpeg"
+	}
+	return format
+}
+
+// InitImageFormats reads IMAGE_TRANSPORT_FORMATS (comma-separated, most preferred
+// first, default jpeg), IMAGE_ARCHIVE_FORMAT (jpeg or png; unset stores images as
+// received) and IMAGE_ARCHIVE_JPEG_QUALITY (default 90). Returns the archive format.
+func InitImageFormats() string {
+	imageFormats.transport = []string{"jpeg"}
+	if v := os.Getenv("IMAGE_TRANSPORT_FORMATS"); v != "" {
+		var formats []string
+		for _, f := range strings.Split(v, ",") {
+			f = normalizeImageFormat(f)
+			if _, ok := imageFormatContentTypes[f]; ok {
+				formats = append(formats, f)
+			} else if f != "" {
+				log.Printf("⚠️ Ignoring unsupported image transport format %q", f)
+			}
+		}
+		if len(formats) > 0 {
+			imageFormats.transport = formats
+		}
+	}
+
+	imageFormats.archive = ""
+	if v := normalizeImageFormat(os.Getenv("IMAGE_ARCHIVE_FORMAT")); v != "" {
+		if _, ok := imageFormatExtensions[v]; ok {
+			imageFormats.archive = v
+		} else {
+			log.Printf("⚠️ Invalid IMAGE_ARCHIVE_FORMAT %q, storing images as received", v)
+		}
+	}
+
+	imageFormats.jpegQuality = defaultArchiveJPEGQuality
+	if v := os.Getenv("IMAGE_ARCHIVE_JPEG_QUALITY"); v != "" {
+		if q, err := strconv.Atoi(v); err == nil && q >= 1 && q <= 100 {
+			imageFormats.jpegQuality = q
+		}
+	}
+
+	return imageFormats.archive
+}
+
+// imageFormatConfig is the image section of the worker config
+func imageFormatConfig() map[string]interface{} {
+	archive := imageFormats.archive
+	if archive == "" {
+		archive = "original"
+	}
+	return map[string]interface{}{
+		"preferred": imageFormats.transport,
+		"accepted":  acceptedImageFormats,
+		"archive":   archive,
+	}
+}
+
+// archiveImage converts an uploaded image to its device's archive format. It
+// returns the reader to store and the storage filename, which gets the archive
+// format's extension when the image was transcoded. Images already in the
+// archive format, or that can't be decoded, are stored unchanged apart from
+// JPEG metadata stripping, unless the device sets its own JPEG quality.
+func archiveImage(src io.Reader, filename, deviceID string) (io.Reader, string, error) {
+	if imageMetadataStripping {
+		data, err := io.ReadAll(src)
+		if err != nil {
+			return nil, filename, err
+		}
+		src = bytes.NewReader(stripImageMetadata(data, filename))
+	}
+	settings := deviceArchiveSettings(deviceID)
+	if settings.format == "" {
+		return src, filename, nil
+	}
+
+	buffered := bufio.NewReader(src)
+	head, _ := buffered.Peek(512)
+	if !settings.reencode && http.DetectContentType(head) == imageFormatContentTypes[settings.format] {
+		return buffered, filename, nil
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, filename, err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Storing %s as received, can't decode it: %v", filename, err)
+		return bytes.NewReader(data), filename, nil
+	}
+
+	var out bytes.Buffer
+	switch settings.format {
+	case "jpeg":
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: settings.quality})
+	case "png":
+		err = png.Encode(&out, img)
+	}
+	if err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Storing %s as received, transcoding %s failed: %v", filename, format, err)
+		return bytes.NewReader(data), filename, nil
+	}
+
+	ext := imageFormatExtensions[settings.format]
+	return &out, strings.TrimSuffix(filename, filepath.Ext(filename)) + ext, nil
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// imagePolicyConfigKey is the device config key holding its image storage policy
+const imagePolicyConfigKey = "imageStorage"
+
+const imagePolicyRefreshInterval = time.Minute
+
+// ImageStoragePolicy - How a device's images are stored, overriding the
+// global image settings. Violation evidence is always stored.
+type ImageStoragePolicy struct {
+	Store         *bool  `json:"store,omitempty"`         // false = images of other events are dropped; unset = stored
+	Format        string `json:"format,omitempty"`        // jpeg, png or original; empty = IMAGE_ARCHIVE_FORMAT
+	Quality       int    `json:"quality,omitempty"`       // JPEG quality 1-100; 0 = IMAGE_ARCHIVE_JPEG_QUALITY
+	RetentionDays int    `json:"retentionDays,omitempty"` // detection image retention; 0 = DETECTION_IMAGE_RETENTION_HOURS
+}
+
+// imagePolicies caches the image storage policy of every device that has one,
+// so ingest doesn't load the device before saving its images. It's reloaded
+// when a policy changes and periodically, to pick up changes made elsewhere.
+var imagePolicies = struct {
+	mu      sync.RWMutex
+	devices map[string]ImageStoragePolicy
+}{}
+
+// StartImagePolicies loads the per-device image storage policies and reloads
+// them every minute. Returns how many devices have one.
+func StartImagePolicies() int {
+	reloadImagePolicies()
+	go func() {
+		ticker := time.NewTicker(imagePolicyRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reloadImagePolicies()
+		}
+	}()
+
+	imagePolicies.mu.RLock()
+	defer imagePolicies.mu.RUnlock()
+	return len(imagePolicies.devices)
+}
+
+// decodeImagePolicy reads the image storage policy from a device config
+func decodeImagePolicy(config models.JSONB) (ImageStoragePolicy, bool) {
+	var policy ImageStoragePolicy
+	cfg, ok := config.Data.(map[string]interface{})
+	if !ok || cfg[imagePolicyConfigKey] == nil {
+		return policy, false
+	}
+	raw, err := json.Marshal(cfg[imagePolicyConfigKey])
+	if err != nil || json.Unmarshal(raw, &policy) != nil {
+		return policy, false
+	}
+	return policy, true
+}
+
+// reloadImagePolicies loads the devices whose config has an image storage policy
+func reloadImagePolicies() {
+	var devices []models.Device
+	if err := database.DB.Select("id, config").
+		Where("jsonb_exists(config, ?)", imagePolicyConfigKey).
+		Find(&devices).Error; err != nil {
+		log.Printf("⚠️ [STORAGE] Failed to load image storage policies: %v", err)
+		return
+	}
+
+	policies := make(map[string]ImageStoragePolicy, len(devices))
+	for _, d := range devices {
+		if policy, ok := decodeImagePolicy(d.Config); ok {
+			policies[d.ID] = policy
+		}
+	}
+
+	imagePolicies.mu.Lock()
+	imagePolicies.devices = policies
+	imagePolicies.mu.Unlock()
+}
+
+// deviceImagePolicy returns a device's image storage policy; the zero policy
+// follows the global settings
+func deviceImagePolicy(deviceID string) ImageStoragePolicy {
+	imagePolicies.mu.RLock()
+	defer imagePolicies.mu.RUnlock()
+	return imagePolicies.devices[deviceID]
+}
+
+// storesDetectionImages reports whether a policy keeps images of events
+// other than violations
+func (p ImageStoragePolicy) storesDetectionImages() bool {
+	return p.Store == nil || *p.Store
+}
+
+// storesEventImages reports whether the images uploaded with an event are
+// kept. Violation evidence always is, whatever the device's policy.
+func storesEventImages(event IngestEvent) bool {
+	return event.Type == "violation" || deviceImagePolicy(event.DeviceID).storesDetectionImages()
+}
+
+// imageArchiveSettings is how one device's images are archived
+type imageArchiveSettings struct {
+	format   string // jpeg or png; empty = as received
+	quality  int
+	reencode bool // re-encode images already in format, to apply a device's quality
+}
+
+// deviceArchiveSettings resolves the archive format and quality of a device's
+// images. A device quality without a format archives as JPEG.
+func deviceArchiveSettings(deviceID string) imageArchiveSettings {
+	settings := imageArchiveSettings{format: imageFormats.archive, quality: imageFormats.jpegQuality}
+	policy := deviceImagePolicy(deviceID)
+	switch policy.Format {
+	case "":
+	case "original":
+		settings.format = ""
+	default:
+		settings.format = policy.Format
+	}
+	if policy.Quality > 0 {
+		settings.quality = policy.Quality
+		if policy.Format == "" {
+			settings.format = "jpeg"
+		}
+		settings.reencode = settings.format == "jpeg"
+	}
+	return settings
+}
+
+// imageRetentionOverrides returns the detection image retention of every
+// device whose policy sets one
+func imageRetentionOverrides() map[string]time.Duration {
+	imagePolicies.mu.RLock()
+	defer imagePolicies.mu.RUnlock()
+	overrides := make(map[string]time.Duration)
+	for deviceID, policy := range imagePolicies.devices {
+		if policy.RetentionDays > 0 {
+			overrides[deviceID] = time.Duration(policy.RetentionDays) * 24 * time.Hour
+		}
+	}
+	return overrides
+}
+
+// validateImagePolicy checks a policy, normalizing its format
+func validateImagePolicy(policy *ImageStoragePolicy) error {
+	if policy.Format != "" && policy.Format != "original" {
+		policy.Format = normalizeImageFormat(policy.Format)
+		if _, ok := imageFormatExtensions[policy.Format]; !ok {
+			return fmt.Errorf("format must be jpeg, png or original")
+		}
+	}
+	if policy.Quality < 0 || policy.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if policy.Quality > 0 && policy.Format == "png" {
+		return fmt.Errorf("quality only applies to jpeg")
+	}
+	if policy.RetentionDays < 0 {
+		return fmt.Errorf("retentionDays must not be negative")
+	}
+	return nil
+}
+
+// setDeviceImagePolicy writes a policy into a device's config, or removes it
+// when policy is nil, and bumps the config version of the device's worker,
+// which is told whether to upload detection images
+func setDeviceImagePolicy(deviceID string, policy *ImageStoragePolicy) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var device models.Device
+		if err := tx.Select("id, config, worker_id").First(&device, "id = ?", deviceID).Error; err != nil {
+			return err
+		}
+		cfg, ok := device.Config.Data.(map[string]interface{})
+		if !ok {
+			cfg = map[string]interface{}{}
+		}
+		if policy == nil {
+			if _, had := cfg[imagePolicyConfigKey]; !had {
+				return gorm.ErrRecordNotFound
+			}
+			delete(cfg, imagePolicyConfigKey)
+		} else {
+			cfg[imagePolicyConfigKey] = policy
+		}
+		if err := tx.Model(&device).Update("config", models.NewJSONB(cfg)).Error; err != nil {
+			return err
+		}
+		if device.WorkerID != nil && *device.WorkerID != "" {
+			return bumpConfigVersion(tx, *device.WorkerID)
+		}
+		return nil
+	})
+	if err == nil {
+		reloadImagePolicies()
+	}
+	return err
+}
+
+// GetImageStoragePolicies lists the devices with their own image storage
+// policy, along with the global settings they override (admin)
+// GET /api/admin/storage/policies
+func GetImageStoragePolicies(c *gin.Context) {
+	var devices []models.Device
+	if err := database.DB.Select("id, name, config").
+		Where("jsonb_exists(config, ?)", imagePolicyConfigKey).
+		Order("id ASC").
+		Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image storage policies"})
+		return
+	}
+
+	policies := make([]gin.H, 0, len(devices))
+	for _, d := range devices {
+		policy, ok := decodeImagePolicy(d.Config)
+		if !ok {
+			continue
+		}
+		policies = append(policies, gin.H{
+			"deviceId": d.ID,
+			"name":     d.Name,
+			"policy":   policy,
+		})
+	}
+
+	archive := imageFormats.archive
+	if archive == "" {
+		archive = "original"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"defaults": gin.H{
+			"store":          true,
+			"format":         archive,
+			"quality":        imageFormats.jpegQuality,
+			"retentionHours": int(detectionRetention.maxAge / time.Hour), // 0 = kept until quota trimming
+		},
+		"devices": policies,
+	})
+}
+
+// SetImageStoragePolicy sets a device's image storage policy (admin)
+// PUT /api/admin/storage/policies/:deviceId
+func SetImageStoragePolicy(c *gin.Context) {
+	var policy ImageStoragePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateImagePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deviceID := c.Param("deviceId")
+	if err := setDeviceImagePolicy(deviceID, &policy); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image storage policy"})
+		return
+	}
+
+	log.Printf("📝 [STORAGE] Image storage policy for %s set to %+v", deviceID, policy)
+	c.JSON(http.StatusOK, gin.H{"deviceId": deviceID, "policy": policy})
+}
+
+// DeleteImageStoragePolicy removes a device's image storage policy so the
+// global settings apply (admin)
+// DELETE /api/admin/storage/policies/:deviceId
+func DeleteImageStoragePolicy(c *gin.Context) {
+	if err := setDeviceImagePolicy(c.Param("deviceId"), nil); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No image storage policy for device"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image storage policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image storage policy removed"})
+}
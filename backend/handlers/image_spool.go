@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const (
+	defaultImageRetryInterval    = 30 * time.Second
+	defaultImageRetryMaxAttempts = 10
+
+	// imageRetryBatch bounds how many spooled images one retry pass writes
+	imageRetryBatch = 50
+)
+
+// imageSpool holds the retry settings and counters for failed image saves
+var imageSpool = struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	maxAttempts int
+
+	failed    int64 // saves that failed at ingest
+	spooled   int64 // failed saves kept for retry
+	lost      int64 // failed saves that couldn't be spooled
+	recovered int64 // spooled images written on retry
+	exhausted int64 // spooled images that ran out of attempts
+}{
+	interval:    defaultImageRetryInterval,
+	maxAttempts: defaultImageRetryMaxAttempts,
+}
+
+// InitImageSpool reads IMAGE_RETRY_INTERVAL_SECONDS (default 30) and
+// IMAGE_RETRY_MAX_ATTEMPTS (default 10), starts the retry loop and returns the interval
+func InitImageSpool() time.Duration {
+	imageSpool.interval = defaultImageRetryInterval
+	if v := os.Getenv("IMAGE_RETRY_INTERVAL_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			imageSpool.interval = time.Duration(secs) * time.Second
+		}
+	}
+	imageSpool.maxAttempts = defaultImageRetryMaxAttempts
+	if v := os.Getenv("IMAGE_RETRY_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			imageSpool.maxAttempts = n
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(imageSpool.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			retrySpooledImages()
+		}
+	}()
+
+	return imageSpool.interval
+}
+
+// writeImageFile saves an uploaded image to path, creating its directory.
+// A partially written file is removed.
+func writeImageFile(path string, src io.Reader) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	dst, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return written, nil
+}
+
+// spoolImage keeps the bytes of an image whose save failed so it can be
+// written to path later. Returns false if the image couldn't be spooled and is lost.
+func spoolImage(event IngestEvent, key string, file *multipart.FileHeader, path, url string, saveErr error) bool {
+	imageSpool.mu.Lock()
+	imageSpool.failed++
+	imageSpool.mu.Unlock()
+
+	data, err := readUploadedFile(file, event.DeviceID)
+	if err == nil {
+		pending := models.PendingImage{
+			EventID:       event.ID,
+			DeviceID:      event.DeviceID,
+			WorkerID:      event.WorkerID,
+			EventType:     event.Type,
+			Key:           key,
+			Path:          path,
+			URL:           url,
+			Data:          data,
+			Bytes:         int64(len(data)),
+			Status:        "pending",
+			LastError:     saveErr.Error(),
+			NextAttemptAt: time.Now().Add(imageSpool.interval),
+		}
+		err = database.DB.Create(&pending).Error
+	}
+
+	imageSpool.mu.Lock()
+	defer imageSpool.mu.Unlock()
+	if err != nil {
+		imageSpool.lost++
+		log.Printf("❌ [EVENT_INGEST] Image lost - Key: %s, Event: %s, Save error: %v, Spool error: %v", key, event.ID, saveErr, err)
+		return false
+	}
+	imageSpool.spooled++
+	log.Printf("📥 [EVENT_INGEST] Image spooled for retry - Key: %s, Event: %s, Error: %v", key, event.ID, saveErr)
+	return true
+}
+
+// readUploadedFile reads a multipart upload as it will be archived for a device
+func readUploadedFile(file *multipart.FileHeader, deviceID string) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	archived, _, err := archiveImage(src, file.Filename, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(archived)
+}
+
+// retrySpooledImages writes due spooled images to their planned paths. Images
+// that keep failing are marked failed after the configured number of attempts
+// and kept for manual recovery.
+func retrySpooledImages() {
+	var pending []models.PendingImage
+	if err := database.DB.Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(imageRetryBatch).
+		Find(&pending).Error; err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Failed to load spooled images: %v", err)
+		return
+	}
+
+	for _, img := range pending {
+		written, err := writeImageFile(img.Path, bytes.NewReader(img.Data))
+		if err == nil {
+			recordStoredImage(IngestEvent{ID: img.EventID, DeviceID: img.DeviceID, WorkerID: img.WorkerID, Type: img.EventType}, img.Path, img.URL, written)
+			database.DB.Delete(&img)
+
+			imageSpool.mu.Lock()
+			imageSpool.recovered++
+			imageSpool.mu.Unlock()
+			log.Printf("💾 [EVENT_INGEST] Spooled image saved - Key: %s, Event: %s, Attempt: %d", img.Key, img.EventID, img.Attempts+1)
+			continue
+		}
+
+		img.Attempts++
+		img.LastError = err.Error()
+		if img.Attempts >= imageSpool.maxAttempts {
+			img.Status = "failed"
+			imageSpool.mu.Lock()
+			imageSpool.exhausted++
+			imageSpool.mu.Unlock()
+			log.Printf("❌ [EVENT_INGEST] Spooled image gave up after %d attempts - Key: %s, Event: %s, Error: %v", img.Attempts, img.Key, img.EventID, err)
+		} else {
+			// Back off linearly so a long outage doesn't hammer the disk
+			img.NextAttemptAt = time.Now().Add(time.Duration(img.Attempts+1) * imageSpool.interval)
+		}
+		if err := database.DB.Model(&img).Updates(map[string]interface{}{
+			"attempts":        img.Attempts,
+			"last_error":      img.LastError,
+			"status":          img.Status,
+			"next_attempt_at": img.NextAttemptAt,
+		}).Error; err != nil {
+			log.Printf("⚠️ [EVENT_INGEST] Failed to update spooled image %d: %v", img.ID, err)
+		}
+	}
+}
+
+// imageSpoolStats returns failed-save counters and the spool backlog
+func imageSpoolStats() gin.H {
+	var backlog []struct {
+		Status string
+		Count  int64
+		Bytes  int64
+	}
+	database.DB.Model(&models.PendingImage{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(bytes), 0) AS bytes").
+		Group("status").
+		Scan(&backlog)
+
+	stats := gin.H{
+		"pending":      int64(0),
+		"pendingBytes": int64(0),
+		"failedImages": int64(0),
+	}
+	for _, b := range backlog {
+		switch b.Status {
+		case "pending":
+			stats["pending"] = b.Count
+			stats["pendingBytes"] = b.Bytes
+		case "failed":
+			stats["failedImages"] = b.Count
+		}
+	}
+
+	imageSpool.mu.Lock()
+	defer imageSpool.mu.Unlock()
+	stats["saveFailures"] = imageSpool.failed
+	stats["spooled"] = imageSpool.spooled
+	stats["lost"] = imageSpool.lost
+	stats["recovered"] = imageSpool.recovered
+	stats["exhausted"] = imageSpool.exhausted
+	stats["retryInterval"] = imageSpool.interval.String()
+	stats["maxAttempts"] = imageSpool.maxAttempts
+	return stats
+}
@@ -24,11 +24,11 @@ func PostIngest(c *gin.Context) {
 		return
 	}
 
-	// Upsert device
+	// Upsert device; a new one is commissioned like one first seen at event ingest
 	device := models.Device{
 		ID:     req.DeviceID,
 		Type:   models.DeviceTypeCamera, // Default to camera
-		Status: "active",
+		Status: newDeviceStatus(),
 	}
 
 	// Extract lat/lng/metadata from data if available
@@ -94,6 +94,22 @@ func PostIngest(c *gin.Context) {
 		}
 	}
 
+	// Devices that aren't commissioned yet only produce test events
+	if req.Type != "camera_status" && deviceHeldForCommissioning(device.Status) {
+		data, _ := req.Data.Data.(map[string]interface{})
+		if err := processCommissioningTestEvent(database.DB, IngestEvent{
+			DeviceID:  req.DeviceID,
+			Type:      req.Type,
+			Data:      data,
+			Timestamp: &timestamp,
+		}, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest event"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"success": true, "commissioningTest": true})
+		return
+	}
+
 	event := models.Event{
 		DeviceID:  req.DeviceID,
 		Type:      req.Type,
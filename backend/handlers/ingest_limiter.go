@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// throttleRetryAfter is the Retry-After, in seconds, given for a throttled event
+const throttleRetryAfter = "1"
+
+// healthEventInterval limits how often a throttled device raises a health event
+const healthEventInterval = time.Minute
+
+// detectionEventTypes are the event types subject to the per-device rate cap.
+// Violations, status and alert events are never throttled.
+var detectionEventTypes = map[string]bool{
+	"anpr":             true,
+	"plate_detected":   true,
+	"vcc":              true,
+	"vehicle_detected": true,
+	"crowd":            true,
+	"crowd_density":    true,
+}
+
+// deviceRate tracks detections for one device in the current one-second window
+type deviceRate struct {
+	window      int64 // unix second
+	count       int
+	dropped     int64 // total dropped since startup
+	lastDropped time.Time
+	lastAlert   time.Time
+}
+
+// detectionLimiter is a per-device detections-per-second safety valve
+type detectionLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	devices map[string]*deviceRate
+}
+
+var ingestLimiter = newDetectionLimiter(0)
+
+func newDetectionLimiter(limit int) *detectionLimiter {
+	return &detectionLimiter{
+		limit:   limit,
+		devices: make(map[string]*deviceRate),
+	}
+}
+
+// InitIngestLimiter configures the per-device detection rate cap from
+// MAX_DETECTIONS_PER_SECOND (0 or unset = disabled) and returns the active limit.
+// Throttled events aren't acknowledged, so the edge resends them later.
+func InitIngestLimiter() int {
+	limit := 0
+	if v := os.Getenv("MAX_DETECTIONS_PER_SECOND"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			limit = parsed
+		}
+	}
+	ingestLimiter = newDetectionLimiter(limit)
+	return limit
+}
+
+// allow reports whether an event may be processed now. Excess detections are
+// counted and a device health event is raised (at most once per healthEventInterval).
+func (l *detectionLimiter) allow(event IngestEvent) bool {
+	if l.limit == 0 || !detectionEventTypes[event.Type] {
+		return true
+	}
+
+	now := time.Now()
+	sec := now.Unix()
+
+	l.mu.Lock()
+	rate, ok := l.devices[event.DeviceID]
+	if !ok {
+		rate = &deviceRate{}
+		l.devices[event.DeviceID] = rate
+	}
+	if rate.window != sec {
+		rate.window = sec
+		rate.count = 0
+	}
+	rate.count++
+	if rate.count <= l.limit {
+		l.mu.Unlock()
+		return true
+	}
+
+	rate.dropped++
+	rate.lastDropped = now
+	raiseAlert := now.Sub(rate.lastAlert) >= healthEventInterval
+	if raiseAlert {
+		rate.lastAlert = now
+	}
+	dropped := rate.dropped
+	l.mu.Unlock()
+
+	if raiseAlert {
+		log.Printf("⚠️ [EVENT_INGEST] Detection rate cap exceeded - Device: %s, Worker: %s, Limit: %d/s, Dropped: %d",
+			event.DeviceID, event.WorkerID, l.limit, dropped)
+		raiseRateLimitHealthEvent(event, l.limit, dropped)
+	}
+	return false
+}
+
+// raiseRateLimitHealthEvent records a device health event for a throttled device
+func raiseRateLimitHealthEvent(event IngestEvent, limit int, dropped int64) {
+	riskLevel := "high"
+	healthEvent := models.Event{
+		DeviceID:  event.DeviceID,
+		Timestamp: time.Now(),
+		Type:      "device_health",
+		Data: models.NewJSONB(map[string]interface{}{
+			"reason":        "detection_rate_exceeded",
+			"worker_id":     event.WorkerID,
+			"event_type":    event.Type,
+			"limit_per_sec": limit,
+			"dropped_total": dropped,
+		}),
+		RiskLevel: &riskLevel,
+	}
+	if err := database.DB.Create(&healthEvent).Error; err != nil {
+		log.Printf("⚠️ [EVENT_INGEST] Failed to record health event - Device: %s, Error: %v", event.DeviceID, err)
+	}
+}
+
+// stats returns the cap and per-device drop counts
+func (l *detectionLimiter) stats() gin.H {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var total int64
+	byDevice := make([]gin.H, 0)
+	for deviceID, rate := range l.devices {
+		if rate.dropped == 0 {
+			continue
+		}
+		total += rate.dropped
+		byDevice = append(byDevice, gin.H{
+			"deviceId":    deviceID,
+			"dropped":     rate.dropped,
+			"lastDropped": rate.lastDropped,
+		})
+	}
+
+	return gin.H{
+		"maxDetectionsPerSecond": l.limit,
+		"totalDropped":           total,
+		"byDevice":               byDevice,
+	}
+}
+
+// deviceStats returns the drop count of one device
+func (l *detectionLimiter) deviceStats(deviceID string) gin.H {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := gin.H{"maxDetectionsPerSecond": l.limit, "dropped": int64(0)}
+	if rate, ok := l.devices[deviceID]; ok && rate.dropped > 0 {
+		stats["dropped"] = rate.dropped
+		stats["lastDropped"] = rate.lastDropped
+	}
+	return stats
+}
+
+// GetIngestStats returns detection rate cap settings, drop counts, failed
+// image saves, dead-lettered events and out-of-order delivery
+// GET /api/events/ingest/stats
+func GetIngestStats(c *gin.Context) {
+	stats := ingestLimiter.stats()
+	stats["images"] = imageSpoolStats()
+	stats["deadLetter"] = deadLetterStats()
+	stats["ordering"] = eventOrdering.stats()
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultIngestLogSummaryInterval = time.Minute
+
+// chattyRouteSuffixes are the worker routes hit once per event batch or
+// heartbeat. Their access log lines are left out unless debug logging is on;
+// the periodic summary covers them instead.
+var chattyRouteSuffixes = []string{"/events/ingest", "/workers/:id/heartbeat", "/workers/heartbeat/batch", "/ingest"}
+
+// ingestTypeCounts is how one event type fared since the last summary
+type ingestTypeCounts struct {
+	processed int
+	failed    int
+	dropped   int
+}
+
+// ingestLogging rolls ingest and heartbeat activity up into a periodic log
+// summary, in place of a line per event. In debug mode every event and
+// request is logged as well.
+var ingestLogging = struct {
+	mu         sync.Mutex
+	debug      bool
+	interval   time.Duration
+	since      time.Time
+	types      map[string]*ingestTypeCounts
+	heartbeats int
+	rejected   int // heartbeat requests answered with an error
+}{interval: defaultIngestLogSummaryInterval, since: time.Now(), types: make(map[string]*ingestTypeCounts)}
+
+// InitIngestLogging reads INGEST_DEBUG_LOG (default false), which logs every
+// ingested event and worker request, and INGEST_LOG_SUMMARY_SECONDS (default
+// 60, 0 disables the summary). Returns both.
+func InitIngestLogging() (bool, time.Duration) {
+	ingestLogging.mu.Lock()
+	defer ingestLogging.mu.Unlock()
+	ingestLogging.debug = os.Getenv("INGEST_DEBUG_LOG") == "true"
+	ingestLogging.interval = defaultIngestLogSummaryInterval
+	if v := os.Getenv("INGEST_LOG_SUMMARY_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			ingestLogging.interval = time.Duration(secs) * time.Second
+		}
+	}
+	return ingestLogging.debug, ingestLogging.interval
+}
+
+// StartIngestLogSummary logs the rolled-up ingest activity every interval
+func StartIngestLogSummary() {
+	ingestLogging.mu.Lock()
+	interval := ingestLogging.interval
+	ingestLogging.since = time.Now()
+	ingestLogging.mu.Unlock()
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if summary := takeIngestSummary(); summary != "" {
+				log.Print(summary)
+			}
+		}
+	}()
+}
+
+// ingestDebugLogging reports whether per-event ingest lines are logged
+func ingestDebugLogging() bool {
+	ingestLogging.mu.Lock()
+	defer ingestLogging.mu.Unlock()
+	return ingestLogging.debug
+}
+
+// ingestDebugf logs a per-event or per-request ingest line, only in debug mode
+func ingestDebugf(format string, args ...interface{}) {
+	if ingestDebugLogging() {
+		log.Printf(format, args...)
+	}
+}
+
+// ingestTypeCountsLocked returns the counters of an event type; the caller
+// holds ingestLogging.mu
+func ingestTypeCountsLocked(eventType string) *ingestTypeCounts {
+	if eventType == "" {
+		eventType = "unknown"
+	}
+	counts, ok := ingestLogging.types[eventType]
+	if !ok {
+		counts = &ingestTypeCounts{}
+		ingestLogging.types[eventType] = counts
+	}
+	return counts
+}
+
+// recordIngestResult counts an event processed, or failed when err is set
+func recordIngestResult(eventType string, err error) {
+	ingestLogging.mu.Lock()
+	defer ingestLogging.mu.Unlock()
+	counts := ingestTypeCountsLocked(eventType)
+	if err != nil {
+		counts.failed++
+	} else {
+		counts.processed++
+	}
+}
+
+// recordIngestDropped counts an event dropped by the detection rate cap
+func recordIngestDropped(eventType string) {
+	ingestLogging.mu.Lock()
+	defer ingestLogging.mu.Unlock()
+	ingestTypeCountsLocked(eventType).dropped++
+}
+
+// takeIngestSummary formats the activity since the last summary and resets
+// the counters. Returns "" when nothing happened.
+func takeIngestSummary() string {
+	ingestLogging.mu.Lock()
+	types, heartbeats, rejected := ingestLogging.types, ingestLogging.heartbeats, ingestLogging.rejected
+	elapsed := time.Since(ingestLogging.since).Seconds()
+	ingestLogging.types = make(map[string]*ingestTypeCounts)
+	ingestLogging.heartbeats, ingestLogging.rejected = 0, 0
+	ingestLogging.since = time.Now()
+	ingestLogging.mu.Unlock()
+
+	if len(types) == 0 && heartbeats == 0 {
+		return ""
+	}
+	if elapsed <= 0 {
+		elapsed = 1
+	}
+
+	names := make([]string, 0, len(types))
+	total, failed, dropped := 0, 0, 0
+	for name, counts := range types {
+		names = append(names, name)
+		total += counts.processed + counts.failed
+		failed += counts.failed
+		dropped += counts.dropped
+	}
+	sort.Strings(names)
+
+	rates := make([]string, 0, len(names))
+	for _, name := range names {
+		counts := types[name]
+		rate := fmt.Sprintf("%s %.1f/s", name, float64(counts.processed+counts.failed)/elapsed)
+		if counts.failed > 0 {
+			rate += fmt.Sprintf(" (%d failed)", counts.failed)
+		}
+		if counts.dropped > 0 {
+			rate += fmt.Sprintf(" (%d dropped)", counts.dropped)
+		}
+		rates = append(rates, rate)
+	}
+	errorRate := 0.0
+	if total > 0 {
+		errorRate = float64(failed) / float64(total) * 100
+	}
+
+	return fmt.Sprintf("📊 [EVENT_INGEST] Last %s - Events: %d (%.1f/s) [%s], Errors: %d (%.1f%%), Dropped: %d, Heartbeats: %d (%d rejected)",
+		time.Duration(elapsed*float64(time.Second)).Round(time.Second), total, float64(total)/elapsed,
+		strings.Join(rates, ", "), failed, errorRate, dropped, heartbeats, rejected)
+}
+
+// isChattyRoute reports whether a route is hit per event batch or heartbeat
+func isChattyRoute(route string) bool {
+	for _, suffix := range chattyRouteSuffixes {
+		if strings.HasSuffix(route, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestLogger is gin's access log, except that event ingest and heartbeat
+// requests are only counted towards the ingest summary unless debug logging
+// is on. Failed requests to them are still logged.
+func RequestLogger() gin.HandlerFunc {
+	access := gin.Logger()
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		chatty := isChattyRoute(route)
+		if !chatty || ingestDebugLogging() {
+			access(c)
+		} else {
+			start := time.Now()
+			c.Next()
+			if status := c.Writer.Status(); status >= 400 {
+				log.Printf("⚠️ [EVENT_INGEST] %s %s - Status: %d, IP: %s, Duration: %v",
+					c.Request.Method, c.Request.URL.Path, status, c.ClientIP(), time.Since(start))
+			}
+		}
+
+		if chatty && strings.Contains(route, "heartbeat") {
+			ingestLogging.mu.Lock()
+			defer ingestLogging.mu.Unlock()
+			ingestLogging.heartbeats++
+			if c.Writer.Status() >= 400 {
+				ingestLogging.rejected++
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ndjsonContentType is the content type of a streamed ingest body, one
+	// event per line
+	ndjsonContentType = "application/x-ndjson"
+
+	defaultNDJSONMaxLineBytes = 1 << 20
+
+	// maxNDJSONFailures caps the per-line failures reported back, so a stream
+	// of bad lines doesn't grow the response without bound
+	maxNDJSONFailures = 1000
+)
+
+// ndjsonIngest is how streamed ingest bodies are accepted
+var ndjsonIngest = struct {
+	enabled      bool
+	maxLineBytes int
+}{enabled: true, maxLineBytes: defaultNDJSONMaxLineBytes}
+
+// errNDJSONLineTooLong marks a line longer than the configured maximum
+var errNDJSONLineTooLong = errors.New("line too long")
+
+// InitNDJSONIngest reads INGEST_NDJSON (false to disable) and
+// INGEST_NDJSON_MAX_LINE_BYTES (default 1MiB). Returns whether NDJSON bodies
+// are accepted and the longest line.
+func InitNDJSONIngest() (bool, int) {
+	ndjsonIngest.enabled = os.Getenv("INGEST_NDJSON") != "false"
+	ndjsonIngest.maxLineBytes = defaultNDJSONMaxLineBytes
+	if v := os.Getenv("INGEST_NDJSON_MAX_LINE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			ndjsonIngest.maxLineBytes = n
+		}
+	}
+	return ndjsonIngest.enabled, ndjsonIngest.maxLineBytes
+}
+
+// isNDJSON reports whether a content type is a streamed ingest body
+func isNDJSON(contentType string) bool {
+	return contentType == ndjsonContentType || contentType == "application/ndjson"
+}
+
+// NDJSONLineFailure - An NDJSON line that wasn't stored
+type NDJSONLineFailure struct {
+	Line    int    `json:"line"` // 1-based line number in the body
+	EventID string `json:"eventId,omitempty"`
+	Status  string `json:"status"` // invalid, dropped, dead_lettered or failed
+	Error   string `json:"error,omitempty"`
+}
+
+// readNDJSONLine reads the next line without its newline. Lines longer than
+// max are skipped through to their end and errNDJSONLineTooLong returned, so
+// the stream stays in step.
+func readNDJSONLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > max+1 {
+			line = nil
+			for err == bufio.ErrBufferFull {
+				_, err = r.ReadSlice('\n')
+			}
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			return nil, errNDJSONLineTooLong
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(line) > 0 {
+			err = nil
+		}
+		return bytes.TrimRight(line, "\r\n"), err
+	}
+}
+
+// ingestNDJSON processes a body of newline-delimited events as it arrives,
+// so memory stays bounded by the longest line rather than the body. Lines
+// are independent: a bad line is reported and the stream carries on.
+// POST /api/events/ingest (Content-Type: application/x-ndjson)
+func ingestNDJSON(c *gin.Context, workerID string, startTime time.Time) {
+	if !ndjsonIngest.enabled {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "NDJSON ingest is disabled"})
+		return
+	}
+
+	reader := bufio.NewReaderSize(c.Request.Body, 64<<10)
+	failures := []NDJSONLineFailure{}
+	failed := 0
+	fail := func(f NDJSONLineFailure) {
+		failed++
+		if len(failures) < maxNDJSONFailures {
+			failures = append(failures, f)
+		}
+	}
+
+	lineNo, total, processed, dropped, deadLettered := 0, 0, 0, 0, 0
+	var streamErr error
+	for {
+		line, err := readNDJSONLine(reader, ndjsonIngest.maxLineBytes)
+		if err == io.EOF {
+			break
+		}
+		lineNo++
+		if err == errNDJSONLineTooLong {
+			total++
+			fail(NDJSONLineFailure{Line: lineNo, Status: "invalid", Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			// The body broke off; lines already processed stay stored
+			streamErr = err
+			break
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		total++
+
+		var event IngestEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			fail(NDJSONLineFailure{Line: lineNo, Status: "invalid", Error: err.Error()})
+			continue
+		}
+		normalizeEvent(&event)
+
+		// Per-device detections-per-second safety valve
+		if !ingestLimiter.allow(event) {
+			recordIngestDropped(event.Type)
+			eventOrdering.pass(event)
+			dropped++
+			fail(NDJSONLineFailure{Line: lineNo, EventID: event.ID, Status: "dropped"})
+			continue
+		}
+
+		err = processEvent(event, nil)
+		recordIngestResult(event.Type, err)
+		if err != nil {
+			ingestDebugf("⚠️ [EVENT_INGEST] Failed to process event - WorkerID: %s, EventID: %s, Type: %s, Error: %v",
+				workerID, event.ID, event.Type, err)
+			status := "failed"
+			if deadLetterEvent(event, nil, err) {
+				deadLettered++
+				status = "dead_lettered"
+			}
+			fail(NDJSONLineFailure{Line: lineNo, EventID: event.ID, Status: status, Error: err.Error()})
+			continue
+		}
+		processed++
+	}
+
+	// Per-line failures are in the debug log; the stream line is enough otherwise
+	logStream := ingestDebugf
+	if failed > dropped {
+		logStream = log.Printf
+	}
+	logStream("✅ [EVENT_INGEST] NDJSON stream processed - WorkerID: %s, Processed: %d/%d, Dropped: %d, Failed: %d, Duration: %v",
+		workerID, processed, total, dropped, failed-dropped, time.Since(startTime))
+
+	resp := gin.H{
+		"status":       "ok",
+		"processed":    processed,
+		"dropped":      dropped,
+		"deadLettered": deadLettered,
+		"failed":       failed,
+		"total":        total,
+		"failures":     failures, // Lines not listed were stored
+	}
+	if failed > len(failures) {
+		resp["failuresTruncated"] = true
+	}
+	if streamErr != nil {
+		log.Printf("❌ [EVENT_INGEST] NDJSON stream broke off after line %d - WorkerID: %s, Error: %v", lineNo, workerID, streamErr)
+		resp["status"] = "incomplete"
+		resp["error"] = streamErr.Error()
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaintenanceRetryAfter = 60 * time.Second
+	defaultMaintenanceMessage    = "The backend is under maintenance"
+)
+
+// maintenanceExemptRoutes keep working in maintenance mode despite not being
+// reads: switching maintenance off, signing in, and signing image URLs. Keyed
+// on the unversioned path.
+var maintenanceExemptRoutes = map[string]bool{
+	"/api/admin/maintenance": true,
+	"/api/login":             true,
+	"/api/images/sign":       true,
+}
+
+// maintenanceState is the backend's maintenance mode. It's kept in memory
+// rather than in the settings table so it works while the database is down.
+type maintenanceState struct {
+	mu         sync.RWMutex
+	enabled    bool
+	message    string
+	since      time.Time
+	setBy      string
+	retryAfter time.Duration
+}
+
+var maintenance = &maintenanceState{retryAfter: defaultMaintenanceRetryAfter}
+
+// snapshot returns the current maintenance state
+func (m *maintenanceState) snapshot() gin.H {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state := gin.H{
+		"enabled":    m.enabled,
+		"retryAfter": int(m.retryAfter.Seconds()),
+	}
+	if m.enabled {
+		state["message"] = m.message
+		state["since"] = m.since
+		state["setBy"] = m.setBy
+	}
+	return state
+}
+
+// set turns maintenance mode on or off
+func (m *maintenanceState) set(enabled bool, message, setBy string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if enabled && !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = enabled
+	m.message = message
+	m.setBy = setBy
+}
+
+// InitMaintenance reads MAINTENANCE_MODE (start in maintenance when true),
+// MAINTENANCE_MESSAGE and MAINTENANCE_RETRY_AFTER_SECONDS (default 60).
+// Returns whether the backend starts in maintenance mode.
+func InitMaintenance() bool {
+	maintenance.retryAfter = defaultMaintenanceRetryAfter
+	if v := os.Getenv("MAINTENANCE_RETRY_AFTER_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			maintenance.retryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	enabled := os.Getenv("MAINTENANCE_MODE") == "true"
+	maintenance.set(enabled, os.Getenv("MAINTENANCE_MESSAGE"), "env")
+	return enabled
+}
+
+// MaintenanceMiddleware rejects writes with 503 and Retry-After while the
+// backend is in maintenance, so workers keep their events queued and retry
+// instead of dropping them. Reads still work and carry the maintenance
+// message in X-Maintenance-Mode so the UI can show it.
+func MaintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maintenance.mu.RLock()
+		enabled, message, retryAfter := maintenance.enabled, maintenance.message, maintenance.retryAfter
+		maintenance.mu.RUnlock()
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		c.Header("X-Maintenance-Mode", message)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if maintenanceExemptRoutes[unversionedAPIPath(c.FullPath())] {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":       message,
+			"maintenance": true,
+			"retryAfter":  int(retryAfter.Seconds()),
+		})
+	}
+}
+
+// GetMaintenance returns the maintenance mode state (admin)
+// GET /api/admin/maintenance
+func GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, maintenance.snapshot())
+}
+
+// SetMaintenance turns maintenance mode on or off (admin)
+// PUT /api/admin/maintenance
+func SetMaintenance(c *gin.Context) {
+	var req struct {
+		Enabled   *bool  `json:"enabled" binding:"required"`
+		Message   string `json:"message"`
+		ChangedBy string `json:"changedBy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	maintenance.set(*req.Enabled, req.Message, req.ChangedBy)
+	log.Printf("🚧 [MAINTENANCE] Enabled=%v (set by %s)", *req.Enabled, req.ChangedBy)
+	c.JSON(http.StatusOK, maintenance.snapshot())
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const (
+	defaultMLSampleLimit = 100
+	maxMLSampleLimit     = 5000
+	defaultMLSampleRange = 30 * 24 * time.Hour
+)
+
+// mlSampleSource is a table detections can be sampled from, with the query
+// names of the columns a sample may be stratified by
+type mlSampleSource struct {
+	table  string
+	strata map[string]string
+}
+
+// mlSampleSources maps the type param to its source
+var mlSampleSources = map[string]mlSampleSource{
+	"anpr": {
+		table: "vehicle_detections",
+		strata: map[string]string{
+			"vehicleType": "vehicle_type",
+			"deviceId":    "device_id",
+			"direction":   "direction",
+			"color":       "color",
+		},
+	},
+	"violation": {
+		table: "traffic_violations",
+		strata: map[string]string{
+			"violationType": "violation_type",
+			"deviceId":      "device_id",
+			"status":        "status",
+		},
+	},
+}
+
+// MLSample - One sampled detection with its labels and image URLs, signed
+// for requesters allowed to view images
+type MLSample struct {
+	ID        int64                  `json:"id"`
+	DeviceID  string                 `json:"deviceId"`
+	Timestamp time.Time              `json:"timestamp"`
+	Stratum   *string                `json:"stratum,omitempty"`
+	Labels    map[string]interface{} `json:"labels"`
+	Images    map[string]string      `json:"images"`
+	Metadata  models.JSONB           `json:"metadata,omitempty"`
+}
+
+// sampledRow is a sampled ID and the stratum it was drawn from
+type sampledRow struct {
+	ID      int64
+	Stratum string
+}
+
+// stratumCount is the population of one stratum in the sampled range
+type stratumCount struct {
+	Stratum string
+	Count   int64
+}
+
+// addSampleImage adds an image URL to a sample if the image exists
+func addSampleImage(images map[string]string, name string, url *string) {
+	if url != nil && *url != "" {
+		images[name] = *url
+	}
+}
+
+// detectionSamples loads sampled vehicle detections, keyed by ID, with their
+// plates masked unless plates are visible to the requester
+func detectionSamples(ids []int64, plates bool) (map[int64]MLSample, error) {
+	var detections []models.VehicleDetection
+	if err := database.DB.Where("id IN ?", ids).Find(&detections).Error; err != nil {
+		return nil, err
+	}
+	samples := make(map[int64]MLSample, len(detections))
+	for _, d := range detections {
+		if !plates {
+			maskPlatePtr(d.PlateNumber)
+		}
+		sample := MLSample{
+			ID:        d.ID,
+			DeviceID:  d.DeviceID,
+			Timestamp: d.Timestamp,
+			Labels: map[string]interface{}{
+				"plateNumber":     d.PlateNumber,
+				"plateConfidence": d.PlateConfidence,
+				"vehicleType":     d.VehicleType,
+				"make":            d.Make,
+				"model":           d.Model,
+				"color":           d.Color,
+				"direction":       d.Direction,
+				"confidence":      d.Confidence,
+			},
+			Images:   map[string]string{},
+			Metadata: d.Metadata,
+		}
+		addSampleImage(sample.Images, "frame", d.FullImageURL)
+		addSampleImage(sample.Images, "plate", d.PlateImageURL)
+		addSampleImage(sample.Images, "vehicle", d.VehicleImageURL)
+		samples[d.ID] = sample
+	}
+	return samples, nil
+}
+
+// violationSamples loads sampled violations, keyed by ID, with their plates
+// masked unless plates are visible to the requester
+func violationSamples(ids []int64, plates bool) (map[int64]MLSample, error) {
+	var violations []models.TrafficViolation
+	if err := database.DB.Where("id IN ?", ids).Find(&violations).Error; err != nil {
+		return nil, err
+	}
+	samples := make(map[int64]MLSample, len(violations))
+	for _, v := range violations {
+		if !plates {
+			maskPlatePtr(v.PlateNumber)
+		}
+		sample := MLSample{
+			ID:        v.ID,
+			DeviceID:  v.DeviceID,
+			Timestamp: v.Timestamp,
+			Labels: map[string]interface{}{
+				"violationType":   v.ViolationType,
+				"status":          v.Status,
+				"plateNumber":     v.PlateNumber,
+				"plateConfidence": v.PlateConfidence,
+				"detectedSpeed":   v.DetectedSpeed,
+				"confidence":      v.Confidence,
+			},
+			Images:   map[string]string{},
+			Metadata: v.Metadata,
+		}
+		addSampleImage(sample.Images, "frame", v.FullSnapshotURL)
+		for i, img := range v.PlateImageList() {
+			name := "plate"
+			if i > 0 {
+				name = fmt.Sprintf("plate_%d", i+1)
+			}
+			addSampleImage(sample.Images, name, &img.URL)
+		}
+		samples[v.ID] = sample
+	}
+	return samples, nil
+}
+
+// GetMLSample handles GET /api/admin/ml/sample - Draw a random sample of
+// detections for building a retraining set (admin)
+// Query: type (anpr|violation), from, to (default last 30 days; startTime
+// and endTime are accepted too), deviceId, limit (default 100, max 5000),
+// stratifyBy, seed.
+//
+// With stratifyBy the sample is balanced across the strata: each stratum
+// contributes in turn, so rare classes are as well represented as the data
+// allows. Sampling is a hash of the row ID and the seed, so the same seed and
+// filters return the same sample; the seed used is always returned.
+func GetMLSample(c *gin.Context) {
+	sampleType := c.DefaultQuery("type", "anpr")
+	source, ok := mlSampleSources[sampleType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be anpr or violation"})
+		return
+	}
+
+	startParam, endParam := "from", "to"
+	if c.Query("from") == "" && c.Query("to") == "" {
+		startParam, endParam = "startTime", "endTime"
+	} else if c.Query("startTime") != "" || c.Query("endTime") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "use from/to or startTime/endTime, not both"})
+		return
+	}
+	startTime, endTime, err := parseTimeRangeParams(c, startParam, endParam, defaultMLSampleRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := defaultMLSampleLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxMLSampleLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxMLSampleLimit)})
+			return
+		}
+	}
+
+	stratifyBy := c.Query("stratifyBy")
+	stratumColumn, partition := "''", ""
+	if stratifyBy != "" {
+		column, ok := source.strata[stratifyBy]
+		if !ok {
+			var names []string
+			for name := range source.strata {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("stratifyBy for %s must be one of: %s", sampleType, strings.Join(names, ", "))})
+			return
+		}
+		stratumColumn = fmt.Sprintf("COALESCE(CAST(%s AS text), '')", column)
+		partition = "PARTITION BY " + stratumColumn + " "
+	}
+
+	seed := rand.Int63()
+	if v := c.Query("seed"); v != "" {
+		seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seed must be an integer"})
+			return
+		}
+	}
+
+	where := "timestamp >= ? AND timestamp < ?"
+	args := []interface{}{startTime, endTime}
+	if deviceID := c.Query("deviceId"); deviceID != "" {
+		where += " AND device_id = ?"
+		args = append(args, deviceID)
+	}
+
+	var population []stratumCount
+	if err := database.DB.Raw(fmt.Sprintf(
+		"SELECT %s AS stratum, COUNT(*) AS count FROM %s WHERE %s GROUP BY 1 ORDER BY 1",
+		stratumColumn, source.table, where), args...).Scan(&population).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count detections"})
+		return
+	}
+
+	// Rows are ranked within their stratum by hash, then taken rank by rank
+	// across strata
+	hash := "md5(CAST(id AS text) || ':' || CAST(? AS text))"
+	var rows []sampledRow
+	if err := database.DB.Raw(fmt.Sprintf(
+		`SELECT id, stratum FROM (
+			SELECT id, %[1]s AS stratum, %[2]s AS h,
+				ROW_NUMBER() OVER (%[3]sORDER BY %[2]s) AS rn
+			FROM %[4]s WHERE %[5]s
+		) s ORDER BY rn, h LIMIT ?`,
+		stratumColumn, hash, partition, source.table, where),
+		append(append([]interface{}{seed, seed}, args...), limit)...).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sample detections"})
+		return
+	}
+
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var byID map[int64]MLSample
+	if sampleType == "violation" {
+		byID, err = violationSamples(ids, platesVisible(c))
+	} else {
+		byID, err = detectionSamples(ids, platesVisible(c))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sampled detections"})
+		return
+	}
+
+	signImages := listImagesSigned(c)
+	samples := make([]MLSample, 0, len(rows))
+	sampled := map[string]int{}
+	for _, row := range rows {
+		sample, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		if stratifyBy != "" {
+			stratum := row.Stratum
+			sample.Stratum = &stratum
+			sampled[stratum]++
+		}
+		if signImages {
+			for name, url := range sample.Images {
+				sample.Images[name] = signedImageURL(url)
+			}
+		}
+		samples = append(samples, sample)
+	}
+
+	response := gin.H{
+		"type":      sampleType,
+		"seed":      strconv.FormatInt(seed, 10),
+		"startTime": startTime,
+		"endTime":   endTime,
+		"limit":     limit,
+		"count":     len(samples),
+		"samples":   samples,
+	}
+	if stratifyBy != "" {
+		strata := make([]gin.H, 0, len(population))
+		for _, p := range population {
+			strata = append(strata, gin.H{"stratum": p.Stratum, "population": p.Count, "sampled": sampled[p.Stratum]})
+		}
+		response["stratifyBy"] = stratifyBy
+		response["strata"] = strata
+	} else {
+		var total int64
+		for _, p := range population {
+			total += p.Count
+		}
+		response["population"] = total
+	}
+
+	c.JSON(http.StatusOK, response)
+}
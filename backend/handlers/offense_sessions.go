@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultOffenseSessionWindow = 30 * time.Second
+
+// offenseSessionWindow is how close together violations of one vehicle on one
+// device must be to form an offense session; 0 disables grouping
+var offenseSessionWindow = defaultOffenseSessionWindow
+
+// InitOffenseSessions reads OFFENSE_SESSION_WINDOW_SECONDS (default 30, 0
+// disables grouping) and returns the window
+func InitOffenseSessions() time.Duration {
+	offenseSessionWindow = defaultOffenseSessionWindow
+	if v := os.Getenv("OFFENSE_SESSION_WINDOW_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			offenseSessionWindow = time.Duration(secs) * time.Second
+		}
+	}
+	return offenseSessionWindow
+}
+
+// sameOffender scopes a query on violations or sessions to the vehicle of a
+// violation on its device: by vehicle when it's known, else by plate
+func sameOffender(db *gorm.DB, violation *models.TrafficViolation) *gorm.DB {
+	db = db.Where("device_id = ?", violation.DeviceID)
+	if violation.VehicleID != nil {
+		return db.Where("vehicle_id = ?", *violation.VehicleID)
+	}
+	return db.Where("plate_number = ?", *violation.PlateNumber)
+}
+
+// groupOffenseSession adds a newly stored violation to the pending offense
+// session of its vehicle on the device, or opens one with the vehicle's other
+// ungrouped violations within the window. A lone violation stays ungrouped.
+// Violations without a plate can't be told apart and are never grouped.
+func groupOffenseSession(db *gorm.DB, violation *models.TrafficViolation) {
+	if offenseSessionWindow <= 0 || (violation.VehicleID == nil && (violation.PlateNumber == nil || *violation.PlateNumber == "")) {
+		return
+	}
+
+	var offender string
+	if violation.VehicleID != nil {
+		offender = fmt.Sprintf("vehicle:%d", *violation.VehicleID)
+	} else {
+		offender = "plate:" + *violation.PlateNumber
+	}
+	from := violation.Timestamp.Add(-offenseSessionWindow)
+	to := violation.Timestamp.Add(offenseSessionWindow)
+
+	var sessionID int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Violations of one pass arrive together; serialize them so they
+		// don't each open a session
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "offense_session:"+violation.DeviceID+":"+offender).Error; err != nil {
+			return err
+		}
+
+		var session models.OffenseSession
+		err := sameOffender(tx, violation).
+			Where("status = ? AND ended_at >= ? AND started_at <= ?", models.ViolationPending, from, to).
+			Order("ended_at DESC").
+			First(&session).Error
+		if err == nil {
+			if err := tx.Model(&session).Updates(map[string]interface{}{
+				"started_at":      gorm.Expr("LEAST(started_at, ?)", violation.Timestamp),
+				"ended_at":        gorm.Expr("GREATEST(ended_at, ?)", violation.Timestamp),
+				"violation_count": gorm.Expr("violation_count + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			sessionID = session.ID
+			return tx.Model(violation).Update("offense_session_id", session.ID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var others []models.TrafficViolation
+		if err := sameOffender(tx.Model(&models.TrafficViolation{}), violation).
+			Select("id, timestamp").
+			Where("offense_session_id IS NULL AND id <> ? AND timestamp >= ? AND timestamp <= ?", violation.ID, from, to).
+			Find(&others).Error; err != nil {
+			return err
+		}
+		if len(others) == 0 {
+			return nil
+		}
+
+		session = models.OffenseSession{
+			DeviceID:       violation.DeviceID,
+			VehicleID:      violation.VehicleID,
+			PlateNumber:    violation.PlateNumber,
+			StartedAt:      violation.Timestamp,
+			EndedAt:        violation.Timestamp,
+			ViolationCount: len(others) + 1,
+			Status:         models.ViolationPending,
+		}
+		ids := []int64{violation.ID}
+		for _, o := range others {
+			ids = append(ids, o.ID)
+			if o.Timestamp.Before(session.StartedAt) {
+				session.StartedAt = o.Timestamp
+			}
+			if o.Timestamp.After(session.EndedAt) {
+				session.EndedAt = o.Timestamp
+			}
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		sessionID = session.ID
+		return tx.Model(&models.TrafficViolation{}).Where("id IN ?", ids).Update("offense_session_id", session.ID).Error
+	})
+	if err != nil {
+		log.Printf("⚠️ [OFFENSE_SESSION] Failed to group violation %d: %v", violation.ID, err)
+		return
+	}
+	if sessionID != 0 {
+		violation.OffenseSessionID = &sessionID
+		ingestDebugf("🧾 [OFFENSE_SESSION] Violation %d grouped into session %d", violation.ID, sessionID)
+	}
+}
+
+// GetOffenseSessions handles GET /api/offense-sessions - List offense sessions
+func GetOffenseSessions(c *gin.Context) {
+	query := database.DB.Model(&models.OffenseSession{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", strings.ToUpper(status))
+	}
+	if deviceID := c.Query("deviceId"); deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if plateNumber := c.Query("plateNumber"); plateNumber != "" {
+		query = query.Where("plate_number ILIKE ?", "%"+plateNumber+"%")
+	}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !startTime.IsZero() {
+		query = query.Where("started_at >= ?", startTime)
+	}
+	if c.Query("endTime") != "" {
+		query = query.Where("started_at <= ?", endTime)
+	}
+
+	limit := 50
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 && parsed <= 200 {
+		limit = parsed
+	}
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var sessions []models.OffenseSession
+	if err := query.Order("started_at DESC").Limit(limit).Offset(offset).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offense sessions"})
+		return
+	}
+
+	if !platesVisible(c) {
+		maskOffenseSessionPlates(sessions)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// loadOffenseSession loads a session with its violations, oldest first
+func loadOffenseSession(id int64) (*models.OffenseSession, error) {
+	var session models.OffenseSession
+	err := database.DB.Preload("Violations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("timestamp ASC")
+	}).First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetOffenseSession handles GET /api/offense-sessions/:id - A session with
+// its violations, for reviewing them as a unit
+func GetOffenseSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offense session ID"})
+		return
+	}
+
+	session, err := loadOffenseSession(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Offense session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offense session"})
+		return
+	}
+	signViolationImages(c, session.Violations)
+	if !platesVisible(c) {
+		maskOffenseSessionPlate(session)
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// TransitionOffenseSession handles POST /api/offense-sessions/:id/transition -
+// Move a session and each of its violations to a status, validated against
+// the violation workflow. Fining a session issues one combined fine: the
+// amount is kept on the session and its violations carry its reference.
+// Violations the workflow doesn't let move (e.g. already rejected on their
+// own) are left as they are and listed as skipped.
+func TransitionOffenseSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offense session ID"})
+		return
+	}
+
+	var req TransitionViolationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to := models.ViolationStatus(strings.ToUpper(req.Status))
+	if to == models.ViolationFined && (req.FineReference == nil || *req.FineReference == "") {
+		reference := fmt.Sprintf("SESSION-%d", id)
+		req.FineReference = &reference
+	}
+	updates, err := req.updates(to, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := loadOffenseSession(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Offense session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offense session"})
+		return
+	}
+	if session.Status == to {
+		// Already there - a retried request succeeds without changing anything
+		signViolationImages(c, session.Violations)
+		if !platesVisible(c) {
+			maskOffenseSessionPlate(session)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"session": session,
+			"moved":   []int64{},
+			"skipped": []gin.H{},
+		})
+		return
+	}
+	workflow := getViolationWorkflow()
+	if !workflow.allows(session.Status, to) {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s: %s -> %s", errIllegalTransition, session.Status, to)})
+		return
+	}
+
+	// Violations the workflow won't move from their status are left where they
+	// are; the rest move together with the session or not at all
+	movable := make([]models.TrafficViolation, 0, len(session.Violations))
+	skipped := make([]gin.H, 0)
+	for _, v := range session.Violations {
+		if workflow.allows(v.Status, to) {
+			movable = append(movable, v)
+		} else {
+			skipped = append(skipped, gin.H{"violationId": v.ID, "status": v.Status})
+		}
+	}
+	if len(movable) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("No violation of the session can move to %s", to), "skipped": skipped})
+		return
+	}
+
+	// The combined fine is split across the violations it covers, so each can
+	// be paid and together they add up to the session's amount
+	var fineShares []float64
+	if to == models.ViolationFined {
+		fine := session.FineAmount
+		if amount, ok := updates["fine_amount"].(float64); ok {
+			fine = &amount
+		}
+		if fine != nil {
+			fineShares = splitFine(*fine, len(movable))
+		}
+	}
+
+	moved := make([]int64, 0, len(movable))
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, v := range movable {
+			perViolation := make(map[string]interface{}, len(updates))
+			for k, val := range updates {
+				perViolation[k] = val
+			}
+			delete(perViolation, "fine_amount")
+			if fineShares != nil {
+				perViolation["fine_amount"] = fineShares[i]
+			}
+			if _, err := transitionViolationTx(tx, workflow, v.ID, to, perViolation); err != nil {
+				return fmt.Errorf("violation %d: %w", v.ID, err)
+			}
+			moved = append(moved, v.ID)
+		}
+
+		sessionUpdates := make(map[string]interface{}, len(updates)+1)
+		for k, v := range updates {
+			sessionUpdates[k] = v
+		}
+		sessionUpdates["status"] = to
+		result := tx.Model(&models.OffenseSession{}).
+			Where("id = ? AND status = ?", id, session.Status).
+			Updates(sessionUpdates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: session status changed concurrently", errIllegalTransition)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errIllegalTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("❌ [OFFENSE_SESSION] Failed to move session %d to %s: %v", id, to, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update offense session"})
+		return
+	}
+
+	if session, err = loadOffenseSession(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch offense session"})
+		return
+	}
+	signViolationImages(c, session.Violations)
+	if !platesVisible(c) {
+		maskOffenseSessionPlate(session)
+	}
+	log.Printf("🧾 [OFFENSE_SESSION] Session %d moved to %s (%d violations, %d skipped)", id, to, len(moved), len(skipped))
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"moved":   moved,
+		"skipped": skipped,
+	})
+}
+
+// splitFine divides a fine into n shares in whole cents that add up to it,
+// the remainder going to the first shares
+func splitFine(total float64, n int) []float64 {
+	cents := int64(math.Round(total * 100))
+	shares := make([]float64, n)
+	for i := range shares {
+		share := cents / int64(n)
+		if int64(i) < cents%int64(n) {
+			share++
+		}
+		shares[i] = float64(share) / 100
+	}
+	return shares
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// defaultOrphanGracePeriod is how long a worker may be silent before its cameras
+// are reported as orphaned. Short outages (reboots, flaky uplinks) stay below it.
+const defaultOrphanGracePeriod = 30 * time.Minute
+
+// orphanGracePeriod reads WORKER_ORPHAN_GRACE_MINUTES
+func orphanGracePeriod() time.Duration {
+	if v := os.Getenv("WORKER_ORPHAN_GRACE_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins > 0 {
+			return time.Duration(mins) * time.Minute
+		}
+	}
+	return defaultOrphanGracePeriod
+}
+
+// GetOrphanedCameras lists active camera assignments held by workers that have
+// been offline longer than the grace period (admin). Assignments are not revoked;
+// an operator decides whether to reassign them.
+// GET /api/admin/workers/orphaned-cameras
+func GetOrphanedCameras(c *gin.Context) {
+	grace := orphanGracePeriod()
+	if v := c.Query("graceMinutes"); v != "" {
+		mins, err := strconv.Atoi(v)
+		if err != nil || mins <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "graceMinutes must be a positive integer"})
+			return
+		}
+		grace = time.Duration(mins) * time.Minute
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-grace)
+
+	var workers []models.Worker
+	if err := database.DB.
+		Where("last_seen < ? AND status <> ?", cutoff, models.WorkerStatusPending).
+		Order("last_seen ASC").
+		Find(&workers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workers"})
+		return
+	}
+
+	result := make([]gin.H, 0)
+	total := 0
+	for _, w := range workers {
+		var assignments []models.WorkerCameraAssignment
+		database.DB.Preload("Device").Where("worker_id = ? AND is_active = true", w.ID).Find(&assignments)
+		if len(assignments) == 0 {
+			continue
+		}
+
+		cameras := make([]gin.H, 0, len(assignments))
+		for _, a := range assignments {
+			camera := gin.H{
+				"assignmentId": a.ID,
+				"deviceId":     a.DeviceID,
+				"analytics":    a.Analytics,
+			}
+			if a.Device != nil {
+				camera["name"] = a.Device.Name
+			}
+			cameras = append(cameras, camera)
+		}
+		total += len(cameras)
+
+		offline := now.Sub(w.LastSeen)
+		result = append(result, gin.H{
+			"workerId":       w.ID,
+			"workerName":     w.Name,
+			"status":         w.Status,
+			"lastSeen":       w.LastSeen,
+			"offlineSeconds": int64(offline.Seconds()),
+			"offlineFor":     offline.Round(time.Minute).String(),
+			"cameras":        cameras,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"graceMinutes": int(grace.Minutes()),
+		"workers":      result,
+		"totalCameras": total,
+	})
+}
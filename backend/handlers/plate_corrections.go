@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// settingPlateCorrectionEnabled switches learned OCR corrections on ingest on/off
+const settingPlateCorrectionEnabled = "plates.ocr_correction.enabled"
+
+const (
+	// defaultPlateCorrectionMinCount is how many times a substitution must be
+	// seen before it is applied
+	defaultPlateCorrectionMinCount = 3
+
+	defaultPlateLearnInterval = time.Hour
+
+	// maxCorrectionPositions caps how many characters of a plate are considered
+	// for substitution, to bound the search
+	maxCorrectionPositions = 6
+)
+
+// plateCorrectionDictionary is the in-memory copy of learned substitutions used on ingest
+type plateCorrectionDictionary struct {
+	mu       sync.RWMutex
+	enabled  bool
+	minCount int64
+	// deviceID ("" = all devices) -> raw char -> corrected chars, most frequent first
+	subs map[string]map[rune][]rune
+}
+
+var plateDictionary = &plateCorrectionDictionary{
+	minCount: defaultPlateCorrectionMinCount,
+	subs:     make(map[string]map[rune][]rune),
+}
+
+// cleanPlate uppercases a plate and strips spaces and dashes
+func cleanPlate(plate string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(plate)))
+}
+
+// NormalizePlate applies learned OCR corrections to a plate read by a device.
+// Plates that already look valid, or that no combination of learned
+// substitutions turns into a valid plate, are returned unchanged.
+func NormalizePlate(plate, deviceID string) string {
+	if plate == "" || !plateDictionary.isEnabled() || isValidPlate(plate) {
+		return plate
+	}
+
+	if corrected, ok := plateDictionary.correct(cleanPlate(plate), deviceID); ok {
+		log.Printf("🔤 [PLATE_CORRECTION] %s -> %s (device: %s)", logPlate(plate), logPlate(corrected), deviceID)
+		return corrected
+	}
+	return plate
+}
+
+func (d *plateCorrectionDictionary) isEnabled() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.enabled
+}
+
+// candidates returns the substitutions for a character, device-specific first
+func (d *plateCorrectionDictionary) candidates(deviceID string, ch rune) []rune {
+	var out []rune
+	if deviceID != "" {
+		out = append(out, d.subs[deviceID][ch]...)
+	}
+	for _, r := range d.subs[""][ch] {
+		dup := false
+		for _, o := range out {
+			if o == r {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// correct searches for the smallest set of learned substitutions that makes
+// the plate valid
+func (d *plateCorrectionDictionary) correct(plate, deviceID string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	chars := []rune(plate)
+	var positions []int
+	options := make(map[int][]rune)
+	for i, ch := range chars {
+		if subs := d.candidates(deviceID, ch); len(subs) > 0 {
+			positions = append(positions, i)
+			options[i] = subs
+			if len(positions) == maxCorrectionPositions {
+				break
+			}
+		}
+	}
+	if len(positions) == 0 {
+		return "", false
+	}
+
+	best := ""
+	bestChanges := len(positions) + 1
+	var search func(idx, changes int)
+	search = func(idx, changes int) {
+		if changes >= bestChanges {
+			return
+		}
+		if idx == len(positions) {
+			if changes > 0 && isValidPlate(string(chars)) {
+				best = string(chars)
+				bestChanges = changes
+			}
+			return
+		}
+
+		pos := positions[idx]
+		search(idx+1, changes) // keep the original character
+		orig := chars[pos]
+		for _, r := range options[pos] {
+			chars[pos] = r
+			search(idx+1, changes+1)
+		}
+		chars[pos] = orig
+	}
+	search(0, 0)
+
+	return best, best != ""
+}
+
+// diffPlates returns the character substitutions between a raw and corrected
+// plate. Only same-length plates are aligned; insertions and deletions aren't learned.
+func diffPlates(original, corrected string) [][2]rune {
+	a, b := []rune(cleanPlate(original)), []rune(cleanPlate(corrected))
+	if len(a) == 0 || len(a) != len(b) {
+		return nil
+	}
+	var pairs [][2]rune
+	for i := range a {
+		if a[i] != b[i] {
+			pairs = append(pairs, [2]rune{a[i], b[i]})
+		}
+	}
+	return pairs
+}
+
+// recordPlateCorrection stores a manual plate fix for the learner
+func recordPlateCorrection(violationID *int64, deviceID, original, corrected, correctedBy string) {
+	if original == "" || cleanPlate(original) == cleanPlate(corrected) {
+		return
+	}
+	correction := models.PlateCorrection{
+		ViolationID: violationID,
+		DeviceID:    deviceID,
+		Original:    original,
+		Corrected:   corrected,
+		CorrectedBy: correctedBy,
+	}
+	if err := database.DB.Create(&correction).Error; err != nil {
+		log.Printf("⚠️ [PLATE_CORRECTION] Failed to record correction %s -> %s: %v", logPlate(original), logPlate(corrected), err)
+	}
+}
+
+// StartPlateCorrectionLearner rebuilds the substitution dictionary from manual
+// corrections on startup and then every PLATE_CORRECTION_LEARN_INTERVAL_MINUTES (default 60).
+// PLATE_CORRECTION_MIN_COUNT sets how often a substitution must be seen before it applies.
+func StartPlateCorrectionLearner() {
+	interval := defaultPlateLearnInterval
+	if v := os.Getenv("PLATE_CORRECTION_LEARN_INTERVAL_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins > 0 {
+			interval = time.Duration(mins) * time.Minute
+		}
+	}
+	plateDictionary.minCount = defaultPlateCorrectionMinCount
+	if v := os.Getenv("PLATE_CORRECTION_MIN_COUNT"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			plateDictionary.minCount = n
+		}
+	}
+
+	go func() {
+		learnPlateSubstitutions()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			learnPlateSubstitutions()
+		}
+	}()
+}
+
+// learnPlateSubstitutions counts raw->corrected substitutions per device and
+// across all devices, then reloads the dictionary
+func learnPlateSubstitutions() {
+	var corrections []models.PlateCorrection
+	if err := database.DB.Find(&corrections).Error; err != nil {
+		log.Printf("⚠️ [PLATE_CORRECTION] Failed to load corrections: %v", err)
+		return
+	}
+
+	type subKey struct {
+		deviceID string
+		from, to rune
+	}
+	counts := make(map[subKey]int64)
+	lastSeen := make(map[subKey]time.Time)
+	for _, corr := range corrections {
+		for _, pair := range diffPlates(corr.Original, corr.Corrected) {
+			keys := []subKey{{"", pair[0], pair[1]}}
+			if corr.DeviceID != "" {
+				keys = append(keys, subKey{corr.DeviceID, pair[0], pair[1]})
+			}
+			for _, key := range keys {
+				counts[key]++
+				if corr.CreatedAt.After(lastSeen[key]) {
+					lastSeen[key] = corr.CreatedAt
+				}
+			}
+		}
+	}
+
+	for key, count := range counts {
+		var sub models.PlateSubstitution
+		err := database.DB.Where("device_id = ? AND from_char = ? AND to_char = ?",
+			key.deviceID, string(key.from), string(key.to)).First(&sub).Error
+		if err != nil {
+			// New substitutions are enabled; admins can disable them after review
+			sub = models.PlateSubstitution{
+				DeviceID:   key.deviceID,
+				From:       string(key.from),
+				To:         string(key.to),
+				Count:      count,
+				Enabled:    true,
+				LastSeenAt: lastSeen[key],
+			}
+			if err := database.DB.Create(&sub).Error; err != nil {
+				log.Printf("⚠️ [PLATE_CORRECTION] Failed to save substitution %s -> %s: %v", sub.From, sub.To, err)
+			}
+			continue
+		}
+		if sub.Count != count {
+			database.DB.Model(&sub).Updates(map[string]interface{}{
+				"count":        count,
+				"last_seen_at": lastSeen[key],
+			})
+		}
+	}
+
+	reloadPlateDictionary()
+	log.Printf("🔤 [PLATE_CORRECTION] Learned %d substitutions from %d corrections", len(counts), len(corrections))
+}
+
+// reloadPlateDictionary loads enabled substitutions above the minimum count into memory
+func reloadPlateDictionary() {
+	enabled := getSettingBool(settingPlateCorrectionEnabled, false)
+
+	plateDictionary.mu.RLock()
+	minCount := plateDictionary.minCount
+	plateDictionary.mu.RUnlock()
+
+	var rows []models.PlateSubstitution
+	if err := database.DB.Where("enabled = ? AND count >= ?", true, minCount).
+		Order("count DESC").Find(&rows).Error; err != nil {
+		log.Printf("⚠️ [PLATE_CORRECTION] Failed to load substitutions: %v", err)
+		return
+	}
+
+	subs := make(map[string]map[rune][]rune)
+	for _, row := range rows {
+		from, to := []rune(row.From), []rune(row.To)
+		if len(from) != 1 || len(to) != 1 {
+			continue
+		}
+		if subs[row.DeviceID] == nil {
+			subs[row.DeviceID] = make(map[rune][]rune)
+		}
+		subs[row.DeviceID][from[0]] = append(subs[row.DeviceID][from[0]], to[0])
+	}
+
+	plateDictionary.mu.Lock()
+	plateDictionary.enabled = enabled
+	plateDictionary.subs = subs
+	plateDictionary.mu.Unlock()
+}
+
+// GetPlateCorrections returns learned OCR substitutions for admin review (admin)
+// GET /api/admin/plate-corrections
+func GetPlateCorrections(c *gin.Context) {
+	query := database.DB.Model(&models.PlateSubstitution{})
+	if deviceID := c.Query("deviceId"); deviceID != "" {
+		query = query.Where("device_id = ?", deviceID)
+	}
+
+	var subs []models.PlateSubstitution
+	if err := query.Order("count DESC").Limit(500).Find(&subs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch plate corrections"})
+		return
+	}
+
+	var recent []models.PlateCorrection
+	database.DB.Order("created_at DESC").Limit(50).Find(&recent)
+
+	plateDictionary.mu.RLock()
+	enabled, minCount := plateDictionary.enabled, plateDictionary.minCount
+	plateDictionary.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":           enabled,
+		"minCount":          minCount,
+		"substitutions":     subs,
+		"recentCorrections": recent,
+	})
+}
+
+// UpdatePlateSubstitution enables or disables a learned substitution (admin)
+// PUT /api/admin/plate-corrections/:id
+func UpdatePlateSubstitution(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid substitution ID"})
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sub models.PlateSubstitution
+	if err := database.DB.First(&sub, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Substitution not found"})
+		return
+	}
+	if err := database.DB.Model(&sub).Update("enabled", *req.Enabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update substitution"})
+		return
+	}
+
+	reloadPlateDictionary()
+	c.JSON(http.StatusOK, sub)
+}
+
+// SetPlateCorrectionEnabled toggles applying learned corrections on ingest (admin)
+// PUT /api/admin/plate-corrections/settings
+func SetPlateCorrectionEnabled(c *gin.Context) {
+	var req struct {
+		Enabled   *bool  `json:"enabled" binding:"required"`
+		ChangedBy string `json:"changedBy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	if err := setSettingBool(database.DB, settingPlateCorrectionEnabled, *req.Enabled, req.ChangedBy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update setting"})
+		return
+	}
+	reloadPlateDictionary()
+
+	log.Printf("🔤 [PLATE_CORRECTION] Enabled=%v (set by %s)", *req.Enabled, req.ChangedBy)
+	c.JSON(http.StatusOK, gin.H{"enabled": *req.Enabled})
+}
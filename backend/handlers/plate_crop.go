@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultPlateCropPadding = 0.1
+
+// plateBBoxKeys are the event data keys an edge may put a plate bounding box under
+var plateBBoxKeys = []string{"plate_bbox", "plate_box"}
+
+// plateCrop controls cutting the plate out of the full frame when the edge
+// sends a bounding box but no plate crop
+var plateCrop = struct {
+	enabled bool
+	padding float64 // fraction of the box size added on each side
+}{
+	enabled: true,
+	padding: defaultPlateCropPadding,
+}
+
+// InitPlateCrop reads PLATE_CROP (false to disable) and PLATE_CROP_PADDING_PERCENT
+// (default 10). Returns whether cropping is enabled and the padding fraction.
+func InitPlateCrop() (bool, float64) {
+	plateCrop.enabled = os.Getenv("PLATE_CROP") != "false"
+	plateCrop.padding = defaultPlateCropPadding
+	if v := os.Getenv("PLATE_CROP_PADDING_PERCENT"); v != "" {
+		if pct, err := strconv.ParseFloat(v, 64); err == nil && pct >= 0 && pct <= 100 {
+			plateCrop.padding = pct / 100
+		}
+	}
+	return plateCrop.enabled, plateCrop.padding
+}
+
+// bboxNumber reads a JSON number
+func bboxNumber(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+// parsePlateBBox reads a plate bounding box from event data as x1, y1, x2, y2
+func parsePlateBBox(data map[string]interface{}) ([4]float64, bool) {
+	return parseBBox(data, plateBBoxKeys)
+}
+
+// parseBBox reads a bounding box under the first of keys present in data, as
+// x1, y1, x2, y2. Accepts [x1, y1, x2, y2] or {x, y, width|w, height|h}.
+func parseBBox(data map[string]interface{}, keys []string) ([4]float64, bool) {
+	var box [4]float64
+	for _, key := range keys {
+		switch v := data[key].(type) {
+		case []interface{}:
+			if len(v) != 4 {
+				continue
+			}
+			for i := range box {
+				n, ok := bboxNumber(v[i])
+				if !ok {
+					return box, false
+				}
+				box[i] = n
+			}
+			return box, true
+		case map[string]interface{}:
+			x, okX := bboxNumber(v["x"])
+			y, okY := bboxNumber(v["y"])
+			w, okW := bboxNumber(v["width"])
+			if !okW {
+				w, okW = bboxNumber(v["w"])
+			}
+			h, okH := bboxNumber(v["height"])
+			if !okH {
+				h, okH = bboxNumber(v["h"])
+			}
+			if !okX || !okY || !okW || !okH {
+				return box, false
+			}
+			return [4]float64{x, y, x + w, y + h}, true
+		}
+	}
+	return box, false
+}
+
+// plateCropRect converts a bounding box to a padded pixel rectangle inside the
+// frame. Boxes whose coordinates are all within 0-1 are taken as normalized.
+func plateCropRect(box [4]float64, bounds image.Rectangle) image.Rectangle {
+	if box[0] <= 1 && box[1] <= 1 && box[2] <= 1 && box[3] <= 1 {
+		box[0] *= float64(bounds.Dx())
+		box[2] *= float64(bounds.Dx())
+		box[1] *= float64(bounds.Dy())
+		box[3] *= float64(bounds.Dy())
+	}
+	padX := (box[2] - box[0]) * plateCrop.padding
+	padY := (box[3] - box[1]) * plateCrop.padding
+	rect := image.Rect(
+		int(box[0]-padX), int(box[1]-padY),
+		int(box[2]+padX), int(box[3]+padY),
+	).Add(bounds.Min)
+	return rect.Intersect(bounds)
+}
+
+// cropPlateFromFrame stores a plate crop cut from the full frame when the event
+// has a plate bounding box but no plate image, and adds it to imageURLs
+func cropPlateFromFrame(event IngestEvent, imageURLs map[string]string) {
+	if !plateCrop.enabled || imageURLs["plate.jpg"] != "" {
+		return
+	}
+	frameURL, ok := imageURLs["frame.jpg"]
+	if !ok || !strings.HasPrefix(frameURL, "/uploads/") {
+		return
+	}
+	box, ok := parsePlateBBox(event.Data)
+	if !ok {
+		return
+	}
+
+	framePath := filepath.Join(getUploadBaseDir(), filepath.FromSlash(strings.TrimPrefix(frameURL, "/uploads/")))
+	file, err := os.Open(framePath)
+	if err != nil {
+		// Frames still waiting in the retry spool aren't on disk yet
+		log.Printf("⚠️ [PLATE_CROP] Frame not available - Device: %s, Path: %s, Error: %v", event.DeviceID, framePath, err)
+		return
+	}
+	frame, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		log.Printf("⚠️ [PLATE_CROP] Failed to decode frame - Device: %s, Path: %s, Error: %v", event.DeviceID, framePath, err)
+		return
+	}
+
+	rect := plateCropRect(box, frame.Bounds())
+	if rect.Empty() {
+		log.Printf("⚠️ [PLATE_CROP] Plate box outside frame - Device: %s, Box: %v", event.DeviceID, box)
+		return
+	}
+	sub, ok := frame.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, sub.SubImage(rect), &jpeg.Options{Quality: imageFormats.jpegQuality}); err != nil {
+		log.Printf("⚠️ [PLATE_CROP] Failed to encode crop - Device: %s, Error: %v", event.DeviceID, err)
+		return
+	}
+
+	storagePath := generateImagePath(event.WorkerID, event.DeviceID, event.Type, "plate.jpg")
+	written, err := writeImageFile(storagePath, &out)
+	if err != nil {
+		log.Printf("⚠️ [PLATE_CROP] Failed to save crop - Path: %s, Error: %v", storagePath, err)
+		return
+	}
+	url := uploadURL(storagePath)
+	recordStoredImage(event, storagePath, url, written)
+	imageURLs["plate.jpg"] = url
+	log.Printf("✂️ [PLATE_CROP] Cropped plate from frame - Device: %s, Rect: %v", event.DeviceID, rect)
+}
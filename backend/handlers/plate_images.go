@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/irisdrone/backend/models"
+)
+
+const (
+	defaultMaxPlateImages = 4
+
+	// primaryPlateImageKey is the upload key of the single plate image edges
+	// have always sent; it's the primary unless the event names another
+	primaryPlateImageKey = "plate.jpg"
+)
+
+// maxPlateImages caps how many plate images are linked to one violation
+var maxPlateImages = defaultMaxPlateImages
+
+// InitPlateImages reads VIOLATION_MAX_PLATE_IMAGES (default 4) and returns it
+func InitPlateImages() int {
+	maxPlateImages = defaultMaxPlateImages
+	if v := os.Getenv("VIOLATION_MAX_PLATE_IMAGES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxPlateImages = n
+		}
+	}
+	return maxPlateImages
+}
+
+// imageKey is the key an uploaded file is recorded under. The first file of a
+// form field keeps the field name; any further ones under the same field are
+// numbered, e.g. plate.jpg, plate_2.jpg, plate_3.jpg.
+func imageKey(field string, index int) string {
+	if index == 0 {
+		return field
+	}
+	ext := path.Ext(field)
+	return fmt.Sprintf("%s_%d%s", strings.TrimSuffix(field, ext), index+1, ext)
+}
+
+// plateImageView returns the view named by a plate image key
+// (plate_rear.jpg -> "rear"), or "" for plate.jpg and numbered keys
+func plateImageView(key string) string {
+	name := strings.TrimSuffix(key, path.Ext(key))
+	view := strings.TrimLeft(strings.TrimPrefix(name, "plate"), "_-")
+	if _, err := strconv.Atoi(view); err == nil {
+		return ""
+	}
+	return view
+}
+
+// collectPlateImages returns the plate images among an event's uploads
+// (plate.jpg, plate_front.jpg, plate_rear.jpg, ...), primary first. The event
+// may name the primary by view or key in primary_plate; otherwise plate.jpg
+// is, or failing that the first by key.
+func collectPlateImages(imageURLs map[string]string, data map[string]interface{}) []models.PlateImage {
+	var keys []string
+	for key := range imageURLs {
+		if strings.HasPrefix(key, "plate") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	primary := primaryPlateImageKey
+	if named, _ := data["primary_plate"].(string); named != "" {
+		for _, key := range keys {
+			if key == named || plateImageView(key) == named {
+				primary = key
+				break
+			}
+		}
+	}
+	if _, ok := imageURLs[primary]; !ok {
+		primary = keys[0]
+	}
+
+	images := []models.PlateImage{{URL: imageURLs[primary], View: plateImageView(primary), Primary: true}}
+	for _, key := range keys {
+		if key == primary {
+			continue
+		}
+		if len(images) >= maxPlateImages {
+			break
+		}
+		images = append(images, models.PlateImage{URL: imageURLs[key], View: plateImageView(key)})
+	}
+	return images
+}
+
+// orderPlateImages puts the primary plate image first and caps the list.
+// The primary is primaryURL when given (added if it's not in the list), else
+// the one marked primary, else the first.
+func orderPlateImages(images []models.PlateImage, primaryURL *string) []models.PlateImage {
+	if len(images) == 0 && primaryURL == nil {
+		return nil
+	}
+
+	primary := -1
+	for i, img := range images {
+		if (primaryURL != nil && img.URL == *primaryURL) || (primaryURL == nil && img.Primary) {
+			primary = i
+			break
+		}
+	}
+
+	var ordered []models.PlateImage
+	switch {
+	case primary >= 0:
+		ordered = append(ordered, images[primary])
+	case primaryURL != nil:
+		ordered = append(ordered, models.PlateImage{URL: *primaryURL})
+	default:
+		primary = 0
+		ordered = append(ordered, images[0])
+	}
+	ordered[0].Primary = true
+
+	for i, img := range images {
+		if i == primary {
+			continue
+		}
+		if len(ordered) >= maxPlateImages {
+			break
+		}
+		img.Primary = false
+		ordered = append(ordered, img)
+	}
+	return ordered
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/models"
+)
+
+// plateMasking controls where plate numbers are redacted. Counting responses
+// and logs are always masked when it's on; enforcement responses only for
+// requesters outside roles.
+var plateMasking = struct {
+	enabled bool
+	roles   map[string]bool // roles that see full plates in enforcement responses; nil = everyone
+}{}
+
+// InitPlateMasking reads PLATE_MASKING (default false) and PLATE_UNMASKED_ROLES
+// (comma-separated, default any requester) and returns whether plates are masked
+func InitPlateMasking() bool {
+	plateMasking.enabled = os.Getenv("PLATE_MASKING") == "true"
+	plateMasking.roles = nil
+	if v := os.Getenv("PLATE_UNMASKED_ROLES"); v != "" && v != "*" {
+		plateMasking.roles = make(map[string]bool)
+		for _, role := range strings.Split(v, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				plateMasking.roles[role] = true
+			}
+		}
+	}
+	return plateMasking.enabled
+}
+
+// maskPlate redacts the middle of a plate, keeping the region and the last
+// digits so counts can still be told apart, e.g. KA01AB1249 -> KA01****49
+func maskPlate(plate string) string {
+	chars := []rune(plate)
+	keepStart, keepEnd := 4, 2
+	switch {
+	case len(chars) < 5:
+		keepStart, keepEnd = 0, 0
+	case len(chars) < 8:
+		keepStart, keepEnd = 2, 1
+	}
+	for i := keepStart; i < len(chars)-keepEnd; i++ {
+		chars[i] = '*'
+	}
+	return string(chars)
+}
+
+// maskPlatePtr masks an optional plate in place
+func maskPlatePtr(plate *string) {
+	if plate != nil {
+		*plate = maskPlate(*plate)
+	}
+}
+
+// logPlate is the form of a plate written to logs
+func logPlate(plate string) string {
+	if !plateMasking.enabled {
+		return plate
+	}
+	return maskPlate(plate)
+}
+
+// platesVisible reports whether the requester may see full plates in
+// enforcement responses: violations, offense sessions, vehicles and the watchlist
+func platesVisible(c *gin.Context) bool {
+	if !plateMasking.enabled || plateMasking.roles == nil {
+		return true
+	}
+	user, _ := requestUser(c)
+	return user != nil && plateMasking.roles[user.Role]
+}
+
+// feedPlatesVisible reports whether the requester may see full plates in a
+// live feed anyone can open: with masking on, only a logged-in requester
+// whose role is in the unmasked roles (any role when none are set)
+func feedPlatesVisible(c *gin.Context) bool {
+	if !plateMasking.enabled {
+		return true
+	}
+	user, _ := requestUser(c)
+	return user != nil && (plateMasking.roles == nil || plateMasking.roles[user.Role])
+}
+
+// maskCountingPlates masks the plates of detections in a counting response,
+// whoever is asking
+func maskCountingPlates(detections []models.VehicleDetection) {
+	if !plateMasking.enabled {
+		return
+	}
+	maskDetectionPlates(detections)
+}
+
+// maskDetectionPlates masks the plates of detections and their vehicles
+func maskDetectionPlates(detections []models.VehicleDetection) {
+	for i := range detections {
+		maskPlatePtr(detections[i].PlateNumber)
+		if detections[i].Vehicle != nil {
+			maskVehiclePlate(detections[i].Vehicle)
+		}
+	}
+}
+
+// maskVehiclePlate masks a vehicle's plate and those of its loaded detections
+// and violations
+func maskVehiclePlate(v *models.Vehicle) {
+	maskPlatePtr(v.PlateNumber)
+	maskDetectionPlates(v.Detections)
+	maskViolationPlates(v.Violations)
+}
+
+// maskViolationPlates masks the plates of violations and their vehicles
+func maskViolationPlates(violations []models.TrafficViolation) {
+	for i := range violations {
+		maskViolationPlate(&violations[i])
+	}
+}
+
+// maskViolationPlate masks the plate of one violation and its vehicle
+func maskViolationPlate(v *models.TrafficViolation) {
+	maskPlatePtr(v.PlateNumber)
+	if v.Vehicle != nil {
+		maskPlatePtr(v.Vehicle.PlateNumber)
+	}
+}
+
+// maskOffenseSessionPlates masks the plates of offense sessions and their violations
+func maskOffenseSessionPlates(sessions []models.OffenseSession) {
+	for i := range sessions {
+		maskOffenseSessionPlate(&sessions[i])
+	}
+}
+
+// maskOffenseSessionPlate masks the plate of one offense session and its violations
+func maskOffenseSessionPlate(s *models.OffenseSession) {
+	maskPlatePtr(s.PlateNumber)
+	maskViolationPlates(s.Violations)
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// isCriticalAlert reports whether an alert is always pushed, even in quiet hours
+func isCriticalAlert(severity models.HotspotSeverity) bool {
+	return severity == models.SeverityRed
+}
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// quietDays returns the weekdays of a window; nil means every day
+func quietDays(days models.JSONB) map[time.Weekday]bool {
+	list, ok := days.Data.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil
+	}
+	set := make(map[time.Weekday]bool, len(list))
+	for _, d := range list {
+		if n, ok := d.(float64); ok {
+			set[time.Weekday(n)] = true
+		}
+	}
+	return set
+}
+
+// windowCovers reports whether a local time falls in a quiet hours window.
+// A window that spans midnight belongs to the day it starts on.
+func windowCovers(window models.QuietHoursWindow, local time.Time) bool {
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(window.End)
+	if err != nil {
+		return false
+	}
+	days := quietDays(window.Days)
+	onDay := func(t time.Time) bool { return days == nil || days[t.Weekday()] }
+
+	now := local.Hour()*60 + local.Minute()
+	if start < end {
+		return now >= start && now < end && onDay(local)
+	}
+	if now >= start {
+		return onDay(local)
+	}
+	return now < end && onDay(local.AddDate(0, 0, -1))
+}
+
+// siteInQuietHours reports whether any enabled window of a site covers a time
+func siteInQuietHours(site *models.Site, at time.Time) bool {
+	var windows []models.QuietHoursWindow
+	if err := database.DB.Where("site_id = ? AND enabled = true", site.ID).Find(&windows).Error; err != nil || len(windows) == 0 {
+		return false
+	}
+
+	local := at
+	if site.Timezone != "" {
+		if loc, err := time.LoadLocation(site.Timezone); err == nil {
+			local = at.In(loc)
+		}
+	}
+	for _, w := range windows {
+		if windowCovers(w, local) {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceInQuietHours reports whether a device's site is in quiet hours at a time
+func deviceInQuietHours(deviceID string, at time.Time) bool {
+	var site models.Site
+	err := database.DB.Where("id = (?)",
+		database.DB.Model(&models.Device{}).Select("site_id").Where("id = ?", deviceID)).
+		First(&site).Error
+	if err != nil {
+		return false
+	}
+	return siteInQuietHours(&site, at)
+}
+
+// notifyAlert pushes a stored alert to connected operators. Non-critical alerts
+// raised during the device site's quiet hours are kept but not pushed.
+func notifyAlert(deviceID string, severity models.HotspotSeverity, alert interface{}) {
+	if feedHub == nil {
+		return
+	}
+	if !isCriticalAlert(severity) && deviceInQuietHours(deviceID, time.Now()) {
+		log.Printf("🌙 [QUIET_HOURS] Suppressed %s alert notification for device %s", severity, deviceID)
+		return
+	}
+	feedHub.BroadcastAlert(deviceID, alert)
+}
+
+// QuietHoursRequest - Create or update a quiet hours window
+type QuietHoursRequest struct {
+	Start   *string `json:"start"`
+	End     *string `json:"end"`
+	Days    *[]int  `json:"days"`
+	Enabled *bool   `json:"enabled"`
+}
+
+// applyQuietHoursRequest copies the set fields of req onto window and validates it
+func applyQuietHoursRequest(window *models.QuietHoursWindow, req *QuietHoursRequest) error {
+	if req.Start != nil {
+		window.Start = *req.Start
+	}
+	if req.End != nil {
+		window.End = *req.End
+	}
+	if req.Days != nil {
+		for _, d := range *req.Days {
+			if d < 0 || d > 6 {
+				return fmt.Errorf("days must be between 0 (Sunday) and 6 (Saturday)")
+			}
+		}
+		window.Days = models.NewJSONB(*req.Days)
+	}
+	if req.Enabled != nil {
+		window.Enabled = *req.Enabled
+	}
+
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return fmt.Errorf("start: %v", err)
+	}
+	end, err := parseClock(window.End)
+	if err != nil {
+		return fmt.Errorf("end: %v", err)
+	}
+	if start == end {
+		return fmt.Errorf("start and end must differ")
+	}
+	return nil
+}
+
+// GetQuietHours handles GET /api/sites/:id/quiet-hours - List a site's quiet
+// hours windows and whether it is in quiet hours now
+func GetQuietHours(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	var windows []models.QuietHoursWindow
+	if err := database.DB.Where("site_id = ?", site.ID).Order("start_time").Find(&windows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"windows":  windows,
+		"timezone": site.Timezone,
+		"quietNow": siteInQuietHours(&site, time.Now()),
+	})
+}
+
+// CreateQuietHours handles POST /api/sites/:id/quiet-hours - Add a quiet hours window
+func CreateQuietHours(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	var req QuietHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := models.QuietHoursWindow{SiteID: site.ID, Enabled: true}
+	if err := applyQuietHoursRequest(&window, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// findQuietHoursWindow loads the window named in the URL, scoped to its site
+func findQuietHoursWindow(c *gin.Context) (*models.QuietHoursWindow, bool) {
+	windowID, err := strconv.ParseInt(c.Param("windowId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window ID"})
+		return nil, false
+	}
+	var window models.QuietHoursWindow
+	if err := database.DB.Where("id = ? AND site_id = ?", windowID, c.Param("id")).First(&window).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quiet hours window not found"})
+		return nil, false
+	}
+	return &window, true
+}
+
+// UpdateQuietHours handles PUT /api/sites/:id/quiet-hours/:windowId - Change or
+// disable a quiet hours window
+func UpdateQuietHours(c *gin.Context) {
+	window, ok := findQuietHoursWindow(c)
+	if !ok {
+		return
+	}
+
+	var req QuietHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyQuietHoursRequest(window, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
+
+// DeleteQuietHours handles DELETE /api/sites/:id/quiet-hours/:windowId - Remove a quiet hours window
+func DeleteQuietHours(c *gin.Context) {
+	window, ok := findQuietHoursWindow(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(window).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quiet hours"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quiet hours window deleted"})
+}
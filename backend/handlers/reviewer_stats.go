@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// reviewerStatsRow is one reviewer's decisions in the range
+type reviewerStatsRow struct {
+	ReviewedBy           string   `json:"reviewedBy"`
+	Total                int64    `json:"total"`
+	Approved             int64    `json:"approved"`
+	Rejected             int64    `json:"rejected"`
+	Fined                int64    `json:"fined"`
+	Other                int64    `json:"other"`        // Custom workflow statuses
+	ApprovalRate         *float64 `json:"approvalRate"` // (approved + fined) / (approved + fined + rejected)
+	AvgReviewLatencySecs float64  `json:"avgReviewLatencySeconds"`
+	MaxReviewLatencySecs float64  `json:"maxReviewLatencySeconds"`
+}
+
+// GetReviewerStats aggregates violation reviews per reviewer (admin)
+// GET /api/admin/violations/reviewer-stats?startTime=..&endTime=..&includeAuto=false
+// Reviews are bucketed by reviewed_at (default last 7 days); latency runs from
+// the violation's timestamp to its review.
+func GetReviewerStats(c *gin.Context) {
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.TrafficViolation{}).
+		Select(`reviewed_by,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE status = ?) AS approved,
+			COUNT(*) FILTER (WHERE status = ?) AS rejected,
+			COUNT(*) FILTER (WHERE status = ?) AS fined,
+			COALESCE(AVG(EXTRACT(EPOCH FROM reviewed_at - timestamp)), 0) AS avg_review_latency_secs,
+			COALESCE(MAX(EXTRACT(EPOCH FROM reviewed_at - timestamp)), 0) AS max_review_latency_secs`,
+			models.ViolationApproved, models.ViolationRejected, models.ViolationFined).
+		Where("reviewed_by IS NOT NULL AND reviewed_by <> '' AND reviewed_at >= ? AND reviewed_at <= ?", startTime, endTime)
+
+	// Auto-approved violations aren't human reviews
+	if c.Query("includeAuto") != "true" {
+		query = query.Where("auto_approved = false")
+	}
+
+	var rows []reviewerStatsRow
+	if err := query.Group("reviewed_by").Order("total DESC").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate reviewer stats"})
+		return
+	}
+
+	var totalReviews int64
+	for i := range rows {
+		r := &rows[i]
+		r.Other = r.Total - r.Approved - r.Rejected - r.Fined
+		if decided := r.Approved + r.Fined + r.Rejected; decided > 0 {
+			rate := float64(r.Approved+r.Fined) / float64(decided)
+			r.ApprovalRate = &rate
+		}
+		totalReviews += r.Total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"startTime":    startTime,
+		"endTime":      endTime,
+		"totalReviews": totalReviews,
+		"reviewers":    rows,
+	})
+}
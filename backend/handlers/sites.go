@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// maxSiteBoundaryPoints bounds the size of a site boundary polygon
+const maxSiteBoundaryPoints = 256
+
+// siteBuckets maps groupBy to the DATE_TRUNC unit and TO_CHAR format of a stats bucket
+var siteBuckets = map[string][2]string{
+	"hour":  {"hour", "YYYY-MM-DD HH24:00"},
+	"day":   {"day", "YYYY-MM-DD"},
+	"week":  {"week", "IYYY-\"W\"IW"},
+	"month": {"month", "YYYY-MM"},
+}
+
+// SiteRequest - Create or update a site
+type SiteRequest struct {
+	Name     *string       `json:"name"`
+	Boundary *[][2]float64 `json:"boundary"` // [[lat, lng], ...]; empty clears it
+	Timezone *string       `json:"timezone"`
+}
+
+// validateSiteBoundary checks a boundary polygon of [lat, lng] points.
+// An empty boundary is valid.
+func validateSiteBoundary(points [][2]float64) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if len(points) < 3 {
+		return fmt.Errorf("boundary needs at least 3 points")
+	}
+	if len(points) > maxSiteBoundaryPoints {
+		return fmt.Errorf("boundary can have at most %d points", maxSiteBoundaryPoints)
+	}
+	for i, p := range points {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || p[0] < -90 || p[0] > 90 || p[1] < -180 || p[1] > 180 {
+			return fmt.Errorf("boundary point %d is not a valid [lat, lng]", i)
+		}
+	}
+	return nil
+}
+
+// applySiteRequest copies the set fields of req onto site
+func applySiteRequest(site *models.Site, req *SiteRequest) error {
+	if req.Name != nil {
+		if *req.Name == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		site.Name = *req.Name
+	}
+	if req.Timezone != nil {
+		if *req.Timezone != "" {
+			if _, err := time.LoadLocation(*req.Timezone); err != nil {
+				return fmt.Errorf("invalid timezone %q", *req.Timezone)
+			}
+		}
+		site.Timezone = *req.Timezone
+	}
+	if req.Boundary != nil {
+		if err := validateSiteBoundary(*req.Boundary); err != nil {
+			return err
+		}
+		if len(*req.Boundary) == 0 {
+			site.Boundary = models.JSONB{}
+		} else {
+			site.Boundary = models.NewJSONB(*req.Boundary)
+		}
+	}
+	return nil
+}
+
+// siteDevices selects the IDs of the devices in a site
+func siteDevices(siteID string) *gorm.DB {
+	return database.DB.Model(&models.Device{}).Select("id").Where("site_id = ?", siteID)
+}
+
+// siteZoneCount is the number of devices a site has in one zone
+type siteZoneCount struct {
+	ZoneID  string `json:"zoneId"`
+	Devices int64  `json:"devices"`
+}
+
+// siteZones lists the zones of a site with their device counts
+func siteZones(siteID string) ([]siteZoneCount, error) {
+	zones := []siteZoneCount{}
+	err := database.DB.Model(&models.Device{}).
+		Select("zone_id, COUNT(*) AS devices").
+		Where("site_id = ? AND zone_id IS NOT NULL AND zone_id <> ''", siteID).
+		Group("zone_id").
+		Order("zone_id ASC").
+		Scan(&zones).Error
+	return zones, err
+}
+
+// GetSites handles GET /api/sites - List sites with their device and zone counts
+func GetSites(c *gin.Context) {
+	var sites []models.Site
+	if err := database.DB.Order("name ASC").Find(&sites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sites"})
+		return
+	}
+
+	var counts []struct {
+		SiteID  string
+		Devices int64
+		Zones   int64
+	}
+	database.DB.Model(&models.Device{}).
+		Select("site_id, COUNT(*) AS devices, COUNT(DISTINCT NULLIF(zone_id, '')) AS zones").
+		Group("site_id").
+		Scan(&counts)
+	bySite := make(map[string]int, len(counts))
+	for i, sc := range counts {
+		bySite[sc.SiteID] = i
+	}
+
+	result := make([]gin.H, 0, len(sites))
+	for _, site := range sites {
+		entry := gin.H{
+			"site":    site,
+			"devices": int64(0),
+			"zones":   int64(0),
+		}
+		if i, ok := bySite[site.ID]; ok {
+			entry["devices"] = counts[i].Devices
+			entry["zones"] = counts[i].Zones
+		}
+		result = append(result, entry)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetSite handles GET /api/sites/:id - Get a site with its zones
+func GetSite(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	zones, err := siteZones(site.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site zones"})
+		return
+	}
+
+	var devices int64
+	database.DB.Model(&models.Device{}).Where("site_id = ?", site.ID).Count(&devices)
+
+	c.JSON(http.StatusOK, gin.H{
+		"site":    site,
+		"zones":   zones,
+		"devices": devices,
+	})
+}
+
+// CreateSite handles POST /api/sites - Define a site
+func CreateSite(c *gin.Context) {
+	var req SiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	site := models.Site{ID: generateID("site")}
+	if err := applySiteRequest(&site, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&site).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create site"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, site)
+}
+
+// UpdateSite handles PUT /api/sites/:id - Rename a site or change its boundary or timezone
+func UpdateSite(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	var req SiteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applySiteRequest(&site, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(&site).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update site"})
+		return
+	}
+
+	c.JSON(http.StatusOK, site)
+}
+
+// DeleteSite handles DELETE /api/sites/:id - Remove a site, moving its devices
+// back to the default site
+func DeleteSite(c *gin.Context) {
+	siteID := c.Param("id")
+	if siteID == models.DefaultSiteID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The default site cannot be deleted"})
+		return
+	}
+
+	var moved int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Site{}, "id = ?", siteID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("site_id = ?", siteID).Delete(&models.QuietHoursWindow{}).Error; err != nil {
+			return err
+		}
+		result = tx.Model(&models.Device{}).Where("site_id = ?", siteID).Update("site_id", models.DefaultSiteID)
+		moved = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete site"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Site deleted", "devicesMoved": moved})
+}
+
+// AssignZoneToSite handles PUT /api/sites/:id/zones/:zoneId - Move a zone, and
+// every device in it, to a site
+func AssignZoneToSite(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	zoneID := c.Param("zoneId")
+	result := database.DB.Model(&models.Device{}).Where("zone_id = ?", zoneID).Update("site_id", site.ID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign zone"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No devices in zone"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"siteId": site.ID, "zoneId": zoneID, "devicesMoved": result.RowsAffected})
+}
+
+// AssignDeviceToSite handles PUT /api/sites/:id/devices/:deviceId - Move a
+// device that is not in a zone to a site. Zoned devices follow their zone.
+func AssignDeviceToSite(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	var device models.Device
+	if err := database.DB.Select("id, zone_id, site_id").First(&device, "id = ?", c.Param("deviceId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if device.ZoneID != nil && *device.ZoneID != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Device is in zone %s; move the zone instead", *device.ZoneID)})
+		return
+	}
+
+	if err := database.DB.Model(&device).Update("site_id", site.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"siteId": site.ID, "deviceId": device.ID})
+}
+
+// siteBucketCount is one time bucket of a site stats series
+type siteBucketCount struct {
+	TimePeriod string
+	Count      int64
+}
+
+// siteTimeSeries counts a table's rows for the site's devices per time bucket.
+// Buckets are cut in the site's timezone when it has one, otherwise in the
+// database timezone.
+func siteTimeSeries(table string, site *models.Site, bucket [2]string, start, end time.Time) ([]siteBucketCount, error) {
+	ts := "timestamp"
+	args := []interface{}{}
+	if site.Timezone != "" {
+		ts = "timestamp AT TIME ZONE ?"
+		args = append(args, site.Timezone)
+	}
+	args = append(args, start, end, siteDevices(site.ID))
+
+	query := fmt.Sprintf(`
+		SELECT TO_CHAR(DATE_TRUNC('%s', %s), '%s') AS time_period, COUNT(*) AS count
+		FROM %s
+		WHERE timestamp >= ? AND timestamp <= ? AND device_id IN (?)
+		GROUP BY 1
+		ORDER BY 1
+	`, bucket[0], ts, bucket[1], table)
+
+	var counts []siteBucketCount
+	err := database.DB.Raw(query, args...).Scan(&counts).Error
+	return counts, err
+}
+
+// GetSiteStats handles GET /api/sites/:id/stats - Aggregate activity across a
+// site's devices, bucketed in the site's timezone
+func GetSiteStats(c *gin.Context) {
+	var site models.Site
+	if err := database.DB.First(&site, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Site not found"})
+		return
+	}
+
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groupBy := c.DefaultQuery("groupBy", "hour")
+	bucket, ok := siteBuckets[groupBy]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "groupBy must be hour, day, week or month"})
+		return
+	}
+
+	series := map[string]string{
+		"events":      "events",
+		"violations":  "traffic_violations",
+		"detections":  "vehicle_detections",
+		"crowdAlerts": "crowd_alerts",
+	}
+	totals := make(gin.H, len(series))
+	byTime := make(map[string]gin.H)
+	for name, table := range series {
+		counts, err := siteTimeSeries(table, &site, bucket, startTime, endTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate site " + name})
+			return
+		}
+		var total int64
+		for _, bc := range counts {
+			entry, ok := byTime[bc.TimePeriod]
+			if !ok {
+				entry = gin.H{groupBy: bc.TimePeriod, "events": 0, "violations": 0, "detections": 0, "crowdAlerts": 0}
+				byTime[bc.TimePeriod] = entry
+			}
+			entry[name] = bc.Count
+			total += bc.Count
+		}
+		totals[name] = total
+	}
+
+	timeline := make([]gin.H, 0, len(byTime))
+	for _, entry := range byTime {
+		timeline = append(timeline, entry)
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i][groupBy].(string) < timeline[j][groupBy].(string)
+	})
+
+	zones, err := siteZones(site.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site zones"})
+		return
+	}
+
+	var devices, online int64
+	database.DB.Model(&models.Device{}).Where("site_id = ?", site.ID).Count(&devices)
+	database.DB.Model(&models.Device{}).
+		Where("site_id = ? AND last_event_at >= ?", site.ID, time.Now().Add(-deviceOnlineWindow)).
+		Count(&online)
+
+	timezone := site.Timezone
+	if timezone == "" {
+		database.DB.Raw("SHOW timezone").Scan(&timezone)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"siteId":        site.ID,
+		"timezone":      timezone,
+		"startTime":     startTime,
+		"endTime":       endTime,
+		"groupBy":       groupBy,
+		"devices":       devices,
+		"onlineDevices": online,
+		"zones":         zones,
+		"totals":        totals,
+		"byTime":        timeline,
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatsCacheTTL = 30 * time.Second
+
+	// maxStatsCacheEntries bounds the cache; past it, expired entries are
+	// dropped and, failing that, the whole cache is
+	maxStatsCacheEntries = 1000
+)
+
+// StatsCacheRealtime is the cache group of realtime detection stats, dropped
+// whenever a detection is ingested
+const StatsCacheRealtime = "realtime"
+
+// statsCacheEntry is one cached stats response
+type statsCacheEntry struct {
+	body    []byte
+	group   string
+	expires time.Time
+}
+
+// statsCache holds recent responses of expensive stats endpoints, keyed on
+// path and query, so operators viewing the same dashboard share one query
+var statsCache = struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]statsCacheEntry
+}{ttl: defaultStatsCacheTTL, entries: make(map[string]statsCacheEntry)}
+
+// InitStatsCache reads STATS_CACHE_TTL_SECONDS (default 30, 0 = no caching)
+// and returns the TTL
+func InitStatsCache() time.Duration {
+	statsCache.mu.Lock()
+	defer statsCache.mu.Unlock()
+	statsCache.ttl = defaultStatsCacheTTL
+	if v := os.Getenv("STATS_CACHE_TTL_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			statsCache.ttl = time.Duration(secs) * time.Second
+		}
+	}
+	statsCache.entries = make(map[string]statsCacheEntry)
+	return statsCache.ttl
+}
+
+// invalidateStatsCache drops the cached responses of a group, for realtime
+// endpoints whose numbers change with every ingested event
+func invalidateStatsCache(group string) {
+	statsCache.mu.Lock()
+	defer statsCache.mu.Unlock()
+	for key, entry := range statsCache.entries {
+		if entry.group == group {
+			delete(statsCache.entries, key)
+		}
+	}
+}
+
+// statsCacheWriter captures the body of a response while writing it through
+type statsCacheWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *statsCacheWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *statsCacheWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// CacheStats serves repeated identical requests from the stats cache for the
+// configured TTL. Only 200 responses are cached. Pass ?nocache=true or
+// Cache-Control: no-cache to bypass it; the fresh result still replaces the
+// cached one. Responses carry X-Cache: HIT or MISS.
+//
+// Entries of a group can be dropped early with invalidateStatsCache; realtime
+// endpoints use a group that ingest invalidates.
+func CacheStats(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statsCache.mu.Lock()
+		ttl := statsCache.ttl
+		statsCache.mu.Unlock()
+		if ttl == 0 {
+			c.Next()
+			return
+		}
+
+		query := c.Request.URL.Query()
+		bypass := query.Get("nocache") == "true" || c.GetHeader("Cache-Control") == "no-cache"
+		query.Del("nocache")
+		key := c.Request.URL.Path + "?" + query.Encode()
+
+		if !bypass {
+			statsCache.mu.Lock()
+			entry, ok := statsCache.entries[key]
+			statsCache.mu.Unlock()
+			if ok && time.Now().Before(entry.expires) {
+				c.Header("X-Cache", "HIT")
+				c.Data(http.StatusOK, "application/json; charset=utf-8", entry.body)
+				c.Abort()
+				return
+			}
+		}
+
+		c.Header("X-Cache", "MISS")
+		writer := &statsCacheWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if c.Writer.Status() != http.StatusOK {
+			return
+		}
+		now := time.Now()
+		statsCache.mu.Lock()
+		defer statsCache.mu.Unlock()
+		if len(statsCache.entries) >= maxStatsCacheEntries {
+			for k, e := range statsCache.entries {
+				if now.After(e.expires) {
+					delete(statsCache.entries, k)
+				}
+			}
+			if len(statsCache.entries) >= maxStatsCacheEntries {
+				statsCache.entries = make(map[string]statsCacheEntry)
+			}
+		}
+		statsCache.entries[key] = statsCacheEntry{body: writer.body.Bytes(), group: group, expires: now.Add(ttl)}
+	}
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const (
+	// storageLowWater is the fraction of quota a device is trimmed down to once
+	// it goes over, so retention doesn't run again on the very next image
+	storageLowWater = 0.9
+
+	// retentionBatchSize is how many of a device's oldest images are removed per query
+	retentionBatchSize = 500
+
+	defaultRetentionInterval = 10 * time.Minute
+)
+
+// defaultDeviceQuotaBytes applies to devices without an override (0 = unlimited)
+var defaultDeviceQuotaBytes int64
+
+// InitStorageQuota reads DEVICE_STORAGE_QUOTA_MB (0 or unset = unlimited)
+// and returns the default per-device quota in bytes
+func InitStorageQuota() int64 {
+	defaultDeviceQuotaBytes = 0
+	if v := os.Getenv("DEVICE_STORAGE_QUOTA_MB"); v != "" {
+		if mb, err := strconv.ParseInt(v, 10, 64); err == nil && mb > 0 {
+			defaultDeviceQuotaBytes = mb * 1024 * 1024
+		}
+	}
+	return defaultDeviceQuotaBytes
+}
+
+// StartStorageRetention periodically purges expired detection images, moves
+// old ones to cold storage and trims devices that are over their storage quota.
+// The interval is read from STORAGE_RETENTION_INTERVAL_MINUTES (default 10).
+func StartStorageRetention() {
+	interval := defaultRetentionInterval
+	if v := os.Getenv("STORAGE_RETENTION_INTERVAL_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins > 0 {
+			interval = time.Duration(mins) * time.Minute
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeDetectionImages()
+			archiveColdImages()
+			enforceStorageQuotas()
+		}
+	}()
+}
+
+// recordStoredImage accounts a saved evidence image against its device
+func recordStoredImage(event IngestEvent, path, url string, size int64) {
+	image := models.StoredImage{
+		DeviceID:  event.DeviceID,
+		WorkerID:  event.WorkerID,
+		EventType: event.Type,
+		Path:      path,
+		URL:       url,
+		Bytes:     size,
+		CreatedAt: time.Now(),
+	}
+	if err := database.DB.Create(&image).Error; err != nil {
+		log.Printf("⚠️ [STORAGE] Failed to record image - Device: %s, Path: %s, Error: %v", event.DeviceID, path, err)
+	}
+}
+
+// deviceStorageUsage is the aggregate of stored images for one device
+type deviceStorageUsage struct {
+	DeviceID string    `json:"deviceId"`
+	WorkerID string    `json:"workerId"`
+	Bytes    int64     `json:"bytes"`
+	Images   int64     `json:"images"`
+	Oldest   time.Time `json:"oldest"`
+}
+
+// workerStorageUsage rolls device usage up to the worker that uploaded it
+type workerStorageUsage struct {
+	WorkerID string `json:"workerId"`
+	Bytes    int64  `json:"bytes"`
+	Images   int64  `json:"images"`
+	Devices  int    `json:"devices"`
+}
+
+// loadDeviceUsage sums each device's images on local storage; images moved
+// to cold storage don't count against quotas
+func loadDeviceUsage() ([]deviceStorageUsage, error) {
+	var usage []deviceStorageUsage
+	err := database.DB.Model(&models.StoredImage{}).
+		Where("cold_key IS NULL").
+		Select("device_id, MAX(worker_id) AS worker_id, SUM(bytes) AS bytes, COUNT(*) AS images, MIN(created_at) AS oldest").
+		Group("device_id").
+		Order("bytes DESC").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// loadQuotaOverrides returns per-device quota overrides keyed by device ID
+func loadQuotaOverrides() (map[string]int64, error) {
+	var quotas []models.DeviceStorageQuota
+	if err := database.DB.Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]int64, len(quotas))
+	for _, q := range quotas {
+		overrides[q.DeviceID] = q.QuotaBytes
+	}
+	return overrides, nil
+}
+
+func quotaFor(deviceID string, overrides map[string]int64) int64 {
+	if q, ok := overrides[deviceID]; ok {
+		return q
+	}
+	return defaultDeviceQuotaBytes
+}
+
+// enforceStorageQuotas trims every device that is over its quota
+func enforceStorageQuotas() {
+	usage, err := loadDeviceUsage()
+	if err != nil {
+		log.Printf("⚠️ [STORAGE] Failed to load usage: %v", err)
+		return
+	}
+	overrides, err := loadQuotaOverrides()
+	if err != nil {
+		log.Printf("⚠️ [STORAGE] Failed to load quotas: %v", err)
+		return
+	}
+
+	for _, u := range usage {
+		quota := quotaFor(u.DeviceID, overrides)
+		if quota > 0 && u.Bytes > quota {
+			trimDeviceStorage(u.DeviceID, u.Bytes, int64(float64(quota)*storageLowWater))
+		}
+	}
+}
+
+// trimDeviceStorage deletes a device's oldest images until its usage is at or
+// below target. Violation evidence and images under a legal hold are never
+// trimmed.
+func trimDeviceStorage(deviceID string, used, target int64) {
+	var freed, removed int64
+
+	for used-freed > target {
+		var batch []models.StoredImage
+		if err := database.DB.Where("device_id = ? AND cold_key IS NULL", deviceID).
+			Where("NOT " + violationImageCondition).
+			Where("NOT " + heldImageCondition).
+			Order("created_at ASC").
+			Limit(retentionBatchSize).
+			Find(&batch).Error; err != nil {
+			log.Printf("⚠️ [STORAGE] Failed to load images for %s: %v", deviceID, err)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]int64, 0, len(batch))
+		for _, img := range batch {
+			if used-freed <= target {
+				break
+			}
+			if err := os.Remove(img.Path); err != nil && !os.IsNotExist(err) {
+				log.Printf("⚠️ [STORAGE] Failed to delete %s: %v", img.Path, err)
+				continue
+			}
+			ids = append(ids, img.ID)
+			freed += img.Bytes
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := database.DB.Delete(&models.StoredImage{}, ids).Error; err != nil {
+			log.Printf("⚠️ [STORAGE] Failed to remove image records for %s: %v", deviceID, err)
+			break
+		}
+		removed += int64(len(ids))
+	}
+
+	if removed > 0 {
+		log.Printf("🧹 [STORAGE] Device %s over quota - removed %d oldest images (%d MB freed)",
+			deviceID, removed, freed/(1024*1024))
+	}
+}
+
+// GetStorageUsage returns image storage usage per device and per worker (admin)
+// GET /api/admin/storage/usage
+func GetStorageUsage(c *gin.Context) {
+	usage, err := loadDeviceUsage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage usage"})
+		return
+	}
+	overrides, err := loadQuotaOverrides()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage quotas"})
+		return
+	}
+
+	var totalBytes, totalImages int64
+	devices := make([]gin.H, 0, len(usage))
+	workers := make(map[string]*workerStorageUsage)
+	byWorker := make([]*workerStorageUsage, 0)
+
+	for _, u := range usage {
+		totalBytes += u.Bytes
+		totalImages += u.Images
+
+		quota := quotaFor(u.DeviceID, overrides)
+		_, hasOverride := overrides[u.DeviceID]
+		device := gin.H{
+			"deviceId":    u.DeviceID,
+			"workerId":    u.WorkerID,
+			"bytes":       u.Bytes,
+			"images":      u.Images,
+			"oldest":      u.Oldest,
+			"quotaBytes":  quota,
+			"customQuota": hasOverride,
+			"overQuota":   quota > 0 && u.Bytes > quota,
+		}
+		if quota > 0 {
+			device["usedPercent"] = float64(u.Bytes) / float64(quota) * 100
+		}
+		devices = append(devices, device)
+
+		w, ok := workers[u.WorkerID]
+		if !ok {
+			w = &workerStorageUsage{WorkerID: u.WorkerID}
+			workers[u.WorkerID] = w
+			byWorker = append(byWorker, w)
+		}
+		w.Bytes += u.Bytes
+		w.Images += u.Images
+		w.Devices++
+	}
+
+	var cold struct {
+		Bytes  int64
+		Images int64
+	}
+	database.DB.Model(&models.StoredImage{}).Where("cold_key IS NOT NULL").
+		Select("COALESCE(SUM(bytes), 0) AS bytes, COUNT(*) AS images").Scan(&cold)
+
+	c.JSON(http.StatusOK, gin.H{
+		"defaultQuotaBytes": defaultDeviceQuotaBytes,
+		"totalBytes":        totalBytes,
+		"totalImages":       totalImages,
+		"coldBytes":         cold.Bytes, // Moved to cold storage, not in the totals above
+		"coldImages":        cold.Images,
+		"devices":           devices,
+		"workers":           byWorker,
+	})
+}
+
+// SetDeviceStorageQuota sets a per-device storage quota override (admin)
+// PUT /api/admin/storage/quotas/:deviceId
+func SetDeviceStorageQuota(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	var req struct {
+		QuotaMB   *int64 `json:"quotaMb" binding:"required"` // 0 = unlimited
+		ChangedBy string `json:"changedBy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.QuotaMB < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quotaMb must not be negative"})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	var device models.Device
+	if err := database.DB.Select("id").First(&device, "id = ?", deviceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	quota := models.DeviceStorageQuota{
+		DeviceID:   deviceID,
+		QuotaBytes: *req.QuotaMB * 1024 * 1024,
+		UpdatedBy:  req.ChangedBy,
+	}
+	if err := database.DB.Save(&quota).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save quota"})
+		return
+	}
+
+	log.Printf("📝 [STORAGE] Quota for %s set to %d MB by %s", deviceID, *req.QuotaMB, req.ChangedBy)
+	c.JSON(http.StatusOK, quota)
+}
+
+// DeleteDeviceStorageQuota removes a device's quota override so the default applies (admin)
+// DELETE /api/admin/storage/quotas/:deviceId
+func DeleteDeviceStorageQuota(c *gin.Context) {
+	deviceID := c.Param("deviceId")
+
+	result := database.DB.Delete(&models.DeviceStorageQuota{}, "device_id = ?", deviceID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quota"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No quota override for device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quota override removed"})
+}
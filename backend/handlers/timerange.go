@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTimeRange caps how far apart startTime and endTime may be in a query
+const maxTimeRange = 366 * 24 * time.Hour
+
+// parseTimeRange parses the startTime/endTime (RFC3339) query params shared by
+// the stats and list handlers.
+//
+// endTime defaults to now. startTime defaults to endTime-defaultDuration; with a
+// defaultDuration of 0 a missing startTime is returned as the zero time, meaning
+// the range is open-ended (callers should skip the lower bound).
+// Malformed timestamps, start >= end, and ranges longer than maxTimeRange are
+// rejected so callers can respond with 400 instead of silently returning nothing.
+func parseTimeRange(c *gin.Context, defaultDuration time.Duration) (start, end time.Time, err error) {
+	return parseTimeRangeParams(c, "startTime", "endTime", defaultDuration)
+}
+
+// parseTimeRangeParams is parseTimeRange for a range given in other params
+func parseTimeRangeParams(c *gin.Context, startParam, endParam string, defaultDuration time.Duration) (start, end time.Time, err error) {
+	end = time.Now()
+	if endStr := c.Query(endParam); endStr != "" {
+		end, err = time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid %s, expected RFC3339: %q", endParam, endStr)
+		}
+	}
+
+	if startStr := c.Query(startParam); startStr != "" {
+		start, err = time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid %s, expected RFC3339: %q", startParam, startStr)
+		}
+	} else if defaultDuration > 0 {
+		start = end.Add(-defaultDuration)
+	}
+
+	if start.IsZero() {
+		return start, end, nil
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%s must be before %s", startParam, endParam)
+	}
+	if end.Sub(start) > maxTimeRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("time range too large, maximum is %d days", int(maxTimeRange.Hours()/24))
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// defaultTrackDedupWindow is how long a track ID (or plate) stays "the same vehicle" on a device
+const defaultTrackDedupWindow = 60 * time.Second
+
+// getTrackDedupWindow returns the dedup window from TRACK_DEDUP_WINDOW_SECONDS.
+// A value of 0 disables dedup.
+func getTrackDedupWindow() time.Duration {
+	if v := os.Getenv("TRACK_DEDUP_WINDOW_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return defaultTrackDedupWindow
+}
+
+// trackIDFromData extracts the edge tracker ID from event data.
+// Trackers send it either top-level or nested under "metadata", as a string or number.
+func trackIDFromData(data map[string]interface{}) string {
+	raw, ok := data["track_id"]
+	if !ok {
+		if meta, isMap := data["metadata"].(map[string]interface{}); isMap {
+			raw, ok = meta["track_id"]
+		}
+	}
+	if !ok || raw == nil {
+		return ""
+	}
+
+	switch v := raw.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// findDuplicateDetection looks for a detection of the same vehicle by the same
+// analytic on the same device within the dedup window. The track ID is the
+// primary key; the plate is only used when the edge didn't send a track ID.
+// Analytics keep their own detections, so an ANPR read is never taken for a
+// duplicate of the VCC count of the same track.
+func findDuplicateDetection(analytic, deviceID, trackID, plateNumber string, ts time.Time) (*models.VehicleDetection, bool) {
+	window := getTrackDedupWindow()
+	if window == 0 || (trackID == "" && plateNumber == "") {
+		return nil, false
+	}
+
+	query := database.DB.Where("analytic = ? AND device_id = ? AND timestamp >= ? AND timestamp <= ?",
+		analytic, deviceID, ts.Add(-window), ts.Add(window))
+	if trackID != "" {
+		query = query.Where("track_id = ?", trackID)
+	} else {
+		query = query.Where("plate_number = ?", plateNumber)
+	}
+
+	var existing models.VehicleDetection
+	if err := query.Order("timestamp DESC").First(&existing).Error; err != nil {
+		return nil, false
+	}
+	return &existing, true
+}
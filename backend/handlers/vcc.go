@@ -12,26 +12,48 @@ import (
 	"github.com/irisdrone/backend/models"
 )
 
-// GetVCCStats handles GET /api/vcc/stats - Vehicle Classification and Counting statistics
-func GetVCCStats(c *gin.Context) {
-	// Parse time range
-	startTime := time.Now().AddDate(0, 0, -7) // Default: last 7 days
-	endTime := time.Now()
-
-	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
-			startTime = parsed
-		}
+// parseMinConfidence reads the optional minConfidence query parameter (0-1)
+func parseMinConfidence(c *gin.Context) (float64, error) {
+	v := c.Query("minConfidence")
+	if v == "" {
+		return 0, nil
 	}
+	min, err := strconv.ParseFloat(v, 64)
+	if err != nil || min < 0 || min > 1 {
+		return 0, fmt.Errorf("minConfidence must be a number between 0 and 1")
 	}
-	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
-			endTime = parsed
-		}
+	return min, nil
+}
+
+// vccConfidenceFilter returns a SQL condition keeping detections whose
+// confidence column is at least minConfidence ("TRUE" without a threshold).
+// Detections with no recorded confidence, such as plain ANPR reads, are kept.
+func vccConfidenceFilter(column string, minConfidence float64) string {
+	if minConfidence <= 0 {
+		return "TRUE"
+	}
+	return fmt.Sprintf("(%s IS NULL OR %s >= %s)", column, column, strconv.FormatFloat(minConfidence, 'f', -1, 64))
+}
+
+// GetVCCStats handles GET /api/vcc/stats - Vehicle Classification and Counting statistics.
+// With minConfidence, detections below it are left out and reported as excludedDetections.
+func GetVCCStats(c *gin.Context) {
+	// Parse time range (default: last 7 days)
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	location := c.Query("location")
 
+	minConfidence, err := parseMinConfidence(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	confidence := vccConfidenceFilter("vehicle_detections.confidence", minConfidence)
+
 	// Group by time period
 	groupBy := c.DefaultQuery("groupBy", "hour") // hour, day, week, month
 
@@ -47,6 +69,8 @@ func GetVCCStats(c *gin.Context) {
 		PeakDay          string                        `json:"peakDay"`
 		AveragePerHour   float64                       `json:"averagePerHour"`
 		Classification   map[string]interface{}        `json:"classification"`
+		MinConfidence    float64                       `json:"minConfidence,omitempty"`
+		ExcludedDetections int64                       `json:"excludedDetections"` // Below minConfidence, left out of every count
 	}
 
 	stats.ByVehicleType = make(map[string]int64)
@@ -60,7 +84,19 @@ func GetVCCStats(c *gin.Context) {
 		totalQuery = totalQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	totalQuery.Count(&stats.TotalDetections)
+	totalQuery.Where(confidence).Count(&stats.TotalDetections)
+
+	// Detections the confidence threshold leaves out
+	if minConfidence > 0 {
+		stats.MinConfidence = minConfidence
+		excludedQuery := database.DB.Model(&models.VehicleDetection{}).
+			Where("timestamp >= ? AND timestamp <= ? AND NOT "+confidence, startTime, endTime)
+		if location != "" {
+			excludedQuery = excludedQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
+				Where("devices.metadata->>'location' = ?", location)
+		}
+		excludedQuery.Count(&stats.ExcludedDetections)
+	}
 
 	// Unique vehicles detected
 	uniqueQuery := database.DB.Model(&models.VehicleDetection{}).
@@ -69,7 +105,7 @@ func GetVCCStats(c *gin.Context) {
 		uniqueQuery = uniqueQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	uniqueQuery.Distinct("vehicle_id").Count(&stats.UniqueVehicles)
+	uniqueQuery.Where(confidence).Distinct("vehicle_id").Count(&stats.UniqueVehicles)
 
 	// Count by vehicle type
 	var typeCounts []struct {
@@ -83,7 +119,7 @@ func GetVCCStats(c *gin.Context) {
 		typeQuery = typeQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	typeQuery.Group("vehicle_type").Scan(&typeCounts)
+	typeQuery.Where(confidence).Group("vehicle_type").Scan(&typeCounts)
 
 	for _, tc := range typeCounts {
 		stats.ByVehicleType[tc.VehicleType] = tc.Count
@@ -152,20 +188,20 @@ func GetVCCStats(c *gin.Context) {
 			%s
 			FROM vehicle_detections T
 			JOIN devices ON T.device_id = devices.id
-			WHERE T.timestamp >= ? AND T.timestamp <= ?
+			WHERE T.timestamp >= ? AND T.timestamp <= ? AND %s
 			AND devices.metadata->>'location' = ?
 			GROUP BY DATE_TRUNC('%s', T.timestamp)
 			ORDER BY DATE_TRUNC('%s', T.timestamp)
-		`, selectClause, timeTrunc, timeTrunc)
+		`, selectClause, vccConfidenceFilter("T.confidence", minConfidence), timeTrunc, timeTrunc)
 		args = []interface{}{startTime, endTime, location}
 	} else {
 		rawQuery = fmt.Sprintf(`
 			%s
 			FROM vehicle_detections T
-			WHERE T.timestamp >= ? AND T.timestamp <= ?
+			WHERE T.timestamp >= ? AND T.timestamp <= ? AND %s
 			GROUP BY DATE_TRUNC('%s', T.timestamp)
 			ORDER BY DATE_TRUNC('%s', T.timestamp)
-		`, selectClause, timeTrunc, timeTrunc)
+		`, selectClause, vccConfidenceFilter("T.confidence", minConfidence), timeTrunc, timeTrunc)
 		args = []interface{}{startTime, endTime}
 	}
 	
@@ -202,7 +238,7 @@ func GetVCCStats(c *gin.Context) {
 		dtQuery = dtQuery.Where("devices.metadata->>'location' = ?", location)
 	}
 
-	dtQuery.Group("vehicle_detections.device_id, devices.name, vehicle_type").
+	dtQuery.Where(confidence).Group("vehicle_detections.device_id, devices.name, vehicle_type").
 		Scan(&deviceTypeCounts)
 
 	// Aggregate by device
@@ -254,7 +290,7 @@ func GetVCCStats(c *gin.Context) {
 			FROM vehicle_detections
 			JOIN devices ON vehicle_detections.device_id = devices.id
 			WHERE vehicle_detections.timestamp >= ? AND vehicle_detections.timestamp <= ?
-			AND devices.metadata->>'location' = ?
+			AND devices.metadata->>'location' = ? AND `+confidence+`
 			GROUP BY EXTRACT(HOUR FROM vehicle_detections.timestamp)
 			ORDER BY hour
 		`
@@ -263,7 +299,7 @@ func GetVCCStats(c *gin.Context) {
 		hourQuery = `
 			SELECT EXTRACT(HOUR FROM timestamp)::int as hour, COUNT(*) as count
 			FROM vehicle_detections
-			WHERE timestamp >= ? AND timestamp <= ?
+			WHERE timestamp >= ? AND timestamp <= ? AND `+confidence+`
 			GROUP BY EXTRACT(HOUR FROM timestamp)
 			ORDER BY hour
 		`
@@ -299,7 +335,7 @@ func GetVCCStats(c *gin.Context) {
 			FROM vehicle_detections
 			JOIN devices ON vehicle_detections.device_id = devices.id
 			WHERE vehicle_detections.timestamp >= ? AND vehicle_detections.timestamp <= ?
-			AND devices.metadata->>'location' = ?
+			AND devices.metadata->>'location' = ? AND `+confidence+`
 			GROUP BY TO_CHAR(vehicle_detections.timestamp, 'Day')
 			ORDER BY count DESC
 		`
@@ -308,7 +344,7 @@ func GetVCCStats(c *gin.Context) {
 		dayQuery = `
 			SELECT TO_CHAR(timestamp, 'Day') as day_of_week, COUNT(*) as count
 			FROM vehicle_detections
-			WHERE timestamp >= ? AND timestamp <= ?
+			WHERE timestamp >= ? AND timestamp <= ? AND `+confidence+`
 			GROUP BY TO_CHAR(timestamp, 'Day')
 			ORDER BY count DESC
 		`
@@ -353,7 +389,7 @@ func GetVCCStats(c *gin.Context) {
 		wpQuery = wpQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	wpQuery.Count(&withPlates)
+	wpQuery.Where(confidence).Count(&withPlates)
 	
 	wopQuery := database.DB.Model(&models.VehicleDetection{}).
 		Where("timestamp >= ? AND timestamp <= ? AND plate_detected = ?", startTime, endTime, false)
@@ -361,7 +397,7 @@ func GetVCCStats(c *gin.Context) {
 		wopQuery = wopQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	wopQuery.Count(&withoutPlates)
+	wopQuery.Where(confidence).Count(&withoutPlates)
 
 	wmmQuery := database.DB.Model(&models.VehicleDetection{}).
 		Where("timestamp >= ? AND timestamp <= ? AND make_model_detected = ?", startTime, endTime, true)
@@ -369,7 +405,7 @@ func GetVCCStats(c *gin.Context) {
 		wmmQuery = wmmQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	wmmQuery.Count(&withMakeModel)
+	wmmQuery.Where(confidence).Count(&withMakeModel)
 
 	stats.Classification["withPlates"] = withPlates
 	stats.Classification["withoutPlates"] = withoutPlates
@@ -390,7 +426,7 @@ func GetVCCStats(c *gin.Context) {
 		dirQuery = dirQuery.Joins("JOIN devices ON vehicle_detections.device_id = devices.id").
 			Where("devices.metadata->>'location' = ?", location)
 	}
-	dirQuery.Group("direction").Scan(&directionCounts)
+	dirQuery.Where(confidence).Group("direction").Scan(&directionCounts)
 
 	byDirection := make(map[string]int64)
 	for _, dc := range directionCounts {
@@ -409,24 +445,23 @@ func GetVCCStats(c *gin.Context) {
 func GetVCCByDevice(c *gin.Context) {
 	deviceID := c.Param("deviceId")
 
-	// Parse time range
-	startTime := time.Now().AddDate(0, 0, -1) // Default: last 24 hours
-	endTime := time.Now()
-
-	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
-			startTime = parsed
-		}
-	}
-	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
-			endTime = parsed
-		}
+	// Parse time range (default: last 24 hours)
+	startTime, endTime, err := parseTimeRange(c, 24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Group by time period
 	groupBy := c.DefaultQuery("groupBy", "hour")
 
+	minConfidence, err := parseMinConfidence(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	confidence := vccConfidenceFilter("vehicle_detections.confidence", minConfidence)
+
 	var stats struct {
 		DeviceID        string                `json:"deviceId"`
 		DeviceName      string                `json:"deviceName"`
@@ -439,6 +474,8 @@ func GetVCCByDevice(c *gin.Context) {
 		PeakHour        int                   `json:"peakHour"`
 		AveragePerHour  float64               `json:"averagePerHour"`
 		Classification  map[string]interface{} `json:"classification"`
+		MinConfidence   float64               `json:"minConfidence,omitempty"`
+		ExcludedDetections int64              `json:"excludedDetections"` // Below minConfidence, left out of every count
 	}
 
 	stats.ByVehicleType = make(map[string]int64)
@@ -508,10 +545,10 @@ func GetVCCByDevice(c *gin.Context) {
 		SUM(CASE WHEN vehicle_type = 'TRUCK' THEN 1 ELSE 0 END) as count_truck,
 		SUM(CASE WHEN vehicle_type = 'HMV' THEN 1 ELSE 0 END) as count_hmv
 		FROM vehicle_detections
-		WHERE device_id = $1 AND timestamp >= $2 AND timestamp <= $3
+		WHERE device_id = $1 AND timestamp >= $2 AND timestamp <= $3 AND %s
 		GROUP BY DATE_TRUNC('%s', timestamp)
 		ORDER BY DATE_TRUNC('%s', timestamp)
-	`, timeTrunc, timeFormat, timeTrunc, timeTrunc)
+	`, timeTrunc, timeFormat, confidence, timeTrunc, timeTrunc)
 	
 	database.DB.Raw(query, deviceID, startTime, endTime).Scan(&timeCounts)
 
@@ -532,11 +569,20 @@ func GetVCCByDevice(c *gin.Context) {
 	// Total detections
 	database.DB.Model(&models.VehicleDetection{}).
 		Where("device_id = ? AND timestamp >= ? AND timestamp <= ?", deviceID, startTime, endTime).
+		Where(confidence).
 		Count(&stats.TotalDetections)
 
+	if minConfidence > 0 {
+		stats.MinConfidence = minConfidence
+		database.DB.Model(&models.VehicleDetection{}).
+			Where("device_id = ? AND timestamp >= ? AND timestamp <= ? AND NOT "+confidence, deviceID, startTime, endTime).
+			Count(&stats.ExcludedDetections)
+	}
+
 	// Unique vehicles
 	database.DB.Model(&models.VehicleDetection{}).
 		Where("device_id = ? AND timestamp >= ? AND timestamp <= ? AND vehicle_id IS NOT NULL", deviceID, startTime, endTime).
+		Where(confidence).
 		Distinct("vehicle_id").
 		Count(&stats.UniqueVehicles)
 
@@ -548,6 +594,7 @@ func GetVCCByDevice(c *gin.Context) {
 	database.DB.Model(&models.VehicleDetection{}).
 		Select("vehicle_type, COUNT(*) as count").
 		Where("device_id = ? AND timestamp >= ? AND timestamp <= ?", deviceID, startTime, endTime).
+		Where(confidence).
 		Group("vehicle_type").
 		Scan(&typeCounts)
 
@@ -563,7 +610,7 @@ func GetVCCByDevice(c *gin.Context) {
 	database.DB.Raw(`
 		SELECT EXTRACT(HOUR FROM timestamp)::int as hour, COUNT(*) as count
 		FROM vehicle_detections
-		WHERE device_id = ? AND timestamp >= ? AND timestamp <= ?
+		WHERE device_id = ? AND timestamp >= ? AND timestamp <= ? AND `+confidence+`
 		GROUP BY EXTRACT(HOUR FROM timestamp)
 		ORDER BY hour
 	`, deviceID, startTime, endTime).Scan(&hourCounts)
@@ -590,7 +637,7 @@ func GetVCCByDevice(c *gin.Context) {
 	database.DB.Raw(`
 		SELECT TO_CHAR(timestamp, 'Day') as day_of_week, COUNT(*) as count
 		FROM vehicle_detections
-		WHERE device_id = ? AND timestamp >= ? AND timestamp <= ?
+		WHERE device_id = ? AND timestamp >= ? AND timestamp <= ? AND `+confidence+`
 		GROUP BY TO_CHAR(timestamp, 'Day')
 		ORDER BY count DESC
 	`, deviceID, startTime, endTime).Scan(&dayCounts)
@@ -610,10 +657,12 @@ func GetVCCByDevice(c *gin.Context) {
 	var withPlates, withMakeModel int64
 	database.DB.Model(&models.VehicleDetection{}).
 		Where("device_id = ? AND timestamp >= ? AND timestamp <= ? AND plate_detected = ?", deviceID, startTime, endTime, true).
+		Where(confidence).
 		Count(&withPlates)
 
 	database.DB.Model(&models.VehicleDetection{}).
 		Where("device_id = ? AND timestamp >= ? AND timestamp <= ? AND make_model_detected = ?", deviceID, startTime, endTime, true).
+		Where(confidence).
 		Count(&withMakeModel)
 
 	stats.Classification = map[string]interface{}{
@@ -693,19 +742,11 @@ func GetVCCRealtime(c *gin.Context) {
 
 // GetVCCEvents handles GET /api/vcc/events - List raw VCC detection events
 func GetVCCEvents(c *gin.Context) {
-	// Parse range
-	startTime := time.Now().AddDate(0, 0, -7)
-	endTime := time.Now()
-
-	if startTimeStr := c.Query("startTime"); startTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
-			startTime = parsed
-		}
-	}
-	if endTimeStr := c.Query("endTime"); endTimeStr != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
-			endTime = parsed
-		}
+	// Parse range (default: last 7 days)
+	startTime, endTime, err := parseTimeRange(c, 7*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	query := database.DB.Model(&models.VehicleDetection{}).
@@ -719,6 +760,12 @@ func GetVCCEvents(c *gin.Context) {
 	if vehicleType := c.Query("vehicleType"); vehicleType != "" {
 		query = query.Where("vehicle_type = ?", vehicleType)
 	}
+	region, err := parseFrameRegion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = frameRegionScope(query, region)
 
 	// Pagination
 	limit := 1000
@@ -742,6 +789,9 @@ func GetVCCEvents(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
 		return
 	}
+	fillDetectionLocation(detections)
+	signDetectionImages(c, detections)
+	maskCountingPlates(detections)
 
 	c.JSON(http.StatusOK, gin.H{
 		"events": detections,
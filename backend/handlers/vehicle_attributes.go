@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultAttributeConfidenceHalfLife = 30 * 24 * time.Hour
+
+// manualAttributeConfidence is recorded for attributes an operator set, so
+// only a detection more confident than the decayed edit replaces them
+const manualAttributeConfidence = 1.0
+
+// vehicleAttributeWeighting controls how detections update a vehicle's
+// make, model, type and color
+var vehicleAttributeWeighting = struct {
+	enabled  bool
+	halfLife time.Duration // 0 = confidences don't decay
+}{enabled: true, halfLife: defaultAttributeConfidenceHalfLife}
+
+// InitVehicleAttributeWeighting reads VEHICLE_ATTRIBUTE_WEIGHTING (default
+// true; false = the latest detection wins) and
+// VEHICLE_ATTRIBUTE_CONFIDENCE_HALF_LIFE_DAYS (default 30, 0 = no decay).
+// Returns whether weighting is on and the half-life.
+func InitVehicleAttributeWeighting() (bool, time.Duration) {
+	vehicleAttributeWeighting.enabled = os.Getenv("VEHICLE_ATTRIBUTE_WEIGHTING") != "false"
+	vehicleAttributeWeighting.halfLife = defaultAttributeConfidenceHalfLife
+	if v := os.Getenv("VEHICLE_ATTRIBUTE_CONFIDENCE_HALF_LIFE_DAYS"); v != "" {
+		if days, err := strconv.ParseFloat(v, 64); err == nil && days >= 0 {
+			vehicleAttributeWeighting.halfLife = time.Duration(days * float64(24*time.Hour))
+		}
+	}
+	return vehicleAttributeWeighting.enabled, vehicleAttributeWeighting.halfLife
+}
+
+// vehicleAttributeValue returns the current value of a vehicle attribute
+// column, "" when unset
+func vehicleAttributeValue(vehicle *models.Vehicle, column string) string {
+	var value *string
+	switch column {
+	case "make":
+		value = vehicle.Make
+	case "model":
+		value = vehicle.Model
+	case "color":
+		value = vehicle.Color
+	case "vehicle_type":
+		if vehicle.VehicleType == models.VehicleTypeUnknown {
+			return ""
+		}
+		return string(vehicle.VehicleType)
+	}
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// attributeConfidence is the confidence that set an attribute's current
+// value, decayed by the half-life since then. Values set before weighting
+// have no recorded confidence and count as 0.
+func attributeConfidence(confidences map[string]interface{}, column string, at time.Time) float64 {
+	entry, ok := confidences[column].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	confidence, _ := entry["confidence"].(float64)
+	setAt, _ := entry["at"].(string)
+	if vehicleAttributeWeighting.halfLife == 0 {
+		return confidence
+	}
+	t, err := time.Parse(time.RFC3339, setAt)
+	if err != nil || !at.After(t) {
+		return confidence
+	}
+	return confidence * math.Pow(0.5, float64(at.Sub(t))/float64(vehicleAttributeWeighting.halfLife))
+}
+
+// vehicleAttributeConfidences returns a copy of a vehicle's recorded
+// per-attribute confidences
+func vehicleAttributeConfidences(vehicle *models.Vehicle) map[string]interface{} {
+	confidences := make(map[string]interface{})
+	if m, ok := vehicle.AttributeConfidence.Data.(map[string]interface{}); ok {
+		for k, v := range m {
+			confidences[k] = v
+		}
+	}
+	return confidences
+}
+
+// weighVehicleAttributes adds to updates the attributes a detection should
+// set on a vehicle. An attribute that disagrees with the current value only
+// replaces it when the detection is more confident than the (decayed)
+// confidence that set it, so the vehicle converges on its most reliable
+// observations instead of its latest. Agreeing detections refresh the
+// recorded confidence. attrs maps columns to detected values; empty values
+// are ignored.
+func weighVehicleAttributes(vehicle *models.Vehicle, attrs map[string]string, confidence *float64, at time.Time, updates map[string]interface{}) {
+	if !vehicleAttributeWeighting.enabled {
+		for column, value := range attrs {
+			if value != "" {
+				updates[column] = value
+			}
+		}
+		return
+	}
+
+	var detected float64
+	if confidence != nil {
+		detected = *confidence
+	}
+	confidences := vehicleAttributeConfidences(vehicle)
+	changed := false
+	for column, value := range attrs {
+		if value == "" {
+			continue
+		}
+		current := vehicleAttributeValue(vehicle, column)
+		if current != "" && detected <= attributeConfidence(confidences, column, at) {
+			continue
+		}
+		if current != value {
+			updates[column] = value
+		}
+		confidences[column] = map[string]interface{}{"confidence": detected, "at": at.UTC().Format(time.RFC3339)}
+		changed = true
+	}
+	if changed {
+		updates["attribute_confidence"] = models.NewJSONB(confidences)
+	}
+}
+
+// recordAttributeConfidences sets the confidence of attributes set without a
+// comparison: on a new vehicle, or by an operator
+func recordAttributeConfidences(vehicle *models.Vehicle, columns []string, confidence *float64, at time.Time) models.JSONB {
+	var recorded float64
+	if confidence != nil {
+		recorded = *confidence
+	}
+	confidences := vehicleAttributeConfidences(vehicle)
+	for _, column := range columns {
+		confidences[column] = map[string]interface{}{"confidence": recorded, "at": at.UTC().Format(time.RFC3339)}
+	}
+	return models.NewJSONB(confidences)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultEnrichViolationWindow = 30 * 24 * time.Hour
+
+// enrichViolationWindow is how far back violations count as recent in a
+// detection's vehicle history
+var enrichViolationWindow = defaultEnrichViolationWindow
+
+// InitVehicleEnrichment reads VEHICLE_ENRICH_VIOLATION_DAYS (default 30) and
+// returns the recent-violation window
+func InitVehicleEnrichment() time.Duration {
+	enrichViolationWindow = defaultEnrichViolationWindow
+	if v := os.Getenv("VEHICLE_ENRICH_VIOLATION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			enrichViolationWindow = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return enrichViolationWindow
+}
+
+// vehicleHistory summarizes what's known about a detected vehicle: how often
+// it's been seen, whether it's watchlisted and its recent violations. The
+// vehicle is expected to already reflect the detection being reported.
+func vehicleHistory(vehicle *models.Vehicle) gin.H {
+	since := time.Now().Add(-enrichViolationWindow)
+	violations := database.DB.Model(&models.TrafficViolation{}).Where("timestamp >= ?", since)
+	if vehicle.PlateNumber != nil && *vehicle.PlateNumber != "" {
+		violations = violations.Where("(vehicle_id = ? OR plate_number = ?)", vehicle.ID, *vehicle.PlateNumber)
+	} else {
+		violations = violations.Where("vehicle_id = ?", vehicle.ID)
+	}
+	var recentViolations int64
+	violations.Count(&recentViolations)
+
+	history := gin.H{
+		"detectionCount":      vehicle.DetectionCount,
+		"firstSeen":           vehicle.FirstSeen,
+		"isWatchlisted":       vehicle.IsWatchlisted,
+		"recentViolations":    recentViolations,
+		"recentViolationDays": int(enrichViolationWindow.Hours() / 24),
+	}
+
+	if watchlist, ok := activeWatchlistEntry(vehicle.ID); ok {
+		history["isWatchlisted"] = true
+		history["watchlist"] = gin.H{
+			"category": watchlist.Category,
+			"severity": watchlist.Severity,
+			"reason":   watchlist.Reason,
+		}
+	}
+	return history
+}
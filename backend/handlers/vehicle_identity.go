@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// vehicleIdentity is what makes two detections the same vehicle: the plate
+// number alone, or the plate number within its issuing region
+var vehicleIdentity = models.VehicleIdentityPlate
+
+// InitVehicleIdentity reads VEHICLE_IDENTITY (plate, the default, or
+// plate_region), migrates the unique index on vehicles to match and returns
+// the mode in effect. If existing vehicles conflict under the new mode the
+// previous index is kept, and so is matching on plate and region.
+//
+// Switching to plate_region first fills in the region of vehicles stored
+// without one, so their next detection finds them instead of creating a
+// second vehicle. A vehicle whose plate and region already exist is merged
+// into that one.
+func InitVehicleIdentity() string {
+	mode := os.Getenv("VEHICLE_IDENTITY")
+	if mode != models.VehicleIdentityPlateRegion {
+		if mode != "" && mode != models.VehicleIdentityPlate {
+			log.Printf("⚠️ [VEHICLE_IDENTITY] Unknown VEHICLE_IDENTITY %q, using plate", mode)
+		}
+		mode = models.VehicleIdentityPlate
+	}
+
+	if mode == models.VehicleIdentityPlateRegion {
+		filled, merged, err := backfillPlateRegions()
+		if err != nil {
+			log.Printf("⚠️ [VEHICLE_IDENTITY] Plate region backfill stopped: %v", err)
+		}
+		if filled > 0 || merged > 0 {
+			log.Printf("🚗 [VEHICLE_IDENTITY] Filled in the plate region of %d vehicles, merged %d into existing ones", filled, merged)
+		}
+	}
+
+	if err := database.EnsureVehicleIdentityIndex(mode); err != nil {
+		log.Printf("⚠️ [VEHICLE_IDENTITY] %v; vehicles stay unique on plate and region", err)
+		mode = models.VehicleIdentityPlateRegion
+	}
+	vehicleIdentity = mode
+	return mode
+}
+
+// plateRegion returns the issuing region of a plate: the one the edge
+// reported, else the device's configured plate_region (for cameras that only
+// see one region's temporary or trade plates), else the state code of a
+// standard plate. Bharat series plates aren't issued by a state and are
+// returned as BH. "" when unknown.
+func plateRegion(plate, reported string, device *models.Device) string {
+	if region := strings.ToUpper(strings.TrimSpace(reported)); region != "" {
+		return region
+	}
+	if device != nil {
+		if meta, ok := device.Metadata.Data.(map[string]interface{}); ok {
+			if region, _ := meta["plate_region"].(string); strings.TrimSpace(region) != "" {
+				return strings.ToUpper(strings.TrimSpace(region))
+			}
+		}
+	}
+	plate = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(plate))
+	switch {
+	case standardPlatePattern.MatchString(plate):
+		return plate[:2]
+	case bharatPlatePattern.MatchString(plate):
+		return "BH"
+	}
+	return ""
+}
+
+// vehicleByPlate scopes a query to the vehicle with a plate under the
+// identity mode in effect
+func vehicleByPlate(db *gorm.DB, plate, region string) *gorm.DB {
+	if vehicleIdentity == models.VehicleIdentityPlateRegion {
+		return db.Where("plate_number = ? AND plate_region = ?", plate, region)
+	}
+	return db.Where("plate_number = ?", plate)
+}
+
+// backfillPlateRegions sets the region of vehicles stored without one, using
+// the device of their latest detection for plates that don't show it. Returns
+// how many vehicles got a region and how many were merged into an existing
+// vehicle with the same plate and region. Vehicles whose region can't be told
+// stay "".
+func backfillPlateRegions() (filled, merged int, err error) {
+	var lastID int64
+	for {
+		var batch []models.Vehicle
+		if err := database.DB.
+			Where("plate_region = '' AND plate_number IS NOT NULL AND id > ?", lastID).
+			Order("id").Limit(vehiclePruneBatchSize).
+			Find(&batch).Error; err != nil {
+			return filled, merged, err
+		}
+		if len(batch) == 0 {
+			return filled, merged, nil
+		}
+		lastID = batch[len(batch)-1].ID
+
+		for _, vehicle := range batch {
+			region := plateRegion(*vehicle.PlateNumber, "", nil)
+			if region == "" {
+				var device models.Device
+				if database.DB.Where("id = (?)", database.DB.Model(&models.VehicleDetection{}).
+					Select("device_id").Where("vehicle_id = ?", vehicle.ID).
+					Order("timestamp DESC").Limit(1)).
+					First(&device).Error == nil {
+					region = plateRegion(*vehicle.PlateNumber, "", &device)
+				}
+			}
+			if region == "" {
+				continue
+			}
+
+			var existing models.Vehicle
+			err := database.DB.Where("plate_number = ? AND plate_region = ?", *vehicle.PlateNumber, region).
+				First(&existing).Error
+			switch {
+			case err == nil:
+				if err := database.DB.Transaction(func(tx *gorm.DB) error {
+					return mergeVehicle(tx, vehicle, existing)
+				}); err != nil {
+					return filled, merged, fmt.Errorf("merge vehicle %d into %d: %w", vehicle.ID, existing.ID, err)
+				}
+				merged++
+			case err == gorm.ErrRecordNotFound:
+				if err := database.DB.Model(&vehicle).Update("plate_region", region).Error; err != nil {
+					return filled, merged, err
+				}
+				filled++
+			default:
+				return filled, merged, err
+			}
+		}
+	}
+}
+
+// mergeVehicle moves everything linked to vehicle from onto vehicle into,
+// folds its sightings and attributes into into's and deletes it
+func mergeVehicle(tx *gorm.DB, from, into models.Vehicle) error {
+	for _, model := range []interface{}{
+		&models.VehicleDetection{}, &models.TrafficViolation{}, &models.OffenseSession{}, &models.VehicleEmbedding{},
+	} {
+		if err := tx.Model(model).Where("vehicle_id = ?", from.ID).Update("vehicle_id", into.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	// One watchlist entry per vehicle: into's wins
+	if err := tx.Where("vehicle_id = ? AND EXISTS (SELECT 1 FROM watchlist w WHERE w.vehicle_id = ?)", from.ID, into.ID).
+		Delete(&models.Watchlist{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Watchlist{}).Where("vehicle_id = ?", from.ID).Update("vehicle_id", into.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("vehicle_id = ? AND geofence_id IN (SELECT geofence_id FROM geofence_vehicles WHERE vehicle_id = ?)", from.ID, into.ID).
+		Delete(&models.GeofenceVehicle{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.GeofenceVehicle{}).Where("vehicle_id = ?", from.ID).Update("vehicle_id", into.ID).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"detection_count": into.DetectionCount + from.DetectionCount,
+		"is_watchlisted":  into.IsWatchlisted || from.IsWatchlisted,
+	}
+	if from.FirstSeen.Before(into.FirstSeen) {
+		updates["first_seen"] = from.FirstSeen
+	}
+	if from.LastSeen.After(into.LastSeen) {
+		updates["last_seen"] = from.LastSeen
+	}
+	if into.Make == nil && from.Make != nil {
+		updates["make"] = *from.Make
+	}
+	if into.Model == nil && from.Model != nil {
+		updates["model"] = *from.Model
+	}
+	if into.Color == nil && from.Color != nil {
+		updates["color"] = *from.Color
+	}
+	if (into.VehicleType == "" || into.VehicleType == models.VehicleTypeUnknown) && from.VehicleType != "" {
+		updates["vehicle_type"] = from.VehicleType
+	}
+	if err := tx.Model(&models.Vehicle{}).Where("id = ?", into.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Vehicle{}, from.ID).Error
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultVehiclePruneInterval = 24 * time.Hour
+
+	// vehiclePruneBatchSize is how many vehicles are archived or deleted per transaction
+	vehiclePruneBatchSize = 500
+)
+
+var (
+	errVehiclePruneDisabled = errors.New("vehicle pruning is disabled (VEHICLE_RETENTION_DAYS is unset)")
+	errVehiclePruneBusy     = errors.New("a pruning pass is already running")
+)
+
+// vehiclePrune holds the settings and counters of the vehicle registry pruning job
+var vehiclePrune = struct {
+	mu       sync.Mutex
+	maxAge   time.Duration // 0 = disabled
+	interval time.Duration
+	archive  bool // copy pruned vehicles to archived_vehicles before deleting them
+	dryRun   bool // only count what would be pruned
+
+	running bool
+	pruned  int64 // vehicles pruned since startup
+	lastRun *vehiclePruneResult
+}{
+	interval: defaultVehiclePruneInterval,
+	archive:  true,
+}
+
+// vehiclePruneResult is the outcome of one pruning pass
+type vehiclePruneResult struct {
+	StartedAt time.Time `json:"startedAt"`
+	Cutoff    time.Time `json:"cutoff"`
+	DryRun    bool      `json:"dryRun"`
+	Archived  bool      `json:"archived"`
+	Matched   int64     `json:"matched"` // vehicles eligible for pruning
+	Pruned    int64     `json:"pruned"`  // vehicles actually removed (0 on a dry run)
+	Error     string    `json:"error,omitempty"`
+}
+
+// InitVehiclePruning reads VEHICLE_RETENTION_DAYS (0 or unset = disabled),
+// VEHICLE_PRUNE_MODE (archive, the default, or delete),
+// VEHICLE_PRUNE_DRY_RUN and VEHICLE_PRUNE_INTERVAL_HOURS (default 24), starts
+// the pruning loop and returns the retention age
+func InitVehiclePruning() time.Duration {
+	vehiclePrune.mu.Lock()
+	defer vehiclePrune.mu.Unlock()
+
+	vehiclePrune.maxAge = 0
+	if v := os.Getenv("VEHICLE_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			vehiclePrune.maxAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	vehiclePrune.archive = os.Getenv("VEHICLE_PRUNE_MODE") != "delete"
+	vehiclePrune.dryRun = os.Getenv("VEHICLE_PRUNE_DRY_RUN") == "true"
+	vehiclePrune.interval = defaultVehiclePruneInterval
+	if v := os.Getenv("VEHICLE_PRUNE_INTERVAL_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			vehiclePrune.interval = time.Duration(hours) * time.Hour
+		}
+	}
+
+	if vehiclePrune.maxAge > 0 {
+		interval := vehiclePrune.interval
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				vehiclePrune.mu.Lock()
+				dryRun := vehiclePrune.dryRun
+				vehiclePrune.mu.Unlock()
+				pruneVehicles(dryRun)
+			}
+		}()
+	}
+
+	return vehiclePrune.maxAge
+}
+
+// prunableVehicles scopes vehicles to those not seen since cutoff that are of
+// no enforcement interest: never watchlisted and without any violation, linked
+// either by vehicle ID or by plate, and with no detection under legal hold
+func prunableVehicles(db *gorm.DB, cutoff time.Time) *gorm.DB {
+	return db.Model(&models.Vehicle{}).
+		Where("last_seen < ? AND is_watchlisted = ?", cutoff, false).
+		Where(`NOT EXISTS (SELECT 1 FROM watchlists w WHERE w.vehicle_id = vehicles.id)`).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM traffic_violations v
+			WHERE v.vehicle_id = vehicles.id
+				OR (vehicles.plate_number IS NOT NULL AND v.plate_number = vehicles.plate_number)
+		)`).
+		Where(`NOT EXISTS (SELECT 1 FROM vehicle_detections d WHERE d.vehicle_id = vehicles.id AND d.legal_hold)`)
+}
+
+// pruneVehicles archives (or deletes) vehicles past the retention age in
+// batches. With dryRun it only counts them. Only one pass runs at a time.
+func pruneVehicles(dryRun bool) (*vehiclePruneResult, error) {
+	vehiclePrune.mu.Lock()
+	if vehiclePrune.maxAge == 0 {
+		vehiclePrune.mu.Unlock()
+		return nil, errVehiclePruneDisabled
+	}
+	if vehiclePrune.running {
+		vehiclePrune.mu.Unlock()
+		return nil, errVehiclePruneBusy
+	}
+	vehiclePrune.running = true
+	result := &vehiclePruneResult{
+		StartedAt: time.Now(),
+		Cutoff:    time.Now().Add(-vehiclePrune.maxAge),
+		DryRun:    dryRun,
+		Archived:  vehiclePrune.archive,
+	}
+	vehiclePrune.mu.Unlock()
+
+	defer func() {
+		vehiclePrune.mu.Lock()
+		vehiclePrune.running = false
+		vehiclePrune.pruned += result.Pruned
+		vehiclePrune.lastRun = result
+		vehiclePrune.mu.Unlock()
+	}()
+
+	if err := prunableVehicles(database.DB, result.Cutoff).Count(&result.Matched).Error; err != nil {
+		result.Error = err.Error()
+		log.Printf("⚠️ [VEHICLES] Failed to count prunable vehicles: %v", err)
+		return result, err
+	}
+	if dryRun {
+		log.Printf("🔎 [VEHICLES] Dry run: %d vehicles not seen since %s would be pruned",
+			result.Matched, result.Cutoff.Format(time.RFC3339))
+		return result, nil
+	}
+
+	for result.Pruned < result.Matched {
+		n, err := pruneVehicleBatch(result.Cutoff, result.Archived)
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("⚠️ [VEHICLES] Failed to prune vehicles: %v", err)
+			break
+		}
+		if n == 0 {
+			break
+		}
+		result.Pruned += n
+	}
+
+	if result.Pruned > 0 {
+		action := "deleted"
+		if result.Archived {
+			action = "archived"
+		}
+		log.Printf("🧹 [VEHICLES] %s %d vehicles not seen since %s", action, result.Pruned, result.Cutoff.Format(time.RFC3339))
+	}
+	if result.Error != "" {
+		return result, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// pruneVehicleBatch removes one batch of prunable vehicles in a transaction,
+// archiving them first if configured. Their detections are kept but unlinked.
+func pruneVehicleBatch(cutoff time.Time, archive bool) (int64, error) {
+	var pruned int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var batch []models.Vehicle
+		if err := prunableVehicles(tx, cutoff).
+			Order("last_seen ASC").
+			Limit(vehiclePruneBatchSize).
+			Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(batch))
+		archived := make([]models.ArchivedVehicle, 0, len(batch))
+		for _, v := range batch {
+			ids = append(ids, v.ID)
+			archived = append(archived, models.ArchivedVehicle{
+				ID:             v.ID,
+				PlateNumber:    v.PlateNumber,
+				PlateRegion:    v.PlateRegion,
+				Make:           v.Make,
+				Model:          v.Model,
+				VehicleType:    v.VehicleType,
+				Color:          v.Color,
+				FirstSeen:      v.FirstSeen,
+				LastSeen:       v.LastSeen,
+				DetectionCount: v.DetectionCount,
+				Metadata:       v.Metadata,
+				CreatedAt:      v.CreatedAt,
+				ArchivedAt:     time.Now(),
+			})
+		}
+
+		if archive {
+			if err := tx.Create(&archived).Error; err != nil {
+				return fmt.Errorf("archive: %w", err)
+			}
+		}
+		if err := tx.Model(&models.VehicleDetection{}).
+			Where("vehicle_id IN ?", ids).
+			Update("vehicle_id", nil).Error; err != nil {
+			return fmt.Errorf("unlink detections: %w", err)
+		}
+		if err := tx.Delete(&models.Vehicle{}, ids).Error; err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		pruned = int64(len(ids))
+		return nil
+	})
+	return pruned, err
+}
+
+// GetVehiclePruneStats returns the pruning settings, counters and last run (admin)
+// GET /api/admin/vehicles/prune
+func GetVehiclePruneStats(c *gin.Context) {
+	vehiclePrune.mu.Lock()
+	defer vehiclePrune.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":       vehiclePrune.maxAge > 0,
+		"retentionDays": int(vehiclePrune.maxAge / (24 * time.Hour)),
+		"intervalHours": int(vehiclePrune.interval / time.Hour),
+		"archive":       vehiclePrune.archive,
+		"dryRun":        vehiclePrune.dryRun,
+		"running":       vehiclePrune.running,
+		"pruned":        vehiclePrune.pruned,
+		"lastRun":       vehiclePrune.lastRun,
+	})
+}
+
+// RunVehiclePrune runs a pruning pass now; ?dryRun=true only counts. A real
+// pass needs a confirmation token (admin).
+// POST /api/admin/vehicles/prune
+func RunVehiclePrune(c *gin.Context) {
+	vehiclePrune.mu.Lock()
+	dryRun := vehiclePrune.dryRun || c.Query("dryRun") == "true"
+	maxAge, archive := vehiclePrune.maxAge, vehiclePrune.archive
+	vehiclePrune.mu.Unlock()
+
+	if !dryRun && maxAge > 0 && !requireConfirmation(c, "prune vehicles", "registry", func() (gin.H, error) {
+		cutoff := time.Now().Add(-maxAge)
+		var matched int64
+		if err := prunableVehicles(database.DB, cutoff).Count(&matched).Error; err != nil {
+			return nil, err
+		}
+		return gin.H{"vehicles": matched, "cutoff": cutoff, "archived": archive}, nil
+	}) {
+		return
+	}
+
+	result, err := pruneVehicles(dryRun)
+	if errors.Is(err, errVehiclePruneDisabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, errVehiclePruneBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReIDDims       = 512
+	defaultReIDCandidates = 5000
+	defaultReIDWindow     = 24 * time.Hour
+	defaultReIDResults    = 20
+	maxReIDResults        = 100
+)
+
+// vehicleReID is the cross-camera re-identification setup: edges may send an
+// appearance embedding with a detection, searched by cosine similarity
+var vehicleReID = struct {
+	enabled       bool
+	pgvector      bool // nearest-neighbour search in Postgres; else a scan in Go
+	dims          int
+	maxCandidates int // embeddings a Go scan compares at most, newest first
+}{dims: defaultReIDDims, maxCandidates: defaultReIDCandidates}
+
+// InitVehicleReID reads VEHICLE_REID (default false), VEHICLE_REID_DIMS
+// (default 512) and VEHICLE_REID_MAX_CANDIDATES (default 5000) and, when
+// enabled, migrates the embeddings table. Returns whether it's enabled and
+// whether pgvector searches it.
+func InitVehicleReID() (bool, bool) {
+	vehicleReID.enabled = os.Getenv("VEHICLE_REID") == "true"
+	if v, err := strconv.Atoi(os.Getenv("VEHICLE_REID_DIMS")); err == nil && v > 0 {
+		vehicleReID.dims = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("VEHICLE_REID_MAX_CANDIDATES")); err == nil && v > 0 {
+		vehicleReID.maxCandidates = v
+	}
+	if !vehicleReID.enabled {
+		return false, false
+	}
+
+	pgvector, err := database.EnsureVehicleEmbeddings(vehicleReID.dims)
+	if err != nil {
+		log.Printf("❌ [REID] Failed to set up vehicle embeddings, re-identification disabled: %v", err)
+		vehicleReID.enabled = false
+		return false, false
+	}
+	vehicleReID.pgvector = pgvector
+	return true, pgvector
+}
+
+// normalizeEmbedding checks an embedding's size and scales it to unit length,
+// so cosine similarity is a dot product
+func normalizeEmbedding(values []float64) (models.Embedding, error) {
+	if len(values) != vehicleReID.dims {
+		return nil, fmt.Errorf("embedding has %d dimensions, expected %d", len(values), vehicleReID.dims)
+	}
+	var norm float64
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("embedding has a non-finite value")
+		}
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("embedding is all zeros")
+	}
+	norm = math.Sqrt(norm)
+	vec := make(models.Embedding, len(values))
+	for i, v := range values {
+		vec[i] = float32(v / norm)
+	}
+	return vec, nil
+}
+
+// detectionEmbedding reads the embedding an edge sent in the event data; nil
+// when there's none
+func detectionEmbedding(data map[string]interface{}) (models.Embedding, error) {
+	raw, ok := data["embedding"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, nil
+	}
+	values := make([]float64, len(raw))
+	for i, v := range raw {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("embedding value %d is not a number", i)
+		}
+		values[i] = f
+	}
+	return normalizeEmbedding(values)
+}
+
+// withoutEmbedding returns event data minus its embedding, which is stored
+// on its own rather than copied into detection metadata
+func withoutEmbedding(data map[string]interface{}) map[string]interface{} {
+	if !vehicleReID.enabled {
+		return data
+	}
+	if _, ok := data["embedding"]; !ok {
+		return data
+	}
+	stripped := make(map[string]interface{}, len(data)-1)
+	for k, v := range data {
+		if k != "embedding" {
+			stripped[k] = v
+		}
+	}
+	return stripped
+}
+
+// vectorLiteral formats an embedding as a pgvector value
+func vectorLiteral(vec models.Embedding) string {
+	parts := make([]string, len(vec))
+	for i, v := range vec {
+		parts[i] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// storeDetectionEmbedding saves the embedding sent with a stored detection.
+// A bad embedding is logged and skipped; the detection itself is kept.
+func storeDetectionEmbedding(db *gorm.DB, detection *models.VehicleDetection, data map[string]interface{}) {
+	if !vehicleReID.enabled {
+		return
+	}
+	vec, err := detectionEmbedding(data)
+	if err != nil {
+		log.Printf("⚠️ [REID] Ignoring embedding of detection %d from %s: %v", detection.ID, detection.DeviceID, err)
+		return
+	}
+	if vec == nil {
+		return
+	}
+
+	row := models.VehicleEmbedding{
+		DetectionID: detection.ID,
+		VehicleID:   detection.VehicleID,
+		DeviceID:    detection.DeviceID,
+		Timestamp:   detection.Timestamp,
+		Embedding:   vec,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		if !vehicleReID.pgvector {
+			return nil
+		}
+		return tx.Exec("UPDATE vehicle_embeddings SET vec = ?::vector WHERE id = ?", vectorLiteral(vec), row.ID).Error
+	})
+	if err != nil {
+		log.Printf("⚠️ [REID] Failed to store embedding of detection %d: %v", detection.ID, err)
+	}
+}
+
+// SimilarVehicleRequest - What to search for: a stored detection's embedding
+// or an embedding given directly
+type SimilarVehicleRequest struct {
+	DetectionID   *int64     `json:"detectionId"`
+	Embedding     []float64  `json:"embedding"`
+	DeviceIDs     []string   `json:"deviceIds"`     // only search these cameras
+	StartTime     *time.Time `json:"startTime"`     // default: 24h before endTime
+	EndTime       *time.Time `json:"endTime"`       // default: now
+	MinSimilarity float64    `json:"minSimilarity"` // cosine similarity, -1 to 1
+	Limit         int        `json:"limit"`         // default 20, at most 100
+}
+
+// embeddingHit is one nearest neighbour
+type embeddingHit struct {
+	DetectionID int64
+	Similarity  float64
+}
+
+// searchEmbeddingsPG ranks embeddings by cosine distance in Postgres
+func searchEmbeddingsPG(query *gorm.DB, vec models.Embedding, limit int) ([]embeddingHit, error) {
+	lit := vectorLiteral(vec)
+	var hits []embeddingHit
+	err := query.Select("detection_id, 1 - (vec <=> ?::vector) AS similarity", lit).
+		Where("vec IS NOT NULL").
+		Order(gorm.Expr("vec <=> ?::vector", lit)).
+		Limit(limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// searchEmbeddingsScan compares the newest candidates with vec in Go.
+// Returns the hits and how many candidates were compared.
+func searchEmbeddingsScan(query *gorm.DB, vec models.Embedding, limit int) ([]embeddingHit, int, error) {
+	var candidates []models.VehicleEmbedding
+	if err := query.Select("detection_id, embedding").
+		Order("timestamp DESC").
+		Limit(vehicleReID.maxCandidates).
+		Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]embeddingHit, 0, len(candidates))
+	for _, cand := range candidates {
+		if len(cand.Embedding) != len(vec) {
+			continue
+		}
+		var dot float64
+		for i, v := range cand.Embedding {
+			dot += float64(v) * float64(vec[i])
+		}
+		hits = append(hits, embeddingHit{DetectionID: cand.DetectionID, Similarity: dot})
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Similarity > hits[j].Similarity })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, len(candidates), nil
+}
+
+// FindSimilarVehicles finds detections whose appearance embedding is nearest
+// to a detection's or a given embedding, to re-identify plateless vehicles
+// across cameras
+// POST /api/vehicles/similar
+func FindSimilarVehicles(c *gin.Context) {
+	if !vehicleReID.enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle re-identification is disabled"})
+		return
+	}
+
+	var req SimilarVehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var vec models.Embedding
+	switch {
+	case req.DetectionID != nil:
+		var row models.VehicleEmbedding
+		if err := database.DB.First(&row, "detection_id = ?", *req.DetectionID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No embedding stored for detection"})
+			return
+		}
+		vec = row.Embedding
+	case len(req.Embedding) > 0:
+		var err error
+		if vec, err = normalizeEmbedding(req.Embedding); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "detectionId or embedding is required"})
+		return
+	}
+
+	end := time.Now()
+	if req.EndTime != nil {
+		end = *req.EndTime
+	}
+	start := end.Add(-defaultReIDWindow)
+	if req.StartTime != nil {
+		start = *req.StartTime
+	}
+	if !start.Before(end) || end.Sub(start) > maxTimeRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("startTime must be before endTime and at most %d days earlier", int(maxTimeRange.Hours()/24))})
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultReIDResults
+	}
+	if limit > maxReIDResults {
+		limit = maxReIDResults
+	}
+
+	query := database.DB.Model(&models.VehicleEmbedding{}).Where("timestamp >= ? AND timestamp <= ?", start, end)
+	if len(req.DeviceIDs) > 0 {
+		query = query.Where("device_id IN ?", req.DeviceIDs)
+	}
+	if req.DetectionID != nil {
+		query = query.Where("detection_id <> ?", *req.DetectionID)
+	}
+
+	var (
+		hits    []embeddingHit
+		scanned int
+		err     error
+		method  = "scan"
+	)
+	if vehicleReID.pgvector {
+		method = "pgvector"
+		hits, err = searchEmbeddingsPG(query, vec, limit)
+	} else {
+		hits, scanned, err = searchEmbeddingsScan(query, vec, limit)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search embeddings"})
+		return
+	}
+
+	ids := make([]int64, 0, len(hits))
+	for _, h := range hits {
+		if h.Similarity >= req.MinSimilarity {
+			ids = append(ids, h.DetectionID)
+		}
+	}
+	var detections []models.VehicleDetection
+	if len(ids) > 0 {
+		if err := database.DB.Where("id IN ?", ids).Find(&detections).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load detections"})
+			return
+		}
+		signDetectionImages(c, detections)
+		if !platesVisible(c) {
+			maskDetectionPlates(detections)
+		}
+	}
+	byID := make(map[int64]models.VehicleDetection, len(detections))
+	for _, d := range detections {
+		byID[d.ID] = d
+	}
+
+	matches := make([]gin.H, 0, len(ids))
+	for _, h := range hits {
+		if d, ok := byID[h.DetectionID]; ok && h.Similarity >= req.MinSimilarity {
+			matches = append(matches, gin.H{"similarity": h.Similarity, "detection": d})
+		}
+	}
+
+	resp := gin.H{"matches": matches, "method": method}
+	if method == "scan" {
+		resp["scanned"] = scanned
+		resp["truncated"] = scanned >= vehicleReID.maxCandidates // older embeddings in range weren't compared
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// builtinVehicleTypeMappings are common makes and models on Indian roads. An
+// empty model covers every model of a make that only builds one type.
+// Admins extend or override these with vehicle_type_mappings.
+var builtinVehicleTypeMappings = []models.VehicleTypeMapping{
+	// Two-wheelers
+	{Make: "hero", VehicleType: models.VehicleType2Wheeler},
+	{Make: "royal enfield", VehicleType: models.VehicleType2Wheeler},
+	{Make: "ktm", VehicleType: models.VehicleType2Wheeler},
+	{Make: "ather", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "pulsar", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "platina", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "ct", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "avenger", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "dominar", VehicleType: models.VehicleType2Wheeler},
+	{Make: "bajaj", Model: "chetak", VehicleType: models.VehicleType2Wheeler},
+	{Make: "honda", Model: "activa", VehicleType: models.VehicleType2Wheeler},
+	{Make: "honda", Model: "shine", VehicleType: models.VehicleType2Wheeler},
+	{Make: "honda", Model: "unicorn", VehicleType: models.VehicleType2Wheeler},
+	{Make: "honda", Model: "dio", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "apache", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "jupiter", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "ntorq", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "xl", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "raider", VehicleType: models.VehicleType2Wheeler},
+	{Make: "tvs", Model: "iqube", VehicleType: models.VehicleType2Wheeler},
+	{Make: "suzuki", Model: "access", VehicleType: models.VehicleType2Wheeler},
+	{Make: "suzuki", Model: "burgman", VehicleType: models.VehicleType2Wheeler},
+	{Make: "yamaha", VehicleType: models.VehicleType2Wheeler},
+
+	// Autos
+	{Make: "bajaj", Model: "re", VehicleType: models.VehicleTypeAuto},
+	{Make: "bajaj", Model: "maxima", VehicleType: models.VehicleTypeAuto},
+	{Make: "tvs", Model: "king", VehicleType: models.VehicleTypeAuto},
+	{Make: "piaggio", Model: "ape", VehicleType: models.VehicleTypeAuto},
+
+	// Cars
+	{Make: "maruti", VehicleType: models.VehicleType4Wheeler},
+	{Make: "maruti suzuki", VehicleType: models.VehicleType4Wheeler},
+	{Make: "hyundai", VehicleType: models.VehicleType4Wheeler},
+	{Make: "kia", VehicleType: models.VehicleType4Wheeler},
+	{Make: "mg", VehicleType: models.VehicleType4Wheeler},
+	{Make: "skoda", VehicleType: models.VehicleType4Wheeler},
+	{Make: "volkswagen", VehicleType: models.VehicleType4Wheeler},
+	{Make: "honda", Model: "city", VehicleType: models.VehicleType4Wheeler},
+	{Make: "honda", Model: "amaze", VehicleType: models.VehicleType4Wheeler},
+	{Make: "honda", Model: "elevate", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "nexon", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "punch", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "tiago", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "tigor", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "altroz", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "harrier", VehicleType: models.VehicleType4Wheeler},
+	{Make: "tata", Model: "safari", VehicleType: models.VehicleType4Wheeler},
+	{Make: "mahindra", Model: "scorpio", VehicleType: models.VehicleType4Wheeler},
+	{Make: "mahindra", Model: "xuv", VehicleType: models.VehicleType4Wheeler},
+	{Make: "mahindra", Model: "thar", VehicleType: models.VehicleType4Wheeler},
+	{Make: "mahindra", Model: "bolero", VehicleType: models.VehicleType4Wheeler},
+	{Make: "toyota", Model: "innova", VehicleType: models.VehicleType4Wheeler},
+	{Make: "toyota", Model: "fortuner", VehicleType: models.VehicleType4Wheeler},
+	{Make: "toyota", Model: "glanza", VehicleType: models.VehicleType4Wheeler},
+
+	// Heavy vehicles and buses
+	{Make: "bharatbenz", VehicleType: models.VehicleTypeHMV},
+	{Make: "tata", Model: "signa", VehicleType: models.VehicleTypeHMV},
+	{Make: "tata", Model: "prima", VehicleType: models.VehicleTypeHMV},
+	{Make: "tata", Model: "starbus", VehicleType: models.VehicleTypeBus},
+	{Make: "eicher", Model: "skyline", VehicleType: models.VehicleTypeBus},
+	{Make: "volvo", Model: "9600", VehicleType: models.VehicleTypeBus},
+}
+
+// vehicleTypeTable is the in-memory make/model lookup used on ingest:
+// make key -> model key ("" = any model) -> type
+var vehicleTypeTable = struct {
+	mu      sync.RWMutex
+	enabled bool
+	types   map[string]map[string]models.VehicleType
+}{}
+
+// vehicleTypeKey folds a make or model for matching, so "Wagon-R" and
+// "WagonR" are the same model
+func vehicleTypeKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// InitVehicleTypeInference reads VEHICLE_TYPE_INFERENCE (false to disable),
+// loads the make/model table and returns whether inference is on and how
+// many mappings it has
+func InitVehicleTypeInference() (bool, int) {
+	enabled := os.Getenv("VEHICLE_TYPE_INFERENCE") != "false"
+	vehicleTypeTable.mu.Lock()
+	vehicleTypeTable.enabled = enabled
+	vehicleTypeTable.mu.Unlock()
+	if !enabled {
+		return false, 0
+	}
+	return true, reloadVehicleTypeTable()
+}
+
+// reloadVehicleTypeTable rebuilds the lookup from the built-in mappings and
+// the admin-defined ones, which take precedence. Returns the mapping count.
+func reloadVehicleTypeTable() int {
+	var custom []models.VehicleTypeMapping
+	if err := database.DB.Find(&custom).Error; err != nil {
+		log.Printf("⚠️ [VEHICLE_TYPE] Failed to load vehicle type mappings: %v", err)
+	}
+
+	types := make(map[string]map[string]models.VehicleType)
+	count := 0
+	for _, m := range append(builtinVehicleTypeMappings, custom...) {
+		makeKey := vehicleTypeKey(m.Make)
+		if types[makeKey] == nil {
+			types[makeKey] = make(map[string]models.VehicleType)
+		}
+		if _, ok := types[makeKey][vehicleTypeKey(m.Model)]; !ok {
+			count++
+		}
+		types[makeKey][vehicleTypeKey(m.Model)] = m.VehicleType
+	}
+
+	vehicleTypeTable.mu.Lock()
+	vehicleTypeTable.types = types
+	vehicleTypeTable.mu.Unlock()
+	return count
+}
+
+// inferVehicleType returns the type of a vehicle from its make and model, or
+// UNKNOWN. The longest mapped model that prefixes the detected one wins, so
+// "Pulsar" covers "Pulsar NS200"; otherwise the make's catch-all applies.
+// Edges that report no make but a model like "Bajaj Pulsar" are matched on
+// the leading make.
+func inferVehicleType(make, model string) models.VehicleType {
+	vehicleTypeTable.mu.RLock()
+	defer vehicleTypeTable.mu.RUnlock()
+	if !vehicleTypeTable.enabled {
+		return models.VehicleTypeUnknown
+	}
+
+	makeKey, modelKey := vehicleTypeKey(make), vehicleTypeKey(model)
+	if makeKey == "" {
+		for key := range vehicleTypeTable.types {
+			if key != "" && strings.HasPrefix(modelKey, key) && len(key) > len(makeKey) {
+				makeKey = key
+			}
+		}
+		if makeKey == "" {
+			return models.VehicleTypeUnknown
+		}
+		modelKey = strings.TrimPrefix(modelKey, makeKey)
+	}
+
+	byModel, ok := vehicleTypeTable.types[makeKey]
+	if !ok {
+		return models.VehicleTypeUnknown
+	}
+	best := ""
+	vehicleType, found := byModel[""]
+	for key, t := range byModel {
+		if key != "" && strings.HasPrefix(modelKey, key) && len(key) > len(best) {
+			best, vehicleType, found = key, t, true
+		}
+	}
+	if !found {
+		return models.VehicleTypeUnknown
+	}
+	return vehicleType
+}
+
+// validVehicleType reports whether t is one of the known vehicle types
+func validVehicleType(t models.VehicleType) bool {
+	switch t {
+	case models.VehicleType2Wheeler, models.VehicleType4Wheeler, models.VehicleTypeAuto,
+		models.VehicleTypeTruck, models.VehicleTypeBus, models.VehicleTypeHMV, models.VehicleTypeUnknown:
+		return true
+	}
+	return false
+}
+
+// GetVehicleTypeMappings lists the built-in and admin-defined make/model
+// mappings used to infer vehicle types (admin)
+// GET /api/admin/vehicle-type-mappings
+func GetVehicleTypeMappings(c *gin.Context) {
+	var custom []models.VehicleTypeMapping
+	if err := database.DB.Order("make ASC, model ASC").Find(&custom).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicle type mappings"})
+		return
+	}
+
+	vehicleTypeTable.mu.RLock()
+	enabled := vehicleTypeTable.enabled
+	vehicleTypeTable.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  enabled,
+		"builtin":  builtinVehicleTypeMappings,
+		"mappings": custom,
+	})
+}
+
+// UpsertVehicleTypeMappingRequest - Request to map a make/model to a vehicle type
+type UpsertVehicleTypeMappingRequest struct {
+	Make        string             `json:"make" binding:"required"`
+	Model       string             `json:"model"` // Empty for every model of the make
+	VehicleType models.VehicleType `json:"vehicleType" binding:"required"`
+	ChangedBy   string             `json:"changedBy"`
+}
+
+// UpsertVehicleTypeMapping creates or updates a make/model mapping (admin).
+// Mapping a built-in combination to UNKNOWN stops it from being inferred.
+// PUT /api/admin/vehicle-type-mappings
+func UpsertVehicleTypeMapping(c *gin.Context) {
+	var req UpsertVehicleTypeMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.VehicleType = models.VehicleType(strings.ToUpper(string(req.VehicleType)))
+	if !validVehicleType(req.VehicleType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle type"})
+		return
+	}
+	mapping := models.VehicleTypeMapping{
+		Make:        strings.ToLower(strings.TrimSpace(req.Make)),
+		Model:       strings.ToLower(strings.TrimSpace(req.Model)),
+		VehicleType: req.VehicleType,
+		UpdatedBy:   req.ChangedBy,
+	}
+	if vehicleTypeKey(mapping.Make) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "make is required"})
+		return
+	}
+	if mapping.UpdatedBy == "" {
+		mapping.UpdatedBy = "admin"
+	}
+
+	if err := database.DB.Save(&mapping).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save vehicle type mapping"})
+		return
+	}
+	reloadVehicleTypeTable()
+
+	log.Printf("📝 [VEHICLE_TYPE] %q %q mapped to %s by %s", mapping.Make, mapping.Model, mapping.VehicleType, mapping.UpdatedBy)
+	c.JSON(http.StatusOK, mapping)
+}
+
+// DeleteVehicleTypeMapping removes an admin-defined mapping, restoring the
+// built-in one if there is one (admin)
+// DELETE /api/admin/vehicle-type-mappings?make=...&model=...
+func DeleteVehicleTypeMapping(c *gin.Context) {
+	make := strings.ToLower(strings.TrimSpace(c.Query("make")))
+	model := strings.ToLower(strings.TrimSpace(c.Query("model")))
+	if make == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "make is required"})
+		return
+	}
+
+	result := database.DB.Where("make = ? AND model = ?", make, model).Delete(&models.VehicleTypeMapping{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete vehicle type mapping"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle type mapping not found"})
+		return
+	}
+	reloadVehicleTypeTable()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle type mapping deleted"})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,11 +13,13 @@ import (
 )
 
 // PostVehicleDetection handles POST /api/vehicles/detect - Ingest vehicle detection from camera
+// With ?enrich=true the response includes the matched vehicle's history
 func PostVehicleDetection(c *gin.Context) {
 	var req struct {
 		DeviceID         string                 `json:"deviceId" binding:"required"`
 		PlateNumber     *string                `json:"plateNumber"`
 		PlateConfidence *float64               `json:"plateConfidence"`
+		PlateRegion     *string               `json:"plateRegion"` // Issuing state/authority, when the edge knows it
 		Make            *string               `json:"make"`
 		Model           *string               `json:"model"`
 		VehicleType     models.VehicleType    `json:"vehicleType"`
@@ -79,13 +82,36 @@ func PostVehicleDetection(c *gin.Context) {
 		PlateDetected:    plateDetected,
 		MakeModelDetected: makeModelDetected,
 	}
+	stampDetectionLocation(&detection, &device)
+	stampDetectionBBox(&detection)
+
+	// Detected attributes, by vehicle column
+	detectedAttributes := map[string]string{}
+	if req.VehicleType != models.VehicleTypeUnknown {
+		detectedAttributes["vehicle_type"] = string(req.VehicleType)
+	}
+	if req.Make != nil {
+		detectedAttributes["make"] = *req.Make
+	}
+	if req.Model != nil {
+		detectedAttributes["model"] = *req.Model
+	}
+	if req.Color != nil {
+		detectedAttributes["color"] = *req.Color
+	}
 
 	// Try to find or create vehicle
 	var vehicle *models.Vehicle
 	if plateDetected && req.PlateNumber != nil {
+		var reportedRegion string
+		if req.PlateRegion != nil {
+			reportedRegion = *req.PlateRegion
+		}
+		region := plateRegion(*req.PlateNumber, reportedRegion, &device)
+
 		// Try to find existing vehicle by plate
 		var existingVehicle models.Vehicle
-		err := database.DB.Where("plate_number = ?", *req.PlateNumber).First(&existingVehicle).Error
+		err := vehicleByPlate(database.DB, *req.PlateNumber, region).First(&existingVehicle).Error
 		
 		if err == nil {
 			// Found existing vehicle - update it
@@ -96,25 +122,17 @@ func PostVehicleDetection(c *gin.Context) {
 			}
 			
 			// Update vehicle info if we have better data
-			if req.Make != nil && *req.Make != "" {
-				updates["make"] = *req.Make
-			}
-			if req.Model != nil && *req.Model != "" {
-				updates["model"] = *req.Model
-			}
-			if req.VehicleType != "" {
-				updates["vehicle_type"] = req.VehicleType
-			}
-			if req.Color != nil && *req.Color != "" {
-				updates["color"] = *req.Color
-			}
+			weighVehicleAttributes(&existingVehicle, detectedAttributes, req.Confidence, timestamp, updates)
 			
 			database.DB.Model(&existingVehicle).Updates(updates)
+			existingVehicle.LastSeen = timestamp
+			existingVehicle.DetectionCount++
 			detection.VehicleID = &vehicle.ID
 		} else if err == gorm.ErrRecordNotFound {
 			// Create new vehicle
 			newVehicle := models.Vehicle{
 				PlateNumber:    req.PlateNumber,
+				PlateRegion:    region,
 				Make:           req.Make,
 				Model:          req.Model,
 				VehicleType:    req.VehicleType,
@@ -124,6 +142,13 @@ func PostVehicleDetection(c *gin.Context) {
 				DetectionCount: 1,
 				IsWatchlisted:  false,
 			}
+			var setAttributes []string
+			for column, value := range detectedAttributes {
+				if value != "" {
+					setAttributes = append(setAttributes, column)
+				}
+			}
+			newVehicle.AttributeConfidence = recordAttributeConfidences(&newVehicle, setAttributes, req.Confidence, timestamp)
 			
 			if err := database.DB.Create(&newVehicle).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create vehicle"})
@@ -149,6 +174,9 @@ func PostVehicleDetection(c *gin.Context) {
 	}
 	if vehicle != nil {
 		response["vehicleId"] = strconv.FormatInt(vehicle.ID, 10)
+		if c.Query("enrich") == "true" {
+			response["vehicle"] = vehicleHistory(vehicle)
+		}
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -193,15 +221,16 @@ func GetVehicles(c *gin.Context) {
 	}
 
 	// Filter by date range
-	if startTime := c.Query("startTime"); startTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTime); err == nil {
-			query = query.Where("last_seen >= ?", parsed)
-		}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if endTime := c.Query("endTime"); endTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTime); err == nil {
-			query = query.Where("last_seen <= ?", parsed)
-		}
+	if !startTime.IsZero() {
+		query = query.Where("last_seen >= ?", startTime)
+	}
+	if c.Query("endTime") != "" {
+		query = query.Where("last_seen <= ?", endTime)
 	}
 
 	// Pagination
@@ -236,6 +265,12 @@ func GetVehicles(c *gin.Context) {
 		return
 	}
 
+	if !platesVisible(c) {
+		for i := range vehicles {
+			maskVehiclePlate(&vehicles[i])
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"vehicles": vehicles,
 		"total":    total,
@@ -287,6 +322,10 @@ func GetVehicle(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vehicle"})
 		return
 	}
+	signDetectionImages(c, vehicle.Detections)
+	if !platesVisible(c) {
+		maskVehiclePlate(&vehicle)
+	}
 
 	c.JSON(http.StatusOK, vehicle)
 }
@@ -319,16 +358,25 @@ func GetVehicleDetections(c *gin.Context) {
 	}
 
 	// Filter by date range
-	if startTime := c.Query("startTime"); startTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTime); err == nil {
-			query = query.Where("timestamp >= ?", parsed)
-		}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if endTime := c.Query("endTime"); endTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTime); err == nil {
-			query = query.Where("timestamp <= ?", parsed)
-		}
+	if !startTime.IsZero() {
+		query = query.Where("timestamp >= ?", startTime)
 	}
+	if c.Query("endTime") != "" {
+		query = query.Where("timestamp <= ?", endTime)
+	}
+
+	// Filter by where in the frame the vehicle was
+	region, err := parseFrameRegion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = frameRegionScope(query, region)
 
 	limit := 100
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -344,6 +392,11 @@ func GetVehicleDetections(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch detections"})
 		return
 	}
+	fillDetectionLocation(detections)
+	signDetectionImages(c, detections)
+	if !platesVisible(c) {
+		maskDetectionPlates(detections)
+	}
 
 	c.JSON(http.StatusOK, detections)
 }
@@ -378,6 +431,10 @@ func GetVehicleViolations(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violations"})
 		return
 	}
+	signViolationImages(c, violations)
+	if !platesVisible(c) {
+		maskViolationPlates(violations)
+	}
 
 	c.JSON(http.StatusOK, violations)
 }
@@ -397,12 +454,18 @@ func AddToWatchlist(c *gin.Context) {
 		AlertOnDetection bool  `json:"alertOnDetection"`
 		AlertOnViolation bool  `json:"alertOnViolation"`
 		Notes           *string `json:"notes"`
+		Category        models.WatchlistCategory `json:"category"` // default OTHER
+		Severity        models.HotspotSeverity   `json:"severity"` // default depends on category
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	if err := validateWatchlistClass(&req.Category, &req.Severity); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Check vehicle exists
 	var vehicle models.Vehicle
@@ -426,6 +489,8 @@ func AddToWatchlist(c *gin.Context) {
 	watchlist := models.Watchlist{
 		VehicleID:        id,
 		Reason:           req.Reason,
+		Category:         req.Category,
+		Severity:         req.Severity,
 		AddedBy:         req.AddedBy,
 		IsActive:        true,
 		AlertOnDetection: req.AlertOnDetection,
@@ -440,6 +505,10 @@ func AddToWatchlist(c *gin.Context) {
 
 	// Update vehicle watchlist flag
 	database.DB.Model(&vehicle).Update("is_watchlisted", true)
+	reloadWatchlistSet()
+
+	// Surface sightings from before it was watched
+	startWatchlistBackscan(watchlist)
 
 	c.JSON(http.StatusCreated, watchlist)
 }
@@ -463,19 +532,32 @@ func RemoveFromWatchlist(c *gin.Context) {
 
 	// Update vehicle watchlist flag
 	database.DB.Model(&models.Vehicle{}).Where("id = ?", id).Update("is_watchlisted", false)
+	reloadWatchlistSet()
 
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
 // GetWatchlist handles GET /api/watchlist - Get all watchlisted vehicles
+// Query: category, severity (comma-separated lists)
 func GetWatchlist(c *gin.Context) {
 	query := database.DB.Model(&models.Watchlist{}).Where("is_active = ?", true)
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category IN ?", strings.Split(strings.ToUpper(category), ","))
+	}
+	if severity := c.Query("severity"); severity != "" {
+		query = query.Where("severity IN ?", strings.Split(strings.ToUpper(severity), ","))
+	}
 
 	var watchlist []models.Watchlist
 	if err := query.Preload("Vehicle").Order("added_at DESC").Find(&watchlist).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist"})
 		return
 	}
+	if !platesVisible(c) {
+		for i := range watchlist {
+			maskVehiclePlate(&watchlist[i].Vehicle)
+		}
+	}
 
 	c.JSON(http.StatusOK, watchlist)
 }
@@ -561,25 +643,39 @@ func UpdateVehicle(c *gin.Context) {
 	}
 
 	updates := make(map[string]interface{})
+	var edited []string
 	if req.PlateNumber != nil {
 		updates["plate_number"] = *req.PlateNumber
 	}
 	if req.Make != nil {
 		updates["make"] = *req.Make
+		edited = append(edited, "make")
 	}
 	if req.Model != nil {
 		updates["model"] = *req.Model
+		edited = append(edited, "model")
 	}
 	if req.VehicleType != nil {
 		updates["vehicle_type"] = *req.VehicleType
+		edited = append(edited, "vehicle_type")
 	}
 	if req.Color != nil {
 		updates["color"] = *req.Color
+		edited = append(edited, "color")
 	}
 	if req.Metadata.Data != nil {
 		updates["metadata"] = req.Metadata
 	}
 
+	// Operator edits outweigh detections until they decay
+	if len(edited) > 0 {
+		var current models.Vehicle
+		if err := database.DB.Select("id, attribute_confidence").First(&current, id).Error; err == nil {
+			confidence := manualAttributeConfidence
+			updates["attribute_confidence"] = recordAttributeConfidences(&current, edited, &confidence, time.Now())
+		}
+	}
+
 	if err := database.DB.Model(&models.Vehicle{}).Where("id = ?", id).Updates(updates).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+)
+
+const (
+	minRotationDwell     = 5
+	maxRotationDwell     = 3600
+	defaultRotationDwell = 15
+	maxRotationCameras   = 64
+)
+
+// ViewRotationRequest - Create a live-view rotation
+type ViewRotationRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Cameras      []string `json:"cameras" binding:"required"` // workerID.cameraID, in display order
+	DwellSeconds int      `json:"dwellSeconds"`
+	CreatedBy    string   `json:"createdBy"`
+}
+
+// rotationCameras decodes the camera keys stored on a rotation
+func rotationCameras(r *models.ViewRotation) []string {
+	list, _ := r.Cameras.Data.([]interface{})
+	cameras := make([]string, 0, len(list))
+	for _, v := range list {
+		if key, ok := v.(string); ok && key != "" {
+			cameras = append(cameras, key)
+		}
+	}
+	return cameras
+}
+
+// validateRotationCameras checks each key is workerID.cameraID for an active assignment
+func validateRotationCameras(cameras []string) error {
+	if len(cameras) == 0 {
+		return fmt.Errorf("at least one camera is required")
+	}
+	if len(cameras) > maxRotationCameras {
+		return fmt.Errorf("a rotation can have at most %d cameras", maxRotationCameras)
+	}
+	for _, key := range cameras {
+		workerID, cameraID, ok := strings.Cut(key, ".")
+		if !ok || workerID == "" || cameraID == "" {
+			return fmt.Errorf("invalid camera %q, expected workerID.cameraID", key)
+		}
+		var count int64
+		database.DB.Model(&models.WorkerCameraAssignment{}).
+			Where("worker_id = ? AND device_id = ? AND is_active = true", workerID, cameraID).
+			Count(&count)
+		if count == 0 {
+			return fmt.Errorf("camera %s is not assigned to worker %s", cameraID, workerID)
+		}
+	}
+	return nil
+}
+
+// CreateViewRotation handles POST /api/view-rotations - Define a camera rotation
+func CreateViewRotation(c *gin.Context) {
+	var req ViewRotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DwellSeconds == 0 {
+		req.DwellSeconds = defaultRotationDwell
+	}
+	if req.DwellSeconds < minRotationDwell || req.DwellSeconds > maxRotationDwell {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("dwellSeconds must be between %d and %d", minRotationDwell, maxRotationDwell)})
+		return
+	}
+	if err := validateRotationCameras(req.Cameras); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CreatedBy == "" {
+		req.CreatedBy = "admin"
+	}
+
+	rotation := models.ViewRotation{
+		ID:           generateID("rot"),
+		Name:         req.Name,
+		Cameras:      models.NewJSONB(req.Cameras),
+		DwellSeconds: req.DwellSeconds,
+		CreatedBy:    req.CreatedBy,
+	}
+	if err := database.DB.Create(&rotation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create view rotation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rotation)
+}
+
+// GetViewRotations handles GET /api/view-rotations - List camera rotations
+func GetViewRotations(c *gin.Context) {
+	var rotations []models.ViewRotation
+	if err := database.DB.Order("created_at DESC").Find(&rotations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch view rotations"})
+		return
+	}
+	c.JSON(http.StatusOK, rotations)
+}
+
+// DeleteViewRotation handles DELETE /api/view-rotations/:id - Remove a camera rotation
+func DeleteViewRotation(c *gin.Context) {
+	result := database.DB.Delete(&models.ViewRotation{}, "id = ?", c.Param("id"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete view rotation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View rotation not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "View rotation deleted"})
+}
+
+// HandleRotationWebSocket handles GET /ws/rotations/:id - Stream a rotation's
+// active camera, advancing server-side every dwell period
+func HandleRotationWebSocket(c *gin.Context) {
+	if feedHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Feed hub not initialized"})
+		return
+	}
+
+	var rotation models.ViewRotation
+	if err := database.DB.First(&rotation, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "View rotation not found"})
+		return
+	}
+	cameras := rotationCameras(&rotation)
+	if len(cameras) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "View rotation has no cameras"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("⚠️ WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		userID = "anonymous"
+	}
+
+	client := services.NewFeedClient(feedHub, conn, userID, c.ClientIP())
+	feedHub.Register(client)
+	client.StartRotation(cameras, time.Duration(rotation.DwellSeconds)*time.Second)
+
+	go client.WritePump()
+	go client.ReadPump()
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+)
+
+const (
+	defaultFrameCaptureTimeout = 3 * time.Second
+
+	// liveFrameMaxAge is how old a frame already held by the feed hub may be
+	// and still count as contemporaneous with the violation
+	liveFrameMaxAge = 2 * time.Second
+
+	// violationFrameMaxLag is how long after a violation happened a live frame
+	// may still be captured for it. Later frames (events resent from an edge
+	// queue, a slow ingest) would show the scene after the vehicle has left.
+	violationFrameMaxLag = 5 * time.Second
+)
+
+// violationFrameCapture controls fetching a live frame for image-less violations
+var violationFrameCapture = struct {
+	enabled bool
+	timeout time.Duration
+}{
+	timeout: defaultFrameCaptureTimeout,
+}
+
+// InitViolationFrameCapture reads VIOLATION_FRAME_CAPTURE (true to enable) and
+// VIOLATION_FRAME_CAPTURE_TIMEOUT_MS (default 3000)
+func InitViolationFrameCapture() (bool, time.Duration) {
+	violationFrameCapture.enabled = os.Getenv("VIOLATION_FRAME_CAPTURE") == "true"
+	violationFrameCapture.timeout = defaultFrameCaptureTimeout
+	if v := os.Getenv("VIOLATION_FRAME_CAPTURE_TIMEOUT_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			violationFrameCapture.timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	return violationFrameCapture.enabled, violationFrameCapture.timeout
+}
+
+// captureViolationFrame attaches a live camera frame to a violation that arrived
+// without an image. The feed hub's latest frame is used when someone is already
+// viewing the camera; otherwise the MagicBox is asked for one over central NATS.
+// Nothing is captured for violations that happened more than
+// violationFrameMaxLag ago.
+func captureViolationFrame(violationID int64, workerID, deviceID string, occurredAt time.Time) {
+	if lag := time.Since(occurredAt); lag > violationFrameMaxLag {
+		log.Printf("⏭️ [FRAME_CAPTURE] Skipping violation %d: happened %s ago, a live frame wouldn't show it", violationID, lag.Round(time.Second))
+		return
+	}
+
+	jpegData, source := captureLiveFrame(workerID, deviceID)
+	if jpegData == nil {
+		return
+	}
+	if lag := time.Since(occurredAt); lag > violationFrameMaxLag {
+		log.Printf("⏭️ [FRAME_CAPTURE] Discarding frame for violation %d: captured %s after it happened", violationID, lag.Round(time.Second))
+		return
+	}
+
+	storagePath := generateImagePath(workerID, deviceID, "violation", "frame.jpg")
+	if err := os.MkdirAll(filepath.Dir(storagePath), 0755); err != nil {
+		log.Printf("⚠️ [FRAME_CAPTURE] Failed to create directory - Path: %s, Error: %v", storagePath, err)
+		return
+	}
+	if err := os.WriteFile(storagePath, jpegData, 0644); err != nil {
+		log.Printf("⚠️ [FRAME_CAPTURE] Failed to save frame - Path: %s, Error: %v", storagePath, err)
+		return
+	}
+
+	relPath, err := filepath.Rel(getUploadBaseDir(), storagePath)
+	if err != nil {
+		relPath = filepath.Base(storagePath)
+	}
+	url := "/uploads/" + filepath.ToSlash(relPath)
+	recordStoredImage(IngestEvent{WorkerID: workerID, DeviceID: deviceID, Type: "violation"}, storagePath, url, int64(len(jpegData)))
+
+	// Don't overwrite evidence that was attached some other way in the meantime
+	result := database.DB.Model(&models.TrafficViolation{}).
+		Where("id = ? AND full_snapshot_url IS NULL", violationID).
+		Update("full_snapshot_url", url)
+	if result.Error != nil {
+		log.Printf("⚠️ [FRAME_CAPTURE] Failed to attach frame - Violation: %d, Error: %v", violationID, result.Error)
+		return
+	}
+
+	log.Printf("📸 [FRAME_CAPTURE] Attached live frame to violation %d (source: %s, device: %s)", violationID, source, deviceID)
+}
+
+// eventOccurredAt returns when an event happened in server time: the time the
+// edge stamped on it, corrected by the worker's last reported clock skew, when
+// that parses; else the time it was received
+func eventOccurredAt(event IngestEvent) time.Time {
+	t, err := time.Parse(time.RFC3339, event.TimestampRaw)
+	if err != nil {
+		return *event.Timestamp
+	}
+	var worker models.Worker
+	if err := database.DB.Select("clock_skew_ms").First(&worker, "id = ?", event.WorkerID).Error; err == nil && worker.ClockSkewMs != nil {
+		t = t.Add(-time.Duration(*worker.ClockSkewMs) * time.Millisecond)
+	}
+	return t
+}
+
+// captureLiveFrame returns a current JPEG for a camera and where it came from
+func captureLiveFrame(workerID, deviceID string) ([]byte, string) {
+	if feedHub != nil {
+		if frame, ok := feedHub.LatestFrame(workerID, deviceID, liveFrameMaxAge); ok {
+			return frame, "feed_hub"
+		}
+	}
+
+	if commandBus == nil {
+		return nil, ""
+	}
+
+	reply, err := commandBus.Send(workerID, services.WorkerCommand{
+		ID:       generateID("cmd"),
+		Action:   services.CommandCaptureFrame,
+		CameraID: deviceID,
+	}, violationFrameCapture.timeout)
+	if err != nil {
+		log.Printf("⚠️ [FRAME_CAPTURE] Frame request failed - Worker: %s, Device: %s, Error: %v", workerID, deviceID, err)
+		return nil, ""
+	}
+	if !reply.Success {
+		log.Printf("⚠️ [FRAME_CAPTURE] Worker could not capture frame - Worker: %s, Device: %s, Error: %s", workerID, deviceID, reply.Error)
+		return nil, ""
+	}
+
+	var data struct {
+		Frame string `json:"frame"`
+	}
+	if err := json.Unmarshal(reply.Data, &data); err != nil || data.Frame == "" {
+		log.Printf("⚠️ [FRAME_CAPTURE] Reply has no frame - Worker: %s, Device: %s", workerID, deviceID)
+		return nil, ""
+	}
+	jpegData, err := base64.StdEncoding.DecodeString(data.Frame)
+	if err != nil {
+		log.Printf("⚠️ [FRAME_CAPTURE] Failed to decode frame - Worker: %s, Device: %s, Error: %v", workerID, deviceID, err)
+		return nil, ""
+	}
+	return jpegData, "worker"
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // notices must render in local time even on hosts without zoneinfo
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNoticeTimezone = "Asia/Kolkata"
+	defaultNoticeCurrency = "INR"
+	defaultNoticeLocale   = "en-IN"
+
+	// noticeTimeLayout is how the offense time is printed on a notice
+	noticeTimeLayout = "02 Jan 2006, 03:04:05 PM MST"
+)
+
+// currencySymbols maps ISO 4217 codes to the symbol printed on notices
+var currencySymbols = map[string]string{
+	"INR": "₹",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// noticeFormat holds the timezone and currency settings used to render notices
+var noticeFormat = struct {
+	location *time.Location
+	currency string
+	locale   string
+}{
+	location: time.UTC,
+	currency: defaultNoticeCurrency,
+	locale:   defaultNoticeLocale,
+}
+
+// InitNoticeFormat reads NOTICE_TIMEZONE, NOTICE_CURRENCY and NOTICE_LOCALE
+// and returns the timezone in use
+func InitNoticeFormat() string {
+	tz := os.Getenv("NOTICE_TIMEZONE")
+	if tz == "" {
+		tz = defaultNoticeTimezone
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		log.Printf("⚠️ Invalid NOTICE_TIMEZONE %q, using UTC: %v", tz, err)
+		loc = time.UTC
+	}
+	noticeFormat.location = loc
+
+	noticeFormat.currency = strings.ToUpper(os.Getenv("NOTICE_CURRENCY"))
+	if noticeFormat.currency == "" {
+		noticeFormat.currency = defaultNoticeCurrency
+	}
+	noticeFormat.locale = os.Getenv("NOTICE_LOCALE")
+	if noticeFormat.locale == "" {
+		noticeFormat.locale = defaultNoticeLocale
+	}
+
+	return loc.String()
+}
+
+// formatCurrency formats an amount with the currency symbol and the digit
+// grouping of the locale (en-IN uses lakh/crore grouping: 1,23,456.00)
+func formatCurrency(amount float64, currency, locale string) string {
+	negative := amount < 0
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	frac := fmt.Sprintf("%02d", cents%100)
+
+	var groups []string
+	if strings.HasSuffix(locale, "-IN") && len(whole) > 3 {
+		groups = append(groups, whole[len(whole)-3:])
+		whole = whole[:len(whole)-3]
+		for len(whole) > 2 {
+			groups = append([]string{whole[len(whole)-2:]}, groups...)
+			whole = whole[:len(whole)-2]
+		}
+		groups = append([]string{whole}, groups...)
+	} else {
+		for len(whole) > 3 {
+			groups = append([]string{whole[len(whole)-3:]}, groups...)
+			whole = whole[:len(whole)-3]
+		}
+		groups = append([]string{whole}, groups...)
+	}
+
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		symbol = currency + " "
+	}
+
+	formatted := symbol + strings.Join(groups, ",") + "." + frac
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// GetViolationNotice handles GET /api/violations/:id/notice - Render a violation notice
+func GetViolationNotice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid violation ID"})
+		return
+	}
+
+	var violation models.TrafficViolation
+	if err := database.DB.Preload("Device").First(&violation, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violation"})
+		return
+	}
+
+	// Notices are only issued for reviewed violations
+	if violation.Status != models.ViolationApproved && violation.Status != models.ViolationFined && violation.Status != models.ViolationPaid {
+		c.JSON(http.StatusConflict, gin.H{"error": "Notice is only available for approved, fined or paid violations"})
+		return
+	}
+
+	loc := noticeFormat.location
+	offenseTime := violation.Timestamp.In(loc)
+
+	notice := gin.H{
+		"violationId":   violation.ID,
+		"violationType": violation.ViolationType,
+		"status":        violation.Status,
+		"offense": gin.H{
+			"timestamp": offenseTime.Format(time.RFC3339),
+			"formatted": offenseTime.Format(noticeTimeLayout),
+			"timezone":  loc.String(),
+		},
+		"locale": noticeFormat.locale,
+	}
+
+	if violation.PlateNumber != nil {
+		notice["plateNumber"] = *violation.PlateNumber
+	}
+	if violation.DetectedSpeed != nil {
+		speed := gin.H{"detected": *violation.DetectedSpeed}
+		if violation.SpeedLimit4W != nil {
+			speed["limit"] = *violation.SpeedLimit4W
+		}
+		notice["speed"] = speed
+	}
+
+	// Device location
+	location := gin.H{
+		"deviceId": violation.DeviceID,
+		"lat":      violation.Device.Lat,
+		"lng":      violation.Device.Lng,
+	}
+	if violation.Device.Name != nil {
+		location["name"] = *violation.Device.Name
+	}
+	if violation.Device.ZoneID != nil {
+		location["zoneId"] = *violation.Device.ZoneID
+	}
+	notice["location"] = location
+
+	// Fine
+	if violation.FineAmount != nil {
+		fine := gin.H{
+			"amount":    *violation.FineAmount,
+			"currency":  noticeFormat.currency,
+			"formatted": formatCurrency(*violation.FineAmount, noticeFormat.currency, noticeFormat.locale),
+		}
+		if violation.FineReference != nil {
+			fine["reference"] = *violation.FineReference
+		}
+		if violation.FineIssuedAt != nil {
+			issued := violation.FineIssuedAt.In(loc)
+			fine["issuedAt"] = issued.Format(time.RFC3339)
+			fine["issuedAtFormatted"] = issued.Format(noticeTimeLayout)
+		}
+		if violation.PaidAt != nil {
+			paid := violation.PaidAt.In(loc)
+			fine["paidAt"] = paid.Format(time.RFC3339)
+			fine["paidAtFormatted"] = paid.Format(noticeTimeLayout)
+		} else if violation.PaymentURL != nil {
+			fine["paymentUrl"] = *violation.PaymentURL
+		}
+		notice["fine"] = fine
+	}
+
+	// Evidence
+	evidence := gin.H{}
+	signViolationImage(c, &violation)
+	if violation.FullSnapshotURL != nil {
+		evidence["snapshotUrl"] = *violation.FullSnapshotURL
+	}
+	if violation.PlateImageURL != nil {
+		evidence["plateImageUrl"] = *violation.PlateImageURL
+	}
+	if images := violation.PlateImageList(); len(images) > 0 {
+		evidence["plateImages"] = images
+	}
+	notice["evidence"] = evidence
+
+	c.JSON(http.StatusOK, notice)
+}
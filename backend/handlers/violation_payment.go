@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+	"gorm.io/gorm"
+)
+
+// paymentGateway collects fines; nil when no gateway is configured
+var paymentGateway services.PaymentGateway
+
+// paymentCallbackURL is where the gateway sends payers after paying
+var paymentCallbackURL string
+
+// SetPaymentGateway sets the gateway fines are collected through
+func SetPaymentGateway(gateway services.PaymentGateway) {
+	paymentGateway = gateway
+}
+
+// InitPaymentGateway reads PAYMENT_GATEWAY_URL (empty = off),
+// PAYMENT_GATEWAY_API_KEY, PAYMENT_WEBHOOK_SECRET (required to accept
+// webhooks) and PAYMENT_CALLBACK_URL. Returns the gateway host, which is safe
+// to log.
+func InitPaymentGateway() string {
+	gatewayURL := os.Getenv("PAYMENT_GATEWAY_URL")
+	paymentCallbackURL = os.Getenv("PAYMENT_CALLBACK_URL")
+	if gatewayURL == "" {
+		return ""
+	}
+	u, err := url.Parse(gatewayURL)
+	if err != nil || u.Host == "" {
+		log.Printf("⚠️ [PAYMENT] Invalid PAYMENT_GATEWAY_URL, payments disabled")
+		return ""
+	}
+	if os.Getenv("PAYMENT_WEBHOOK_SECRET") == "" {
+		log.Printf("⚠️ [PAYMENT] PAYMENT_WEBHOOK_SECRET is not set, payment webhooks will be rejected")
+	}
+	SetPaymentGateway(services.NewHTTPPaymentGateway(gatewayURL, os.Getenv("PAYMENT_GATEWAY_API_KEY"), os.Getenv("PAYMENT_WEBHOOK_SECRET")))
+	return u.Host
+}
+
+// CreateViolationPayment handles POST /api/violations/:id/payment-request -
+// Create a gateway payment request for a fined violation and store its link
+func CreateViolationPayment(c *gin.Context) {
+	if paymentGateway == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment gateway is not configured"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid violation ID"})
+		return
+	}
+
+	var violation models.TrafficViolation
+	if err := database.DB.First(&violation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violation"})
+		return
+	}
+	if violation.Status != models.ViolationFined {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payments can only be requested for fined violations"})
+		return
+	}
+	if violation.FineAmount == nil || *violation.FineAmount <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Violation has no fine amount"})
+		return
+	}
+
+	reference := fmt.Sprintf("VIOLATION-%d", violation.ID)
+	if violation.FineReference != nil && *violation.FineReference != "" {
+		reference = *violation.FineReference
+	}
+	fine := services.FinePayment{
+		Reference:   reference,
+		Amount:      *violation.FineAmount,
+		Currency:    noticeFormat.currency,
+		Description: fmt.Sprintf("Traffic fine: %s", violation.ViolationType),
+		CallbackURL: paymentCallbackURL,
+	}
+	if violation.PlateNumber != nil {
+		fine.PlateNumber = *violation.PlateNumber
+	}
+
+	payment, err := paymentGateway.CreatePaymentRequest(fine)
+	if err != nil {
+		log.Printf("⚠️ [PAYMENT] Failed to create payment for violation %d: %v", violation.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create payment request"})
+		return
+	}
+
+	// Earlier links stay valid, so each is kept for the webhook to find. The
+	// violation shows the latest one. Guard on FINED so a payment can't be
+	// attached to a violation that moved on.
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TrafficViolation{}).
+			Where("id = ? AND status = ?", violation.ID, models.ViolationFined).
+			Updates(map[string]interface{}{"payment_reference": payment.ID, "payment_url": payment.URL})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errIllegalTransition
+		}
+		return tx.Create(&models.ViolationPaymentRequest{
+			ViolationID: violation.ID,
+			Reference:   payment.ID,
+			URL:         payment.URL,
+			ExpiresAt:   payment.ExpiresAt,
+		}).Error
+	})
+	if errors.Is(err, errIllegalTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Violation status changed concurrently"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store payment request"})
+		return
+	}
+
+	log.Printf("💳 [PAYMENT] Payment %s requested for violation %d via %s", payment.ID, violation.ID, paymentGateway.Name())
+	c.JSON(http.StatusCreated, gin.H{
+		"violationId":      violation.ID,
+		"paymentReference": payment.ID,
+		"paymentUrl":       payment.URL,
+		"expiresAt":        payment.ExpiresAt,
+		"amount":           fine.Amount,
+		"currency":         fine.Currency,
+	})
+}
+
+// ViolationPaymentWebhook handles POST /api/violations/payment-webhook - The
+// gateway's payment confirmation. The signature is verified by the gateway;
+// a paid confirmation for the full fine moves the violation from FINED to
+// PAID. Repeated deliveries of the same payment are acknowledged unchanged.
+func ViolationPaymentWebhook(c *gin.Context) {
+	if paymentGateway == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Payment gateway is not configured"})
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+	confirmation, err := paymentGateway.ParseWebhook(body, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPaymentSignature) {
+			log.Printf("⚠️ [PAYMENT] Rejected webhook with invalid signature from %s", c.ClientIP())
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Links created before payment requests were kept are only on the violation
+	var violation models.TrafficViolation
+	if err := database.DB.
+		Where("id IN (?)", database.DB.Model(&models.ViolationPaymentRequest{}).Select("violation_id").Where("reference = ?", confirmation.PaymentID)).
+		Or("payment_reference = ?", confirmation.PaymentID).
+		First(&violation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No violation for this payment"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violation"})
+		return
+	}
+
+	if violation.Status == models.ViolationPaid {
+		c.JSON(http.StatusOK, gin.H{"violationId": violation.ID, "status": violation.Status, "duplicate": true})
+		return
+	}
+	if !strings.EqualFold(confirmation.Status, services.PaymentStatusPaid) {
+		log.Printf("💳 [PAYMENT] Payment %s for violation %d reported %s", confirmation.PaymentID, violation.ID, confirmation.Status)
+		c.JSON(http.StatusOK, gin.H{"violationId": violation.ID, "status": violation.Status})
+		return
+	}
+	if violation.FineAmount != nil && confirmation.Amount < *violation.FineAmount {
+		log.Printf("⚠️ [PAYMENT] Payment %s for violation %d is %.2f, short of the %.2f fine", confirmation.PaymentID, violation.ID, confirmation.Amount, *violation.FineAmount)
+		c.JSON(http.StatusConflict, gin.H{"error": "Paid amount is less than the fine"})
+		return
+	}
+
+	paidAt := time.Now()
+	if confirmation.PaidAt != nil {
+		paidAt = *confirmation.PaidAt
+	}
+	result := database.DB.Model(&models.TrafficViolation{}).
+		Where("id = ? AND status = ?", violation.ID, models.ViolationFined).
+		Updates(map[string]interface{}{
+			"status":      models.ViolationPaid,
+			"paid_at":     paidAt,
+			"paid_amount": confirmation.Amount,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update violation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Violation is %s, not FINED", violation.Status)})
+		return
+	}
+
+	log.Printf("💳 [PAYMENT] Violation %d paid (payment %s)", violation.ID, confirmation.PaymentID)
+	c.JSON(http.StatusOK, gin.H{"violationId": violation.ID, "status": models.ViolationPaid, "paidAt": paidAt})
+}
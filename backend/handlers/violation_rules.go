@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// settingAutoApproveEnabled is the global kill switch for violation auto-approval
+const settingAutoApproveEnabled = "violations.auto_approve.enabled"
+
+// autoApproveReviewer is recorded as reviewed_by on auto-approved violations
+const autoApproveReviewer = "system"
+
+// Indian registration plates: standard (KA01AB1234) and Bharat series (22BH1234AB)
+var (
+	standardPlatePattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{1,2}[A-Z]{0,3}[0-9]{1,4}$`)
+	bharatPlatePattern   = regexp.MustCompile(`^[0-9]{2}BH[0-9]{4}[A-Z]{1,2}$`)
+)
+
+// isValidPlate checks whether an OCR'd plate looks like a real registration number
+func isValidPlate(plate string) bool {
+	plate = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(plate))
+	if plate == "" {
+		return false
+	}
+	return standardPlatePattern.MatchString(plate) || bharatPlatePattern.MatchString(plate)
+}
+
+// getSettingBool reads a boolean system setting, falling back to def when unset
+func getSettingBool(key string, def bool) bool {
+	var setting models.SystemSetting
+	if err := database.DB.First(&setting, "key = ?", key).Error; err != nil {
+		return def
+	}
+	return setting.Value == "true"
+}
+
+// setSettingBool upserts a boolean system setting
+func setSettingBool(tx *gorm.DB, key string, value bool, updatedBy string) error {
+	setting := models.SystemSetting{
+		Key:       key,
+		Value:     "false",
+		UpdatedBy: updatedBy,
+	}
+	if value {
+		setting.Value = "true"
+	}
+	return tx.Save(&setting).Error
+}
+
+// isAutoApproveEnabled reports whether the global auto-approve kill switch is on
+func isAutoApproveEnabled() bool {
+	return getSettingBool(settingAutoApproveEnabled, true)
+}
+
+// shouldAutoApprove evaluates the auto-approve rule for a violation type.
+// Violations that don't match an enabled rule stay PENDING for manual review.
+func shouldAutoApprove(violationType models.ViolationType, confidence float64, plateNumber string, plateConfidence float64) bool {
+	if !isAutoApproveEnabled() {
+		return false
+	}
+
+	var rule models.ViolationAutoApproveRule
+	if err := database.DB.Where("violation_type = ? AND enabled = true", violationType).First(&rule).Error; err != nil {
+		return false
+	}
+
+	if confidence < rule.MinConfidence {
+		return false
+	}
+	if rule.RequireValidPlate && !isValidPlate(plateNumber) {
+		return false
+	}
+	if rule.MinPlateConfidence > 0 && plateConfidence < rule.MinPlateConfidence {
+		return false
+	}
+
+	return true
+}
+
+// writeRuleAudit records a change to the auto-approve configuration
+func writeRuleAudit(tx *gorm.DB, violationType *string, action string, before, after interface{}, changedBy string) error {
+	audit := models.ViolationRuleAudit{
+		ViolationType: violationType,
+		Action:        action,
+		ChangedBy:     changedBy,
+	}
+	if before != nil {
+		audit.Before = models.NewJSONB(before)
+	}
+	if after != nil {
+		audit.After = models.NewJSONB(after)
+	}
+	return tx.Create(&audit).Error
+}
+
+// validViolationType checks the type against the known violation types
+func validViolationType(t models.ViolationType) bool {
+	switch t {
+	case models.ViolationSpeed, models.ViolationHelmet, models.ViolationWrongSide,
+		models.ViolationRedLight, models.ViolationNoSeatbelt, models.ViolationOverloading,
+		models.ViolationIllegalParking, models.ViolationOther:
+		return true
+	}
+	return false
+}
+
+// GetViolationRules lists auto-approve rules and the kill switch state (admin)
+// GET /api/admin/violation-rules
+func GetViolationRules(c *gin.Context) {
+	var rules []models.ViolationAutoApproveRule
+	if err := database.DB.Order("violation_type ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"autoApproveEnabled": isAutoApproveEnabled(),
+		"rules":              rules,
+	})
+}
+
+// UpsertViolationRuleRequest - Request to create or update an auto-approve rule
+type UpsertViolationRuleRequest struct {
+	Enabled            *bool    `json:"enabled"`
+	MinConfidence      *float64 `json:"minConfidence"`
+	RequireValidPlate  *bool    `json:"requireValidPlate"`
+	MinPlateConfidence *float64 `json:"minPlateConfidence"`
+	Notes              *string  `json:"notes"`
+	ChangedBy          string   `json:"changedBy"`
+}
+
+// UpsertViolationRule creates or updates the auto-approve rule for a type (admin)
+// PUT /api/admin/violation-rules/:type
+func UpsertViolationRule(c *gin.Context) {
+	violationType := models.ViolationType(strings.ToUpper(c.Param("type")))
+	if !validViolationType(violationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid violation type"})
+		return
+	}
+
+	var req UpsertViolationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.MinConfidence != nil && (*req.MinConfidence < 0 || *req.MinConfidence > 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minConfidence must be between 0 and 1"})
+		return
+	}
+	if req.MinPlateConfidence != nil && (*req.MinPlateConfidence < 0 || *req.MinPlateConfidence > 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minPlateConfidence must be between 0 and 1"})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	var rule models.ViolationAutoApproveRule
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		action := "update"
+		var before interface{}
+
+		if err := tx.Where("violation_type = ?", violationType).First(&rule).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			// Conservative defaults for a new rule
+			action = "create"
+			rule = models.ViolationAutoApproveRule{
+				ViolationType:     violationType,
+				MinConfidence:     0.95,
+				RequireValidPlate: true,
+			}
+		} else {
+			before = rule
+		}
+
+		if req.Enabled != nil {
+			rule.Enabled = *req.Enabled
+		}
+		if req.MinConfidence != nil {
+			rule.MinConfidence = *req.MinConfidence
+		}
+		if req.RequireValidPlate != nil {
+			rule.RequireValidPlate = *req.RequireValidPlate
+		}
+		if req.MinPlateConfidence != nil {
+			rule.MinPlateConfidence = *req.MinPlateConfidence
+		}
+		if req.Notes != nil {
+			rule.Notes = req.Notes
+		}
+		rule.UpdatedBy = req.ChangedBy
+
+		if err := tx.Save(&rule).Error; err != nil {
+			return err
+		}
+
+		typeStr := string(violationType)
+		return writeRuleAudit(tx, &typeStr, action, before, rule, req.ChangedBy)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rule"})
+		return
+	}
+
+	log.Printf("📝 [VIOLATION_RULES] %s rule updated by %s (enabled=%v)", violationType, req.ChangedBy, rule.Enabled)
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteViolationRule removes the auto-approve rule for a type (admin)
+// DELETE /api/admin/violation-rules/:type
+func DeleteViolationRule(c *gin.Context) {
+	violationType := models.ViolationType(strings.ToUpper(c.Param("type")))
+	changedBy := c.DefaultQuery("changedBy", "admin")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var rule models.ViolationAutoApproveRule
+		if err := tx.Where("violation_type = ?", violationType).First(&rule).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&rule).Error; err != nil {
+			return err
+		}
+
+		typeStr := string(violationType)
+		return writeRuleAudit(tx, &typeStr, "delete", rule, nil, changedBy)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+// GetViolationRuleAudit returns the change history for auto-approve config (admin)
+// GET /api/admin/violation-rules/audit
+func GetViolationRuleAudit(c *gin.Context) {
+	query := database.DB.Model(&models.ViolationRuleAudit{})
+
+	if violationType := c.Query("type"); violationType != "" {
+		query = query.Where("violation_type = ?", strings.ToUpper(violationType))
+	}
+
+	var entries []models.ViolationRuleAudit
+	if err := query.Order("created_at DESC").Limit(200).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// SetAutoApproveEnabled toggles the global auto-approve kill switch (admin)
+// PUT /api/admin/auto-approve
+func SetAutoApproveEnabled(c *gin.Context) {
+	var req struct {
+		Enabled   *bool  `json:"enabled" binding:"required"`
+		ChangedBy string `json:"changedBy"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	previous := isAutoApproveEnabled()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setSettingBool(tx, settingAutoApproveEnabled, *req.Enabled, req.ChangedBy); err != nil {
+			return err
+		}
+		return writeRuleAudit(tx, nil, "kill_switch",
+			gin.H{"enabled": previous}, gin.H{"enabled": *req.Enabled}, req.ChangedBy)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update kill switch"})
+		return
+	}
+
+	log.Printf("⚠️ [VIOLATION_RULES] Auto-approve enabled=%v (set by %s)", *req.Enabled, req.ChangedBy)
+	c.JSON(http.StatusOK, gin.H{
+		"autoApproveEnabled": *req.Enabled,
+		"updatedAt":          time.Now(),
+	})
+}
+
+// GetAutoApproveEnabled returns the global auto-approve kill switch state (admin)
+// GET /api/admin/auto-approve
+func GetAutoApproveEnabled(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"autoApproveEnabled": isAutoApproveEnabled()})
+}
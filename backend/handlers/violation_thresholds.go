@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// isLowConfidence reports whether a violation falls below its type's confidence
+// threshold. A missing confidence counts as zero; types without a threshold are
+// never flagged.
+func isLowConfidence(violationType models.ViolationType, confidence float64) bool {
+	var threshold models.ViolationConfidenceThreshold
+	if err := database.DB.Where("violation_type = ?", violationType).First(&threshold).Error; err != nil {
+		return false
+	}
+	return confidence < threshold.MinConfidence
+}
+
+// hiddenLowConfidenceTypes selects the types whose low-confidence violations are
+// kept out of the default review queue
+func hiddenLowConfidenceTypes() *gorm.DB {
+	return database.DB.Model(&models.ViolationConfidenceThreshold{}).
+		Select("violation_type").
+		Where("hide_from_queue = true")
+}
+
+// GetViolationThresholds lists the per-type confidence thresholds (admin)
+// GET /api/admin/violation-thresholds
+func GetViolationThresholds(c *gin.Context) {
+	var thresholds []models.ViolationConfidenceThreshold
+	if err := database.DB.Order("violation_type ASC").Find(&thresholds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thresholds"})
+		return
+	}
+	c.JSON(http.StatusOK, thresholds)
+}
+
+// UpsertViolationThresholdRequest - Request to set a type's confidence threshold
+type UpsertViolationThresholdRequest struct {
+	MinConfidence *float64 `json:"minConfidence" binding:"required"`
+	HideFromQueue *bool    `json:"hideFromQueue"`
+	ChangedBy     string   `json:"changedBy"`
+}
+
+// UpsertViolationThreshold creates or updates the confidence threshold for a type (admin)
+// PUT /api/admin/violation-thresholds/:type
+func UpsertViolationThreshold(c *gin.Context) {
+	violationType := models.ViolationType(strings.ToUpper(c.Param("type")))
+	if !validViolationType(violationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid violation type"})
+		return
+	}
+
+	var req UpsertViolationThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.MinConfidence < 0 || *req.MinConfidence > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "minConfidence must be between 0 and 1"})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	var threshold models.ViolationConfidenceThreshold
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var before interface{}
+		if err := tx.Where("violation_type = ?", violationType).First(&threshold).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			threshold = models.ViolationConfidenceThreshold{ViolationType: violationType}
+		} else {
+			before = threshold
+		}
+
+		threshold.MinConfidence = *req.MinConfidence
+		if req.HideFromQueue != nil {
+			threshold.HideFromQueue = *req.HideFromQueue
+		}
+		threshold.UpdatedBy = req.ChangedBy
+
+		if err := tx.Save(&threshold).Error; err != nil {
+			return err
+		}
+
+		typeStr := string(violationType)
+		return writeRuleAudit(tx, &typeStr, "threshold_update", before, threshold, req.ChangedBy)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save threshold"})
+		return
+	}
+
+	log.Printf("📝 [VIOLATION_RULES] %s confidence threshold set to %.2f by %s (hideFromQueue=%v)",
+		violationType, threshold.MinConfidence, req.ChangedBy, threshold.HideFromQueue)
+	c.JSON(http.StatusOK, threshold)
+}
+
+// DeleteViolationThreshold removes the confidence threshold for a type (admin).
+// Violations already flagged keep their low_confidence flag.
+// DELETE /api/admin/violation-thresholds/:type
+func DeleteViolationThreshold(c *gin.Context) {
+	violationType := models.ViolationType(strings.ToUpper(c.Param("type")))
+	changedBy := c.DefaultQuery("changedBy", "admin")
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var threshold models.ViolationConfidenceThreshold
+		if err := tx.Where("violation_type = ?", violationType).First(&threshold).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&threshold).Error; err != nil {
+			return err
+		}
+
+		typeStr := string(violationType)
+		return writeRuleAudit(tx, &typeStr, "threshold_delete", threshold, nil, changedBy)
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete threshold"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Threshold deleted"})
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// settingViolationWorkflow holds the JSON-encoded violation status workflow
+const settingViolationWorkflow = "violations.workflow"
+
+// violationStatusPattern restricts custom status names (e.g. UNDER_APPEAL)
+var violationStatusPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,31}$`)
+
+// builtinViolationStatuses are referenced by ingest, auto-approve and notices
+// and can't be removed from a workflow
+var builtinViolationStatuses = []models.ViolationStatus{
+	models.ViolationPending, models.ViolationApproved, models.ViolationRejected, models.ViolationFined,
+}
+
+// ViolationWorkflow lists the allowed status transitions, keyed by the current status
+type ViolationWorkflow struct {
+	Transitions map[models.ViolationStatus][]models.ViolationStatus `json:"transitions"`
+}
+
+// defaultViolationWorkflow matches the review flow before workflows were configurable:
+// a reviewer decision can be reversed until a fine is issued. PAID is only
+// set by the payment gateway webhook, never by a transition.
+func defaultViolationWorkflow() ViolationWorkflow {
+	return ViolationWorkflow{
+		Transitions: map[models.ViolationStatus][]models.ViolationStatus{
+			models.ViolationPending:  {models.ViolationApproved, models.ViolationRejected},
+			models.ViolationApproved: {models.ViolationRejected, models.ViolationFined},
+			models.ViolationRejected: {models.ViolationApproved},
+			models.ViolationFined:    {},
+			models.ViolationPaid:     {},
+		},
+	}
+}
+
+// validate checks status names, that built-in statuses are present and that
+// every target is itself a status of the workflow
+func (w *ViolationWorkflow) validate() error {
+	if len(w.Transitions) == 0 {
+		return fmt.Errorf("transitions are required")
+	}
+	for from, targets := range w.Transitions {
+		if !violationStatusPattern.MatchString(string(from)) {
+			return fmt.Errorf("invalid status %q, use upper-case letters, digits and underscores", from)
+		}
+		for _, to := range targets {
+			if _, ok := w.Transitions[to]; !ok {
+				return fmt.Errorf("%s -> %s targets an undeclared status", from, to)
+			}
+			if to == from {
+				return fmt.Errorf("%s cannot transition to itself", from)
+			}
+			if to == models.ViolationPaid {
+				return fmt.Errorf("%s -> %s: %s is only set by the payment webhook", from, to, to)
+			}
+		}
+	}
+	for _, s := range builtinViolationStatuses {
+		if _, ok := w.Transitions[s]; !ok {
+			return fmt.Errorf("built-in status %s must be part of the workflow", s)
+		}
+	}
+	return nil
+}
+
+// allows reports whether a violation may move from one status to another.
+// Nothing moves to PAID this way, whatever the workflow says.
+func (w *ViolationWorkflow) allows(from, to models.ViolationStatus) bool {
+	if to == models.ViolationPaid {
+		return false
+	}
+	for _, t := range w.Transitions[from] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// getViolationWorkflow loads the configured workflow, falling back to the default
+func getViolationWorkflow() ViolationWorkflow {
+	var setting models.SystemSetting
+	if err := database.DB.First(&setting, "key = ?", settingViolationWorkflow).Error; err != nil {
+		return defaultViolationWorkflow()
+	}
+	var workflow ViolationWorkflow
+	if err := json.Unmarshal([]byte(setting.Value), &workflow); err != nil {
+		log.Printf("⚠️ [VIOLATION_WORKFLOW] Stored workflow is invalid, using default")
+		return defaultViolationWorkflow()
+	}
+	// Workflows saved before PAID became webhook-only may still target it
+	for from, targets := range workflow.Transitions {
+		kept := targets[:0]
+		for _, to := range targets {
+			if to != models.ViolationPaid {
+				kept = append(kept, to)
+			}
+		}
+		workflow.Transitions[from] = kept
+	}
+	if workflow.validate() != nil {
+		log.Printf("⚠️ [VIOLATION_WORKFLOW] Stored workflow is invalid, using default")
+		return defaultViolationWorkflow()
+	}
+	return workflow
+}
+
+// errIllegalTransition is returned when the workflow doesn't allow a status change
+var errIllegalTransition = errors.New("illegal status transition")
+
+// transitionViolation moves a violation to a new status, applying extra column
+// updates, if the workflow allows it from the violation's current status. A
+// violation already in the status is returned unchanged, so retried requests
+// succeed.
+func transitionViolation(id int64, to models.ViolationStatus, updates map[string]interface{}) (*models.TrafficViolation, error) {
+	var violation *models.TrafficViolation
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		violation, err = transitionViolationTx(tx, getViolationWorkflow(), id, to, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return violation, nil
+}
+
+// transitionViolationTx is transitionViolation within a caller's transaction
+func transitionViolationTx(tx *gorm.DB, workflow ViolationWorkflow, id int64, to models.ViolationStatus, updates map[string]interface{}) (*models.TrafficViolation, error) {
+	var violation models.TrafficViolation
+	if err := tx.First(&violation, id).Error; err != nil {
+		return nil, err
+	}
+	if violation.Status == to {
+		return &violation, nil
+	}
+	if !workflow.allows(violation.Status, to) {
+		return nil, fmt.Errorf("%w: %s -> %s", errIllegalTransition, violation.Status, to)
+	}
+
+	updates["status"] = to
+	// Guard on the current status so concurrent transitions can't both apply
+	result := tx.Model(&models.TrafficViolation{}).
+		Where("id = ? AND status = ?", id, violation.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: status changed concurrently", errIllegalTransition)
+	}
+	if err := tx.First(&violation, id).Error; err != nil {
+		return nil, err
+	}
+	return &violation, nil
+}
+
+// respondTransitionError maps a transitionViolation error to a response
+func respondTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
+	case errors.Is(err, errIllegalTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update violation status"})
+	}
+}
+
+// TransitionViolationRequest - Move a violation to another workflow status
+type TransitionViolationRequest struct {
+	Status          string   `json:"status" binding:"required"`
+	ReviewedBy      *string  `json:"reviewedBy"`
+	Note            *string  `json:"note"`
+	RejectionReason *string  `json:"rejectionReason"` // Required for REJECTED
+	FineAmount      *float64 `json:"fineAmount"`
+	FineReference   *string  `json:"fineReference"`
+}
+
+// updates returns the column updates that go with moving to a status
+func (req *TransitionViolationRequest) updates(to models.ViolationStatus, now time.Time) (map[string]interface{}, error) {
+	updates := map[string]interface{}{}
+	switch to {
+	case models.ViolationApproved:
+		updates["reviewed_at"] = now
+	case models.ViolationRejected:
+		if req.RejectionReason == nil || *req.RejectionReason == "" {
+			return nil, fmt.Errorf("rejectionReason is required")
+		}
+		updates["reviewed_at"] = now
+		updates["rejection_reason"] = *req.RejectionReason
+	case models.ViolationFined:
+		updates["fine_issued_at"] = now
+		if req.FineAmount != nil {
+			if *req.FineAmount < 0 {
+				return nil, fmt.Errorf("fineAmount cannot be negative")
+			}
+			updates["fine_amount"] = *req.FineAmount
+		}
+		if req.FineReference != nil {
+			updates["fine_reference"] = *req.FineReference
+		}
+	}
+	if req.ReviewedBy != nil {
+		updates["reviewed_by"] = *req.ReviewedBy
+	}
+	if req.Note != nil {
+		updates["review_note"] = *req.Note
+	}
+	return updates, nil
+}
+
+// TransitionViolation handles POST /api/violations/:id/transition - Change a
+// violation's status, validated against the workflow
+func TransitionViolation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid violation ID"})
+		return
+	}
+
+	var req TransitionViolationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to := models.ViolationStatus(strings.ToUpper(req.Status))
+	if to == models.ViolationPaid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PAID is only set by the payment webhook"})
+		return
+	}
+	updates, err := req.updates(to, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	violation, err := transitionViolation(id, to, updates)
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, violation)
+}
+
+// GetViolationWorkflow returns the status workflow in effect (admin)
+// GET /api/admin/violation-workflow
+func GetViolationWorkflow(c *gin.Context) {
+	c.JSON(http.StatusOK, getViolationWorkflow())
+}
+
+// UpdateViolationWorkflowRequest - Replace the status workflow
+type UpdateViolationWorkflowRequest struct {
+	ViolationWorkflow
+	ChangedBy string `json:"changedBy"`
+}
+
+// UpdateViolationWorkflow replaces the status workflow (admin)
+// PUT /api/admin/violation-workflow
+func UpdateViolationWorkflow(c *gin.Context) {
+	var req UpdateViolationWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	workflow := req.ViolationWorkflow
+	for from, targets := range workflow.Transitions {
+		if targets == nil {
+			workflow.Transitions[from] = []models.ViolationStatus{}
+		}
+	}
+	if err := workflow.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChangedBy == "" {
+		req.ChangedBy = "admin"
+	}
+
+	// Violations already in a status must not be stranded outside the workflow
+	var stranded []string
+	database.DB.Model(&models.TrafficViolation{}).Distinct("status").Pluck("status", &stranded)
+	for _, s := range stranded {
+		if _, ok := workflow.Transitions[models.ViolationStatus(s)]; !ok {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Violations are still in status %s", s)})
+			return
+		}
+	}
+
+	value, _ := json.Marshal(workflow)
+	setting := models.SystemSetting{
+		Key:       settingViolationWorkflow,
+		Value:     string(value),
+		UpdatedBy: req.ChangedBy,
+	}
+	if err := database.DB.Save(&setting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save workflow"})
+		return
+	}
+
+	log.Printf("📝 [VIOLATION_WORKFLOW] Workflow updated by %s (%d statuses)", req.ChangedBy, len(workflow.Transitions))
+	c.JSON(http.StatusOK, workflow)
+}
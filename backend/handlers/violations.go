@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
@@ -19,7 +20,9 @@ func PostViolation(c *gin.Context) {
 		DetectionMethod models.DetectionMethod `json:"detectionMethod"`
 		PlateNumber    *string                `json:"plateNumber"`
 		PlateConfidence *float64              `json:"plateConfidence"`
+		PlateRegion    *string                `json:"plateRegion"` // Issuing state/authority, when the edge knows it
 		PlateImageURL  *string                `json:"plateImageUrl"`
+		PlateImages    []models.PlateImage    `json:"plateImages"` // All plate images; the primary is also taken as plateImageUrl
 		FullSnapshotURL *string               `json:"fullSnapshotUrl"`
 		FrameID        *string                `json:"frameId"`
 		DetectedSpeed  *float64               `json:"detectedSpeed"`
@@ -36,11 +39,12 @@ func PostViolation(c *gin.Context) {
 		return
 	}
 
-	// Upsert device - create if not exists
+	// Upsert device - create if not exists, commissioned like one first seen
+	// at event ingest
 	device := models.Device{
 		ID:     req.DeviceID,
 		Type:   models.DeviceTypeCamera, // Default to camera
-		Status: "active",
+		Status: newDeviceStatus(),
 	}
 
 	// Try to extract lat/lng from metadata if available
@@ -71,11 +75,35 @@ func PostViolation(c *gin.Context) {
 		return
 	}
 
+	// Devices that aren't commissioned yet only produce test events
+	if deviceHeldForCommissioning(device.Status) {
+		var data map[string]interface{}
+		if raw, err := json.Marshal(req); err == nil {
+			json.Unmarshal(raw, &data)
+		}
+		now := time.Now()
+		if err := processCommissioningTestEvent(database.DB, IngestEvent{
+			DeviceID:  req.DeviceID,
+			Type:      "violation",
+			Data:      data,
+			Timestamp: &now,
+		}, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create violation"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"success": true, "commissioningTest": true})
+		return
+	}
+
 	// Try to link to vehicle if plate number is provided
 	var vehicleID *int64
 	if req.PlateNumber != nil && *req.PlateNumber != "" {
+		var reportedRegion string
+		if req.PlateRegion != nil {
+			reportedRegion = *req.PlateRegion
+		}
 		var vehicle models.Vehicle
-		err := database.DB.Where("plate_number = ?", *req.PlateNumber).First(&vehicle).Error
+		err := vehicleByPlate(database.DB, *req.PlateNumber, plateRegion(*req.PlateNumber, reportedRegion, &device)).First(&vehicle).Error
 		if err == nil {
 			vehicleID = &vehicle.ID
 		}
@@ -93,6 +121,12 @@ func PostViolation(c *gin.Context) {
 		}
 	}
 
+	// Exactly one plate image is primary, and it's the one in plateImageUrl
+	plateImages := orderPlateImages(req.PlateImages, req.PlateImageURL)
+	if len(plateImages) > 0 {
+		req.PlateImageURL = &plateImages[0].URL
+	}
+
 	violation := models.TrafficViolation{
 		DeviceID:        req.DeviceID,
 		VehicleID:      vehicleID, // Link to vehicle if found
@@ -112,6 +146,11 @@ func PostViolation(c *gin.Context) {
 		Metadata:        req.Metadata,
 		Timestamp:       timestamp,
 	}
+	stampViolationBBox(&violation)
+
+	if len(plateImages) > 0 {
+		violation.PlateImages = models.NewJSONB(plateImages)
+	}
 
 	if err := database.DB.Create(&violation).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create violation"})
@@ -145,18 +184,35 @@ func GetViolations(c *gin.Context) {
 		query = query.Where("plate_number ILIKE ?", "%"+plateNumber+"%")
 	}
 
+	// Low-confidence violations of types configured to hide them are left out of
+	// the default queue; lowConfidence=true|false filters on the flag explicitly
+	if lowConfidence := c.Query("lowConfidence"); lowConfidence != "" {
+		query = query.Where("low_confidence = ?", lowConfidence == "true")
+	} else if c.Query("includeLowConfidence") != "true" {
+		query = query.Where("NOT (low_confidence = true AND violation_type IN (?))", hiddenLowConfidenceTypes())
+	}
+
 	// Filter by date range
-	if startTime := c.Query("startTime"); startTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, startTime); err == nil {
-			query = query.Where("timestamp >= ?", parsed)
-		}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if endTime := c.Query("endTime"); endTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, endTime); err == nil {
-			query = query.Where("timestamp <= ?", parsed)
-		}
+	if !startTime.IsZero() {
+		query = query.Where("timestamp >= ?", startTime)
+	}
+	if c.Query("endTime") != "" {
+		query = query.Where("timestamp <= ?", endTime)
 	}
 
+	// Filter by where in the frame the violation was
+	region, err := parseFrameRegion(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = frameRegionScope(query, region)
+
 	// Pagination
 	limit := 50
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -184,6 +240,10 @@ func GetViolations(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violations"})
 		return
 	}
+	signViolationImages(c, violations)
+	if !platesVisible(c) {
+		maskViolationPlates(violations)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"violations": violations,
@@ -211,6 +271,10 @@ func GetViolation(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch violation"})
 		return
 	}
+	signViolationImage(c, &violation)
+	if !platesVisible(c) {
+		maskViolationPlate(&violation)
+	}
 
 	c.JSON(http.StatusOK, violation)
 }
@@ -235,7 +299,6 @@ func ApproveViolation(c *gin.Context) {
 
 	now := time.Now()
 	updates := map[string]interface{}{
-		"status":      models.ViolationApproved,
 		"reviewed_at": now,
 	}
 	if req.ReviewNote != nil {
@@ -245,17 +308,11 @@ func ApproveViolation(c *gin.Context) {
 		updates["reviewed_by"] = *req.ReviewedBy
 	}
 
-	if err := database.DB.Model(&models.TrafficViolation{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve violation"})
+	violation, err := transitionViolation(id, models.ViolationApproved, updates)
+	if err != nil {
+		respondTransitionError(c, err)
 		return
 	}
-
-	var violation models.TrafficViolation
-	database.DB.First(&violation, id)
 	c.JSON(http.StatusOK, violation)
 }
 
@@ -280,7 +337,6 @@ func RejectViolation(c *gin.Context) {
 
 	now := time.Now()
 	updates := map[string]interface{}{
-		"status":           models.ViolationRejected,
 		"reviewed_at":      now,
 		"rejection_reason": req.RejectionReason,
 	}
@@ -288,17 +344,11 @@ func RejectViolation(c *gin.Context) {
 		updates["reviewed_by"] = *req.ReviewedBy
 	}
 
-	if err := database.DB.Model(&models.TrafficViolation{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject violation"})
+	violation, err := transitionViolation(id, models.ViolationRejected, updates)
+	if err != nil {
+		respondTransitionError(c, err)
 		return
 	}
-
-	var violation models.TrafficViolation
-	database.DB.First(&violation, id)
 	c.JSON(http.StatusOK, violation)
 }
 
@@ -313,23 +363,35 @@ func UpdateViolationPlate(c *gin.Context) {
 
 	var req struct {
 		PlateNumber string `json:"plateNumber" binding:"required"`
+		CorrectedBy string `json:"correctedBy"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "plateNumber is required"})
 		return
 	}
+	if req.CorrectedBy == "" {
+		req.CorrectedBy = "admin"
+	}
+
+	var violation models.TrafficViolation
+	if err := database.DB.First(&violation, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
+		return
+	}
+	original := ""
+	if violation.PlateNumber != nil {
+		original = *violation.PlateNumber
+	}
 
 	if err := database.DB.Model(&models.TrafficViolation{}).Where("id = ?", id).Update("plate_number", req.PlateNumber).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Violation not found"})
-			return
-		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plate number"})
 		return
 	}
 
-	var violation models.TrafficViolation
+	// Feed the fix to the OCR correction learner
+	recordPlateCorrection(&violation.ID, violation.DeviceID, original, req.PlateNumber, req.CorrectedBy)
+
 	database.DB.First(&violation, id)
 	c.JSON(http.StatusOK, violation)
 }
@@ -342,19 +404,33 @@ func GetViolationStats(c *gin.Context) {
 		Approved    int64 `json:"approved"`
 		Rejected    int64 `json:"rejected"`
 		Fined       int64 `json:"fined"`
+		ByStatus    map[string]int64 `json:"byStatus"` // Includes custom workflow statuses
 		ByType      map[string]int64 `json:"byType"`
 		ByDevice    map[string]int64 `json:"byDevice"`
 	}
 
+	stats.ByStatus = make(map[string]int64)
 	stats.ByType = make(map[string]int64)
 	stats.ByDevice = make(map[string]int64)
 
 	// Get counts by status
-	database.DB.Model(&models.TrafficViolation{}).Count(&stats.Total)
-	database.DB.Model(&models.TrafficViolation{}).Where("status = ?", models.ViolationPending).Count(&stats.Pending)
-	database.DB.Model(&models.TrafficViolation{}).Where("status = ?", models.ViolationApproved).Count(&stats.Approved)
-	database.DB.Model(&models.TrafficViolation{}).Where("status = ?", models.ViolationRejected).Count(&stats.Rejected)
-	database.DB.Model(&models.TrafficViolation{}).Where("status = ?", models.ViolationFined).Count(&stats.Fined)
+	var statusCounts []struct {
+		Status string
+		Count  int64
+	}
+	database.DB.Model(&models.TrafficViolation{}).
+		Select("status, COUNT(*) as count").
+		Group("status").
+		Scan(&statusCounts)
+
+	for _, sc := range statusCounts {
+		stats.ByStatus[sc.Status] = sc.Count
+		stats.Total += sc.Count
+	}
+	stats.Pending = stats.ByStatus[string(models.ViolationPending)]
+	stats.Approved = stats.ByStatus[string(models.ViolationApproved)]
+	stats.Rejected = stats.ByStatus[string(models.ViolationRejected)]
+	stats.Fined = stats.ByStatus[string(models.ViolationFined)]
 
 	// Get counts by type
 	var typeCounts []struct {
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// watchlistCategorySeverity is the default severity of a watchlist entry in
+// each category, used when an entry is added without one
+var watchlistCategorySeverity = map[models.WatchlistCategory]models.HotspotSeverity{
+	models.WatchlistStolen:           models.SeverityRed,
+	models.WatchlistWanted:           models.SeverityRed,
+	models.WatchlistVIP:              models.SeverityOrange,
+	models.WatchlistParkingDefaulter: models.SeverityGreen,
+	models.WatchlistOther:            models.SeverityYellow,
+}
+
+// watchlistAlertPriority maps a watchlist severity onto the 1-10 alert priority scale
+var watchlistAlertPriority = map[models.HotspotSeverity]int{
+	models.SeverityRed:    10,
+	models.SeverityOrange: 8,
+	models.SeverityYellow: 5,
+	models.SeverityGreen:  3,
+}
+
+// InitWatchlistCategories applies WATCHLIST_CATEGORY_SEVERITY overrides
+// (e.g. "VIP=RED,PARKING_DEFAULTER=YELLOW") to the default severity of each
+// category and returns the result
+func InitWatchlistCategories() map[models.WatchlistCategory]models.HotspotSeverity {
+	for _, pair := range strings.Split(os.Getenv("WATCHLIST_CATEGORY_SEVERITY"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		category := models.WatchlistCategory(strings.ToUpper(strings.TrimSpace(name)))
+		severity := models.HotspotSeverity(strings.ToUpper(strings.TrimSpace(value)))
+		if _, known := watchlistCategorySeverity[category]; !known {
+			log.Printf("⚠️ [WATCHLIST] Ignoring severity for unknown category %q", name)
+			continue
+		}
+		if _, valid := watchlistAlertPriority[severity]; !valid {
+			log.Printf("⚠️ [WATCHLIST] Ignoring invalid severity %q for %s", value, category)
+			continue
+		}
+		watchlistCategorySeverity[category] = severity
+	}
+	return watchlistCategorySeverity
+}
+
+// validateWatchlistClass checks a watchlist category and severity, filling in
+// the defaults for empty ones
+func validateWatchlistClass(category *models.WatchlistCategory, severity *models.HotspotSeverity) error {
+	*category = models.WatchlistCategory(strings.ToUpper(string(*category)))
+	*severity = models.HotspotSeverity(strings.ToUpper(string(*severity)))
+	if *category == "" {
+		*category = models.WatchlistOther
+	}
+	defaultSeverity, ok := watchlistCategorySeverity[*category]
+	if !ok {
+		return fmt.Errorf("invalid category %q", *category)
+	}
+	if *severity == "" {
+		*severity = defaultSeverity
+	}
+	if _, ok := watchlistAlertPriority[*severity]; !ok {
+		return fmt.Errorf("invalid severity %q, expected GREEN, YELLOW, ORANGE or RED", *severity)
+	}
+	return nil
+}
+
+// raiseWatchlistAlert records an alert for a watchlisted vehicle seen by a
+// device, at the entry's severity and priority. GREEN hits are recorded for
+// later review but not pushed; RED hits are pushed even in quiet hours.
+func raiseWatchlistAlert(entry *models.Watchlist, plateNumber, deviceID, seenAs string, at time.Time) {
+	alert := newWatchlistAlert(entry, plateNumber, deviceID, seenAs, at, nil)
+	severity := alert.Severity
+	if err := database.DB.Create(&alert).Error; err != nil {
+		log.Printf("⚠️ [WATCHLIST] Failed to record alert for %s on %s: %v", logPlate(plateNumber), deviceID, err)
+		return
+	}
+	log.Printf("🚨 [WATCHLIST] %s %s vehicle %s seen on %s", severity, entry.Category, logPlate(plateNumber), deviceID)
+
+	if severity == models.SeverityGreen {
+		return
+	}
+	notifyAlert(deviceID, severity, alert)
+}
+
+// newWatchlistAlert builds the alert for a watchlisted vehicle seen by a
+// device, at the entry's severity and priority; extra is added to its trigger rule
+func newWatchlistAlert(entry *models.Watchlist, plateNumber, deviceID, seenAs string, at time.Time, extra map[string]interface{}) models.CrowdAlert {
+	severity := entry.Severity
+	priority, ok := watchlistAlertPriority[severity]
+	if !ok {
+		severity = models.SeverityYellow
+		priority = watchlistAlertPriority[severity]
+	}
+
+	rule := map[string]interface{}{
+		"watchlistId": entry.ID,
+		"vehicleId":   entry.VehicleID,
+		"category":    entry.Category,
+		"seenAs":      seenAs,
+	}
+	for k, v := range extra {
+		rule[k] = v
+	}
+
+	description := fmt.Sprintf("%s: %s", entry.Category, entry.Reason)
+	return models.CrowdAlert{
+		DeviceID:     deviceID,
+		Timestamp:    at,
+		AlertType:    "watchlist_hit",
+		Severity:     severity,
+		Priority:     priority,
+		Title:        fmt.Sprintf("Watchlisted vehicle %s (%s)", plateNumber, seenAs),
+		Description:  &description,
+		DensityLevel: models.DensityLow,
+		TriggerRule:  models.NewJSONB(rule),
+	}
+}
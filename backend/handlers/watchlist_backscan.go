@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+const defaultBackscanDays = 30
+
+// maxBackscanDays caps the lookback of a backscan requested through the API
+const maxBackscanDays = 365
+
+// maxBackscanSightings caps the earlier sightings a backscan returns and alerts on
+const maxBackscanSightings = 500
+
+// watchlistBackscanLookback is how far back a vehicle's detections are
+// scanned when it's added to the watchlist; 0 = not scanned on add
+var watchlistBackscanLookback = defaultBackscanDays * 24 * time.Hour
+
+// InitWatchlistBackscan reads WATCHLIST_BACKSCAN_DAYS (default 30, 0 turns off
+// the scan on add) and returns the lookback
+func InitWatchlistBackscan() time.Duration {
+	watchlistBackscanLookback = defaultBackscanDays * 24 * time.Hour
+	if v := os.Getenv("WATCHLIST_BACKSCAN_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days >= 0 {
+			watchlistBackscanLookback = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return watchlistBackscanLookback
+}
+
+// startWatchlistBackscan scans for earlier sightings of a newly watchlisted
+// vehicle in the background
+func startWatchlistBackscan(entry models.Watchlist) {
+	if watchlistBackscanLookback <= 0 {
+		return
+	}
+	go func() {
+		if _, _, err := backscanWatchlist(&entry, watchlistBackscanLookback); err != nil {
+			log.Printf("⚠️ [WATCHLIST] Backscan of entry %d failed: %v", entry.ID, err)
+		}
+	}()
+}
+
+// backscanWatchlist finds detections of a watchlisted vehicle, by vehicle or
+// plate, within lookback before it was added, and records an alert for each
+// one not already alerted on. Alerts are backdated to the sighting and not
+// pushed to operators. Returns the sightings, newest first, and the number
+// of alerts recorded.
+func backscanWatchlist(entry *models.Watchlist, lookback time.Duration) ([]models.VehicleDetection, int, error) {
+	var vehicle models.Vehicle
+	if err := database.DB.First(&vehicle, entry.VehicleID).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch vehicle %d: %w", entry.VehicleID, err)
+	}
+
+	until := entry.AddedAt
+	if until.IsZero() {
+		until = time.Now()
+	}
+	query := database.DB.Where("timestamp >= ? AND timestamp < ?", until.Add(-lookback), until)
+	if vehicle.PlateNumber != nil && *vehicle.PlateNumber != "" {
+		query = query.Where("(vehicle_id = ? OR plate_number = ?)", vehicle.ID, *vehicle.PlateNumber)
+	} else {
+		query = query.Where("vehicle_id = ?", vehicle.ID)
+	}
+
+	var sightings []models.VehicleDetection
+	if err := query.Order("timestamp DESC").Limit(maxBackscanSightings).Find(&sightings).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch detections: %w", err)
+	}
+	if len(sightings) == 0 {
+		return sightings, 0, nil
+	}
+
+	// Skip sightings an earlier backscan of this entry already alerted on
+	ids := make([]string, len(sightings))
+	for i, d := range sightings {
+		ids[i] = strconv.FormatInt(d.ID, 10)
+	}
+	var alerted []string
+	if err := database.DB.Model(&models.CrowdAlert{}).
+		Where("alert_type = ? AND trigger_rule->>'watchlistId' = ?", "watchlist_hit", strconv.FormatInt(entry.ID, 10)).
+		Where("trigger_rule->>'detectionId' IN ?", ids).
+		Pluck("trigger_rule->>'detectionId'", &alerted).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch earlier alerts: %w", err)
+	}
+	done := make(map[string]bool, len(alerted))
+	for _, id := range alerted {
+		done[id] = true
+	}
+
+	alerts := make([]models.CrowdAlert, 0, len(sightings))
+	for i, d := range sightings {
+		if done[ids[i]] {
+			continue
+		}
+		plateNumber := fmt.Sprintf("#%d", vehicle.ID)
+		if d.PlateNumber != nil && *d.PlateNumber != "" {
+			plateNumber = *d.PlateNumber
+		} else if vehicle.PlateNumber != nil && *vehicle.PlateNumber != "" {
+			plateNumber = *vehicle.PlateNumber
+		}
+		alerts = append(alerts, newWatchlistAlert(entry, plateNumber, d.DeviceID, "earlier detection", d.Timestamp,
+			map[string]interface{}{"retroactive": true, "detectionId": d.ID}))
+	}
+	if len(alerts) > 0 {
+		if err := database.DB.Create(&alerts).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to record alerts: %w", err)
+		}
+	}
+
+	log.Printf("🔎 [WATCHLIST] Backscan of entry %d found %d earlier sightings in %s, %d new alerts",
+		entry.ID, len(sightings), lookback, len(alerts))
+	return sightings, len(alerts), nil
+}
+
+// BackscanWatchlist looks for sightings of a watchlisted vehicle from before
+// it was added and records them as retroactive watchlist alerts
+// POST /api/watchlist/:id/backscan
+// Query: lookbackDays (default WATCHLIST_BACKSCAN_DAYS, or 30 when that's 0; max 365)
+func BackscanWatchlist(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid watchlist ID"})
+		return
+	}
+
+	lookback := watchlistBackscanLookback
+	if lookback <= 0 {
+		lookback = defaultBackscanDays * 24 * time.Hour
+	}
+	if v := c.Query("lookbackDays"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 || days > maxBackscanDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("lookbackDays must be between 1 and %d", maxBackscanDays)})
+			return
+		}
+		lookback = time.Duration(days) * 24 * time.Hour
+	}
+
+	var entry models.Watchlist
+	if err := database.DB.First(&entry, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist entry not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist entry"})
+		return
+	}
+	if !entry.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Watchlist entry is no longer active"})
+		return
+	}
+
+	sightings, created, err := backscanWatchlist(&entry, lookback)
+	if err != nil {
+		log.Printf("⚠️ [WATCHLIST] Backscan of entry %d failed: %v", entry.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan detections"})
+		return
+	}
+	signDetectionImages(c, sightings)
+	if !platesVisible(c) {
+		maskDetectionPlates(sightings)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"watchlistId":   entry.ID,
+		"vehicleId":     entry.VehicleID,
+		"lookbackDays":  int(lookback / (24 * time.Hour)),
+		"count":         len(sightings),
+		"alertsCreated": created,
+		"sightings":     sightings,
+	})
+}
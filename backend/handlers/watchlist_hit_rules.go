@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultWatchlistHitWindow = 5 * time.Minute
+
+// watchlistHitRule gates the detection alerts of one watchlist severity on
+// how confident the plate read was. The zero rule alerts on every detection.
+type watchlistHitRule struct {
+	MinConfidence  float64 `json:"minConfidence"`  // reads below it never alert
+	HighConfidence float64 `json:"highConfidence"` // a read at or above it alerts on its own; 0 = none does
+	Corroborations int     `json:"corroborations"` // reads above the minimum within the window needed to alert otherwise; 0 or 1 = one is enough
+}
+
+func (r watchlistHitRule) String() string {
+	return fmt.Sprintf("min %.2f, high %.2f, %d reads", r.MinConfidence, r.HighConfidence, r.Corroborations)
+}
+
+// watchlistHitRules holds the rule of each watchlist severity and the window
+// corroborating reads are counted in
+var watchlistHitRules = struct {
+	bySeverity map[models.HotspotSeverity]watchlistHitRule
+	window     time.Duration
+}{
+	bySeverity: map[models.HotspotSeverity]watchlistHitRule{},
+	window:     defaultWatchlistHitWindow,
+}
+
+// parseSeverityValues parses "RED=0.9,YELLOW=0.7" into a value per severity
+func parseSeverityValues(env string, parse func(string) (float64, error)) map[models.HotspotSeverity]float64 {
+	values := make(map[models.HotspotSeverity]float64)
+	for _, pair := range strings.Split(os.Getenv(env), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		severity := models.HotspotSeverity(strings.ToUpper(strings.TrimSpace(name)))
+		if _, valid := watchlistAlertPriority[severity]; !valid {
+			log.Printf("⚠️ [WATCHLIST] Ignoring %s for unknown severity %q", env, name)
+			continue
+		}
+		v, err := parse(strings.TrimSpace(value))
+		if err != nil {
+			log.Printf("⚠️ [WATCHLIST] Ignoring invalid %s value %q for %s", env, value, severity)
+			continue
+		}
+		values[severity] = v
+	}
+	return values
+}
+
+// parseConfidence parses a plate read confidence between 0 and 1
+func parseConfidence(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 || v > 1 {
+		return 0, fmt.Errorf("confidence must be between 0 and 1")
+	}
+	return v, nil
+}
+
+// parseCorroborations parses a positive read count
+func parseCorroborations(s string) (float64, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("count must be at least 1")
+	}
+	return float64(n), nil
+}
+
+// InitWatchlistHitRules reads per-severity watchlist alert gating, each as
+// SEVERITY=value pairs (e.g. "RED=0.5,GREEN=0.8"):
+// WATCHLIST_HIT_MIN_CONFIDENCE, WATCHLIST_HIT_HIGH_CONFIDENCE and
+// WATCHLIST_HIT_CORROBORATIONS, plus WATCHLIST_HIT_WINDOW_SECONDS (default
+// 300). Returns the rules of the severities that gate alerts.
+func InitWatchlistHitRules() map[models.HotspotSeverity]watchlistHitRule {
+	rules := make(map[models.HotspotSeverity]watchlistHitRule)
+	for severity, v := range parseSeverityValues("WATCHLIST_HIT_MIN_CONFIDENCE", parseConfidence) {
+		rule := rules[severity]
+		rule.MinConfidence = v
+		rules[severity] = rule
+	}
+	for severity, v := range parseSeverityValues("WATCHLIST_HIT_HIGH_CONFIDENCE", parseConfidence) {
+		rule := rules[severity]
+		rule.HighConfidence = v
+		rules[severity] = rule
+	}
+	for severity, v := range parseSeverityValues("WATCHLIST_HIT_CORROBORATIONS", parseCorroborations) {
+		rule := rules[severity]
+		rule.Corroborations = int(v)
+		rules[severity] = rule
+	}
+	watchlistHitRules.bySeverity = rules
+
+	watchlistHitRules.window = defaultWatchlistHitWindow
+	if v := os.Getenv("WATCHLIST_HIT_WINDOW_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			watchlistHitRules.window = time.Duration(secs) * time.Second
+		}
+	}
+	return rules
+}
+
+// watchlistHitConfirmed reports whether a detection of a watchlisted vehicle
+// is trustworthy enough to alert on: its plate read must reach the severity's
+// minimum confidence, and then either reach the high confidence or be
+// corroborated by enough earlier reads of the vehicle within the window. A
+// read without a confidence counts as 0. The reason is set when it isn't.
+func watchlistHitConfirmed(entry *models.Watchlist, confidence float64, at time.Time) (bool, string) {
+	rule := watchlistHitRules.bySeverity[entry.Severity]
+	if confidence < rule.MinConfidence {
+		return false, fmt.Sprintf("read confidence %.2f below minimum %.2f", confidence, rule.MinConfidence)
+	}
+	if rule.Corroborations <= 1 || (rule.HighConfidence > 0 && confidence >= rule.HighConfidence) {
+		return true, ""
+	}
+
+	// The current detection isn't stored yet, so it's the +1
+	var earlier int64
+	if err := database.DB.Model(&models.VehicleDetection{}).
+		Where("vehicle_id = ? AND timestamp >= ? AND timestamp <= ?", entry.VehicleID, at.Add(-watchlistHitRules.window), at).
+		Where("COALESCE(plate_confidence, 0) >= ?", rule.MinConfidence).
+		Count(&earlier).Error; err != nil {
+		// Alerting on a shaky read beats missing a genuine hit
+		log.Printf("⚠️ [WATCHLIST] Failed to count corroborating reads of vehicle %d: %v", entry.VehicleID, err)
+		return true, ""
+	}
+	if int(earlier)+1 < rule.Corroborations {
+		return false, fmt.Sprintf("%d of %d corroborating reads within %s", earlier+1, rule.Corroborations, watchlistHitRules.window)
+	}
+	return true, ""
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+const defaultWatchlistRefreshInterval = time.Minute
+
+// watchlistSet holds the IDs of actively watchlisted vehicles, so ingest can
+// rule out a watchlist hit without a query per detection. It's reloaded when
+// the watchlist changes and periodically, to pick up changes made elsewhere.
+var watchlistSet = struct {
+	mu       sync.RWMutex
+	loaded   bool
+	vehicles map[int64]struct{}
+}{}
+
+// StartWatchlistSet loads the watchlist set and reloads it every
+// WATCHLIST_REFRESH_SECONDS (default 60). Returns the interval.
+func StartWatchlistSet() time.Duration {
+	interval := defaultWatchlistRefreshInterval
+	if v := os.Getenv("WATCHLIST_REFRESH_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			interval = time.Duration(secs) * time.Second
+		}
+	}
+
+	go func() {
+		reloadWatchlistSet()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reloadWatchlistSet()
+		}
+	}()
+	return interval
+}
+
+// reloadWatchlistSet loads the vehicles with an active watchlist entry
+func reloadWatchlistSet() {
+	var ids []int64
+	if err := database.DB.Model(&models.Watchlist{}).Where("is_active = ?", true).
+		Pluck("vehicle_id", &ids).Error; err != nil {
+		log.Printf("⚠️ [WATCHLIST] Failed to load watchlist set: %v", err)
+		return
+	}
+
+	vehicles := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		vehicles[id] = struct{}{}
+	}
+
+	watchlistSet.mu.Lock()
+	watchlistSet.vehicles = vehicles
+	watchlistSet.loaded = true
+	watchlistSet.mu.Unlock()
+}
+
+// maybeWatchlisted reports whether a vehicle may have an active watchlist
+// entry. It's exact once the set is loaded; until then every vehicle may be,
+// so callers fall back to the database.
+func maybeWatchlisted(vehicleID int64) bool {
+	watchlistSet.mu.RLock()
+	defer watchlistSet.mu.RUnlock()
+	if !watchlistSet.loaded {
+		return true
+	}
+	_, ok := watchlistSet.vehicles[vehicleID]
+	return ok
+}
+
+// activeWatchlistEntry returns a vehicle's active watchlist entry, querying
+// the database only for vehicles in the watchlist set
+func activeWatchlistEntry(vehicleID int64) (*models.Watchlist, bool) {
+	if !maybeWatchlisted(vehicleID) {
+		return nil, false
+	}
+	var entry models.Watchlist
+	if err := database.DB.Where("vehicle_id = ? AND is_active = true", vehicleID).First(&entry).Error; err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
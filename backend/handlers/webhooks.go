@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"net/url"
+	"os"
+)
+
+// webhookSigning is the signing policy for every outbound webhook
+var webhookSigning struct {
+	defaultSecret string // for targets without a secret of their own
+	allowUnsigned bool   // unsigned targets are sent without a warning
+}
+
+// InitWebhookSigning reads WEBHOOK_SECRET (signs deliveries to targets that
+// have no secret of their own) and WEBHOOK_ALLOW_UNSIGNED (default false,
+// which warns about each webhook sent unsigned). Returns whether unsigned
+// deliveries are allowed without a warning.
+func InitWebhookSigning() bool {
+	webhookSigning.defaultSecret = os.Getenv("WEBHOOK_SECRET")
+	webhookSigning.allowUnsigned = os.Getenv("WEBHOOK_ALLOW_UNSIGNED") == "true"
+	return webhookSigning.allowUnsigned
+}
+
+// webhookTarget resolves a webhook's URL and signing secret, falling back to
+// WEBHOOK_SECRET. Returns the URL's host, which is safe to log where the full
+// URL may carry a token, or "" when the webhook is off: no URL or an invalid
+// one. A webhook without a secret is still sent, unsigned, so deployments
+// that predate signing keep working - with a warning unless allowed.
+func webhookTarget(name, rawURL, secret string) (string, string) {
+	if rawURL == "" {
+		return "", ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		log.Printf("⚠️ [WEBHOOK] Invalid %s URL, webhook disabled", name)
+		return "", ""
+	}
+	if secret == "" {
+		secret = webhookSigning.defaultSecret
+	}
+	if secret == "" && !webhookSigning.allowUnsigned {
+		log.Printf("⚠️⚠️⚠️ [WEBHOOK] %s has NO signing secret - its deliveries are sent UNSIGNED and receivers can't tell them from forged ones. Set its secret or WEBHOOK_SECRET (or WEBHOOK_ALLOW_UNSIGNED=true to accept this)", name)
+	}
+	return u.Host, secret
+}
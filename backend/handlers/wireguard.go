@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/irisdrone/backend/services"
@@ -14,6 +16,24 @@ func InitWireGuard(endpoint string) {
 	wgService = services.NewWireGuardService(endpoint)
 }
 
+// InitWireGuardDNS reads WIREGUARD_DNS and WIREGUARD_SEARCH_DOMAINS
+// (comma-separated) and pushes them to workers in the setup response. Returns
+// the DNS servers and search domains applied.
+func InitWireGuardDNS() ([]string, []string) {
+	return wgService.SetDNS(splitList(os.Getenv("WIREGUARD_DNS")), splitList(os.Getenv("WIREGUARD_SEARCH_DOMAINS")))
+}
+
+// splitList splits a comma-separated setting, dropping blanks
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // WireGuardSetupRequest from MagicBox
 type WireGuardSetupRequest struct {
 	WorkerID  string `json:"worker_id" binding:"required"`
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+)
+
+// WorkerStartupRequest - What a worker reports once when it starts
+type WorkerStartupRequest struct {
+	Version        string     `json:"version" binding:"required"`
+	BuildTime      string     `json:"build_time"`
+	ConfigVersion  int        `json:"config_version"`
+	CameraCount    int        `json:"camera_count"`
+	DecoderBackend string     `json:"decoder_backend"`
+	DecoderAccel   string     `json:"decoder_accel"`
+	StartedAt      *time.Time `json:"started_at"` // default: when the report is received
+}
+
+// ReportWorkerStartup records a worker's boot as a boot event and takes its
+// version, so restarts and rollouts show up without waiting for a heartbeat
+// POST /api/workers/:id/startup
+func ReportWorkerStartup(c *gin.Context) {
+	received := time.Now()
+
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if !workerTokenValid(&worker, c.GetHeader("X-Auth-Token")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
+		return
+	}
+	if worker.Status == models.WorkerStatusRevoked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Worker has been revoked"})
+		return
+	}
+
+	var req WorkerStartupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ip := c.ClientIP()
+	boot := models.WorkerBootEvent{
+		WorkerID:        worker.ID,
+		Version:         req.Version,
+		PreviousVersion: worker.Version,
+		BuildTime:       req.BuildTime,
+		ConfigVersion:   req.ConfigVersion,
+		CameraCount:     req.CameraCount,
+		DecoderBackend:  req.DecoderBackend,
+		DecoderAccel:    req.DecoderAccel,
+		IP:              &ip,
+		BootedAt:        received,
+		ReceivedAt:      received,
+	}
+	if req.StartedAt != nil {
+		boot.BootedAt = *req.StartedAt
+	}
+	if err := database.DB.Create(&boot).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record startup"})
+		return
+	}
+
+	if err := database.DB.Model(&worker).Updates(map[string]interface{}{
+		"version":   req.Version,
+		"last_seen": received,
+		"last_ip":   ip,
+	}).Error; err != nil {
+		log.Printf("⚠️ [WORKER] Failed to update worker %s after startup: %v", worker.ID, err)
+	}
+
+	if boot.PreviousVersion != nil && *boot.PreviousVersion != req.Version {
+		log.Printf("🔄 [WORKER] Worker %s started v%s (was v%s), config v%d, %d cameras", worker.ID, req.Version, *boot.PreviousVersion, req.ConfigVersion, req.CameraCount)
+	} else {
+		log.Printf("🔄 [WORKER] Worker %s started v%s, config v%d, %d cameras", worker.ID, req.Version, req.ConfigVersion, req.CameraCount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"boot_id":        boot.ID,
+		"config_version": worker.ConfigVersion,
+	})
+}
+
+// GetWorkerBoots lists boot events, newest first: of one worker, or across
+// the fleet filtered by version and time, to follow restarts and rollouts (admin)
+// GET /api/admin/workers/:id/boots
+// GET /api/admin/workers/boots
+func GetWorkerBoots(c *gin.Context) {
+	query := database.DB.Model(&models.WorkerBootEvent{})
+	if workerID := c.Param("id"); workerID != "" {
+		query = query.Where("worker_id = ?", workerID)
+	} else if workerID := c.Query("workerId"); workerID != "" {
+		query = query.Where("worker_id = ?", workerID)
+	}
+	if version := c.Query("version"); version != "" {
+		query = query.Where("version = ?", version)
+	}
+	startTime, endTime, err := parseTimeRange(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !startTime.IsZero() {
+		query = query.Where("received_at >= ?", startTime)
+	}
+	if c.Query("endTime") != "" {
+		query = query.Where("received_at <= ?", endTime)
+	}
+
+	limit := 50
+	if parsed, err := strconv.Atoi(c.Query("limit")); err == nil && parsed > 0 && parsed <= 500 {
+		limit = parsed
+	}
+	offset := 0
+	if parsed, err := strconv.Atoi(c.Query("offset")); err == nil && parsed >= 0 {
+		offset = parsed
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var boots []models.WorkerBootEvent
+	if err := query.Order("received_at DESC").Limit(limit).Offset(offset).Find(&boots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch boot events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"boots":  boots,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+)
+
+const (
+	defaultCommandTimeout = 10 * time.Second
+	maxCommandTimeout     = 60 * time.Second
+)
+
+var commandBus *services.CommandBus
+
+// workerCommandActions are the actions admins may send to a worker
+var workerCommandActions = map[string]bool{
+	services.CommandRestartCamera: true,
+	services.CommandResyncConfig:  true,
+	services.CommandDiagnostics:   true,
+	services.CommandRotateToken:   true,
+}
+
+// SetCommandBus sets the command bus for the handlers
+func SetCommandBus(bus *services.CommandBus) {
+	commandBus = bus
+}
+
+// SendWorkerCommand sends a management command to a worker and waits for its reply (admin)
+// POST /api/admin/workers/:id/command
+func SendWorkerCommand(c *gin.Context) {
+	if commandBus == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Command bus not initialized"})
+		return
+	}
+
+	workerID := c.Param("id")
+
+	var req struct {
+		Action   string                 `json:"action" binding:"required"`
+		CameraID string                 `json:"cameraId"`
+		Params   map[string]interface{} `json:"params"`
+		Timeout  int                    `json:"timeout"` // seconds
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !workerCommandActions[req.Action] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown action: " + req.Action})
+		return
+	}
+	if req.Action == services.CommandRestartCamera && req.CameraID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cameraId is required for restart_camera"})
+		return
+	}
+
+	timeout := defaultCommandTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+		if timeout > maxCommandTimeout {
+			timeout = maxCommandTimeout
+		}
+	}
+
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", workerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if worker.Status == models.WorkerStatusRevoked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Worker has been revoked"})
+		return
+	}
+
+	cmd := services.WorkerCommand{
+		ID:       generateID("cmd"),
+		Action:   req.Action,
+		CameraID: req.CameraID,
+		Params:   req.Params,
+	}
+
+	// New tokens are always issued by the server, never taken from the request,
+	// and never sent over NATS: the command only tells the worker to fetch the
+	// pending token from GET /api/workers/:id/token with its current one. Both
+	// are accepted until the worker uses the new one, even if the reply never
+	// arrives. A retried rotation keeps the same pending token.
+	var newToken string
+	if req.Action == services.CommandRotateToken {
+		if worker.PendingAuthToken != nil {
+			newToken = *worker.PendingAuthToken
+		} else {
+			newToken = generateAuthToken()
+			if err := database.DB.Model(&worker).Update("pending_auth_token", newToken).Error; err != nil {
+				log.Printf("⚠️ Failed to save pending token for worker %s: %v", worker.ID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save new token"})
+				return
+			}
+		}
+		cmd.Params = nil
+	}
+
+	reply, err := commandBus.Send(worker.ID, cmd, timeout)
+	if err != nil {
+		log.Printf("⚠️ Command %s to worker %s failed: %v", req.Action, worker.ID, err)
+		switch {
+		case errors.Is(err, services.ErrWorkerUnreachable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "commandId": cmd.ID})
+		case errors.Is(err, services.ErrCommandTimeout):
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error(), "commandId": cmd.ID})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "commandId": cmd.ID})
+		}
+		return
+	}
+
+	if !reply.Success {
+		c.JSON(http.StatusBadGateway, gin.H{"error": reply.Error, "commandId": cmd.ID, "reply": reply})
+		return
+	}
+
+	// The worker confirmed it saved the token, so the old one can go now. If
+	// this fails the pending token still works and the rotation completes on
+	// the worker's next request.
+	if newToken != "" {
+		if err := database.DB.Model(&worker).Updates(map[string]interface{}{
+			"auth_token":         newToken,
+			"pending_auth_token": nil,
+		}).Error; err != nil {
+			log.Printf("⚠️ Worker %s accepted new token but completing the rotation failed: %v", worker.ID, err)
+		} else {
+			log.Printf("🔑 Rotated auth token for worker %s", worker.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"commandId": cmd.ID,
+		"reply":     reply,
+	})
+}
+
+// GetPendingWorkerToken hands a worker the token issued by a rotate_token
+// command. The worker authenticates with its current token; once it uses
+// the new one, the rotation completes (see workerTokenValid).
+// GET /api/workers/:id/token
+func GetPendingWorkerToken(c *gin.Context) {
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if !workerTokenValid(&worker, c.GetHeader("X-Auth-Token")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
+		return
+	}
+	if worker.Status == models.WorkerStatusRevoked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Worker has been revoked"})
+		return
+	}
+	if worker.PendingAuthToken == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No token rotation pending"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"authToken": *worker.PendingAuthToken})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/irisdrone/backend/models"
+	"github.com/irisdrone/backend/services"
+)
+
+// Worker lifecycle events posted to the webhook
+const (
+	WorkerEventRegistered = "worker.registered"
+	WorkerEventRevoked    = "worker.revoked"
+	WorkerEventDeleted    = "worker.deleted"
+)
+
+const defaultWorkerWebhookRetries = 3
+
+// workerWebhook posts worker lifecycle events to an ops endpoint
+var workerWebhook struct {
+	target services.WebhookTarget // URL "" = off
+	sender *services.WebhookSender
+}
+
+// InitWorkerWebhook reads WORKER_WEBHOOK_URL (empty = off),
+// WORKER_WEBHOOK_SECRET (falls back to WEBHOOK_SECRET) and
+// WORKER_WEBHOOK_RETRIES (default 3). Returns the webhook host, which is safe
+// to log where the full URL may carry a token.
+func InitWorkerWebhook() string {
+	retries := defaultWorkerWebhookRetries
+	if v := os.Getenv("WORKER_WEBHOOK_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			retries = n
+		}
+	}
+	workerWebhook.sender = services.NewWebhookSender(retries)
+
+	workerWebhook.target = services.WebhookTarget{}
+	webhookURL := os.Getenv("WORKER_WEBHOOK_URL")
+	host, secret := webhookTarget("WORKER_WEBHOOK", webhookURL, os.Getenv("WORKER_WEBHOOK_SECRET"))
+	if host == "" {
+		return ""
+	}
+	// Receivers built before timestamped signatures check X-Iris-Signature
+	workerWebhook.target = services.WebhookTarget{URL: webhookURL, Secret: secret, LegacySignature: true}
+	return host
+}
+
+// WorkerWebhookPayload - Body posted to the worker webhook
+type WorkerWebhookPayload struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	WorkerID  string    `json:"worker_id"`
+	Name      string    `json:"name"`
+	Model     string    `json:"model"`
+	IP        string    `json:"ip"`
+	MAC       string    `json:"mac"`
+	Version   *string   `json:"version,omitempty"`
+	By        *string   `json:"by,omitempty"` // Who approved, revoked or deleted the worker
+}
+
+// notifyWorkerEvent posts a worker lifecycle event to the webhook in the
+// background, signed and retried by the shared webhook sender
+func notifyWorkerEvent(event string, worker *models.Worker, by *string) {
+	if workerWebhook.target.URL == "" {
+		return
+	}
+	body, err := json.Marshal(WorkerWebhookPayload{
+		Event:     event,
+		Timestamp: time.Now(),
+		WorkerID:  worker.ID,
+		Name:      worker.Name,
+		Model:     worker.Model,
+		IP:        worker.IP,
+		MAC:       worker.MAC,
+		Version:   worker.Version,
+		By:        by,
+	})
+	if err != nil {
+		log.Printf("⚠️ [WORKER_WEBHOOK] Failed to encode %s for %s: %v", event, worker.ID, err)
+		return
+	}
+
+	go func() {
+		if err := workerWebhook.sender.Deliver(workerWebhook.target, body); err != nil {
+			log.Printf("⚠️ [WORKER_WEBHOOK] Failed to deliver %s for %s: %v", event, worker.ID, err)
+		}
+	}()
+}
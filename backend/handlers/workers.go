@@ -2,8 +2,14 @@ package handlers
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +32,29 @@ func generateAuthToken() string {
 	return hex.EncodeToString(bytes)
 }
 
+// workerTokenValid checks a worker's auth token. While a token rotation is
+// pending, both the current and the new token are accepted; the first request
+// with the new one completes the rotation.
+func workerTokenValid(worker *models.Worker, token string) bool {
+	if token == worker.AuthToken {
+		return true
+	}
+	if worker.PendingAuthToken == nil || token != *worker.PendingAuthToken {
+		return false
+	}
+	if err := database.DB.Model(worker).Updates(map[string]interface{}{
+		"auth_token":         token,
+		"pending_auth_token": nil,
+	}).Error; err != nil {
+		log.Printf("⚠️ Failed to complete token rotation for worker %s: %v", worker.ID, err)
+	} else {
+		log.Printf("🔑 Worker %s switched to its rotated auth token", worker.ID)
+	}
+	worker.AuthToken = token
+	worker.PendingAuthToken = nil
+	return true
+}
+
 // ==================== Worker Registration ====================
 
 // RegisterWorkerRequest - Token-based registration
@@ -119,6 +148,8 @@ func RegisterWorker(c *gin.Context) {
 	token.UsedAt = &now
 	database.DB.Save(&token)
 
+	notifyWorkerEvent(WorkerEventRegistered, &worker, &token.CreatedBy)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"status":     "registered",
 		"worker_id":  worker.ID,
@@ -135,6 +166,25 @@ type RequestApprovalRequest struct {
 	Model      string `json:"model" binding:"required"`
 }
 
+// defaultApprovalRejectCooldown is how long a rejected device must wait before requesting again
+const defaultApprovalRejectCooldown = time.Hour
+
+// approvalRejectCooldown reads APPROVAL_REJECT_COOLDOWN_MINUTES (0 disables the cooldown)
+func approvalRejectCooldown() time.Duration {
+	if v := os.Getenv("APPROVAL_REJECT_COOLDOWN_MINUTES"); v != "" {
+		if mins, err := strconv.Atoi(v); err == nil && mins >= 0 {
+			return time.Duration(mins) * time.Minute
+		}
+	}
+	return defaultApprovalRejectCooldown
+}
+
+// approvalFingerprint identifies a device across approval requests
+func approvalFingerprint(mac, model string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(mac)) + "|" + strings.TrimSpace(model)))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
 // RequestApproval handles tokenless registration requests (needs admin approval)
 // POST /api/workers/request-approval
 func RequestApproval(c *gin.Context) {
@@ -144,9 +194,19 @@ func RequestApproval(c *gin.Context) {
 		return
 	}
 
-	// Check if there's already a pending request for this MAC
+	fingerprint := approvalFingerprint(req.MAC, req.Model)
+	now := time.Now()
+
+	// Collapse repeats of a pending request into it instead of queueing another
 	var existingRequest models.WorkerApprovalRequest
-	if err := database.DB.Where("mac = ? AND status = 'pending'", req.MAC).First(&existingRequest).Error; err == nil {
+	if err := database.DB.Where("(fingerprint = ? OR mac = ?) AND status = 'pending'", fingerprint, req.MAC).
+		First(&existingRequest).Error; err == nil {
+		database.DB.Model(&existingRequest).Updates(map[string]interface{}{
+			"retry_count":     gorm.Expr("retry_count + 1"),
+			"last_request_at": now,
+			"ip":              req.IP,
+			"fingerprint":     fingerprint,
+		})
 		c.JSON(http.StatusOK, gin.H{
 			"status":     "pending",
 			"request_id": existingRequest.ID,
@@ -170,6 +230,30 @@ func RequestApproval(c *gin.Context) {
 		return
 	}
 
+	// A recently rejected device has to wait out the cooldown before asking again
+	if cooldown := approvalRejectCooldown(); cooldown > 0 {
+		var rejected models.WorkerApprovalRequest
+		err := database.DB.Where("(fingerprint = ? OR mac = ?) AND status = 'rejected' AND rejected_at > ?",
+			fingerprint, req.MAC, now.Add(-cooldown)).
+			Order("rejected_at DESC").First(&rejected).Error
+		if err == nil {
+			database.DB.Model(&rejected).Updates(map[string]interface{}{
+				"retry_count":     gorm.Expr("retry_count + 1"),
+				"last_request_at": now,
+			})
+			retryAfter := int(rejected.RejectedAt.Add(cooldown).Sub(now).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"status":        "rejected",
+				"request_id":    rejected.ID,
+				"reject_reason": rejected.RejectReason,
+				"retry_after":   retryAfter,
+				"error":         "Approval request was rejected recently. Try again later.",
+			})
+			return
+		}
+	}
+
 	// Create approval request
 	request := models.WorkerApprovalRequest{
 		ID:         generateID("req"),
@@ -178,6 +262,9 @@ func RequestApproval(c *gin.Context) {
 		MAC:        req.MAC,
 		Model:      req.Model,
 		Status:     "pending",
+
+		Fingerprint:   fingerprint,
+		LastRequestAt: &now,
 	}
 
 	if err := database.DB.Create(&request).Error; err != nil {
@@ -230,11 +317,19 @@ type HeartbeatRequest struct {
 	Cameras   int                    `json:"cameras_active"`
 	Analytics []string               `json:"analytics_running"`
 	Events    map[string]int         `json:"events_stats,omitempty"` // Events sent stats
+
+	// Worker's clock when it sent the heartbeat, for clock skew detection
+	CurrentTime *time.Time `json:"current_time,omitempty"`
+
+	// Config version the worker is running, and why it couldn't apply a newer one
+	AppliedConfigVersion *int   `json:"config_version_applied,omitempty"`
+	ConfigError          string `json:"config_error,omitempty"`
 }
 
 // WorkerHeartbeat handles worker heartbeat/status updates
 // POST /api/workers/:id/heartbeat
 func WorkerHeartbeat(c *gin.Context) {
+	received := time.Now()
 	workerID := c.Param("id")
 	authToken := c.GetHeader("X-Auth-Token")
 
@@ -246,7 +341,7 @@ func WorkerHeartbeat(c *gin.Context) {
 	}
 
 	// Validate auth token
-	if worker.AuthToken != authToken {
+	if !workerTokenValid(&worker, authToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 		return
 	}
@@ -263,8 +358,17 @@ func WorkerHeartbeat(c *gin.Context) {
 		return
 	}
 
-	// Update worker status
-	ip := c.ClientIP()
+	applyHeartbeat(&worker, &req, c.ClientIP(), received)
+
+	// Return current config version (for config sync)
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"config_version": worker.ConfigVersion,
+	})
+}
+
+// applyHeartbeat records a heartbeat on a validated worker and saves it
+func applyHeartbeat(worker *models.Worker, req *HeartbeatRequest, ip string, received time.Time) {
 	worker.LastSeen = time.Now()
 	worker.LastIP = &ip
 	worker.Status = models.WorkerStatusActive
@@ -273,12 +377,94 @@ func WorkerHeartbeat(c *gin.Context) {
 		worker.Resources = models.NewJSONB(req.Resources)
 	}
 
-	database.DB.Save(&worker)
+	if req.CurrentTime != nil {
+		recordClockSkew(worker, *req.CurrentTime, received)
+	}
+
+	if req.AppliedConfigVersion != nil {
+		recordAppliedConfig(worker, *req.AppliedConfigVersion, req.ConfigError)
+	}
+
+	// Remember which analytics the worker runs, for the coverage view
+	if req.Analytics != nil {
+		meta, ok := worker.Metadata.Data.(map[string]interface{})
+		if !ok {
+			meta = make(map[string]interface{})
+		}
+		meta["analyticsRunning"] = req.Analytics
+		worker.Metadata = models.NewJSONB(meta)
+	}
+
+	database.DB.Save(worker)
+}
+
+// maxBatchHeartbeats caps the entries of one batched heartbeat request
+const maxBatchHeartbeats = 500
+
+// BatchHeartbeatEntry - One worker's heartbeat in a batch, with its own credentials
+type BatchHeartbeatEntry struct {
+	WorkerID  string `json:"worker_id"`
+	AuthToken string `json:"auth_token"`
+	HeartbeatRequest
+}
+
+// BatchHeartbeatRequest - Heartbeats of several workers sharing one uplink
+type BatchHeartbeatRequest struct {
+	Heartbeats []BatchHeartbeatEntry `json:"heartbeats"`
+}
+
+// WorkerHeartbeatBatch handles heartbeats of several workers in one request,
+// for sites where many MagicBoxes share a constrained uplink. Each entry is
+// authenticated and applied on its own; one bad entry doesn't fail the rest.
+// POST /api/workers/heartbeat/batch
+func WorkerHeartbeatBatch(c *gin.Context) {
+	received := time.Now()
+
+	var req BatchHeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Heartbeats) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No heartbeats"})
+		return
+	}
+	if len(req.Heartbeats) > maxBatchHeartbeats {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("At most %d heartbeats per batch", maxBatchHeartbeats)})
+		return
+	}
+
+	ip := c.ClientIP()
+	results := make([]gin.H, 0, len(req.Heartbeats))
+	accepted := 0
+	for i := range req.Heartbeats {
+		entry := &req.Heartbeats[i]
+		result := gin.H{"worker_id": entry.WorkerID}
+
+		var worker models.Worker
+		switch err := database.DB.First(&worker, "id = ?", entry.WorkerID).Error; {
+		case entry.WorkerID == "" || err != nil:
+			result["status"], result["code"], result["error"] = "error", http.StatusNotFound, "Worker not found"
+		case !workerTokenValid(&worker, entry.AuthToken):
+			result["status"], result["code"], result["error"] = "error", http.StatusUnauthorized, "Invalid auth token"
+		case worker.Status == models.WorkerStatusRevoked:
+			result["status"], result["code"], result["error"] = "error", http.StatusForbidden, "Worker has been revoked"
+		default:
+			applyHeartbeat(&worker, &entry.HeartbeatRequest, ip, received)
+			result["status"], result["code"], result["config_version"] = "ok", http.StatusOK, worker.ConfigVersion
+			accepted++
+		}
+		results = append(results, result)
+	}
+
+	if rejected := len(req.Heartbeats) - accepted; rejected > 0 {
+		log.Printf("⚠️ [WORKER] Batch heartbeat from %s: %d accepted, %d rejected", ip, accepted, rejected)
+	}
 
-	// Return current config version (for config sync)
 	c.JSON(http.StatusOK, gin.H{
-		"status":         "ok",
-		"config_version": worker.ConfigVersion,
+		"accepted": accepted,
+		"rejected": len(req.Heartbeats) - accepted,
+		"results":  results,
 	})
 }
 
@@ -296,14 +482,31 @@ func GetWorkerConfig(c *gin.Context) {
 	}
 
 	// Validate auth token
-	if worker.AuthToken != authToken {
+	if !workerTokenValid(&worker, authToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 		return
 	}
 
+	c.JSON(http.StatusOK, buildWorkerConfig(&worker))
+}
+
+// GetWorkerEffectiveConfig returns the config payload the worker receives (admin, read-only)
+// GET /api/admin/workers/:id/effective-config
+func GetWorkerEffectiveConfig(c *gin.Context) {
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildWorkerConfig(&worker))
+}
+
+// buildWorkerConfig assembles the config payload served to a worker
+func buildWorkerConfig(worker *models.Worker) gin.H {
 	// Get camera assignments with device details
 	var assignments []models.WorkerCameraAssignment
-	database.DB.Preload("Device").Where("worker_id = ? AND is_active = true", workerID).Find(&assignments)
+	database.DB.Preload("Device").Where("worker_id = ? AND is_active = true", worker.ID).Find(&assignments)
 
 	// Build camera config
 	cameras := make([]gin.H, 0)
@@ -318,17 +521,24 @@ func GetWorkerConfig(c *gin.Context) {
 			"analytics":  a.Analytics,
 			"fps":        a.FPS,
 			"resolution": a.Resolution,
+			"roi":        a.ROI,
+			"priority":   a.Priority,
+
+			// Violation evidence is uploaded whatever this says
+			"store_detection_images": deviceImagePolicy(a.DeviceID).storesDetectionImages(),
 		}
 		cameras = append(cameras, camera)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"worker_id":      worker.ID,
 		"worker_name":    worker.Name,
 		"config_version": worker.ConfigVersion,
 		"cameras":        cameras,
+		"images":         imageFormatConfig(),
+		"event_filters":  workerEventFilters(worker),
 		"updated_at":     worker.UpdatedAt,
-	})
+	}
 }
 
 // ==================== Worker Camera Discovery ====================
@@ -354,7 +564,7 @@ func ReportCameras(c *gin.Context) {
 	}
 
 	// Validate auth token
-	if worker.AuthToken != authToken {
+	if !workerTokenValid(&worker, authToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 		return
 	}
@@ -435,7 +645,7 @@ func GetWorkerDiscoveredCameras(c *gin.Context) {
 	}
 
 	// Validate auth token
-	if worker.AuthToken != authToken {
+	if !workerTokenValid(&worker, authToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 		return
 	}
@@ -495,7 +705,7 @@ func DeleteWorkerCamera(c *gin.Context) {
 	}
 
 	// Validate auth token
-	if worker.AuthToken != authToken {
+	if !workerTokenValid(&worker, authToken) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid auth token"})
 		return
 	}
@@ -526,6 +736,19 @@ func GetWorkers(c *gin.Context) {
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	// clockSkewed=true lists only workers whose clock is off by more than the
+	// threshold; none are when the check is off
+	if c.Query("clockSkewed") == "true" {
+		if clockSkewThreshold == 0 {
+			query = query.Where("1 = 0")
+		} else {
+			query = query.Where("ABS(clock_skew_ms) > ?", clockSkewThreshold.Milliseconds())
+		}
+	}
+	// configDrifted=true lists only workers behind their assigned config version
+	if c.Query("configDrifted") == "true" {
+		query = configDriftedScope(query)
+	}
 
 	var workers []models.Worker
 	query.Order("created_at DESC").Find(&workers)
@@ -533,7 +756,10 @@ func GetWorkers(c *gin.Context) {
 	// Get camera counts for each worker
 	type WorkerWithCounts struct {
 		models.Worker
-		CameraCount int `json:"cameraCount"`
+		CameraCount   int  `json:"cameraCount"`
+		ClockSkewed   bool `json:"clockSkewed"`   // Clock off by more than WORKER_CLOCK_SKEW_THRESHOLD_SECONDS
+		ConfigDrift   *int `json:"configDrift"`   // Config versions behind; null if the worker doesn't report it
+		ConfigDrifted bool `json:"configDrifted"` // Behind past WORKER_CONFIG_DRIFT_GRACE_SECONDS, or failed to apply
 	}
 
 	result := make([]WorkerWithCounts, len(workers))
@@ -542,7 +768,10 @@ func GetWorkers(c *gin.Context) {
 		database.DB.Model(&models.WorkerCameraAssignment{}).Where("worker_id = ? AND is_active = true", w.ID).Count(&count)
 		result[i] = WorkerWithCounts{
 			Worker:      w,
-			CameraCount: int(count),
+			CameraCount:   int(count),
+			ClockSkewed:   clockSkewed(&w),
+			ConfigDrift:   configDrift(&w),
+			ConfigDrifted: configDrifted(&w),
 		}
 	}
 
@@ -594,6 +823,21 @@ func UpdateWorker(c *gin.Context) {
 	c.JSON(http.StatusOK, worker)
 }
 
+// workerImpact describes what revoking or deleting a worker takes offline
+func workerImpact(worker *models.Worker) (gin.H, error) {
+	var cameras int64
+	if err := database.DB.Model(&models.WorkerCameraAssignment{}).
+		Where("worker_id = ?", worker.ID).Count(&cameras).Error; err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"workerName":        worker.Name,
+		"status":            worker.Status,
+		"lastSeen":          worker.LastSeen,
+		"cameraAssignments": cameras,
+	}, nil
+}
+
 // RevokeWorker revokes a worker's access (admin)
 // POST /api/admin/workers/:id/revoke
 func RevokeWorker(c *gin.Context) {
@@ -604,10 +848,17 @@ func RevokeWorker(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
 		return
 	}
+	if !requireConfirmation(c, "revoke worker", worker.ID, func() (gin.H, error) {
+		return workerImpact(&worker)
+	}) {
+		return
+	}
 
 	worker.Status = models.WorkerStatusRevoked
 	database.DB.Save(&worker)
 
+	notifyWorkerEvent(WorkerEventRevoked, &worker, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Worker revoked successfully"})
 }
 
@@ -616,6 +867,17 @@ func RevokeWorker(c *gin.Context) {
 func DeleteWorker(c *gin.Context) {
 	workerID := c.Param("id")
 
+	var worker models.Worker
+	if err := database.DB.First(&worker, "id = ?", workerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Worker not found"})
+		return
+	}
+	if !requireConfirmation(c, "delete worker", worker.ID, func() (gin.H, error) {
+		return workerImpact(&worker)
+	}) {
+		return
+	}
+
 	// Delete camera assignments first
 	database.DB.Where("worker_id = ?", workerID).Delete(&models.WorkerCameraAssignment{})
 
@@ -626,6 +888,8 @@ func DeleteWorker(c *gin.Context) {
 		return
 	}
 
+	notifyWorkerEvent(WorkerEventDeleted, &worker, nil)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Worker deleted successfully"})
 }
 
@@ -686,6 +950,8 @@ func ApproveWorkerRequest(c *gin.Context) {
 	request.WorkerID = &worker.ID
 	database.DB.Save(&request)
 
+	notifyWorkerEvent(WorkerEventRegistered, &worker, &adminUser)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Worker approved successfully",
 		"worker_id": worker.ID,
@@ -731,11 +997,19 @@ type AssignCamerasRequest struct {
 	Assignments []struct {
 		DeviceID   string   `json:"device_id" binding:"required"`
 		Analytics  []string `json:"analytics" binding:"required"`
-		FPS        int      `json:"fps"`
-		Resolution string   `json:"resolution"`
+		FPS        int          `json:"fps"`
+		Resolution string       `json:"resolution"`
+		ROI        [][2]float64 `json:"roi"` // [[x,y],...] as fractions of the frame
+		Priority   int          `json:"priority"` // 1-100, higher first; 0 = default
 	} `json:"assignments" binding:"required"`
 }
 
+const (
+	minAssignmentPriority     = 1
+	maxAssignmentPriority     = 100
+	defaultAssignmentPriority = 50
+)
+
 // AssignCameras assigns cameras to a worker (admin)
 // POST /api/admin/workers/:id/cameras
 func AssignCameras(c *gin.Context) {
@@ -753,6 +1027,18 @@ func AssignCameras(c *gin.Context) {
 		return
 	}
 
+	for _, a := range req.Assignments {
+		if err := validateROI(a.ROI); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid ROI for %s: %v", a.DeviceID, err)})
+			return
+		}
+		if a.Priority != 0 && (a.Priority < minAssignmentPriority || a.Priority > maxAssignmentPriority) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid priority for %s: must be between %d and %d",
+				a.DeviceID, minAssignmentPriority, maxAssignmentPriority)})
+			return
+		}
+	}
+
 	// Start transaction
 	tx := database.DB.Begin()
 
@@ -777,6 +1063,10 @@ func AssignCameras(c *gin.Context) {
 		if resolution == "" {
 			resolution = "720p"
 		}
+		priority := a.Priority
+		if priority == 0 {
+			priority = defaultAssignmentPriority
+		}
 
 		// Check if assignment exists
 		var existing models.WorkerCameraAssignment
@@ -790,6 +1080,8 @@ func AssignCameras(c *gin.Context) {
 				Analytics:  models.NewJSONB(a.Analytics),
 				FPS:        fps,
 				Resolution: resolution,
+				ROI:        roiJSONB(a.ROI),
+				Priority:   priority,
 				IsActive:   true,
 			}
 			tx.Create(&assignment)
@@ -798,6 +1090,8 @@ func AssignCameras(c *gin.Context) {
 			existing.Analytics = models.NewJSONB(a.Analytics)
 			existing.FPS = fps
 			existing.Resolution = resolution
+			existing.ROI = roiJSONB(a.ROI)
+			existing.Priority = priority
 			existing.IsActive = true
 			tx.Save(&existing)
 		}
@@ -807,7 +1101,7 @@ func AssignCameras(c *gin.Context) {
 	}
 
 	// Increment config version
-	tx.Model(&worker).Update("config_version", gorm.Expr("config_version + 1"))
+	bumpConfigVersion(tx, workerID)
 
 	tx.Commit()
 
@@ -846,7 +1140,40 @@ func UnassignCamera(c *gin.Context) {
 	database.DB.Model(&models.Device{}).Where("id = ?", deviceID).Update("worker_id", nil)
 
 	// Increment config version
-	database.DB.Model(&models.Worker{}).Where("id = ?", workerID).Update("config_version", gorm.Expr("config_version + 1"))
+	bumpConfigVersion(database.DB, workerID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Camera unassigned"})
 }
+
+// SetCameraPriority changes the scheduling priority of a camera assignment (admin)
+// PUT /api/admin/workers/:id/cameras/:deviceId/priority
+func SetCameraPriority(c *gin.Context) {
+	workerID := c.Param("id")
+	deviceID := c.Param("deviceId")
+
+	var req struct {
+		Priority int `json:"priority" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority is required"})
+		return
+	}
+	if req.Priority < minAssignmentPriority || req.Priority > maxAssignmentPriority {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("priority must be between %d and %d", minAssignmentPriority, maxAssignmentPriority)})
+		return
+	}
+
+	result := database.DB.Model(&models.WorkerCameraAssignment{}).
+		Where("worker_id = ? AND device_id = ? AND is_active = true", workerID, deviceID).
+		Update("priority", req.Priority)
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assignment not found"})
+		return
+	}
+
+	// Increment config version so the worker picks up the new order
+	bumpConfigVersion(database.DB, workerID)
+
+	c.JSON(http.StatusOK, gin.H{"deviceId": deviceID, "priority": req.Priority})
+}
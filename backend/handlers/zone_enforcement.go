@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/backend/database"
+	"github.com/irisdrone/backend/models"
+	"gorm.io/gorm"
+)
+
+// zoneDirections are the travel directions a zone can expect
+var zoneDirections = map[string]bool{"north": true, "south": true, "east": true, "west": true}
+
+// ZoneEnforcementRequest - Set a zone's enforcement parameters. A zero limit or
+// empty direction clears it.
+type ZoneEnforcementRequest struct {
+	SpeedLimit2W      *float64 `json:"speedLimit2W"`
+	SpeedLimit4W      *float64 `json:"speedLimit4W"`
+	ExpectedDirection *string  `json:"expectedDirection"`
+}
+
+// applyZoneEnforcementRequest copies the set fields of req onto zone
+func applyZoneEnforcementRequest(zone *models.ZoneEnforcement, req *ZoneEnforcementRequest) error {
+	for name, limit := range map[string]*float64{"speedLimit2W": req.SpeedLimit2W, "speedLimit4W": req.SpeedLimit4W} {
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if req.SpeedLimit2W != nil {
+		zone.SpeedLimit2W = nil
+		if *req.SpeedLimit2W > 0 {
+			zone.SpeedLimit2W = req.SpeedLimit2W
+		}
+	}
+	if req.SpeedLimit4W != nil {
+		zone.SpeedLimit4W = nil
+		if *req.SpeedLimit4W > 0 {
+			zone.SpeedLimit4W = req.SpeedLimit4W
+		}
+	}
+	if req.ExpectedDirection != nil {
+		direction := strings.ToLower(*req.ExpectedDirection)
+		if direction != "" && !zoneDirections[direction] {
+			return fmt.Errorf("expectedDirection must be north, south, east or west")
+		}
+		zone.ExpectedDirection = nil
+		if direction != "" {
+			zone.ExpectedDirection = &direction
+		}
+	}
+	return nil
+}
+
+// deviceZoneEnforcement returns the enforcement parameters of a device's
+// zone, or nil if the device is not in a zone or its zone has none
+func deviceZoneEnforcement(deviceID string) *models.ZoneEnforcement {
+	var device models.Device
+	if err := database.DB.Select("id, zone_id").First(&device, "id = ?", deviceID).Error; err != nil {
+		return nil
+	}
+	if device.ZoneID == nil || *device.ZoneID == "" {
+		return nil
+	}
+	var zone models.ZoneEnforcement
+	if err := database.DB.First(&zone, "zone_id = ?", *device.ZoneID).Error; err != nil {
+		return nil
+	}
+	return &zone
+}
+
+// zoneSpeedLimit returns the zone's limit for a vehicle type: the two-wheeler
+// limit for 2W, the four-wheeler limit for everything else
+func zoneSpeedLimit(zone *models.ZoneEnforcement, vehicleType models.VehicleType) *float64 {
+	if vehicleType == models.VehicleType2Wheeler {
+		return zone.SpeedLimit2W
+	}
+	return zone.SpeedLimit4W
+}
+
+// GetZoneEnforcements handles GET /api/zones/enforcement - List the
+// enforcement parameters of every configured zone
+func GetZoneEnforcements(c *gin.Context) {
+	var zones []models.ZoneEnforcement
+	if err := database.DB.Order("zone_id").Find(&zones).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch zone enforcement"})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+// GetZoneEnforcement handles GET /api/zones/:zoneId/enforcement - Get a zone's
+// enforcement parameters
+func GetZoneEnforcement(c *gin.Context) {
+	var zone models.ZoneEnforcement
+	if err := database.DB.First(&zone, "zone_id = ?", c.Param("zoneId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Zone has no enforcement parameters"})
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// SetZoneEnforcement handles PUT /api/zones/:zoneId/enforcement - Set a zone's
+// speed limits and expected direction. Applies to every camera in the zone.
+func SetZoneEnforcement(c *gin.Context) {
+	zoneID := c.Param("zoneId")
+
+	var req ZoneEnforcementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var devices int64
+	database.DB.Model(&models.Device{}).Where("zone_id = ?", zoneID).Count(&devices)
+	if devices == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No devices in zone"})
+		return
+	}
+
+	zone := models.ZoneEnforcement{ZoneID: zoneID}
+	if err := database.DB.First(&zone, "zone_id = ?", zoneID).Error; err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch zone enforcement"})
+		return
+	}
+	if err := applyZoneEnforcementRequest(&zone, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(&zone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save zone enforcement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"zone": zone, "devices": devices})
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -30,6 +31,18 @@ func main() {
 	}
 	defer database.Close()
 
+	// Whether vehicles are identified by plate alone or by plate and region
+	log.Printf("🚗 Vehicles identified by %s", handlers.InitVehicleIdentity())
+
+	// Appearance embeddings for re-identifying plateless vehicles
+	if enabled, pgvector := handlers.InitVehicleReID(); enabled {
+		if pgvector {
+			log.Println("🧬 Vehicle re-identification enabled (pgvector search)")
+		} else {
+			log.Println("🧬 Vehicle re-identification enabled (scanning embeddings in Go, pgvector unavailable)")
+		}
+	}
+
 	// Start embedded NATS server for central communication
 	// Using port 4233 to avoid conflict with MagicBox local NATS on 4222
 	natsPort := 4233
@@ -54,8 +67,17 @@ func main() {
 	feedHub := services.NewFeedHub(natsConn)
 	go feedHub.Run()
 	handlers.SetFeedHub(feedHub)
+	if err := feedHub.StartOverview(); err != nil {
+		log.Printf("⚠️ Overview frames unavailable: %v", err)
+	}
+	if policy := handlers.InitFeedAcks(); policy.MaxLag > 0 {
+		log.Printf("📺 Acking feed clients with frames unacked for more than %s are throttled", policy.MaxLag)
+	}
 	log.Println("📺 Feed hub initialized")
 
+	// Request/reply commands to workers over central NATS
+	handlers.SetCommandBus(services.NewCommandBus(natsConn))
+
 	// Initialize WireGuard service
 	wgEndpoint := os.Getenv("WIREGUARD_ENDPOINT")
 	if wgEndpoint == "" {
@@ -63,21 +85,199 @@ func main() {
 	}
 	handlers.InitWireGuard(wgEndpoint)
 	log.Printf("🔐 WireGuard service initialized (endpoint: %s)", wgEndpoint)
+	if dns, domains := handlers.InitWireGuardDNS(); len(dns) > 0 {
+		log.Printf("🔐 WireGuard DNS pushed to workers: %v (search: %v)", dns, domains)
+	}
+
+	if enabled, maxLine := handlers.InitNDJSONIngest(); enabled {
+		log.Printf("📥 NDJSON event streams accepted (lines up to %d bytes)", maxLine)
+	}
+
+	// Per-device detection rate cap
+	if limit := handlers.InitIngestLimiter(); limit > 0 {
+		log.Printf("🚦 Detection rate cap: %d/s per device", limit)
+	} else {
+		log.Println("ℹ️ Detection rate cap disabled (set MAX_DETECTIONS_PER_SECOND to enable)")
+	}
+
+	// Per-device event sequence numbers: flag gaps, or reorder within a window
+	switch mode, window := handlers.InitEventOrdering(); mode {
+	case handlers.EventOrderingReorder:
+		log.Printf("🔢 Out-of-order events are reordered within %s per device", window)
+	case handlers.EventOrderingFlag:
+		log.Println("🔢 Out-of-order events are flagged, gaps raise device health events")
+	}
+
+	// Per-device image storage quota and retention
+	if quota := handlers.InitStorageQuota(); quota > 0 {
+		log.Printf("💾 Default storage quota: %d MB per device", quota/(1024*1024))
+	} else {
+		log.Println("💾 Default storage quota: unlimited")
+	}
+	if age := handlers.InitDetectionRetention(); age > 0 {
+		log.Printf("💾 Detection images kept for %s unless linked to a violation", age)
+	}
+	if store, age := handlers.InitColdStorage(); store != "" {
+		log.Printf("🧊 Detection images older than %s moved to cold storage (%s)", age, store)
+	}
+	if devices := handlers.StartImagePolicies(); devices > 0 {
+		log.Printf("🖼️ %d devices have their own image storage policy", devices)
+	}
+	handlers.StartStorageRetention()
+	log.Printf("💾 Failed image saves are spooled and retried every %s", handlers.InitImageSpool())
+	if enabled, interval := handlers.InitDeadLetter(); !enabled {
+		log.Println("⚠️ Dead-letter store disabled (DEAD_LETTER=false), failed events are dropped")
+	} else if interval > 0 {
+		log.Printf("📥 Failed events are dead-lettered and retried every %s", interval)
+	} else {
+		log.Println("📥 Failed events are dead-lettered for manual reprocessing")
+	}
+	if archive := handlers.InitImageFormats(); archive != "" {
+		log.Printf("🖼️ Evidence images archived as %s", archive)
+	}
+	if !handlers.InitImageMetadata() {
+		log.Println("🖼️ JPEG metadata stripping disabled, images are stored with their EXIF")
+	}
+
+	// Prune long-unseen vehicles of no enforcement interest
+	if age := handlers.InitVehiclePruning(); age > 0 {
+		log.Printf("🚗 Vehicles unseen for %s are pruned unless watchlisted or linked to a violation", age)
+	}
+	// Purge records past their class's retention, sparing those under legal hold
+	if policy := handlers.InitDataRetention(); len(policy) > 0 {
+		log.Printf("⚖️ Data retention: %v (records under legal hold are kept)", policy)
+	}
+
+	if threshold := handlers.InitClockSkew(); threshold > 0 {
+		log.Printf("⏰ Workers with clocks more than %s off are flagged", threshold)
+	}
+	if enabled, mappings := handlers.InitVehicleTypeInference(); enabled {
+		log.Printf("🚙 Unclassified ANPR detections typed from make/model (%d mappings)", mappings)
+	}
+	log.Printf("⚙️ Workers still behind their config version after %s are flagged as drifted", handlers.InitConfigDrift())
+
+	// Learn plate OCR corrections from reviewer fixes
+	handlers.StartPlateCorrectionLearner()
+
+	// In-memory watchlist so ANPR ingest only queries on a hit
+	log.Printf("🚨 Watchlist set refreshed every %s", handlers.StartWatchlistSet())
+
+	if !handlers.InitGeofenceAlerts() {
+		log.Println("📍 Geofence alerts disabled (GEOFENCE_ALERTS=false)")
+	}
+
+	// Crowd anomalies that raise alerts
+	log.Printf("🚨 Crowd anomaly alerts for: %s", strings.Join(handlers.InitCrowdAnomalyFlags(), ", "))
+
+	// Timezone and currency for violation notices
+	log.Printf("🧾 Violation notices use timezone %s", handlers.InitNoticeFormat())
+
+	// Live frame capture for violations that arrive without an image
+	if enabled, timeout := handlers.InitViolationFrameCapture(); enabled {
+		log.Printf("📸 Violation frame capture enabled (timeout: %v)", timeout)
+	}
+
+	// Hold devices out of live stats until commissioned
+	if handlers.InitDeviceCommissioning() {
+		log.Println("🧪 Device commissioning required before devices count toward live stats")
+	}
+
+	if meters := handlers.InitDeviceLocationCheck(); meters > 0 {
+		log.Printf("📍 Devices placed within %.1fm of another device are flagged", meters)
+	}
+
+	log.Printf("🔢 Up to %d plate images linked per violation", handlers.InitPlateImages())
+	if handlers.InitPlateMasking() {
+		log.Println("🕶️ Plate numbers masked in logs and counting responses")
+	}
+
+	// Default alert severity of each watchlist category
+	log.Printf("🚨 Watchlist category severities: %v", handlers.InitWatchlistCategories())
+	if lookback := handlers.InitWatchlistBackscan(); lookback > 0 {
+		log.Printf("🔎 Newly watchlisted vehicles are checked against %s of detections", lookback)
+	}
+	if window := handlers.InitOffenseSessions(); window > 0 {
+		log.Printf("🧾 A vehicle's violations on one device within %s are grouped into offense sessions", window)
+	}
+	if rules := handlers.InitWatchlistHitRules(); len(rules) > 0 {
+		log.Printf("🔇 Watchlist detection alerts gated on read confidence: %v", rules)
+	}
+	if host := handlers.InitPaymentGateway(); host != "" {
+		log.Printf("💳 Fines payable through the gateway at %s", host)
+	}
+	if handlers.InitWebhookSigning() {
+		log.Println("⚠️ Webhooks without a signing secret are sent unsigned without a warning (WEBHOOK_ALLOW_UNSIGNED=true)")
+	}
+	if host := handlers.InitWorkerWebhook(); host != "" {
+		log.Printf("🪝 Worker registrations, revocations and deletions are posted to %s", host)
+	}
+	if !handlers.InitCameraStatusDedup() {
+		log.Println("📷 Camera status dedup disabled, every camera_status event saves its device")
+	}
+	if handlers.InitBBoxColumns() {
+		log.Println("📐 Bounding boxes copied to bbox columns for frame-region queries")
+	}
+	if !handlers.InitDetectionLocation() {
+		log.Println("📍 Detection location stamping disabled, locations come from the device's current position")
+	}
+	if !handlers.InitHTTPMetrics() {
+		log.Println("📈 Request metrics disabled (HTTP_METRICS=false)")
+	}
+	if ttl := handlers.InitStatsCache(); ttl > 0 {
+		log.Printf("📊 Stats responses cached for %s", ttl)
+	}
+	if enabled, ttl := handlers.InitDestructiveConfirmation(); enabled {
+		log.Printf("🛑 Destructive admin actions need a confirmation token (valid for %s)", ttl)
+	} else {
+		log.Println("⚠️ Destructive admin actions run without confirmation (DESTRUCTIVE_CONFIRMATION=false)")
+	}
+	if handlers.InitMaintenance() {
+		log.Printf("🚧 Starting in maintenance mode: writes are rejected with 503")
+	}
+	if weighted, halfLife := handlers.InitVehicleAttributeWeighting(); weighted {
+		log.Printf("🚗 Vehicle attributes only change on a more confident detection (half-life: %s)", halfLife)
+	}
+	log.Printf("🚗 Enriched detections count violations from the last %d days", int(handlers.InitVehicleEnrichment().Hours()/24))
+
+	// Cut plate crops out of full frames when edges only send a plate box
+	if enabled, padding := handlers.InitPlateCrop(); enabled {
+		log.Printf("✂️ Plate cropping from frames enabled (padding: %.0f%%)", padding*100)
+	}
+
+	if debug, interval := handlers.InitIngestLogging(); debug {
+		log.Println("🐛 Ingest debug logging enabled, every event and worker request is logged")
+	} else if interval > 0 {
+		log.Printf("📊 Ingest activity logged as a summary every %s (INGEST_DEBUG_LOG=true logs every event)", interval)
+	}
+	handlers.StartIngestLogSummary()
 
 	// Setup Gin router
 	if os.Getenv("ENV") == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	// Worker ingest and heartbeat requests are summarized rather than logged one by one
+	router := gin.New()
+	router.Use(handlers.RequestLogger(), gin.Recovery())
+
+	// Per-route latency and status metrics, exported at /metrics
+	router.Use(handlers.MetricsMiddleware())
+	router.GET("/metrics", handlers.GetMetrics)
 
 	// CORS middleware
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Auth-Token", "X-Worker-ID"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Auth-Token", "X-Worker-ID", "X-Confirm-Token"}
+	config.ExposeHeaders = []string{"X-Maintenance-Mode", "Retry-After", "Deprecation", "Sunset", "Link"}
 	router.Use(cors.New(config))
 
+	// Compress JSON responses (images and WebSockets are left alone)
+	router.Use(handlers.GzipMiddleware())
+
+	// Reject writes with 503 + Retry-After during maintenance
+	router.Use(handlers.MaintenanceMiddleware())
+
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -86,6 +286,12 @@ func main() {
 		})
 	})
 
+	// Evidence images are served openly under /uploads, or only via /api/images
+	uploadsOpen, err := handlers.InitImageAccess()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
 	// Serve heatmaps statically
 	usr, err := user.Current()
 	if err == nil {
@@ -93,10 +299,14 @@ func main() {
 		log.Printf("📁 Serving heatmaps from: %s", heatmapsDir)
 		router.Static("/heatmaps", heatmapsDir)
 		
-		// Serve uploaded images from ~/itms/data
-		uploadsDir := filepath.Join(usr.HomeDir, "itms", "data")
-		log.Printf("📁 Serving uploads from: %s", uploadsDir)
-		router.Static("/uploads", uploadsDir)
+		// Serve uploaded images from ~/itms/data, unless image access requires auth
+		if uploadsOpen {
+			uploadsDir := filepath.Join(usr.HomeDir, "itms", "data")
+			log.Printf("📁 Serving uploads from: %s", uploadsDir)
+			router.Static("/uploads", uploadsDir)
+		} else {
+			log.Println("🔒 Uploads require auth, served via /api/images")
+		}
 	}
 
 	// Debug route for heatmaps
@@ -145,162 +355,324 @@ func main() {
 
 	// WebSocket route for camera feeds (outside /api group)
 	router.GET("/ws/feeds", handlers.HandleFeedWebSocket)
+	router.GET("/ws/rotations/:id", handlers.HandleRotationWebSocket)
+	router.GET("/ws/devices/:id/events", handlers.HandleDeviceEventsWebSocket)
+
+	// API Routes, versioned under /api/v1. The unversioned /api stays an
+	// alias of v1 for clients that predate versioning.
+	registerAPIRoutes(router.Group(handlers.APIV1Prefix))
+	if unversioned, sunset := handlers.InitAPIVersioning(); unversioned {
+		registerAPIRoutes(router.Group(handlers.APIPrefix, handlers.UnversionedAPIMiddleware()))
+		if !sunset.IsZero() {
+			log.Printf("🔀 Unversioned /api routes are deprecated, sunset %s", sunset.Format(time.RFC3339))
+		}
+	} else {
+		log.Println("🔀 Unversioned /api routes disabled, clients must use /api/v1")
+	}
 
-	// API Routes
-	api := router.Group("/api")
-	{
-		// Auth routes
-		api.POST("/login", handlers.Login)
+	// Start server
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "3001"
+	}
+
+	log.Printf("🚀 Server running on http://localhost:%s", port)
+	if err := router.Run(":" + port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
 
-		// Feed hub stats
-		api.GET("/feeds/stats", handlers.GetFeedHubStats)
+// registerAPIRoutes mounts the v1 API routes on a group
+func registerAPIRoutes(api *gin.RouterGroup) {
+	// Auth routes
+	api.POST("/login", handlers.Login)
 
-		// Device routes
-		devices := api.Group("/devices")
-		{
-			devices.GET("", handlers.GetDevices)
-			devices.GET("/:id/latest", handlers.GetDeviceLatest)
-			devices.GET("/analytics/surges", handlers.GetDeviceSurges)
-		}
+	// Authenticated and signed access to evidence images
+	api.GET("/images/*path", handlers.ServeImage)
+	api.POST("/images/sign", handlers.SignImageURLs)
 
-		// Ingest routes (legacy)
-		ingest := api.Group("/ingest")
-		{
-			ingest.POST("", handlers.PostIngest)
-		}
+	// Feed hub stats
+	api.GET("/feeds/stats", handlers.GetFeedHubStats)
 
-		// Event ingest from edge workers
-		events := api.Group("/events")
-		{
-			events.POST("/ingest", handlers.IngestEvents)
-		}
+	// Device routes
+	devices := api.Group("/devices")
+	{
+		devices.GET("", handlers.GetDevices)
+		devices.GET("/:id/latest", handlers.GetDeviceLatest)
+		devices.GET("/:id/health", handlers.GetDeviceHealth)
+		devices.GET("/analytics/surges", handlers.GetDeviceSurges)
+		devices.GET("/:id/commissioning", handlers.GetDeviceCommissioning)
+		devices.POST("/:id/commissioning/start", handlers.StartDeviceCommissioning)
+		devices.PUT("/:id/commissioning/location", handlers.SetDeviceCommissioningLocation)
+		devices.POST("/:id/commissioning/complete", handlers.CompleteDeviceCommissioning)
+	}
 
-		// Worker routes (for edge workers to call)
-		workers := api.Group("/workers")
+	// Ingest routes (legacy, superseded by /events/ingest)
+	ingest := api.Group("/ingest", handlers.Deprecated(time.Time{}, handlers.APIV1Prefix+"/events/ingest"))
+	{
+		ingest.POST("", handlers.PostIngest)
+	}
+
+	// Event ingest from edge workers
+	events := api.Group("/events")
+	{
+		events.POST("/ingest", handlers.IngestEvents)
+		events.GET("/ingest/stats", handlers.GetIngestStats)
+		events.GET("/schemas", handlers.GetEventSchemas)
+	}
+
+	// Worker routes (for edge workers to call)
+	workers := api.Group("/workers")
+	{
+		// Registration
+		workers.POST("/register", handlers.RegisterWorker)
+		workers.POST("/request-approval", handlers.RequestApproval)
+		workers.GET("/approval-status/:requestId", handlers.CheckApprovalStatus)
+		
+		// Authenticated worker endpoints
+		workers.POST("/:id/heartbeat", handlers.WorkerHeartbeat)
+		workers.POST("/heartbeat/batch", handlers.WorkerHeartbeatBatch)
+		workers.GET("/:id/config", handlers.GetWorkerConfig)
+		workers.POST("/:id/config/applied", handlers.ReportConfigApplied)
+		workers.POST("/:id/startup", handlers.ReportWorkerStartup)
+		workers.GET("/:id/token", handlers.GetPendingWorkerToken)
+		
+		// Worker camera discovery/management
+		workers.POST("/:id/cameras", handlers.ReportCameras)
+		workers.GET("/:id/cameras", handlers.GetWorkerDiscoveredCameras)
+		workers.DELETE("/:id/cameras/:deviceId", handlers.DeleteWorkerCamera)
+		
+		// WireGuard setup
+		workers.POST("/:id/wireguard/setup", handlers.SetupWireGuard)
+	}
+
+	// Admin routes for worker management
+	admin := api.Group("/admin")
+	{
+		// Workers
+		adminWorkers := admin.Group("/workers")
 		{
-			// Registration
-			workers.POST("/register", handlers.RegisterWorker)
-			workers.POST("/request-approval", handlers.RequestApproval)
-			workers.GET("/approval-status/:requestId", handlers.CheckApprovalStatus)
-			
-			// Authenticated worker endpoints
-			workers.POST("/:id/heartbeat", handlers.WorkerHeartbeat)
-			workers.GET("/:id/config", handlers.GetWorkerConfig)
+			adminWorkers.GET("", handlers.GetWorkers)
+			adminWorkers.GET("/orphaned-cameras", handlers.GetOrphanedCameras)
+			adminWorkers.GET("/boots", handlers.GetWorkerBoots)
+			adminWorkers.GET("/:id", handlers.GetWorker)
+			adminWorkers.GET("/:id/effective-config", handlers.GetWorkerEffectiveConfig)
+			adminWorkers.GET("/:id/boots", handlers.GetWorkerBoots)
+			adminWorkers.GET("/:id/event-filters", handlers.GetWorkerEventFilters)
+			adminWorkers.PUT("/:id/event-filters", handlers.SetWorkerEventFilters)
+			adminWorkers.PUT("/:id", handlers.UpdateWorker)
+			adminWorkers.POST("/:id/revoke", handlers.RevokeWorker)
+			adminWorkers.POST("/:id/command", handlers.SendWorkerCommand)
+			adminWorkers.DELETE("/:id", handlers.DeleteWorker)
 			
-			// Worker camera discovery/management
-			workers.POST("/:id/cameras", handlers.ReportCameras)
-			workers.GET("/:id/cameras", handlers.GetWorkerDiscoveredCameras)
-			workers.DELETE("/:id/cameras/:deviceId", handlers.DeleteWorkerCamera)
+			// Camera assignments
+			adminWorkers.GET("/:id/cameras", handlers.GetWorkerCameras)
+			adminWorkers.POST("/:id/cameras", handlers.AssignCameras)
+			adminWorkers.DELETE("/:id/cameras/:deviceId", handlers.UnassignCamera)
+			adminWorkers.PUT("/:id/cameras/:deviceId/priority", handlers.SetCameraPriority)
 			
-			// WireGuard setup
-			workers.POST("/:id/wireguard/setup", handlers.SetupWireGuard)
+			// Approval requests
+			adminWorkers.GET("/approval-requests", handlers.GetApprovalRequests)
+			adminWorkers.POST("/approval-requests/:id/approve", handlers.ApproveWorkerRequest)
+			adminWorkers.POST("/approval-requests/:id/reject", handlers.RejectWorkerRequest)
 		}
 
-		// Admin routes for worker management
-		admin := api.Group("/admin")
+
+		// Fleet-wide analytics coverage
+		admin.GET("/analytics/coverage", handlers.GetAnalyticsCoverage)
+		
+		// Worker tokens
+		tokens := admin.Group("/worker-tokens")
 		{
-			// Workers
-			adminWorkers := admin.Group("/workers")
-			{
-				adminWorkers.GET("", handlers.GetWorkers)
-				adminWorkers.GET("/:id", handlers.GetWorker)
-				adminWorkers.PUT("/:id", handlers.UpdateWorker)
-				adminWorkers.POST("/:id/revoke", handlers.RevokeWorker)
-				adminWorkers.DELETE("/:id", handlers.DeleteWorker)
-				
-				// Camera assignments
-				adminWorkers.GET("/:id/cameras", handlers.GetWorkerCameras)
-				adminWorkers.POST("/:id/cameras", handlers.AssignCameras)
-				adminWorkers.DELETE("/:id/cameras/:deviceId", handlers.UnassignCamera)
-				
-				// Approval requests
-				adminWorkers.GET("/approval-requests", handlers.GetApprovalRequests)
-				adminWorkers.POST("/approval-requests/:id/approve", handlers.ApproveWorkerRequest)
-				adminWorkers.POST("/approval-requests/:id/reject", handlers.RejectWorkerRequest)
-			}
-			
-			// Worker tokens
-			tokens := admin.Group("/worker-tokens")
-			{
-				tokens.POST("", handlers.CreateWorkerToken)
-				tokens.POST("/bulk", handlers.BulkCreateWorkerTokens)
-				tokens.GET("", handlers.GetWorkerTokens)
-				tokens.GET("/:id", handlers.GetWorkerToken)
-				tokens.POST("/:id/revoke", handlers.RevokeWorkerToken)
-				tokens.DELETE("/:id", handlers.DeleteWorkerToken)
-			}
-
-			// WireGuard management
-			wg := admin.Group("/wireguard")
-			{
-				wg.GET("/status", handlers.GetWireGuardStatus)
-				wg.DELETE("/peers/:pubkey", handlers.RemoveWireGuardPeer)
-			}
+			tokens.POST("", handlers.CreateWorkerToken)
+			tokens.POST("/bulk", handlers.BulkCreateWorkerTokens)
+			tokens.GET("", handlers.GetWorkerTokens)
+			tokens.GET("/:id", handlers.GetWorkerToken)
+			tokens.POST("/:id/revoke", handlers.RevokeWorkerToken)
+			tokens.DELETE("/:id", handlers.DeleteWorkerToken)
 		}
 
-		// Crowd routes
-		crowd := api.Group("/crowd")
+		// WireGuard management
+		wg := admin.Group("/wireguard")
 		{
-			crowd.POST("/analysis", handlers.PostCrowdAnalysis)
-			crowd.GET("/analysis", handlers.GetCrowdAnalysis)
-			crowd.GET("/analysis/latest", handlers.GetLatestCrowdAnalysis)
-			crowd.POST("/alerts", handlers.PostCrowdAlert)
-			crowd.GET("/alerts", handlers.GetCrowdAlerts)
-			crowd.PATCH("/alerts/:id/resolve", handlers.ResolveCrowdAlert)
-			crowd.GET("/hotspots", handlers.GetHotspots)
+			wg.GET("/status", handlers.GetWireGuardStatus)
+			wg.DELETE("/peers/:pubkey", handlers.RemoveWireGuardPeer)
 		}
 
-		// Violations routes (ITMS)
-		violations := api.Group("/violations")
+		// Violation auto-approve rules
+		violationRules := admin.Group("/violation-rules")
 		{
-			violations.POST("", handlers.PostViolation)
-			violations.GET("", handlers.GetViolations)
-			violations.GET("/stats", handlers.GetViolationStats)
-			violations.GET("/:id", handlers.GetViolation)
-			violations.PATCH("/:id/approve", handlers.ApproveViolation)
-			violations.PATCH("/:id/reject", handlers.RejectViolation)
-			violations.PATCH("/:id/plate", handlers.UpdateViolationPlate)
+			violationRules.GET("", handlers.GetViolationRules)
+			violationRules.GET("/audit", handlers.GetViolationRuleAudit)
+			violationRules.PUT("/:type", handlers.UpsertViolationRule)
+			violationRules.DELETE("/:type", handlers.DeleteViolationRule)
+		}
+		admin.GET("/auto-approve", handlers.GetAutoApproveEnabled)
+		admin.PUT("/auto-approve", handlers.SetAutoApproveEnabled)
+		admin.GET("/violations/reviewer-stats", handlers.GetReviewerStats)
+		admin.GET("/events/dead-letter", handlers.GetDeadLetterEvents)
+		admin.POST("/events/dead-letter/:id/reprocess", handlers.ReprocessDeadLetterEvent)
+		admin.DELETE("/events/dead-letter/:id", handlers.DiscardDeadLetterEvent)
+		admin.GET("/vehicles/prune", handlers.GetVehiclePruneStats)
+		admin.POST("/vehicles/prune", handlers.RunVehiclePrune)
+		admin.GET("/retention", handlers.GetDataRetention)
+		admin.POST("/retention/run", handlers.RunDataRetention)
+		admin.PUT("/legal-holds", handlers.SetLegalHold)
+		admin.GET("/legal-holds/audit", handlers.GetLegalHoldAudit)
+		admin.GET("/ml/sample", handlers.GetMLSample)
+		admin.GET("/maintenance", handlers.GetMaintenance)
+		admin.GET("/metrics/routes", handlers.GetRouteLatency)
+		admin.PUT("/maintenance", handlers.SetMaintenance)
+		admin.GET("/violation-workflow", handlers.GetViolationWorkflow)
+		admin.PUT("/violation-workflow", handlers.UpdateViolationWorkflow)
+
+		// Per-type confidence thresholds for flagging low-confidence violations
+		violationThresholds := admin.Group("/violation-thresholds")
+		{
+			violationThresholds.GET("", handlers.GetViolationThresholds)
+			violationThresholds.PUT("/:type", handlers.UpsertViolationThreshold)
+			violationThresholds.DELETE("/:type", handlers.DeleteViolationThreshold)
 		}
 
-		// Vehicles routes (ANPR/VCC)
-		vehicles := api.Group("/vehicles")
+		// Make/model to vehicle type mappings for unclassified detections
+		vehicleTypeMappings := admin.Group("/vehicle-type-mappings")
 		{
-			vehicles.POST("/detect", handlers.PostVehicleDetection)
-			vehicles.GET("", handlers.GetVehicles)
-			vehicles.GET("/stats", handlers.GetVehicleStats)
-			vehicles.GET("/:id", handlers.GetVehicle)
-			vehicles.PATCH("/:id", handlers.UpdateVehicle)
-			vehicles.GET("/:id/detections", handlers.GetVehicleDetections)
-			vehicles.GET("/:id/violations", handlers.GetVehicleViolations)
-			vehicles.POST("/:id/watchlist", handlers.AddToWatchlist)
-			vehicles.DELETE("/:id/watchlist", handlers.RemoveFromWatchlist)
+			vehicleTypeMappings.GET("", handlers.GetVehicleTypeMappings)
+			vehicleTypeMappings.PUT("", handlers.UpsertVehicleTypeMapping)
+			vehicleTypeMappings.DELETE("", handlers.DeleteVehicleTypeMapping)
 		}
 
-		// Watchlist routes
-		watchlist := api.Group("/watchlist")
+		// Evidence storage accounting and per-device quotas
+		storage := admin.Group("/storage")
 		{
-			watchlist.GET("", handlers.GetWatchlist)
+			storage.GET("/usage", handlers.GetStorageUsage)
+			storage.PUT("/quotas/:deviceId", handlers.SetDeviceStorageQuota)
+			storage.DELETE("/quotas/:deviceId", handlers.DeleteDeviceStorageQuota)
+			storage.GET("/policies", handlers.GetImageStoragePolicies)
+			storage.PUT("/policies/:deviceId", handlers.SetImageStoragePolicy)
+			storage.DELETE("/policies/:deviceId", handlers.DeleteImageStoragePolicy)
 		}
 
-		// VCC (Vehicle Classification and Counting) routes
-		vcc := api.Group("/vcc")
+		// Plate OCR corrections learned from manual fixes
+		plateCorrections := admin.Group("/plate-corrections")
 		{
-			vcc.GET("/stats", handlers.GetVCCStats)
-			vcc.GET("/device/:deviceId", handlers.GetVCCByDevice)
-			vcc.GET("/realtime", handlers.GetVCCRealtime)
-			vcc.GET("/events", handlers.GetVCCEvents)
+			plateCorrections.GET("", handlers.GetPlateCorrections)
+			plateCorrections.PUT("/settings", handlers.SetPlateCorrectionEnabled)
+			plateCorrections.PUT("/:id", handlers.UpdatePlateSubstitution)
 		}
 	}
 
-	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3001"
+	// Sites (Site -> Zone -> Device) and site-scoped stats
+	sites := api.Group("/sites")
+	{
+		sites.GET("", handlers.GetSites)
+		sites.POST("", handlers.CreateSite)
+		sites.GET("/:id", handlers.GetSite)
+		sites.PUT("/:id", handlers.UpdateSite)
+		sites.DELETE("/:id", handlers.DeleteSite)
+		sites.GET("/:id/stats", handlers.GetSiteStats)
+		sites.PUT("/:id/zones/:zoneId", handlers.AssignZoneToSite)
+		sites.PUT("/:id/devices/:deviceId", handlers.AssignDeviceToSite)
+		sites.GET("/:id/quiet-hours", handlers.GetQuietHours)
+		sites.POST("/:id/quiet-hours", handlers.CreateQuietHours)
+		sites.PUT("/:id/quiet-hours/:windowId", handlers.UpdateQuietHours)
+		sites.DELETE("/:id/quiet-hours/:windowId", handlers.DeleteQuietHours)
 	}
 
-	log.Printf("🚀 Server running on http://localhost:%s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Per-zone enforcement parameters (speed limits, expected direction)
+	zones := api.Group("/zones")
+	{
+		zones.GET("/enforcement", handlers.GetZoneEnforcements)
+		zones.GET("/:zoneId/enforcement", handlers.GetZoneEnforcement)
+		zones.PUT("/:zoneId/enforcement", handlers.SetZoneEnforcement)
+	}
+
+	// Server-driven live-view rotations
+	rotations := api.Group("/view-rotations")
+	{
+		rotations.POST("", handlers.CreateViewRotation)
+		rotations.GET("", handlers.GetViewRotations)
+		rotations.DELETE("/:id", handlers.DeleteViewRotation)
 	}
-}
 
+	// Crowd routes
+	crowd := api.Group("/crowd")
+	{
+		crowd.POST("/analysis", handlers.PostCrowdAnalysis)
+		crowd.GET("/analysis", handlers.GetCrowdAnalysis)
+		crowd.GET("/analysis/latest", handlers.GetLatestCrowdAnalysis)
+		crowd.POST("/alerts", handlers.PostCrowdAlert)
+		crowd.GET("/alerts", handlers.GetCrowdAlerts)
+		crowd.PATCH("/alerts/:id/resolve", handlers.ResolveCrowdAlert)
+		crowd.GET("/hotspots", handlers.GetHotspots)
+		crowd.GET("/demographics", handlers.GetCrowdDemographics)
+		crowd.GET("/anomalies", handlers.GetCrowdAnomalies)
+	}
+
+	// Violations routes (ITMS)
+	violations := api.Group("/violations")
+	{
+		violations.POST("", handlers.PostViolation)
+		violations.GET("", handlers.GetViolations)
+		violations.GET("/stats", handlers.CacheStats("violations"), handlers.GetViolationStats)
+		violations.GET("/:id", handlers.GetViolation)
+		violations.GET("/:id/notice", handlers.GetViolationNotice)
+		violations.PATCH("/:id/approve", handlers.ApproveViolation)
+		violations.PATCH("/:id/reject", handlers.RejectViolation)
+		violations.POST("/:id/transition", handlers.TransitionViolation)
+		violations.POST("/:id/payment-request", handlers.CreateViolationPayment)
+		violations.POST("/payment-webhook", handlers.ViolationPaymentWebhook)
+		violations.PATCH("/:id/plate", handlers.UpdateViolationPlate)
+	}
+
+	// Offense sessions - a vehicle's violations on one pass, reviewed as a unit
+	offenseSessions := api.Group("/offense-sessions")
+	{
+		offenseSessions.GET("", handlers.GetOffenseSessions)
+		offenseSessions.GET("/:id", handlers.GetOffenseSession)
+		offenseSessions.POST("/:id/transition", handlers.TransitionOffenseSession)
+	}
+
+	// Vehicles routes (ANPR/VCC)
+	vehicles := api.Group("/vehicles")
+	{
+		vehicles.POST("/detect", handlers.PostVehicleDetection)
+		vehicles.GET("", handlers.GetVehicles)
+		vehicles.GET("/stats", handlers.CacheStats("vehicles"), handlers.GetVehicleStats)
+		vehicles.POST("/similar", handlers.FindSimilarVehicles)
+		vehicles.GET("/:id", handlers.GetVehicle)
+		vehicles.PATCH("/:id", handlers.UpdateVehicle)
+		vehicles.GET("/:id/detections", handlers.GetVehicleDetections)
+		vehicles.GET("/:id/violations", handlers.GetVehicleViolations)
+		vehicles.POST("/:id/watchlist", handlers.AddToWatchlist)
+		vehicles.DELETE("/:id/watchlist", handlers.RemoveFromWatchlist)
+	}
+
+	// Watchlist routes
+	watchlist := api.Group("/watchlist")
+	{
+		watchlist.GET("", handlers.GetWatchlist)
+		watchlist.POST("/:id/backscan", handlers.BackscanWatchlist)
+	}
+
+	// Geofences alerting when linked watchlisted vehicles enter or leave them
+	geofences := api.Group("/geofences")
+	{
+		geofences.GET("", handlers.GetGeofences)
+		geofences.POST("", handlers.CreateGeofence)
+		geofences.GET("/:id", handlers.GetGeofence)
+		geofences.PUT("/:id", handlers.UpdateGeofence)
+		geofences.DELETE("/:id", handlers.DeleteGeofence)
+		geofences.POST("/:id/vehicles", handlers.LinkGeofenceVehicle)
+		geofences.DELETE("/:id/vehicles/:vehicleId", handlers.UnlinkGeofenceVehicle)
+	}
+
+	// VCC (Vehicle Classification and Counting) routes
+	vcc := api.Group("/vcc")
+	{
+		vcc.GET("/stats", handlers.CacheStats("vcc"), handlers.GetVCCStats)
+		vcc.GET("/device/:deviceId", handlers.CacheStats("vcc"), handlers.GetVCCByDevice)
+		vcc.GET("/realtime", handlers.CacheStats(handlers.StatsCacheRealtime), handlers.GetVCCRealtime)
+		vcc.GET("/events", handlers.GetVCCEvents)
+	}
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/binary"
 	"encoding/json"
+	"math"
 	"time"
 )
 
@@ -88,12 +90,20 @@ func (j *JSONB) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, &j.Data)
 }
 
+// Device statuses used by the commissioning workflow. Edges may report others (e.g. offline).
+const (
+	DeviceStatusDiscovered    = "discovered"    // reported by a worker, not yet reviewed
+	DeviceStatusCommissioning = "commissioning" // placed, being validated; kept out of live stats
+	DeviceStatusActive        = "active"
+)
+
 // Device model
 type Device struct {
 	ID       string     `gorm:"primaryKey;column:id" json:"id"`
 	Type     DeviceType `gorm:"column:type" json:"type"`
 	Name     *string    `gorm:"column:name" json:"name,omitempty"`
 	ZoneID   *string    `gorm:"column:zone_id" json:"zoneId,omitempty"`
+	SiteID   string     `gorm:"column:site_id;default:default;index" json:"siteId"`
 	Lat      float64    `gorm:"column:lat" json:"lat"`
 	Lng      float64    `gorm:"column:lng" json:"lng"`
 	Status   string     `gorm:"column:status;default:active" json:"status"`
@@ -102,6 +112,13 @@ type Device struct {
 	Config   JSONB      `gorm:"type:jsonb;column:config" json:"config,omitempty"`
 	WorkerID *string    `gorm:"column:worker_id" json:"workerId,omitempty"`
 
+	// Denormalized time of the most recent ingested event, maintained on ingest
+	LastEventAt *time.Time `gorm:"column:last_event_at;index" json:"lastEventAt,omitempty"`
+
+	// Commissioning: when validation started and when the device went live
+	CommissioningStartedAt *time.Time `gorm:"column:commissioning_started_at" json:"commissioningStartedAt,omitempty"`
+	CommissionedAt         *time.Time `gorm:"column:commissioned_at" json:"commissionedAt,omitempty"`
+
 	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
 
@@ -125,6 +142,8 @@ type Event struct {
 	Type      string    `gorm:"column:type" json:"type"`
 	Data      JSONB     `gorm:"type:jsonb;column:data" json:"data"`
 	RiskLevel *string   `gorm:"column:risk_level" json:"riskLevel,omitempty"`
+	SchemaVersion int   `gorm:"column:schema_version;default:1" json:"schemaVersion"` // Layout version of Data for this type
+	LegalHold     bool  `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Never purged while set
 }
 
 func (Event) TableName() string {
@@ -156,6 +175,7 @@ type Worker struct {
 	
 	// Authentication
 	AuthToken   string    `gorm:"column:auth_token;uniqueIndex" json:"-"` // Hidden from JSON
+	PendingAuthToken *string `gorm:"column:pending_auth_token" json:"-"` // Rotated token not used yet; accepted alongside AuthToken until it is
 	
 	// Approval
 	ApprovedAt  *time.Time `gorm:"column:approved_at" json:"approvedAt,omitempty"`
@@ -164,6 +184,7 @@ type Worker struct {
 	// Status tracking
 	LastSeen    time.Time `gorm:"column:last_seen;default:CURRENT_TIMESTAMP;index" json:"lastSeen"`
 	LastIP      *string   `gorm:"column:last_ip" json:"lastIp,omitempty"`
+	ClockSkewMs *int64    `gorm:"column:clock_skew_ms" json:"clockSkewMs,omitempty"` // Worker clock minus server clock at the last heartbeat reporting it
 	
 	// Resource monitoring
 	Resources   JSONB     `gorm:"type:jsonb;column:resources" json:"resources,omitempty"` // CPU, GPU, memory, temp
@@ -171,6 +192,10 @@ type Worker struct {
 	// Configuration
 	Config      JSONB     `gorm:"type:jsonb;column:config" json:"config,omitempty"` // Full worker config
 	ConfigVersion int     `gorm:"column:config_version;default:0" json:"configVersion"`
+	ConfigUpdatedAt      *time.Time `gorm:"column:config_updated_at" json:"configUpdatedAt,omitempty"`           // When ConfigVersion last changed
+	AppliedConfigVersion *int       `gorm:"column:applied_config_version" json:"appliedConfigVersion,omitempty"` // Version the worker reports running
+	ConfigAppliedAt      *time.Time `gorm:"column:config_applied_at" json:"configAppliedAt,omitempty"`
+	ConfigApplyError     *string    `gorm:"column:config_apply_error" json:"configApplyError,omitempty"` // Why the worker couldn't apply the latest config
 	
 	// Metadata
 	Metadata    JSONB     `gorm:"type:jsonb;column:metadata" json:"metadata,omitempty"`
@@ -226,6 +251,14 @@ type WorkerCameraAssignment struct {
 	Analytics   JSONB     `gorm:"type:jsonb;column:analytics" json:"analytics"` // ["anpr", "vcc", "crowd"]
 	FPS         int       `gorm:"column:fps;default:15" json:"fps"`
 	Resolution  string    `gorm:"column:resolution;default:720p" json:"resolution"`
+
+	// Region of interest polygon, [[x,y],...] as fractions of frame size.
+	// Detections outside it are masked on the edge. Empty = whole frame.
+	ROI         JSONB     `gorm:"type:jsonb;column:roi" json:"roi"`
+
+	// Scheduling priority (1-100, higher first). An overloaded edge sheds the
+	// lowest-priority cameras first.
+	Priority    int       `gorm:"column:priority;default:50" json:"priority"`
 	
 	// Status
 	IsActive    bool      `gorm:"column:is_active;default:true" json:"isActive"`
@@ -247,6 +280,11 @@ type WorkerApprovalRequest struct {
 	IP          string    `gorm:"column:ip" json:"ip"`
 	MAC         string    `gorm:"column:mac;index" json:"mac"`
 	Model       string    `gorm:"column:model" json:"model"`
+
+	// Repeated requests from the same device are collapsed into one row
+	Fingerprint   string     `gorm:"column:fingerprint;index" json:"fingerprint"`
+	RetryCount    int        `gorm:"column:retry_count;default:0" json:"retryCount"`
+	LastRequestAt *time.Time `gorm:"column:last_request_at" json:"lastRequestAt,omitempty"`
 	
 	// Request status
 	Status      string    `gorm:"column:status;default:pending;index" json:"status"` // pending, approved, rejected
@@ -267,6 +305,26 @@ func (WorkerApprovalRequest) TableName() string {
 	return "worker_approval_requests"
 }
 
+// WorkerBootEvent model - A worker's report of starting up, one per boot
+type WorkerBootEvent struct {
+	ID              int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	WorkerID        string    `gorm:"column:worker_id;index" json:"workerId"`
+	Version         string    `gorm:"column:version;index" json:"version"`
+	PreviousVersion *string   `gorm:"column:previous_version" json:"previousVersion,omitempty"` // Version the worker last reported, to spot upgrades
+	BuildTime       string    `gorm:"column:build_time" json:"buildTime"`
+	ConfigVersion   int       `gorm:"column:config_version" json:"configVersion"`
+	CameraCount     int       `gorm:"column:camera_count" json:"cameraCount"`
+	DecoderBackend  string    `gorm:"column:decoder_backend" json:"decoderBackend"` // e.g., "gstreamer"
+	DecoderAccel    string    `gorm:"column:decoder_accel" json:"decoderAccel"`     // e.g., "nvidia", "software"
+	IP              *string   `gorm:"column:ip" json:"ip,omitempty"`
+	BootedAt        time.Time `gorm:"column:booted_at;index" json:"bootedAt"` // Worker clock
+	ReceivedAt      time.Time `gorm:"column:received_at" json:"receivedAt"`   // Server clock
+}
+
+func (WorkerBootEvent) TableName() string {
+	return "worker_boot_events"
+}
+
 // CrowdAnalysis model
 type CrowdAnalysis struct {
 	ID        int64             `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
@@ -302,6 +360,8 @@ type CrowdAnalysis struct {
 	
 	ModelType  *string  `gorm:"column:model_type" json:"modelType,omitempty"`
 	Confidence *float64 `gorm:"column:confidence" json:"confidence,omitempty"`
+	SchemaVersion int   `gorm:"column:schema_version;default:1" json:"schemaVersion"` // Layout version of the JSONB fields
+	LegalHold     bool  `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Never purged while set
 	
 	CrowdAlerts []CrowdAlert `gorm:"foreignKey:AnalysisID" json:"crowdAlerts,omitempty"`
 }
@@ -341,6 +401,8 @@ type CrowdAlert struct {
 	
 	ResolvedBy     *string `gorm:"column:resolved_by" json:"resolvedBy,omitempty"`
 	ResolutionNote *string `gorm:"column:resolution_note" json:"resolutionNote,omitempty"`
+
+	LegalHold bool `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Never purged while set
 }
 
 func (CrowdAlert) TableName() string {
@@ -369,6 +431,7 @@ const (
 	ViolationApproved ViolationStatus = "APPROVED"
 	ViolationRejected ViolationStatus = "REJECTED"
 	ViolationFined    ViolationStatus = "FINED"
+	ViolationPaid     ViolationStatus = "PAID" // Fine paid through the payment gateway
 )
 
 // DetectionMethod enum
@@ -396,7 +459,8 @@ type TrafficViolation struct {
 
 	PlateNumber    *string  `gorm:"column:plate_number;index" json:"plateNumber,omitempty"`
 	PlateConfidence *float64 `gorm:"column:plate_confidence" json:"plateConfidence,omitempty"`
-	PlateImageURL  *string  `gorm:"column:plate_image_url" json:"plateImageUrl,omitempty"`
+	PlateImageURL  *string  `gorm:"column:plate_image_url" json:"plateImageUrl,omitempty"` // The primary plate image
+	PlateImages    JSONB    `gorm:"type:jsonb;column:plate_images" json:"plateImages,omitempty"` // []PlateImage, all plate images including the primary
 
 	FullSnapshotURL *string `gorm:"column:full_snapshot_url" json:"fullSnapshotUrl,omitempty"`
 	FrameID         *string `gorm:"column:frame_id" json:"frameId,omitempty"`
@@ -408,7 +472,18 @@ type TrafficViolation struct {
 
 	Confidence *float64 `gorm:"column:confidence" json:"confidence,omitempty"`
 	Metadata   JSONB    `gorm:"type:jsonb;column:metadata" json:"metadata,omitempty"`
+	SchemaVersion int   `gorm:"column:schema_version;default:1" json:"schemaVersion"` // Layout version of Metadata
+
+	// Bounding box from Metadata as fractions of the frame (0-1), denormalized
+	// for frame-region queries, which are served by an index on the box
+	// centre; nil when Metadata has no normalizable box
+	BBoxX *float64 `gorm:"column:bbox_x;index:idx_violation_bbox_centre,expression:(bbox_x + bbox_w / 2),priority:1" json:"bboxX,omitempty"`
+	BBoxY *float64 `gorm:"column:bbox_y;index:idx_violation_bbox_centre,expression:(bbox_y + bbox_h / 2),priority:2" json:"bboxY,omitempty"`
+	BBoxW *float64 `gorm:"column:bbox_w" json:"bboxW,omitempty"`
+	BBoxH *float64 `gorm:"column:bbox_h" json:"bboxH,omitempty"`
 
+	AutoApproved   bool       `gorm:"column:auto_approved;default:false;index" json:"autoApproved"` // Approved by an auto-approve rule
+	LowConfidence  bool       `gorm:"column:low_confidence;default:false;index" json:"lowConfidence"` // Below the type's confidence threshold
 	ReviewedAt     *time.Time `gorm:"column:reviewed_at" json:"reviewedAt,omitempty"`
 	ReviewedBy     *string    `gorm:"column:reviewed_by" json:"reviewedBy,omitempty"`
 	ReviewNote     *string    `gorm:"column:review_note" json:"reviewNote,omitempty"`
@@ -417,12 +492,94 @@ type TrafficViolation struct {
 	FineAmount    *float64   `gorm:"column:fine_amount" json:"fineAmount,omitempty"`
 	FineIssuedAt  *time.Time `gorm:"column:fine_issued_at" json:"fineIssuedAt,omitempty"`
 	FineReference *string    `gorm:"column:fine_reference" json:"fineReference,omitempty"`
+
+	PaymentReference *string    `gorm:"column:payment_reference;index" json:"paymentReference,omitempty"` // Gateway's ID for the fine's latest payment link
+	PaymentURL       *string    `gorm:"column:payment_url" json:"paymentUrl,omitempty"`
+	PaidAmount       *float64   `gorm:"column:paid_amount" json:"paidAmount,omitempty"`
+	PaidAt           *time.Time `gorm:"column:paid_at" json:"paidAt,omitempty"`
+
+	OffenseSessionID *int64 `gorm:"column:offense_session_id;index" json:"offenseSessionId,omitempty"` // Grouped with the vehicle's other violations on the device
+
+	LegalHold bool `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Never purged, nor its evidence images, while set
 }
 
 func (TrafficViolation) TableName() string {
 	return "traffic_violations"
 }
 
+// ViolationPaymentRequest - A payment link created for a fined violation.
+// Every link stays payable, so a webhook for an older link still finds its
+// violation after a newer one replaced it on the violation.
+type ViolationPaymentRequest struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ViolationID int64      `gorm:"column:violation_id;index" json:"violationId"`
+	Reference   string     `gorm:"column:reference;uniqueIndex" json:"reference"` // Gateway's payment ID
+	URL         string     `gorm:"column:url" json:"url"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at" json:"expiresAt,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (ViolationPaymentRequest) TableName() string {
+	return "violation_payment_requests"
+}
+
+// OffenseSession - Violations of one vehicle on one device within a short
+// window (helmet, wrong side and speed on a single pass), reviewed and fined
+// as a unit
+type OffenseSession struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	DeviceID    string    `gorm:"column:device_id;index" json:"deviceId"`
+	VehicleID   *int64    `gorm:"column:vehicle_id;index" json:"vehicleId,omitempty"`
+	PlateNumber *string   `gorm:"column:plate_number;index" json:"plateNumber,omitempty"`
+	StartedAt   time.Time `gorm:"column:started_at;index" json:"startedAt"` // First violation
+	EndedAt     time.Time `gorm:"column:ended_at" json:"endedAt"`           // Last violation
+	ViolationCount int    `gorm:"column:violation_count" json:"violationCount"`
+
+	Status     ViolationStatus `gorm:"column:status;default:PENDING;index" json:"status"`
+	ReviewedAt *time.Time      `gorm:"column:reviewed_at" json:"reviewedAt,omitempty"`
+	ReviewedBy *string         `gorm:"column:reviewed_by" json:"reviewedBy,omitempty"`
+	ReviewNote *string         `gorm:"column:review_note" json:"reviewNote,omitempty"`
+	RejectionReason *string    `gorm:"column:rejection_reason" json:"rejectionReason,omitempty"`
+
+	FineAmount    *float64   `gorm:"column:fine_amount" json:"fineAmount,omitempty"` // Combined fine for all its violations
+	FineIssuedAt  *time.Time `gorm:"column:fine_issued_at" json:"fineIssuedAt,omitempty"`
+	FineReference *string    `gorm:"column:fine_reference" json:"fineReference,omitempty"`
+
+	Violations []TrafficViolation `gorm:"foreignKey:OffenseSessionID" json:"violations,omitempty"`
+
+	LegalHold bool `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Holds all its violations while set
+
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (OffenseSession) TableName() string {
+	return "offense_sessions"
+}
+
+// PlateImage - One plate image of a violation. A vehicle photographed from
+// several sides or cameras has several, one of them primary.
+type PlateImage struct {
+	URL     string `json:"url"`
+	View    string `json:"view,omitempty"` // e.g. "front", "rear"
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// PlateImageList returns the violation's plate images, falling back to the
+// single PlateImageURL of violations stored before multiple images existed
+func (v *TrafficViolation) PlateImageList() []PlateImage {
+	var images []PlateImage
+	if v.PlateImages.Data != nil {
+		if data, err := json.Marshal(v.PlateImages.Data); err == nil {
+			json.Unmarshal(data, &images)
+		}
+	}
+	if len(images) == 0 && v.PlateImageURL != nil {
+		images = []PlateImage{{URL: *v.PlateImageURL, Primary: true}}
+	}
+	return images
+}
+
 // VehicleType enum
 type VehicleType string
 
@@ -439,13 +596,15 @@ const (
 // Vehicle model - Represents a unique vehicle (identified by plate or characteristics)
 type Vehicle struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	PlateNumber *string `gorm:"column:plate_number;uniqueIndex;index" json:"plateNumber,omitempty"` // Nullable - some vehicles may not have plates
+	PlateNumber *string `gorm:"column:plate_number;index" json:"plateNumber,omitempty"` // Nullable - some vehicles may not have plates
+	PlateRegion string  `gorm:"column:plate_region;default:''" json:"plateRegion,omitempty"` // Issuing state/authority, e.g. "KA"; "" = unknown
 	
 	// Vehicle characteristics (may be partial)
 	Make       *string    `gorm:"column:make" json:"make,omitempty"`       // e.g., "Honda", "Toyota"
 	Model      *string    `gorm:"column:model" json:"model,omitempty"`     // e.g., "City", "Innova"
 	VehicleType VehicleType `gorm:"column:vehicle_type" json:"vehicleType"` // 2W, 4W, AUTO, TRUCK, BUS
 	Color      *string    `gorm:"column:color" json:"color,omitempty"`     // e.g., "White", "Black"
+	AttributeConfidence JSONB `gorm:"type:jsonb;column:attribute_confidence" json:"attributeConfidence,omitempty"` // Per attribute column: {confidence, at} of the observation that set it
 	
 	// Tracking
 	FirstSeen      time.Time `gorm:"column:first_seen;index" json:"firstSeen"`
@@ -467,10 +626,38 @@ type Vehicle struct {
 	Watchlist  *Watchlist          `gorm:"foreignKey:VehicleID" json:"watchlist,omitempty"`
 }
 
+// Vehicle identity modes: which columns a vehicle is unique on
+const (
+	VehicleIdentityPlate       = "plate"        // plate number alone
+	VehicleIdentityPlateRegion = "plate_region" // plate number within its issuing region
+)
+
 func (Vehicle) TableName() string {
 	return "vehicles"
 }
 
+// ArchivedVehicle - A vehicle pruned from the registry after going unseen for
+// the retention period, kept so its plate history isn't lost
+type ArchivedVehicle struct {
+	ID             int64       `gorm:"primaryKey;column:id" json:"id"` // Original vehicles.id
+	PlateNumber    *string     `gorm:"column:plate_number;index" json:"plateNumber,omitempty"`
+	PlateRegion    string      `gorm:"column:plate_region" json:"plateRegion,omitempty"`
+	Make           *string     `gorm:"column:make" json:"make,omitempty"`
+	Model          *string     `gorm:"column:model" json:"model,omitempty"`
+	VehicleType    VehicleType `gorm:"column:vehicle_type" json:"vehicleType"`
+	Color          *string     `gorm:"column:color" json:"color,omitempty"`
+	FirstSeen      time.Time   `gorm:"column:first_seen" json:"firstSeen"`
+	LastSeen       time.Time   `gorm:"column:last_seen;index" json:"lastSeen"`
+	DetectionCount int64       `gorm:"column:detection_count" json:"detectionCount"`
+	Metadata       JSONB       `gorm:"type:jsonb;column:metadata" json:"metadata,omitempty"`
+	CreatedAt      time.Time   `gorm:"column:created_at" json:"createdAt"`
+	ArchivedAt     time.Time   `gorm:"column:archived_at;default:CURRENT_TIMESTAMP;index" json:"archivedAt"`
+}
+
+func (ArchivedVehicle) TableName() string {
+	return "archived_vehicles"
+}
+
 // VehicleDetection model - Each time a vehicle is detected by a camera
 type VehicleDetection struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
@@ -498,19 +685,89 @@ type VehicleDetection struct {
 	PlateImageURL  *string `gorm:"column:plate_image_url" json:"plateImageUrl,omitempty"`
 	VehicleImageURL *string `gorm:"column:vehicle_image_url" json:"vehicleImageUrl,omitempty"`
 	FrameID        *string `gorm:"column:frame_id" json:"frameId,omitempty"`
+	TrackID        *string `gorm:"column:track_id;index:idx_detection_track" json:"trackId,omitempty"` // Edge tracker ID, used for dedup
+	Analytic       string  `gorm:"column:analytic" json:"analytic,omitempty"`                          // Analytic that produced it (anpr or vcc); tracks are deduped per analytic
 	
 	// Location and direction
+	Lat            *float64 `gorm:"column:lat" json:"lat,omitempty"` // Device location at ingest; nil on rows stored before stamping
+	Lng            *float64 `gorm:"column:lng" json:"lng,omitempty"`
 	Direction      *string  `gorm:"column:direction" json:"direction,omitempty"` // "north", "south", "east", "west"
 	Lane           *int     `gorm:"column:lane" json:"lane,omitempty"`
 	
 	// Metadata
 	Metadata JSONB `gorm:"type:jsonb;column:metadata" json:"metadata,omitempty"` // Bounding boxes, speed, etc.
+	SchemaVersion int `gorm:"column:schema_version;default:1" json:"schemaVersion"` // Layout version of Metadata for the analytic that produced it
+
+	// Bounding box from Metadata as fractions of the frame (0-1), denormalized
+	// for frame-region queries, which are served by an index on the box
+	// centre; nil when Metadata has no normalizable box
+	BBoxX *float64 `gorm:"column:bbox_x;index:idx_detection_bbox_centre,expression:(bbox_x + bbox_w / 2),priority:1" json:"bboxX,omitempty"`
+	BBoxY *float64 `gorm:"column:bbox_y;index:idx_detection_bbox_centre,expression:(bbox_y + bbox_h / 2),priority:2" json:"bboxY,omitempty"`
+	BBoxW *float64 `gorm:"column:bbox_w" json:"bboxW,omitempty"`
+	BBoxH *float64 `gorm:"column:bbox_h" json:"bboxH,omitempty"`
+
+	LegalHold bool `gorm:"column:legal_hold;default:false;index" json:"legalHold"` // Never purged, nor its images, while set
 }
 
 func (VehicleDetection) TableName() string {
 	return "vehicle_detections"
 }
 
+// Embedding is an appearance vector, stored as little-endian float32 bytes
+type Embedding []float32
+
+func (e Embedding) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	buf := make([]byte, 4*len(e))
+	for i, v := range e {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf, nil
+}
+
+func (e *Embedding) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok || len(bytes)%4 != 0 {
+		*e = nil
+		return nil
+	}
+	vec := make(Embedding, len(bytes)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(bytes[4*i:]))
+	}
+	*e = vec
+	return nil
+}
+
+// VehicleEmbedding - Appearance embedding an edge sent with a detection, for
+// re-identifying plateless vehicles across cameras. Only migrated when
+// VEHICLE_REID is on; with pgvector the vector is also kept in a vec column.
+type VehicleEmbedding struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	DetectionID int64     `gorm:"column:detection_id;uniqueIndex" json:"detectionId"`
+	VehicleID   *int64    `gorm:"column:vehicle_id;index" json:"vehicleId,omitempty"`
+	DeviceID    string    `gorm:"column:device_id;index" json:"deviceId"`
+	Timestamp   time.Time `gorm:"column:timestamp;index" json:"timestamp"`
+	Embedding   Embedding `gorm:"type:bytea;column:embedding" json:"-"` // L2-normalized
+}
+
+func (VehicleEmbedding) TableName() string {
+	return "vehicle_embeddings"
+}
+
+// WatchlistCategory enum - Why a vehicle is watched
+type WatchlistCategory string
+
+const (
+	WatchlistStolen           WatchlistCategory = "STOLEN"
+	WatchlistWanted           WatchlistCategory = "WANTED"
+	WatchlistVIP              WatchlistCategory = "VIP"
+	WatchlistParkingDefaulter WatchlistCategory = "PARKING_DEFAULTER"
+	WatchlistOther            WatchlistCategory = "OTHER"
+)
+
 // Watchlist model - Vehicles to monitor/watch
 type Watchlist struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
@@ -518,6 +775,8 @@ type Watchlist struct {
 	Vehicle   Vehicle   `gorm:"foreignKey:VehicleID" json:"vehicle,omitempty"`
 	
 	Reason    string    `gorm:"column:reason" json:"reason"` // Why it's watchlisted
+	Category  WatchlistCategory `gorm:"column:category;default:OTHER;index" json:"category"`
+	Severity  HotspotSeverity   `gorm:"column:severity;default:YELLOW;index" json:"severity"` // Severity of the alerts it raises
 	AddedBy   string    `gorm:"column:added_by" json:"addedBy"` // User ID
 	AddedAt   time.Time `gorm:"column:added_at;default:CURRENT_TIMESTAMP" json:"addedAt"`
 	IsActive  bool      `gorm:"column:is_active;default:true;index" json:"isActive"`
@@ -537,3 +796,302 @@ func (Watchlist) TableName() string {
 	return "watchlist"
 }
 
+// Geofence - A [lat, lng] polygon that raises an alert when one of its linked
+// watchlisted vehicles is detected entering or leaving it
+type Geofence struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Name        string    `gorm:"column:name" json:"name"`
+	Description *string   `gorm:"column:description" json:"description,omitempty"`
+	Boundary    JSONB     `gorm:"type:jsonb;column:boundary" json:"boundary"` // [[lat, lng], ...] polygon
+	IsActive    bool      `gorm:"column:is_active;index" json:"isActive"` // No gorm default, which would turn a false on create into true
+	CreatedBy   string    `gorm:"column:created_by" json:"createdBy"`
+	CreatedAt   time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (Geofence) TableName() string {
+	return "geofences"
+}
+
+// GeofenceVehicle - A watchlisted vehicle linked to a geofence, with where it
+// was last detected relative to it so only crossings raise alerts
+type GeofenceVehicle struct {
+	GeofenceID   int64      `gorm:"primaryKey;column:geofence_id" json:"geofenceId"`
+	VehicleID    int64      `gorm:"primaryKey;column:vehicle_id;index" json:"vehicleId"`
+	Vehicle      *Vehicle   `gorm:"foreignKey:VehicleID" json:"vehicle,omitempty"`
+	Inside       bool       `gorm:"column:inside;default:false" json:"inside"`
+	LastDeviceID *string    `gorm:"column:last_device_id" json:"lastDeviceId,omitempty"`
+	LastSeenAt   *time.Time `gorm:"column:last_seen_at" json:"lastSeenAt,omitempty"` // nil = not detected since linking
+	LinkedBy     string     `gorm:"column:linked_by" json:"linkedBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (GeofenceVehicle) TableName() string {
+	return "geofence_vehicles"
+}
+
+// ViolationAutoApproveRule - Per-type rule for skipping manual review
+type ViolationAutoApproveRule struct {
+	ID            int64         `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ViolationType ViolationType `gorm:"column:violation_type;uniqueIndex" json:"violationType"`
+	Enabled       bool          `gorm:"column:enabled;default:false" json:"enabled"`
+
+	// Conditions - all must pass for a violation to be auto-approved
+	MinConfidence      float64 `gorm:"column:min_confidence" json:"minConfidence"`
+	RequireValidPlate  bool    `gorm:"column:require_valid_plate" json:"requireValidPlate"`
+	MinPlateConfidence float64 `gorm:"column:min_plate_confidence" json:"minPlateConfidence"`
+
+	Notes     *string   `gorm:"column:notes" json:"notes,omitempty"`
+	UpdatedBy string    `gorm:"column:updated_by" json:"updatedBy"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ViolationAutoApproveRule) TableName() string {
+	return "violation_auto_approve_rules"
+}
+
+// ViolationRuleAudit - Change history for auto-approve rules and the kill switch
+type ViolationRuleAudit struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ViolationType *string   `gorm:"column:violation_type;index" json:"violationType,omitempty"` // nil for kill switch changes
+	Action        string    `gorm:"column:action" json:"action"`                                // create, update, delete, kill_switch, threshold_update, threshold_delete
+	Before        JSONB     `gorm:"type:jsonb;column:before" json:"before,omitempty"`
+	After         JSONB     `gorm:"type:jsonb;column:after" json:"after,omitempty"`
+	ChangedBy     string    `gorm:"column:changed_by" json:"changedBy"`
+	CreatedAt     time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+func (ViolationRuleAudit) TableName() string {
+	return "violation_rule_audit"
+}
+
+// LegalHoldAudit - A legal hold placed on or released from a record, which
+// exempts it from retention purges while held
+type LegalHoldAudit struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	RecordType string    `gorm:"column:record_type;index:idx_legal_hold_record,priority:1" json:"recordType"` // events, detections, violations, crowd, alerts or offense_sessions
+	RecordID   int64     `gorm:"column:record_id;index:idx_legal_hold_record,priority:2" json:"recordId"`
+	Held       bool      `gorm:"column:held" json:"held"` // true = placed, false = released
+	Reason     string    `gorm:"column:reason" json:"reason"`
+	ChangedBy  string    `gorm:"column:changed_by" json:"changedBy"`
+	CreatedAt  time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+func (LegalHoldAudit) TableName() string {
+	return "legal_hold_audit"
+}
+
+// ViolationConfidenceThreshold - Per-type minimum confidence; violations below it are flagged low_confidence
+type ViolationConfidenceThreshold struct {
+	ViolationType  ViolationType `gorm:"primaryKey;column:violation_type" json:"violationType"`
+	MinConfidence  float64       `gorm:"column:min_confidence" json:"minConfidence"`
+	HideFromQueue  bool          `gorm:"column:hide_from_queue;default:false" json:"hideFromQueue"` // Exclude flagged violations from the default review queue
+	UpdatedBy      string        `gorm:"column:updated_by" json:"updatedBy"`
+	UpdatedAt      time.Time     `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ViolationConfidenceThreshold) TableName() string {
+	return "violation_confidence_thresholds"
+}
+
+// VehicleTypeMapping - Admin-defined make/model to vehicle type, used to fill
+// in the type of detections the edge couldn't classify. Make and model are
+// stored lowercased; an empty model applies to every model of the make.
+type VehicleTypeMapping struct {
+	Make        string      `gorm:"primaryKey;column:make" json:"make"`
+	Model       string      `gorm:"primaryKey;column:model" json:"model"`
+	VehicleType VehicleType `gorm:"column:vehicle_type" json:"vehicleType"` // UNKNOWN stops a built-in mapping from applying
+	UpdatedBy   string      `gorm:"column:updated_by" json:"updatedBy"`
+	UpdatedAt   time.Time   `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (VehicleTypeMapping) TableName() string {
+	return "vehicle_type_mappings"
+}
+
+// SystemSetting - Key/value store for runtime switches editable by admins
+type SystemSetting struct {
+	Key       string    `gorm:"primaryKey;column:key" json:"key"`
+	Value     string    `gorm:"column:value" json:"value"`
+	UpdatedBy string    `gorm:"column:updated_by" json:"updatedBy"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (SystemSetting) TableName() string {
+	return "system_settings"
+}
+
+// StoredImage - Evidence image written to disk, used for storage accounting and retention
+type StoredImage struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	DeviceID  string    `gorm:"column:device_id;index:idx_stored_image_device_time" json:"deviceId"`
+	WorkerID  string    `gorm:"column:worker_id;index" json:"workerId"`
+	EventType string    `gorm:"column:event_type" json:"eventType"`
+	Path      string    `gorm:"column:path" json:"path"`
+	URL       string    `gorm:"column:url" json:"url"`
+	Bytes     int64     `gorm:"column:bytes" json:"bytes"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index:idx_stored_image_device_time" json:"createdAt"`
+
+	// Set once the image is moved to cold storage; Path is then gone and URL
+	// points at the image's cold copy
+	ColdKey    *string    `gorm:"column:cold_key" json:"coldKey,omitempty"`
+	ArchivedAt *time.Time `gorm:"column:archived_at" json:"archivedAt,omitempty"`
+}
+
+func (StoredImage) TableName() string {
+	return "stored_images"
+}
+
+// PendingImage - Evidence image whose save failed at ingest, spooled for retry.
+// The event already references URL; the retry writes the bytes to Path.
+type PendingImage struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	EventID       string    `gorm:"column:event_id;index" json:"eventId"`
+	DeviceID      string    `gorm:"column:device_id;index" json:"deviceId"`
+	WorkerID      string    `gorm:"column:worker_id" json:"workerId"`
+	EventType     string    `gorm:"column:event_type" json:"eventType"`
+	Key           string    `gorm:"column:key" json:"key"` // Multipart field, e.g. frame.jpg
+	Path          string    `gorm:"column:path" json:"path"`
+	URL           string    `gorm:"column:url" json:"url"`
+	Data          []byte    `gorm:"column:data" json:"-"`
+	Bytes         int64     `gorm:"column:bytes" json:"bytes"`
+	Status        string    `gorm:"column:status;default:pending;index" json:"status"` // pending, failed
+	Attempts      int       `gorm:"column:attempts;default:0" json:"attempts"`
+	LastError     string    `gorm:"column:last_error" json:"lastError"`
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at;index" json:"nextAttemptAt"`
+	CreatedAt     time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (PendingImage) TableName() string {
+	return "pending_images"
+}
+
+// DeadLetterEvent - Ingested event whose processing failed, kept with its full
+// payload so it can be reprocessed instead of being lost
+type DeadLetterEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	EventID       string     `gorm:"column:event_id;index" json:"eventId"`
+	WorkerID      string     `gorm:"column:worker_id" json:"workerId"`
+	DeviceID      string     `gorm:"column:device_id;index" json:"deviceId"`
+	EventType     string     `gorm:"column:event_type;index" json:"eventType"`
+	Payload       JSONB      `gorm:"column:payload;type:jsonb" json:"payload"`       // The event as received
+	ImageURLs     JSONB      `gorm:"column:image_urls;type:jsonb" json:"imageUrls"` // Images already saved for it
+	ReceivedAt    time.Time  `gorm:"column:received_at" json:"receivedAt"`
+	Status        string     `gorm:"column:status;default:pending;index" json:"status"` // pending, failed, reprocessed, discarded
+	Attempts      int        `gorm:"column:attempts;default:0" json:"attempts"`         // Reprocessing attempts
+	LastError     string     `gorm:"column:last_error" json:"lastError"`
+	NextAttemptAt *time.Time `gorm:"column:next_attempt_at;index" json:"nextAttemptAt,omitempty"`
+	ReprocessedAt *time.Time `gorm:"column:reprocessed_at" json:"reprocessedAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+func (DeadLetterEvent) TableName() string {
+	return "dead_letter_events"
+}
+
+// DeviceStorageQuota - Per-device override of the default image storage quota
+type DeviceStorageQuota struct {
+	DeviceID   string    `gorm:"primaryKey;column:device_id" json:"deviceId"`
+	QuotaBytes int64     `gorm:"column:quota_bytes" json:"quotaBytes"` // 0 = unlimited
+	UpdatedBy  string    `gorm:"column:updated_by" json:"updatedBy"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (DeviceStorageQuota) TableName() string {
+	return "device_storage_quotas"
+}
+
+// PlateCorrection - Manual plate fix made by a reviewer, used to learn OCR confusions
+type PlateCorrection struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ViolationID *int64    `gorm:"column:violation_id;index" json:"violationId,omitempty"`
+	DeviceID    string    `gorm:"column:device_id;index" json:"deviceId"`
+	Original    string    `gorm:"column:original" json:"original"`
+	Corrected   string    `gorm:"column:corrected" json:"corrected"`
+	CorrectedBy string    `gorm:"column:corrected_by" json:"correctedBy"`
+	CreatedAt   time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP;index" json:"createdAt"`
+}
+
+func (PlateCorrection) TableName() string {
+	return "plate_corrections"
+}
+
+// PlateSubstitution - Character substitution learned from plate corrections
+type PlateSubstitution struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	DeviceID   string    `gorm:"column:device_id;uniqueIndex:idx_plate_substitution" json:"deviceId"` // empty = all devices
+	From       string    `gorm:"column:from_char;uniqueIndex:idx_plate_substitution" json:"from"`
+	To         string    `gorm:"column:to_char;uniqueIndex:idx_plate_substitution" json:"to"`
+	Count      int64     `gorm:"column:count" json:"count"`
+	Enabled    bool      `gorm:"column:enabled" json:"enabled"` // admins can disable bad substitutions
+	LastSeenAt time.Time `gorm:"column:last_seen_at" json:"lastSeenAt"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (PlateSubstitution) TableName() string {
+	return "plate_substitutions"
+}
+
+// ViewRotation - Ordered set of cameras shown one at a time on a live-view rotation
+type ViewRotation struct {
+	ID           string    `gorm:"primaryKey;column:id" json:"id"`
+	Name         string    `gorm:"column:name" json:"name"`
+	Cameras      JSONB     `gorm:"type:jsonb;column:cameras" json:"cameras"` // ["workerID.cameraID", ...] in display order
+	DwellSeconds int       `gorm:"column:dwell_seconds" json:"dwellSeconds"`
+	CreatedBy    string    `gorm:"column:created_by" json:"createdBy"`
+	CreatedAt    time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ViewRotation) TableName() string {
+	return "view_rotations"
+}
+
+// DefaultSiteID is the site that devices without an explicit site belong to
+const DefaultSiteID = "default"
+
+// Site - A physical location (stadium, junction) grouping zones and devices.
+// Zones belong to the site of their devices.
+type Site struct {
+	ID        string    `gorm:"primaryKey;column:id" json:"id"`
+	Name      string    `gorm:"column:name" json:"name"`
+	Boundary  JSONB     `gorm:"type:jsonb;column:boundary" json:"boundary,omitempty"` // [[lat, lng], ...] polygon
+	Timezone  string    `gorm:"column:timezone" json:"timezone"`                      // IANA name; empty = database timezone
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (Site) TableName() string {
+	return "sites"
+}
+
+// QuietHoursWindow - A daily window, in the site's timezone, during which
+// non-critical alerts are stored but not pushed to operators
+type QuietHoursWindow struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	SiteID    string    `gorm:"column:site_id;index" json:"siteId"`
+	Start     string    `gorm:"column:start_time" json:"start"`               // HH:MM
+	End       string    `gorm:"column:end_time" json:"end"`                   // HH:MM; before Start = spans midnight
+	Days      JSONB     `gorm:"type:jsonb;column:days" json:"days,omitempty"` // weekdays the window starts on (0 = Sunday); empty = every day
+	Enabled   bool      `gorm:"column:enabled" json:"enabled"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"createdAt"`
+}
+
+func (QuietHoursWindow) TableName() string {
+	return "quiet_hours_windows"
+}
+
+// ZoneEnforcement - Enforcement parameters of a zone (a road segment). Every
+// camera in the zone shares them, so one change covers the whole stretch.
+type ZoneEnforcement struct {
+	ZoneID            string    `gorm:"primaryKey;column:zone_id" json:"zoneId"`
+	SpeedLimit2W      *float64  `gorm:"column:speed_limit_2w" json:"speedLimit2W,omitempty"`           // km/h; two-wheelers
+	SpeedLimit4W      *float64  `gorm:"column:speed_limit_4w" json:"speedLimit4W,omitempty"`           // km/h; everything else
+	ExpectedDirection *string   `gorm:"column:expected_direction" json:"expectedDirection,omitempty"` // north, south, east or west
+	UpdatedAt         time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ZoneEnforcement) TableName() string {
+	return "zone_enforcement"
+}
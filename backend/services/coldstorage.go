@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrColdObjectNotFound is returned when a cold store has no object under a key
+var ErrColdObjectNotFound = errors.New("cold storage object not found")
+
+// ColdStore keeps images moved off local storage. Keys are slash-separated
+// paths.
+type ColdStore interface {
+	// Name identifies the store in logs and responses
+	Name() string
+	// Put stores an object under key, replacing any existing one
+	Put(key string, body io.Reader, size int64) error
+	// Get opens the object under key
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the object under key; a missing object isn't an error
+	Delete(key string) error
+}
+
+// cleanColdKey rejects keys that would escape the store's root
+func cleanColdKey(key string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if clean == "" || clean == "." {
+		return "", fmt.Errorf("invalid cold storage key %q", key)
+	}
+	return clean, nil
+}
+
+// DirColdStore keeps objects under a directory, typically a mount of cheaper
+// storage such as an archive volume or a bucket mounted with s3fs
+type DirColdStore struct {
+	root string
+}
+
+// NewDirColdStore creates a store rooted at dir
+func NewDirColdStore(dir string) *DirColdStore {
+	return &DirColdStore{root: dir}
+}
+
+func (s *DirColdStore) Name() string { return "dir:" + s.root }
+
+func (s *DirColdStore) objectPath(key string) (string, error) {
+	clean, err := cleanColdKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+// Put writes to a temporary file and renames it, so a reader never sees a
+// partial object
+func (s *DirColdStore) Put(key string, body io.Reader, size int64) error {
+	dest, err := s.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".cold-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func (s *DirColdStore) Get(key string) (io.ReadCloser, error) {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrColdObjectNotFound
+	}
+	return f, err
+}
+
+func (s *DirColdStore) Delete(key string) error {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// HTTPColdStore keeps objects behind a plain HTTP object API: PUT, GET and
+// DELETE on the base URL plus the key, with a bearer key. An S3 storage class
+// is passed in X-Amz-Storage-Class for S3-compatible gateways.
+type HTTPColdStore struct {
+	baseURL      string
+	apiKey       string
+	storageClass string
+	client       *http.Client
+}
+
+// NewHTTPColdStore creates a store client
+func NewHTTPColdStore(baseURL, apiKey, storageClass string) *HTTPColdStore {
+	return &HTTPColdStore{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		apiKey:       apiKey,
+		storageClass: storageClass,
+		client:       &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *HTTPColdStore) Name() string { return s.baseURL }
+
+func (s *HTTPColdStore) do(method, key string, body io.Reader, size int64) (*http.Response, error) {
+	clean, err := cleanColdKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, s.baseURL+"/"+clean, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	if method == http.MethodPut && s.storageClass != "" {
+		req.Header.Set("X-Amz-Storage-Class", s.storageClass)
+	}
+	return s.client.Do(req)
+}
+
+func (s *HTTPColdStore) Put(key string, body io.Reader, size int64) error {
+	resp, err := s.do(http.MethodPut, key, body, size)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("cold storage returned %s for PUT %s", resp.Status, key)
+	}
+	return nil
+}
+
+func (s *HTTPColdStore) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrColdObjectNotFound
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		resp.Body.Close()
+		return nil, fmt.Errorf("cold storage returned %s for GET %s", resp.Status, key)
+	}
+	return resp.Body, nil
+}
+
+func (s *HTTPColdStore) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		return fmt.Errorf("cold storage returned %s for DELETE %s", resp.Status, key)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Worker command actions that expect a reply from the MagicBox.
+// start_stream/stop_stream are sent fire-and-forget by the FeedHub.
+const (
+	CommandRestartCamera = "restart_camera" // cameraId required
+	CommandResyncConfig  = "resync_config"
+	CommandDiagnostics   = "run_diagnostics"
+	CommandRotateToken   = "rotate_token"  // worker fetches the new token over HTTP
+	CommandCaptureFrame  = "capture_frame" // cameraId required
+)
+
+// ErrWorkerUnreachable is returned when no MagicBox is listening on the worker's command subject
+var ErrWorkerUnreachable = errors.New("worker is not connected to central NATS")
+
+// ErrCommandTimeout is returned when the worker doesn't reply in time
+var ErrCommandTimeout = errors.New("timed out waiting for worker reply")
+
+// WorkerCommand is published to command.<workerID>
+type WorkerCommand struct {
+	ID       string                 `json:"id,omitempty"`
+	Action   string                 `json:"action"`
+	CameraID string                 `json:"cameraId,omitempty"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+}
+
+// WorkerCommandReply is the MagicBox's response to a command
+type WorkerCommandReply struct {
+	ID      string          `json:"id,omitempty"`
+	Action  string          `json:"action"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// CommandBus sends request/reply commands to MagicBox workers over central NATS
+type CommandBus struct {
+	natsConn *nats.Conn
+}
+
+// NewCommandBus creates a new command bus
+func NewCommandBus(nc *nats.Conn) *CommandBus {
+	return &CommandBus{natsConn: nc}
+}
+
+// Send publishes a command to a worker and waits for its reply
+func (b *CommandBus) Send(workerID string, cmd WorkerCommand, timeout time.Duration) (*WorkerCommandReply, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command: %w", err)
+	}
+
+	subject := fmt.Sprintf("command.%s", workerID)
+	log.Printf("📤 Sending %s command to %s (id: %s)", cmd.Action, workerID, cmd.ID)
+
+	msg, err := b.natsConn.Request(subject, data, timeout)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, ErrWorkerUnreachable
+		}
+		if errors.Is(err, nats.ErrTimeout) {
+			return nil, ErrCommandTimeout
+		}
+		return nil, err
+	}
+
+	var reply WorkerCommandReply
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		return nil, fmt.Errorf("invalid reply from worker: %w", err)
+	}
+	return &reply, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// WatchDevice makes the client receive the events of one device as they are
+// ingested, limited to types when any are given. view names what the client
+// may see of an event (see BroadcastDeviceEvent). Must be called before Register.
+func (c *FeedClient) WatchDevice(deviceID string, types []string, view string) {
+	c.device = deviceID
+	c.deviceView = view
+	c.deviceTypes = make(map[string]bool, len(types))
+	for _, t := range types {
+		c.deviceTypes[t] = true
+	}
+}
+
+// DeviceWatched reports whether any client watches a device's events
+func (h *FeedHub) DeviceWatched(deviceID string) bool {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	for client := range h.clients {
+		if client.device == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// BroadcastDeviceEvent pushes an ingested event to the clients watching its
+// device. render gives the event as seen with a client's view; it's called,
+// and the result encoded, once per view among the watching clients.
+func (h *FeedHub) BroadcastDeviceEvent(deviceID, eventType string, render func(view string) interface{}) {
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+
+	var watchers []*FeedClient
+	for client := range h.clients {
+		if client.device != deviceID {
+			continue
+		}
+		if len(client.deviceTypes) > 0 && !client.deviceTypes[eventType] {
+			continue
+		}
+		watchers = append(watchers, client)
+	}
+	if len(watchers) == 0 {
+		return
+	}
+
+	messages := make(map[string][]byte)
+	for _, client := range watchers {
+		msgBytes, ok := messages[client.deviceView]
+		if !ok {
+			eventBytes, err := json.Marshal(render(client.deviceView))
+			if err != nil {
+				log.Printf("⚠️ Failed to encode device event: %v", err)
+				return
+			}
+			msgBytes, _ = json.Marshal(FeedMessage{
+				Type:   "event",
+				Camera: deviceID,
+				Data:   eventBytes,
+			})
+			messages[client.deviceView] = msgBytes
+		}
+		select {
+		case client.send <- msgBytes:
+		default:
+			// Client buffer full, skip
+		}
+	}
+}
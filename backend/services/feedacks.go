@@ -0,0 +1,183 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// ackRingSize is how many recent frame queue times a client keeps to measure
+// ack latency; acks further behind than this only report lag in frames
+const ackRingSize = 1024
+
+// ackCheckPeriod is how often the hub looks for stalled clients
+const ackCheckPeriod = 5 * time.Second
+
+// FeedAckPolicy is how the hub treats clients that acknowledge frames.
+// Clients that never ack are only limited by their send buffer.
+type FeedAckPolicy struct {
+	MaxLag       time.Duration // Skip frames to a client whose oldest unacked frame is this old; 0 = never
+	StallTimeout time.Duration // Disconnect a client behind and silent this long; 0 = never
+}
+
+// feedAckState is a client's frame acknowledgment state. Frames are numbered
+// per client in the order they're queued to it, so a client acks with the
+// count of frames it has received.
+type feedAckState struct {
+	mu        sync.Mutex
+	acking    bool // the client has sent an ack
+	queued    uint64
+	queuedAt  [ackRingSize]time.Time
+	acked     uint64
+	lastAckAt time.Time
+	latency   time.Duration // queue-to-ack time of the last acked frame
+	throttled uint64        // frames skipped because the client lagged
+}
+
+// ClientFeedStats reports how far one client is behind
+type ClientFeedStats struct {
+	RemoteAddr      string     `json:"remoteAddr"`
+	UserID          string     `json:"userId"`
+	Cameras         int        `json:"cameras"`
+	Device          string     `json:"device,omitempty"` // Device whose events it watches
+	Buffered        int        `json:"buffered"`         // Messages waiting in the send buffer
+	Acking          bool       `json:"acking"`
+	FramesQueued    uint64     `json:"framesQueued"`
+	FramesAcked     uint64     `json:"framesAcked"`
+	LagFrames       uint64     `json:"lagFrames"`
+	LagMs           int64      `json:"lagMs"` // Queue-to-ack time of the last acked frame
+	LastAckAt       *time.Time `json:"lastAckAt,omitempty"`
+	ThrottledFrames uint64     `json:"throttledFrames"`
+}
+
+// SetAckPolicy sets how lagging acking clients are throttled and disconnected
+func (h *FeedHub) SetAckPolicy(policy FeedAckPolicy) {
+	h.ackPolicyMu.Lock()
+	h.ackPolicy = policy
+	h.ackPolicyMu.Unlock()
+}
+
+func (h *FeedHub) getAckPolicy() FeedAckPolicy {
+	h.ackPolicyMu.RLock()
+	defer h.ackPolicyMu.RUnlock()
+	return h.ackPolicy
+}
+
+// queueFrame queues a frame to a client unless its buffer is full or, for
+// acking clients, its oldest unacked frame was queued more than MaxLag ago.
+// Lag is measured in time rather than frames as clients ack periodically, so
+// a healthy one can have a second's worth of frames unacked at any moment.
+func (c *FeedClient) queueFrame(msg []byte, policy FeedAckPolicy) {
+	now := time.Now()
+	c.acks.mu.Lock()
+	defer c.acks.mu.Unlock()
+	if policy.MaxLag > 0 && c.acks.behind(policy.MaxLag, now) {
+		c.acks.throttled++
+		return
+	}
+	select {
+	case c.send <- msg:
+		c.acks.queued++
+		c.acks.queuedAt[c.acks.queued%ackRingSize] = now
+	default:
+		// Client buffer full, skip frame
+	}
+}
+
+// recordAck records a client's ack of the frames it has received
+func (c *FeedClient) recordAck(data json.RawMessage) {
+	var ack struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(data, &ack); err != nil {
+		c.sendError("ack requires data.seq")
+		return
+	}
+
+	now := time.Now()
+	c.acks.mu.Lock()
+	defer c.acks.mu.Unlock()
+	if ack.Seq > c.acks.queued {
+		ack.Seq = c.acks.queued
+	}
+	c.acks.acking = true
+	c.acks.lastAckAt = now
+	if ack.Seq < c.acks.acked {
+		return
+	}
+	c.acks.acked = ack.Seq
+	if ack.Seq > 0 && c.acks.queued-ack.Seq < ackRingSize {
+		c.acks.latency = now.Sub(c.acks.queuedAt[ack.Seq%ackRingSize])
+	}
+}
+
+// feedStats returns the client's lag
+func (c *FeedClient) feedStats() ClientFeedStats {
+	c.camerasMu.RLock()
+	cameras := len(c.cameras)
+	c.camerasMu.RUnlock()
+
+	c.acks.mu.Lock()
+	defer c.acks.mu.Unlock()
+	stats := ClientFeedStats{
+		RemoteAddr:      c.remoteAddr,
+		UserID:          c.userID,
+		Cameras:         cameras,
+		Device:          c.device,
+		Buffered:        len(c.send),
+		Acking:          c.acks.acking,
+		FramesQueued:    c.acks.queued,
+		FramesAcked:     c.acks.acked,
+		LagFrames:       c.acks.queued - c.acks.acked,
+		LagMs:           c.acks.latency.Milliseconds(),
+		ThrottledFrames: c.acks.throttled,
+	}
+	if c.acks.acking {
+		lastAckAt := c.acks.lastAckAt
+		stats.LastAckAt = &lastAckAt
+	}
+	return stats
+}
+
+// behind reports whether an acking client has left a frame unacked for longer
+// than age. It's timed from when the oldest unacked frame was queued, not from
+// the last ack, as a client only acks when frames arrive and may not have had
+// any for a while. Past the ring, the slot holds a later frame's time, which
+// only makes the check more lenient. Call with mu held.
+func (a *feedAckState) behind(age time.Duration, now time.Time) bool {
+	if !a.acking || a.queued <= a.acked {
+		return false
+	}
+	return now.Sub(a.queuedAt[(a.acked+1)%ackRingSize]) > age
+}
+
+// stalled reports whether an acking client has left a frame unacked for
+// longer than timeout
+func (c *FeedClient) stalled(timeout time.Duration, now time.Time) bool {
+	c.acks.mu.Lock()
+	defer c.acks.mu.Unlock()
+	return c.acks.behind(timeout, now)
+}
+
+// watchAcks disconnects acking clients that stopped acking while behind. The
+// read pump then unregisters them as for any other dropped connection.
+func (h *FeedHub) watchAcks() {
+	ticker := time.NewTicker(ackCheckPeriod)
+	defer ticker.Stop()
+	for range ticker.C {
+		policy := h.getAckPolicy()
+		if policy.StallTimeout == 0 {
+			continue
+		}
+		now := time.Now()
+		h.clientsMu.RLock()
+		for client := range h.clients {
+			if client.stalled(policy.StallTimeout, now) {
+				log.Printf("📺 Disconnecting stalled client %s (frame unacked for %s)", client.remoteAddr, policy.StallTimeout)
+				client.conn.Close()
+			}
+		}
+		h.clientsMu.RUnlock()
+	}
+}
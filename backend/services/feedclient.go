@@ -34,12 +34,18 @@ func NewFeedClient(hub *FeedHub, conn *websocket.Conn, userID, remoteAddr string
 		cameras:    make(map[string]bool),
 		userID:     userID,
 		remoteAddr: remoteAddr,
+		closed:     make(chan struct{}),
 	}
 }
 
 // ReadPump pumps messages from the WebSocket connection to the hub
 func (c *FeedClient) ReadPump() {
 	defer func() {
+		close(c.closed)
+		// Let a rotation release its camera before the send channel is closed
+		if c.rotationDone != nil {
+			<-c.rotationDone
+		}
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
@@ -70,21 +76,38 @@ func (c *FeedClient) ReadPump() {
 		// Handle message
 		switch msg.Type {
 		case "subscribe":
+			if c.rotationDone != nil {
+				c.sendError("feeds are managed by the view rotation")
+				continue
+			}
 			if msg.Camera != "" {
-				if err := c.hub.Subscribe(c, msg.Camera); err != nil {
+				if err := c.hub.Subscribe(c, msg.Camera, msg.Analytic); err != nil {
 					log.Printf("⚠️ Subscribe failed: %v", err)
 					c.sendError(err.Error())
 				}
 			}
 
 		case "unsubscribe":
+			if c.rotationDone != nil {
+				c.sendError("feeds are managed by the view rotation")
+				continue
+			}
 			if msg.Camera != "" {
 				c.hub.Unsubscribe(c, msg.Camera)
 			}
 
+		case "subscribe_overview":
+			c.hub.SubscribeOverview(c)
+
+		case "unsubscribe_overview":
+			c.hub.UnsubscribeOverview(c)
+
 		case "ping":
 			c.sendPong()
 
+		case "ack":
+			c.recordAck(msg.Data)
+
 		default:
 			log.Printf("⚠️ Unknown message type: %s", msg.Type)
 		}
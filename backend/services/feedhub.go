@@ -43,17 +43,42 @@ type FeedHub struct {
 	fpsCount map[string]int
 	fpsMu    sync.Mutex
 	stopFPS  chan struct{}
+
+	// Throttling and disconnecting of clients that ack frames
+	ackPolicy   FeedAckPolicy
+	ackPolicyMu sync.RWMutex
+
+	// Overview frames (see StartOverview): latest message per camera key
+	overviewSub *nats.Subscription
+	overviews   map[string][]byte
+	overviewMu  sync.Mutex
 }
 
 // cameraSubscription tracks a camera feed subscription
 type cameraSubscription struct {
 	cameraKey   string // format: workerID.cameraID
+	analytic    string // Analytic the camera is streamed for ("" = all frames)
 	natsSub     *nats.Subscription
 	detectSub   *nats.Subscription
 	viewers     map[*FeedClient]bool
 	viewersMu   sync.RWMutex
+	frameMu     sync.RWMutex
 	lastFrame   []byte
 	lastFrameAt time.Time
+
+	// Sequence tracking for gap detection
+	seqMu      sync.Mutex
+	lastSeq    uint64
+	gaps       int64
+	framesLost uint64
+	lastGapAt  time.Time
+}
+
+// FrameGap describes a run of frames that never reached the hub
+type FrameGap struct {
+	From   uint64 `json:"from"`   // First missing sequence number
+	To     uint64 `json:"to"`     // Last missing sequence number
+	Missed uint64 `json:"missed"` // Number of frames lost
 }
 
 // FeedClient represents a WebSocket client viewing feeds
@@ -65,12 +90,27 @@ type FeedClient struct {
 	camerasMu  sync.RWMutex
 	userID     string
 	remoteAddr string
+
+	// closed is closed when the connection's read loop exits
+	closed chan struct{}
+	// rotationDone is set for clients whose feeds are driven by StartRotation
+	rotationDone chan struct{}
+	// acks tracks the frames the client acknowledged, for clients that do
+	acks feedAckState
+	// device is set for clients watching one device's ingested events
+	device      string
+	deviceTypes map[string]bool // event types pushed; empty = all
+	deviceView  string          // what the client may see of an event, as the caller defines it
+	// overview is set for clients receiving every camera's overview frames;
+	// guarded by the hub's overviewMu
+	overview bool
 }
 
 // FeedMessage is a message sent to/from clients
 type FeedMessage struct {
-	Type     string          `json:"type"`     // subscribe, unsubscribe, frame, detection
-	Camera   string          `json:"camera"`   // workerID.cameraID
+	Type     string          `json:"type"`               // subscribe, unsubscribe, subscribe_overview, unsubscribe_overview, ack, frame, detection, overview, gap, alert, rotation, event
+	Camera   string          `json:"camera"`             // workerID.cameraID
+	Analytic string          `json:"analytic,omitempty"` // subscribe: only frames the analytic is active on
 	Data     json.RawMessage `json:"data,omitempty"`
 	Binary   bool            `json:"-"` // True if this is binary frame data
 	RawBytes []byte          `json:"-"` // Raw binary data
@@ -86,9 +126,11 @@ func NewFeedHub(natsConn *nats.Conn) *FeedHub {
 		unregister:    make(chan *FeedClient),
 		fpsCount:      make(map[string]int),
 		stopFPS:       make(chan struct{}),
+		overviews:     make(map[string][]byte),
 	}
 	// Start FPS logging goroutine
 	go h.logFPS()
+	go h.watchAcks()
 	return h
 }
 
@@ -138,20 +180,26 @@ func (h *FeedHub) Run() {
 			}
 			h.clientsMu.Unlock()
 
-			// Unsubscribe from all cameras
-			client.camerasMu.Lock()
+			// Unsubscribe from all cameras (unsubscribeClient takes camerasMu itself)
+			client.camerasMu.RLock()
+			cameraKeys := make([]string, 0, len(client.cameras))
 			for cameraKey := range client.cameras {
+				cameraKeys = append(cameraKeys, cameraKey)
+			}
+			client.camerasMu.RUnlock()
+			for _, cameraKey := range cameraKeys {
 				h.unsubscribeClient(client, cameraKey)
 			}
-			client.camerasMu.Unlock()
 
 			log.Printf("📺 Client disconnected: %s", client.remoteAddr)
 		}
 	}
 }
 
-// Subscribe subscribes a client to a camera feed
-func (h *FeedHub) Subscribe(client *FeedClient, cameraKey string) error {
+// Subscribe subscribes a client to a camera feed. With an analytic, the
+// camera only streams frames while that analytic is active on it; viewers
+// asking for different analytics of one camera share its full feed.
+func (h *FeedHub) Subscribe(client *FeedClient, cameraKey, analytic string) error {
 	// Parse workerID and cameraID
 	workerID, cameraID, err := parseCameraKey(cameraKey)
 	if err != nil {
@@ -167,6 +215,7 @@ func (h *FeedHub) Subscribe(client *FeedClient, cameraKey string) error {
 		// Create new subscription
 		sub = &cameraSubscription{
 			cameraKey: cameraKey,
+			analytic:  analytic,
 			viewers:   make(map[*FeedClient]bool),
 		}
 
@@ -192,9 +241,14 @@ func (h *FeedHub) Subscribe(client *FeedClient, cameraKey string) error {
 		h.subscriptions[cameraKey] = sub
 
 		// Send command to MagicBox to start streaming
-		h.sendStartStreamCommand(workerID, cameraID)
+		h.sendStartStreamCommand(workerID, cameraID, analytic)
 
 		log.Printf("📺 Created subscription for camera %s", cameraKey)
+	} else if sub.analytic != "" && sub.analytic != analytic {
+		// Another viewer wants other frames - widen the stream to all of them
+		sub.analytic = ""
+		h.sendStopStreamCommand(workerID, cameraID)
+		h.sendStartStreamCommand(workerID, cameraID, "")
 	}
 
 	// Add client to viewers
@@ -280,9 +334,16 @@ func (h *FeedHub) broadcastFrame(cameraKey string, frameData []byte) {
 		return
 	}
 
+	// Tell viewers the feed is degraded before sending the frame after a gap
+	if gap := sub.trackSeq(frameMsg.Seq); gap != nil {
+		h.broadcastGap(sub, cameraKey, gap)
+	}
+
 	// Update last frame
+	sub.frameMu.Lock()
 	sub.lastFrame = jpegData
 	sub.lastFrameAt = time.Now()
+	sub.frameMu.Unlock()
 
 	// Create message with camera prefix
 	// Format: [1 byte type][camera key length][camera key][raw JPEG data]
@@ -293,14 +354,11 @@ func (h *FeedHub) broadcastFrame(cameraKey string, frameData []byte) {
 	copy(msg[2+len(cameraKey):], jpegData)
 
 	// Send to all viewers
+	policy := h.getAckPolicy()
 	sub.viewersMu.RLock()
 	viewerCount := len(sub.viewers)
 	for client := range sub.viewers {
-		select {
-		case client.send <- msg:
-		default:
-			// Client buffer full, skip frame
-		}
+		client.queueFrame(msg, policy)
 	}
 	sub.viewersMu.RUnlock()
 
@@ -312,6 +370,90 @@ func (h *FeedHub) broadcastFrame(cameraKey string, frameData []byte) {
 	}
 }
 
+// LatestFrame returns the most recent JPEG for a camera that is currently being
+// viewed, if it was received within maxAge
+func (h *FeedHub) LatestFrame(workerID, cameraID string, maxAge time.Duration) ([]byte, bool) {
+	h.subscriptionsMu.RLock()
+	sub, exists := h.subscriptions[workerID+"."+cameraID]
+	h.subscriptionsMu.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	sub.frameMu.RLock()
+	defer sub.frameMu.RUnlock()
+	if sub.lastFrame == nil || time.Since(sub.lastFrameAt) > maxAge {
+		return nil, false
+	}
+	return sub.lastFrame, true
+}
+
+// trackSeq records a frame sequence number and returns the gap if frames were skipped.
+// A sequence lower than the last one means the MagicBox publisher restarted, so tracking resets.
+func (s *cameraSubscription) trackSeq(seq uint64) *FrameGap {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	last := s.lastSeq
+	s.lastSeq = seq
+	if last == 0 || seq <= last+1 {
+		return nil
+	}
+
+	gap := &FrameGap{From: last + 1, To: seq - 1, Missed: seq - last - 1}
+	s.gaps++
+	s.framesLost += gap.Missed
+	s.lastGapAt = time.Now()
+	return gap
+}
+
+// broadcastGap sends a gap marker to all viewers of a camera
+func (h *FeedHub) broadcastGap(sub *cameraSubscription, cameraKey string, gap *FrameGap) {
+	gapBytes, _ := json.Marshal(gap)
+	msg := FeedMessage{
+		Type:   "gap",
+		Camera: cameraKey,
+		Data:   gapBytes,
+	}
+	msgBytes, _ := json.Marshal(msg)
+
+	sub.viewersMu.RLock()
+	for client := range sub.viewers {
+		select {
+		case client.send <- msgBytes:
+		default:
+			// Client buffer full, skip
+		}
+	}
+	sub.viewersMu.RUnlock()
+}
+
+// BroadcastAlert pushes an alert to every connected client, whatever feeds it
+// is viewing. camera is the device the alert was raised for.
+func (h *FeedHub) BroadcastAlert(camera string, alert interface{}) {
+	alertBytes, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode alert: %v", err)
+		return
+	}
+	msg := FeedMessage{
+		Type:   "alert",
+		Camera: camera,
+		Data:   alertBytes,
+	}
+	msgBytes, _ := json.Marshal(msg)
+
+	h.clientsMu.RLock()
+	for client := range h.clients {
+		select {
+		case client.send <- msgBytes:
+		default:
+			// Client buffer full, skip
+		}
+	}
+	h.clientsMu.RUnlock()
+}
+
 // broadcastDetection sends detection data to all viewers of a camera
 func (h *FeedHub) broadcastDetection(cameraKey string, detectData []byte) {
 	h.subscriptionsMu.RLock()
@@ -343,11 +485,14 @@ func (h *FeedHub) broadcastDetection(cameraKey string, detectData []byte) {
 }
 
 // sendStartStreamCommand tells MagicBox to start streaming a camera
-func (h *FeedHub) sendStartStreamCommand(workerID, cameraID string) {
-	cmd := map[string]string{
+func (h *FeedHub) sendStartStreamCommand(workerID, cameraID, analytic string) {
+	cmd := map[string]interface{}{
 		"action":   "start_stream",
 		"cameraId": cameraID,
 	}
+	if analytic != "" {
+		cmd["params"] = map[string]string{"analytic": analytic}
+	}
 	cmdBytes, _ := json.Marshal(cmd)
 
 	subject := fmt.Sprintf("command.%s", workerID)
@@ -386,20 +531,57 @@ func parseCameraKey(key string) (workerID, cameraID string, err error) {
 
 // Stats returns hub statistics
 type HubStats struct {
-	Clients       int      `json:"clients"`
-	Subscriptions int      `json:"subscriptions"`
-	ActiveCameras []string `json:"activeCameras"`
+	Clients       int               `json:"clients"`
+	Subscriptions int               `json:"subscriptions"`
+	ActiveCameras []string          `json:"activeCameras"`
+	Cameras       []CameraFeedStats `json:"cameras"`
+	ClientLag     []ClientFeedStats `json:"clientLag"`
+}
+
+// CameraFeedStats reports frame loss for one subscribed camera
+type CameraFeedStats struct {
+	Camera     string     `json:"camera"`
+	Viewers    int        `json:"viewers"`
+	LastSeq    uint64     `json:"lastSeq"`
+	Gaps       int64      `json:"gaps"`
+	FramesLost uint64     `json:"framesLost"`
+	LastGapAt  *time.Time `json:"lastGapAt,omitempty"`
 }
 
 func (h *FeedHub) Stats() HubStats {
 	h.clientsMu.RLock()
 	clientCount := len(h.clients)
+	clientLag := make([]ClientFeedStats, 0, clientCount)
+	for client := range h.clients {
+		clientLag = append(clientLag, client.feedStats())
+	}
 	h.clientsMu.RUnlock()
 
 	h.subscriptionsMu.RLock()
 	cameras := make([]string, 0, len(h.subscriptions))
-	for key := range h.subscriptions {
+	cameraStats := make([]CameraFeedStats, 0, len(h.subscriptions))
+	for key, sub := range h.subscriptions {
 		cameras = append(cameras, key)
+
+		sub.viewersMu.RLock()
+		viewers := len(sub.viewers)
+		sub.viewersMu.RUnlock()
+
+		sub.seqMu.Lock()
+		stat := CameraFeedStats{
+			Camera:     key,
+			Viewers:    viewers,
+			LastSeq:    sub.lastSeq,
+			Gaps:       sub.gaps,
+			FramesLost: sub.framesLost,
+		}
+		if !sub.lastGapAt.IsZero() {
+			lastGapAt := sub.lastGapAt
+			stat.LastGapAt = &lastGapAt
+		}
+		sub.seqMu.Unlock()
+
+		cameraStats = append(cameraStats, stat)
 	}
 	h.subscriptionsMu.RUnlock()
 
@@ -407,6 +589,8 @@ func (h *FeedHub) Stats() HubStats {
 		Clients:       clientCount,
 		Subscriptions: len(cameras),
 		ActiveCameras: cameras,
+		Cameras:       cameraStats,
+		ClientLag:     clientLag,
 	}
 }
 
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// overviewSubject is where MagicBox forwards every camera's downscaled
+// overview frames: overview.<workerID>.<cameraID>
+const overviewSubject = "overview.*.*"
+
+// StartOverview subscribes to the overview frames workers forward, for the
+// clients that asked for them (see SubscribeOverview). Unlike full frames they
+// flow without a viewer, so the latest one per camera is kept for clients
+// that subscribe later.
+func (h *FeedHub) StartOverview() error {
+	sub, err := h.natsConn.Subscribe(overviewSubject, func(msg *nats.Msg) {
+		h.broadcastOverview(strings.TrimPrefix(msg.Subject, "overview."), msg.Data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to overview frames: %w", err)
+	}
+	h.overviewMu.Lock()
+	h.overviewSub = sub
+	h.overviewMu.Unlock()
+	return nil
+}
+
+// SubscribeOverview makes the client receive the overview frames of every
+// camera, starting with the latest one of each
+func (h *FeedHub) SubscribeOverview(client *FeedClient) {
+	h.overviewMu.Lock()
+	defer h.overviewMu.Unlock()
+
+	if client.overview {
+		return
+	}
+	client.overview = true
+	for _, msgBytes := range h.overviews {
+		select {
+		case client.send <- msgBytes:
+		default:
+			// Client buffer full, skip
+		}
+	}
+}
+
+// UnsubscribeOverview stops the client's overview frames
+func (h *FeedHub) UnsubscribeOverview(client *FeedClient) {
+	h.overviewMu.Lock()
+	client.overview = false
+	h.overviewMu.Unlock()
+}
+
+// broadcastOverview sends a camera's overview frame to the clients that
+// subscribed to overview frames
+func (h *FeedHub) broadcastOverview(cameraKey string, frameData []byte) {
+	msgBytes, err := json.Marshal(FeedMessage{
+		Type:   "overview",
+		Camera: cameraKey,
+		Data:   frameData,
+	})
+	if err != nil {
+		log.Printf("⚠️ Invalid overview frame for %s: %v", cameraKey, err)
+		return
+	}
+
+	h.clientsMu.RLock()
+	defer h.clientsMu.RUnlock()
+	h.overviewMu.Lock()
+	defer h.overviewMu.Unlock()
+
+	h.overviews[cameraKey] = msgBytes
+	for client := range h.clients {
+		if !client.overview {
+			continue
+		}
+		select {
+		case client.send <- msgBytes:
+		default:
+			// Client buffer full, skip
+		}
+	}
+}
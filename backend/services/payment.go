@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPaymentSignature is returned when a payment webhook isn't signed
+// by the gateway
+var ErrInvalidPaymentSignature = errors.New("invalid payment webhook signature")
+
+// Payment statuses reported by a gateway
+const (
+	PaymentStatusPaid   = "paid"
+	PaymentStatusFailed = "failed"
+)
+
+// FinePayment describes a fine to collect
+type FinePayment struct {
+	Reference   string  `json:"reference"` // Violation reference shown to the payer
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Description string  `json:"description"`
+	PlateNumber string  `json:"plateNumber,omitempty"`
+	CallbackURL string  `json:"callbackUrl,omitempty"`
+}
+
+// PaymentRequest is a payment the gateway created for a fine
+type PaymentRequest struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url"` // Where the offender pays
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// PaymentConfirmation is a gateway's verified report on a payment
+type PaymentConfirmation struct {
+	PaymentID string     `json:"paymentId"`
+	Reference string     `json:"reference"`
+	Status    string     `json:"status"` // paid or failed
+	Amount    float64    `json:"amount"`
+	PaidAt    *time.Time `json:"paidAt,omitempty"`
+}
+
+// PaymentGateway collects fines through an external payment provider
+type PaymentGateway interface {
+	// Name identifies the gateway in logs and responses
+	Name() string
+	// CreatePaymentRequest asks the gateway for a payment link for a fine
+	CreatePaymentRequest(fine FinePayment) (*PaymentRequest, error)
+	// ParseWebhook verifies a webhook's signature and decodes its confirmation
+	ParseWebhook(body []byte, header http.Header) (*PaymentConfirmation, error)
+}
+
+// HTTPPaymentGateway talks to a gateway with a plain JSON API: payment
+// requests are POSTed to its URL with a bearer key, and webhooks carry an
+// HMAC-SHA256 of the body, hex encoded, in X-Payment-Signature
+type HTTPPaymentGateway struct {
+	url           string
+	apiKey        string
+	webhookSecret string
+	client        *http.Client
+}
+
+// NewHTTPPaymentGateway creates a gateway client
+func NewHTTPPaymentGateway(url, apiKey, webhookSecret string) *HTTPPaymentGateway {
+	return &HTTPPaymentGateway{
+		url:           url,
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the gateway name
+func (g *HTTPPaymentGateway) Name() string {
+	return "http"
+}
+
+// CreatePaymentRequest posts the fine to the gateway and returns its payment
+func (g *HTTPPaymentGateway) CreatePaymentRequest(fine FinePayment) (*PaymentRequest, error) {
+	body, err := json.Marshal(fine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payment request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("payment gateway unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("payment gateway returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var payment PaymentRequest
+	if err := json.Unmarshal(respBody, &payment); err != nil {
+		return nil, fmt.Errorf("invalid payment gateway response: %w", err)
+	}
+	if payment.ID == "" {
+		return nil, fmt.Errorf("payment gateway response has no payment id")
+	}
+	return &payment, nil
+}
+
+// ParseWebhook checks X-Payment-Signature and decodes the confirmation
+func (g *HTTPPaymentGateway) ParseWebhook(body []byte, header http.Header) (*PaymentConfirmation, error) {
+	if g.webhookSecret == "" {
+		return nil, ErrInvalidPaymentSignature
+	}
+	signature := strings.TrimPrefix(header.Get("X-Payment-Signature"), "sha256=")
+	mac := hmac.New(sha256.New, []byte(g.webhookSecret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidPaymentSignature
+	}
+
+	var confirmation PaymentConfirmation
+	if err := json.Unmarshal(body, &confirmation); err != nil {
+		return nil, fmt.Errorf("invalid payment webhook body: %w", err)
+	}
+	if confirmation.PaymentID == "" {
+		return nil, fmt.Errorf("payment webhook has no paymentId")
+	}
+	return &confirmation, nil
+}
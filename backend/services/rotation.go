@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// RotationState is pushed to a rotation client each time the active camera changes
+type RotationState struct {
+	Index   int    `json:"index"`   // Position of the active camera in the rotation
+	Total   int    `json:"total"`   // Number of cameras in the rotation
+	Dwell   int    `json:"dwell"`   // Seconds the camera stays active
+	Next    string `json:"next"`    // Camera that follows
+	Started int64  `json:"started"` // Unix ms the camera became active
+}
+
+// StartRotation makes the hub drive this client's subscriptions: each camera
+// (workerID.cameraID) is viewed for dwell before moving on to the next, so only
+// one camera streams to the client at a time. The client can't subscribe to
+// other cameras while rotating. Must be called before ReadPump.
+func (c *FeedClient) StartRotation(cameras []string, dwell time.Duration) {
+	if len(cameras) == 0 {
+		return
+	}
+	c.rotationDone = make(chan struct{})
+	go c.rotate(cameras, dwell)
+}
+
+func (c *FeedClient) rotate(cameras []string, dwell time.Duration) {
+	defer close(c.rotationDone)
+
+	var current string
+	defer func() {
+		if current != "" {
+			c.hub.Unsubscribe(c, current)
+		}
+	}()
+
+	for i := 0; ; i = (i + 1) % len(cameras) {
+		next := cameras[i]
+		if next != current {
+			if current != "" {
+				c.hub.Unsubscribe(c, current)
+				current = ""
+			}
+			if err := c.hub.Subscribe(c, next, ""); err != nil {
+				log.Printf("⚠️ Rotation subscribe failed for %s: %v", next, err)
+				c.sendError(err.Error())
+			} else {
+				current = next
+			}
+		}
+
+		state := RotationState{
+			Index:   i,
+			Total:   len(cameras),
+			Dwell:   int(dwell.Seconds()),
+			Next:    cameras[(i+1)%len(cameras)],
+			Started: time.Now().UnixMilli(),
+		}
+		stateBytes, _ := json.Marshal(state)
+		msgBytes, _ := json.Marshal(FeedMessage{Type: "rotation", Camera: next, Data: stateBytes})
+		select {
+		case c.send <- msgBytes:
+		default:
+		}
+
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(dwell):
+		}
+	}
+}
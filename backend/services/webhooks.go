@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader carries the signature of every outbound webhook:
+//
+//	X-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+//
+// The timestamp is signed with the body, so a captured delivery can't be
+// replayed later with a fresh timestamp.
+const WebhookSignatureHeader = "X-Signature"
+
+// LegacyWebhookSignatureHeader is the worker webhook's original signature,
+// an untimestamped HMAC of the body:
+//
+//	X-Iris-Signature: sha256=<hex HMAC-SHA256 of body>
+//
+// Deprecated: it's still sent alongside X-Signature to targets that ask for
+// it, until their receivers have moved to X-Signature.
+const LegacyWebhookSignatureHeader = "X-Iris-Signature"
+
+// DefaultWebhookTolerance is how old a delivery receivers should accept
+const DefaultWebhookTolerance = 5 * time.Minute
+
+var (
+	// ErrWebhookSignatureInvalid is returned for a missing, malformed or
+	// wrong webhook signature
+	ErrWebhookSignatureInvalid = errors.New("invalid webhook signature")
+	// ErrWebhookStale is returned for a correctly signed delivery outside the
+	// tolerance, most likely a replay
+	ErrWebhookStale = errors.New("stale webhook delivery")
+)
+
+// webhookMAC is the hex HMAC-SHA256 of a timestamp and body
+func webhookMAC(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignWebhook returns the X-Signature value for a body sent at a time
+func SignWebhook(secret, body []byte, at time.Time) string {
+	t := at.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", t, webhookMAC(secret, t, body))
+}
+
+// VerifyWebhook checks a delivery's X-Signature against the raw body, as a
+// receiver should before trusting it: the HMAC must match and the signed
+// timestamp must be within tolerance of now. Receivers in Go can call it
+// directly; elsewhere, split the header on "," and "=", recompute the HMAC of
+// "<t>.<body>" with the shared secret, compare in constant time and reject
+// deliveries whose t is too far from the current time.
+func VerifyWebhook(secret []byte, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrWebhookSignatureInvalid
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrWebhookSignatureInvalid
+	}
+
+	expected := []byte(webhookMAC(secret, timestamp, body))
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrWebhookSignatureInvalid
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age < 0 {
+		age = -age
+	}
+	if tolerance > 0 && age > tolerance {
+		return ErrWebhookStale
+	}
+	return nil
+}
+
+// WebhookTarget is an endpoint webhooks are posted to, with the secret its
+// deliveries are signed with. An empty secret sends them unsigned.
+type WebhookTarget struct {
+	URL    string
+	Secret string
+
+	// LegacySignature also signs deliveries with X-Iris-Signature
+	LegacySignature bool
+}
+
+// legacyWebhookSignature returns the X-Iris-Signature value for a body
+func legacyWebhookSignature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookSender posts signed webhooks, retrying transient failures
+type WebhookSender struct {
+	retries int
+	client  *http.Client
+}
+
+// NewWebhookSender creates a sender that retries each delivery up to retries
+// times with exponential backoff
+func NewWebhookSender(retries int) *WebhookSender {
+	return &WebhookSender{
+		retries: retries,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Deliver posts a JSON body to a target, retrying on network errors and
+// 5xx/429 responses. It blocks until delivered or out of retries.
+func (s *WebhookSender) Deliver(target WebhookTarget, body []byte) error {
+	var lastErr error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+		}
+		retryable, err := s.post(target, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post makes one delivery attempt, signed at the time it's sent. retryable
+// reports whether the failure is transient.
+func (s *WebhookSender) post(target WebhookTarget, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if target.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook([]byte(target.Secret), body, time.Now()))
+		if target.LegacySignature {
+			req.Header.Set(LegacyWebhookSignatureHeader, legacyWebhookSignature([]byte(target.Secret), body))
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return false, nil
+}
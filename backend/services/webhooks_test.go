@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookAcceptsSignedDelivery(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"event":"worker.registered"}`)
+	at := time.Unix(1700000000, 0)
+
+	header := SignWebhook(secret, body, at)
+	if err := VerifyWebhook(secret, header, body, DefaultWebhookTolerance, at.Add(time.Minute)); err != nil {
+		t.Fatalf("VerifyWebhook(%q) = %v, want nil", header, err)
+	}
+}
+
+func TestVerifyWebhookRejectsBadDeliveries(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"event":"worker.registered"}`)
+	at := time.Unix(1700000000, 0)
+	header := SignWebhook(secret, body, at)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"tampered body", secret, header, []byte(`{"event":"worker.deleted"}`), at, ErrWebhookSignatureInvalid},
+		{"wrong secret", []byte("other"), header, body, at, ErrWebhookSignatureInvalid},
+		{"missing header", secret, "", body, at, ErrWebhookSignatureInvalid},
+		{"malformed timestamp", secret, "t=soon,v1=abc", body, at, ErrWebhookSignatureInvalid},
+		{"re-timestamped", secret, "t=1700000100" + header[len("t=1700000000"):], body, at, ErrWebhookSignatureInvalid},
+		{"replayed later", secret, header, body, at.Add(DefaultWebhookTolerance + time.Second), ErrWebhookStale},
+		{"from the future", secret, header, body, at.Add(-DefaultWebhookTolerance - time.Second), ErrWebhookStale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.secret, tt.header, tt.body, DefaultWebhookTolerance, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyWebhook = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverSignsWithBothHeaders(t *testing.T) {
+	var got http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"event":"worker.revoked"}`)
+	target := WebhookTarget{URL: srv.URL, Secret: "secret", LegacySignature: true}
+	if err := NewWebhookSender(0).Deliver(target, body); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyWebhook([]byte("secret"), got.Get(WebhookSignatureHeader), gotBody, DefaultWebhookTolerance, time.Now()); err != nil {
+		t.Errorf("%s doesn't verify: %v", WebhookSignatureHeader, err)
+	}
+	if want := legacyWebhookSignature([]byte("secret"), body); got.Get(LegacyWebhookSignatureHeader) != want {
+		t.Errorf("%s = %q, want %q", LegacyWebhookSignatureHeader, got.Get(LegacyWebhookSignatureHeader), want)
+	}
+}
+
+func TestDeliverUnsignedWithoutSecret(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookSender(0).Deliver(WebhookTarget{URL: srv.URL, LegacySignature: true}, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(WebhookSignatureHeader) != "" || got.Get(LegacyWebhookSignatureHeader) != "" {
+		t.Errorf("unsigned delivery carried signatures: %v", got)
+	}
+}
@@ -33,6 +33,8 @@ type WireGuardService struct {
 	mu              sync.Mutex
 	serverPublicKey string
 	serverEndpoint  string // e.g., "platform.example.com:51820"
+	dns             []string
+	searchDomains   []string
 }
 
 // NewWireGuardService creates a new WireGuard service
@@ -54,6 +56,55 @@ func NewWireGuardService(endpoint string) *WireGuardService {
 	return svc
 }
 
+// SetDNS sets the DNS servers and search domains pushed to workers in the
+// setup response. Invalid entries are dropped with a warning; search domains
+// are only pushed along with a DNS server. Returns what was applied.
+func (s *WireGuardService) SetDNS(servers, searchDomains []string) ([]string, []string) {
+	var dns, domains []string
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			log.Printf("⚠️ Ignoring invalid WireGuard DNS server %q", server)
+			continue
+		}
+		dns = append(dns, server)
+	}
+	for _, domain := range searchDomains {
+		if !validSearchDomain(domain) {
+			log.Printf("⚠️ Ignoring invalid WireGuard search domain %q", domain)
+			continue
+		}
+		domains = append(domains, domain)
+	}
+	if len(dns) == 0 && len(domains) > 0 {
+		log.Printf("⚠️ Ignoring WireGuard search domains without a DNS server")
+		domains = nil
+	}
+
+	s.mu.Lock()
+	s.dns, s.searchDomains = dns, domains
+	s.mu.Unlock()
+	return dns, domains
+}
+
+// validSearchDomain reports whether domain is a plausible DNS name
+func validSearchDomain(domain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || len(domain) > 253 || net.ParseIP(domain) != nil {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // getServerPublicKey retrieves the server's public key
 func (s *WireGuardService) getServerPublicKey() (string, error) {
 	// Try to read from wg show first
@@ -287,10 +338,12 @@ type WireGuardSetupRequest struct {
 
 // RegisterWorkerResponse for WireGuard setup
 type WireGuardSetupResponse struct {
-	AssignedIP     string `json:"assigned_ip"`
-	ServerPubKey   string `json:"server_pubkey"`
-	ServerEndpoint string `json:"server_endpoint"`
-	ServerIP       string `json:"server_ip"`
+	AssignedIP     string   `json:"assigned_ip"`
+	ServerPubKey   string   `json:"server_pubkey"`
+	ServerEndpoint string   `json:"server_endpoint"`
+	ServerIP       string   `json:"server_ip"`
+	DNS            []string `json:"dns,omitempty"`            // Resolvers reachable over the tunnel
+	SearchDomains  []string `json:"search_domains,omitempty"` // So the backend resolves by hostname
 }
 
 // SetupWorkerWireGuard handles WireGuard setup for a worker
@@ -319,6 +372,8 @@ func (s *WireGuardService) SetupWorkerWireGuard(workerID, publicKey string) (*Wi
 		ServerPubKey:   s.serverPublicKey,
 		ServerEndpoint: s.serverEndpoint,
 		ServerIP:       WGServerIP,
+		DNS:            s.dns,
+		SearchDomains:  s.searchDomains,
 	}, nil
 }
 
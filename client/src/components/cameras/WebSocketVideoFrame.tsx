@@ -1,5 +1,5 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
-import { Camera, Wifi, WifiOff, AlertCircle } from 'lucide-react';
+import { Camera, Wifi, WifiOff, AlertCircle, AlertTriangle } from 'lucide-react';
 
 interface Detection {
   type: string;
@@ -9,6 +9,18 @@ interface Detection {
   color?: string;
 }
 
+// Gap marker sent by the feed hub when frames were lost upstream
+interface FrameGap {
+  from: number;
+  to: number;
+  missed: number;
+}
+
+type FeedData = ArrayBuffer | Detection[] | { gap: FrameGap };
+
+// How long to show the degraded badge after the last gap
+const DEGRADED_DISPLAY_MS = 5000;
+
 interface WebSocketVideoFrameProps {
   workerId: string;
   cameraId: string;
@@ -20,9 +32,16 @@ interface WebSocketVideoFrameProps {
 // Global WebSocket connection (shared across all video frames)
 let globalWs: WebSocket | null = null;
 let wsConnecting = false;
-const wsSubscribers = new Map<string, Set<(data: ArrayBuffer | Detection[]) => void>>();
+const wsSubscribers = new Map<string, Set<(data: FeedData) => void>>();
 let reconnectTimeout: number | null = null;
 
+// Frames received on the current connection. The count is acked to the feed
+// hub every second so it can measure how far behind this client is.
+const ACK_INTERVAL_MS = 1000;
+let framesReceived = 0;
+let framesAcked = 0;
+let ackInterval: number | null = null;
+
 function getWsUrl(): string {
   // Connect to backend WebSocket
   const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
@@ -44,6 +63,16 @@ function connectWebSocket() {
     globalWs = ws;
     wsConnecting = false;
 
+    framesReceived = 0;
+    framesAcked = 0;
+    if (ackInterval) clearInterval(ackInterval);
+    ackInterval = window.setInterval(() => {
+      if (framesReceived > framesAcked && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'ack', data: { seq: framesReceived } }));
+        framesAcked = framesReceived;
+      }
+    }, ACK_INTERVAL_MS);
+
     // Re-subscribe to all cameras
     wsSubscribers.forEach((_, cameraKey) => {
       ws.send(JSON.stringify({ type: 'subscribe', camera: cameraKey }));
@@ -56,6 +85,7 @@ function connectWebSocket() {
       // Format: [1 byte type][1 byte key length][camera key][frame data]
       const data = new Uint8Array(event.data);
       if (data[0] !== 0x01) return; // Not a frame
+      framesReceived++;
 
       const keyLength = data[1];
       const cameraKey = new TextDecoder().decode(data.slice(2, 2 + keyLength));
@@ -75,6 +105,11 @@ function connectWebSocket() {
           if (handlers) {
             handlers.forEach(handler => handler(msg.data));
           }
+        } else if (msg.type === 'gap' && msg.camera) {
+          const handlers = wsSubscribers.get(msg.camera);
+          if (handlers) {
+            handlers.forEach(handler => handler({ gap: msg.data as FrameGap }));
+          }
         } else if (msg.type === 'error') {
           console.error('Feed hub error:', msg.error);
         }
@@ -88,6 +123,10 @@ function connectWebSocket() {
     console.log('📺 WebSocket disconnected');
     globalWs = null;
     wsConnecting = false;
+    if (ackInterval) {
+      clearInterval(ackInterval);
+      ackInterval = null;
+    }
 
     // Reconnect after delay
     if (reconnectTimeout) clearTimeout(reconnectTimeout);
@@ -104,7 +143,7 @@ function connectWebSocket() {
   };
 }
 
-function subscribe(cameraKey: string, handler: (data: ArrayBuffer | Detection[]) => void) {
+function subscribe(cameraKey: string, handler: (data: FeedData) => void) {
   if (!wsSubscribers.has(cameraKey)) {
     wsSubscribers.set(cameraKey, new Set());
   }
@@ -119,7 +158,7 @@ function subscribe(cameraKey: string, handler: (data: ArrayBuffer | Detection[])
   }
 }
 
-function unsubscribe(cameraKey: string, handler: (data: ArrayBuffer | Detection[]) => void) {
+function unsubscribe(cameraKey: string, handler: (data: FeedData) => void) {
   const handlers = wsSubscribers.get(cameraKey);
   if (handlers) {
     handlers.delete(handler);
@@ -153,13 +192,16 @@ export function WebSocketVideoFrame({
   const [fps, setFps] = useState<number>(0);
   const [detections, setDetections] = useState<Detection[]>([]);
   const [error, setError] = useState<string | null>(null);
+  const [lastGapTime, setLastGapTime] = useState<number>(0);
+  const [framesLost, setFramesLost] = useState<number>(0);
+  const [degraded, setDegraded] = useState(false);
   const frameCountRef = useRef(0);
   const lastFpsUpdateRef = useRef(Date.now());
 
   const cameraKey = `${workerId}.${cameraId}`;
 
   // Handle incoming data (frames or detections)
-  const handleData = useCallback((data: ArrayBuffer | Detection[]) => {
+  const handleData = useCallback((data: FeedData) => {
     if (data instanceof ArrayBuffer) {
       // Frame data
       const blob = new Blob([data], { type: 'image/jpeg' });
@@ -209,6 +251,11 @@ export function WebSocketVideoFrame({
       };
       
       img.src = url;
+    } else if (data && typeof data === 'object' && 'gap' in data) {
+      // Frames were dropped between the camera and the hub
+      setLastGapTime(Date.now());
+      setFramesLost(prev => prev + data.gap.missed);
+      setDegraded(true);
     } else {
       // Detection data - can be array or object with detections property
       if (Array.isArray(data)) {
@@ -241,6 +288,13 @@ export function WebSocketVideoFrame({
     return () => clearInterval(interval);
   }, [lastFrameTime]);
 
+  // Clear degraded badge once the feed has been clean for a while
+  useEffect(() => {
+    if (!lastGapTime) return;
+    const timeout = setTimeout(() => setDegraded(false), DEGRADED_DISPLAY_MS);
+    return () => clearTimeout(timeout);
+  }, [lastGapTime]);
+
   // Notify parent of connection changes
   useEffect(() => {
     onConnectionChange?.(connected);
@@ -255,6 +309,15 @@ export function WebSocketVideoFrame({
       
       {/* Status overlay */}
       <div className="absolute top-2 right-2 flex items-center gap-2">
+        {connected && degraded && (
+          <div
+            className="bg-amber-500/80 rounded-full px-2 py-0.5 flex items-center gap-1"
+            title={`${framesLost} frames lost since viewing started`}
+          >
+            <AlertTriangle className="w-3 h-3 text-white" />
+            <span className="text-xs text-white font-medium">Feed degraded</span>
+          </div>
+        )}
         {connected ? (
           <div className="bg-green-500/80 rounded-full px-2 py-0.5 flex items-center gap-1">
             <Wifi className="w-3 h-3 text-white" />
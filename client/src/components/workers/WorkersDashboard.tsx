@@ -139,9 +139,13 @@ export function WorkersDashboard() {
   };
 
   const handleDeleteWorker = async (workerId: string) => {
-    if (!confirm('Are you sure you want to delete this worker?')) return;
     try {
-      await apiClient.deleteWorker(workerId);
+      const pending = await apiClient.deleteWorker(workerId);
+      if ('confirmationRequired' in pending) {
+        const cameras = pending.impact.cameraAssignments ?? 0;
+        if (!confirm(`Delete worker ${pending.impact.workerName}? ${cameras} camera assignment(s) will be removed.`)) return;
+        await apiClient.deleteWorker(workerId, pending.confirmToken);
+      }
       fetchData();
     } catch (error) {
       console.error('Failed to delete worker:', error);
@@ -236,6 +240,7 @@ export function WorkersDashboard() {
                     <p className="font-medium">{req.deviceName}</p>
                     <p className="text-sm text-gray-500">
                       {req.model} • {req.ip} • {timeAgo(req.createdAt)}
+                      {req.retryCount > 0 && ` • ${req.retryCount} repeat request${req.retryCount > 1 ? 's' : ''}`}
                     </p>
                   </div>
                   <div className="flex gap-2">
@@ -291,6 +296,20 @@ export function WorkersDashboard() {
                         <p className="text-xs text-gray-400 mt-1">
                           Last seen: {timeAgo(worker.lastSeen)}
                         </p>
+                        {worker.clockSkewed && worker.clockSkewMs != null && (
+                          <p className="flex items-center gap-1 text-xs text-red-500 mt-1" title="Check NTP on the device">
+                            <AlertTriangle className="w-3 h-3" />
+                            Clock {Math.round(Math.abs(worker.clockSkewMs) / 1000)}s {worker.clockSkewMs > 0 ? 'ahead' : 'behind'}
+                          </p>
+                        )}
+                        {worker.configDrifted && (
+                          <p className="flex items-center gap-1 text-xs text-amber-500 mt-1" title={worker.configApplyError ?? 'Worker has not applied the latest config'}>
+                            <AlertTriangle className="w-3 h-3" />
+                            {worker.configApplyError
+                              ? `Config v${worker.configVersion} failed to apply`
+                              : `Config v${worker.appliedConfigVersion} of v${worker.configVersion}`}
+                          </p>
+                        )}
                       </div>
                     </div>
 
@@ -26,12 +26,14 @@ export interface Device {
   lng: number;
   status: DeviceStatus;
   zoneId?: string;
+  siteId?: string;
   description?: string | null;
   rtspUrl?: string | null;
   metadata?: Record<string, any>;
   config?: Record<string, any>;
   events?: any[];
   workerId?: string | null;
+  lastEventAt?: string | null;
   createdAt: string;
   updatedAt: string;
   latestEvent?: {
@@ -64,6 +66,8 @@ export interface DeviceMapMarker {
   lat: number;
   lng: number;
   status: DeviceStatus;
+  lastEventAt?: string | null;
+  online?: boolean;
 }
 
 // Hotspot interface for crowd visualization
@@ -80,6 +84,8 @@ export interface Hotspot {
   densityLevel: 'LOW' | 'MEDIUM' | 'HIGH' | 'CRITICAL';
   congestionLevel: number | null;
   lastUpdated: string | null;
+  lastEventAt: string | null;
+  online: boolean;
 }
 
 // Crowd Analysis interface
@@ -126,6 +132,18 @@ export interface ApiResponse<T> {
   error?: string;
 }
 
+// Returned (HTTP 428) by a destructive admin action called without a valid
+// confirmation token
+export interface ConfirmationRequired {
+  confirmationRequired: true;
+  error: string;
+  action: string;
+  target: string;
+  impact: Record<string, any>;
+  confirmToken: string;
+  expiresAt: string;
+}
+
 // Import worker types for use in ApiClient methods
 import type {
   WorkerStatus,
@@ -211,6 +229,39 @@ class ApiClient {
     return response.json();
   }
 
+  // Destructive admin actions answer 428 with their impact and a short-lived
+  // token; they only run when called again with that token
+  private async requestConfirmed<T>(
+    endpoint: string,
+    options: RequestInit,
+    confirmToken?: string
+  ): Promise<T | ConfirmationRequired> {
+    const headers: HeadersInit = {
+      'Content-Type': 'application/json',
+      ...options.headers,
+    };
+    if (this.token) {
+      // @ts-ignore
+      headers['Authorization'] = `Bearer ${this.token}`;
+    }
+    if (confirmToken) {
+      // @ts-ignore
+      headers['X-Confirm-Token'] = confirmToken;
+    }
+
+    const response = await fetch(`${this.baseUrl}${endpoint}`, {
+      ...options,
+      headers,
+    });
+    if (response.status === 428) {
+      return response.json();
+    }
+    if (!response.ok) {
+      throw new Error(`API Error: ${response.statusText}`);
+    }
+    return response.json();
+  }
+
   // Device endpoints
   async getDevices(options?: {
     type?: DeviceType;
@@ -324,12 +375,13 @@ class ApiClient {
 
   // Violation endpoints (ITMS)
   async getViolations(options?: {
-    status?: 'PENDING' | 'APPROVED' | 'REJECTED' | 'FINED';
+    status?: 'PENDING' | 'APPROVED' | 'REJECTED' | 'FINED' | 'PAID';
     violationType?: 'SPEED' | 'HELMET' | 'WRONG_SIDE' | 'RED_LIGHT' | 'NO_SEATBELT' | 'OVERLOADING' | 'ILLEGAL_PARKING' | 'OTHER';
     deviceId?: string;
     plateNumber?: string;
     startTime?: string;
     endTime?: string;
+    includeLowConfidence?: boolean;
     limit?: number;
     offset?: number;
   }): Promise<{ violations: TrafficViolation[]; total: number; limit: number; offset: number }> {
@@ -340,6 +392,7 @@ class ApiClient {
     if (options?.plateNumber) params.append('plateNumber', options.plateNumber);
     if (options?.startTime) params.append('startTime', options.startTime);
     if (options?.endTime) params.append('endTime', options.endTime);
+    if (options?.includeLowConfidence) params.append('includeLowConfidence', 'true');
     if (options?.limit) params.append('limit', options.limit.toString());
     if (options?.offset) params.append('offset', options.offset.toString());
     const query = params.toString();
@@ -366,6 +419,26 @@ class ApiClient {
     });
   }
 
+  async transitionViolation(id: string, data: {
+    status: ViolationStatus | string;
+    reviewedBy?: string;
+    note?: string;
+    rejectionReason?: string;
+    fineAmount?: number;
+    fineReference?: string;
+  }): Promise<TrafficViolation> {
+    return this.request<TrafficViolation>(`/api/violations/${id}/transition`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async requestViolationPayment(id: string): Promise<ViolationPaymentRequest> {
+    return this.request<ViolationPaymentRequest>(`/api/violations/${id}/payment-request`, {
+      method: 'POST',
+    });
+  }
+
   async updateViolationPlate(id: string, plateNumber: string): Promise<TrafficViolation> {
     return this.request<TrafficViolation>(`/api/violations/${id}/plate`, {
       method: 'PATCH',
@@ -485,6 +558,8 @@ class ApiClient {
     alertOnDetection?: boolean;
     alertOnViolation?: boolean;
     notes?: string;
+    category?: WatchlistCategory;
+    severity?: 'GREEN' | 'YELLOW' | 'ORANGE' | 'RED'; // defaults by category
   }): Promise<Watchlist> {
     return this.request<Watchlist>(`/api/vehicles/${id}/watchlist`, {
       method: 'POST',
@@ -498,8 +573,15 @@ class ApiClient {
     });
   }
 
-  async getWatchlist(): Promise<Watchlist[]> {
-    return this.request<Watchlist[]>('/api/watchlist');
+  async getWatchlist(options?: {
+    category?: WatchlistCategory;
+    severity?: 'GREEN' | 'YELLOW' | 'ORANGE' | 'RED';
+  }): Promise<Watchlist[]> {
+    const params = new URLSearchParams();
+    if (options?.category) params.append('category', options.category);
+    if (options?.severity) params.append('severity', options.severity);
+    const query = params.toString();
+    return this.request<Watchlist[]>(`/api/watchlist${query ? `?${query}` : ''}`);
   }
 
   async getVehicleStats(): Promise<VehicleStats> {
@@ -512,12 +594,14 @@ class ApiClient {
     endTime?: string;
     groupBy?: 'minute' | 'hour' | 'day' | 'week' | 'month';
     location?: string;
+    minConfidence?: number; // 0-1, detections below it are excluded
   }): Promise<VCCStats> {
     const params = new URLSearchParams();
     if (options?.startTime) params.append('startTime', options.startTime);
     if (options?.endTime) params.append('endTime', options.endTime);
     if (options?.groupBy) params.append('groupBy', options.groupBy);
     if (options?.location) params.append('location', options.location);
+    if (options?.minConfidence) params.append('minConfidence', options.minConfidence.toString());
     const query = params.toString();
     return this.request<VCCStats>(`/api/vcc/stats${query ? `?${query}` : ''}`);
   }
@@ -526,11 +610,13 @@ class ApiClient {
     startTime?: string;
     endTime?: string;
     groupBy?: 'minute' | 'hour' | 'day' | 'week' | 'month';
+    minConfidence?: number; // 0-1, detections below it are excluded
   }): Promise<VCCDeviceStats> {
     const params = new URLSearchParams();
     if (options?.startTime) params.append('startTime', options.startTime);
     if (options?.endTime) params.append('endTime', options.endTime);
     if (options?.groupBy) params.append('groupBy', options.groupBy);
+    if (options?.minConfidence) params.append('minConfidence', options.minConfidence.toString());
     const query = params.toString();
     return this.request<VCCDeviceStats>(`/api/vcc/device/${deviceId}${query ? `?${query}` : ''}`);
   }
@@ -583,18 +669,18 @@ class ApiClient {
     });
   }
 
-  // Admin: Revoke worker
-  async revokeWorker(id: string): Promise<void> {
-    return this.request<void>(`/api/admin/workers/${id}/revoke`, {
+  // Admin: Revoke worker; returns the impact to confirm unless confirmToken is given
+  async revokeWorker(id: string, confirmToken?: string): Promise<{ message: string } | ConfirmationRequired> {
+    return this.requestConfirmed<{ message: string }>(`/api/admin/workers/${id}/revoke`, {
       method: 'POST',
-    });
+    }, confirmToken);
   }
 
-  // Admin: Delete worker
-  async deleteWorker(id: string): Promise<void> {
-    return this.request<void>(`/api/admin/workers/${id}`, {
+  // Admin: Delete worker; returns the impact to confirm unless confirmToken is given
+  async deleteWorker(id: string, confirmToken?: string): Promise<{ message: string } | ConfirmationRequired> {
+    return this.requestConfirmed<{ message: string }>(`/api/admin/workers/${id}`, {
       method: 'DELETE',
-    });
+    }, confirmToken);
   }
 
   // Admin: Get pending approval requests
@@ -686,7 +772,7 @@ export const apiClient = new ApiClient(API_BASE_URL);
 
 // Violation Types
 export type ViolationType = 'SPEED' | 'HELMET' | 'WRONG_SIDE' | 'RED_LIGHT' | 'NO_SEATBELT' | 'OVERLOADING' | 'ILLEGAL_PARKING' | 'OTHER';
-export type ViolationStatus = 'PENDING' | 'APPROVED' | 'REJECTED' | 'FINED';
+export type ViolationStatus = 'PENDING' | 'APPROVED' | 'REJECTED' | 'FINED' | 'PAID';
 export type DetectionMethod = 'RADAR' | 'CAMERA' | 'AI_VISION' | 'MANUAL';
 
 export interface TrafficViolation {
@@ -713,7 +799,14 @@ export interface TrafficViolation {
   speedLimit4W?: number | null;
   speedOverLimit?: number | null;
   confidence?: number | null;
+  lowConfidence?: boolean;
   metadata?: any;
+  schemaVersion?: number; // layout version of metadata, see /api/events/schemas
+  // Bounding box from metadata as fractions of the frame, when normalizable
+  bboxX?: number;
+  bboxY?: number;
+  bboxW?: number;
+  bboxH?: number;
   reviewedAt?: string | null;
   reviewedBy?: string | null;
   reviewNote?: string | null;
@@ -721,6 +814,19 @@ export interface TrafficViolation {
   fineAmount?: number | null;
   fineIssuedAt?: string | null;
   fineReference?: string | null;
+  paymentReference?: string | null;
+  paymentUrl?: string | null;
+  paidAmount?: number | null;
+  paidAt?: string | null;
+}
+
+export interface ViolationPaymentRequest {
+  violationId: number;
+  paymentReference: string;
+  paymentUrl: string;
+  expiresAt?: string | null;
+  amount: number;
+  currency: string;
 }
 
 export interface ViolationStats {
@@ -739,10 +845,12 @@ export type VehicleType = '2W' | '4W' | 'AUTO' | 'TRUCK' | 'BUS' | 'UNKNOWN';
 export interface Vehicle {
   id: string;
   plateNumber?: string | null;
+  plateRegion?: string;
   make?: string | null;
   model?: string | null;
   vehicleType: VehicleType;
   color?: string | null;
+  attributeConfidence?: Record<string, { confidence: number; at: string }> | null;
   firstSeen: string;
   lastSeen: string;
   detectionCount: number;
@@ -779,16 +887,28 @@ export interface VehicleDetection {
   plateImageUrl?: string | null;
   vehicleImageUrl?: string | null;
   frameId?: string | null;
+  lat?: number | null; // where the detection happened
+  lng?: number | null;
   direction?: string | null;
   lane?: number | null;
   metadata?: any;
+  schemaVersion?: number; // layout version of metadata, see /api/events/schemas
+  // Bounding box from metadata as fractions of the frame, when normalizable
+  bboxX?: number;
+  bboxY?: number;
+  bboxW?: number;
+  bboxH?: number;
 }
 
+export type WatchlistCategory = 'STOLEN' | 'WANTED' | 'VIP' | 'PARKING_DEFAULTER' | 'OTHER';
+
 export interface Watchlist {
   id: string;
   vehicleId: string;
   vehicle?: Vehicle;
   reason: string;
+  category: WatchlistCategory;
+  severity: 'GREEN' | 'YELLOW' | 'ORANGE' | 'RED';
   addedBy: string;
   addedAt: string;
   isActive: boolean;
@@ -845,6 +965,8 @@ export interface VCCStats {
     plateOnly: number;
     fullClassification: number;
   };
+  minConfidence?: number;
+  excludedDetections: number; // below minConfidence
 }
 
 export interface VCCDeviceStats {
@@ -877,6 +999,8 @@ export interface VCCDeviceStats {
     plateOnly: number;
     fullClassification: number;
   };
+  minConfidence?: number;
+  excludedDetections: number; // below minConfidence
 }
 
 export interface VCCRealtime {
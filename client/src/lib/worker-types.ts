@@ -11,16 +11,21 @@ export interface WorkerCameraAssignment {
   analytics: string[];
   fps: number;
   resolution: string;
+  roi?: RoiPolygon | null;
   isActive: boolean;
   createdAt: string;
   updatedAt: string;
 }
 
+// Region of interest: polygon points as [x, y] fractions of frame width/height (0-1)
+export type RoiPolygon = [number, number][];
+
 export interface CameraAssignment {
   device_id: string;
   analytics: string[];
   fps?: number;
   resolution?: string;
+  roi?: RoiPolygon;
 }
 
 export interface Worker {
@@ -35,6 +40,7 @@ export interface Worker {
   approvedBy?: string | null;
   lastSeen: string;
   lastIp?: string | null;
+  clockSkewMs?: number | null; // worker clock minus server clock
   resources?: {
     cpu_percent?: number;
     gpu_percent?: number;
@@ -43,6 +49,10 @@ export interface Worker {
   } | null;
   config?: any;
   configVersion: number;
+  configUpdatedAt?: string | null;
+  appliedConfigVersion?: number | null; // version the worker reports running
+  configAppliedAt?: string | null;
+  configApplyError?: string | null;
   metadata?: any;
   tags?: string[] | null;
   createdAt: string;
@@ -52,6 +62,9 @@ export interface Worker {
 
 export interface WorkerWithCounts extends Worker {
   cameraCount: number;
+  clockSkewed: boolean; // clock off by more than the server's threshold
+  configDrift: number | null; // config versions behind; null if the worker doesn't report it
+  configDrifted: boolean; // behind past the grace period, or failed to apply
 }
 
 export interface WorkerToken {
@@ -76,6 +89,9 @@ export interface WorkerApprovalRequest {
   ip: string;
   mac: string;
   model: string;
+  fingerprint: string;
+  retryCount: number;
+  lastRequestAt?: string | null;
   status: 'pending' | 'approved' | 'rejected';
   workerId?: string | null;
   rejectedBy?: string | null;
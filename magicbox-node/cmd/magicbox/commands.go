@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/irisdrone/magicbox-node/internal/central"
+	"github.com/irisdrone/magicbox-node/internal/config"
+	"github.com/irisdrone/magicbox-node/internal/decoder"
+	"github.com/irisdrone/magicbox-node/internal/natsserver"
+	"github.com/irisdrone/magicbox-node/internal/platform"
+	"github.com/irisdrone/magicbox-node/internal/queue"
+	"github.com/irisdrone/magicbox-node/internal/streamer"
+)
+
+// captureFrameTimeout bounds how long capture_frame waits for the next frame
+const captureFrameTimeout = 2 * time.Second
+
+// registerCommandHandlers wires remote management commands from central to local components
+func registerCommandHandlers(
+	centralClient *central.Client,
+	cfg *config.Manager,
+	platformClient *platform.Client,
+	pipeline *streamer.Pipeline,
+	eventQueue *queue.FileQueue,
+	nats *natsserver.EmbeddedNATS,
+	hwInfo *decoder.HardwareInfo,
+	startedAt time.Time,
+) {
+	// Restart a single camera reader
+	centralClient.RegisterCommandHandler(central.ActionRestartCamera, func(cmd central.Command) (interface{}, error) {
+		if pipeline == nil {
+			return nil, fmt.Errorf("streaming pipeline is disabled")
+		}
+		if cmd.CameraID == "" {
+			return nil, fmt.Errorf("cameraId is required")
+		}
+		if err := pipeline.RefreshCamera(cmd.CameraID); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"cameraId": cmd.CameraID}, nil
+	})
+
+	// Pull config from the platform now instead of waiting for the sync loop
+	centralClient.RegisterCommandHandler(central.ActionResyncConfig, func(cmd central.Command) (interface{}, error) {
+		workerCfg, err := platformClient.FetchConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := platformClient.ApplyConfig(workerCfg); err != nil {
+			return nil, err
+		}
+
+		// Notify pipeline to sync cameras
+		nats.Publish("config.cameras", []byte("updated"))
+
+		log.Printf("📥 Remote resync: config version %d, %d cameras", workerCfg.ConfigVersion, len(workerCfg.Cameras))
+		return map[string]interface{}{
+			"configVersion": workerCfg.ConfigVersion,
+			"cameraCount":   len(workerCfg.Cameras),
+		}, nil
+	})
+
+	// Report node health for remote troubleshooting
+	centralClient.RegisterCommandHandler(central.ActionDiagnostics, func(cmd central.Command) (interface{}, error) {
+		nodeCfg := cfg.Get()
+
+		diag := map[string]interface{}{
+			"version":       version,
+			"buildTime":     buildTime,
+			"uptimeSeconds": int(time.Since(startedAt).Seconds()),
+			"state":         nodeCfg.State,
+			"configVersion": nodeCfg.ConfigVersion,
+			"lastSync":      nodeCfg.LastSync,
+			"cameraCount":   len(nodeCfg.Cameras),
+			"queue":         eventQueue.GetStats(),
+			"central":       centralClient.GetStats(),
+			"decoder": map[string]interface{}{
+				"type":    hwInfo.Type,
+				"backend": hwInfo.Backend,
+				"gpu":     hwInfo.GPUName,
+			},
+		}
+		if pipeline != nil {
+			diag["streams"] = pipeline.GetStats()
+		}
+		return diag, nil
+	})
+
+	// Swap in the new platform auth token central issued, fetched from the platform API
+	centralClient.RegisterCommandHandler(central.ActionRotateToken, func(cmd central.Command) (interface{}, error) {
+		if err := platformClient.RotateAuthToken(); err != nil {
+			return nil, err
+		}
+
+		log.Println("🔑 Platform auth token rotated")
+		return map[string]interface{}{"rotated": true}, nil
+	})
+
+	// Return the next live frame from a camera (used for violation evidence)
+	centralClient.RegisterCommandHandler(central.ActionCaptureFrame, func(cmd central.Command) (interface{}, error) {
+		if pipeline == nil {
+			return nil, fmt.Errorf("streaming pipeline is disabled")
+		}
+		if cmd.CameraID == "" {
+			return nil, fmt.Errorf("cameraId is required")
+		}
+		if _, ok := pipeline.GetCameraStats(cmd.CameraID); !ok {
+			return nil, fmt.Errorf("camera %s is not streaming", cmd.CameraID)
+		}
+
+		sub, err := nats.Conn().SubscribeSync("frames." + cmd.CameraID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to subscribe to frames: %w", err)
+		}
+		defer sub.Unsubscribe()
+
+		msg, err := sub.NextMsg(captureFrameTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("no frame received from camera %s: %w", cmd.CameraID, err)
+		}
+
+		var frame streamer.FrameMessage
+		if err := json.Unmarshal(msg.Data, &frame); err != nil {
+			return nil, fmt.Errorf("invalid frame: %w", err)
+		}
+		return map[string]interface{}{
+			"cameraId":  frame.Camera,
+			"seq":       frame.Seq,
+			"timestamp": frame.Timestamp,
+			"width":     frame.Width,
+			"height":    frame.Height,
+			"frame":     frame.Frame, // base64 JPEG
+		}, nil
+	})
+}
@@ -10,7 +10,9 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
+	"github.com/irisdrone/magicbox-node/internal/camera"
 	"github.com/irisdrone/magicbox-node/internal/central"
 	"github.com/irisdrone/magicbox-node/internal/config"
 	"github.com/irisdrone/magicbox-node/internal/decoder"
@@ -19,6 +21,7 @@ import (
 	"github.com/irisdrone/magicbox-node/internal/queue"
 	"github.com/irisdrone/magicbox-node/internal/streamer"
 	"github.com/irisdrone/magicbox-node/internal/web"
+	"github.com/irisdrone/magicbox-node/internal/wireguard"
 )
 
 var (
@@ -33,10 +36,29 @@ func main() {
 	webPort := flag.Int("port", 8080, "Web UI port")
 	natsPort := flag.Int("nats-port", 4222, "NATS server port")
 	enableStreamer := flag.Bool("enable-streamer", true, "Enable frame streaming pipeline")
+	uploadBatch := flag.Int("upload-batch", 0, "Upload up to N queued image-less events per gzip request (0 = one request per event)")
+	partitionFrames := flag.Bool("partition-frames", false, "Also publish frames on frames.<camera>.<analytic> for each active analytic")
+	overviewFPS := flag.Float64("overview-fps", 0, "Also publish downscaled overview frames on overview.<camera> at up to N fps (0 = disabled)")
+	overviewWidth := flag.Int("overview-width", streamer.DefaultOverviewWidth, "Width in pixels of overview frames")
+	allowReplay := flag.Bool("allow-replay", false, "Allow camera URLs of file:///video or dir:///jpegs, replayed in a loop (for testing and demos)")
+	maxCameras := flag.Int("max-cameras", 0, "Stream at most N cameras, shedding the lowest-priority ones first (0 = unlimited)")
+	gateCPU := flag.Float64("frame-gate-cpu", 0, "Hold back live frames forwarded to central while CPU usage is at or above N percent (0 = not checked)")
+	gateTemp := flag.Float64("frame-gate-temp", 0, "Hold back live frames forwarded to central while the CPU is at or above N degrees C (0 = not checked)")
+	gateMode := flag.String("frame-gate-mode", streamer.GateReduce, "What the frame gate does when tripped: reduce or suspend")
+	gateFPS := flag.Float64("frame-gate-fps", streamer.DefaultGateReducedFPS, "Frames per camera per second forwarded while the frame gate is reduced")
+	magicNetworkRetries := flag.Int("magicnetwork-retries", web.DefaultMagicNetworkAttempts, "Attempts per MagicNetwork registration")
+	magicNetworkTimeout := flag.Duration("magicnetwork-timeout", web.DefaultMagicNetworkTimeout, "Timeout of each MagicNetwork registration attempt")
+	magicNetworkCooldown := flag.Duration("magicnetwork-cooldown", web.DefaultMagicNetworkCooldown, "How long to fail fast after repeated MagicNetwork failures")
+	wgMonitorInterval := flag.Duration("wg-monitor-interval", wireguard.DefaultMonitorInterval, "How often to check the WireGuard handshake (0 = no monitor)")
+	wgRestartAfter := flag.Int("wg-restart-after", wireguard.DefaultMonitorThreshold, "Restart the WireGuard tunnel after N consecutive checks without a handshake")
+	scanConcurrency := flag.Int("scan-concurrency", camera.DefaultScanConcurrency, "Most RTSP probes in flight during a subnet camera scan")
+	scanTimeout := flag.Duration("scan-timeout", camera.DefaultScanTimeout, "Timeout of each RTSP probe during a subnet camera scan")
+	startupReport := flag.Bool("startup-report", true, "Report version, config version and decoder to the platform once on start")
 	showVersion := flag.Bool("version", false, "Show version")
 	install := flag.Bool("install", false, "Install MagicBox as systemd service")
 	uninstall := flag.Bool("uninstall", false, "Uninstall MagicBox systemd service")
 	flag.Parse()
+	config.AllowReplaySources = *allowReplay
 
 	if *showVersion {
 		fmt.Printf("MagicBox Node v%s (built %s)\n", version, buildTime)
@@ -72,6 +94,7 @@ func main() {
 	}
 
 	log.Printf("🚀 Starting MagicBox Node v%s", version)
+	startedAt := time.Now()
 
 	// Detect hardware and available decoders
 	hwInfo := decoder.Init()
@@ -98,21 +121,52 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize event queue: %v", err)
 	}
+	eventQueue.SetUploadBatchSize(*uploadBatch)
+	eventQueue.SetFilterRules(cfg.EventFilters)
 
 	// Initialize platform client
 	platformClient := platform.NewClient(cfg, eventQueue)
+	if *startupReport {
+		platformClient.SetStartupReport(platform.BuildInfo{
+			Version:        version,
+			BuildTime:      buildTime,
+			DecoderBackend: string(hwInfo.Backend),
+			DecoderAccel:   string(hwInfo.Type),
+			StartedAt:      startedAt,
+		})
+	}
 
 	// Initialize streaming pipeline (optional, can be disabled for management-only mode)
 	var pipeline *streamer.Pipeline
 	if *enableStreamer {
 		pipeline = streamer.NewPipeline(cfg, nats)
+		pipeline.SetPartitionFrames(*partitionFrames)
+		pipeline.SetMaxCameras(*maxCameras)
+		pipeline.SetOverview(*overviewWidth, *overviewFPS)
+		pipeline.SetHealthGate(streamer.GateConfig{
+			MaxCPU:     *gateCPU,
+			MaxTemp:    *gateTemp,
+			Mode:       *gateMode,
+			ReducedFPS: *gateFPS,
+		}, platform.SampleHealth)
 	}
 
 	// Initialize central NATS client (forwards events/frames to central)
 	centralClient := central.NewClient(cfg, nats)
+	centralClient.SetEventFilter(func(eventType, deviceID string, data map[string]interface{}) bool {
+		return eventQueue.FilterEvent(queue.EventType(eventType), deviceID, data)
+	})
+	centralClient.SetEventSequencer(eventQueue.NextSeq)
+	if pipeline != nil {
+		centralClient.SetFrameGate(pipeline.AllowForward)
+	}
+	registerCommandHandlers(centralClient, cfg, platformClient, pipeline, eventQueue, nats, hwInfo, startedAt)
 
 	// Initialize web server with all components
 	webServer := web.NewServer(cfg, platformClient, eventQueue, nats, pipeline, centralClient, *webPort)
+	webServer.SetMagicNetworkRetry(*magicNetworkRetries, *magicNetworkTimeout, *magicNetworkCooldown)
+	webServer.SetDiscoveryLimits(*scanConcurrency, *scanTimeout)
+	webServer.StartWireGuardMonitor(*wgMonitorInterval, *wgRestartAfter)
 
 	// Start background services
 	go platformClient.Start()
@@ -140,6 +194,9 @@ func main() {
 	log.Printf("📡 NATS: nats://localhost:%d", *natsPort)
 	if *enableStreamer {
 		log.Printf("🎥 Streamer: enabled (subscribe to frames.<camera_id>)")
+		if *overviewFPS > 0 {
+			log.Printf("🗺️ Overview frames: %dpx at %g fps (subscribe to overview.<camera_id>)", *overviewWidth, *overviewFPS)
+		}
 	} else {
 		log.Printf("🎥 Streamer: disabled")
 	}
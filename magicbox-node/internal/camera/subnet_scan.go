@@ -0,0 +1,253 @@
+package camera
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subnet scan defaults
+const (
+	DefaultScanConcurrency = 64
+	DefaultScanTimeout     = time.Second
+
+	// MaxScanHosts bounds a scan to a /20, so a typo in the prefix length
+	// can't start probing a whole /8
+	MaxScanHosts = 4096
+
+	// MaxScanPorts bounds the ports probed on each host
+	MaxScanPorts = 16
+
+	// MaxScanProbes bounds hosts × ports, which lets a /20 be scanned on the
+	// default ports but a long port list only on a smaller subnet
+	MaxScanProbes = MaxScanHosts * 3
+)
+
+// DefaultRTSPPorts are the ports cameras commonly serve RTSP on
+var DefaultRTSPPorts = []int{554, 8554, 10554}
+
+// ScanOptions configures a subnet scan
+type ScanOptions struct {
+	CIDR         string
+	Ports        []int         // empty = DefaultRTSPPorts
+	Concurrency  int           // probes in flight at once; 0 = DefaultScanConcurrency
+	Timeout      time.Duration // per probe; 0 = DefaultScanTimeout
+	ProbeStreams bool          // also send an RTSP OPTIONS to each open port
+}
+
+// StreamInfo is what an RTSP server said in reply to OPTIONS
+type StreamInfo struct {
+	URL          string   `json:"url"`
+	Status       int      `json:"status,omitempty"` // RTSP status code; 0 = no valid reply
+	Server       string   `json:"server,omitempty"`
+	Methods      []string `json:"methods,omitempty"`
+	AuthRequired bool     `json:"authRequired"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// ScanHost is a host with at least one reachable RTSP port
+type ScanHost struct {
+	IP      string       `json:"ip"`
+	Ports   []int        `json:"ports"`
+	Streams []StreamInfo `json:"streams,omitempty"`
+}
+
+// ScanResult is the outcome of a subnet scan
+type ScanResult struct {
+	CIDR     string     `json:"cidr"`
+	Scanned  int        `json:"scanned"` // hosts probed
+	Ports    []int      `json:"ports"`
+	Hosts    []ScanHost `json:"hosts"`
+	Duration string     `json:"duration"`
+	Canceled bool       `json:"canceled,omitempty"` // stopped early; hosts are partial
+}
+
+// subnetHosts lists the host addresses of an IPv4 CIDR, leaving out the
+// network and broadcast addresses of anything wider than a /31
+func subnetHosts(cidr string) ([]net.IP, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", cidr)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("only IPv4 subnets can be scanned")
+	}
+	ones, bits := ipnet.Mask.Size()
+	size := uint64(1) << uint(bits-ones)
+	if size > MaxScanHosts {
+		return nil, fmt.Errorf("subnet /%d is too large, at most %d addresses can be scanned", ones, MaxScanHosts)
+	}
+
+	first := binary.BigEndian.Uint32(ipnet.IP.To4())
+	start, end := uint64(0), size
+	if size > 2 {
+		start, end = 1, size-1
+	}
+	hosts := make([]net.IP, 0, end-start)
+	for i := start; i < end; i++ {
+		host := make(net.IP, 4)
+		binary.BigEndian.PutUint32(host, first+uint32(i))
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// ScanSubnet probes every host of a subnet on the RTSP ports with at most
+// Concurrency TCP connects in flight. Canceling ctx stops it early with the
+// hosts found so far.
+func ScanSubnet(ctx context.Context, opts ScanOptions) (*ScanResult, error) {
+	hosts, err := subnetHosts(opts.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	ports := opts.Ports
+	if len(ports) == 0 {
+		ports = DefaultRTSPPorts
+	}
+	if len(ports) > MaxScanPorts {
+		return nil, fmt.Errorf("too many ports, at most %d can be scanned", MaxScanPorts)
+	}
+	for _, p := range ports {
+		if p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %d", p)
+		}
+	}
+	if probes := len(hosts) * len(ports); probes > MaxScanProbes {
+		return nil, fmt.Errorf("scan of %d hosts on %d ports is %d probes, at most %d are allowed", len(hosts), len(ports), probes, MaxScanProbes)
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultScanConcurrency
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultScanTimeout
+	}
+
+	type target struct {
+		ip   string
+		port int
+	}
+	started := time.Now()
+	targets := make(chan target)
+	var (
+		mu    sync.Mutex
+		found = make(map[string]*ScanHost)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dialer := net.Dialer{Timeout: timeout}
+			for t := range targets {
+				addr := net.JoinHostPort(t.ip, strconv.Itoa(t.port))
+				conn, err := dialer.DialContext(ctx, "tcp", addr)
+				if err != nil {
+					continue
+				}
+				var info *StreamInfo
+				if opts.ProbeStreams {
+					probed := probeRTSP(conn, t.ip, t.port, timeout)
+					info = &probed
+				}
+				conn.Close()
+
+				mu.Lock()
+				host, ok := found[t.ip]
+				if !ok {
+					host = &ScanHost{IP: t.ip}
+					found[t.ip] = host
+				}
+				host.Ports = append(host.Ports, t.port)
+				if info != nil {
+					host.Streams = append(host.Streams, *info)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	canceled := false
+feed:
+	for _, ip := range hosts {
+		for _, port := range ports {
+			select {
+			case targets <- target{ip: ip.String(), port: port}:
+			case <-ctx.Done():
+				canceled = true
+				break feed
+			}
+		}
+	}
+	close(targets)
+	wg.Wait()
+
+	result := &ScanResult{
+		CIDR:     opts.CIDR,
+		Scanned:  len(hosts),
+		Ports:    ports,
+		Hosts:    make([]ScanHost, 0, len(found)),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+		Canceled: canceled,
+	}
+	for _, host := range found {
+		sort.Ints(host.Ports)
+		sort.Slice(host.Streams, func(i, j int) bool { return host.Streams[i].URL < host.Streams[j].URL })
+		result.Hosts = append(result.Hosts, *host)
+	}
+	sort.Slice(result.Hosts, func(i, j int) bool {
+		return binary.BigEndian.Uint32(net.ParseIP(result.Hosts[i].IP).To4()) <
+			binary.BigEndian.Uint32(net.ParseIP(result.Hosts[j].IP).To4())
+	})
+	return result, nil
+}
+
+// probeRTSP sends an RTSP OPTIONS on an open connection and reads the reply
+// headers. A 401 means the stream is there but needs credentials.
+func probeRTSP(conn net.Conn, ip string, port int, timeout time.Duration) StreamInfo {
+	url := fmt.Sprintf("rtsp://%s:%d/", ip, port)
+	info := StreamInfo{URL: url}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintf(conn, "OPTIONS %s RTSP/1.0\r\nCSeq: 1\r\nUser-Agent: MagicBox\r\n\r\n", url); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	parts := strings.SplitN(statusLine, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "RTSP/") {
+		info.Error = "not an RTSP server"
+		return info
+	}
+	info.Status, _ = strconv.Atoi(parts[1])
+	info.AuthRequired = info.Status == 401
+
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return info
+	}
+	info.Server = header.Get("Server")
+	if public := header.Get("Public"); public != "" {
+		for _, m := range strings.Split(public, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				info.Methods = append(info.Methods, m)
+			}
+		}
+	}
+	return info
+}
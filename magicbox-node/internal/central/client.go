@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/irisdrone/magicbox-node/internal/config"
@@ -27,22 +28,42 @@ type Client struct {
 	// Subscriptions
 	eventSub     *nats.Subscription
 	detectionSub *nats.Subscription
+	overviewSub  *nats.Subscription
 	commandSub   *nats.Subscription
 
+	// Command handlers for request/reply commands (see commands.go)
+	handlers   map[string]CommandHandler
+	handlersMu sync.RWMutex
+
 	// Active streams (cameras being viewed remotely)
 	activeStreams     map[string]*nats.Subscription // cameraID -> frame subscription
 	activeDetections  map[string]*nats.Subscription // cameraID -> detection subscription
 	activeStreamsMu   sync.RWMutex
 
-	// Stats
+	// Stats, updated atomically from the subscription goroutines
 	eventsForwarded     uint64
+	eventsMasked        uint64
 	framesForwarded     uint64
 	detectionsForwarded uint64
+	detectionsMasked    uint64
+	overviewsForwarded  uint64
 
 	// FPS tracking per camera
 	fpsCount   map[string]int
 	fpsMu      sync.Mutex
 
+	// frameGate decides whether a camera's frame is forwarded (see
+	// SetFrameGate); nil = always
+	frameGate func(cameraID string) bool
+
+	// eventFilter decides whether an event is forwarded (see
+	// SetEventFilter); nil = always
+	eventFilter EventFilter
+
+	// eventSequencer numbers forwarded events (see SetEventSequencer); nil =
+	// they're forwarded unnumbered
+	eventSequencer EventSequencer
+
 	mu       sync.RWMutex
 	running  bool
 	stopChan chan struct{}
@@ -56,6 +77,7 @@ func NewClient(cfg *config.Manager, localNATS *natsserver.EmbeddedNATS) *Client
 		activeStreams:    make(map[string]*nats.Subscription),
 		activeDetections: make(map[string]*nats.Subscription),
 		fpsCount:         make(map[string]int),
+		handlers:         make(map[string]CommandHandler),
 		stopChan:         make(chan struct{}),
 	}
 	// Start FPS logging goroutine
@@ -165,6 +187,10 @@ func (c *Client) connectLoop() {
 			log.Printf("⚠️ Failed to subscribe to local detections: %v", err)
 		}
 
+		if err := c.subscribeToLocalOverview(); err != nil {
+			log.Printf("⚠️ Failed to subscribe to local overview frames: %v", err)
+		}
+
 		c.mu.Lock()
 		c.running = true
 		c.mu.Unlock()
@@ -205,6 +231,9 @@ func (c *Client) Stop() {
 	if c.detectionSub != nil {
 		c.detectionSub.Unsubscribe()
 	}
+	if c.overviewSub != nil {
+		c.overviewSub.Unsubscribe()
+	}
 	if c.commandSub != nil {
 		c.commandSub.Unsubscribe()
 	}
@@ -244,8 +273,10 @@ func (c *Client) subscribeToCommands() error {
 
 // Command represents a command from central
 type Command struct {
-	Action   string `json:"action"`   // start_stream, stop_stream
-	CameraID string `json:"cameraId"` // Camera to start/stop
+	ID       string                 `json:"id,omitempty"`       // Correlation ID echoed in the reply
+	Action   string                 `json:"action"`             // See Action* constants
+	CameraID string                 `json:"cameraId,omitempty"` // Camera the command applies to
+	Params   map[string]interface{} `json:"params,omitempty"`   // Action-specific parameters
 }
 
 // handleCommand processes commands from central
@@ -253,23 +284,39 @@ func (c *Client) handleCommand(msg *nats.Msg) {
 	var cmd Command
 	if err := json.Unmarshal(msg.Data, &cmd); err != nil {
 		log.Printf("⚠️ Invalid command: %v", err)
+		c.respond(msg, CommandReply{Error: "invalid command: " + err.Error()})
 		return
 	}
 
 	log.Printf("📥 Command received: %s for camera %s", cmd.Action, cmd.CameraID)
 
 	switch cmd.Action {
-	case "start_stream":
-		c.startStreamForward(cmd.CameraID)
-	case "stop_stream":
+	case ActionStartStream:
+		analytic, _ := cmd.Params["analytic"].(string)
+		c.startStreamForward(cmd.CameraID, analytic)
+		c.respond(msg, CommandReply{ID: cmd.ID, Action: cmd.Action, Success: true})
+	case ActionStopStream:
 		c.stopStreamForward(cmd.CameraID)
+		c.respond(msg, CommandReply{ID: cmd.ID, Action: cmd.Action, Success: true})
 	default:
-		log.Printf("⚠️ Unknown command: %s", cmd.Action)
+		// Handlers may block (e.g. config resync over HTTP), so don't hold up
+		// the subscription and delay stream commands behind them
+		go c.runCommandHandler(msg, cmd)
 	}
 }
 
-// startStreamForward begins forwarding frames for a camera to central
-func (c *Client) startStreamForward(cameraID string) {
+// SetFrameGate makes live frames be forwarded to central only when gate
+// allows them, e.g. to shed video while the box is overloaded. Call before
+// Start.
+func (c *Client) SetFrameGate(gate func(cameraID string) bool) {
+	c.frameGate = gate
+}
+
+// startStreamForward begins forwarding frames for a camera to central.
+// With an analytic, only that analytic's frame subject is forwarded, which
+// carries frames only while the analytic is active on the camera (requires
+// the pipeline to run with frame partitioning).
+func (c *Client) startStreamForward(cameraID, analytic string) {
 	c.activeStreamsMu.Lock()
 	defer c.activeStreamsMu.Unlock()
 
@@ -281,14 +328,20 @@ func (c *Client) startStreamForward(cameraID string) {
 
 	// Subscribe to local frames for this camera
 	localFrameSubject := fmt.Sprintf("frames.%s", cameraID)
+	if analytic != "" {
+		localFrameSubject = fmt.Sprintf("frames.%s.%s", cameraID, analytic)
+	}
 	centralFrameSubject := fmt.Sprintf("frames.%s.%s", c.workerID, cameraID)
 
 	frameSub, err := c.localNATS.Subscribe(localFrameSubject, func(msg *nats.Msg) {
+		if c.frameGate != nil && !c.frameGate(cameraID) {
+			return
+		}
 		// Forward to central
 		if err := c.centralConn.Publish(centralFrameSubject, msg.Data); err != nil {
 			log.Printf("⚠️ Failed to forward frame: %v", err)
 		} else {
-			c.framesForwarded++
+			atomic.AddUint64(&c.framesForwarded, 1)
 			c.fpsMu.Lock()
 			c.fpsCount[cameraID]++
 			c.fpsMu.Unlock()
@@ -306,10 +359,10 @@ func (c *Client) startStreamForward(cameraID string) {
 
 	detectSub, err := c.localNATS.Subscribe(localDetectSubject, func(msg *nats.Msg) {
 		// Forward detections to central for UI overlay
-		if err := c.centralConn.Publish(centralDetectSubject, msg.Data); err != nil {
+		if err := c.centralConn.Publish(centralDetectSubject, c.maskDetections(cameraID, msg.Data)); err != nil {
 			log.Printf("⚠️ Failed to forward detection: %v", err)
 		} else {
-			c.detectionsForwarded++
+			atomic.AddUint64(&c.detectionsForwarded, 1)
 		}
 	})
 	if err != nil {
@@ -319,7 +372,7 @@ func (c *Client) startStreamForward(cameraID string) {
 		c.activeDetections[cameraID] = detectSub
 	}
 
-	log.Printf("📹 Started streaming camera %s to central (frames + detections)", cameraID)
+	log.Printf("📹 Started streaming camera %s to central (frames + detections from %s)", cameraID, localFrameSubject)
 }
 
 // stopStreamForward stops forwarding frames for a camera
@@ -342,17 +395,23 @@ func (c *Client) stopStreamForward(cameraID string) {
 	log.Printf("📹 Stopped streaming camera %s to central", cameraID)
 }
 
-// subscribeToLocalEvents forwards all events to central
+// subscribeToLocalEvents forwards local events to central, except those
+// dropped by the event filter rules
 func (c *Client) subscribeToLocalEvents() error {
 	// Subscribe to all local events
 	var err error
 	c.eventSub, err = c.localNATS.Subscribe("events.*", func(msg *nats.Msg) {
+		data, ok := c.prepareEvent(msg.Data)
+		if !ok {
+			return
+		}
+
 		// Forward to central with worker prefix
 		centralSubject := fmt.Sprintf("events.%s", c.workerID)
-		if err := c.centralConn.Publish(centralSubject, msg.Data); err != nil {
+		if err := c.centralConn.Publish(centralSubject, data); err != nil {
 			log.Printf("⚠️ Failed to forward event: %v", err)
 		} else {
-			c.eventsForwarded++
+			atomic.AddUint64(&c.eventsForwarded, 1)
 		}
 	})
 	if err != nil {
@@ -378,10 +437,10 @@ func (c *Client) subscribeToLocalDetections() error {
 
 		if isStreaming {
 			centralSubject := fmt.Sprintf("detections.%s.%s", c.workerID, cameraID)
-			if err := c.centralConn.Publish(centralSubject, msg.Data); err != nil {
+			if err := c.centralConn.Publish(centralSubject, c.maskDetections(cameraID, msg.Data)); err != nil {
 				log.Printf("⚠️ Failed to forward detection: %v", err)
 			} else {
-				c.detectionsForwarded++
+				atomic.AddUint64(&c.detectionsForwarded, 1)
 			}
 		}
 	})
@@ -393,13 +452,47 @@ func (c *Client) subscribeToLocalDetections() error {
 	return nil
 }
 
+// subscribeToLocalOverview forwards overview frames of all cameras. They're
+// small and low-rate by design, so unlike full frames they flow without a
+// viewer asking for them.
+func (c *Client) subscribeToLocalOverview() error {
+	var err error
+	c.overviewSub, err = c.localNATS.Subscribe("overview.*", func(msg *nats.Msg) {
+		cameraID := msg.Subject[len("overview."):]
+		centralSubject := fmt.Sprintf("overview.%s.%s", c.workerID, cameraID)
+		if err := c.centralConn.Publish(centralSubject, msg.Data); err != nil {
+			log.Printf("⚠️ Failed to forward overview frame: %v", err)
+		} else {
+			atomic.AddUint64(&c.overviewsForwarded, 1)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to local overview frames: %w", err)
+	}
+
+	log.Println("📤 Forwarding overview frames to central")
+	return nil
+}
+
+// maskDetections drops detections outside the camera's region of interest
+func (c *Client) maskDetections(cameraID string, data []byte) []byte {
+	masked, removed := c.config.CameraROI(cameraID).Filter(data)
+	if removed > 0 {
+		atomic.AddUint64(&c.detectionsMasked, uint64(removed))
+	}
+	return masked
+}
+
 // Stats returns forwarding statistics
 type Stats struct {
 	Connected           bool     `json:"connected"`
 	CentralURL          string   `json:"centralUrl"`
 	EventsForwarded     uint64   `json:"eventsForwarded"`
+	EventsMasked        uint64   `json:"eventsMasked"` // Outside the camera's ROI
 	FramesForwarded     uint64   `json:"framesForwarded"`
 	DetectionsForwarded uint64   `json:"detectionsForwarded"`
+	DetectionsMasked    uint64   `json:"detectionsMasked"` // Outside the camera's ROI
+	OverviewsForwarded  uint64   `json:"overviewsForwarded"`
 	ActiveStreams       []string `json:"activeStreams"`
 }
 
@@ -421,9 +514,12 @@ func (c *Client) GetStats() Stats {
 	return Stats{
 		Connected:           connected,
 		CentralURL:          centralURL,
-		EventsForwarded:     c.eventsForwarded,
-		FramesForwarded:     c.framesForwarded,
-		DetectionsForwarded: c.detectionsForwarded,
+		EventsForwarded:     atomic.LoadUint64(&c.eventsForwarded),
+		EventsMasked:        atomic.LoadUint64(&c.eventsMasked),
+		FramesForwarded:     atomic.LoadUint64(&c.framesForwarded),
+		DetectionsForwarded: atomic.LoadUint64(&c.detectionsForwarded),
+		DetectionsMasked:    atomic.LoadUint64(&c.detectionsMasked),
+		OverviewsForwarded:  atomic.LoadUint64(&c.overviewsForwarded),
 		ActiveStreams:       streams,
 	}
 }
@@ -0,0 +1,81 @@
+package central
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Command actions accepted on command.<workerID>.
+//
+// start_stream/stop_stream are fire-and-forget (sent by the feed hub).
+// The others are sent with request/reply: the MagicBox executes the command
+// and responds with a CommandReply on the message's reply subject.
+const (
+	ActionStartStream   = "start_stream" // params.analytic optional
+	ActionStopStream    = "stop_stream"
+	ActionRestartCamera = "restart_camera" // cameraId required
+	ActionResyncConfig  = "resync_config"
+	ActionDiagnostics   = "run_diagnostics"
+	ActionRotateToken   = "rotate_token"  // fetch the pending token from the platform
+	ActionCaptureFrame  = "capture_frame" // cameraId required
+)
+
+// CommandReply is sent back to central for request/reply commands
+type CommandReply struct {
+	ID      string      `json:"id,omitempty"`
+	Action  string      `json:"action"`
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// CommandHandler executes a command and returns data to include in the reply
+type CommandHandler func(cmd Command) (interface{}, error)
+
+// RegisterCommandHandler registers the handler for a command action
+func (c *Client) RegisterCommandHandler(action string, handler CommandHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.handlers[action] = handler
+}
+
+// runCommandHandler executes a registered handler and replies if central asked for one
+func (c *Client) runCommandHandler(msg *nats.Msg, cmd Command) {
+	c.handlersMu.RLock()
+	handler, ok := c.handlers[cmd.Action]
+	c.handlersMu.RUnlock()
+
+	reply := CommandReply{ID: cmd.ID, Action: cmd.Action}
+	if !ok {
+		log.Printf("⚠️ Unknown command: %s", cmd.Action)
+		reply.Error = fmt.Sprintf("unknown command: %s", cmd.Action)
+	} else if data, err := handler(cmd); err != nil {
+		log.Printf("⚠️ Command %s failed: %v", cmd.Action, err)
+		reply.Error = err.Error()
+	} else {
+		log.Printf("✅ Command %s completed", cmd.Action)
+		reply.Success = true
+		reply.Data = data
+	}
+
+	c.respond(msg, reply)
+}
+
+// respond sends a reply if the command was sent with request/reply
+func (c *Client) respond(msg *nats.Msg, reply CommandReply) {
+	if msg.Reply == "" {
+		return
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		log.Printf("⚠️ Failed to encode command reply: %v", err)
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Printf("⚠️ Failed to send command reply: %v", err)
+	}
+}
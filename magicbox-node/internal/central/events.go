@@ -0,0 +1,76 @@
+package central
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// localEvent is the part of a local event the forwarder reads. Analytics
+// publish events on events.* in the platform's ingest format.
+type localEvent struct {
+	Type     string                 `json:"type"`
+	DeviceID string                 `json:"device_id"`
+	Data     map[string]interface{} `json:"data"`
+	Seq      int64                  `json:"seq"`
+}
+
+// EventFilter decides whether an event is forwarded (see
+// queue.FileQueue.FilterEvent)
+type EventFilter func(eventType, deviceID string, data map[string]interface{}) bool
+
+// EventSequencer returns the next sequence number of a device's events and the
+// run it counts within (see queue.FileQueue.NextSeq)
+type EventSequencer func(deviceID string) (seq, epoch int64)
+
+// SetEventSequencer makes forwarded events that don't carry a sequence number
+// get one, so the platform can tell when they arrive out of order. Call
+// before Start.
+func (c *Client) SetEventSequencer(sequencer EventSequencer) {
+	c.eventSequencer = sequencer
+}
+
+// SetEventFilter makes events be forwarded to central only when filter
+// allows them. Call before Start.
+func (c *Client) SetEventFilter(filter EventFilter) {
+	c.eventFilter = filter
+}
+
+// prepareEvent applies the forward path's rules to a local event and returns
+// the message to forward, or false if the event is dropped: by the event
+// filter, or for being outside the camera's region of interest. Forwarded
+// events are numbered by the event sequencer unless they already are.
+// Messages that aren't ingest-format events are forwarded as they are.
+func (c *Client) prepareEvent(data []byte) ([]byte, bool) {
+	var event localEvent
+	if err := json.Unmarshal(data, &event); err != nil || event.Type == "" {
+		return data, true
+	}
+	if c.eventFilter != nil && !c.eventFilter(event.Type, event.DeviceID, event.Data) {
+		return nil, false
+	}
+	if !c.config.CameraROI(event.DeviceID).ContainsEvent(event.Data) {
+		atomic.AddUint64(&c.eventsMasked, 1)
+		return nil, false
+	}
+	if c.eventSequencer != nil && event.Seq == 0 && event.DeviceID != "" {
+		return c.numberEvent(data, event.DeviceID), true
+	}
+	return data, true
+}
+
+// numberEvent adds the device's next sequence number to an event, leaving
+// the rest of the message as it was published
+func (c *Client) numberEvent(data []byte, deviceID string) []byte {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return data
+	}
+	seq, epoch := c.eventSequencer(deviceID)
+	msg["seq"], _ = json.Marshal(seq)
+	msg["seq_epoch"], _ = json.Marshal(epoch)
+	numbered, err := json.Marshal(msg)
+	if err != nil {
+		return data
+	}
+	return numbered
+}
@@ -0,0 +1,201 @@
+package central
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/irisdrone/magicbox-node/internal/config"
+	"github.com/irisdrone/magicbox-node/internal/natsserver"
+	"github.com/irisdrone/magicbox-node/internal/queue"
+	"github.com/irisdrone/magicbox-node/internal/roi"
+	"github.com/nats-io/nats.go"
+)
+
+// startNATS starts an embedded NATS server on a free port
+func startNATS(t *testing.T) *natsserver.EmbeddedNATS {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	cfg := natsserver.DefaultConfig()
+	cfg.Port = port
+	ns, err := natsserver.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+// forwarder is a client forwarding the events of a local NATS to a central
+// one, and a subscription to what reaches central
+type forwarder struct {
+	client   *Client
+	local    *natsserver.EmbeddedNATS
+	cfg      *config.Manager
+	received *nats.Subscription
+}
+
+func newForwarder(t *testing.T) *forwarder {
+	t.Helper()
+	local, central := startNATS(t), startNATS(t)
+
+	dir := t.TempDir()
+	cfg, err := config.NewManager(filepath.Join(dir, "config.json"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(cfg, local)
+	t.Cleanup(func() { close(c.stopChan) })
+	c.workerID = "wk-1"
+	if c.centralConn, err = nats.Connect(central.Address()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.centralConn.Close)
+
+	received, err := central.Conn().SubscribeSync("events.wk-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	central.Conn().Flush()
+	return &forwarder{client: c, local: local, cfg: cfg, received: received}
+}
+
+// publish publishes an ingest-format event on the local NATS
+func (f *forwarder) publish(t *testing.T, eventType, deviceID string, data map[string]interface{}) {
+	t.Helper()
+	msg, err := json.Marshal(map[string]interface{}{
+		"id":        eventType + "-" + deviceID,
+		"worker_id": "wk-1",
+		"device_id": deviceID,
+		"type":      eventType,
+		"data":      data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.local.Publish("events."+deviceID, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// forwarded returns the events that reached central, waiting until none
+// has arrived for a while
+func (f *forwarder) forwarded(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	f.local.Conn().Flush()
+	var events []map[string]interface{}
+	for {
+		msg, err := f.received.NextMsg(200 * time.Millisecond)
+		if err != nil {
+			return events
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestForwardedEventsAreFiltered(t *testing.T) {
+	f := newForwarder(t)
+
+	q, err := queue.NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.cfg.SetEventFilters([]config.EventFilterRule{
+		{Types: []string{"vcc"}, Devices: []string{"cam-2"}, Action: config.FilterDrop},
+		{Types: []string{"vcc"}, Action: config.FilterSample, SampleEvery: 2},
+	})
+	q.SetFilterRules(f.cfg.EventFilters)
+	f.client.SetEventFilter(func(eventType, deviceID string, data map[string]interface{}) bool {
+		return q.FilterEvent(queue.EventType(eventType), deviceID, data)
+	})
+	if err := f.client.subscribeToLocalEvents(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.publish(t, "vcc", "cam-2", nil) // dropped
+	f.publish(t, "vcc", "cam-1", nil) // 1st of the sample: forwarded
+	f.publish(t, "vcc", "cam-1", nil) // 2nd: sampled out
+	f.publish(t, "anpr", "cam-2", map[string]interface{}{"plate_number": "KA01AB1234"})
+
+	var types []string
+	for _, event := range f.forwarded(t) {
+		types = append(types, event["type"].(string)+"/"+event["device_id"].(string))
+	}
+	if len(types) != 2 || types[0] != "vcc/cam-1" || types[1] != "anpr/cam-2" {
+		t.Errorf("forwarded %v, want [vcc/cam-1 anpr/cam-2]", types)
+	}
+	if filtered := q.GetStats().Filtered; filtered != 2 {
+		t.Errorf("filtered = %d, want 2", filtered)
+	}
+}
+
+func TestForwardedEventsOutsideROIAreMasked(t *testing.T) {
+	f := newForwarder(t)
+
+	// Left half of the frame
+	if err := f.cfg.SetCameras([]config.CameraConfig{{
+		DeviceID: "cam-1",
+		ROI:      roi.Polygon{{0, 0}, {0.5, 0}, {0.5, 1}, {0, 1}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.client.subscribeToLocalEvents(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.publish(t, "vcc", "cam-1", map[string]interface{}{"bbox": []interface{}{0.1, 0.2, 0.3, 0.4}})
+	f.publish(t, "vcc", "cam-1", map[string]interface{}{"bbox": []interface{}{0.6, 0.2, 0.9, 0.4}})
+	f.publish(t, "vcc", "cam-1", map[string]interface{}{ // pixels, right half
+		"bbox": []interface{}{1400.0, 300.0, 1600.0, 500.0}, "frame_width": 1920.0, "frame_height": 1080.0,
+	})
+	f.publish(t, "vcc", "cam-1", nil) // no box: kept
+	f.publish(t, "vcc", "cam-2", map[string]interface{}{"bbox": []interface{}{0.6, 0.2, 0.9, 0.4}})
+
+	if events := f.forwarded(t); len(events) != 3 {
+		t.Errorf("forwarded %d events, want 3", len(events))
+	}
+	if masked := f.client.GetStats().EventsMasked; masked != 2 {
+		t.Errorf("eventsMasked = %d, want 2", masked)
+	}
+}
+
+func TestForwardedEventsAreNumbered(t *testing.T) {
+	f := newForwarder(t)
+
+	q, err := queue.NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.client.SetEventSequencer(q.NextSeq)
+	if err := f.client.subscribeToLocalEvents(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.publish(t, "vcc", "cam-1", nil)
+	f.publish(t, "vcc", "cam-2", nil)
+	f.publish(t, "vcc", "cam-1", map[string]interface{}{"count": 3.0})
+
+	var seqs []string
+	for _, event := range f.forwarded(t) {
+		if event["seq_epoch"] == nil {
+			t.Errorf("event of %s has no seq_epoch", event["device_id"])
+		}
+		seqs = append(seqs, fmt.Sprintf("%s/%v", event["device_id"], event["seq"]))
+	}
+	if want := []string{"cam-1/1", "cam-2/1", "cam-1/2"}; fmt.Sprint(seqs) != fmt.Sprint(want) {
+		t.Errorf("forwarded %v, want %v", seqs, want)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/irisdrone/magicbox-node/internal/roi"
 )
 
 // NodeState represents the current state of the node
@@ -42,6 +45,16 @@ type WireGuardConfig struct {
 	ServerEndpoint string `json:"serverEndpoint,omitempty"` // e.g., "vpn.example.com:51820"
 	ServerIP       string `json:"serverIp,omitempty"`       // e.g., "10.10.0.1"
 	Configured     bool   `json:"configured"`               // Has been set up
+
+	// Tunnel tuning; zero values use the WireGuard manager defaults
+	MTU                 int      `json:"mtu,omitempty"`
+	PersistentKeepalive int      `json:"persistentKeepalive,omitempty"`
+	AllowedIPs          []string `json:"allowedIps,omitempty"`
+	RouteMode           string   `json:"routeMode,omitempty"` // overlay (default) or host
+	RouteHost           string   `json:"routeHost,omitempty"`
+	ExtraAllowedIPs     []string `json:"extraAllowedIps,omitempty"`
+	DNS                 []string `json:"dns,omitempty"`           // Pushed by MagicNetwork
+	SearchDomains       []string `json:"searchDomains,omitempty"` // Pushed by MagicNetwork
 	
 	// MagicNetwork server
 	MagicNetworkURL    string `json:"magicNetworkUrl,omitempty"`    // e.g., "http://vpn.example.com:8080"
@@ -57,6 +70,65 @@ type CameraConfig struct {
 	FPS        int      `json:"fps"`
 	Resolution string   `json:"resolution"`
 	Enabled    bool     `json:"enabled"`
+
+	// Region of interest set centrally; detections outside it are masked
+	ROI roi.Polygon `json:"roi,omitempty"`
+
+	// Scheduling priority set centrally (1-100, higher runs first); 0 = default
+	Priority int `json:"priority,omitempty"`
+}
+
+// Event filter actions
+const (
+	FilterForward = "forward"
+	FilterDrop    = "drop"
+	FilterSample  = "sample"
+)
+
+// EventFilterRule is a rule pushed by the platform that decides whether an
+// event is queued. Rules are checked in order and the first one matching an
+// event decides; events matching none are queued.
+type EventFilterRule struct {
+	Types        []string `json:"types,omitempty"`        // event types; empty = any
+	Devices      []string `json:"devices,omitempty"`      // camera device IDs; empty = any
+	VehicleTypes []string `json:"vehicleTypes,omitempty"` // vehicle_type of the event; empty = any
+	Action       string   `json:"action"`                 // forward, drop or sample
+	SampleEvery  int      `json:"sampleEvery,omitempty"`  // sample: queue 1 in this many matching events
+}
+
+// vehicleTypeAliases maps the vehicle types analytics report to the names
+// filter rules use
+var vehicleTypeAliases = map[string]string{
+	"BIKE": "2W", "CAR": "4W", "TRUCK": "HMV", "HEAVY": "HMV",
+}
+
+// Matches reports whether an event falls under the rule
+func (r EventFilterRule) Matches(eventType, deviceID, vehicleType string) bool {
+	if len(r.Types) > 0 && !containsFold(r.Types, eventType) {
+		return false
+	}
+	if len(r.Devices) > 0 && !containsFold(r.Devices, deviceID) {
+		return false
+	}
+	if len(r.VehicleTypes) > 0 {
+		vehicleType = strings.ToUpper(strings.TrimSpace(vehicleType))
+		if alias, ok := vehicleTypeAliases[vehicleType]; ok {
+			vehicleType = alias
+		}
+		if vehicleType == "" || !containsFold(r.VehicleTypes, vehicleType) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
 }
 
 // NodeConfig holds the complete node configuration
@@ -78,6 +150,9 @@ type NodeConfig struct {
 	// Camera assignments
 	Cameras     []CameraConfig `json:"cameras"`
 	
+	// Event filter rules (from platform)
+	EventFilters []EventFilterRule `json:"eventFilters,omitempty"`
+	
 	// Config version (from platform)
 	ConfigVersion int `json:"configVersion"`
 	
@@ -196,6 +271,34 @@ func (m *Manager) SetCameras(cameras []CameraConfig) error {
 	return m.saveUnsafe()
 }
 
+// SetEventFilters updates the event filter rules
+func (m *Manager) SetEventFilters(rules []EventFilterRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.EventFilters = rules
+	m.config.UpdatedAt = time.Now()
+	return m.saveUnsafe()
+}
+
+// EventFilters returns the event filter rules
+func (m *Manager) EventFilters() []EventFilterRule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.EventFilters
+}
+
+// CameraROI returns the region of interest for a camera (empty = whole frame)
+func (m *Manager) CameraROI(deviceID string) roi.Polygon {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, cam := range m.config.Cameras {
+		if cam.DeviceID == deviceID {
+			return cam.ROI
+		}
+	}
+	return nil
+}
+
 // SetConfigVersion updates the config version
 func (m *Manager) SetConfigVersion(version int) error {
 	m.mu.Lock()
@@ -229,6 +332,34 @@ func (m *Manager) UpdateLastSync() error {
 	return m.saveUnsafe()
 }
 
+// Import validates and applies a complete config in one step. Nothing is
+// changed unless the whole config is valid and saved. Node identity (MAC,
+// model, creation time) is kept from the current config.
+func (m *Manager) Import(cfg NodeConfig) error {
+	if errs := cfg.Validate(); errs != nil {
+		return errs
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := m.config
+	cfg.MAC = previous.MAC
+	cfg.NodeModel = previous.NodeModel
+	cfg.CreatedAt = previous.CreatedAt
+	cfg.UpdatedAt = time.Now()
+	if cfg.Cameras == nil {
+		cfg.Cameras = []CameraConfig{}
+	}
+
+	m.config = &cfg
+	if err := m.saveUnsafe(); err != nil {
+		m.config = previous
+		return err
+	}
+	return nil
+}
+
 // Reset clears the configuration to default
 func (m *Manager) Reset() error {
 	m.mu.Lock()
@@ -273,7 +404,13 @@ func (m *Manager) saveUnsafe() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(m.configPath, data, 0644)
+
+	// Write to a temp file and rename so a crash never leaves a half-written config
+	tmpPath := m.configPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, m.configPath)
 }
 
 // createDefaultConfig creates a new default configuration
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Camera limits enforced on the config import/update paths
+const (
+	MinCameraFPS      = 1
+	MaxCameraFPS      = 30
+	MaxNodeNameLength = 64
+
+	MaxCameraPriority     = 100
+	DefaultCameraPriority = 50
+)
+
+// AllowedResolutions is the whitelist of camera resolutions the pipeline supports
+var AllowedResolutions = []string{"480p", "720p", "1080p"}
+
+// AllowReplaySources lets cameras use file:// (a local video file) or dir://
+// (a directory of JPEGs) stream URLs, replayed in a loop in place of a live
+// camera. Meant for development and demos; set from --allow-replay.
+var AllowReplaySources bool
+
+// ValidationError describes a single invalid field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is the full list of problems found in a config
+type ValidationErrors []ValidationError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationErrors) add(field, format string, args ...interface{}) {
+	*e = append(*e, ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a complete node config. It returns nil when the config is valid.
+func (c *NodeConfig) Validate() ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(c.NodeName) == "" {
+		errs.add("nodeName", "is required")
+	} else if len(c.NodeName) > MaxNodeNameLength {
+		errs.add("nodeName", "must be at most %d characters", MaxNodeNameLength)
+	}
+
+	switch c.State {
+	case StateUnconfigured, StatePending, StateApproved, StateActive, StateError:
+	default:
+		errs.add("state", "unknown state %q", c.State)
+	}
+
+	// Platform
+	if c.Platform.ServerURL != "" {
+		if u, err := url.Parse(c.Platform.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.add("platform.serverUrl", "must be an http(s) URL")
+		}
+	}
+	if c.Platform.ServerIP != "" && net.ParseIP(c.Platform.ServerIP) == nil {
+		errs.add("platform.serverIp", "must be a valid IP address")
+	}
+	if c.Platform.CentralNATS != "" {
+		if u, err := url.Parse(c.Platform.CentralNATS); err != nil || u.Scheme != "nats" || u.Host == "" {
+			errs.add("platform.centralNats", "must be a nats:// URL")
+		}
+	}
+
+	// WireGuard
+	if c.WireGuard.Enabled {
+		if c.WireGuard.AssignedIP == "" {
+			errs.add("wireguard.assignedIp", "is required when WireGuard is enabled")
+		} else if _, _, err := net.ParseCIDR(c.WireGuard.AssignedIP); err != nil {
+			errs.add("wireguard.assignedIp", "must be in CIDR form (e.g. 10.10.0.10/24)")
+		}
+		if c.WireGuard.ServerPubKey == "" {
+			errs.add("wireguard.serverPubKey", "is required when WireGuard is enabled")
+		}
+		if _, _, err := net.SplitHostPort(c.WireGuard.ServerEndpoint); err != nil {
+			errs.add("wireguard.serverEndpoint", "must be host:port")
+		}
+	}
+
+	// Cameras
+	seenIDs := make(map[string]bool)
+	seenURLs := make(map[string]bool)
+	for i, cam := range c.Cameras {
+		field := fmt.Sprintf("cameras[%d]", i)
+		errs = append(errs, cam.Validate(field)...)
+
+		if cam.DeviceID != "" {
+			if seenIDs[cam.DeviceID] {
+				errs.add(field+".deviceId", "duplicate device ID %q", cam.DeviceID)
+			}
+			seenIDs[cam.DeviceID] = true
+		}
+		if cam.RTSPUrl != "" {
+			if seenURLs[cam.RTSPUrl] {
+				errs.add(field+".rtspUrl", "duplicate RTSP URL")
+			}
+			seenURLs[cam.RTSPUrl] = true
+		}
+	}
+
+	// Event filters
+	for i, rule := range c.EventFilters {
+		field := fmt.Sprintf("eventFilters[%d]", i)
+		switch rule.Action {
+		case FilterForward, FilterDrop:
+		case FilterSample:
+			if rule.SampleEvery < 2 {
+				errs.add(field+".sampleEvery", "must be at least 2")
+			}
+		default:
+			errs.add(field+".action", "must be forward, drop or sample")
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Validate checks a single camera config. field is the prefix used in error paths.
+func (cam CameraConfig) Validate(field string) ValidationErrors {
+	var errs ValidationErrors
+
+	if strings.TrimSpace(cam.DeviceID) == "" {
+		errs.add(field+".deviceId", "is required")
+	}
+	if strings.TrimSpace(cam.Name) == "" {
+		errs.add(field+".name", "is required")
+	}
+	if err := ValidateRTSPURL(cam.RTSPUrl); err != nil {
+		errs.add(field+".rtspUrl", "%v", err)
+	}
+	if cam.FPS < MinCameraFPS || cam.FPS > MaxCameraFPS {
+		errs.add(field+".fps", "must be between %d and %d", MinCameraFPS, MaxCameraFPS)
+	}
+	if !isAllowedResolution(cam.Resolution) {
+		errs.add(field+".resolution", "must be one of %s", strings.Join(AllowedResolutions, ", "))
+	}
+	if cam.Priority < 0 || cam.Priority > MaxCameraPriority {
+		errs.add(field+".priority", "must be between 0 and %d", MaxCameraPriority)
+	}
+	if err := cam.ROI.Validate(); err != nil {
+		errs.add(field+".roi", "%v", err)
+	}
+	for j, a := range cam.Analytics {
+		if strings.TrimSpace(a) == "" {
+			errs.add(fmt.Sprintf("%s.analytics[%d]", field, j), "must not be empty")
+		} else if strings.ContainsAny(a, ".*> \t") || a == "raw" {
+			// Used as a NATS subject token for per-analytic frames (frames.<camera>.<analytic>)
+			errs.add(fmt.Sprintf("%s.analytics[%d]", field, j), "must be a single NATS subject token other than \"raw\"")
+		}
+	}
+
+	return errs
+}
+
+// ValidateRTSPURL checks that a stream URL uses the rtsp(s) scheme and has a
+// host, or is an absolute file:// or dir:// path when replay sources are allowed
+func ValidateRTSPURL(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL")
+	}
+	if u.Scheme == "file" || u.Scheme == "dir" {
+		if !AllowReplaySources {
+			return fmt.Errorf("replay sources (file://, dir://) need --allow-replay")
+		}
+		if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+			return fmt.Errorf("must be an absolute path, e.g. %s:///var/lib/magicbox/replay", u.Scheme)
+		}
+		return nil
+	}
+	if u.Scheme != "rtsp" && u.Scheme != "rtsps" {
+		return fmt.Errorf("must use the rtsp:// or rtsps:// scheme")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("must include a host")
+	}
+	return nil
+}
+
+func isAllowedResolution(res string) bool {
+	for _, r := range AllowedResolutions {
+		if r == res {
+			return true
+		}
+	}
+	return false
+}
@@ -91,6 +91,15 @@ func New(cfg DecoderConfig) (Decoder, error) {
 		cfg.JPEGQuality = 75
 	}
 
+	// Stored frames or a video file stand in for a camera
+	if scheme, path, ok := ReplaySource(cfg.RTSPURL); ok {
+		log.Printf("🎬 Creating replay decoder for %s from %s", cfg.CameraID, cfg.RTSPURL)
+		if scheme == ReplaySchemeDir {
+			return NewReplayDecoder(cfg, path)
+		}
+		return NewFFmpegDecoder(cfg, hwInfo)
+	}
+
 	log.Printf("🎬 Creating decoder for %s using %s backend (%s)", 
 		cfg.CameraID, hwInfo.Backend, hwInfo.Type)
 
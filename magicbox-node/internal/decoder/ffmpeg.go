@@ -166,11 +166,20 @@ func (d *FFmpegDecoder) buildFFmpegArgs() []string {
 		log.Printf("🚀 Using FFmpeg hardware acceleration: %v", hwArgs)
 	}
 
-	// Input options
-	args = append(args,
-		"-rtsp_transport", "tcp",
-		"-i", d.cfg.RTSPURL,
-	)
+	// Input options. A replayed video file is read in real time and looped
+	// forever, so it behaves like a live camera.
+	if scheme, path, ok := ReplaySource(d.cfg.RTSPURL); ok && scheme == ReplaySchemeFile {
+		args = append(args,
+			"-re",
+			"-stream_loop", "-1",
+			"-i", path,
+		)
+	} else {
+		args = append(args,
+			"-rtsp_transport", "tcp",
+			"-i", d.cfg.RTSPURL,
+		)
+	}
 
 	// Video filters
 	vf := fmt.Sprintf("fps=%d", d.cfg.FPS)
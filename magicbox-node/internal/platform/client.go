@@ -2,6 +2,7 @@ package platform
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/irisdrone/magicbox-node/internal/config"
 	"github.com/irisdrone/magicbox-node/internal/queue"
+	"github.com/irisdrone/magicbox-node/internal/roi"
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
 )
@@ -27,6 +29,8 @@ type Client struct {
 	stopChan    chan struct{}
 	wg          sync.WaitGroup
 	mu          sync.Mutex
+	startup     *BuildInfo // Reported once on start; nil = no startup report
+	configError string     // Why the latest config couldn't be applied; guarded by mu
 }
 
 // RegistrationRequest is sent when registering with a token
@@ -72,11 +76,17 @@ type ApprovalStatusResponse struct {
 
 // HeartbeatRequest sent periodically
 type HeartbeatRequest struct {
-	Status        string                 `json:"status"`
-	Resources     map[string]interface{} `json:"resources"`
-	CameraStatus  []CameraStatus         `json:"cameraStatus"`
-	QueueStats    queue.QueueStats       `json:"queueStats"`
-	ConfigVersion int                    `json:"configVersion"`
+	Status       string                 `json:"status"`
+	Resources    map[string]interface{} `json:"resources"`
+	CameraStatus []CameraStatus         `json:"cameraStatus"`
+	QueueStats   queue.QueueStats       `json:"queueStats"`
+
+	// Config version the node runs, and why it couldn't apply a newer one
+	ConfigVersion int    `json:"config_version_applied"`
+	ConfigError   string `json:"config_error,omitempty"`
+
+	// Sent so the platform can detect a wrong clock on the box
+	CurrentTime time.Time `json:"current_time"`
 }
 
 // CameraStatus for each camera
@@ -89,8 +99,92 @@ type CameraStatus struct {
 
 // WorkerConfig from platform
 type WorkerConfig struct {
-	ConfigVersion int                   `json:"configVersion"`
-	Cameras       []config.CameraConfig `json:"cameras"`
+	ConfigVersion int
+	Cameras       []config.CameraConfig
+	EventFilters  []config.EventFilterRule
+}
+
+// workerConfigResponse is the platform's worker config as it's sent
+// (GET /api/workers/:id/config)
+type workerConfigResponse struct {
+	ConfigVersion int                      `json:"config_version"`
+	Cameras       []platformCamera         `json:"cameras"`
+	EventFilters  []config.EventFilterRule `json:"event_filters"`
+}
+
+// platformCamera is a camera assignment in the platform's worker config
+type platformCamera struct {
+	DeviceID   string      `json:"device_id"`
+	Name       *string     `json:"name"`
+	RTSPUrl    *string     `json:"rtsp_url"`
+	Analytics  []string    `json:"analytics"`
+	FPS        int         `json:"fps"`
+	Resolution string      `json:"resolution"`
+	ROI        roi.Polygon `json:"roi"`
+	Priority   int         `json:"priority"`
+}
+
+// cameraConfig converts an assignment to local camera config. Only active
+// assignments are sent, so the camera is enabled.
+func (p platformCamera) cameraConfig() config.CameraConfig {
+	cam := config.CameraConfig{
+		DeviceID:   p.DeviceID,
+		Analytics:  p.Analytics,
+		FPS:        p.FPS,
+		Resolution: p.Resolution,
+		Enabled:    true,
+		ROI:        p.ROI,
+		Priority:   p.Priority,
+	}
+	if p.Name != nil {
+		cam.Name = *p.Name
+	}
+	if p.RTSPUrl != nil {
+		cam.RTSPUrl = *p.RTSPUrl
+	}
+	return cam
+}
+
+// IngestEvent is a queued event as the platform's ingest endpoint reads it
+type IngestEvent struct {
+	ID        string                 `json:"id"`
+	Timestamp time.Time              `json:"timestamp"`
+	WorkerID  string                 `json:"worker_id"`
+	DeviceID  string                 `json:"device_id"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data"`
+	Images    []string               `json:"images,omitempty"` // Image filenames
+	Seq       int64                  `json:"seq,omitempty"`
+	SeqEpoch  int64                  `json:"seq_epoch,omitempty"`
+}
+
+// IngestBatchResponse is the platform's reply to an event batch
+type IngestBatchResponse struct {
+	Processed    int      `json:"processed"`
+	Dropped      int      `json:"dropped"`      // Throttled by the platform's rate cap
+	DeadLettered int      `json:"deadLettered"` // Failed, but kept by the platform for reprocessing
+	Total        int      `json:"total"`
+	FailedIDs    []string `json:"failedIds"`  // Failed and not kept; must be resent
+	DroppedIDs   []string `json:"droppedIds"` // Throttled; must be resent
+}
+
+// newIngestEvent converts a queued event to the ingest wire format
+func newIngestEvent(workerID string, event *queue.Event) IngestEvent {
+	images := make([]string, 0, len(event.Images))
+	for _, img := range event.Images {
+		images = append(images, filepath.Base(img))
+	}
+	return IngestEvent{
+		ID:        event.ID,
+		Timestamp: event.Timestamp,
+		WorkerID:  workerID,
+		DeviceID:  event.DeviceID,
+		Type:      string(event.Type),
+		Data:      event.Data,
+		Images:    images,
+		Seq:       event.Seq,
+		SeqEpoch:  event.SeqEpoch,
+	}
 }
 
 // NewClient creates a new platform client
@@ -114,6 +208,11 @@ func (c *Client) Start() {
 	
 	// Config sync loop
 	go c.configSyncLoop()
+
+	if c.startup != nil {
+		c.wg.Add(1)
+		go c.startupReportLoop()
+	}
 }
 
 // Stop halts background tasks
@@ -301,7 +400,7 @@ func (c *Client) FetchConfig() (*WorkerConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+cfg.Platform.AuthToken)
+	req.Header.Set("X-Auth-Token", cfg.Platform.AuthToken)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -314,14 +413,140 @@ func (c *Client) FetchConfig() (*WorkerConfig, error) {
 		return nil, fmt.Errorf("failed to fetch config: %s", string(respBody))
 	}
 
-	var workerCfg WorkerConfig
-	if err := json.NewDecoder(resp.Body).Decode(&workerCfg); err != nil {
+	var body workerConfigResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
 
+	workerCfg := WorkerConfig{
+		ConfigVersion: body.ConfigVersion,
+		Cameras:       make([]config.CameraConfig, 0, len(body.Cameras)),
+		EventFilters:  body.EventFilters,
+	}
+	for _, cam := range body.Cameras {
+		workerCfg.Cameras = append(workerCfg.Cameras, cam.cameraConfig())
+	}
 	return &workerCfg, nil
 }
 
+// RotateAuthToken fetches the token issued by a rotate_token command and
+// switches to it. The token only travels over this authenticated request,
+// never over NATS.
+func (c *Client) RotateAuthToken() error {
+	cfg := c.config.Get()
+
+	if cfg.Platform.WorkerID == "" || cfg.Platform.AuthToken == "" {
+		return fmt.Errorf("not registered with platform")
+	}
+
+	req, err := http.NewRequest(
+		"GET",
+		cfg.Platform.ServerURL+"/api/workers/"+cfg.Platform.WorkerID+"/token",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", cfg.Platform.AuthToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to fetch new token: %s", string(respBody))
+	}
+
+	var result struct {
+		AuthToken string `json:"authToken"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode new token: %w", err)
+	}
+	if result.AuthToken == "" {
+		return fmt.Errorf("platform returned no token")
+	}
+
+	platCfg := cfg.Platform
+	platCfg.AuthToken = result.AuthToken
+	if err := c.config.SetPlatformConfig(platCfg); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	return nil
+}
+
+// ApplyConfig saves a config fetched from the platform and reports to the
+// platform the version the node now runs, or why it couldn't apply it
+func (c *Client) ApplyConfig(workerCfg *WorkerConfig) error {
+	err := c.config.SetCameras(workerCfg.Cameras)
+	if err != nil {
+		err = fmt.Errorf("failed to save cameras: %w", err)
+	} else if err = c.config.SetEventFilters(workerCfg.EventFilters); err != nil {
+		err = fmt.Errorf("failed to save event filters: %w", err)
+	} else if err = c.config.SetConfigVersion(workerCfg.ConfigVersion); err != nil {
+		err = fmt.Errorf("failed to save config version: %w", err)
+	} else {
+		c.config.UpdateLastSync()
+	}
+
+	configError := ""
+	if err != nil {
+		configError = err.Error()
+	}
+	c.mu.Lock()
+	c.configError = configError
+	c.mu.Unlock()
+
+	if reportErr := c.ReportConfigApplied(c.config.Get().ConfigVersion, configError); reportErr != nil {
+		log.Printf("⚠️ Failed to report applied config: %v", reportErr)
+	}
+	return err
+}
+
+// ReportConfigApplied tells the platform which config version the node runs
+// and, if the latest couldn't be applied, why
+func (c *Client) ReportConfigApplied(version int, configError string) error {
+	cfg := c.config.Get()
+
+	if cfg.Platform.WorkerID == "" || cfg.Platform.AuthToken == "" {
+		return fmt.Errorf("not registered with platform")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"config_version": version,
+		"error":          configError,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		cfg.Platform.ServerURL+"/api/workers/"+cfg.Platform.WorkerID+"/config/applied",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Auth-Token", cfg.Platform.AuthToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to report applied config: %s", string(respBody))
+	}
+	return nil
+}
+
 // SendHeartbeat sends a heartbeat to the platform
 func (c *Client) SendHeartbeat() error {
 	cfg := c.config.Get()
@@ -330,12 +555,18 @@ func (c *Client) SendHeartbeat() error {
 		return nil
 	}
 
+	c.mu.Lock()
+	configError := c.configError
+	c.mu.Unlock()
+
 	hb := HeartbeatRequest{
 		Status:        string(cfg.State),
 		Resources:     c.getResources(),
 		CameraStatus:  c.getCameraStatus(),
 		QueueStats:    c.queue.GetStats(),
 		ConfigVersion: cfg.ConfigVersion,
+		ConfigError:   configError,
+		CurrentTime:   time.Now(),
 	}
 
 	body, err := json.Marshal(hb)
@@ -352,7 +583,7 @@ func (c *Client) SendHeartbeat() error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+cfg.Platform.AuthToken)
+	req.Header.Set("X-Auth-Token", cfg.Platform.AuthToken)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -383,7 +614,7 @@ func (c *Client) SendEvent(event *queue.Event) error {
 		writer := multipart.NewWriter(&body)
 		
 		// Add event data
-		eventData, _ := json.Marshal(event)
+		eventData, _ := json.Marshal(newIngestEvent(cfg.Platform.WorkerID, event))
 		if err := writer.WriteField("event", string(eventData)); err != nil {
 			return err
 		}
@@ -406,7 +637,7 @@ func (c *Client) SendEvent(event *queue.Event) error {
 		writer.Close()
 		contentType = writer.FormDataContentType()
 	} else {
-		eventData, _ := json.Marshal(event)
+		eventData, _ := json.Marshal(newIngestEvent(cfg.Platform.WorkerID, event))
 		body.Write(eventData)
 		contentType = "application/json"
 	}
@@ -437,6 +668,81 @@ func (c *Client) SendEvent(event *queue.Event) error {
 	return nil
 }
 
+// SendEvents uploads image-less events in a single gzip-compressed request
+// (used by the queue processor in batched upload mode). It returns the IDs of
+// the events the platform failed or throttled, which are to be resent.
+func (c *Client) SendEvents(events []*queue.Event) ([]string, error) {
+	cfg := c.config.Get()
+
+	if cfg.Platform.WorkerID == "" || cfg.Platform.AuthToken == "" {
+		return nil, fmt.Errorf("not registered with platform")
+	}
+
+	batch := make([]IngestEvent, 0, len(events))
+	for _, event := range events {
+		batch = append(batch, newIngestEvent(cfg.Platform.WorkerID, event))
+	}
+	payload, err := json.Marshal(map[string]interface{}{"events": batch})
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	if _, err := gz.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		cfg.Platform.ServerURL+"/api/events/ingest",
+		&body,
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer "+cfg.Platform.AuthToken)
+	req.Header.Set("X-Worker-ID", cfg.Platform.WorkerID)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("event batch rejected: %s", string(respBody))
+	}
+
+	var result IngestBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unreadable event batch response: %w", err)
+	}
+	if result.Total != len(events) {
+		return nil, fmt.Errorf("platform took %d of %d events in the batch", result.Total, len(events))
+	}
+	if result.DeadLettered > 0 {
+		log.Printf("⚠️ Platform dead-lettered %d of %d events in the batch", result.DeadLettered, len(events))
+	}
+
+	failed := result.Total - result.Processed - result.Dropped - result.DeadLettered
+	if len(result.FailedIDs) != failed || len(result.DroppedIDs) != result.Dropped {
+		// The platform didn't say which ones it didn't take; resend the whole batch
+		return nil, fmt.Errorf("platform didn't take %d of %d events in the batch",
+			failed+result.Dropped, len(events))
+	}
+	if result.Dropped > 0 {
+		log.Printf("⚠️ Platform throttled %d of %d events in the batch", result.Dropped, len(events))
+	}
+	return append(result.FailedIDs, result.DroppedIDs...), nil
+}
+
 // Disconnect disconnects from the platform
 func (c *Client) Disconnect() error {
 	if err := c.config.Reset(); err != nil {
@@ -501,9 +807,9 @@ func (c *Client) configSyncLoop() {
 				// Check if config has changed
 				if workerCfg.ConfigVersion > cfg.ConfigVersion {
 					log.Printf("📥 New config version %d (was %d)", workerCfg.ConfigVersion, cfg.ConfigVersion)
-					c.config.SetCameras(workerCfg.Cameras)
-					c.config.SetConfigVersion(workerCfg.ConfigVersion)
-					c.config.UpdateLastSync()
+					if err := c.ApplyConfig(workerCfg); err != nil {
+						log.Printf("⚠️ Failed to apply config version %d: %v", workerCfg.ConfigVersion, err)
+					}
 				}
 			}
 		}
@@ -635,6 +941,19 @@ func getJetsonGPUUsage() (float64, error) {
 	return load / 10, nil // Jetson reports in 0.1% units
 }
 
+// SampleHealth returns the CPU usage in percent and the CPU temperature in
+// degrees; a reading that isn't available is 0
+func SampleHealth() (float64, float64) {
+	var cpuPercent, temp float64
+	if percent, err := cpu.Percent(0, false); err == nil && len(percent) > 0 {
+		cpuPercent = percent[0]
+	}
+	if t, err := getTemperature(); err == nil {
+		temp = t
+	}
+	return cpuPercent, temp
+}
+
 // getTemperature reads CPU temperature
 func getTemperature() (float64, error) {
 	// Try thermal zone
@@ -769,10 +1088,12 @@ type WireGuardSetupRequest struct {
 
 // WireGuardSetupResponse from platform
 type WireGuardSetupResponse struct {
-	AssignedIP     string `json:"assigned_ip"`
-	ServerPubKey   string `json:"server_pubkey"`
-	ServerEndpoint string `json:"server_endpoint"`
-	ServerIP       string `json:"server_ip"`
+	AssignedIP     string   `json:"assigned_ip"`
+	ServerPubKey   string   `json:"server_pubkey"`
+	ServerEndpoint string   `json:"server_endpoint"`
+	ServerIP       string   `json:"server_ip"`
+	DNS            []string `json:"dns,omitempty"`
+	SearchDomains  []string `json:"search_domains,omitempty"`
 }
 
 // SetupWireGuard requests WireGuard configuration from the platform
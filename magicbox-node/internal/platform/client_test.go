@@ -0,0 +1,158 @@
+package platform
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/irisdrone/magicbox-node/internal/config"
+	"github.com/irisdrone/magicbox-node/internal/queue"
+)
+
+// newTestClient returns a client registered with a platform that answers
+// event batches with reply
+func newTestClient(t *testing.T, reply IngestBatchResponse) *Client {
+	t.Helper()
+	return newTestClientWith(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(reply)
+	}))
+}
+
+// newTestClientWith returns a client registered, with token "token", with a
+// platform served by handler
+func newTestClientWith(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	cfg, err := config.NewManager(filepath.Join(dir, "config.json"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.SetPlatformConfig(config.PlatformConfig{ServerURL: srv.URL, WorkerID: "wk-1", AuthToken: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(cfg, nil)
+}
+
+func TestSendEventsResendsThrottledEvents(t *testing.T) {
+	events := []*queue.Event{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	c := newTestClient(t, IngestBatchResponse{
+		Processed:  1,
+		Dropped:    2,
+		Total:      4,
+		FailedIDs:  []string{"d"},
+		DroppedIDs: []string{"b", "c"},
+	})
+
+	resend, err := c.SendEvents(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(resend)
+	if got := strings.Join(resend, ","); got != "b,c,d" {
+		t.Errorf("resend = %s, want b,c,d", got)
+	}
+}
+
+func TestSendEventsFailsBatchWithoutDroppedIDs(t *testing.T) {
+	// A platform that throttles without saying which events it throttled
+	c := newTestClient(t, IngestBatchResponse{Processed: 1, Dropped: 1, Total: 2})
+
+	if _, err := c.SendEvents([]*queue.Event{{ID: "a"}, {ID: "b"}}); err == nil {
+		t.Fatal("SendEvents succeeded, want the whole batch resent")
+	}
+}
+
+func TestRotateAuthToken(t *testing.T) {
+	c := newTestClientWith(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/workers/wk-1/token" || r.Header.Get("X-Auth-Token") != "token" {
+			http.Error(w, `{"error": "Invalid auth token"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"authToken": "rotated"})
+	}))
+
+	if err := c.RotateAuthToken(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.config.Get().Platform.AuthToken; got != "rotated" {
+		t.Errorf("token = %q, want the rotated one", got)
+	}
+}
+
+func TestFetchConfigReadsPlatformConfig(t *testing.T) {
+	c := newTestClientWith(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/workers/wk-1/config" || r.Header.Get("X-Auth-Token") != "token" {
+			http.Error(w, `{"error": "Invalid auth token"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{
+			"worker_id": "wk-1",
+			"config_version": 7,
+			"cameras": [{"device_id": "cam-1", "name": "Gate", "rtsp_url": "rtsp://cam-1/stream", "analytics": ["anpr"], "fps": 15,
+				"resolution": "720p", "roi": [[0, 0], [1, 0], [1, 1]], "priority": 60}],
+			"event_filters": [{"types": ["vcc"], "action": "drop"}]
+		}`))
+	}))
+
+	workerCfg, err := c.FetchConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workerCfg.ConfigVersion != 7 {
+		t.Errorf("config version = %d, want 7", workerCfg.ConfigVersion)
+	}
+	if len(workerCfg.Cameras) != 1 {
+		t.Fatalf("cameras = %+v, want 1", workerCfg.Cameras)
+	}
+	cam := workerCfg.Cameras[0]
+	if cam.DeviceID != "cam-1" || cam.Name != "Gate" || cam.RTSPUrl != "rtsp://cam-1/stream" || !cam.Enabled || len(cam.ROI) != 3 || cam.Priority != 60 {
+		t.Errorf("camera = %+v", cam)
+	}
+	if len(workerCfg.EventFilters) != 1 || workerCfg.EventFilters[0].Action != "drop" {
+		t.Errorf("event filters = %+v", workerCfg.EventFilters)
+	}
+}
+
+func TestApplyConfigReportsAppliedVersion(t *testing.T) {
+	var reported map[string]interface{}
+	c := newTestClientWith(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/workers/wk-1/config/applied" || r.Header.Get("X-Auth-Token") != "token" {
+			http.Error(w, `{"error": "Invalid auth token"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&reported)
+		w.Write([]byte(`{"success": true}`))
+	}))
+
+	workerCfg := &WorkerConfig{
+		ConfigVersion: 7,
+		Cameras:       []config.CameraConfig{{DeviceID: "cam-1", Name: "Gate", Enabled: true}},
+	}
+	if err := c.ApplyConfig(workerCfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.config.Get().ConfigVersion; got != 7 {
+		t.Errorf("config version = %d, want 7", got)
+	}
+	if reported == nil || reported["config_version"] != float64(7) || reported["error"] != "" {
+		t.Errorf("reported = %v, want version 7 applied without error", reported)
+	}
+
+	// The heartbeat carries the applied version under the platform's name
+	body, err := json.Marshal(HeartbeatRequest{ConfigVersion: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hb map[string]interface{}
+	json.Unmarshal(body, &hb)
+	if hb["config_version_applied"] != float64(7) {
+		t.Errorf("heartbeat = %s, want config_version_applied 7", body)
+	}
+}
@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/irisdrone/magicbox-node/internal/config"
+)
+
+// startupRetryInterval is how often an unsent startup report is retried
+const startupRetryInterval = 30 * time.Second
+
+// errStartupUnsupported means the platform doesn't take startup reports
+var errStartupUnsupported = errors.New("platform does not accept startup reports")
+
+// BuildInfo describes the running build, sent in the startup report
+type BuildInfo struct {
+	Version        string
+	BuildTime      string
+	DecoderBackend string
+	DecoderAccel   string
+	StartedAt      time.Time
+}
+
+// StartupReport is sent once when the node starts
+type StartupReport struct {
+	Version        string    `json:"version"`
+	BuildTime      string    `json:"build_time"`
+	ConfigVersion  int       `json:"config_version"`
+	CameraCount    int       `json:"camera_count"`
+	DecoderBackend string    `json:"decoder_backend"`
+	DecoderAccel   string    `json:"decoder_accel"`
+	StartedAt      time.Time `json:"started_at"`
+}
+
+// SetStartupReport makes Start report info to the platform once, as soon as
+// the node is registered. Call before Start.
+func (c *Client) SetStartupReport(info BuildInfo) {
+	c.startup = &info
+}
+
+// SendStartupReport reports the node's start with its current config
+func (c *Client) SendStartupReport(info BuildInfo) error {
+	cfg := c.config.Get()
+
+	if cfg.Platform.WorkerID == "" || cfg.Platform.AuthToken == "" {
+		return fmt.Errorf("not registered with platform")
+	}
+
+	report := StartupReport{
+		Version:        info.Version,
+		BuildTime:      info.BuildTime,
+		ConfigVersion:  cfg.ConfigVersion,
+		CameraCount:    len(cfg.Cameras),
+		DecoderBackend: info.DecoderBackend,
+		DecoderAccel:   info.DecoderAccel,
+		StartedAt:      info.StartedAt,
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(
+		"POST",
+		cfg.Platform.ServerURL+"/api/workers/"+cfg.Platform.WorkerID+"/startup",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Auth-Token", cfg.Platform.AuthToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("startup report failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errStartupUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("startup report failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// startupReportLoop sends the startup report once the node is registered,
+// retrying until it's delivered
+func (c *Client) startupReportLoop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(startupRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		cfg := c.config.Get()
+		if cfg.State == config.StateApproved || cfg.State == config.StateActive {
+			err := c.SendStartupReport(*c.startup)
+			if err == nil {
+				log.Printf("🔄 Reported startup of v%s to platform", c.startup.Version)
+				return
+			}
+			if errors.Is(err, errStartupUnsupported) {
+				log.Printf("ℹ️ Skipping startup report: %v", err)
+				return
+			}
+			log.Printf("⚠️ Startup report failed: %v", err)
+		}
+
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
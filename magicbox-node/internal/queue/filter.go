@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/irisdrone/magicbox-node/internal/config"
+)
+
+// ErrFiltered is returned by Enqueue when an event filter rule drops the event
+var ErrFiltered = errors.New("event dropped by filter rule")
+
+// eventFilter applies the platform's filter rules to events before they're
+// queued, counting matches of sampling rules
+type eventFilter struct {
+	mu      sync.Mutex
+	rules   func() []config.EventFilterRule
+	sampled map[int]int // matching events seen per sampling rule, by index
+}
+
+// SetFilterRules makes Enqueue apply the rules returned by source, read on
+// every event so rule updates apply immediately
+func (q *FileQueue) SetFilterRules(source func() []config.EventFilterRule) {
+	q.filter.mu.Lock()
+	defer q.filter.mu.Unlock()
+	q.filter.rules = source
+	q.filter.sampled = make(map[int]int)
+}
+
+// allows reports whether an event should be queued: the first matching rule
+// decides, and events matching none are queued
+func (f *eventFilter) allows(eventType EventType, deviceID string, data map[string]interface{}) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rules == nil {
+		return true
+	}
+
+	vehicleType, _ := data["vehicle_type"].(string)
+	for i, rule := range f.rules() {
+		if !rule.Matches(string(eventType), deviceID, vehicleType) {
+			continue
+		}
+		switch rule.Action {
+		case config.FilterDrop:
+			return false
+		case config.FilterSample:
+			seen := f.sampled[i]
+			f.sampled[i] = seen + 1
+			return rule.SampleEvery <= 1 || seen%rule.SampleEvery == 0
+		default:
+			return true
+		}
+	}
+	return true
+}
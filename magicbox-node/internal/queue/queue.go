@@ -34,14 +34,19 @@ const (
 	StatusFailed     EventStatus = "failed"
 )
 
-// Event represents a queued event
+// Event represents a queued event. Its ID is assigned once at enqueue and
+// names the event's directory, so every send of the event - retries, manual
+// retries of failed events, resends after a restart - carries the same ID and
+// the platform can recognise a resend by worker and event ID.
 type Event struct {
 	ID        string                 `json:"id"`
 	Type      EventType              `json:"type"`
 	DeviceID  string                 `json:"deviceId"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
-	Images    []string               `json:"images,omitempty"` // Paths to image files
+	Images    []string               `json:"images,omitempty"`    // Paths to image files
+	Seq       int64                  `json:"seq,omitempty"`       // Per-device sequence number, from 1
+	SeqEpoch  int64                  `json:"seq_epoch,omitempty"` // Start of the run Seq counts within (unix ms)
 	Status    EventStatus            `json:"status"`
 	Retries   int                    `json:"retries"`
 	Error     string                 `json:"error,omitempty"`
@@ -54,6 +59,7 @@ type QueueStats struct {
 	Pending   int `json:"pending"`
 	Failed    int `json:"failed"`
 	Processed int `json:"processed"`
+	Filtered  int `json:"filtered"` // dropped by event filter rules since startup
 }
 
 // EventSender interface for sending events
@@ -61,6 +67,13 @@ type EventSender interface {
 	SendEvent(event *Event) error
 }
 
+// BatchEventSender is implemented by senders that can upload several
+// image-less events in one request. It returns the IDs of events the
+// platform didn't take (failed or throttled); an error fails the whole batch.
+type BatchEventSender interface {
+	SendEvents(events []*Event) (failedIDs []string, err error)
+}
+
 // FileQueue implements a file-based event queue
 type FileQueue struct {
 	baseDir     string
@@ -76,6 +89,17 @@ type FileQueue struct {
 	retryDelay  time.Duration
 	batchSize   int
 	processRate time.Duration
+
+	// uploadBatchSize is the max number of image-less events sent in one
+	// request when the sender supports it (0 = send events one at a time)
+	uploadBatchSize int
+
+	filter eventFilter
+
+	// Per-device sequence numbers, so the platform can tell when retries
+	// deliver events out of order. They restart with each run.
+	seqEpoch int64
+	seqs     map[string]int64
 }
 
 // NewFileQueue creates a new file-based queue
@@ -90,6 +114,8 @@ func NewFileQueue(baseDir string) (*FileQueue, error) {
 		retryDelay:  5 * time.Second,
 		batchSize:   10,
 		processRate: 1 * time.Second,
+		seqEpoch:    time.Now().UnixMilli(),
+		seqs:        make(map[string]int64),
 	}
 
 	// Create directories
@@ -110,8 +136,46 @@ func (q *FileQueue) SetSender(sender EventSender) {
 	q.sender = sender
 }
 
-// Enqueue adds an event to the queue
+// SetUploadBatchSize enables batched upload of image-less events, up to size
+// events per request (0 disables). Events with images are always sent singly.
+func (q *FileQueue) SetUploadBatchSize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	q.uploadBatchSize = size
+}
+
+// FilterEvent reports whether an event passes the filter rules, counting it
+// as filtered if it doesn't. Enqueue applies it; events that reach central
+// without being queued go through it too.
+func (q *FileQueue) FilterEvent(eventType EventType, deviceID string, data map[string]interface{}) bool {
+	if q.filter.allows(eventType, deviceID, data) {
+		return true
+	}
+	q.mu.Lock()
+	q.stats.Filtered++
+	q.mu.Unlock()
+	return false
+}
+
+// NextSeq takes the next sequence number of a device's events, and the run it
+// counts within. Enqueue numbers events with it; events that reach central
+// without being queued are numbered from the same counter.
+func (q *FileQueue) NextSeq(deviceID string) (seq, epoch int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seqs[deviceID]++
+	return q.seqs[deviceID], q.seqEpoch
+}
+
+// Enqueue adds an event to the queue. Events dropped by the filter rules
+// return ErrFiltered.
 func (q *FileQueue) Enqueue(eventType EventType, deviceID string, data map[string]interface{}, images []string) (*Event, error) {
+	if !q.FilterEvent(eventType, deviceID, data) {
+		return nil, ErrFiltered
+	}
+
+	seq, epoch := q.NextSeq(deviceID)
 	event := &Event{
 		ID:        uuid.New().String(),
 		Type:      eventType,
@@ -119,6 +183,8 @@ func (q *FileQueue) Enqueue(eventType EventType, deviceID string, data map[strin
 		Timestamp: time.Now(),
 		Data:      data,
 		Images:    images,
+		Seq:       seq,
+		SeqEpoch:  epoch,
 		Status:    StatusPending,
 		Retries:   0,
 		CreatedAt: time.Now(),
@@ -294,6 +360,23 @@ func (q *FileQueue) processBatch() {
 		return events[i].CreatedAt.Before(events[j].CreatedAt)
 	})
 
+	// Image-less events go up together when the sender can batch them
+	batchSender, canBatch := q.sender.(BatchEventSender)
+	if canBatch && q.uploadBatchSize > 0 {
+		var batch, single []*Event
+		for _, event := range events {
+			if len(event.Images) == 0 && len(batch) < q.uploadBatchSize {
+				batch = append(batch, event)
+			} else if len(event.Images) > 0 {
+				single = append(single, event)
+			}
+		}
+		if len(batch) > 0 {
+			q.processEvents(batchSender, batch)
+		}
+		events = single
+	}
+
 	// Process batch
 	processed := 0
 	for _, event := range events {
@@ -308,6 +391,46 @@ func (q *FileQueue) processBatch() {
 	}
 }
 
+// processEvents sends image-less events in a single request. Events the
+// platform reports as failed or throttled are retried; a failed request
+// retries them all.
+func (q *FileQueue) processEvents(sender BatchEventSender, events []*Event) {
+	for _, event := range events {
+		event.Status = StatusProcessing
+		event.UpdatedAt = time.Now()
+		q.saveEvent(event, q.pendingDir)
+	}
+
+	failedIDs, err := sender.SendEvents(events)
+	failed := make(map[string]bool, len(failedIDs))
+	for _, id := range failedIDs {
+		failed[id] = true
+	}
+	for _, event := range events {
+		var markErr error
+		switch {
+		case err != nil:
+			markErr = q.markFailed(event, err)
+		case failed[event.ID]:
+			markErr = q.markFailed(event, fmt.Errorf("platform didn't take the event"))
+		default:
+			markErr = q.markSent(event)
+		}
+		if markErr != nil {
+			log.Printf("⚠️ Event %s failed: %v", event.ID[:8], markErr)
+		}
+	}
+
+	switch {
+	case err != nil:
+		log.Printf("⚠️ Event batch of %d failed: %v", len(events), err)
+	case len(failedIDs) > 0:
+		log.Printf("⚠️ Event batch sent: the platform didn't take %d of %d events", len(failedIDs), len(events))
+	default:
+		log.Printf("✅ Event batch sent: %d events", len(events))
+	}
+}
+
 // processEvent attempts to send a single event
 func (q *FileQueue) processEvent(event *Event) error {
 	// Update status to processing
@@ -319,27 +442,42 @@ func (q *FileQueue) processEvent(event *Event) error {
 	err := q.sender.SendEvent(event)
 	
 	if err == nil {
-		// Success - move to sent
-		event.Status = StatusSent
-		event.UpdatedAt = time.Now()
-		
-		if err := q.saveEvent(event, q.sentDir); err != nil {
+		if err := q.markSent(event); err != nil {
 			return err
 		}
-		if err := q.deleteEvent(q.pendingDir, event.ID); err != nil {
-			return err
-		}
-
-		q.mu.Lock()
-		q.stats.Pending--
-		q.stats.Processed++
-		q.mu.Unlock()
-
 		log.Printf("✅ Event sent: %s (%s)", event.ID[:8], event.Type)
 		return nil
 	}
 
-	// Failed
+	if markErr := q.markFailed(event, err); markErr != nil {
+		return markErr
+	}
+	return err
+}
+
+// markSent moves a delivered event from pending to sent
+func (q *FileQueue) markSent(event *Event) error {
+	event.Status = StatusSent
+	event.UpdatedAt = time.Now()
+	
+	if err := q.saveEvent(event, q.sentDir); err != nil {
+		return err
+	}
+	if err := q.deleteEvent(q.pendingDir, event.ID); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	q.stats.Pending--
+	q.stats.Processed++
+	q.mu.Unlock()
+
+	return nil
+}
+
+// markFailed records a failed delivery attempt, moving the event to failed
+// once it has used up its retries
+func (q *FileQueue) markFailed(event *Event, err error) error {
 	event.Retries++
 	event.Error = err.Error()
 	event.UpdatedAt = time.Now()
@@ -367,7 +505,7 @@ func (q *FileQueue) processEvent(event *Event) error {
 		log.Printf("🔄 Event retry %d/%d: %s", event.Retries, q.maxRetries, event.ID[:8])
 	}
 
-	return err
+	return nil
 }
 
 // saveEvent saves an event to a directory
@@ -397,6 +535,11 @@ func (q *FileQueue) loadEvent(dir, eventID string) (*Event, error) {
 	if err := json.Unmarshal(data, &event); err != nil {
 		return nil, err
 	}
+	// The directory is named after the ID it was given at enqueue; keep it
+	// for files written without one, rather than sending them unidentified
+	if event.ID == "" {
+		event.ID = eventID
+	}
 
 	return &event, nil
 }
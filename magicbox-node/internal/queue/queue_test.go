@@ -0,0 +1,155 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+// stubSender fails the first failures sends, then accepts, recording the IDs
+// it was given
+type stubSender struct {
+	failures int
+	sent     []string
+}
+
+func (s *stubSender) SendEvent(event *Event) error {
+	s.sent = append(s.sent, event.ID)
+	if len(s.sent) <= s.failures {
+		return errors.New("platform unreachable")
+	}
+	return nil
+}
+
+func TestRetriedEventKeepsID(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &stubSender{failures: 1}
+	q.SetSender(sender)
+
+	event, err := q.Enqueue(EventTypeANPR, "cam-1", map[string]interface{}{"plate_number": "KA01AB1234"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first send fails and leaves the event pending for a retry
+	q.processBatch()
+	pending, err := q.GetPendingEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != event.ID || pending[0].Retries != 1 {
+		t.Fatalf("after failed send: pending = %+v, want event %s with 1 retry", pending, event.ID)
+	}
+
+	// A restart reloads the event from disk; the retry goes out under the same ID
+	q, err = NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetSender(sender)
+	q.processBatch()
+
+	if len(sender.sent) != 2 {
+		t.Fatalf("sent %d times, want 2", len(sender.sent))
+	}
+	for i, id := range sender.sent {
+		if id != event.ID {
+			t.Errorf("send %d carried ID %s, want %s", i+1, id, event.ID)
+		}
+	}
+	sent, err := q.GetSentEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].ID != event.ID {
+		t.Fatalf("after retry: sent = %+v, want event %s", sent, event.ID)
+	}
+}
+
+func TestManualRetryKeepsID(t *testing.T) {
+	q, err := NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.maxRetries = 1
+	sender := &stubSender{failures: 1}
+	q.SetSender(sender)
+
+	event, err := q.Enqueue(EventTypeVCC, "cam-1", map[string]interface{}{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Out of retries, the event moves to failed
+	q.processBatch()
+	failed, err := q.GetFailedEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].ID != event.ID {
+		t.Fatalf("failed = %+v, want event %s", failed, event.ID)
+	}
+
+	if err := q.RetryEvent(event.ID); err != nil {
+		t.Fatal(err)
+	}
+	q.processBatch()
+
+	if len(sender.sent) != 2 || sender.sent[1] != event.ID {
+		t.Fatalf("sends = %v, want the manual retry to carry %s", sender.sent, event.ID)
+	}
+}
+
+// stubBatchSender fails the events whose IDs are in failIDs
+type stubBatchSender struct {
+	stubSender
+	failIDs map[string]bool
+}
+
+func (s *stubBatchSender) SendEvents(events []*Event) ([]string, error) {
+	var failed []string
+	for _, event := range events {
+		if s.failIDs[event.ID] {
+			failed = append(failed, event.ID)
+		}
+	}
+	return failed, nil
+}
+
+func TestBatchRetriesOnlyFailedEvents(t *testing.T) {
+	q, err := NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetUploadBatchSize(10)
+
+	first, err := q.Enqueue(EventTypeVCC, "cam-1", map[string]interface{}{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := q.Enqueue(EventTypeVCC, "cam-1", map[string]interface{}{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetSender(&stubBatchSender{failIDs: map[string]bool{second.ID: true}})
+
+	q.processBatch()
+
+	sent, err := q.GetSentEvents(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].ID != first.ID {
+		t.Fatalf("sent = %+v, want only %s", sent, first.ID)
+	}
+	pending, err := q.GetPendingEvents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != second.ID || pending[0].Retries != 1 {
+		t.Fatalf("pending = %+v, want %s with 1 retry", pending, second.ID)
+	}
+}
@@ -0,0 +1,250 @@
+// Package roi masks detections that fall outside a camera's region of interest
+package roi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// MaxPoints bounds the size of a region-of-interest polygon
+const MaxPoints = 32
+
+// Polygon is a region of interest as [x, y] points in fractions of the frame (0-1).
+// An empty polygon covers the whole frame.
+type Polygon [][2]float64
+
+// Validate checks that the polygon has 3 to MaxPoints points inside the frame
+// and that no two edges cross
+func (p Polygon) Validate() error {
+	if len(p) == 0 {
+		return nil
+	}
+	if len(p) < 3 {
+		return fmt.Errorf("needs at least 3 points")
+	}
+	if len(p) > MaxPoints {
+		return fmt.Errorf("can have at most %d points", MaxPoints)
+	}
+	for i, pt := range p {
+		if math.IsNaN(pt[0]) || math.IsNaN(pt[1]) || pt[0] < 0 || pt[0] > 1 || pt[1] < 0 || pt[1] > 1 {
+			return fmt.Errorf("point %d must have x and y between 0 and 1", i)
+		}
+	}
+
+	n := len(p)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue // adjacent edges share a vertex
+			}
+			if segmentsCross(p[i], p[(i+1)%n], p[j], p[(j+1)%n]) {
+				return fmt.Errorf("edges %d and %d cross", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports whether a normalized point is inside the polygon (ray casting)
+func (p Polygon) Contains(x, y float64) bool {
+	if len(p) == 0 {
+		return true
+	}
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		xi, yi := p[i][0], p[i][1]
+		xj, yj := p[j][0], p[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Filter removes detections outside the polygon from a detection message.
+//
+// Both formats published by analytics workers are handled: a bare array of
+// detections, or an object with a "detections" array. Each detection is tested
+// at the bottom-centre of its bbox [x, y, w, h] (where a vehicle meets the road).
+// Pixel bboxes are normalized using the message's width/height (or
+// frame_width/frame_height); detections that can't be normalized are kept.
+//
+// It returns the (possibly rewritten) message and the number of detections removed.
+func (p Polygon) Filter(data []byte) ([]byte, int) {
+	if len(p) == 0 {
+		return data, 0
+	}
+
+	var msg map[string]interface{}
+	var dets []interface{}
+	isArray := false
+	if err := json.Unmarshal(data, &msg); err == nil {
+		list, ok := msg["detections"].([]interface{})
+		if !ok {
+			return data, 0
+		}
+		dets = list
+	} else if err := json.Unmarshal(data, &dets); err == nil {
+		isArray = true
+	} else {
+		return data, 0
+	}
+
+	width, height := frameSize(msg)
+	kept := make([]interface{}, 0, len(dets))
+	for _, d := range dets {
+		det, ok := d.(map[string]interface{})
+		if !ok {
+			kept = append(kept, d)
+			continue
+		}
+		x, y, ok := anchorPoint(det["bbox"], width, height)
+		if !ok || p.Contains(x, y) {
+			kept = append(kept, d)
+		}
+	}
+
+	removed := len(dets) - len(kept)
+	if removed == 0 {
+		return data, 0
+	}
+
+	var out []byte
+	var err error
+	if isArray {
+		out, err = json.Marshal(kept)
+	} else {
+		msg["detections"] = kept
+		out, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return data, 0
+	}
+	return out, removed
+}
+
+// eventBBoxKeys are the keys an event's data may carry its bounding box under,
+// as [x1, y1, x2, y2] or {x, y, width, height} (as read by the platform)
+var eventBBoxKeys = []string{"bbox", "boundingBox", "bounding_box", "box"}
+
+// ContainsEvent reports whether an event's bounding box is inside the polygon,
+// tested at the bottom-centre of the box like Filter. Pixel boxes are
+// normalized using the event's frame_width/frame_height (or
+// image_width/image_height, width/height); events without a box that can be
+// normalized are kept.
+func (p Polygon) ContainsEvent(data map[string]interface{}) bool {
+	if len(p) == 0 || data == nil {
+		return true
+	}
+	box, ok := eventBBox(data)
+	if !ok {
+		return true
+	}
+
+	x, y := (box[0]+box[2])/2, box[3]
+	if box[0] > 1 || box[1] > 1 || box[2] > 1 || box[3] > 1 {
+		width, height := 0.0, 0.0
+		for _, keys := range [][2]string{{"frame_width", "frame_height"}, {"image_width", "image_height"}, {"width", "height"}} {
+			w, wok := data[keys[0]].(float64)
+			h, hok := data[keys[1]].(float64)
+			if wok && hok && w > 0 && h > 0 {
+				width, height = w, h
+				break
+			}
+		}
+		if width == 0 {
+			return true
+		}
+		x, y = x/width, y/height
+	}
+	return p.Contains(x, y)
+}
+
+// eventBBox reads an event's bounding box as [x1, y1, x2, y2]
+func eventBBox(data map[string]interface{}) ([4]float64, bool) {
+	var box [4]float64
+	for _, key := range eventBBoxKeys {
+		switch v := data[key].(type) {
+		case []interface{}:
+			if len(v) != 4 {
+				continue
+			}
+			for i := range box {
+				f, ok := v[i].(float64)
+				if !ok {
+					return box, false
+				}
+				box[i] = f
+			}
+			return box, box[2] > box[0] && box[3] > box[1]
+		case map[string]interface{}:
+			x, xok := v["x"].(float64)
+			y, yok := v["y"].(float64)
+			w, wok := v["width"].(float64)
+			if !wok {
+				w, wok = v["w"].(float64)
+			}
+			h, hok := v["height"].(float64)
+			if !hok {
+				h, hok = v["h"].(float64)
+			}
+			if !xok || !yok || !wok || !hok || w <= 0 || h <= 0 {
+				return box, false
+			}
+			return [4]float64{x, y, x + w, y + h}, true
+		}
+	}
+	return box, false
+}
+
+// frameSize reads the frame dimensions from a detection message, if present
+func frameSize(msg map[string]interface{}) (float64, float64) {
+	for _, keys := range [][2]string{{"width", "height"}, {"frame_width", "frame_height"}} {
+		w, wok := msg[keys[0]].(float64)
+		h, hok := msg[keys[1]].(float64)
+		if wok && hok && w > 0 && h > 0 {
+			return w, h
+		}
+	}
+	return 0, 0
+}
+
+// anchorPoint returns the normalized bottom-centre of a bbox
+func anchorPoint(raw interface{}, width, height float64) (float64, float64, bool) {
+	bbox, ok := raw.([]interface{})
+	if !ok || len(bbox) != 4 {
+		return 0, 0, false
+	}
+	var v [4]float64
+	for i, b := range bbox {
+		f, ok := b.(float64)
+		if !ok {
+			return 0, 0, false
+		}
+		v[i] = f
+	}
+
+	x, y := v[0]+v[2]/2, v[1]+v[3]
+	normalized := v[0] <= 1 && v[1] <= 1 && v[2] <= 1 && v[3] <= 1
+	if !normalized {
+		if width == 0 || height == 0 {
+			return 0, 0, false
+		}
+		x, y = x/width, y/height
+	}
+	return x, y, true
+}
+
+func segmentsCross(p1, p2, q1, q2 [2]float64) bool {
+	d1 := cross(q1, q2, p1)
+	d2 := cross(q1, q2, p2)
+	d3 := cross(p1, p2, q1)
+	d4 := cross(p1, p2, q2)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// cross is the cross product of (b-a) x (c-a)
+func cross(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
@@ -18,6 +18,7 @@ type CameraReader struct {
 	width     int
 	height    int
 	publisher *Publisher
+	analytics []string // Per-analytic subjects to publish to (empty = frames.<camera> only)
 
 	decoder decoder.Decoder
 	ctx     context.Context
@@ -38,6 +39,9 @@ type CameraConfig struct {
 	FPS      int
 	Width    int
 	Height   int
+
+	// Analytics whose per-analytic frame subjects are published (see FrameSubject)
+	Analytics []string
 }
 
 // NewCameraReader creates a new camera reader
@@ -60,9 +64,17 @@ func NewCameraReader(cfg CameraConfig, publisher *Publisher) *CameraReader {
 		width:     cfg.Width,
 		height:    cfg.Height,
 		publisher: publisher,
+		analytics: cfg.Analytics,
 	}
 }
 
+// SetAnalytics changes the per-analytic subjects frames are published to
+func (c *CameraReader) SetAnalytics(analytics []string) {
+	c.mu.Lock()
+	c.analytics = analytics
+	c.mu.Unlock()
+}
+
 // Start begins reading frames from the RTSP stream
 func (c *CameraReader) Start() error {
 	c.mu.Lock()
@@ -96,8 +108,12 @@ func (c *CameraReader) Start() error {
 
 // handleFrame is called for each decoded frame
 func (c *CameraReader) handleFrame(frame *decoder.Frame) {
+	c.mu.Lock()
+	analytics := c.analytics
+	c.mu.Unlock()
+
 	// Publish frame to NATS
-	if err := c.publisher.PublishFrame(frame.CameraID, frame.Data, frame.Width, frame.Height); err != nil {
+	if err := c.publisher.PublishFrame(frame.CameraID, analytics, frame.Data, frame.Width, frame.Height); err != nil {
 		log.Printf("⚠️ Failed to publish frame for %s: %v", c.cameraID, err)
 	}
 
@@ -0,0 +1,208 @@
+package streamer
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// What a tripped health gate does to frame forwarding
+const (
+	GateReduce  = "reduce"  // forward at most ReducedFPS frames per camera
+	GateSuspend = "suspend" // forward no frames
+)
+
+// Health gate states
+const (
+	GateOpen      = "open"
+	GateReduced   = "reduced"
+	GateSuspended = "suspended"
+)
+
+// Health gate defaults
+const (
+	DefaultGateReducedFPS = 2
+	DefaultGateInterval   = 5 * time.Second
+
+	// gateHysteresis is how far below its threshold (CPU points or degrees)
+	// a reading must fall before a tripped gate opens again
+	gateHysteresis = 5
+)
+
+// GateConfig configures the health gate, which holds back the live frames
+// forwarded off the box while it's overloaded, so detections and events keep
+// flowing at the expense of live video. Frames published locally for the
+// analytics workers are never held back.
+type GateConfig struct {
+	MaxCPU     float64       // CPU percent that trips the gate; 0 = not checked
+	MaxTemp    float64       // degrees that trip the gate; 0 = not checked
+	Mode       string        // GateReduce or GateSuspend
+	ReducedFPS float64       // frames per camera per second while reduced
+	Interval   time.Duration // how often health is sampled
+}
+
+// GateStatus is the state of the health gate
+type GateStatus struct {
+	Enabled     bool      `json:"enabled"`
+	State       string    `json:"state"`            // open, reduced or suspended
+	Reason      string    `json:"reason,omitempty"` // what tripped it
+	CPUPercent  float64   `json:"cpuPercent"`
+	Temperature float64   `json:"temperature"`
+	Since       time.Time `json:"since"`
+	FramesHeld  uint64    `json:"framesHeld"` // frames not forwarded since startup
+	Trips       int       `json:"trips"`
+}
+
+// healthGate decides whether frames are forwarded, from periodic samples of
+// CPU usage and temperature
+type healthGate struct {
+	mu            sync.Mutex
+	cfg           GateConfig
+	sample        func() (float64, float64)
+	state         string
+	reason        string
+	cpu, temp     float64
+	since         time.Time
+	held          uint64
+	trips         int
+	lastPublished map[string]time.Time
+}
+
+// SetHealthGate holds back forwarded frames (see AllowForward) while sample,
+// returning CPU percent and temperature, crosses the thresholds of cfg.
+// Overview frames stop whenever the gate is tripped. Call before Start.
+func (p *Pipeline) SetHealthGate(cfg GateConfig, sample func() (float64, float64)) {
+	if cfg.MaxCPU <= 0 && cfg.MaxTemp <= 0 {
+		return
+	}
+	if cfg.Mode != GateSuspend {
+		cfg.Mode = GateReduce
+	}
+	if cfg.ReducedFPS <= 0 {
+		cfg.ReducedFPS = DefaultGateReducedFPS
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultGateInterval
+	}
+	gate := &healthGate{
+		cfg:           cfg,
+		sample:        sample,
+		state:         GateOpen,
+		since:         time.Now(),
+		lastPublished: make(map[string]time.Time),
+	}
+	p.publisher.gate = gate
+	go gate.run()
+}
+
+// AllowForward reports whether a camera's live frame may be forwarded off the
+// box now, as the health gate decides
+func (p *Pipeline) AllowForward(cameraID string) bool {
+	gate := p.publisher.gate
+	return gate == nil || gate.allows(cameraID, time.Now())
+}
+
+// GateStatus returns the state of the health gate
+func (p *Pipeline) GateStatus() GateStatus {
+	gate := p.publisher.gate
+	if gate == nil {
+		return GateStatus{State: GateOpen}
+	}
+	gate.mu.Lock()
+	defer gate.mu.Unlock()
+	return GateStatus{
+		Enabled:     true,
+		State:       gate.state,
+		Reason:      gate.reason,
+		CPUPercent:  gate.cpu,
+		Temperature: gate.temp,
+		Since:       gate.since,
+		FramesHeld:  gate.held,
+		Trips:       gate.trips,
+	}
+}
+
+// run samples health and moves the gate
+func (g *healthGate) run() {
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		g.update(g.sample())
+	}
+}
+
+// update trips the gate when a reading crosses its threshold, and opens it
+// again once every reading is back below its threshold less the hysteresis
+func (g *healthGate) update(cpu, temp float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cpu, g.temp = cpu, temp
+
+	var reason string
+	switch {
+	case g.cfg.MaxCPU > 0 && cpu >= g.cfg.MaxCPU:
+		reason = fmt.Sprintf("CPU %.0f%% >= %.0f%%", cpu, g.cfg.MaxCPU)
+	case g.cfg.MaxTemp > 0 && temp >= g.cfg.MaxTemp:
+		reason = fmt.Sprintf("temperature %.0f°C >= %.0f°C", temp, g.cfg.MaxTemp)
+	}
+
+	if g.state == GateOpen {
+		if reason == "" {
+			return
+		}
+		g.state = GateReduced
+		if g.cfg.Mode == GateSuspend {
+			g.state = GateSuspended
+		}
+		g.reason = reason
+		g.since = time.Now()
+		g.trips++
+		if g.state == GateSuspended {
+			log.Printf("🚦 Frame forwarding suspended: %s", reason)
+		} else {
+			log.Printf("🚦 Frame forwarding reduced to %g fps per camera: %s", g.cfg.ReducedFPS, reason)
+		}
+		return
+	}
+
+	if reason != "" {
+		g.reason = reason
+		return
+	}
+	if (g.cfg.MaxCPU > 0 && cpu >= g.cfg.MaxCPU-gateHysteresis) ||
+		(g.cfg.MaxTemp > 0 && temp >= g.cfg.MaxTemp-gateHysteresis) {
+		return
+	}
+	log.Printf("🚦 Frame forwarding resumed after %s (CPU %.0f%%, %.0f°C)", time.Since(g.since).Round(time.Second), cpu, temp)
+	g.state = GateOpen
+	g.reason = ""
+	g.since = time.Now()
+}
+
+// allows reports whether a camera's frame may be forwarded now, counting
+// the frames held back
+func (g *healthGate) allows(cameraID string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.state {
+	case GateSuspended:
+		g.held++
+		return false
+	case GateReduced:
+		if now.Sub(g.lastPublished[cameraID]) < time.Duration(float64(time.Second)/g.cfg.ReducedFPS) {
+			g.held++
+			return false
+		}
+	}
+	g.lastPublished[cameraID] = now
+	return true
+}
+
+// tripped reports whether the gate is holding back frames
+func (g *healthGate) tripped() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state != GateOpen
+}
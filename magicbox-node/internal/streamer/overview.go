@@ -0,0 +1,169 @@
+package streamer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"time"
+)
+
+// Overview frame defaults
+const (
+	DefaultOverviewWidth = 160
+	overviewJPEGQuality  = 60
+)
+
+// OverviewSubject returns the NATS subject a camera's downscaled overview
+// frames are published on. It's kept out of the frames.* namespace so frame
+// consumers never pick up the thumbnails by accident.
+func OverviewSubject(cameraID string) string {
+	return "overview." + cameraID
+}
+
+// SetOverview enables overview frames: every camera additionally publishes a
+// frame downscaled to width pixels wide at most fps frames per second on
+// OverviewSubject. fps <= 0 disables them.
+func (p *Publisher) SetOverview(width int, fps float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if width <= 0 {
+		width = DefaultOverviewWidth
+	}
+	p.overviewWidth = width
+	p.overviewInterval = 0
+	if fps > 0 {
+		p.overviewInterval = time.Duration(float64(time.Second) / fps)
+	}
+}
+
+// overviewDue reports whether a camera's next overview frame is due and the
+// previous one is done, and if so claims the slot. The claim holds until
+// overviewDone.
+func (p *Publisher) overviewDue(cameraID string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.overviewInterval <= 0 || p.overviewBusy[cameraID] {
+		return false
+	}
+	if now.Sub(p.lastOverview[cameraID]) < p.overviewInterval {
+		return false
+	}
+	p.lastOverview[cameraID] = now
+	p.overviewBusy[cameraID] = true
+	return true
+}
+
+// overviewDone releases a camera's overview slot
+func (p *Publisher) overviewDone(cameraID string) {
+	p.mu.Lock()
+	delete(p.overviewBusy, cameraID)
+	p.mu.Unlock()
+}
+
+// publishOverview publishes a downscaled copy of a frame if one is due. The
+// frame is downscaled in the background, so the full stream never waits on
+// it; frames arriving while a camera's previous overview is still being
+// encoded are skipped. Failures are logged, never returned, so they can't
+// affect the full stream.
+func (p *Publisher) publishOverview(cameraID string, seq uint64, jpegData []byte) {
+	// Thumbnails are the first thing to go under load
+	if p.gate != nil && p.gate.tripped() {
+		return
+	}
+	now := time.Now()
+	if !p.overviewDue(cameraID, now) {
+		return
+	}
+
+	p.mu.Lock()
+	width := p.overviewWidth
+	p.mu.Unlock()
+
+	// The caller may reuse the frame's buffer once PublishFrame returns
+	frame := append([]byte(nil), jpegData...)
+	go func() {
+		defer p.overviewDone(cameraID)
+		p.encodeOverview(cameraID, seq, now, frame, width)
+	}()
+}
+
+// encodeOverview downscales a frame and publishes it as a camera's overview
+func (p *Publisher) encodeOverview(cameraID string, seq uint64, now time.Time, jpegData []byte, width int) {
+	small, w, h, err := downscaleJPEG(jpegData, width)
+	if err != nil {
+		log.Printf("⚠️ [PUBLISHER] Failed to downscale overview frame for %s: %v", cameraID, err)
+		return
+	}
+
+	data, err := json.Marshal(FrameMessage{
+		Camera:    cameraID,
+		Seq:       seq,
+		Timestamp: now.UnixMilli(),
+		Width:     w,
+		Height:    h,
+		Frame:     base64.StdEncoding.EncodeToString(small),
+	})
+	if err != nil {
+		return
+	}
+	if err := p.nats.Publish(OverviewSubject(cameraID), data); err != nil {
+		log.Printf("⚠️ [PUBLISHER] Failed to publish overview frame for %s: %v", cameraID, err)
+	}
+}
+
+// downscaleJPEG shrinks a JPEG to width pixels wide, keeping the aspect ratio,
+// by averaging each block of source pixels. Frames already narrower are only
+// re-encoded.
+func downscaleJPEG(data []byte, width int) ([]byte, int, int, error) {
+	src, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("decode: %w", err)
+	}
+
+	b := src.Bounds()
+	if width > b.Dx() {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+
+			var r, g, bl, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r += cr >> 8
+					g += cg >> 8
+					bl += cb >> 8
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: overviewJPEGQuality}); err != nil {
+		return nil, 0, 0, fmt.Errorf("encode: %w", err)
+	}
+	return buf.Bytes(), width, height, nil
+}
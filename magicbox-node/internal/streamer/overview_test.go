@@ -0,0 +1,28 @@
+package streamer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverviewSkipsFramesWhileEncoding(t *testing.T) {
+	p := NewPublisher(nil)
+	p.SetOverview(160, 10)
+	now := time.Now()
+
+	if !p.overviewDue("cam-1", now) {
+		t.Fatal("first overview frame not due")
+	}
+	// Due again by time, but the previous one is still being encoded
+	if p.overviewDue("cam-1", now.Add(time.Second)) {
+		t.Error("overview frame due while the previous one is encoding")
+	}
+	if !p.overviewDue("cam-2", now) {
+		t.Error("another camera's overview held back")
+	}
+
+	p.overviewDone("cam-1")
+	if !p.overviewDue("cam-1", now.Add(time.Second)) {
+		t.Error("overview frame not due once the previous one is done")
+	}
+}
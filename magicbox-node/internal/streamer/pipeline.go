@@ -2,6 +2,7 @@ package streamer
 
 import (
 	"log"
+	"sort"
 	"sync"
 
 	"github.com/irisdrone/magicbox-node/internal/config"
@@ -17,6 +18,14 @@ type Pipeline struct {
 	cameras   map[string]*CameraReader
 	mu        sync.RWMutex
 	running   bool
+
+	// partitionFrames also publishes each frame on frames.<camera>.<analytic>
+	// for every analytic active on the camera
+	partitionFrames bool
+
+	// maxCameras caps how many cameras stream at once; when more are enabled
+	// the lowest-priority ones are shed first. 0 = unlimited
+	maxCameras int
 }
 
 // NewPipeline creates a new streaming pipeline
@@ -31,6 +40,45 @@ func NewPipeline(cfg *config.Manager, nats *natsserver.EmbeddedNATS) *Pipeline {
 	}
 }
 
+// SetPartitionFrames enables or disables per-analytic frame subjects.
+// Call before Start.
+func (p *Pipeline) SetPartitionFrames(enabled bool) {
+	p.mu.Lock()
+	p.partitionFrames = enabled
+	p.mu.Unlock()
+}
+
+// SetMaxCameras caps the number of cameras streamed at once (0 = unlimited).
+// Call before Start.
+func (p *Pipeline) SetMaxCameras(n int) {
+	p.mu.Lock()
+	p.maxCameras = n
+	p.mu.Unlock()
+}
+
+// SetOverview publishes a downscaled overview frame per camera, width pixels
+// wide at up to fps frames per second, on overview.<camera> (fps 0 = off).
+// Call before Start.
+func (p *Pipeline) SetOverview(width int, fps float64) {
+	p.publisher.SetOverview(width, fps)
+}
+
+// cameraPriority returns a camera's scheduling priority, applying the default
+func cameraPriority(cam config.CameraConfig) int {
+	if cam.Priority == 0 {
+		return config.DefaultCameraPriority
+	}
+	return cam.Priority
+}
+
+// frameAnalytics returns the analytics a camera's frames are partitioned by
+func (p *Pipeline) frameAnalytics(analytics []string) []string {
+	if !p.partitionFrames {
+		return nil
+	}
+	return analytics
+}
+
 // Start starts the streaming pipeline
 func (p *Pipeline) Start() {
 	p.mu.Lock()
@@ -76,24 +124,44 @@ func (p *Pipeline) syncCameras() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// Highest priority first, so a capacity cap sheds the lowest-priority cameras
+	var enabled []config.CameraConfig
+	for _, cam := range cfg.Cameras {
+		if cam.Enabled {
+			enabled = append(enabled, cam)
+		}
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return cameraPriority(enabled[i]) > cameraPriority(enabled[j])
+	})
+	if p.maxCameras > 0 && len(enabled) > p.maxCameras {
+		for _, cam := range enabled[p.maxCameras:] {
+			log.Printf("⏸️ Shedding camera %s (priority %d, capacity %d cameras)", cam.DeviceID, cameraPriority(cam), p.maxCameras)
+		}
+		enabled = enabled[:p.maxCameras]
+	}
+
 	// Track which cameras should be running
 	desired := make(map[string]bool)
 
-	for _, cam := range cfg.Cameras {
-		if !cam.Enabled {
+	for _, cam := range enabled {
+		desired[cam.DeviceID] = true
+
+		// Already running - pick up analytics changes without a restart
+		if reader, exists := p.cameras[cam.DeviceID]; exists {
+			reader.SetAnalytics(p.frameAnalytics(cam.Analytics))
 			continue
 		}
 
-		desired[cam.DeviceID] = true
-
 		// Start if not already running
 		if _, exists := p.cameras[cam.DeviceID]; !exists {
 			reader := NewCameraReader(CameraConfig{
-				CameraID: cam.DeviceID,
-				RTSPURL:  cam.RTSPUrl,
-				FPS:      cam.FPS,
-				Width:    1280, // Default, could be from config
-				Height:   720,
+				CameraID:  cam.DeviceID,
+				RTSPURL:   cam.RTSPUrl,
+				FPS:       cam.FPS,
+				Width:     1280, // Default, could be from config
+				Height:    720,
+				Analytics: p.frameAnalytics(cam.Analytics),
 			}, p.publisher)
 
 			if err := reader.Start(); err != nil {
@@ -109,7 +177,7 @@ func (p *Pipeline) syncCameras() {
 	// Stop cameras that shouldn't be running
 	for id, cam := range p.cameras {
 		if !desired[id] {
-			log.Printf("⏹️ Stopping camera %s (no longer in config)", id)
+			log.Printf("⏹️ Stopping camera %s (disabled or shed)", id)
 			cam.Stop()
 			delete(p.cameras, id)
 		}
@@ -170,11 +238,12 @@ func (p *Pipeline) RefreshCamera(cameraID string) error {
 	for _, cam := range cfg.Cameras {
 		if cam.DeviceID == cameraID && cam.Enabled {
 			reader := NewCameraReader(CameraConfig{
-				CameraID: cam.DeviceID,
-				RTSPURL:  cam.RTSPUrl,
-				FPS:      cam.FPS,
-				Width:    1280,
-				Height:   720,
+				CameraID:  cam.DeviceID,
+				RTSPURL:   cam.RTSPUrl,
+				FPS:       cam.FPS,
+				Width:     1280,
+				Height:    720,
+				Analytics: p.frameAnalytics(cam.Analytics),
 			}, p.publisher)
 
 			if err := reader.Start(); err != nil {
@@ -11,6 +11,16 @@ import (
 	"github.com/irisdrone/magicbox-node/internal/natsserver"
 )
 
+// FrameSubject returns the NATS subject frames for a camera are published on.
+// With an analytic, it's the per-analytic subject (frames.<camera>.<analytic>)
+// that consumers interested in a single analytic can subscribe to instead.
+func FrameSubject(cameraID, analytic string) string {
+	if analytic == "" {
+		return "frames." + cameraID
+	}
+	return "frames." + cameraID + "." + analytic
+}
+
 // FrameMessage is the message format published to NATS
 type FrameMessage struct {
 	Camera    string `json:"c"`  // Camera ID
@@ -28,6 +38,16 @@ type Publisher struct {
 	fpsCount      map[string]int
 	lastFPSUpdate time.Time
 	mu            sync.Mutex
+
+	// Overview frames (see SetOverview); overviewInterval 0 = disabled
+	overviewWidth    int
+	overviewInterval time.Duration
+	lastOverview     map[string]time.Time
+	overviewBusy     map[string]bool // cameras whose overview frame is being encoded
+
+	// gate holds back frames leaving the box while it's overloaded (see
+	// SetHealthGate); nil = frames are always forwarded
+	gate *healthGate
 }
 
 // NewPublisher creates a new frame publisher
@@ -37,6 +57,9 @@ func NewPublisher(nats *natsserver.EmbeddedNATS) *Publisher {
 		seq:           make(map[string]uint64),
 		fpsCount:      make(map[string]int),
 		lastFPSUpdate: time.Now(),
+		overviewWidth: DefaultOverviewWidth,
+		lastOverview:  make(map[string]time.Time),
+		overviewBusy:  make(map[string]bool),
 	}
 	// Start FPS logging goroutine
 	go p.logFPS()
@@ -59,8 +82,11 @@ func (p *Publisher) logFPS() {
 	}
 }
 
-// PublishFrame publishes a JPEG frame to NATS
-func (p *Publisher) PublishFrame(cameraID string, jpegData []byte, width, height int) error {
+// PublishFrame publishes a JPEG frame to NATS, and additionally to the
+// per-analytic subject of each of the given analytics and, when due, as a
+// downscaled overview frame. Local frames feed the analytics workers, so the
+// health gate never holds them back.
+func (p *Publisher) PublishFrame(cameraID string, analytics []string, jpegData []byte, width, height int) error {
 	p.mu.Lock()
 	p.seq[cameraID]++
 	seq := p.seq[cameraID]
@@ -82,7 +108,16 @@ func (p *Publisher) PublishFrame(cameraID string, jpegData []byte, width, height
 	}
 
 	// Publish to subject: frames.<camera_id>
-	return p.nats.Publish("frames."+cameraID, data)
+	if err := p.nats.Publish(FrameSubject(cameraID, ""), data); err != nil {
+		return err
+	}
+	for _, analytic := range analytics {
+		if err := p.nats.Publish(FrameSubject(cameraID, analytic), data); err != nil {
+			return err
+		}
+	}
+	p.publishOverview(cameraID, seq, jpegData)
+	return nil
 }
 
 // PublishFrameRaw publishes raw bytes (for binary protocol if needed later)
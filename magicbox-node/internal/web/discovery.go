@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/irisdrone/magicbox-node/internal/camera"
+)
+
+// maxScanDuration bounds a whole subnet scan, whatever its size
+const maxScanDuration = 2 * time.Minute
+
+// subnetScanner runs one subnet scan at a time, capping how many probes a
+// request may put in flight
+type subnetScanner struct {
+	maxConcurrency int
+	timeout        time.Duration // per probe, and the longest a request may ask for
+
+	running sync.Mutex
+}
+
+func newSubnetScanner() *subnetScanner {
+	return &subnetScanner{
+		maxConcurrency: camera.DefaultScanConcurrency,
+		timeout:        camera.DefaultScanTimeout,
+	}
+}
+
+// SetDiscoveryLimits caps the concurrent probes of a subnet scan and sets the
+// per-probe timeout. Zero values keep the defaults.
+func (s *Server) SetDiscoveryLimits(maxConcurrency int, timeout time.Duration) {
+	if maxConcurrency > 0 {
+		s.scanner.maxConcurrency = maxConcurrency
+	}
+	if timeout > 0 {
+		s.scanner.timeout = timeout
+	}
+}
+
+// handleAPIDiscoverCameras probes a subnet for hosts serving RTSP, for
+// networks whose cameras don't answer ONVIF discovery
+// POST /api/cameras/discover
+func (s *Server) handleAPIDiscoverCameras(c *gin.Context) {
+	var req struct {
+		CIDR         string `json:"cidr" binding:"required"`
+		Ports        []int  `json:"ports"`        // empty = common RTSP ports; at most camera.MaxScanPorts
+		Concurrency  int    `json:"concurrency"`  // capped at the node's limit
+		TimeoutMs    int    `json:"timeoutMs"`    // per probe, capped at the node's timeout
+		ProbeStreams bool   `json:"probeStreams"` // also ask each open port for its RTSP details
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !s.scanner.running.TryLock() {
+		c.JSON(http.StatusConflict, gin.H{"error": "A subnet scan is already running"})
+		return
+	}
+	defer s.scanner.running.Unlock()
+
+	opts := camera.ScanOptions{
+		CIDR:         strings.TrimSpace(req.CIDR),
+		Ports:        req.Ports,
+		Concurrency:  s.scanner.maxConcurrency,
+		Timeout:      s.scanner.timeout,
+		ProbeStreams: req.ProbeStreams,
+	}
+	if req.Concurrency > 0 && req.Concurrency < opts.Concurrency {
+		opts.Concurrency = req.Concurrency
+	}
+	if t := time.Duration(req.TimeoutMs) * time.Millisecond; t > 0 && t < opts.Timeout {
+		opts.Timeout = t
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), maxScanDuration)
+	defer cancel()
+
+	log.Printf("🔍 Scanning %s for RTSP cameras (%d probes at a time)", opts.CIDR, opts.Concurrency)
+	result, err := camera.ScanSubnet(ctx, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🔍 Scan of %s found %d hosts in %s", opts.CIDR, len(result.Hosts), result.Duration)
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,307 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MagicNetwork registration retry defaults
+const (
+	DefaultMagicNetworkAttempts = 3
+	DefaultMagicNetworkTimeout  = 15 * time.Second
+	DefaultMagicNetworkCooldown = time.Minute
+
+	magicNetworkBaseBackoff = 2 * time.Second
+	magicNetworkMaxBackoff  = 10 * time.Second
+
+	// magicNetworkBreakerThreshold is how many consecutive failed attempts open the circuit
+	magicNetworkBreakerThreshold = 5
+)
+
+// errMagicNetworkCircuitOpen is returned without calling MagicNetwork while the
+// circuit is open after repeated failures
+var errMagicNetworkCircuitOpen = errors.New("MagicNetwork circuit open")
+
+// magicNetworkClient registers nodes with MagicNetwork, retrying transient
+// failures and failing fast once a MagicNetwork server looks down
+type magicNetworkClient struct {
+	attempts int           // tries per registration
+	timeout  time.Duration // per-try HTTP timeout
+	cooldown time.Duration // how long a circuit stays open
+
+	mu       sync.Mutex
+	circuits map[string]*magicNetworkCircuit // by MagicNetwork base URL
+}
+
+// magicNetworkCircuit tracks failures of one MagicNetwork server, so a dead
+// server doesn't fail calls to a healthy one
+type magicNetworkCircuit struct {
+	failures  int // consecutive failed attempts
+	openUntil time.Time
+}
+
+func newMagicNetworkClient() *magicNetworkClient {
+	return &magicNetworkClient{
+		attempts: DefaultMagicNetworkAttempts,
+		timeout:  DefaultMagicNetworkTimeout,
+		cooldown: DefaultMagicNetworkCooldown,
+		circuits: make(map[string]*magicNetworkCircuit),
+	}
+}
+
+// SetMagicNetworkRetry configures registration retries: attempts per setup,
+// the timeout of each attempt and how long to fail fast after repeated
+// failures. Zero values keep the defaults.
+func (s *Server) SetMagicNetworkRetry(attempts int, timeout, cooldown time.Duration) {
+	s.magicNetwork.mu.Lock()
+	defer s.magicNetwork.mu.Unlock()
+
+	if attempts > 0 {
+		s.magicNetwork.attempts = attempts
+	}
+	if timeout > 0 {
+		s.magicNetwork.timeout = timeout
+	}
+	if cooldown > 0 {
+		s.magicNetwork.cooldown = cooldown
+	}
+}
+
+// allow reports whether a call to baseURL may be made, or how long until its
+// circuit closes
+func (m *magicNetworkClient) allow(baseURL string) (bool, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c := m.circuits[baseURL]; c != nil {
+		if wait := time.Until(c.openUntil); wait > 0 {
+			return false, wait
+		}
+	}
+	return true, 0
+}
+
+// succeeded closes the circuit of baseURL
+func (m *magicNetworkClient) succeeded(baseURL string) {
+	m.mu.Lock()
+	delete(m.circuits, baseURL)
+	m.mu.Unlock()
+}
+
+// failed records a failed attempt against baseURL, opening its circuit at the threshold
+func (m *magicNetworkClient) failed(baseURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.circuits[baseURL]
+	if c == nil {
+		c = &magicNetworkCircuit{}
+		m.circuits[baseURL] = c
+	}
+	c.failures++
+	if c.failures >= magicNetworkBreakerThreshold {
+		c.openUntil = time.Now().Add(m.cooldown)
+		log.Printf("⚡ MagicNetwork circuit for %s open after %d consecutive failures, failing fast for %v", baseURL, c.failures, m.cooldown)
+	}
+}
+
+// magicNetworkBackoff is the delay before retry n (1-based), doubling up to magicNetworkMaxBackoff
+func magicNetworkBackoff(n int) time.Duration {
+	d := magicNetworkBaseBackoff << (n - 1)
+	if d <= 0 || d > magicNetworkMaxBackoff {
+		return magicNetworkMaxBackoff
+	}
+	return d
+}
+
+// register POSTs a peer registration, retrying network errors, 5xx and 429
+func (m *magicNetworkClient) register(url, apiKey string, body []byte) (*MagicNetworkResponse, error) {
+	m.mu.Lock()
+	attempts, timeout := m.attempts, m.timeout
+	m.mu.Unlock()
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if ok, wait := m.allow(url); !ok {
+			return nil, fmt.Errorf("%w: MagicNetwork at %s is unreachable, try again in %v",
+				errMagicNetworkCircuitOpen, url, wait.Round(time.Second))
+		}
+
+		result, retryable, err := m.registerOnce(url, apiKey, body, timeout)
+		if err == nil {
+			m.succeeded(url)
+			if attempt > 1 {
+				log.Printf("🔐 MagicNetwork registration succeeded on attempt %d/%d", attempt, attempts)
+			}
+			return result, nil
+		}
+		if !retryable {
+			// MagicNetwork answered, so it's up; the request itself is wrong
+			m.succeeded(url)
+			return nil, err
+		}
+
+		m.failed(url)
+		lastErr = err
+		log.Printf("⚠️ MagicNetwork registration attempt %d/%d failed: %v", attempt, attempts, err)
+		if attempt < attempts {
+			time.Sleep(magicNetworkBackoff(attempt))
+		}
+	}
+	return nil, fmt.Errorf("gave up after %d attempts: %w", attempts, lastErr)
+}
+
+// registerOnce makes a single registration call. retryable reports whether
+// the failure is transient.
+func (m *magicNetworkClient) registerOnce(url, apiKey string, body []byte, timeout time.Duration) (*MagicNetworkResponse, bool, error) {
+	req, err := http.NewRequest("POST", url+"/api/peers", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	warnIfKeyDeprecated(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retryable, fmt.Errorf("MagicNetwork API error (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	// Parse response
+	var result struct {
+		Status string `json:"status"`
+		Peer   struct {
+			AssignedIP string `json:"assigned_ip"`
+		} `json:"peer"`
+		Server struct {
+			PublicKey     string   `json:"public_key"`
+			ServerIP      string   `json:"server_ip"`
+			DNS           []string `json:"dns"`
+			SearchDomains []string `json:"search_domains"`
+		} `json:"server"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &MagicNetworkResponse{
+		AssignedIP:   result.Peer.AssignedIP,
+		ServerPubKey: result.Server.PublicKey,
+		ServerIP:     result.Server.ServerIP,
+
+		DNS:           result.Server.DNS,
+		SearchDomains: result.Server.SearchDomains,
+	}, false, nil
+}
+
+// reachabilityReport is a tunnel test sent to MagicNetwork
+type reachabilityReport struct {
+	Reachable bool    `json:"reachable"`
+	RTTMs     float64 `json:"rtt_ms,omitempty"`
+	Target    string  `json:"target"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// reportReachability records a tunnel test against this node's peer in
+// MagicNetwork. It's a single attempt: the next test reports again.
+func (m *magicNetworkClient) reportReachability(baseURL, apiKey, publicKey string, report reachabilityReport) error {
+	if ok, wait := m.allow(baseURL); !ok {
+		return fmt.Errorf("%w: try again in %v", errMagicNetworkCircuitOpen, wait.Round(time.Second))
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", baseURL+"/api/peers/"+url.PathEscape(publicKey)+"/reachability", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	m.mu.Lock()
+	timeout := m.timeout
+	m.mu.Unlock()
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		m.failed(baseURL)
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	warnIfKeyDeprecated(resp)
+
+	if resp.StatusCode >= 500 {
+		m.failed(baseURL)
+	} else {
+		m.succeeded(baseURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("MagicNetwork API error (%d): %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// warnIfKeyDeprecated logs when MagicNetwork accepted the previous API key
+// during a rotation's overlap window
+func warnIfKeyDeprecated(resp *http.Response) {
+	if resp.Header.Get("X-API-Key-Deprecated") == "true" {
+		log.Printf("⚠️ MagicNetwork API key has been rotated; update it via /api/magicnetwork/api-key before the old key expires")
+	}
+}
+
+// verifyAPIKey checks a key against MagicNetwork without changing anything
+func (m *magicNetworkClient) verifyAPIKey(baseURL, apiKey string) error {
+	if ok, wait := m.allow(baseURL); !ok {
+		return fmt.Errorf("%w: try again in %v", errMagicNetworkCircuitOpen, wait.Round(time.Second))
+	}
+
+	req, err := http.NewRequest("GET", baseURL+"/api/status", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	m.mu.Lock()
+	timeout := m.timeout
+	m.mu.Unlock()
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		m.failed(baseURL)
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		m.failed(baseURL)
+	} else {
+		m.succeeded(baseURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("MagicNetwork API error (%d): %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -1,15 +1,14 @@
 package web
 
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
 	"embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"io"
 	"io/fs"
 	"log"
 	"net/http"
@@ -49,6 +48,9 @@ type Server struct {
 	central   *central.Client
 	wireguard *wireguard.Manager
 	port      int
+
+	magicNetwork *magicNetworkClient
+	scanner      *subnetScanner
 	router    *gin.Engine
 	server    *http.Server
 }
@@ -78,6 +80,9 @@ func NewServer(cfg *config.Manager, plat *platform.Client, q *queue.FileQueue, n
 		wireguard: wgManager,
 		port:      port,
 		router:    gin.New(),
+
+		magicNetwork: newMagicNetworkClient(),
+		scanner:      newSubnetScanner(),
 	}
 
 	// Connect queue to platform sender
@@ -119,8 +124,28 @@ func (s *Server) Start() error {
 	return s.server.ListenAndServe()
 }
 
+// StartWireGuardMonitor restarts the tunnel after threshold consecutive checks,
+// every interval, without a recent handshake. Restarts are published on
+// events.wireguard so they reach central once the tunnel is back.
+func (s *Server) StartWireGuardMonitor(interval time.Duration, threshold int) {
+	s.wireguard.StartMonitor(interval, threshold, func(event wireguard.RestartEvent) {
+		data, err := json.Marshal(map[string]interface{}{
+			"type":    "wireguard_restart",
+			"node":    s.config.Get().NodeName,
+			"restart": event,
+		})
+		if err != nil {
+			return
+		}
+		if err := s.nats.Publish("events.wireguard", data); err != nil {
+			log.Printf("⚠️ Failed to publish WireGuard restart: %v", err)
+		}
+	})
+}
+
 // Stop stops the web server
 func (s *Server) Stop() error {
+	s.wireguard.StopMonitor()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	return s.server.Shutdown(ctx)
@@ -163,6 +188,8 @@ func (s *Server) setupRoutes() {
 		// Config
 		api.GET("/config", s.handleAPIGetConfig)
 		api.PUT("/config", s.handleAPIUpdateConfig)
+		api.PUT("/config/import", s.handleAPIImportConfig)
+		api.POST("/config/validate", s.handleAPIValidateConfig)
 		api.PUT("/config/platform", s.handleAPIUpdatePlatformConfig)
 		api.PUT("/config/network", s.handleAPIUpdateNetworkConfig)
 		api.POST("/sync", s.handleAPISyncConfig)
@@ -181,6 +208,7 @@ func (s *Server) setupRoutes() {
 		api.POST("/cameras", s.handleAPIAddCamera)
 		api.DELETE("/cameras/:id", s.handleAPIDeleteCamera)
 		api.POST("/cameras/test", s.handleAPITestCamera)
+		api.POST("/cameras/discover", s.handleAPIDiscoverCameras)
 		api.POST("/cameras/sync", s.handleAPISyncCameras)
 		api.POST("/cameras/:id/enable", s.handleAPIEnableCamera)
 		api.POST("/cameras/:id/disable", s.handleAPIDisableCamera)
@@ -202,6 +230,8 @@ func (s *Server) setupRoutes() {
 		api.POST("/magicnetwork/up", s.handleAPIMagicNetworkUp)
 		api.POST("/magicnetwork/down", s.handleAPIMagicNetworkDown)
 		api.POST("/magicnetwork/restart", s.handleAPIMagicNetworkRestart)
+		api.POST("/magicnetwork/test", s.handleAPIMagicNetworkTest)
+		api.PUT("/magicnetwork/api-key", s.handleAPIMagicNetworkAPIKey)
 	}
 }
 
@@ -298,6 +328,7 @@ func (s *Server) handleAPIStatus(c *gin.Context) {
 			"enabled":       true,
 			"running":       s.pipeline.IsRunning(),
 			"activeCameras": s.pipeline.CameraCount(),
+			"healthGate":    s.pipeline.GateStatus().State,
 		}
 	}
 	
@@ -448,6 +479,16 @@ func (s *Server) handleAPIUpdateConfig(c *gin.Context) {
 	cfg := s.config.Get()
 	
 	if req.NodeName != "" {
+		if len(req.NodeName) > config.MaxNodeNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":            "Invalid configuration",
+				"validationErrors": config.ValidationErrors{{
+					Field:   "nodeName",
+					Message: fmt.Sprintf("must be at most %d characters", config.MaxNodeNameLength),
+				}},
+			})
+			return
+		}
 		if err := s.config.SetNodeName(req.NodeName); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -466,6 +507,61 @@ func (s *Server) handleAPIUpdateConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// decodeConfigBlob strictly decodes a complete config from the request body.
+// Unknown fields are rejected so typos don't silently fall back to defaults.
+func decodeConfigBlob(c *gin.Context) (config.NodeConfig, error) {
+	var cfg config.NodeConfig
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&cfg)
+	return cfg, err
+}
+
+// handleAPIValidateConfig checks a complete config without applying it
+func (s *Server) handleAPIValidateConfig(c *gin.Context) {
+	cfg, err := decodeConfigBlob(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+
+	if errs := cfg.Validate(); errs != nil {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "validationErrors": errs})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": true, "validationErrors": config.ValidationErrors{}})
+}
+
+// handleAPIImportConfig validates and applies a complete config blob.
+// Either the whole config is applied or nothing is.
+func (s *Server) handleAPIImportConfig(c *gin.Context) {
+	cfg, err := decodeConfigBlob(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON: " + err.Error()})
+		return
+	}
+
+	if err := s.config.Import(cfg); err != nil {
+		if errs, ok := err.(config.ValidationErrors); ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":            "Invalid configuration",
+				"validationErrors": errs,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Notify pipeline to sync cameras
+	if s.pipeline != nil && s.nats != nil {
+		s.nats.Publish("config.cameras", []byte("updated"))
+	}
+
+	log.Printf("📋 Config imported (%d cameras)", len(cfg.Cameras))
+	c.JSON(http.StatusOK, gin.H{"success": true, "config": s.config.Get()})
+}
+
 func (s *Server) handleAPIUpdatePlatformConfig(c *gin.Context) {
 	var req struct {
 		ServerURL string `json:"serverUrl"`
@@ -525,9 +621,10 @@ func (s *Server) handleAPISyncConfig(c *gin.Context) {
 		return
 	}
 	
-	s.config.SetCameras(workerCfg.Cameras)
-	s.config.SetConfigVersion(workerCfg.ConfigVersion)
-	s.config.UpdateLastSync()
+	if err := s.platform.ApplyConfig(workerCfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"success":       true,
@@ -652,6 +749,14 @@ func (s *Server) handleAPIAddCamera(c *gin.Context) {
 		Enabled:    false, // Not enabled until platform assigns analytics
 	}
 	
+	if errs := cam.Validate("camera"); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            "Invalid camera",
+			"validationErrors": errs,
+		})
+		return
+	}
+	
 	// Add to config
 	cfg := s.config.Get()
 	
@@ -797,6 +902,7 @@ func (s *Server) handleAPIStreamingStatus(c *gin.Context) {
 	if s.pipeline != nil {
 		status["running"] = s.pipeline.IsRunning()
 		status["cameras"] = s.pipeline.CameraCount()
+		status["healthGate"] = s.pipeline.GateStatus()
 	}
 
 	c.JSON(http.StatusOK, status)
@@ -911,6 +1017,14 @@ func (s *Server) handleAPIMagicNetworkStatus(c *gin.Context) {
 		"transfer_tx":    status.TransferTx,
 		"configured":     wgCfg.Configured,
 		"enabled":        wgCfg.Enabled,
+		"mtu":            wgCfg.MTU,
+		"persistent_keepalive": wgCfg.PersistentKeepalive,
+		"allowed_ips":    wgCfg.AllowedIPs,
+		"route_mode":     wgCfg.RouteMode,
+		"route_host":     wgCfg.RouteHost,
+		"extra_allowed_ips": wgCfg.ExtraAllowedIPs,
+		"dns":            wgCfg.DNS,
+		"search_domains": wgCfg.SearchDomains,
 	})
 }
 
@@ -919,6 +1033,17 @@ type MagicNetworkSetupRequest struct {
 	MagicNetworkURL    string `json:"magicNetworkUrl" binding:"required"`
 	MagicNetworkAPIKey string `json:"magicNetworkApiKey" binding:"required"`
 	ServerEndpoint     string `json:"serverEndpoint" binding:"required"` // MagicNetwork endpoint (host:port)
+
+	// Optional tunnel tuning, e.g. mtu 1280-1380 on cellular links
+	MTU                 int      `json:"mtu"`
+	PersistentKeepalive int      `json:"persistentKeepalive"`
+	AllowedIPs          []string `json:"allowedIps"`
+
+	// Routing: "host" routes only routeHost (default the server's tunnel IP);
+	// extraAllowedIps are routed through the tunnel in addition
+	RouteMode       string   `json:"routeMode"`
+	RouteHost       string   `json:"routeHost"`
+	ExtraAllowedIPs []string `json:"extraAllowedIps"`
 }
 
 func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
@@ -930,6 +1055,24 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 	
 	cfg := s.config.Get()
 	
+	// Validate tunnel tuning before registering anything
+	tuning := wireguard.Config{
+		MTU:          req.MTU,
+		PersistentKA: req.PersistentKeepalive,
+		AllowedIPs:   req.AllowedIPs,
+
+		RouteMode:       req.RouteMode,
+		RouteHost:       req.RouteHost,
+		ExtraAllowedIPs: req.ExtraAllowedIPs,
+	}
+	if err := tuning.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if tuning.PersistentKA == 0 {
+		tuning.PersistentKA = wireguard.DefaultPersistentKA
+	}
+	
 	// Check WireGuard is installed
 	if !s.wireguard.IsInstalled() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "MagicNetwork not installed yet, please wait"})
@@ -945,10 +1088,21 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 	
 	// Call MagicNetwork API to register this node
 	wgResp, err := s.registerWithMagicNetwork(req.MagicNetworkURL, req.MagicNetworkAPIKey, cfg, publicKey)
+	if errors.Is(err, errMagicNetworkCircuitOpen) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("MagicNetwork registration failed: %v", err)})
 		return
 	}
+
+	// DNS pushed by MagicNetwork; a bad push shouldn't keep the tunnel down
+	dns := wireguard.Config{DNS: wgResp.DNS, SearchDomains: wgResp.SearchDomains}
+	if err := dns.Validate(); err != nil {
+		log.Printf("⚠️ Ignoring DNS pushed by MagicNetwork: %v", err)
+		dns = wireguard.Config{}
+	}
 	
 	// Save WireGuard config
 	wgCfg := config.WireGuardConfig{
@@ -962,6 +1116,15 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 		Configured:         true,
 		MagicNetworkURL:    req.MagicNetworkURL,
 		MagicNetworkAPIKey: req.MagicNetworkAPIKey,
+
+		MTU:                 tuning.MTU,
+		PersistentKeepalive: tuning.PersistentKA,
+		AllowedIPs:          tuning.AllowedIPs,
+		RouteMode:           tuning.RouteMode,
+		RouteHost:           tuning.RouteHost,
+		ExtraAllowedIPs:     tuning.ExtraAllowedIPs,
+		DNS:                 dns.DNS,
+		SearchDomains:       dns.SearchDomains,
 	}
 	
 	if err := s.config.SetWireGuard(wgCfg); err != nil {
@@ -976,7 +1139,17 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 		AssignedIP:     wgResp.AssignedIP,
 		ServerPubKey:   wgResp.ServerPubKey,
 		ServerEndpoint: req.ServerEndpoint,
-		PersistentKA:   25, // NAT keepalive
+		PersistentKA:   tuning.PersistentKA, // NAT keepalive
+		MTU:            tuning.MTU,
+		AllowedIPs:     tuning.AllowedIPs,
+		ServerIP:       wgResp.ServerIP,
+
+		RouteMode:       tuning.RouteMode,
+		RouteHost:       tuning.RouteHost,
+		ExtraAllowedIPs: tuning.ExtraAllowedIPs,
+
+		DNS:           dns.DNS,
+		SearchDomains: dns.SearchDomains,
 	}
 	
 	if err := s.wireguard.Configure(nativeConfig); err != nil {
@@ -989,6 +1162,7 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to bring up WireGuard: %v", err)})
 		return
 	}
+	s.wireguard.SetManualDown(false)
 	
 	// Enable on boot
 	s.wireguard.EnableOnBoot()
@@ -1005,9 +1179,11 @@ func (s *Server) handleAPIMagicNetworkSetup(c *gin.Context) {
 
 // MagicNetworkResponse from MagicNetwork API
 type MagicNetworkResponse struct {
-	AssignedIP   string `json:"assigned_ip"`
-	ServerPubKey string `json:"public_key"`
-	ServerIP     string `json:"server_ip"`
+	AssignedIP    string   `json:"assigned_ip"`
+	ServerPubKey  string   `json:"public_key"`
+	ServerIP      string   `json:"server_ip"`
+	DNS           []string `json:"dns,omitempty"`
+	SearchDomains []string `json:"search_domains,omitempty"`
 }
 
 // registerWithMagicNetwork calls MagicNetwork API to register this node
@@ -1029,49 +1205,7 @@ func (s *Server) registerWithMagicNetwork(url, apiKey string, cfg config.NodeCon
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 	
-	// Create request
-	req, err := http.NewRequest("POST", url+"/api/peers", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	
-	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("MagicNetwork API error (%d): %s", resp.StatusCode, string(respBody))
-	}
-	
-	// Parse response
-	var result struct {
-		Status string `json:"status"`
-		Peer   struct {
-			AssignedIP string `json:"assigned_ip"`
-		} `json:"peer"`
-		Server struct {
-			PublicKey string `json:"public_key"`
-			ServerIP  string `json:"server_ip"`
-		} `json:"server"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-	
-	return &MagicNetworkResponse{
-		AssignedIP:   result.Peer.AssignedIP,
-		ServerPubKey: result.Server.PublicKey,
-		ServerIP:     result.Server.ServerIP,
-	}, nil
+	return s.magicNetwork.register(url, apiKey, body)
 }
 
 func (s *Server) handleAPIMagicNetworkUp(c *gin.Context) {
@@ -1079,10 +1213,14 @@ func (s *Server) handleAPIMagicNetworkUp(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	s.wireguard.SetManualDown(false)
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// handleAPIMagicNetworkDown takes the tunnel down and keeps the handshake
+// monitor from bringing it back until it's brought up again
 func (s *Server) handleAPIMagicNetworkDown(c *gin.Context) {
+	s.wireguard.SetManualDown(true)
 	if err := s.wireguard.Down(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1095,6 +1233,93 @@ func (s *Server) handleAPIMagicNetworkRestart(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	s.wireguard.SetManualDown(false)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// MagicNetworkAPIKeyRequest replaces the MagicNetwork API key after a rotation
+type MagicNetworkAPIKeyRequest struct {
+	MagicNetworkAPIKey string `json:"magicNetworkApiKey" binding:"required"`
+}
+
+// handleAPIMagicNetworkAPIKey switches to a rotated MagicNetwork API key. The
+// key is checked against MagicNetwork first; the tunnel itself is unaffected.
+func (s *Server) handleAPIMagicNetworkAPIKey(c *gin.Context) {
+	var req MagicNetworkAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: magicNetworkApiKey"})
+		return
+	}
+
+	wgCfg := s.config.GetWireGuard()
+	if !wgCfg.Configured || wgCfg.MagicNetworkURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MagicNetwork is not configured"})
+		return
+	}
+
+	err := s.magicNetwork.verifyAPIKey(wgCfg.MagicNetworkURL, req.MagicNetworkAPIKey)
+	if errors.Is(err, errMagicNetworkCircuitOpen) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("API key check failed: %v", err)})
+		return
+	}
+
+	wgCfg.MagicNetworkAPIKey = req.MagicNetworkAPIKey
+	if err := s.config.SetWireGuard(wgCfg); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save config: %v", err)})
+		return
+	}
+
+	log.Printf("🔑 MagicNetwork API key updated")
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// handleAPIMagicNetworkTest pings the server through the tunnel and reports
+// the result to MagicNetwork so tunnel health is visible centrally
+func (s *Server) handleAPIMagicNetworkTest(c *gin.Context) {
+	wgCfg := s.config.GetWireGuard()
+	if !wgCfg.Configured {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "MagicNetwork is not configured"})
+		return
+	}
+
+	// In host mode only the route host is reachable through the tunnel
+	target := wgCfg.ServerIP
+	if wgCfg.RouteMode == wireguard.RouteModeHost && wgCfg.RouteHost != "" {
+		target = wgCfg.RouteHost
+	}
+	if target == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No server IP to test against"})
+		return
+	}
+
+	report := reachabilityReport{Target: target}
+	rtt, err := s.wireguard.Ping(target)
+	if err != nil {
+		report.Error = err.Error()
+	} else {
+		report.Reachable = true
+		report.RTTMs = float64(rtt.Microseconds()) / 1000
+	}
+
+	reported := true
+	var reportErr string
+	if err := s.magicNetwork.reportReachability(wgCfg.MagicNetworkURL, wgCfg.MagicNetworkAPIKey, wgCfg.PublicKey, report); err != nil {
+		reported = false
+		reportErr = err.Error()
+		log.Printf("⚠️ Failed to report tunnel test to MagicNetwork: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reachable":    report.Reachable,
+		"rtt_ms":       report.RTTMs,
+		"target":       report.Target,
+		"ping_error":   report.Error,
+		"reported":     reported,
+		"report_error": reportErr,
+	})
+}
+
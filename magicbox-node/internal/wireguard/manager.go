@@ -7,9 +7,11 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +27,18 @@ const (
 	ConfigDir  = "/etc/wireguard"
 	ConfigFile = "/etc/wireguard/wg-iris.conf"
 	KeyDir     = "/etc/wireguard/keys"
+
+	// Tunnel defaults, used when the setup doesn't override them
+	DefaultAllowedIPs   = "10.10.0.0/16" // route all 10.10.x.x traffic through the tunnel
+	DefaultPersistentKA = 25             // seconds; keeps NAT mappings open on 4G/5G
+
+	// Route modes: the overlay network (or AllowedIPs), or a single host
+	RouteModeOverlay = "overlay"
+	RouteModeHost    = "host"
+
+	// MTU bounds. Cellular links often need ~1280-1380 to avoid fragmentation.
+	MinMTU = 1280
+	MaxMTU = 1500
 )
 
 // Config holds WireGuard configuration from platform
@@ -34,8 +48,107 @@ type Config struct {
 	AssignedIP     string `json:"assigned_ip"`      // e.g., "10.10.0.10/24"
 	ServerPubKey   string `json:"server_pubkey"`    // Platform's public key
 	ServerEndpoint string `json:"server_endpoint"`  // e.g., "platform.example.com:51820"
-	DNS            string `json:"dns,omitempty"`    // Optional DNS server
+	DNS            []string `json:"dns,omitempty"`            // DNS servers pushed by the platform
+	SearchDomains  []string `json:"search_domains,omitempty"` // e.g. "iris.internal", so the backend resolves by short name
 	PersistentKA   int    `json:"persistent_keepalive"` // Keepalive interval (25 for NAT)
+	MTU            int      `json:"mtu,omitempty"`         // 0 = let wg-quick pick
+	AllowedIPs     []string `json:"allowed_ips,omitempty"` // empty = DefaultAllowedIPs
+	ServerIP       string   `json:"server_ip,omitempty"`   // Tunnel address of the server
+
+	// Routing: RouteModeHost routes only RouteHost (default ServerIP) instead of
+	// the overlay; ExtraAllowedIPs (e.g. a camera VLAN) are routed in either mode
+	RouteMode       string   `json:"route_mode,omitempty"`
+	RouteHost       string   `json:"route_host,omitempty"`
+	ExtraAllowedIPs []string `json:"extra_allowed_ips,omitempty"`
+}
+
+// Validate checks the tunnel tuning fields
+func (c *Config) Validate() error {
+	if c.MTU != 0 && (c.MTU < MinMTU || c.MTU > MaxMTU) {
+		return fmt.Errorf("mtu must be between %d and %d", MinMTU, MaxMTU)
+	}
+	for _, dns := range c.DNS {
+		if net.ParseIP(dns) == nil {
+			return fmt.Errorf("invalid DNS server %q, must be an IP address", dns)
+		}
+	}
+	for _, domain := range c.SearchDomains {
+		if !validSearchDomain(domain) {
+			return fmt.Errorf("invalid search domain %q", domain)
+		}
+	}
+	if len(c.SearchDomains) > 0 && len(c.DNS) == 0 {
+		return fmt.Errorf("search domains need a DNS server")
+	}
+	if c.PersistentKA < 0 || c.PersistentKA > 65535 {
+		return fmt.Errorf("persistent keepalive must be between 0 and 65535 seconds")
+	}
+	for _, cidr := range append(append([]string{}, c.AllowedIPs...), c.ExtraAllowedIPs...) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid allowed IP range %q", cidr)
+		}
+	}
+	switch c.RouteMode {
+	case "", RouteModeOverlay:
+		if c.RouteHost != "" {
+			return fmt.Errorf("route host is only used with route mode %q", RouteModeHost)
+		}
+	case RouteModeHost:
+		if len(c.AllowedIPs) > 0 {
+			return fmt.Errorf("allowed IPs can't be combined with route mode %q, use extra allowed IPs", RouteModeHost)
+		}
+		if c.RouteHost != "" && net.ParseIP(c.RouteHost) == nil {
+			return fmt.Errorf("invalid route host %q, must be an IP address", c.RouteHost)
+		}
+	default:
+		return fmt.Errorf("route mode must be %q or %q", RouteModeOverlay, RouteModeHost)
+	}
+	return nil
+}
+
+// validSearchDomain reports whether domain is a plausible DNS name
+func validSearchDomain(domain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || len(domain) > 253 || net.ParseIP(domain) != nil {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// routedIPs is the AllowedIPs list of the peer
+func (c *Config) routedIPs() ([]string, error) {
+	var routes []string
+	switch {
+	case c.RouteMode == RouteModeHost:
+		host := c.RouteHost
+		if host == "" {
+			host = c.ServerIP
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("route mode %q needs a route host or the server IP", RouteModeHost)
+		}
+		if ip.To4() != nil {
+			routes = []string{ip.String() + "/32"}
+		} else {
+			routes = []string{ip.String() + "/128"}
+		}
+	case len(c.AllowedIPs) > 0:
+		routes = append(routes, c.AllowedIPs...)
+	default:
+		routes = []string{DefaultAllowedIPs}
+	}
+	return append(routes, c.ExtraAllowedIPs...), nil
 }
 
 // Status represents current WireGuard status
@@ -57,6 +170,9 @@ type Manager struct {
 	configPath string
 	mu         sync.RWMutex
 	installing bool
+
+	monitorStop chan struct{} // closes to stop the handshake monitor
+	manualDown  bool          // taken down by the operator: the monitor leaves it down
 }
 
 // NewManager creates a new WireGuard manager
@@ -216,10 +332,15 @@ func (m *Manager) Configure(cfg *Config) error {
 		return fmt.Errorf("wireguard is not installed")
 	}
 
+	routes, err := cfg.routedIPs()
+	if err != nil {
+		return err
+	}
+
 	m.config = cfg
 
 	// Generate config file content
-	configContent := m.generateConfig(cfg)
+	configContent := m.generateConfig(cfg, routes)
 
 	// Ensure config directory exists
 	cmd := exec.Command("sudo", "mkdir", "-p", ConfigDir)
@@ -235,28 +356,32 @@ func (m *Manager) Configure(cfg *Config) error {
 }
 
 // generateConfig creates WireGuard config file content
-func (m *Manager) generateConfig(cfg *Config) string {
+func (m *Manager) generateConfig(cfg *Config, routes []string) string {
 	var sb strings.Builder
 
 	sb.WriteString("[Interface]\n")
 	sb.WriteString(fmt.Sprintf("PrivateKey = %s\n", cfg.PrivateKey))
 	sb.WriteString(fmt.Sprintf("Address = %s\n", cfg.AssignedIP))
 
-	if cfg.DNS != "" {
-		sb.WriteString(fmt.Sprintf("DNS = %s\n", cfg.DNS))
+	// wg-quick treats non-IP DNS entries as search domains
+	if len(cfg.DNS) > 0 {
+		entries := append(append([]string{}, cfg.DNS...), cfg.SearchDomains...)
+		sb.WriteString(fmt.Sprintf("DNS = %s\n", strings.Join(entries, ", ")))
+	}
+	if cfg.MTU > 0 {
+		sb.WriteString(fmt.Sprintf("MTU = %d\n", cfg.MTU))
 	}
 
 	sb.WriteString("\n[Peer]\n")
 	sb.WriteString(fmt.Sprintf("PublicKey = %s\n", cfg.ServerPubKey))
 	sb.WriteString(fmt.Sprintf("Endpoint = %s\n", cfg.ServerEndpoint))
-	// Route all 10.10.x.x traffic through tunnel
-	sb.WriteString("AllowedIPs = 10.10.0.0/16\n")
+	sb.WriteString(fmt.Sprintf("AllowedIPs = %s\n", strings.Join(routes, ", ")))
 
 	// Keepalive for NAT traversal (important for 4G/5G connections)
 	if cfg.PersistentKA > 0 {
 		sb.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", cfg.PersistentKA))
 	} else {
-		sb.WriteString("PersistentKeepalive = 25\n")
+		sb.WriteString(fmt.Sprintf("PersistentKeepalive = %d\n", DefaultPersistentKA))
 	}
 
 	return sb.String()
@@ -382,34 +507,43 @@ func (m *Manager) parseWgShow(output string, status *Status) {
 	}
 }
 
-// parseHandshakeTime converts handshake time string to time.Time
+// handshakeUnits maps the units `wg show` prints in "latest handshake" to durations
+var handshakeUnits = map[string]time.Duration{
+	"year":   365 * 24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"day":    24 * time.Hour,
+	"hour":   time.Hour,
+	"minute": time.Minute,
+	"second": time.Second,
+}
+
+// parseHandshakeTime converts a `wg show` handshake age such as
+// "1 hour, 5 minutes, 30 seconds ago" or "Now" to the handshake time.
+// Every component is summed. Returns the zero time if there was no handshake.
 func (m *Manager) parseHandshakeTime(timeStr string) time.Time {
-	// Simple parsing - just check if it contains "ago"
-	if !strings.Contains(timeStr, "ago") {
+	timeStr = strings.ToLower(strings.TrimSpace(timeStr))
+	if timeStr == "now" {
+		return time.Now()
+	}
+	if !strings.HasSuffix(timeStr, "ago") {
 		return time.Time{}
 	}
 
-	// Rough estimation
 	duration := time.Duration(0)
-
-	if strings.Contains(timeStr, "second") {
-		var secs int
-		fmt.Sscanf(timeStr, "%d second", &secs)
-		duration = time.Duration(secs) * time.Second
-	} else if strings.Contains(timeStr, "minute") {
-		var mins int
-		fmt.Sscanf(timeStr, "%d minute", &mins)
-		duration = time.Duration(mins) * time.Minute
-	} else if strings.Contains(timeStr, "hour") {
-		var hours int
-		fmt.Sscanf(timeStr, "%d hour", &hours)
-		duration = time.Duration(hours) * time.Hour
+	for _, part := range strings.Split(strings.TrimSuffix(timeStr, "ago"), ",") {
+		var n int
+		var unit string
+		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d %s", &n, &unit); err != nil {
+			return time.Time{}
+		}
+		d, ok := handshakeUnits[strings.TrimSuffix(unit, "s")]
+		if !ok {
+			return time.Time{}
+		}
+		duration += time.Duration(n) * d
 	}
 
-	if duration > 0 {
-		return time.Now().Add(-duration)
-	}
-	return time.Time{}
+	return time.Now().Add(-duration)
 }
 
 // parseTransferSize converts size string to bytes
@@ -453,7 +587,28 @@ func (m *Manager) writeFileWithSudo(path, content string, perm os.FileMode) erro
 
 // TestConnection tests connectivity to the server through tunnel
 func (m *Manager) TestConnection(serverIP string) bool {
-	cmd := exec.Command("ping", "-c", "1", "-W", "3", serverIP)
-	return cmd.Run() == nil
+	_, err := m.Ping(serverIP)
+	return err == nil
+}
+
+// Ping sends one echo request through the tunnel and returns the round trip
+func (m *Manager) Ping(ip string) (time.Duration, error) {
+	cmd := exec.Command("ping", "-c", "1", "-W", "3", ip)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("no reply from %s", ip)
+	}
+
+	// e.g. "64 bytes from 10.10.0.1: icmp_seq=1 ttl=64 time=23.4 ms"
+	out := string(output)
+	if i := strings.Index(out, "time="); i >= 0 {
+		fields := strings.Fields(out[i+len("time="):])
+		if len(fields) > 0 {
+			if ms, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				return time.Duration(ms * float64(time.Millisecond)), nil
+			}
+		}
+	}
+	return 0, nil
 }
 
@@ -0,0 +1,47 @@
+package wireguard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHandshakeTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		age    time.Duration
+		noTime bool // no handshake: the zero time
+	}{
+		{in: "Now", age: 0},
+		{in: "45 seconds ago", age: 45 * time.Second},
+		{in: "1 second ago", age: time.Second},
+		{in: "1 minute, 30 seconds ago", age: 90 * time.Second},
+		{in: "1 hour, 5 minutes, 2 seconds ago", age: time.Hour + 5*time.Minute + 2*time.Second},
+		{in: "2 days, 3 hours ago", age: 51 * time.Hour},
+		{in: "1 week, 1 day ago", age: 8 * 24 * time.Hour},
+		{in: "  3 Minutes ago ", age: 3 * time.Minute},
+		{in: "(none)", noTime: true},
+		{in: "", noTime: true},
+		{in: "a while ago", noTime: true},
+		{in: "5 fortnights ago", noTime: true},
+		{in: "1 minute, soon ago", noTime: true},
+		{in: "30 seconds", noTime: true},
+	}
+
+	m := &Manager{}
+	for _, tt := range tests {
+		got := m.parseHandshakeTime(tt.in)
+		if tt.noTime {
+			if !got.IsZero() {
+				t.Errorf("parseHandshakeTime(%q) = %v, want the zero time", tt.in, got)
+			}
+			continue
+		}
+		if got.IsZero() {
+			t.Errorf("parseHandshakeTime(%q) = zero time, want %s ago", tt.in, tt.age)
+			continue
+		}
+		if diff := time.Since(got) - tt.age; diff < 0 || diff > time.Second {
+			t.Errorf("parseHandshakeTime(%q) is %s ago, want %s", tt.in, time.Since(got).Round(time.Second), tt.age)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package wireguard
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// Handshake monitor defaults
+const (
+	DefaultMonitorInterval  = time.Minute
+	DefaultMonitorThreshold = 3
+)
+
+// RestartEvent describes an automatic tunnel restart
+type RestartEvent struct {
+	Time          time.Time `json:"time"`
+	Failures      int       `json:"failures"` // consecutive checks without a recent handshake
+	LastHandshake time.Time `json:"last_handshake,omitempty"`
+	InterfaceUp   bool      `json:"interface_up"`
+	Error         string    `json:"error,omitempty"` // set if the restart itself failed
+}
+
+// StartMonitor checks the tunnel every interval and restarts it after
+// threshold consecutive checks without a recent handshake. onRestart, if set,
+// is called after each restart. A non-positive interval or threshold disables
+// the monitor.
+func (m *Manager) StartMonitor(interval time.Duration, threshold int, onRestart func(RestartEvent)) {
+	if interval <= 0 || threshold <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.monitorStop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.monitorStop = stop
+	m.mu.Unlock()
+
+	log.Printf("🩺 WireGuard handshake monitor started (every %v, restart after %d failed checks)", interval, threshold)
+	go m.monitor(interval, threshold, onRestart, stop)
+}
+
+// SetManualDown records whether the operator took the tunnel down. While set,
+// the monitor doesn't restart it.
+func (m *Manager) SetManualDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.manualDown = down
+}
+
+// ManualDown reports whether the operator took the tunnel down
+func (m *Manager) ManualDown() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.manualDown
+}
+
+// StopMonitor stops the handshake monitor
+func (m *Manager) StopMonitor() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.monitorStop != nil {
+		close(m.monitorStop)
+		m.monitorStop = nil
+	}
+}
+
+func (m *Manager) monitor(interval time.Duration, threshold int, onRestart func(RestartEvent), stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Nothing to keep alive until the tunnel has been set up, or while the
+		// operator wants it down
+		if _, err := os.Stat(m.configPath); err != nil || m.ManualDown() {
+			failures = 0
+			continue
+		}
+
+		status := m.GetStatus()
+		if !status.Installed || status.Connected {
+			failures = 0
+			continue
+		}
+
+		failures++
+		log.Printf("⚠️ WireGuard tunnel has no recent handshake (%d/%d checks, last: %v)", failures, threshold, status.LastHandshake)
+		if failures < threshold {
+			continue
+		}
+
+		event := RestartEvent{
+			Time:          time.Now(),
+			Failures:      failures,
+			LastHandshake: status.LastHandshake,
+			InterfaceUp:   status.InterfaceUp,
+		}
+		if err := m.Restart(); err != nil {
+			event.Error = err.Error()
+			log.Printf("❌ WireGuard tunnel restart failed: %v", err)
+		} else {
+			log.Printf("🔄 WireGuard tunnel restarted after %d failed handshake checks", failures)
+		}
+		failures = 0
+
+		if onRestart != nil {
+			onRestart(event)
+		}
+	}
+}
@@ -94,6 +94,10 @@ class BaseWorker(ABC):
         self.cameras = cameras or os.environ.get('CAMERAS', '').split(',')
         self.cameras = [c.strip() for c in self.cameras if c.strip()]
         
+        # Subscribe to per-analytic frames (frames.<camera>.<analytic>) when the
+        # node runs with -partition-frames, so only cameras with this analytic are received
+        self.frame_analytic = os.environ.get('FRAME_ANALYTIC', '').strip()
+        
         self.nats_url = nats_url or os.environ.get('NATS_URL', 'nats://localhost:4222')
         self.platform_url = platform_url or os.environ.get('PLATFORM_URL', 'http://localhost:3001')
         self.worker_id = worker_id or os.environ.get('WORKER_ID', f'{worker_type}_{os.getpid()}')
@@ -261,17 +265,18 @@ class BaseWorker(ABC):
             
             # Subscribe to camera frames
             subscriptions = []
+            suffix = f".{self.frame_analytic}" if self.frame_analytic else ""
             for camera in self.cameras:
-                subject = f"frames.{camera}"
+                subject = f"frames.{camera}{suffix}"
                 sub = await self.nc.subscribe(subject, cb=self._handle_message)
                 subscriptions.append(sub)
                 self.logger.info(f"Subscribed to: {subject}")
             
             # Also subscribe to wildcard if no specific cameras
             if not self.cameras:
-                sub = await self.nc.subscribe("frames.*", cb=self._handle_message)
+                sub = await self.nc.subscribe(f"frames.*{suffix}", cb=self._handle_message)
                 subscriptions.append(sub)
-                self.logger.info("Subscribed to: frames.* (all cameras)")
+                self.logger.info(f"Subscribed to: frames.*{suffix} (all cameras)")
             
             self.logger.info(f"🚀 {self.worker_type} worker running")
             
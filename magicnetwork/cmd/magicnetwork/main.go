@@ -2,8 +2,6 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
@@ -11,6 +9,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +33,9 @@ StandardError=journal
 WantedBy=multi-user.target
 `
 
+// apiKeyFile in the data directory holds the key after a rotation
+const apiKeyFile = "api_key"
+
 func main() {
 	// Parse flags
 	port := flag.Int("port", 8080, "API server port")
@@ -41,6 +43,14 @@ func main() {
 	address := flag.String("address", "10.10.0.1/24", "WireGuard server address")
 	dataDir := flag.String("data", "/var/lib/magicnetwork", "Data directory")
 	apiKey := flag.String("api-key", "", "API key for authentication (auto-generated if empty)")
+	authMaxFailures := flag.Int("auth-max-failures", api.DefaultAuthMaxFailures, "Failed API key attempts per IP before a lockout")
+	authWindow := flag.Duration("auth-window", api.DefaultAuthWindow, "Window in which failed API key attempts are counted")
+	authLockout := flag.Duration("auth-lockout", api.DefaultAuthLockout, "How long an IP is locked out after too many failed attempts")
+	dns := flag.String("dns", "", "Comma-separated DNS servers pushed to peers, e.g. 10.10.0.1")
+	searchDomains := flag.String("search-domains", "", "Comma-separated search domains pushed to peers, e.g. iris.internal")
+	isolatePeers := flag.Bool("isolate-peers", false, "Block peer-to-peer traffic so peers can only reach the server")
+	readyMinPeers := flag.Int("ready-min-peers", 0, "Peers that must be loaded before /ready reports ready")
+	keyOverlap := flag.Duration("key-overlap", api.DefaultKeyOverlap, "How long the previous API key stays valid after a rotation")
 	genKey := flag.Bool("gen-key", false, "Generate a new API key and exit")
 	install := flag.Bool("install", false, "Install as systemd service and start")
 	uninstall := flag.Bool("uninstall", false, "Uninstall systemd service")
@@ -48,7 +58,7 @@ func main() {
 
 	// Generate API key mode
 	if *genKey {
-		key := generateAPIKey()
+		key := api.GenerateAPIKey()
 		fmt.Printf("Generated API Key: %s\n", key)
 		return
 	}
@@ -65,29 +75,45 @@ func main() {
 		return
 	}
 
-	// Get API key from flag or environment
-	key := *apiKey
+	// Ensure data directory exists
+	if err := os.MkdirAll(*dataDir, 0755); err != nil {
+		log.Fatalf("❌ Failed to create data directory: %v", err)
+	}
+
+	// A key rotated through the API replaces the one from flag or environment
+	keyFile := filepath.Join(*dataDir, apiKeyFile)
+	key, err := api.LoadAPIKey(keyFile)
+	if err != nil {
+		log.Fatalf("❌ Failed to read API key file: %v", err)
+	}
+	if key != "" {
+		log.Printf("🔑 Using rotated API key from %s", keyFile)
+	}
+	if key == "" {
+		key = *apiKey
+	}
 	if key == "" {
 		key = os.Getenv("MAGICNETWORK_API_KEY")
 	}
 	if key == "" {
 		// Generate one
-		key = generateAPIKey()
+		key = api.GenerateAPIKey()
 		log.Printf("⚠️  No API key provided, generated: %s", key)
 		log.Printf("   Set MAGICNETWORK_API_KEY or use --api-key flag")
 	}
 
-	// Ensure data directory exists
-	if err := os.MkdirAll(*dataDir, 0755); err != nil {
-		log.Fatalf("❌ Failed to create data directory: %v", err)
-	}
-
 	// Initialize WireGuard server
 	wg, err := wireguard.NewServer(*dataDir, *wgPort, *address)
 	if err != nil {
 		log.Fatalf("❌ Failed to create WireGuard server: %v", err)
 	}
 
+	if err := wg.SetDNS(splitList(*dns), splitList(*searchDomains)); err != nil {
+		log.Fatalf("❌ Invalid DNS settings: %v", err)
+	}
+
+	wg.SetIsolatePeers(*isolatePeers)
+
 	// Initialize server keys
 	if err := wg.Initialize(); err != nil {
 		log.Fatalf("❌ Failed to initialize WireGuard: %v", err)
@@ -98,18 +124,30 @@ func main() {
 		log.Fatalf("❌ Failed to start WireGuard: %v", err)
 	}
 
+	// Re-sync the peer registry with the live interface
+	if err := wg.Reconcile(); err != nil {
+		log.Printf("⚠️ Failed to reconcile peers: %v", err)
+	}
+
 	// Setup API
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(gin.Logger())
+	// Public keys are base64 and may contain "/", which clients path-escape
+	router.UseRawPath = true
 
 	apiHandler := api.NewAPI(wg, key)
+	apiHandler.SetKeyRotation(keyFile, *keyOverlap)
+	apiHandler.SetReadyMinPeers(*readyMinPeers)
+	apiHandler.SetAuthLimits(*authMaxFailures, *authWindow, *authLockout)
 
 	// Public endpoints (no auth required)
+	// /health is liveness (process up), /ready is readiness (VPN serving)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	router.GET("/ready", apiHandler.GetReadiness)
 	router.GET("/api/info", apiHandler.GetServerInfo)
 
 	// Protected endpoints
@@ -117,10 +155,13 @@ func main() {
 	protected.Use(apiHandler.AuthMiddleware())
 	{
 		protected.GET("/status", apiHandler.GetStatus)
+		protected.POST("/rotate-key", apiHandler.RotateKey)
 		protected.GET("/peers", apiHandler.GetPeers)
 		protected.POST("/peers", apiHandler.RegisterPeer)
 		protected.GET("/peers/:pubkey", apiHandler.GetPeer)
 		protected.DELETE("/peers/:pubkey", apiHandler.RemovePeer)
+		protected.POST("/peers/:pubkey/check", apiHandler.CheckPeer)
+		protected.POST("/peers/:pubkey/reachability", apiHandler.ReportReachability)
 	}
 
 	// Print startup info
@@ -158,10 +199,15 @@ func main() {
 	}
 }
 
-func generateAPIKey() string {
-	b := make([]byte, 32)
-	rand.Read(b)
-	return "mn_" + hex.EncodeToString(b)
+// splitList splits a comma-separated flag, dropping blanks
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func installService(port, wgPort int, address, dataDir string) {
@@ -204,7 +250,7 @@ func installService(port, wgPort int, address, dataDir string) {
 	}
 
 	// Generate API key
-	apiKey := generateAPIKey()
+	apiKey := api.GenerateAPIKey()
 	fmt.Printf("🔑 Generated API Key: %s\n", apiKey)
 
 	// Save API key to file for reference
@@ -215,6 +261,9 @@ func installService(port, wgPort int, address, dataDir string) {
 		fmt.Printf("📝 API key saved to: %s\n", keyFile)
 	}
 
+	// A key rotated under a previous install would override the new one
+	os.Remove(filepath.Join(dataDir, apiKeyFile))
+
 	// Create systemd service file
 	serviceContent := fmt.Sprintf(systemdService,
 		installPath, port, wgPort, address, dataDir, apiKey)
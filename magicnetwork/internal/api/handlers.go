@@ -3,7 +3,9 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/irisdrone/magicnetwork/internal/wireguard"
@@ -11,21 +13,36 @@ import (
 
 // API handles HTTP requests
 type API struct {
-	wg     *wireguard.Server
-	apiKey string
+	wg      *wireguard.Server
+	keys    *keyring
+	limiter *authLimiter
+
+	readyMinPeers int // peers that must be loaded for /ready to pass
 }
 
 // NewAPI creates a new API handler
 func NewAPI(wg *wireguard.Server, apiKey string) *API {
 	return &API{
-		wg:     wg,
-		apiKey: apiKey,
+		wg:      wg,
+		keys:    &keyring{current: apiKey, overlap: DefaultKeyOverlap},
+		limiter: newAuthLimiter(),
 	}
 }
 
-// AuthMiddleware validates API key
+// AuthMiddleware validates API key, locking out IPs that keep failing
 func (a *API) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// RemoteIP, not ClientIP: forwarded headers are client-controlled and
+		// would let an attacker dodge the lockout
+		ip := c.RemoteIP()
+		if locked, wait := a.limiter.locked(ip); locked {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many failed authentication attempts, try again later",
+			})
+			return
+		}
+
 		// Check Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -36,12 +53,20 @@ func (a *API) AuthMiddleware() gin.HandlerFunc {
 		// Remove "Bearer " prefix if present
 		authHeader = strings.TrimPrefix(authHeader, "Bearer ")
 
-		if authHeader != a.apiKey {
+		ok, previous := a.keys.check(authHeader)
+		if !ok {
+			a.limiter.failed(ip)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid or missing API key",
 			})
 			return
 		}
+		a.limiter.succeeded(ip)
+		if previous {
+			// Still inside the overlap window after a rotation
+			c.Set(previousKeyContext, true)
+			c.Header("X-API-Key-Deprecated", "true")
+		}
 
 		c.Next()
 	}
@@ -85,6 +110,10 @@ func (a *API) RegisterPeer(c *gin.Context) {
 			"endpoint":    c.Request.Host, // Will be replaced by actual endpoint
 			"listen_port": cfg.ListenPort,
 			"server_ip":   strings.Split(cfg.Address, "/")[0],
+
+			"dns":            cfg.DNS,
+			"search_domains": cfg.SearchDomains,
+			"isolate_peers":  cfg.IsolatePeers, // only the server is reachable, not other peers
 		},
 	})
 }
@@ -131,6 +160,69 @@ func (a *API) RemovePeer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// CheckPeer pings a peer's assigned IP through the tunnel
+// POST /api/peers/:pubkey/check
+func (a *API) CheckPeer(c *gin.Context) {
+	pubKey := c.Param("pubkey")
+
+	if a.wg.GetPeer(pubKey) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Peer not found"})
+		return
+	}
+
+	result, err := a.wg.CheckPeer(pubKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ReportReachabilityRequest is a tunnel test run by the peer itself
+type ReportReachabilityRequest struct {
+	Reachable *bool   `json:"reachable" binding:"required"`
+	RTTMs     float64 `json:"rtt_ms"`
+	Target    string  `json:"target"`
+	Error     string  `json:"error"`
+}
+
+// ReportReachability records a tunnel test reported by a peer
+// POST /api/peers/:pubkey/reachability
+func (a *API) ReportReachability(c *gin.Context) {
+	pubKey := c.Param("pubkey")
+
+	var req ReportReachabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.RTTMs < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rtt_ms must not be negative"})
+		return
+	}
+
+	if a.wg.GetPeer(pubKey) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Peer not found"})
+		return
+	}
+
+	result := &wireguard.Reachability{
+		Reachable: *req.Reachable,
+		RTTMs:     req.RTTMs,
+		Target:    req.Target,
+		Source:    wireguard.ReachabilitySourcePeer,
+		CheckedAt: time.Now(),
+		Error:     req.Error,
+	}
+	if err := a.wg.RecordReachability(pubKey, result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GetStatus returns server status
 // GET /api/status
 func (a *API) GetStatus(c *gin.Context) {
@@ -160,6 +252,22 @@ func (a *API) GetStatus(c *gin.Context) {
 	})
 }
 
+// GetReadiness reports whether the VPN is operational, with 503 when it isn't
+// GET /ready
+func (a *API) GetReadiness(c *gin.Context) {
+	r := a.wg.CheckReadiness(a.readyMinPeers)
+	if !r.Ready {
+		c.JSON(http.StatusServiceUnavailable, r)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// SetReadyMinPeers sets how many peers must be loaded before /ready passes
+func (a *API) SetReadyMinPeers(n int) {
+	a.readyMinPeers = n
+}
+
 // GetServerInfo returns server connection info (public endpoint)
 // GET /api/info
 func (a *API) GetServerInfo(c *gin.Context) {
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultKeyOverlap is how long the previous API key keeps working after a rotation
+const DefaultKeyOverlap = 10 * time.Minute
+
+// MaxKeyOverlap caps the overlap a rotation request can ask for
+const MaxKeyOverlap = 24 * time.Hour
+
+// previousKeyContext marks requests authenticated with the previous key
+const previousKeyContext = "api_key_previous"
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "mn_" + hex.EncodeToString(b)
+}
+
+// LoadAPIKey reads a persisted API key, returning "" if there is none
+func LoadAPIKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveAPIKey writes the key atomically so a crash can't leave a partial key
+func saveAPIKey(path, key string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(key+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// keyring holds the current API key and, during an overlap window, the previous one
+type keyring struct {
+	mu              sync.RWMutex
+	current         string
+	previous        string
+	previousExpires time.Time
+
+	keyFile string        // where rotated keys are persisted
+	overlap time.Duration // default overlap window
+}
+
+// check reports whether key is accepted and whether it's the previous key
+func (k *keyring) check(key string) (ok, previous bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k.current)) == 1 {
+		return true, false
+	}
+	if k.previous != "" && time.Now().Before(k.previousExpires) &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(k.previous)) == 1 {
+		return true, true
+	}
+	return false, false
+}
+
+// rotate replaces the current key, keeping the old one valid for overlap
+func (k *keyring) rotate(overlap time.Duration) (string, time.Time, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := GenerateAPIKey()
+	if k.keyFile != "" {
+		if err := saveAPIKey(k.keyFile, key); err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to persist API key: %w", err)
+		}
+	}
+
+	k.previous = k.current
+	k.previousExpires = time.Now().Add(overlap)
+	k.current = key
+	return key, k.previousExpires, nil
+}
+
+// SetKeyRotation configures where rotated API keys are persisted and how long
+// the previous key stays valid by default. A zero overlap keeps the default.
+func (a *API) SetKeyRotation(keyFile string, overlap time.Duration) {
+	a.keys.mu.Lock()
+	defer a.keys.mu.Unlock()
+
+	a.keys.keyFile = keyFile
+	if overlap > 0 {
+		a.keys.overlap = overlap
+	}
+}
+
+// RotateKeyRequest optionally overrides the overlap window
+type RotateKeyRequest struct {
+	OverlapSeconds int `json:"overlap_seconds"`
+}
+
+// RotateKey generates a new API key. The previous key keeps working for the
+// overlap window so nodes can be switched over without downtime.
+// POST /api/rotate-key
+func (a *API) RotateKey(c *gin.Context) {
+	if c.GetBool(previousKeyContext) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Rotation requires the current API key"})
+		return
+	}
+
+	var req RotateKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	a.keys.mu.RLock()
+	overlap := a.keys.overlap
+	a.keys.mu.RUnlock()
+	if req.OverlapSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "overlap_seconds must not be negative"})
+		return
+	}
+	if req.OverlapSeconds > 0 {
+		overlap = time.Duration(req.OverlapSeconds) * time.Second
+	}
+	if overlap > MaxKeyOverlap {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("overlap must be at most %v", MaxKeyOverlap)})
+		return
+	}
+
+	key, previousExpires, err := a.keys.rotate(overlap)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🔑 API key rotated, previous key valid until %s", previousExpires.Format(time.RFC3339))
+	c.JSON(http.StatusOK, gin.H{
+		"status":                  "ok",
+		"api_key":                 key,
+		"previous_key_expires_at": previousExpires,
+		"overlap_seconds":         int(overlap.Seconds()),
+	})
+}
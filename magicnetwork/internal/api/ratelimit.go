@@ -0,0 +1,119 @@
+package api
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Auth brute-force protection defaults
+const (
+	DefaultAuthMaxFailures = 10
+	DefaultAuthWindow      = 5 * time.Minute
+	DefaultAuthLockout     = 15 * time.Minute
+
+	// authPruneSize is how many tracked IPs trigger pruning of stale entries
+	authPruneSize = 1024
+)
+
+// authFailure tracks failed authentication attempts from one IP
+type authFailure struct {
+	count       int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// authLimiter locks out IPs that fail authentication too often
+type authLimiter struct {
+	mu          sync.Mutex
+	maxFailures int           // failures within window that trigger a lockout
+	window      time.Duration // how long failures are counted
+	lockout     time.Duration // how long a locked out IP is refused
+	failures    map[string]*authFailure
+}
+
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{
+		maxFailures: DefaultAuthMaxFailures,
+		window:      DefaultAuthWindow,
+		lockout:     DefaultAuthLockout,
+		failures:    make(map[string]*authFailure),
+	}
+}
+
+// SetAuthLimits configures brute-force protection: an IP with maxFailures
+// failed attempts within window is refused for lockout. Zero values keep the
+// defaults.
+func (a *API) SetAuthLimits(maxFailures int, window, lockout time.Duration) {
+	a.limiter.mu.Lock()
+	defer a.limiter.mu.Unlock()
+
+	if maxFailures > 0 {
+		a.limiter.maxFailures = maxFailures
+	}
+	if window > 0 {
+		a.limiter.window = window
+	}
+	if lockout > 0 {
+		a.limiter.lockout = lockout
+	}
+}
+
+// locked reports whether ip is locked out, and for how much longer
+func (l *authLimiter) locked(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[ip]
+	if !ok {
+		return false, 0
+	}
+	if wait := time.Until(f.lockedUntil); wait > 0 {
+		return true, wait
+	}
+	return false, 0
+}
+
+// failed records a failed attempt from ip, locking it out at the threshold
+func (l *authLimiter) failed(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.failures) >= authPruneSize {
+		l.prune(now)
+	}
+
+	f, ok := l.failures[ip]
+	if !ok || now.Sub(f.windowStart) > l.window {
+		f = &authFailure{windowStart: now}
+		l.failures[ip] = f
+	}
+	f.count++
+
+	if f.count >= l.maxFailures {
+		f.lockedUntil = now.Add(l.lockout)
+		log.Printf("🚫 Locked out %s for %v after %d failed API key attempts", ip, l.lockout, f.count)
+		// Start counting afresh once the lockout ends
+		f.count = 0
+		f.windowStart = f.lockedUntil
+		return
+	}
+	log.Printf("⚠️ Failed API key attempt from %s (%d/%d)", ip, f.count, l.maxFailures)
+}
+
+// succeeded clears the failures of ip
+func (l *authLimiter) succeeded(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+// prune drops IPs that are neither locked out nor inside a failure window
+func (l *authLimiter) prune(now time.Time) {
+	for ip, f := range l.failures {
+		if now.After(f.lockedUntil) && now.Sub(f.windowStart) > l.window {
+			delete(l.failures, ip)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package wireguard
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Readiness reports whether the VPN is actually serving, not just whether
+// the process is alive
+type Readiness struct {
+	Ready         bool     `json:"ready"`
+	InterfaceUp   bool     `json:"interface_up"`
+	ListenPort    int      `json:"listen_port"` // port the interface is bound to, 0 if none
+	PortBound     bool     `json:"port_bound"`  // bound to the configured port
+	PeersLoaded   bool     `json:"peers_loaded"`
+	PeerCount     int      `json:"peer_count"`
+	FailedReasons []string `json:"failed_reasons,omitempty"`
+}
+
+// CheckReadiness verifies the interface exists, WireGuard is listening on the
+// configured port and the peer registry loaded with at least minPeers peers
+func (s *Server) CheckReadiness(minPeers int) Readiness {
+	var r Readiness
+
+	if exec.Command("ip", "link", "show", InterfaceName).Run() == nil {
+		r.InterfaceUp = true
+	} else {
+		r.FailedReasons = append(r.FailedReasons, fmt.Sprintf("interface %s does not exist", InterfaceName))
+	}
+
+	if r.InterfaceUp {
+		output, err := exec.Command("wg", "show", InterfaceName, "listen-port").Output()
+		if err == nil {
+			r.ListenPort, _ = strconv.Atoi(strings.TrimSpace(string(output)))
+		}
+		r.PortBound = r.ListenPort != 0 && r.ListenPort == s.config.ListenPort
+		if !r.PortBound {
+			r.FailedReasons = append(r.FailedReasons, fmt.Sprintf("WireGuard is not listening on port %d", s.config.ListenPort))
+		}
+	}
+
+	s.mu.RLock()
+	r.PeerCount = len(s.peers)
+	loadErr := s.loadErr
+	s.mu.RUnlock()
+
+	r.PeersLoaded = loadErr == nil
+	if !r.PeersLoaded {
+		r.FailedReasons = append(r.FailedReasons, fmt.Sprintf("peer registry failed to load: %v", loadErr))
+	} else if r.PeerCount < minPeers {
+		r.PeersLoaded = false
+		r.FailedReasons = append(r.FailedReasons, fmt.Sprintf("only %d peers loaded, expected at least %d", r.PeerCount, minPeers))
+	}
+
+	r.Ready = r.InterfaceUp && r.PortBound && r.PeersLoaded
+	return r
+}
@@ -0,0 +1,48 @@
+package wireguard
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+)
+
+// isolationRule drops traffic the server would forward from one peer to
+// another, leaving peer <-> server traffic alone
+var isolationRule = []string{"FORWARD", "-i", InterfaceName, "-o", InterfaceName, "-j", "DROP"}
+
+// SetIsolatePeers enables spoke isolation: peers can reach the server but not
+// each other, and each peer's server-side AllowedIPs is held to its own /32.
+// Call before Start.
+func (s *Server) SetIsolatePeers(isolate bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.IsolatePeers = isolate
+}
+
+// applyIsolation adds or removes the forwarding rule to match the config, so
+// toggling the option takes effect even if the interface was already up
+func (s *Server) applyIsolation() error {
+	s.mu.RLock()
+	isolate := s.config.IsolatePeers
+	s.mu.RUnlock()
+
+	present := exec.Command("iptables", append([]string{"-C"}, isolationRule...)...).Run() == nil
+	switch {
+	case isolate && !present:
+		if output, err := exec.Command("iptables", append([]string{"-I"}, isolationRule...)...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to add peer isolation rule: %s - %w", output, err)
+		}
+		log.Printf("🧱 Peer isolation enabled: peers can only reach the server")
+	case !isolate && present:
+		if output, err := exec.Command("iptables", append([]string{"-D"}, isolationRule...)...).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to remove peer isolation rule: %s - %w", output, err)
+		}
+		log.Printf("🔓 Peer isolation disabled")
+	}
+	return nil
+}
+
+// isolatedAllowedIPs is the only server-side AllowedIPs an isolated peer gets
+func isolatedAllowedIPs(peer *Peer) string {
+	return peer.AssignedIP + "/32"
+}
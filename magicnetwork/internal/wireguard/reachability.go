@@ -0,0 +1,79 @@
+package wireguard
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Reachability check sources
+const (
+	ReachabilitySourceServer = "server" // MagicNetwork pinged the peer's assigned IP
+	ReachabilitySourcePeer   = "peer"   // The peer reported its own tunnel test
+)
+
+// pingCount is how many echo requests a server-side check sends
+const pingCount = 3
+
+// pingAvgRe matches the summary line of iputils and busybox ping, e.g.
+// "rtt min/avg/max/mdev = 0.042/0.051/0.063/0.008 ms"
+var pingAvgRe = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+
+// Reachability is the result of the latest tunnel check of a peer
+type Reachability struct {
+	Reachable bool      `json:"reachable"`
+	RTTMs     float64   `json:"rtt_ms,omitempty"` // Average round trip
+	Target    string    `json:"target"`           // IP that was pinged
+	Source    string    `json:"source"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// CheckPeer pings a peer's assigned IP through the tunnel and records the result
+func (s *Server) CheckPeer(publicKey string) (*Reachability, error) {
+	peer := s.GetPeer(publicKey)
+	if peer == nil {
+		return nil, fmt.Errorf("peer not found")
+	}
+
+	result := &Reachability{
+		Target:    peer.AssignedIP,
+		Source:    ReachabilitySourceServer,
+		CheckedAt: time.Now(),
+	}
+
+	cmd := exec.Command("ping", "-c", strconv.Itoa(pingCount), "-W", "2", peer.AssignedIP)
+	output, err := cmd.Output()
+	if err != nil {
+		result.Error = fmt.Sprintf("no reply from %s", peer.AssignedIP)
+	} else {
+		result.Reachable = true
+		if m := pingAvgRe.FindSubmatch(output); m != nil {
+			result.RTTMs, _ = strconv.ParseFloat(string(m[1]), 64)
+		}
+	}
+
+	if err := s.RecordReachability(publicKey, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RecordReachability stores the latest tunnel check of a peer
+func (s *Server) RecordReachability(publicKey string, result *Reachability) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	peer, ok := s.peers[publicKey]
+	if !ok {
+		return fmt.Errorf("peer not found")
+	}
+
+	peer.Reachability = result
+	if result.Reachable {
+		peer.LastSeen = result.CheckedAt
+	}
+	return s.savePeers()
+}
@@ -3,13 +3,13 @@ package wireguard
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,6 +31,13 @@ type ServerConfig struct {
 	PublicKey   string `json:"public_key"`
 	IPPoolStart string `json:"ip_pool_start"` // e.g., "10.10.0.2"
 	IPPoolEnd   string `json:"ip_pool_end"`   // e.g., "10.10.255.254"
+
+	// Block peer-to-peer forwarding so peers only reach the server
+	IsolatePeers bool `json:"isolate_peers"`
+
+	// Pushed to peers at registration for their [Interface] section
+	DNS           []string `json:"dns,omitempty"`
+	SearchDomains []string `json:"search_domains,omitempty"`
 }
 
 // Peer represents a registered MagicBox node
@@ -45,6 +52,8 @@ type Peer struct {
 	TransferRx  uint64    `json:"transfer_rx,omitempty"`
 	TransferTx  uint64    `json:"transfer_tx,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	Reachability *Reachability `json:"reachability,omitempty"` // Latest tunnel check
 }
 
 // Server manages WireGuard server
@@ -53,6 +62,10 @@ type Server struct {
 	peers    map[string]*Peer // keyed by public key
 	dataDir  string
 	mu       sync.RWMutex
+
+	configFile string // wg-quick config, rewritten as peers change
+
+	loadErr error // why the peer registry couldn't be loaded, if it couldn't
 }
 
 // NewServer creates a new WireGuard server manager
@@ -64,13 +77,15 @@ func NewServer(dataDir string, listenPort int, address string) (*Server, error)
 			IPPoolStart: "10.10.0.2",
 			IPPoolEnd:   "10.10.255.254",
 		},
-		peers:   make(map[string]*Peer),
-		dataDir: dataDir,
+		peers:      make(map[string]*Peer),
+		dataDir:    dataDir,
+		configFile: ConfigFile,
 	}
 
 	// Load existing peers
 	if err := s.loadPeers(); err != nil {
 		log.Printf("⚠️ Could not load existing peers: %v", err)
+		s.loadErr = err
 	}
 
 	return s, nil
@@ -154,7 +169,7 @@ func (s *Server) Start() error {
 	cmd := exec.Command("ip", "link", "show", InterfaceName)
 	if cmd.Run() == nil {
 		log.Printf("✅ WireGuard interface %s already running", InterfaceName)
-		return nil
+		return s.applyIsolation()
 	}
 
 	// Write config file
@@ -170,7 +185,7 @@ func (s *Server) Start() error {
 	}
 
 	log.Printf("⚡ WireGuard interface %s started", InterfaceName)
-	return nil
+	return s.applyIsolation()
 }
 
 // enableIPForwarding enables IP forwarding for routing between peers
@@ -203,6 +218,13 @@ func (s *Server) Stop() error {
 
 // writeConfig writes the WireGuard config file
 func (s *Server) writeConfig() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writeConfigLocked()
+}
+
+// writeConfigLocked writes the WireGuard config file. Callers hold s.mu.
+func (s *Server) writeConfigLocked() error {
 	var sb strings.Builder
 
 	sb.WriteString("[Interface]\n")
@@ -213,35 +235,35 @@ func (s *Server) writeConfig() error {
 	sb.WriteString("\n")
 
 	// Add peers
-	s.mu.RLock()
 	for _, peer := range s.peers {
 		sb.WriteString("[Peer]\n")
 		sb.WriteString(fmt.Sprintf("PublicKey = %s\n", peer.PublicKey))
 		sb.WriteString(fmt.Sprintf("AllowedIPs = %s\n", peer.AllowedIPs))
 		sb.WriteString("\n")
 	}
-	s.mu.RUnlock()
 
 	// Write file
-	if err := os.MkdirAll(ConfigDir, 0700); err != nil {
+	if err := os.MkdirAll(filepath.Dir(s.configFile), 0700); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(ConfigFile, []byte(sb.String()), 0600); err != nil {
+	if err := os.WriteFile(s.configFile, []byte(sb.String()), 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
-// RegisterPeer registers a new peer and returns assigned IP
+// RegisterPeer registers a new peer and returns assigned IP. Registrations
+// are serialized by s.mu from IP allocation through persistence, so
+// concurrent calls never hand out the same IP.
 func (s *Server) RegisterPeer(id, name, publicKey string) (*Peer, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if peer already exists
 	if existing, ok := s.peers[publicKey]; ok {
-		return existing, nil
+		return existing.clone(), nil
 	}
 
 	// Allocate IP
@@ -268,7 +290,7 @@ func (s *Server) RegisterPeer(id, name, publicKey string) (*Peer, error) {
 	}
 
 	// Rewrite config file to include new peer
-	if err := s.writeConfig(); err != nil {
+	if err := s.writeConfigLocked(); err != nil {
 		log.Printf("⚠️ Failed to rewrite config file: %v", err)
 	}
 
@@ -278,7 +300,7 @@ func (s *Server) RegisterPeer(id, name, publicKey string) (*Peer, error) {
 	}
 
 	log.Printf("✅ Registered peer: %s (%s) -> %s", name, id, ip)
-	return peer, nil
+	return peer.clone(), nil
 }
 
 // RemovePeer removes a peer
@@ -321,7 +343,8 @@ func (s *Server) addPeerToWG(peer *Peer) error {
 	return nil
 }
 
-// allocateIP finds the next available IP
+// allocateIP finds the next available IP. Callers hold s.mu for writing
+// until the peer using the IP is in s.peers.
 func (s *Server) allocateIP() (string, error) {
 	usedIPs := make(map[string]bool)
 	for _, peer := range s.peers {
@@ -336,17 +359,41 @@ func (s *Server) allocateIP() (string, error) {
 		return "", fmt.Errorf("invalid IP pool configuration")
 	}
 
+	// Never hand out the server's own address or one outside its subnet
+	serverIP, subnet, err := net.ParseCIDR(s.config.Address)
+	if err != nil {
+		return "", fmt.Errorf("invalid server address %q: %w", s.config.Address, err)
+	}
+	usedIPs[serverIP.String()] = true
+
 	// Find next available
-	for ip := startIP; !ip.Equal(endIP); incrementIP(ip) {
+	for ip := startIP; ; incrementIP(ip) {
 		ipStr := ip.String()
-		if !usedIPs[ipStr] {
+		if subnet.Contains(ip) && !usedIPs[ipStr] && !ip.Equal(subnet.IP) && !isBroadcast(ip, subnet) {
 			return ipStr, nil
 		}
+		if ip.Equal(endIP) {
+			break
+		}
 	}
 
 	return "", fmt.Errorf("no available IPs in pool")
 }
 
+// isBroadcast reports whether ip is the broadcast address of subnet
+func isBroadcast(ip net.IP, subnet *net.IPNet) bool {
+	ip4 := ip.To4()
+	if ip4 == nil || len(subnet.Mask) != net.IPv4len {
+		return false
+	}
+	for i := range ip4 {
+		if ip4[i] != subnet.IP[i]|^subnet.Mask[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func incrementIP(ip net.IP) {
 	for j := len(ip) - 1; j >= 0; j-- {
 		ip[j]++
@@ -363,7 +410,7 @@ func (s *Server) GetPeers() []*Peer {
 
 	peers := make([]*Peer, 0, len(s.peers))
 	for _, peer := range s.peers {
-		peers = append(peers, peer)
+		peers = append(peers, peer.clone())
 	}
 	return peers
 }
@@ -372,11 +419,66 @@ func (s *Server) GetPeers() []*Peer {
 func (s *Server) GetPeer(publicKey string) *Peer {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.peers[publicKey]
+	return s.peers[publicKey].clone()
+}
+
+// clone returns a copy of the peer that is safe to use without s.mu, since
+// UpdatePeerStatus keeps updating the stored one
+func (p *Peer) clone() *Peer {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// SetDNS sets the DNS servers and search domains pushed to peers. DNS
+// servers must be IPs and search domains need at least one DNS server.
+func (s *Server) SetDNS(servers, searchDomains []string) error {
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid DNS server %q, must be an IP address", server)
+		}
+	}
+	for _, domain := range searchDomains {
+		if !validSearchDomain(domain) {
+			return fmt.Errorf("invalid search domain %q", domain)
+		}
+	}
+	if len(searchDomains) > 0 && len(servers) == 0 {
+		return fmt.Errorf("search domains need a DNS server")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.DNS = servers
+	s.config.SearchDomains = searchDomains
+	return nil
+}
+
+// validSearchDomain reports whether domain is a plausible DNS name
+func validSearchDomain(domain string) bool {
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || len(domain) > 253 || net.ParseIP(domain) != nil {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // GetConfig returns server configuration
 func (s *Server) GetConfig() ServerConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.config
 }
 
@@ -401,9 +503,12 @@ func (s *Server) UpdatePeerStatus() {
 		pubKey := fields[0]
 		if peer, ok := s.peers[pubKey]; ok {
 			peer.Endpoint = fields[2]
-			if fields[4] != "0" {
-				// Has handshake
-				peer.LastSeen = time.Now()
+			// Latest handshake in unix seconds; 0 = none yet. A reachability
+			// check may have seen the peer more recently.
+			if handshake, err := strconv.ParseInt(fields[4], 10, 64); err == nil && handshake > 0 {
+				if at := time.Unix(handshake, 0); at.After(peer.LastSeen) {
+					peer.LastSeen = at
+				}
 			}
 			fmt.Sscanf(fields[5], "%d", &peer.TransferRx)
 			fmt.Sscanf(fields[6], "%d", &peer.TransferTx)
@@ -411,28 +516,3 @@ func (s *Server) UpdatePeerStatus() {
 	}
 }
 
-// savePeers saves peers to disk
-func (s *Server) savePeers() error {
-	data, err := json.MarshalIndent(s.peers, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	path := filepath.Join(s.dataDir, DataFile)
-	return os.WriteFile(path, data, 0644)
-}
-
-// loadPeers loads peers from disk
-func (s *Server) loadPeers() error {
-	path := filepath.Join(s.dataDir, DataFile)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-
-	return json.Unmarshal(data, &s.peers)
-}
-
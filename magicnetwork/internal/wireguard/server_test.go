@@ -0,0 +1,143 @@
+package wireguard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubWG puts a wg that accepts any command first on PATH
+func stubWG(t *testing.T) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "wg"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestConcurrentRegistrationsGetDistinctIPs(t *testing.T) {
+	stubWG(t)
+	dir := t.TempDir()
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.configFile = filepath.Join(dir, "wg0.conf")
+
+	const n = 50
+	peers := make([]*Peer, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			peers[i], errs[i] = s.RegisterPeer(fmt.Sprintf("node-%d", i), fmt.Sprintf("Junction %d", i), fmt.Sprintf("pubkey-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]string)
+	for i, peer := range peers {
+		if errs[i] != nil {
+			t.Fatalf("RegisterPeer(node-%d): %v", i, errs[i])
+		}
+		if other, ok := seen[peer.AssignedIP]; ok {
+			t.Errorf("%s and %s were both assigned %s", other, peer.ID, peer.AssignedIP)
+		}
+		seen[peer.AssignedIP] = peer.ID
+		if peer.AssignedIP == "10.10.0.1" {
+			t.Errorf("%s was assigned the server's address", peer.ID)
+		}
+	}
+	if got := len(s.GetPeers()); got != n {
+		t.Errorf("registry holds %d peers, want %d", got, n)
+	}
+
+	// A restart sees the same assignments
+	reloaded, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, peer := range peers {
+		if got := reloaded.GetPeer(peer.PublicKey); got == nil || got.AssignedIP != peer.AssignedIP {
+			t.Errorf("after reload %s = %+v, want %s", peer.PublicKey, got, peer.AssignedIP)
+		}
+	}
+}
+
+func TestConcurrentReregistrationKeepsIP(t *testing.T) {
+	stubWG(t)
+	dir := t.TempDir()
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.configFile = filepath.Join(dir, "wg0.conf")
+
+	// The same node retrying its registration at once gets one address
+	const n = 20
+	ips := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			peer, err := s.RegisterPeer("node-1", "Junction 1", "pubkey-1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ips[i] = peer.AssignedIP
+		}(i)
+	}
+	wg.Wait()
+
+	for i, ip := range ips {
+		if ip != ips[0] {
+			t.Errorf("registration %d got %s, want %s", i, ip, ips[0])
+		}
+	}
+	if got := len(s.GetPeers()); got != 1 {
+		t.Errorf("registry holds %d peers, want 1", got)
+	}
+}
+
+func TestUpdatePeerStatusUsesHandshakeTime(t *testing.T) {
+	stubWG(t)
+	dir := t.TempDir()
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.configFile = filepath.Join(dir, "wg0.conf")
+	for _, key := range []string{"pubkey-a", "pubkey-b"} {
+		if _, err := s.RegisterPeer(key, key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handshake := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	dump := fmt.Sprintf("privkey serverkey 51820 off\n"+
+		"pubkey-a (none) 203.0.113.5:40000 10.10.0.2/32 %d 1024 2048 25\n"+
+		"pubkey-b (none) (none) 10.10.0.3/32 0 0 0 25\n", handshake.Unix())
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncat <<'EOF'\n" + dump + "EOF\n"
+	if err := os.WriteFile(filepath.Join(bin, "wg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	s.UpdatePeerStatus()
+
+	if a := s.GetPeer("pubkey-a"); !a.LastSeen.Equal(handshake) || a.TransferRx != 1024 || a.Endpoint != "203.0.113.5:40000" {
+		t.Errorf("peer a = last seen %v, rx %d, endpoint %s; want %v, 1024, 203.0.113.5:40000", a.LastSeen, a.TransferRx, a.Endpoint, handshake)
+	}
+	if b := s.GetPeer("pubkey-b"); !b.LastSeen.IsZero() {
+		t.Errorf("peer b without a handshake was last seen %v", b.LastSeen)
+	}
+}
@@ -0,0 +1,221 @@
+package wireguard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BackupFile holds the last peers.json that was known to be good
+const BackupFile = DataFile + ".bak"
+
+// savePeers writes peers to disk atomically, keeping the previous good file
+// as a backup. Callers hold s.mu.
+func (s *Server) savePeers() error {
+	data, err := json.MarshalIndent(s.peers, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dataDir, DataFile)
+
+	// Only a file that still parses is worth keeping as the backup
+	if current, err := os.ReadFile(path); err == nil && validPeers(current) {
+		if err := writeFileAtomic(filepath.Join(s.dataDir, BackupFile), current, 0644); err != nil {
+			log.Printf("⚠️ Failed to back up peers: %v", err)
+		}
+	}
+
+	return writeFileAtomic(path, data, 0644)
+}
+
+// writeFileAtomic writes to a temp file, syncs it and renames it into place,
+// so readers see either the old or the new contents, never a partial write
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Persist the rename itself
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// validPeers reports whether data is a parseable peer registry
+func validPeers(data []byte) bool {
+	var peers map[string]*Peer
+	return json.Unmarshal(data, &peers) == nil
+}
+
+// loadPeers loads peers from disk. A corrupt peers.json is moved aside and
+// the backup is restored in its place.
+func (s *Server) loadPeers() error {
+	path := filepath.Join(s.dataDir, DataFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	peers := make(map[string]*Peer)
+	err = json.Unmarshal(data, &peers)
+	if err == nil {
+		s.peers = peers
+		return nil
+	}
+	log.Printf("❌ %s is corrupt: %v", path, err)
+
+	// Keep the corrupt file for inspection
+	corruptPath := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(path, corruptPath); err != nil {
+		return fmt.Errorf("failed to move corrupt peers file aside: %w", err)
+	}
+	log.Printf("📦 Corrupt peers file moved to %s", corruptPath)
+
+	backupPath := filepath.Join(s.dataDir, BackupFile)
+	backup, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("peers file corrupt and no backup available: %w", err)
+	}
+	peers = make(map[string]*Peer)
+	if err := json.Unmarshal(backup, &peers); err != nil {
+		return fmt.Errorf("peers file and backup are both corrupt: %w", err)
+	}
+
+	if err := writeFileAtomic(path, backup, 0644); err != nil {
+		return fmt.Errorf("failed to restore peers from backup: %w", err)
+	}
+	s.peers = peers
+	log.Printf("♻️ Restored %d peers from %s", len(peers), backupPath)
+	return nil
+}
+
+// Reconcile brings the saved peers and the live interface back in line after
+// a restart: saved peers missing from (or out of date on) the interface are
+// re-added, and peers the interface still knows about but the registry lost
+// are recovered into it
+func (s *Server) Reconcile() error {
+	cmd := exec.Command("wg", "show", InterfaceName, "allowed-ips")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to read live peers: %w", err)
+	}
+
+	// Public key -> allowed IPs
+	live := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		live[fields[0]] = strings.Join(fields[1:], ",")
+	}
+
+	added, recovered, err := s.reconcile(live)
+	if added == 0 && recovered == 0 {
+		return err
+	}
+
+	log.Printf("🔁 Reconciled peers: %d re-added to %s, %d recovered from it", added, InterfaceName, recovered)
+	// Keep wg0.conf in line so the result survives an interface restart
+	if err := s.writeConfig(); err != nil {
+		log.Printf("⚠️ Failed to rewrite config file: %v", err)
+	}
+	return err
+}
+
+// reconcile applies Reconcile to the registry given the live peers
+func (s *Server) reconcile(live map[string]string) (added, recovered int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	narrowed := 0
+
+	for pubKey, peer := range s.peers {
+		liveIPs, ok := live[pubKey]
+		if s.config.IsolatePeers && peer.AllowedIPs != isolatedAllowedIPs(peer) {
+			// Only possible for peers recovered before isolation was enabled
+			log.Printf("🧱 Narrowing %s (%s) AllowedIPs from %s to its own address", peer.Name, peer.ID, peer.AllowedIPs)
+			peer.AllowedIPs = isolatedAllowedIPs(peer)
+			narrowed++
+		} else if ok && liveIPs == strings.ReplaceAll(peer.AllowedIPs, " ", "") {
+			continue
+		}
+		if err := s.addPeerToWG(peer); err != nil {
+			log.Printf("⚠️ Failed to restore peer %s (%s) on %s: %v", peer.Name, peer.ID, InterfaceName, err)
+			continue
+		}
+		added++
+	}
+
+	for pubKey, allowedIPs := range live {
+		if _, ok := s.peers[pubKey]; ok {
+			continue
+		}
+		if allowedIPs == "(none)" {
+			continue
+		}
+		assignedIP := strings.Split(strings.Split(allowedIPs, ",")[0], "/")[0]
+		peer := &Peer{
+			ID:         "recovered-" + assignedIP,
+			Name:       "Recovered peer",
+			PublicKey:  pubKey,
+			AssignedIP: assignedIP,
+			AllowedIPs: allowedIPs,
+			CreatedAt:  time.Now(),
+		}
+		if s.config.IsolatePeers && peer.AllowedIPs != isolatedAllowedIPs(peer) {
+			peer.AllowedIPs = isolatedAllowedIPs(peer)
+			if err := s.addPeerToWG(peer); err != nil {
+				log.Printf("⚠️ Failed to narrow recovered peer %s on %s: %v", pubKey, InterfaceName, err)
+			}
+		}
+		s.peers[pubKey] = peer
+		recovered++
+		log.Printf("♻️ Recovered unregistered live peer %s -> %s", pubKey, assignedIP)
+	}
+
+	if recovered > 0 || narrowed > 0 {
+		if err := s.savePeers(); err != nil {
+			return added, recovered, fmt.Errorf("failed to save recovered peers: %w", err)
+		}
+	}
+	return added, recovered, nil
+}
@@ -0,0 +1,109 @@
+package wireguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPeersRestoresCorruptFileFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	good := map[string]*Peer{
+		"pubkey-a": {ID: "a", Name: "Junction 1", PublicKey: "pubkey-a", AssignedIP: "10.10.0.2", AllowedIPs: "10.10.0.2/32", CreatedAt: time.Now().UTC()},
+		"pubkey-b": {ID: "b", Name: "Junction 2", PublicKey: "pubkey-b", AssignedIP: "10.10.0.3", AllowedIPs: "10.10.0.3/32", CreatedAt: time.Now().UTC()},
+	}
+	backup, err := json.MarshalIndent(good, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, BackupFile), backup, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A write cut short, e.g. by power loss
+	if err := os.WriteFile(filepath.Join(dir, DataFile), backup[:len(backup)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.loadErr != nil {
+		t.Fatalf("loadErr = %v, want the backup restored", s.loadErr)
+	}
+	if len(s.peers) != len(good) {
+		t.Fatalf("loaded %d peers, want %d", len(s.peers), len(good))
+	}
+	for key, want := range good {
+		got, ok := s.peers[key]
+		if !ok || got.AssignedIP != want.AssignedIP || got.Name != want.Name {
+			t.Errorf("peer %s = %+v, want %+v", key, got, want)
+		}
+	}
+
+	// peers.json is the backup again, and the corrupt file is kept aside
+	restored, err := os.ReadFile(filepath.Join(dir, DataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored, backup) {
+		t.Errorf("peers.json wasn't restored from the backup")
+	}
+	corrupt, err := filepath.Glob(filepath.Join(dir, DataFile+".corrupt-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupt) != 1 {
+		t.Errorf("found %d corrupt copies, want 1", len(corrupt))
+	}
+}
+
+func TestLoadPeersWithoutBackup(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, DataFile), []byte(`{"pubkey-a": {"id": "a"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.loadErr == nil {
+		t.Fatal("loadErr = nil, want an error with no backup to restore")
+	}
+	if len(s.peers) != 0 {
+		t.Errorf("loaded %d peers from a corrupt file, want 0", len(s.peers))
+	}
+}
+
+func TestSavePeersKeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewServer(dir, 51820, "10.10.0.1/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.peers["pubkey-a"] = &Peer{ID: "a", PublicKey: "pubkey-a", AssignedIP: "10.10.0.2"}
+	if err := s.savePeers(); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(filepath.Join(dir, DataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.peers["pubkey-b"] = &Peer{ID: "b", PublicKey: "pubkey-b", AssignedIP: "10.10.0.3"}
+	if err := s.savePeers(); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := os.ReadFile(filepath.Join(dir, BackupFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, first) {
+		t.Errorf("backup isn't the previous peers.json")
+	}
+}
@@ -22,6 +22,10 @@ API_PORT=${API_PORT:-8080}
 WG_PORT=${WG_PORT:-51820}
 WG_ADDRESS=${WG_ADDRESS:-"10.10.0.1/24"}
 DATA_DIR=${DATA_DIR:-"./data"}
+READY_MIN_PEERS=${READY_MIN_PEERS:-0}
+WG_DNS=${WG_DNS:-""}
+WG_SEARCH_DOMAINS=${WG_SEARCH_DOMAINS:-""}
+ISOLATE_PEERS=${ISOLATE_PEERS:-false}
 
 # Build if needed
 if [ ! -f ./magicnetwork ]; then
@@ -36,5 +40,9 @@ echo "🚀 Starting MagicNetwork..."
     --wg-port "$WG_PORT" \
     --address "$WG_ADDRESS" \
     --data "$DATA_DIR" \
+    --ready-min-peers "$READY_MIN_PEERS" \
+    --dns "$WG_DNS" \
+    --search-domains "$WG_SEARCH_DOMAINS" \
+    --isolate-peers="$ISOLATE_PEERS" \
     ${MAGICNETWORK_API_KEY:+--api-key "$MAGICNETWORK_API_KEY"}
 